@@ -0,0 +1,119 @@
+package adminapi
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gameap/daemon/internal/app/backups"
+	"github.com/gameap/daemon/internal/app/domain"
+	"github.com/gameap/daemon/pkg/logger"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+type BackupManager interface {
+	List(serverID int) ([]backups.Backup, error)
+	Backup(ctx context.Context, serverID int) (backups.Backup, error)
+	Restore(ctx context.Context, serverID int, at time.Time) (backups.Backup, error)
+}
+
+func (s *Server) handleBackups(w http.ResponseWriter, r *http.Request) {
+	server, ok := s.findBackupServer(w, r)
+	if !ok {
+		return
+	}
+
+	list, err := s.backups.List(server.ID())
+	if err != nil {
+		s.writeError(r.Context(), w, http.StatusInternalServerError, err)
+		return
+	}
+
+	views := make([]BackupView, 0, len(list))
+	for _, b := range list {
+		views = append(views, newBackupView(b))
+	}
+
+	writeJSON(w, http.StatusOK, views)
+}
+
+func (s *Server) handleBackupCreate(w http.ResponseWriter, r *http.Request) {
+	server, ok := s.findBackupServer(w, r)
+	if !ok {
+		return
+	}
+
+	ctx := logger.WithLogger(r.Context(), logger.WithField(r.Context(), "gameServerID", server.ID()))
+
+	logger.Info(ctx, "Server backup requested via admin API")
+
+	backup, err := s.backups.Backup(ctx, server.ID())
+	if err != nil {
+		s.writeError(ctx, w, backupErrorStatus(err), errors.WithMessage(err, "failed to back up server"))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, newBackupView(backup))
+}
+
+// handleBackupRestore restores the newest backup made not later than the
+// "at" query parameter (RFC 3339), the newest backup when it is absent.
+func (s *Server) handleBackupRestore(w http.ResponseWriter, r *http.Request) {
+	server, ok := s.findBackupServer(w, r)
+	if !ok {
+		return
+	}
+
+	at := time.Now()
+	if v := r.URL.Query().Get("at"); v != "" {
+		var err error
+		if at, err = time.Parse(time.RFC3339, v); err != nil {
+			writeJSON(w, http.StatusBadRequest, ErrorResponse{Error: "invalid at, expected RFC 3339 time"})
+			return
+		}
+	}
+
+	ctx := logger.WithLogger(r.Context(), logger.WithFields(r.Context(), log.Fields{
+		"gameServerID": server.ID(),
+		"at":           at.Format(time.RFC3339),
+	}))
+
+	logger.Info(ctx, "Server restore requested via admin API")
+
+	backup, err := s.backups.Restore(ctx, server.ID(), at)
+	if err != nil {
+		s.writeError(ctx, w, backupErrorStatus(err), errors.WithMessage(err, "failed to restore server"))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, newBackupView(backup))
+}
+
+func (s *Server) findBackupServer(w http.ResponseWriter, r *http.Request) (*domain.Server, bool) {
+	if s.backups == nil {
+		writeJSON(w, http.StatusNotFound, ErrorResponse{Error: "backups are not available"})
+		return nil, false
+	}
+
+	return s.findServer(w, r)
+}
+
+func backupErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, backups.ErrBusy):
+		return http.StatusConflict
+	case errors.Is(err, backups.ErrNoBackups), errors.Is(err, backups.ErrBackupNotFound):
+		return http.StatusNotFound
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func newBackupView(b backups.Backup) BackupView {
+	return BackupView{
+		Name:      b.Name,
+		CreatedAt: b.CreatedAt,
+		Size:      b.Size,
+	}
+}
@@ -0,0 +1,97 @@
+package adminapi
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gameap/daemon/internal/app/backups"
+	"github.com/gameap/daemon/internal/app/config"
+	"github.com/gameap/daemon/internal/app/repositories"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeBackupManager struct {
+	list       []backups.Backup
+	restoredAt time.Time
+}
+
+func (f *fakeBackupManager) List(_ int) ([]backups.Backup, error) {
+	return f.list, nil
+}
+
+func (f *fakeBackupManager) Backup(_ context.Context, _ int) (backups.Backup, error) {
+	return backups.Backup{}, backups.ErrBusy
+}
+
+func (f *fakeBackupManager) Restore(_ context.Context, _ int, at time.Time) (backups.Backup, error) {
+	f.restoredAt = at
+
+	return f.list[0], nil
+}
+
+func givenBackupAPI(t *testing.T) (http.Handler, *fakeBackupManager) {
+	t.Helper()
+
+	repo := repositories.NewServerRepository()
+	require.NoError(t, repo.Save(context.Background(), givenServer(7)))
+
+	manager := &fakeBackupManager{
+		list: []backups.Backup{
+			{ServerID: 7, Name: "20261018T100000Z.tar.gz", CreatedAt: time.Date(2026, 10, 18, 10, 0, 0, 0, time.UTC)},
+		},
+	}
+
+	server := NewServer(config.NewConfig(), repo, &fakeCommandFactory{}, fakeTaskQueue{}, nil, fakeConnection(true))
+	server.SetBackupManager(manager)
+
+	return server.Handler(), manager
+}
+
+func TestBackups_List(t *testing.T) {
+	h, _ := givenBackupAPI(t)
+
+	var got []BackupView
+	code := doRequest(t, h, http.MethodGet, "/v1/servers/7/backups", &got)
+
+	require.Equal(t, http.StatusOK, code)
+	require.Len(t, got, 1)
+	assert.Equal(t, "20261018T100000Z.tar.gz", got[0].Name)
+}
+
+func TestBackups_CreateBusy(t *testing.T) {
+	h, _ := givenBackupAPI(t)
+
+	code := doRequest(t, h, http.MethodPost, "/v1/servers/7/backups", nil)
+
+	assert.Equal(t, http.StatusConflict, code)
+}
+
+func TestBackups_RestorePointInTime(t *testing.T) {
+	h, manager := givenBackupAPI(t)
+
+	var got BackupView
+	code := doRequest(t, h, http.MethodPost, "/v1/servers/7/restore?at=2026-10-18T11:30:00Z", &got)
+
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "20261018T100000Z.tar.gz", got.Name)
+	assert.Equal(t, time.Date(2026, 10, 18, 11, 30, 0, 0, time.UTC), manager.restoredAt.UTC())
+}
+
+func TestBackups_RestoreInvalidTime(t *testing.T) {
+	h, _ := givenBackupAPI(t)
+
+	code := doRequest(t, h, http.MethodPost, "/v1/servers/7/restore?at=yesterday", nil)
+
+	assert.Equal(t, http.StatusBadRequest, code)
+}
+
+func TestBackups_NotAvailable(t *testing.T) {
+	h, _ := givenAPI(t)
+
+	code := doRequest(t, h, http.MethodGet, "/v1/servers/7/backups", nil)
+
+	assert.Equal(t, http.StatusNotFound, code)
+}
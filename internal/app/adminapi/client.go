@@ -0,0 +1,239 @@
+package adminapi
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var ErrUnexpectedResponse = errors.New("unexpected admin API response")
+
+// Client talks to the admin API of a running daemon over its Unix socket.
+type Client struct {
+	socket     string
+	httpClient *http.Client
+}
+
+func NewClient(socket string) *Client {
+	c := &Client{socket: socket}
+	c.httpClient = &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return c.dial(ctx)
+			},
+		},
+	}
+
+	return c
+}
+
+func (c *Client) Status(ctx context.Context) (StatusResponse, error) {
+	var status StatusResponse
+	err := c.do(ctx, http.MethodGet, "/v1/status", http.StatusOK, &status)
+
+	return status, err
+}
+
+func (c *Client) Servers(ctx context.Context) ([]ServerView, error) {
+	var servers []ServerView
+	err := c.do(ctx, http.MethodGet, "/v1/servers", http.StatusOK, &servers)
+
+	return servers, err
+}
+
+func (c *Client) Server(ctx context.Context, id int) (ServerView, error) {
+	var server ServerView
+	err := c.do(ctx, http.MethodGet, "/v1/servers/"+strconv.Itoa(id), http.StatusOK, &server)
+
+	return server, err
+}
+
+// ServerCommand runs start, stop or restart and waits for it to finish.
+func (c *Client) ServerCommand(ctx context.Context, id int, command string) (CommandResponse, error) {
+	var result CommandResponse
+	err := c.do(ctx, http.MethodPost, "/v1/servers/"+strconv.Itoa(id)+"/"+command, http.StatusOK, &result)
+
+	return result, err
+}
+
+func (c *Client) Tasks(ctx context.Context) (TasksResponse, error) {
+	var tasks TasksResponse
+	err := c.do(ctx, http.MethodGet, "/v1/tasks", http.StatusOK, &tasks)
+
+	return tasks, err
+}
+
+func (c *Client) CancelTask(ctx context.Context, id int) error {
+	return c.do(ctx, http.MethodPost, "/v1/tasks/"+strconv.Itoa(id)+"/cancel", http.StatusNoContent, nil)
+}
+
+// Backups lists the backups of the server ordered from the oldest.
+func (c *Client) Backups(ctx context.Context, id int) ([]BackupView, error) {
+	var list []BackupView
+	err := c.do(ctx, http.MethodGet, "/v1/servers/"+strconv.Itoa(id)+"/backups", http.StatusOK, &list)
+
+	return list, err
+}
+
+// Backup makes a backup of the server and waits for it to finish.
+func (c *Client) Backup(ctx context.Context, id int) (BackupView, error) {
+	var backup BackupView
+	err := c.do(ctx, http.MethodPost, "/v1/servers/"+strconv.Itoa(id)+"/backups", http.StatusOK, &backup)
+
+	return backup, err
+}
+
+// Restore restores the newest backup made not later than at, the newest
+// backup when at is zero.
+func (c *Client) Restore(ctx context.Context, id int, at time.Time) (BackupView, error) {
+	path := "/v1/servers/" + strconv.Itoa(id) + "/restore"
+	if !at.IsZero() {
+		path += "?at=" + url.QueryEscape(at.Format(time.RFC3339))
+	}
+
+	var backup BackupView
+	err := c.do(ctx, http.MethodPost, path, http.StatusOK, &backup)
+
+	return backup, err
+}
+
+// Metrics returns the latest metrics snapshot as JSON.
+func (c *Client) Metrics(ctx context.Context) (json.RawMessage, error) {
+	var metrics json.RawMessage
+	err := c.do(ctx, http.MethodGet, "/v1/metrics", http.StatusOK, &metrics)
+
+	return metrics, err
+}
+
+// Console attaches to the server console. Everything read from in is sent to
+// the server, the output is written to out. It returns when the daemon
+// closes the session, which it does once in is exhausted, or when ctx is
+// done.
+func (c *Client) Console(ctx context.Context, id int, in io.Reader, out io.Writer) error {
+	conn, err := c.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	consoleURL := "http://localhost/v1/servers/" + strconv.Itoa(id) + "/console"
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, consoleURL, nil)
+	if err != nil {
+		return errors.Wrap(err, "failed to create console request")
+	}
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", ConsoleProtocol)
+
+	if err := req.Write(conn); err != nil {
+		return errors.Wrap(err, "failed to send console request")
+	}
+
+	br := bufio.NewReader(conn)
+
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		return errors.Wrap(err, "failed to read console response")
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		defer resp.Body.Close()
+		return responseError(resp)
+	}
+
+	done := make(chan struct{})
+	var closeOnce sync.Once
+	closeConn := func() {
+		closeOnce.Do(func() {
+			close(done)
+			_ = conn.Close()
+		})
+	}
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			closeConn()
+		case <-done:
+		}
+	}()
+
+	// On the end of input only the write side is closed, the daemon ends the
+	// session and the remaining output is still read.
+	go func() {
+		_, _ = io.Copy(conn, in)
+
+		if cw, ok := conn.(interface{ CloseWrite() error }); ok && cw.CloseWrite() == nil {
+			return
+		}
+		closeConn()
+	}()
+
+	_, err = io.Copy(out, br)
+	closeConn()
+
+	if ctx.Err() != nil {
+		return nil
+	}
+
+	if err != nil && !errors.Is(err, net.ErrClosed) {
+		return errors.Wrap(err, "console connection failed")
+	}
+
+	return nil
+}
+
+func (c *Client) dial(ctx context.Context) (net.Conn, error) {
+	dialer := net.Dialer{}
+
+	conn, err := dialer.DialContext(ctx, "unix", c.socket)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to connect to the daemon at %s", c.socket)
+	}
+
+	return conn, nil
+}
+
+func (c *Client) do(ctx context.Context, method, path string, expectedStatus int, v any) error {
+	req, err := http.NewRequestWithContext(ctx, method, "http://localhost"+path, nil)
+	if err != nil {
+		return errors.Wrap(err, "failed to create request")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != expectedStatus {
+		return responseError(resp)
+	}
+
+	if v == nil {
+		return nil
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return errors.Wrap(err, "failed to decode response")
+	}
+
+	return nil
+}
+
+func responseError(resp *http.Response) error {
+	var body ErrorResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err == nil && body.Error != "" {
+		return errors.Wrapf(ErrUnexpectedResponse, "%s: %s", resp.Status, body.Error)
+	}
+
+	return errors.Wrap(ErrUnexpectedResponse, resp.Status)
+}
@@ -0,0 +1,91 @@
+package adminapi
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gameap/daemon/internal/app/config"
+	"github.com/gameap/daemon/internal/app/contracts"
+	"github.com/gameap/daemon/internal/app/domain"
+	"github.com/gameap/daemon/internal/app/repositories"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// echoProcessManager answers every console line with "> " + line.
+type echoProcessManager struct {
+	contracts.ProcessManager
+}
+
+func (echoProcessManager) Attach(_ context.Context, _ *domain.Server, in io.Reader, out io.Writer) error {
+	scanner := bufio.NewScanner(in)
+	for scanner.Scan() {
+		if _, err := io.WriteString(out, "> "+scanner.Text()+"\n"); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func givenRunningAPI(t *testing.T) *Client {
+	t.Helper()
+
+	repo := repositories.NewServerRepository()
+	require.NoError(t, repo.Save(context.Background(), givenServer(7)))
+
+	socket := filepath.Join(t.TempDir(), "admin.sock")
+	api := NewServer(
+		config.NewConfig(), repo, &fakeCommandFactory{}, fakeTaskQueue{}, echoProcessManager{}, fakeConnection(false),
+	)
+
+	l, err := listenUnix(context.Background(), socket)
+	require.NoError(t, err)
+
+	srv := &http.Server{Handler: api.Handler()} //nolint:gosec
+	go func() {
+		_ = srv.Serve(l)
+	}()
+	t.Cleanup(func() {
+		_ = srv.Close()
+	})
+
+	return NewClient(socket)
+}
+
+func TestClient_ServersAndCommands(t *testing.T) {
+	client := givenRunningAPI(t)
+	ctx := context.Background()
+
+	servers, err := client.Servers(ctx)
+	require.NoError(t, err)
+	require.Len(t, servers, 1)
+	assert.Equal(t, 7, servers[0].ID)
+
+	result, err := client.ServerCommand(ctx, 7, "start")
+	require.NoError(t, err)
+	assert.True(t, result.Success)
+	assert.True(t, result.Server.Active)
+
+	_, err = client.Server(ctx, 100)
+	require.ErrorIs(t, err, ErrUnexpectedResponse)
+	assert.Contains(t, err.Error(), "server not found")
+
+	require.NoError(t, client.CancelTask(ctx, 3))
+}
+
+func TestClient_Console(t *testing.T) {
+	client := givenRunningAPI(t)
+
+	out := &bytes.Buffer{}
+	err := client.Console(context.Background(), 7, strings.NewReader("status\n"), out)
+
+	require.NoError(t, err)
+	assert.Equal(t, "> status\n", out.String())
+}
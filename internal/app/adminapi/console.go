@@ -0,0 +1,83 @@
+package adminapi
+
+import (
+	"context"
+	"io"
+	"net/http"
+
+	"github.com/gameap/daemon/pkg/logger"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// ConsoleProtocol is the Upgrade token of the console endpoint. After the
+// switch the connection carries the raw server console: client bytes go to
+// the server stdin, the server output is written back as is.
+const ConsoleProtocol = "gameap-console"
+
+func (s *Server) handleConsole(w http.ResponseWriter, r *http.Request) {
+	if s.processManager == nil {
+		writeJSON(w, http.StatusNotImplemented, ErrorResponse{Error: "console is not available"})
+		return
+	}
+
+	if r.Header.Get("Upgrade") != ConsoleProtocol {
+		writeJSON(w, http.StatusUpgradeRequired, ErrorResponse{Error: "upgrade to " + ConsoleProtocol + " required"})
+		return
+	}
+
+	server, ok := s.findServer(w, r)
+	if !ok {
+		return
+	}
+
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		writeJSON(w, http.StatusInternalServerError, ErrorResponse{Error: "connection does not support upgrade"})
+		return
+	}
+
+	conn, rw, err := hijacker.Hijack()
+	if err != nil {
+		logger.Warn(r.Context(), errors.Wrap(err, "failed to hijack console connection"))
+		return
+	}
+	defer conn.Close()
+
+	_, err = rw.WriteString("HTTP/1.1 101 Switching Protocols\r\n" +
+		"Upgrade: " + ConsoleProtocol + "\r\n" +
+		"Connection: Upgrade\r\n\r\n")
+	if err == nil {
+		err = rw.Flush()
+	}
+	if err != nil {
+		logger.Warn(r.Context(), errors.Wrap(err, "failed to switch console protocol"))
+		return
+	}
+
+	entry := logger.WithFields(r.Context(), log.Fields{"gameServerID": server.ID()})
+	entry.Info("Console attached via admin API")
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	// Attach implementations do not all stop on stdin EOF, the session ends
+	// as soon as the client goes away.
+	stdinPR, stdinPW := io.Pipe()
+	go func() {
+		_, err := io.Copy(stdinPW, rw.Reader)
+		_ = stdinPW.CloseWithError(err)
+		cancel()
+	}()
+
+	err = s.processManager.Attach(ctx, server, stdinPR, conn)
+	_ = stdinPR.Close()
+
+	switch {
+	case err == nil, errors.Is(err, context.Canceled):
+		entry.Info("Console detached")
+	default:
+		entry.WithError(err).Info("Console closed")
+		_, _ = io.WriteString(conn, "\n"+err.Error()+"\n")
+	}
+}
@@ -0,0 +1,279 @@
+package adminapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gameap/daemon/internal/app/build"
+	"github.com/gameap/daemon/internal/app/domain"
+	gameservercommands "github.com/gameap/daemon/internal/app/game_server_commands"
+	"github.com/gameap/daemon/pkg/logger"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// serverCommands are the commands operators may run through the API. Long
+// running ones (install, update, delete) stay with the panel task queue.
+var serverCommands = map[string]domain.ServerCommand{
+	"start":   domain.Start,
+	"stop":    domain.Stop,
+	"restart": domain.Restart,
+}
+
+// Handler returns the HTTP handler with all API routes.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /v1/status", s.handleStatus)
+	mux.HandleFunc("GET /v1/servers", s.handleServers)
+	mux.HandleFunc("GET /v1/servers/{id}", s.handleServer)
+	mux.HandleFunc("POST /v1/servers/{id}/{command}", s.handleServerCommand)
+	mux.HandleFunc("GET /v1/servers/{id}/console", s.handleConsole)
+	mux.HandleFunc("GET /v1/servers/{id}/backups", s.handleBackups)
+	mux.HandleFunc("POST /v1/servers/{id}/backups", s.handleBackupCreate)
+	mux.HandleFunc("POST /v1/servers/{id}/restore", s.handleBackupRestore)
+	mux.HandleFunc("GET /v1/tasks", s.handleTasks)
+	mux.HandleFunc("POST /v1/tasks/{id}/cancel", s.handleTaskCancel)
+	mux.HandleFunc("GET /v1/metrics", s.handleMetrics)
+
+	return mux
+}
+
+func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	ids, err := s.serverRepo.IDs(r.Context())
+	if err != nil {
+		s.writeError(r.Context(), w, http.StatusInternalServerError, err)
+		return
+	}
+
+	stats := s.taskQueue.Stats()
+
+	writeJSON(w, http.StatusOK, StatusResponse{
+		Version:       build.Version,
+		UptimeSeconds: int64(time.Since(domain.StartTime).Seconds()),
+		Connected:     s.connection != nil && s.connection.IsConnected(),
+		Servers:       len(ids),
+		Tasks: TaskCounts{
+			Working: stats.WorkingCount,
+			Waiting: stats.WaitingCount,
+		},
+	})
+}
+
+func (s *Server) handleServers(w http.ResponseWriter, r *http.Request) {
+	ids, err := s.serverRepo.IDs(r.Context())
+	if err != nil {
+		s.writeError(r.Context(), w, http.StatusInternalServerError, err)
+		return
+	}
+
+	views := make([]ServerView, 0, len(ids))
+	for _, id := range ids {
+		server, err := s.serverRepo.FindByID(r.Context(), id)
+		if err != nil {
+			s.writeError(r.Context(), w, http.StatusInternalServerError, err)
+			return
+		}
+		if server == nil {
+			continue
+		}
+
+		views = append(views, newServerView(server))
+	}
+
+	writeJSON(w, http.StatusOK, views)
+}
+
+func (s *Server) handleServer(w http.ResponseWriter, r *http.Request) {
+	server, ok := s.findServer(w, r)
+	if !ok {
+		return
+	}
+
+	writeJSON(w, http.StatusOK, newServerView(server))
+}
+
+func (s *Server) handleServerCommand(w http.ResponseWriter, r *http.Request) {
+	command, ok := serverCommands[r.PathValue("command")]
+	if !ok {
+		writeJSON(w, http.StatusNotFound, ErrorResponse{Error: "unknown server command"})
+		return
+	}
+
+	server, ok := s.findServer(w, r)
+	if !ok {
+		return
+	}
+
+	// The command is not interrupted when the client goes away, a server
+	// must not be left half started or stopped.
+	ctx := logger.WithLogger(context.WithoutCancel(r.Context()), logger.WithFields(r.Context(), log.Fields{
+		"gameServerID": server.ID(),
+		"command":      r.PathValue("command"),
+	}))
+
+	if s.serverLocks != nil {
+		unlock, err := s.serverLocks.Lock(r.Context(), server.ID(), true)
+		if err != nil {
+			s.writeError(ctx, w, http.StatusServiceUnavailable, errors.WithMessage(err, "failed to wait for the server"))
+			return
+		}
+		defer unlock()
+	}
+
+	if timeout := s.cfg.TaskManager.TaskTimeout; timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	logger.Info(ctx, "Running server command requested via admin API")
+
+	cmd := s.commandFactory.LoadServerCommand(command, server)
+
+	err := cmd.Execute(ctx, server)
+	if err != nil {
+		s.writeError(ctx, w, http.StatusInternalServerError, errors.WithMessage(err, "failed to execute server command"))
+		return
+	}
+
+	success := cmd.Result() == gameservercommands.SuccessResult
+	if success {
+		server.NoticeTaskCompleted()
+	}
+
+	if err := s.serverRepo.Save(ctx, server); err != nil {
+		logger.Warn(ctx, errors.WithMessage(err, "failed to save server"))
+	}
+
+	writeJSON(w, http.StatusOK, CommandResponse{
+		Success: success,
+		Output:  string(cmd.ReadOutput()),
+		Server:  newServerView(server),
+	})
+}
+
+func (s *Server) handleTasks(w http.ResponseWriter, _ *http.Request) {
+	stats := s.taskQueue.Stats()
+	_, tasks := s.taskQueue.WorkingTasks()
+
+	views := make([]TaskView, 0, len(tasks))
+	for _, task := range tasks {
+		view := TaskView{
+			ID:     task.ID(),
+			Task:   string(task.Task()),
+			Status: string(task.Status()),
+		}
+		if task.Server() != nil {
+			view.ServerID = task.Server().ID()
+		}
+
+		views = append(views, view)
+	}
+
+	writeJSON(w, http.StatusOK, TasksResponse{
+		TaskCounts: TaskCounts{
+			Working: stats.WorkingCount,
+			Waiting: stats.WaitingCount,
+		},
+		InProgress: views,
+	})
+}
+
+func (s *Server) handleTaskCancel(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id <= 0 {
+		writeJSON(w, http.StatusBadRequest, ErrorResponse{Error: "invalid task id"})
+		return
+	}
+
+	if err := s.taskQueue.CancelTask(id); err != nil {
+		writeJSON(w, http.StatusConflict, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	logger.WithField(r.Context(), "taskID", id).Info("Task cancelled via admin API")
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	if s.metrics == nil {
+		writeJSON(w, http.StatusNotFound, ErrorResponse{Error: "metrics collection is disabled"})
+		return
+	}
+
+	body, err := protojson.Marshal(s.metrics.Current())
+	if err != nil {
+		s.writeError(r.Context(), w, http.StatusInternalServerError, errors.Wrap(err, "failed to encode metrics"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(body)
+}
+
+func (s *Server) findServer(w http.ResponseWriter, r *http.Request) (*domain.Server, bool) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id <= 0 {
+		writeJSON(w, http.StatusBadRequest, ErrorResponse{Error: "invalid server id"})
+		return nil, false
+	}
+
+	server, err := s.serverRepo.FindByID(r.Context(), id)
+	if err != nil {
+		s.writeError(r.Context(), w, http.StatusInternalServerError, err)
+		return nil, false
+	}
+	if server == nil {
+		writeJSON(w, http.StatusNotFound, ErrorResponse{Error: "server not found"})
+		return nil, false
+	}
+
+	return server, true
+}
+
+func (s *Server) writeError(ctx context.Context, w http.ResponseWriter, status int, err error) {
+	logger.Warn(ctx, err)
+
+	writeJSON(w, status, ErrorResponse{Error: err.Error()})
+}
+
+func newServerView(server *domain.Server) ServerView {
+	view := ServerView{
+		ID:           server.ID(),
+		UUID:         server.UUID(),
+		Name:         server.Name(),
+		Enabled:      server.Enabled(),
+		Blocked:      server.Blocked(),
+		Installed:    server.InstallationStatus() == domain.ServerInstalled,
+		Active:       server.IsActive(),
+		State:        string(server.RunState()),
+		AutoStart:    server.AutoStart(),
+		CrashLooping: server.IsCrashLooping(),
+		IP:           server.IP(),
+		ConnectPort:  server.ConnectPort(),
+	}
+
+	if checked := server.LastStatusCheck(); !checked.IsZero() {
+		view.LastStatusCheck = &checked
+	}
+
+	if completed := server.LastTaskCompletedAt(); !completed.IsZero() {
+		view.LastTaskComplete = &completed
+	}
+
+	return view
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,289 @@
+package adminapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gameap/daemon/internal/app/config"
+	"github.com/gameap/daemon/internal/app/contracts"
+	"github.com/gameap/daemon/internal/app/domain"
+	gameservercommands "github.com/gameap/daemon/internal/app/game_server_commands"
+	"github.com/gameap/daemon/internal/app/repositories"
+	"github.com/gameap/daemon/internal/app/serverlock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeCommand struct {
+	output string
+	failed bool
+	run    func(ctx context.Context, server *domain.Server)
+}
+
+func (c *fakeCommand) Execute(ctx context.Context, server *domain.Server) error {
+	if c.run != nil {
+		c.run(ctx, server)
+	}
+
+	return nil
+}
+
+func (c *fakeCommand) ReadOutput() []byte {
+	return []byte(c.output)
+}
+
+func (c *fakeCommand) Result() int {
+	if c.failed {
+		return gameservercommands.ErrorResult
+	}
+
+	return gameservercommands.SuccessResult
+}
+
+func (c *fakeCommand) IsComplete() bool {
+	return true
+}
+
+type fakeCommandFactory struct {
+	loaded  []domain.ServerCommand
+	command *fakeCommand
+}
+
+func (f *fakeCommandFactory) LoadServerCommand(
+	cmd domain.ServerCommand, _ *domain.Server,
+) contracts.GameServerCommand {
+	f.loaded = append(f.loaded, cmd)
+
+	if f.command != nil {
+		return f.command
+	}
+
+	return &fakeCommand{
+		output: "started",
+		run: func(_ context.Context, server *domain.Server) {
+			server.SetStatus(true)
+		},
+	}
+}
+
+type fakeTaskQueue struct{}
+
+func (fakeTaskQueue) Stats() domain.GDTaskStats {
+	return domain.GDTaskStats{WorkingCount: 1, WaitingCount: 2}
+}
+
+func (fakeTaskQueue) WorkingTasks() ([]int, []*domain.GDTask) {
+	return nil, nil
+}
+
+func (fakeTaskQueue) CancelTask(taskID int) error {
+	if taskID != 3 {
+		return errTaskNotFound
+	}
+
+	return nil
+}
+
+var errTaskNotFound = errors.New("task not found")
+
+type fakeConnection bool
+
+func (c fakeConnection) IsConnected() bool {
+	return bool(c)
+}
+
+func givenServer(id int) *domain.Server {
+	return domain.NewServer(
+		id,
+		true,
+		domain.ServerInstalled,
+		false,
+		"test",
+		"9fd2e4d1-4a4e-4d3c-9a2f-5a4b5d8c9e01",
+		"9fd2e4d1",
+		domain.Game{Code: "cstrike", StartCode: "cstrike"},
+		domain.GameMod{ID: 2, Name: "public"},
+		"127.0.0.1",
+		27015,
+		27016,
+		27017,
+		"rconpass",
+		"servers/test",
+		"gameap",
+		"./hlds_run",
+		"quit",
+		"",
+		"",
+		false,
+		time.Time{},
+		nil,
+		domain.Settings{"autostart": "1"},
+		time.Time{},
+		0,
+		0,
+	)
+}
+
+func givenAPI(t *testing.T) (http.Handler, *fakeCommandFactory) {
+	t.Helper()
+
+	server, factory := givenAPIServer(t)
+
+	return server.Handler(), factory
+}
+
+func givenAPIServer(t *testing.T) (*Server, *fakeCommandFactory) {
+	t.Helper()
+
+	repo := repositories.NewServerRepository()
+	require.NoError(t, repo.Save(context.Background(), givenServer(7)))
+
+	factory := &fakeCommandFactory{}
+
+	return NewServer(config.NewConfig(), repo, factory, fakeTaskQueue{}, nil, fakeConnection(true)), factory
+}
+
+func doRequest(t *testing.T, h http.Handler, method, path string, v any) int {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(method, path, nil))
+
+	if v != nil {
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), v), rec.Body.String())
+	}
+
+	return rec.Code
+}
+
+func TestStatus(t *testing.T) {
+	h, _ := givenAPI(t)
+
+	var got StatusResponse
+	code := doRequest(t, h, http.MethodGet, "/v1/status", &got)
+
+	assert.Equal(t, http.StatusOK, code)
+	assert.True(t, got.Connected)
+	assert.Equal(t, 1, got.Servers)
+	assert.Equal(t, TaskCounts{Working: 1, Waiting: 2}, got.Tasks)
+}
+
+func TestServers(t *testing.T) {
+	h, _ := givenAPI(t)
+
+	var got []ServerView
+	code := doRequest(t, h, http.MethodGet, "/v1/servers", &got)
+
+	require.Equal(t, http.StatusOK, code)
+	require.Len(t, got, 1)
+	assert.Equal(t, 7, got[0].ID)
+	assert.Equal(t, "test", got[0].Name)
+	assert.True(t, got[0].Installed)
+	assert.False(t, got[0].Active)
+}
+
+func TestServer_NotFound(t *testing.T) {
+	h, _ := givenAPI(t)
+
+	var got ErrorResponse
+	code := doRequest(t, h, http.MethodGet, "/v1/servers/8", &got)
+
+	assert.Equal(t, http.StatusNotFound, code)
+	assert.Equal(t, "server not found", got.Error)
+}
+
+func TestServerCommand_Start(t *testing.T) {
+	h, factory := givenAPI(t)
+
+	var got CommandResponse
+	code := doRequest(t, h, http.MethodPost, "/v1/servers/7/start", &got)
+
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, []domain.ServerCommand{domain.Start}, factory.loaded)
+	assert.True(t, got.Success)
+	assert.Equal(t, "started", got.Output)
+	assert.True(t, got.Server.Active)
+}
+
+func TestServerCommand_Failed(t *testing.T) {
+	server, factory := givenAPIServer(t)
+	factory.command = &fakeCommand{output: "failed to start", failed: true}
+
+	var got CommandResponse
+	code := doRequest(t, server.Handler(), http.MethodPost, "/v1/servers/7/start", &got)
+
+	require.Equal(t, http.StatusOK, code)
+	assert.False(t, got.Success)
+	gameServer, err := server.serverRepo.FindByID(context.Background(), 7)
+	require.NoError(t, err)
+	assert.True(t, gameServer.LastTaskCompletedAt().IsZero())
+}
+
+func TestServerCommand_NotCanceledWithRequest(t *testing.T) {
+	server, factory := givenAPIServer(t)
+	reqCtx, cancelReq := context.WithCancel(context.Background())
+	defer cancelReq()
+	var cmdErr error
+	factory.command = &fakeCommand{run: func(ctx context.Context, _ *domain.Server) {
+		cancelReq()
+		cmdErr = ctx.Err()
+	}}
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequestWithContext(reqCtx, http.MethodPost, "/v1/servers/7/stop", nil)
+	server.Handler().ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.NoError(t, cmdErr)
+}
+
+func TestServerCommand_WaitsForServerLock(t *testing.T) {
+	server, factory := givenAPIServer(t)
+	locks := serverlock.New()
+	server.SetServerLocks(locks)
+	unlock, err := locks.Lock(context.Background(), 7, true)
+	require.NoError(t, err)
+	defer unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	rec := httptest.NewRecorder()
+	server.Handler().ServeHTTP(rec, httptest.NewRequestWithContext(ctx, http.MethodPost, "/v1/servers/7/start", nil))
+
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Empty(t, factory.loaded)
+}
+
+func TestServerCommand_UnknownCommand(t *testing.T) {
+	h, factory := givenAPI(t)
+
+	code := doRequest(t, h, http.MethodPost, "/v1/servers/7/delete", nil)
+
+	assert.Equal(t, http.StatusNotFound, code)
+	assert.Empty(t, factory.loaded)
+}
+
+func TestMetrics_Disabled(t *testing.T) {
+	h, _ := givenAPI(t)
+
+	code := doRequest(t, h, http.MethodGet, "/v1/metrics", nil)
+
+	assert.Equal(t, http.StatusNotFound, code)
+}
+
+func TestTaskCancel(t *testing.T) {
+	h, _ := givenAPI(t)
+
+	assert.Equal(t, http.StatusNoContent, doRequest(t, h, http.MethodPost, "/v1/tasks/3/cancel", nil))
+
+	var got ErrorResponse
+	code := doRequest(t, h, http.MethodPost, "/v1/tasks/4/cancel", &got)
+
+	assert.Equal(t, http.StatusConflict, code)
+	assert.Equal(t, "task not found", got.Error)
+}
@@ -0,0 +1,288 @@
+// Package adminapi implements the local HTTP API for node operators. It is
+// served on a Unix socket and, optionally, on a TLS protected TCP address,
+// and works on the same repositories, command factory and task manager as
+// the gRPC client.
+package adminapi
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/gameap/daemon/internal/app/config"
+	"github.com/gameap/daemon/internal/app/contracts"
+	"github.com/gameap/daemon/internal/app/domain"
+	grpcclient "github.com/gameap/daemon/internal/app/grpc"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	readHeaderTimeout = 10 * time.Second
+	shutdownTimeout   = 10 * time.Second
+	staleSocketProbe  = time.Second
+)
+
+var (
+	ErrSocketInUse       = errors.New("admin API socket is in use by another process")
+	ErrClientNotAllowed  = errors.New("client certificate is not allowed")
+	ErrClientCANotLoaded = errors.New("failed to add admin API client CA certificate to pool")
+)
+
+type ServerCommandLoader interface {
+	LoadServerCommand(cmd domain.ServerCommand, server *domain.Server) contracts.GameServerCommand
+}
+
+type TaskQueue interface {
+	domain.GDTaskStatsReader
+
+	WorkingTasks() ([]int, []*domain.GDTask)
+	CancelTask(taskID int) error
+}
+
+type ConnectionState interface {
+	IsConnected() bool
+}
+
+// ServerLocker keeps server commands apart from the panel tasks and the
+// scheduled tasks of the same server.
+type ServerLocker interface {
+	Lock(ctx context.Context, serverID int, exclusive bool) (func(), error)
+}
+
+type Server struct {
+	cfg *config.Config
+
+	serverRepo     domain.ServerRepository
+	commandFactory ServerCommandLoader
+	taskQueue      TaskQueue
+	processManager contracts.ProcessManager
+	connection     ConnectionState
+	metrics        grpcclient.MetricsProvider
+	backups        BackupManager
+	serverLocks    ServerLocker
+}
+
+func NewServer(
+	cfg *config.Config,
+	serverRepo domain.ServerRepository,
+	commandFactory ServerCommandLoader,
+	taskQueue TaskQueue,
+	processManager contracts.ProcessManager,
+	connection ConnectionState,
+) *Server {
+	return &Server{
+		cfg:            cfg,
+		serverRepo:     serverRepo,
+		commandFactory: commandFactory,
+		taskQueue:      taskQueue,
+		processManager: processManager,
+		connection:     connection,
+	}
+}
+
+// SetMetricsProvider enables the metrics endpoint. It is left unset when
+// metrics collection is disabled.
+func (s *Server) SetMetricsProvider(provider grpcclient.MetricsProvider) {
+	s.metrics = provider
+}
+
+func (s *Server) SetBackupManager(manager BackupManager) {
+	s.backups = manager
+}
+
+func (s *Server) SetServerLocks(locks ServerLocker) {
+	s.serverLocks = locks
+}
+
+// Run serves the API until ctx is done.
+func (s *Server) Run(ctx context.Context) error {
+	listeners, err := s.listen(ctx)
+	if err != nil {
+		return err
+	}
+
+	srv := &http.Server{
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: readHeaderTimeout,
+		BaseContext: func(_ net.Listener) context.Context {
+			return ctx
+		},
+	}
+
+	errCh := make(chan error, len(listeners))
+	for _, l := range listeners {
+		log.WithField("address", l.Addr().String()).Info("Admin API listening")
+
+		go func(l net.Listener) {
+			errCh <- srv.Serve(l)
+		}(l)
+	}
+
+	select {
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), shutdownTimeout)
+		defer cancel()
+
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			return errors.Wrap(err, "failed to shutdown admin API")
+		}
+
+		return nil
+	case err := <-errCh:
+		_ = srv.Close()
+
+		if errors.Is(err, http.ErrServerClosed) {
+			return nil
+		}
+
+		return errors.Wrap(err, "admin API stopped")
+	}
+}
+
+func (s *Server) listen(ctx context.Context) ([]net.Listener, error) {
+	listeners := make([]net.Listener, 0, 2)
+
+	closeAll := func() {
+		for _, l := range listeners {
+			_ = l.Close()
+		}
+	}
+
+	if s.cfg.AdminAPI.Socket != "" {
+		l, err := listenUnix(ctx, s.cfg.AdminAPI.Socket)
+		if err != nil {
+			return nil, err
+		}
+		listeners = append(listeners, l)
+	}
+
+	if s.cfg.AdminAPI.Listen != "" {
+		tlsConfig, err := s.serverTLSConfig()
+		if err != nil {
+			closeAll()
+			return nil, err
+		}
+
+		lc := net.ListenConfig{}
+		l, err := lc.Listen(ctx, "tcp", s.cfg.AdminAPI.Listen)
+		if err != nil {
+			closeAll()
+			return nil, errors.Wrapf(err, "failed to listen on %s", s.cfg.AdminAPI.Listen)
+		}
+		listeners = append(listeners, tls.NewListener(l, tlsConfig))
+	}
+
+	return listeners, nil
+}
+
+// serverTLSConfig serves the daemon certificate and accepts only operator
+// clients: certificates signed by the operators CA or by the panel CA with an
+// allowed name. The panel CA alone would let in every node of the panel.
+func (s *Server) serverTLSConfig() (*tls.Config, error) {
+	cfg := s.cfg.AdminAPI
+	if cfg.ClientCACertificateFile == "" && len(cfg.ClientNames) == 0 {
+		return nil, config.ErrAdminAPIClientNotPinned
+	}
+
+	tlsConfig, err := grpcclient.NewTLSConfig(s.cfg)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to load admin API TLS config")
+	}
+
+	tlsConfig.ClientCAs = tlsConfig.RootCAs
+	tlsConfig.RootCAs = nil
+	tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+
+	if cfg.ClientCACertificateFile != "" {
+		caCert, err := os.ReadFile(cfg.ClientCACertificateFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read admin API client CA certificate")
+		}
+
+		tlsConfig.ClientCAs = x509.NewCertPool()
+		if !tlsConfig.ClientCAs.AppendCertsFromPEM(caCert) {
+			return nil, ErrClientCANotLoaded
+		}
+	}
+
+	if len(cfg.ClientNames) > 0 {
+		tlsConfig.VerifyConnection = func(state tls.ConnectionState) error {
+			if len(state.PeerCertificates) == 0 {
+				return ErrClientNotAllowed
+			}
+
+			return verifyClientName(state.PeerCertificates[0], cfg.ClientNames)
+		}
+	}
+
+	return tlsConfig, nil
+}
+
+// verifyClientName accepts the certificate when its common name or one of
+// its DNS names is allowed.
+func verifyClientName(cert *x509.Certificate, allowed []string) error {
+	if slices.Contains(allowed, cert.Subject.CommonName) {
+		return nil
+	}
+
+	for _, name := range cert.DNSNames {
+		if slices.Contains(allowed, name) {
+			return nil
+		}
+	}
+
+	return errors.Wrapf(ErrClientNotAllowed, "common name %q", cert.Subject.CommonName)
+}
+
+// listenUnix creates the socket, replacing a socket file left behind by a
+// daemon that was not shut down cleanly. A socket that still accepts
+// connections belongs to a running daemon and is not touched.
+func listenUnix(ctx context.Context, path string) (net.Listener, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, errors.Wrap(err, "failed to create admin API socket directory")
+	}
+
+	lc := net.ListenConfig{}
+
+	l, err := lc.Listen(ctx, "unix", path)
+	if err != nil && isSocket(path) {
+		dialer := net.Dialer{Timeout: staleSocketProbe}
+		conn, dialErr := dialer.DialContext(ctx, "unix", path)
+		if dialErr == nil {
+			_ = conn.Close()
+			return nil, errors.Wrapf(ErrSocketInUse, "socket %s", path)
+		}
+
+		if err := os.Remove(path); err != nil {
+			return nil, errors.Wrap(err, "failed to remove stale admin API socket")
+		}
+
+		l, err = lc.Listen(ctx, "unix", path)
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to listen on %s", path)
+	}
+
+	if err := os.Chmod(path, 0o600); err != nil {
+		_ = l.Close()
+		return nil, errors.Wrap(err, "failed to set admin API socket permissions")
+	}
+
+	return l, nil
+}
+
+func isSocket(path string) bool {
+	fi, err := os.Lstat(path)
+	if err != nil {
+		return false
+	}
+
+	return fi.Mode()&os.ModeSocket != 0
+}
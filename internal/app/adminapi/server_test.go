@@ -0,0 +1,79 @@
+package adminapi
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"testing"
+
+	"github.com/gameap/daemon/internal/app/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyClientName(t *testing.T) {
+	tests := []struct {
+		name    string
+		cert    *x509.Certificate
+		wantErr bool
+	}{
+		{
+			name: "common_name",
+			cert: &x509.Certificate{Subject: pkix.Name{CommonName: "operator"}},
+		},
+		{
+			name: "dns_name",
+			cert: &x509.Certificate{Subject: pkix.Name{CommonName: "node-2"}, DNSNames: []string{"operator"}},
+		},
+		{
+			name:    "other_node",
+			cert:    &x509.Certificate{Subject: pkix.Name{CommonName: "node-2"}, DNSNames: []string{"node-2.example.com"}},
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := verifyClientName(test.cert, []string{"operator"})
+
+			if test.wantErr {
+				require.ErrorIs(t, err, ErrClientNotAllowed)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestServer_serverTLSConfig(t *testing.T) {
+	cfg := givenTLSConfig()
+	cfg.AdminAPI.ClientCACertificateFile = "../../../config/certs/rootca.crt"
+	cfg.AdminAPI.ClientNames = []string{"operator"}
+
+	tlsConfig, err := NewServer(cfg, nil, nil, nil, nil, nil).serverTLSConfig()
+
+	require.NoError(t, err)
+	assert.Equal(t, tls.RequireAndVerifyClientCert, tlsConfig.ClientAuth)
+	assert.NotNil(t, tlsConfig.ClientCAs)
+	assert.Nil(t, tlsConfig.RootCAs)
+	require.NotNil(t, tlsConfig.VerifyConnection)
+	assert.ErrorIs(t, tlsConfig.VerifyConnection(tls.ConnectionState{
+		PeerCertificates: []*x509.Certificate{{Subject: pkix.Name{CommonName: "node-2"}}},
+	}), ErrClientNotAllowed)
+}
+
+func TestServer_serverTLSConfig_NotPinned(t *testing.T) {
+	_, err := NewServer(givenTLSConfig(), nil, nil, nil, nil, nil).serverTLSConfig()
+
+	require.ErrorIs(t, err, config.ErrAdminAPIClientNotPinned)
+}
+
+func givenTLSConfig() *config.Config {
+	cfg := config.NewConfig()
+	cfg.CACertificateFile = "../../../config/certs/rootca.crt"
+	cfg.CertificateChainFile = "../../../config/certs/server.crt"
+	cfg.PrivateKeyFile = "../../../config/certs/server.key"
+	cfg.AdminAPI.Listen = "127.0.0.1:0"
+
+	return cfg
+}
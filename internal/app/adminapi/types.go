@@ -0,0 +1,62 @@
+package adminapi
+
+import "time"
+
+type StatusResponse struct {
+	Version       string     `json:"version"`
+	UptimeSeconds int64      `json:"uptime_seconds"`
+	Connected     bool       `json:"connected"`
+	Servers       int        `json:"servers"`
+	Tasks         TaskCounts `json:"tasks"`
+}
+
+type TaskCounts struct {
+	Working int `json:"working"`
+	Waiting int `json:"waiting"`
+}
+
+type TasksResponse struct {
+	TaskCounts
+
+	InProgress []TaskView `json:"in_progress"`
+}
+
+type TaskView struct {
+	ID       int    `json:"id"`
+	Task     string `json:"task"`
+	Status   string `json:"status"`
+	ServerID int    `json:"server_id,omitempty"`
+}
+
+type ServerView struct {
+	ID               int        `json:"id"`
+	UUID             string     `json:"uuid"`
+	Name             string     `json:"name"`
+	Enabled          bool       `json:"enabled"`
+	Blocked          bool       `json:"blocked"`
+	Installed        bool       `json:"installed"`
+	Active           bool       `json:"active"`
+	State            string     `json:"state"`
+	AutoStart        bool       `json:"autostart"`
+	CrashLooping     bool       `json:"crash_looping"`
+	IP               string     `json:"ip"`
+	ConnectPort      int        `json:"connect_port"`
+	LastStatusCheck  *time.Time `json:"last_status_check,omitempty"`
+	LastTaskComplete *time.Time `json:"last_task_completed_at,omitempty"`
+}
+
+type CommandResponse struct {
+	Success bool       `json:"success"`
+	Output  string     `json:"output"`
+	Server  ServerView `json:"server"`
+}
+
+type BackupView struct {
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+	Size      int64     `json:"size"`
+}
+
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
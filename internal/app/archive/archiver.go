@@ -59,6 +59,10 @@ type Result struct {
 // Totals are intentionally not reported: the proto allows 0 = unknown.
 type ProgressFunc func(filesProcessed, bytesProcessed int64, currentEntry string)
 
+// SkipFunc decides whether an entry is left out of a new archive. name is the
+// slash-separated entry name relative to base_path.
+type SkipFunc func(name string) bool
+
 // accumulator tracks running counters, enforces the limits and reports
 // progress for both create and extract.
 type accumulator struct {
@@ -6,6 +6,7 @@ import (
 	"io/fs"
 	"os"
 	"path"
+	"path/filepath"
 
 	"github.com/pkg/errors"
 
@@ -49,17 +50,7 @@ func Create(ctx context.Context, workDir string, p *pb.CreateArchiveParams, prog
 		return nil, err
 	}
 
-	// The proto resolves an unset create format from the target file extension.
-	format := p.GetFormat()
-	if format == pb.ArchiveFormat_ARCHIVE_FORMAT_UNSPECIFIED {
-		if format = formatFromExtension(archiveRel); format == pb.ArchiveFormat_ARCHIVE_FORMAT_UNSPECIFIED {
-			return nil, errors.Errorf(
-				"archive format is unspecified and %q has no known extension", p.GetArchivePath(),
-			)
-		}
-	}
-
-	class, err := classifyForCreate(format)
+	format, class, err := createFormat(p.GetFormat(), archiveRel, p.GetArchivePath())
 	if err != nil {
 		return nil, err
 	}
@@ -91,7 +82,7 @@ func Create(ctx context.Context, workDir string, p *pb.CreateArchiveParams, prog
 
 	acc := newAccumulator(p.GetMaxTotalBytes(), p.GetMaxFiles(), progress)
 
-	createErr := createInto(ctx, root, archiveFile, baseRel, p, format, class, acc)
+	createErr := createInto(ctx, root, archiveFile, baseRel, p, format, class, acc, nil)
 
 	closeErr := archiveFile.Close()
 	if createErr != nil {
@@ -138,6 +129,82 @@ func Create(ctx context.Context, workDir string, p *pb.CreateArchiveParams, prog
 	}, nil
 }
 
+// CreateInto is Create for callers that keep the archive outside of the work
+// directory: the sources are packed into archiveFile, which stays open and
+// owned by the caller. archive_path, mode and owner of the params are not
+// used, an unspecified format is resolved from the name of archiveFile.
+// Entries for which skip returns true are left out, for a directory together
+// with everything below it; skip may be nil.
+func CreateInto(
+	ctx context.Context,
+	workDir string,
+	archiveFile *os.File,
+	p *pb.CreateArchiveParams,
+	skip SkipFunc,
+	progress ProgressFunc,
+) (*Result, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, errors.Wrap(err, "create archive canceled")
+	}
+
+	if len(p.GetSources()) == 0 {
+		return nil, errors.New("no sources given")
+	}
+
+	root, err := os.OpenRoot(workDir)
+	if err != nil {
+		return nil, errors.Wrap(err, "work directory unavailable")
+	}
+	defer root.Close()
+
+	format, class, err := createFormat(p.GetFormat(), filepath.ToSlash(archiveFile.Name()), archiveFile.Name())
+	if err != nil {
+		return nil, err
+	}
+
+	baseRel, err := fsutil.RootRel(p.GetBasePath())
+	if err != nil {
+		return nil, err
+	}
+
+	acc := newAccumulator(p.GetMaxTotalBytes(), p.GetMaxFiles(), progress)
+
+	if err := createInto(ctx, root, archiveFile, baseRel, p, format, class, acc, skip); err != nil {
+		return nil, err
+	}
+
+	info, err := archiveFile.Stat()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to stat archive file")
+	}
+
+	return &Result{
+		FilesProcessed: acc.files,
+		BytesProcessed: acc.bytes,
+		ArchiveSize:    info.Size(),
+		Format:         format,
+	}, nil
+}
+
+// createFormat resolves the format of a new archive. The proto resolves an
+// unset create format from the target file extension.
+func createFormat(format pb.ArchiveFormat, name, displayName string) (pb.ArchiveFormat, formatClass, error) {
+	if format == pb.ArchiveFormat_ARCHIVE_FORMAT_UNSPECIFIED {
+		if format = FormatFromExtension(name); format == pb.ArchiveFormat_ARCHIVE_FORMAT_UNSPECIFIED {
+			return format, 0, errors.Errorf(
+				"archive format is unspecified and %q has no known extension", displayName,
+			)
+		}
+	}
+
+	class, err := classifyForCreate(format)
+	if err != nil {
+		return format, 0, err
+	}
+
+	return format, class, nil
+}
+
 func createInto(
 	ctx context.Context,
 	root *os.Root,
@@ -147,6 +214,7 @@ func createInto(
 	format pb.ArchiveFormat,
 	class formatClass,
 	acc *accumulator,
+	skip SkipFunc,
 ) error {
 	archiveInfo, err := archiveFile.Stat()
 	if err != nil {
@@ -157,6 +225,7 @@ func createInto(
 		follow:     p.GetFollowSymlinks(),
 		maxEntries: acc.maxFiles,
 		archive:    archiveInfo,
+		skip:       skip,
 	})
 	if err != nil {
 		return err
@@ -189,6 +258,8 @@ type walkLimits struct {
 	// itself. Comparing identity rather than the path name also covers reaching
 	// it through a symlink, where the path differs.
 	archive os.FileInfo
+	// skip leaves matching entries out of the archive.
+	skip SkipFunc
 }
 
 // sourceWalker expands the request sources (relative to base_path) into a flat
@@ -260,6 +331,10 @@ func (w *sourceWalker) walk(rel, name string, symlinkDepth int) error {
 		return errors.Wrap(err, "create archive canceled")
 	}
 
+	if name != "." && w.limits.skip != nil && w.limits.skip(name) {
+		return nil
+	}
+
 	info, err := w.root.Lstat(rel)
 	if err != nil {
 		return errors.Wrapf(err, "failed to stat source %q", rel)
@@ -77,6 +77,45 @@ func TestCreateExtractRoundtrip(t *testing.T) {
 	}
 }
 
+func TestCreateIntoExtractFromOutsideWorkDir(t *testing.T) {
+	workDir := t.TempDir()
+	writeTree(t, workDir, map[string]string{
+		"world/level.dat":      "level",
+		"world/region/r.0.mca": "region",
+		"logs/latest.log":      "log",
+		"server.properties":    "motd=hi",
+	})
+
+	archiveFile, err := os.Create(filepath.Join(t.TempDir(), "backup.tar.gz"))
+	require.NoError(t, err)
+	defer archiveFile.Close()
+
+	createRes, err := CreateInto(context.Background(), workDir, archiveFile, &pb.CreateArchiveParams{
+		BasePath: ".",
+		Sources:  []string{"."},
+	}, func(name string) bool {
+		return name == "logs"
+	}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, pb.ArchiveFormat_ARCHIVE_FORMAT_TAR_GZ, createRes.Format)
+	assert.Positive(t, createRes.ArchiveSize)
+
+	_, err = archiveFile.Seek(0, 0)
+	require.NoError(t, err)
+
+	_, err = ExtractFrom(context.Background(), workDir, archiveFile, &pb.ExtractArchiveParams{
+		Destination:       "restored",
+		CreateDestination: true,
+	}, nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]string{
+		"world/level.dat":      "level",
+		"world/region/r.0.mca": "region",
+		"server.properties":    "motd=hi",
+	}, readTree(t, filepath.Join(workDir, "restored")))
+}
+
 func TestCreateExtractRoundtripBasePath(t *testing.T) {
 	workDir := t.TempDir()
 	writeTree(t, workDir, map[string]string{
@@ -46,9 +46,9 @@ var extensionFormats = []struct {
 	{".zst", pb.ArchiveFormat_ARCHIVE_FORMAT_ZSTD},
 }
 
-// formatFromExtension resolves a format from a file name, returning
+// FormatFromExtension resolves a format from a file name, returning
 // ARCHIVE_FORMAT_UNSPECIFIED when no known suffix matches.
-func formatFromExtension(name string) pb.ArchiveFormat {
+func FormatFromExtension(name string) pb.ArchiveFormat {
 	lower := strings.ToLower(path.Base(name))
 
 	for _, e := range extensionFormats {
@@ -129,7 +129,7 @@ func detectFormat(f *os.File, name string) (pb.ArchiveFormat, error) {
 		return compressedFormat(f, comp), nil
 	}
 
-	if format := formatFromExtension(name); format != pb.ArchiveFormat_ARCHIVE_FORMAT_UNSPECIFIED {
+	if format := FormatFromExtension(name); format != pb.ArchiveFormat_ARCHIVE_FORMAT_UNSPECIFIED {
 		return format, nil
 	}
 
@@ -5,6 +5,7 @@ import (
 	"io"
 	"os"
 	"path"
+	"path/filepath"
 	"strings"
 
 	"github.com/pkg/errors"
@@ -476,16 +477,61 @@ func Extract(ctx context.Context, workDir string, p *pb.ExtractArchiveParams, pr
 	}
 	defer archiveFile.Close()
 
+	return extractFile(ctx, root, archiveFile, archiveRel, destRel, p, progress)
+}
+
+// ExtractFrom is Extract for archives kept outside of the work directory: the
+// already open archiveFile is unpacked into destination, archive_path of the
+// params is not used. The confinement rules for the extracted entries are the
+// same as for Extract.
+func ExtractFrom(
+	ctx context.Context,
+	workDir string,
+	archiveFile *os.File,
+	p *pb.ExtractArchiveParams,
+	progress ProgressFunc,
+) (*Result, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, errors.Wrap(err, "extract archive canceled")
+	}
+
+	root, err := os.OpenRoot(workDir)
+	if err != nil {
+		return nil, errors.Wrap(err, "work directory unavailable")
+	}
+	defer root.Close()
+
+	destRel, err := fsutil.RootRel(p.GetDestination())
+	if err != nil {
+		return nil, err
+	}
+
+	if err := prepareDestination(root, destRel, p); err != nil {
+		return nil, err
+	}
+
+	return extractFile(ctx, root, archiveFile, filepath.ToSlash(archiveFile.Name()), destRel, p, progress)
+}
+
+func extractFile(
+	ctx context.Context,
+	root *os.Root,
+	archiveFile *os.File,
+	archiveName string,
+	destRel string,
+	p *pb.ExtractArchiveParams,
+	progress ProgressFunc,
+) (*Result, error) {
 	archiveInfo, err := archiveFile.Stat()
 	if err != nil {
-		return nil, errors.Wrapf(err, "failed to stat archive %q", p.GetArchivePath())
+		return nil, errors.Wrapf(err, "failed to stat archive %q", archiveName)
 	}
 
 	// The proto lets the request leave the format unset and expects the daemon
 	// to work it out from the content or the file name.
 	format := p.GetFormat()
 	if format == pb.ArchiveFormat_ARCHIVE_FORMAT_UNSPECIFIED {
-		if format, err = detectFormat(archiveFile, archiveRel); err != nil {
+		if format, err = detectFormat(archiveFile, archiveName); err != nil {
 			return nil, err
 		}
 	}
@@ -505,7 +551,7 @@ func Extract(ctx context.Context, workDir string, p *pb.ExtractArchiveParams, pr
 		acc:      newAccumulator(p.GetMaxTotalBytes(), p.GetMaxFiles(), progress),
 	}
 
-	extractErr := extractEntries(ctx, archiveFile, archiveRel, class, format, s)
+	extractErr := extractEntries(ctx, archiveFile, archiveName, class, format, s)
 
 	// Symlink confinement is only settled once the archive can no longer move
 	// anything, so it is decided here — including for a run that failed, which
@@ -0,0 +1,101 @@
+package backups
+
+import (
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+var ErrNothingToBackup = errors.New("include patterns match nothing in the server directory")
+
+// sources expands the include patterns into the archive sources, relative to
+// the server directory. Sources nested in another source are dropped, they
+// are archived with it anyway.
+func sources(fsys fs.FS, include []string) ([]string, error) {
+	if len(include) == 0 {
+		return []string{"."}, nil
+	}
+
+	var matches []string
+	for _, pattern := range include {
+		found, err := fs.Glob(fsys, cleanPattern(pattern))
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid include pattern %q", pattern)
+		}
+
+		matches = append(matches, found...)
+	}
+
+	if len(matches) == 0 {
+		return nil, ErrNothingToBackup
+	}
+
+	// Parents sort before their children.
+	sort.Strings(matches)
+
+	kept := make(map[string]bool, len(matches))
+	result := make([]string, 0, len(matches))
+	for _, m := range matches {
+		if m == "." {
+			return []string{"."}, nil
+		}
+
+		if covered(kept, m) {
+			continue
+		}
+
+		kept[m] = true
+		result = append(result, m)
+	}
+
+	return result, nil
+}
+
+func covered(kept map[string]bool, name string) bool {
+	for dir := name; dir != "."; dir = path.Dir(dir) {
+		if kept[dir] {
+			return true
+		}
+	}
+
+	return false
+}
+
+// excluder returns a function reporting whether an entry is excluded. A
+// pattern containing a slash is matched against the whole path relative to
+// the server directory, any other pattern against the base name at any
+// depth, as in .gitignore.
+func excluder(exclude []string) func(name string) bool {
+	if len(exclude) == 0 {
+		return nil
+	}
+
+	return func(name string) bool {
+		for _, pattern := range exclude {
+			subject := path.Base(name)
+			if strings.Contains(strings.TrimSuffix(pattern, "/"), "/") {
+				subject = name
+			}
+
+			if ok, _ := path.Match(cleanPattern(pattern), subject); ok {
+				return true
+			}
+		}
+
+		return false
+	}
+}
+
+// cleanPattern makes the pattern relative to the server directory, so a
+// leading slash or ".." cannot point outside of it.
+func cleanPattern(pattern string) string {
+	cleaned := strings.TrimPrefix(path.Clean("/"+pattern), "/")
+	if cleaned == "" {
+		return "."
+	}
+
+	return cleaned
+}
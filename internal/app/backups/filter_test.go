@@ -0,0 +1,80 @@
+package backups
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSources(t *testing.T) {
+	fsys := fstest.MapFS{
+		"world/level.dat":        {},
+		"world/region/r.0.mca":   {},
+		"world_nether/level.dat": {},
+		"world-end/level.dat":    {},
+		"server.properties":      {},
+		"logs/latest.log":        {},
+	}
+
+	tests := []struct {
+		name    string
+		include []string
+		want    []string
+		err     error
+	}{
+		{
+			name: "everything by default",
+			want: []string{"."},
+		},
+		{
+			name:    "globs",
+			include: []string{"world*", "*.properties"},
+			want:    []string{"server.properties", "world", "world-end", "world_nether"},
+		},
+		{
+			name:    "nested sources are dropped",
+			include: []string{"world/region", "world-end", "world"},
+			want:    []string{"world", "world-end"},
+		},
+		{
+			name:    "leading slash and dot-dot stay in the server directory",
+			include: []string{"/logs", "../world_nether"},
+			want:    []string{"logs", "world_nether"},
+		},
+		{
+			name:    "nothing matches",
+			include: []string{"plugins"},
+			err:     ErrNothingToBackup,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := sources(fsys, test.include)
+
+			if test.err != nil {
+				require.ErrorIs(t, err, test.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.want, got)
+		})
+	}
+}
+
+func TestExcluder(t *testing.T) {
+	skip := excluder([]string{"*.log", "/cache", "world/*.tmp", "dynmap/"})
+
+	assert.True(t, skip("latest.log"))
+	assert.True(t, skip("logs/old/debug.log"))
+	assert.True(t, skip("cache"))
+	assert.False(t, skip("plugins/cache"))
+	assert.True(t, skip("world/session.tmp"))
+	assert.False(t, skip("world/region/r.tmp"))
+	assert.True(t, skip("plugins/dynmap"))
+	assert.False(t, skip("server.properties"))
+
+	assert.Nil(t, excluder(nil))
+}
@@ -0,0 +1,27 @@
+package backups
+
+import (
+	"time"
+
+	"github.com/gameap/daemon/internal/app/config"
+)
+
+// expired returns the backups the policy no longer keeps. backups are ordered
+// from the oldest. The newest backup is only removed by the count limit, so a
+// server whose backups stopped is not left with none once they all age out.
+func expired(backups []Backup, policy config.BackupPolicy, now time.Time) []Backup {
+	var result []Backup
+
+	for i, b := range backups {
+		newer := len(backups) - 1 - i
+
+		switch {
+		case policy.KeepLast > 0 && newer >= policy.KeepLast:
+			result = append(result, b)
+		case policy.KeepFor > 0 && newer > 0 && now.Sub(b.CreatedAt) > policy.KeepFor:
+			result = append(result, b)
+		}
+	}
+
+	return result
+}
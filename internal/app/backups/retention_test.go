@@ -0,0 +1,71 @@
+package backups
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gameap/daemon/internal/app/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpired(t *testing.T) {
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	backups := []Backup{
+		{Name: "a", CreatedAt: now.Add(-72 * time.Hour)},
+		{Name: "b", CreatedAt: now.Add(-48 * time.Hour)},
+		{Name: "c", CreatedAt: now.Add(-24 * time.Hour)},
+		{Name: "d", CreatedAt: now.Add(-time.Hour)},
+	}
+
+	names := func(list []Backup) []string {
+		result := make([]string, 0, len(list))
+		for _, b := range list {
+			result = append(result, b.Name)
+		}
+
+		return result
+	}
+
+	tests := []struct {
+		name    string
+		backups []Backup
+		policy  config.BackupPolicy
+		want    []string
+	}{
+		{
+			name:    "keep last",
+			backups: backups,
+			policy:  config.BackupPolicy{KeepLast: 2},
+			want:    []string{"a", "b"},
+		},
+		{
+			name:    "keep for",
+			backups: backups,
+			policy:  config.BackupPolicy{KeepFor: 36 * time.Hour},
+			want:    []string{"a", "b"},
+		},
+		{
+			name:    "both limits",
+			backups: backups,
+			policy:  config.BackupPolicy{KeepLast: 3, KeepFor: 60 * time.Hour},
+			want:    []string{"a"},
+		},
+		{
+			name:    "newest backup is kept when it is too old",
+			backups: backups[:1],
+			policy:  config.BackupPolicy{KeepFor: time.Hour},
+			want:    []string{},
+		},
+		{
+			name:    "no limits",
+			backups: backups,
+			want:    []string{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.want, names(expired(test.backups, test.policy, now)))
+		})
+	}
+}
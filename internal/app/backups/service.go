@@ -0,0 +1,392 @@
+// Package backups makes scheduled and manual backups of game server
+// directories and restores them. Backups are archives in per-server
+// subdirectories of the backup directory, see Store. What is backed up, how
+// often and how long the backups are kept is configured with backup policies.
+package backups
+
+import (
+	"context"
+	"io"
+	"math"
+	"os"
+	"time"
+
+	daemonarchive "github.com/gameap/daemon/internal/app/archive"
+	"github.com/gameap/daemon/internal/app/config"
+	"github.com/gameap/daemon/internal/app/contracts"
+	"github.com/gameap/daemon/internal/app/domain"
+	gameservercommands "github.com/gameap/daemon/internal/app/game_server_commands"
+	"github.com/gameap/daemon/internal/app/serverlock"
+	"github.com/gameap/daemon/pkg/logger"
+	pb "github.com/gameap/gameap/pkg/proto"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// checkInterval is how often the schedule of every server is checked.
+const checkInterval = time.Minute
+
+var (
+	ErrServerNotFound = errors.New("server not found")
+	ErrBusy           = errors.New("the server is busy with another backup, restore or task")
+)
+
+type ServerCommandLoader interface {
+	LoadServerCommand(cmd domain.ServerCommand, server *domain.Server) contracts.GameServerCommand
+}
+
+type InputSender interface {
+	SendInput(ctx context.Context, input string, server *domain.Server, out io.Writer) (domain.Result, error)
+}
+
+// ServerLocker keeps backups and restores apart from the panel tasks, the
+// scheduled tasks and the admin API commands of the same server.
+type ServerLocker interface {
+	TryLock(serverID int, exclusive bool) (func(), bool)
+}
+
+type Service struct {
+	cfg            *config.Config
+	store          *Store
+	serverRepo     domain.ServerRepository
+	commandFactory ServerCommandLoader
+	inputSender    InputSender
+	serverLocks    ServerLocker
+	nowFn          func() time.Time
+}
+
+func NewService(
+	cfg *config.Config,
+	serverRepo domain.ServerRepository,
+	commandFactory ServerCommandLoader,
+	inputSender InputSender,
+) *Service {
+	return &Service{
+		cfg:            cfg,
+		store:          NewStore(cfg.Backups.Path),
+		serverRepo:     serverRepo,
+		commandFactory: commandFactory,
+		inputSender:    inputSender,
+		serverLocks:    serverlock.New(),
+		nowFn:          time.Now,
+	}
+}
+
+// SetServerLocks shares the server locks with the panel tasks, the scheduled
+// tasks and the admin API, so a server is not started or stopped in the
+// middle of its backup or restore.
+func (s *Service) SetServerLocks(locks ServerLocker) {
+	s.serverLocks = locks
+}
+
+// Run makes the scheduled backups until ctx is done. Servers are backed up
+// one at a time to keep the disk load of the node low.
+func (s *Service) Run(ctx context.Context) error {
+	ticker := time.NewTicker(checkInterval)
+	defer ticker.Stop()
+
+	for {
+		s.tick(ctx)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Service) tick(ctx context.Context) {
+	ids, err := s.serverRepo.IDs(ctx)
+	if err != nil {
+		logger.Warn(ctx, errors.WithMessage(err, "failed to get servers for backup"))
+		return
+	}
+
+	for _, id := range ids {
+		if ctx.Err() != nil {
+			return
+		}
+
+		due, err := s.due(ctx, id)
+		if err != nil {
+			logger.WithField(ctx, "gameServerID", id).WithError(err).Warn("Failed to check backup schedule")
+			continue
+		}
+		if !due {
+			continue
+		}
+
+		if _, err := s.Backup(ctx, id); err != nil && !errors.Is(err, ErrBusy) {
+			logger.WithField(ctx, "gameServerID", id).WithError(err).Error("Scheduled backup failed")
+		}
+	}
+}
+
+// due reports whether the scheduled backup of the server should be made. The
+// time of the last backup is taken from the backup directory, so the schedule
+// survives daemon restarts.
+func (s *Service) due(ctx context.Context, serverID int) (bool, error) {
+	policy := s.cfg.Backups.Policy(serverID)
+	if policy.Interval <= 0 {
+		return false, nil
+	}
+
+	server, err := s.serverRepo.FindByID(ctx, serverID)
+	if err != nil || server == nil || server.InstallationStatus() != domain.ServerInstalled {
+		return false, err
+	}
+
+	backups, err := s.store.List(serverID)
+	if err != nil {
+		return false, err
+	}
+	if len(backups) == 0 {
+		return true, nil
+	}
+
+	last := backups[len(backups)-1]
+
+	return !s.nowFn().Before(last.CreatedAt.Add(policy.Interval)), nil
+}
+
+// List returns the backups of the server ordered from the oldest.
+func (s *Service) List(serverID int) ([]Backup, error) {
+	return s.store.List(serverID)
+}
+
+// Backup makes a backup of the server now and removes the backups its policy
+// no longer keeps.
+func (s *Service) Backup(ctx context.Context, serverID int) (Backup, error) {
+	server, release, err := s.acquire(ctx, serverID)
+	if err != nil {
+		return Backup{}, err
+	}
+	defer release()
+
+	policy := s.cfg.Backups.Policy(serverID)
+
+	ctx = logger.WithLogger(ctx, logger.WithFields(ctx, log.Fields{
+		"gameServerID": serverID,
+		"format":       policy.Format,
+	}))
+
+	if err := s.store.RemovePartial(serverID); err != nil {
+		logger.Warn(ctx, errors.WithMessage(err, "failed to remove interrupted backups"))
+	}
+
+	workDir := server.WorkDir(s.cfg)
+
+	srcRoot, err := os.OpenRoot(workDir)
+	if err != nil {
+		return Backup{}, errors.Wrap(err, "server directory unavailable")
+	}
+	srcs, err := sources(srcRoot.FS(), policy.Include)
+	_ = srcRoot.Close()
+	if err != nil {
+		return Backup{}, err
+	}
+
+	logger.Info(ctx, "Backing up server")
+
+	finish, err := s.prepare(ctx, server, policy)
+	if err != nil {
+		return Backup{}, err
+	}
+
+	started := s.nowFn()
+
+	backup, err := s.store.Write(serverID, started, policy.Format, func(f *os.File) error {
+		_, err := daemonarchive.CreateInto(ctx, workDir, f, &pb.CreateArchiveParams{
+			Format:   daemonarchive.FormatFromExtension("backup." + policy.Format),
+			BasePath: ".",
+			Sources:  srcs,
+			// Server directories are not bounded by the limits meant for
+			// panel requests.
+			MaxTotalBytes: math.MaxUint64,
+			MaxFiles:      math.MaxUint32,
+		}, excluder(policy.Exclude), nil)
+
+		return err
+	})
+
+	finish()
+
+	if err != nil {
+		return Backup{}, err
+	}
+
+	logger.WithFields(ctx, log.Fields{
+		"backup":   backup.Name,
+		"size":     backup.Size,
+		"duration": s.nowFn().Sub(started).Round(time.Second).String(),
+	}).Info("Server backup completed")
+
+	s.prune(ctx, serverID, policy)
+
+	return backup, nil
+}
+
+// Restore replaces the server files with the newest backup made not later
+// than at. A running server is stopped for the time of the restore. Files
+// created after the backup was made are kept.
+func (s *Service) Restore(ctx context.Context, serverID int, at time.Time) (Backup, error) {
+	server, release, err := s.acquire(ctx, serverID)
+	if err != nil {
+		return Backup{}, err
+	}
+	defer release()
+
+	backup, err := s.store.Find(serverID, at)
+	if err != nil {
+		return Backup{}, err
+	}
+
+	ctx = logger.WithLogger(ctx, logger.WithFields(ctx, log.Fields{
+		"gameServerID": serverID,
+		"backup":       backup.Name,
+	}))
+
+	f, err := os.Open(backup.Path)
+	if err != nil {
+		return Backup{}, errors.Wrap(err, "failed to open backup")
+	}
+	defer f.Close()
+
+	logger.Info(ctx, "Restoring server from backup")
+
+	wasActive := server.IsActive()
+	if wasActive {
+		if err := s.runCommand(ctx, domain.Stop, server); err != nil {
+			return Backup{}, errors.WithMessage(err, "failed to stop server before restore")
+		}
+	}
+
+	_, err = daemonarchive.ExtractFrom(ctx, server.WorkDir(s.cfg), f, &pb.ExtractArchiveParams{
+		Destination:         ".",
+		ConflictPolicy:      pb.ArchiveConflictPolicy_ARCHIVE_CONFLICT_POLICY_OVERWRITE,
+		PreservePermissions: true,
+		OwnerUser:           server.User(),
+		MaxTotalBytes:       math.MaxUint64,
+		MaxFiles:            math.MaxUint32,
+	}, nil)
+	if err != nil {
+		return Backup{}, errors.WithMessage(err, "failed to extract backup")
+	}
+
+	if wasActive {
+		if err := s.runCommand(ctx, domain.Start, server); err != nil {
+			return backup, errors.WithMessage(err, "server restored, but failed to start")
+		}
+	}
+
+	logger.Info(ctx, "Server restored from backup")
+
+	return backup, nil
+}
+
+// acquire locks the server exclusively until release is called, so nothing
+// else is done on the server from the stop to the start around its backup or
+// restore. A caller that already holds the lock, see serverlock.WithHeld,
+// keeps it.
+func (s *Service) acquire(ctx context.Context, serverID int) (*domain.Server, func(), error) {
+	server, err := s.serverRepo.FindByID(ctx, serverID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if server == nil {
+		return nil, nil, errors.Wrapf(ErrServerNotFound, "server %d", serverID)
+	}
+
+	if serverlock.Held(ctx, serverID) {
+		return server, func() {}, nil
+	}
+
+	unlock, ok := s.serverLocks.TryLock(serverID, true)
+	if !ok {
+		return nil, nil, errors.Wrapf(ErrBusy, "server %d", serverID)
+	}
+
+	return server, unlock, nil
+}
+
+// prepare makes the files of a running server consistent before the backup
+// according to the policy. The returned function undoes it.
+func (s *Service) prepare(ctx context.Context, server *domain.Server, policy config.BackupPolicy) (func(), error) {
+	noop := func() {}
+
+	if !server.IsActive() {
+		return noop, nil
+	}
+
+	switch policy.Prepare {
+	case config.BackupPrepareSave:
+		// A backup of unsaved files is still better than none, so a failed
+		// save does not stop it.
+		_, err := s.inputSender.SendInput(ctx, policy.SaveCommand, server, io.Discard)
+		if err != nil {
+			logger.Warn(ctx, errors.WithMessage(err, "failed to send save command before backup"))
+			return noop, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return noop, errors.Wrap(ctx.Err(), "backup canceled")
+		case <-time.After(policy.SaveWait):
+		}
+
+		return noop, nil
+	case config.BackupPrepareStop:
+		if err := s.runCommand(ctx, domain.Stop, server); err != nil {
+			return noop, errors.WithMessage(err, "failed to stop server before backup")
+		}
+
+		return func() {
+			// The server is started even when the backup is canceled by the
+			// daemon shutdown.
+			startCtx := context.WithoutCancel(ctx)
+			if err := s.runCommand(startCtx, domain.Start, server); err != nil {
+				logger.Error(startCtx, errors.WithMessage(err, "failed to start server after backup"))
+			}
+		}, nil
+	default:
+		return noop, nil
+	}
+}
+
+func (s *Service) runCommand(ctx context.Context, command domain.ServerCommand, server *domain.Server) error {
+	cmd := s.commandFactory.LoadServerCommand(command, server)
+
+	if err := cmd.Execute(ctx, server); err != nil {
+		return err
+	}
+
+	server.NoticeTaskCompleted()
+
+	if err := s.serverRepo.Save(ctx, server); err != nil {
+		logger.Warn(ctx, errors.WithMessage(err, "failed to save server"))
+	}
+
+	if cmd.Result() != gameservercommands.SuccessResult {
+		return errors.Errorf("command failed: %s", cmd.ReadOutput())
+	}
+
+	return nil
+}
+
+func (s *Service) prune(ctx context.Context, serverID int, policy config.BackupPolicy) {
+	backups, err := s.store.List(serverID)
+	if err != nil {
+		logger.Warn(ctx, errors.WithMessage(err, "failed to list backups for retention"))
+		return
+	}
+
+	for _, b := range expired(backups, policy, s.nowFn()) {
+		if err := s.store.Remove(b); err != nil {
+			logger.Warn(ctx, err)
+			continue
+		}
+
+		logger.WithField(ctx, "backup", b.Name).Info("Removed expired backup")
+	}
+}
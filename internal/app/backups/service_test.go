@@ -0,0 +1,338 @@
+package backups
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gameap/daemon/internal/app/config"
+	"github.com/gameap/daemon/internal/app/contracts"
+	"github.com/gameap/daemon/internal/app/domain"
+	gameservercommands "github.com/gameap/daemon/internal/app/game_server_commands"
+	"github.com/gameap/daemon/internal/app/repositories"
+	"github.com/gameap/daemon/internal/app/serverlock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeCommand struct {
+	command   domain.ServerCommand
+	onExecute func()
+}
+
+func (c *fakeCommand) Execute(_ context.Context, server *domain.Server) error {
+	if c.onExecute != nil {
+		c.onExecute()
+	}
+	server.SetStatus(c.command == domain.Start)
+
+	return nil
+}
+
+func (c *fakeCommand) ReadOutput() []byte {
+	return nil
+}
+
+func (c *fakeCommand) Result() int {
+	return gameservercommands.SuccessResult
+}
+
+func (c *fakeCommand) IsComplete() bool {
+	return true
+}
+
+type fakeCommandFactory struct {
+	loaded    []domain.ServerCommand
+	onExecute func()
+}
+
+func (f *fakeCommandFactory) LoadServerCommand(
+	cmd domain.ServerCommand, _ *domain.Server,
+) contracts.GameServerCommand {
+	f.loaded = append(f.loaded, cmd)
+
+	return &fakeCommand{command: cmd, onExecute: f.onExecute}
+}
+
+type fakeInputSender struct {
+	inputs []string
+}
+
+func (f *fakeInputSender) SendInput(
+	_ context.Context, input string, _ *domain.Server, _ io.Writer,
+) (domain.Result, error) {
+	f.inputs = append(f.inputs, input)
+
+	return domain.SuccessResult, nil
+}
+
+type serviceFixture struct {
+	service  *Service
+	server   *domain.Server
+	serverID int
+	workDir  string
+	commands *fakeCommandFactory
+	input    *fakeInputSender
+	now      time.Time
+}
+
+func givenService(t *testing.T, policy config.BackupPolicy) *serviceFixture {
+	t.Helper()
+
+	cfg := config.NewConfig()
+	cfg.WorkPath = t.TempDir()
+	cfg.Backups.Path = filepath.Join(t.TempDir(), "backups")
+	if policy.Format == "" {
+		policy.Format = "tar.gz"
+	}
+	cfg.Backups.Servers = map[int]config.BackupPolicy{7: policy}
+
+	server := givenServer(7)
+	repo := repositories.NewServerRepository()
+	require.NoError(t, repo.Save(context.Background(), server))
+
+	f := &serviceFixture{
+		server:   server,
+		serverID: 7,
+		workDir:  server.WorkDir(cfg),
+		commands: &fakeCommandFactory{},
+		input:    &fakeInputSender{},
+		now:      time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC),
+	}
+
+	f.service = NewService(cfg, repo, f.commands, f.input)
+	f.service.nowFn = func() time.Time {
+		return f.now
+	}
+
+	require.NoError(t, os.MkdirAll(f.workDir, 0o755))
+
+	return f
+}
+
+func givenServer(id int) *domain.Server {
+	return domain.NewServer(
+		id,
+		true,
+		domain.ServerInstalled,
+		false,
+		"test",
+		"9fd2e4d1-4a4e-4d3c-9a2f-5a4b5d8c9e01",
+		"9fd2e4d1",
+		domain.Game{Code: "minecraft", StartCode: "minecraft"},
+		domain.GameMod{ID: 2, Name: "vanilla"},
+		"127.0.0.1",
+		25565,
+		25566,
+		25575,
+		"rconpass",
+		"servers/test",
+		"",
+		"./start.sh",
+		"stop",
+		"",
+		"",
+		false,
+		time.Time{},
+		nil,
+		domain.Settings{"autostart": "1"},
+		time.Time{},
+		0,
+		0,
+	)
+}
+
+func (f *serviceFixture) writeFiles(t *testing.T, files map[string]string) {
+	t.Helper()
+
+	for name, content := range files {
+		p := filepath.Join(f.workDir, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(p), 0o755))
+		require.NoError(t, os.WriteFile(p, []byte(content), 0o644))
+	}
+}
+
+func (f *serviceFixture) readFile(t *testing.T, name string) string {
+	t.Helper()
+
+	content, err := os.ReadFile(filepath.Join(f.workDir, filepath.FromSlash(name)))
+	require.NoError(t, err)
+
+	return string(content)
+}
+
+func TestService_BackupAndRestore(t *testing.T) {
+	f := givenService(t, config.BackupPolicy{
+		Include: []string{"world", "server.properties"},
+		Exclude: []string{"*.lock"},
+	})
+	ctx := context.Background()
+
+	f.writeFiles(t, map[string]string{
+		"world/level.dat":    "day 1",
+		"world/session.lock": "lock",
+		"server.properties":  "motd=day 1",
+		"logs/latest.log":    "log",
+	})
+	first, err := f.service.Backup(ctx, f.serverID)
+	require.NoError(t, err)
+
+	f.now = f.now.Add(time.Hour)
+	f.writeFiles(t, map[string]string{
+		"world/level.dat":   "day 2",
+		"server.properties": "motd=day 2",
+	})
+	second, err := f.service.Backup(ctx, f.serverID)
+	require.NoError(t, err)
+
+	list, err := f.service.List(f.serverID)
+	require.NoError(t, err)
+	assert.Equal(t, []Backup{first, second}, list)
+
+	f.writeFiles(t, map[string]string{
+		"world/level.dat":    "broken",
+		"world/session.lock": "new lock",
+	})
+
+	restored, err := f.service.Restore(ctx, f.serverID, f.now.Add(-time.Minute))
+
+	require.NoError(t, err)
+	assert.Equal(t, first.Name, restored.Name)
+	assert.Equal(t, "day 1", f.readFile(t, "world/level.dat"))
+	assert.Equal(t, "motd=day 1", f.readFile(t, "server.properties"))
+	assert.Equal(t, "new lock", f.readFile(t, "world/session.lock"))
+	assert.Empty(t, f.commands.loaded, "stopped server must not be started or stopped")
+}
+
+func TestService_Backup_StopsRunningServer(t *testing.T) {
+	f := givenService(t, config.BackupPolicy{Prepare: config.BackupPrepareStop})
+	f.server.SetStatus(true)
+	f.writeFiles(t, map[string]string{"server.properties": "motd"})
+
+	_, err := f.service.Backup(context.Background(), f.serverID)
+
+	require.NoError(t, err)
+	assert.Equal(t, []domain.ServerCommand{domain.Stop, domain.Start}, f.commands.loaded)
+	assert.True(t, f.server.IsActive())
+}
+
+func TestService_Backup_SavesRunningServer(t *testing.T) {
+	f := givenService(t, config.BackupPolicy{
+		Prepare:     config.BackupPrepareSave,
+		SaveCommand: "save-all flush",
+		SaveWait:    time.Millisecond,
+	})
+	f.server.SetStatus(true)
+	f.writeFiles(t, map[string]string{"server.properties": "motd"})
+
+	_, err := f.service.Backup(context.Background(), f.serverID)
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"save-all flush"}, f.input.inputs)
+	assert.Empty(t, f.commands.loaded)
+}
+
+func TestService_Backup_RemovesExpiredBackups(t *testing.T) {
+	f := givenService(t, config.BackupPolicy{KeepLast: 2})
+	f.writeFiles(t, map[string]string{"server.properties": "motd"})
+
+	for range 3 {
+		_, err := f.service.Backup(context.Background(), f.serverID)
+		require.NoError(t, err)
+		f.now = f.now.Add(time.Hour)
+	}
+
+	list, err := f.service.List(f.serverID)
+	require.NoError(t, err)
+	require.Len(t, list, 2)
+	assert.Equal(t, time.Date(2026, 10, 18, 13, 0, 0, 0, time.UTC), list[0].CreatedAt)
+}
+
+func TestService_Due(t *testing.T) {
+	f := givenService(t, config.BackupPolicy{Interval: time.Hour})
+	f.writeFiles(t, map[string]string{"server.properties": "motd"})
+	ctx := context.Background()
+
+	due, err := f.service.due(ctx, f.serverID)
+	require.NoError(t, err)
+	assert.True(t, due, "a server without backups is due")
+
+	_, err = f.service.Backup(ctx, f.serverID)
+	require.NoError(t, err)
+
+	f.now = f.now.Add(30 * time.Minute)
+	due, err = f.service.due(ctx, f.serverID)
+	require.NoError(t, err)
+	assert.False(t, due)
+
+	f.now = f.now.Add(30 * time.Minute)
+	due, err = f.service.due(ctx, f.serverID)
+	require.NoError(t, err)
+	assert.True(t, due)
+
+	due, err = f.service.due(ctx, 100)
+	require.NoError(t, err)
+	assert.False(t, due, "a server without a scheduled policy is never due")
+}
+
+func TestService_Busy(t *testing.T) {
+	f := givenService(t, config.BackupPolicy{})
+	_, release, err := f.service.acquire(context.Background(), f.serverID)
+	require.NoError(t, err)
+
+	_, err = f.service.Backup(context.Background(), f.serverID)
+	require.ErrorIs(t, err, ErrBusy)
+
+	release()
+	_, err = f.service.Restore(context.Background(), f.serverID, f.now)
+	require.ErrorIs(t, err, ErrNoBackups)
+}
+
+func TestService_SharedServerLocks(t *testing.T) {
+	f := givenService(t, config.BackupPolicy{})
+	locks := serverlock.New()
+	f.service.SetServerLocks(locks)
+
+	unlock, ok := locks.TryLock(f.serverID, false)
+	require.True(t, ok)
+
+	_, err := f.service.Backup(context.Background(), f.serverID)
+	require.ErrorIs(t, err, ErrBusy, "a backup waits for the other work on the server")
+	_, err = f.service.Restore(context.Background(), f.serverID, f.now)
+	require.ErrorIs(t, err, ErrBusy)
+
+	unlock()
+
+	unlock, ok = locks.TryLock(f.serverID, true)
+	require.True(t, ok)
+	defer unlock()
+
+	// A pipeline backs up the server under its own lock.
+	ctx := serverlock.WithHeld(context.Background(), f.serverID)
+	_, err = f.service.Backup(ctx, f.serverID)
+	require.NoError(t, err)
+}
+
+func TestService_BackupHoldsServerLock(t *testing.T) {
+	f := givenService(t, config.BackupPolicy{Prepare: config.BackupPrepareStop})
+	locks := serverlock.New()
+	f.service.SetServerLocks(locks)
+	f.server.SetStatus(true)
+
+	var lockedDuringStop bool
+	f.commands.onExecute = func() {
+		_, ok := locks.TryLock(f.serverID, false)
+		lockedDuringStop = !ok
+	}
+
+	_, err := f.service.Backup(context.Background(), f.serverID)
+	require.NoError(t, err)
+	assert.True(t, lockedDuringStop)
+
+	unlock, ok := locks.TryLock(f.serverID, true)
+	require.True(t, ok, "the lock is released after the backup")
+	unlock()
+}
@@ -0,0 +1,213 @@
+package backups
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gameap/daemon/internal/app/fsutil"
+	"github.com/pkg/errors"
+)
+
+// nameTimeLayout is the UTC creation time a backup file name starts with,
+// e.g. 20261018T120000Z.tar.gz. The time of a backup is taken from its name,
+// so it survives copying the backup directory around.
+const nameTimeLayout = "20060102T150405Z"
+
+// partialPrefix marks the temporary files of backups still being written.
+const partialPrefix = "."
+
+var (
+	ErrNoBackups      = errors.New("server has no backups")
+	ErrBackupNotFound = errors.New("backup not found")
+)
+
+// Backup is one archive in the backup directory of a server.
+type Backup struct {
+	ServerID  int
+	Name      string
+	Path      string
+	CreatedAt time.Time
+	Size      int64
+}
+
+// Store keeps backups in per-server subdirectories of the backup directory.
+type Store struct {
+	path string
+}
+
+func NewStore(path string) *Store {
+	return &Store{path: path}
+}
+
+func (s *Store) serverDir(serverID int) string {
+	return filepath.Join(s.path, strconv.Itoa(serverID))
+}
+
+// List returns the backups of the server ordered from the oldest.
+func (s *Store) List(serverID int) ([]Backup, error) {
+	dir := s.serverDir(serverID)
+
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read backup directory %s", dir)
+	}
+
+	backups := make([]Backup, 0, len(entries))
+	for _, entry := range entries {
+		createdAt, ok := parseName(entry.Name())
+		if !ok || !entry.Type().IsRegular() {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+
+		backups = append(backups, Backup{
+			ServerID:  serverID,
+			Name:      entry.Name(),
+			Path:      filepath.Join(dir, entry.Name()),
+			CreatedAt: createdAt,
+			Size:      info.Size(),
+		})
+	}
+
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].CreatedAt.Before(backups[j].CreatedAt)
+	})
+
+	return backups, nil
+}
+
+// Find returns the newest backup made not later than at.
+func (s *Store) Find(serverID int, at time.Time) (Backup, error) {
+	backups, err := s.List(serverID)
+	if err != nil {
+		return Backup{}, err
+	}
+	if len(backups) == 0 {
+		return Backup{}, ErrNoBackups
+	}
+
+	for i := len(backups) - 1; i >= 0; i-- {
+		if !backups[i].CreatedAt.After(at) {
+			return backups[i], nil
+		}
+	}
+
+	return Backup{}, errors.Wrapf(
+		ErrBackupNotFound, "the oldest backup was made at %s", backups[0].CreatedAt.Format(time.RFC3339),
+	)
+}
+
+// Get returns the backup with the given file name.
+func (s *Store) Get(serverID int, name string) (Backup, error) {
+	backups, err := s.List(serverID)
+	if err != nil {
+		return Backup{}, err
+	}
+
+	for _, b := range backups {
+		if b.Name == name {
+			return b, nil
+		}
+	}
+
+	return Backup{}, errors.Wrapf(ErrBackupNotFound, "no backup named %q", name)
+}
+
+// Write creates a backup made at createdAt, its archive is written by write.
+// The write is atomic, a crash never leaves a truncated archive that looks
+// like a complete backup.
+func (s *Store) Write(serverID int, createdAt time.Time, ext string, write func(f *os.File) error) (Backup, error) {
+	dir := s.serverDir(serverID)
+
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return Backup{}, errors.Wrapf(err, "failed to create backup directory %s", dir)
+	}
+
+	name := createdAt.UTC().Format(nameTimeLayout) + "." + ext
+	target := filepath.Join(dir, name)
+
+	if _, err := os.Lstat(target); err == nil {
+		return Backup{}, errors.Errorf("backup %s already exists", target)
+	}
+
+	if err := fsutil.WriteFileAtomicFunc(target, 0o600, write); err != nil {
+		return Backup{}, errors.WithMessagef(err, "failed to write backup %s", target)
+	}
+
+	info, err := os.Stat(target)
+	if err != nil {
+		return Backup{}, errors.Wrap(err, "failed to stat backup file")
+	}
+
+	return Backup{
+		ServerID:  serverID,
+		Name:      name,
+		Path:      target,
+		CreatedAt: createdAt.UTC().Truncate(time.Second),
+		Size:      info.Size(),
+	}, nil
+}
+
+// RemovePartial removes files left by backups of the server that were
+// interrupted. It must not run while a backup of the server is written.
+func (s *Store) RemovePartial(serverID int) error {
+	dir := s.serverDir(serverID)
+
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return errors.Wrapf(err, "failed to read backup directory %s", dir)
+	}
+
+	for _, entry := range entries {
+		if !strings.HasPrefix(entry.Name(), partialPrefix) || !entry.Type().IsRegular() {
+			continue
+		}
+
+		if err := os.Remove(filepath.Join(dir, entry.Name())); err != nil {
+			return errors.Wrap(err, "failed to remove partial backup")
+		}
+	}
+
+	return nil
+}
+
+// Remove deletes the backup file.
+func (s *Store) Remove(b Backup) error {
+	if err := os.Remove(b.Path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return errors.Wrapf(err, "failed to remove backup %s", b.Path)
+	}
+
+	return nil
+}
+
+func parseName(name string) (time.Time, bool) {
+	if strings.HasPrefix(name, partialPrefix) {
+		return time.Time{}, false
+	}
+
+	stamp, _, ok := strings.Cut(name, ".")
+	if !ok {
+		return time.Time{}, false
+	}
+
+	t, err := time.Parse(nameTimeLayout, stamp)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	return t, true
+}
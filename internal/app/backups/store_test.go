@@ -0,0 +1,110 @@
+package backups
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func givenBackups(t *testing.T, store *Store, serverID int, times ...time.Time) {
+	t.Helper()
+
+	for _, at := range times {
+		_, err := store.Write(serverID, at, "tar", func(f *os.File) error {
+			_, err := f.WriteString(at.String())
+			return err
+		})
+		require.NoError(t, err)
+	}
+}
+
+func TestStore_WriteAndList(t *testing.T) {
+	store := NewStore(t.TempDir())
+	first := time.Date(2026, 10, 18, 10, 0, 0, 0, time.UTC)
+	second := first.Add(time.Hour)
+
+	givenBackups(t, store, 7, second, first)
+
+	backups, err := store.List(7)
+
+	require.NoError(t, err)
+	require.Len(t, backups, 2)
+	assert.Equal(t, "20261018T100000Z.tar", backups[0].Name)
+	assert.Equal(t, first, backups[0].CreatedAt)
+	assert.Equal(t, second, backups[1].CreatedAt)
+	assert.Positive(t, backups[1].Size)
+}
+
+func TestStore_List_NoBackups(t *testing.T) {
+	store := NewStore(t.TempDir())
+
+	backups, err := store.List(7)
+
+	require.NoError(t, err)
+	assert.Empty(t, backups)
+}
+
+func TestStore_Write_FailedWriteLeavesNothing(t *testing.T) {
+	store := NewStore(t.TempDir())
+
+	_, err := store.Write(7, time.Now(), "tar", func(f *os.File) error {
+		_, _ = f.WriteString("partial")
+		return os.ErrClosed
+	})
+
+	require.ErrorIs(t, err, os.ErrClosed)
+	entries, err := os.ReadDir(store.serverDir(7))
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestStore_RemovePartial(t *testing.T) {
+	store := NewStore(t.TempDir())
+	givenBackups(t, store, 7, time.Now())
+	partial := filepath.Join(store.serverDir(7), ".20261018T100000Z.tar.123.tmp")
+	require.NoError(t, os.WriteFile(partial, []byte("partial"), 0o600))
+
+	require.NoError(t, store.RemovePartial(7))
+
+	assert.NoFileExists(t, partial)
+	backups, err := store.List(7)
+	require.NoError(t, err)
+	assert.Len(t, backups, 1)
+}
+
+func TestStore_Find(t *testing.T) {
+	store := NewStore(t.TempDir())
+	first := time.Date(2026, 10, 18, 10, 0, 0, 0, time.UTC)
+	second := first.Add(time.Hour)
+	givenBackups(t, store, 7, first, second)
+
+	t.Run("point in time between backups", func(t *testing.T) {
+		b, err := store.Find(7, second.Add(-time.Minute))
+
+		require.NoError(t, err)
+		assert.Equal(t, first, b.CreatedAt)
+	})
+
+	t.Run("exact time", func(t *testing.T) {
+		b, err := store.Find(7, second)
+
+		require.NoError(t, err)
+		assert.Equal(t, second, b.CreatedAt)
+	})
+
+	t.Run("before the oldest backup", func(t *testing.T) {
+		_, err := store.Find(7, first.Add(-time.Minute))
+
+		require.ErrorIs(t, err, ErrBackupNotFound)
+	})
+
+	t.Run("no backups", func(t *testing.T) {
+		_, err := store.Find(8, second)
+
+		require.ErrorIs(t, err, ErrNoBackups)
+	})
+}
@@ -0,0 +1,64 @@
+package customhandlers
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"strconv"
+
+	"github.com/gameap/daemon/internal/app/contracts"
+	"github.com/gameap/daemon/internal/app/domain"
+	"github.com/gameap/daemon/internal/app/query"
+	"github.com/pkg/errors"
+)
+
+type serverQuerier interface {
+	Query(ctx context.Context, server *domain.Server) (*query.Result, error)
+}
+
+// ServerQuery answers "server-query <id>" with the JSON encoded result of a
+// query of the running game server.
+type ServerQuery struct {
+	querier    serverQuerier
+	serverRepo serverRepo
+}
+
+func NewServerQuery(querier serverQuerier, serverRepo serverRepo) *ServerQuery {
+	return &ServerQuery{
+		querier:    querier,
+		serverRepo: serverRepo,
+	}
+}
+
+func (sq *ServerQuery) Handle(
+	ctx context.Context, args []string, out io.Writer, _ contracts.ExecutorOptions,
+) (int, error) {
+	if len(args) < 1 {
+		return int(domain.ErrorResult), errors.New("no server id provided")
+	}
+
+	serverID, err := strconv.Atoi(args[0])
+	if err != nil {
+		return int(domain.ErrorResult), errors.New("invalid server id, should be integer")
+	}
+
+	server, err := sq.serverRepo.FindByID(ctx, serverID)
+	if err != nil {
+		return int(domain.ErrorResult), errors.WithMessage(err, "failed to get server")
+	}
+
+	if server == nil {
+		return int(domain.ErrorResult), errors.New("server not found")
+	}
+
+	result, err := sq.querier.Query(ctx, server)
+	if err != nil {
+		return int(domain.ErrorResult), err
+	}
+
+	if err := json.NewEncoder(out).Encode(result); err != nil {
+		return int(domain.ErrorResult), errors.Wrap(err, "failed to write query result")
+	}
+
+	return int(domain.SuccessResult), nil
+}
@@ -12,6 +12,7 @@ import (
 	"github.com/gameap/daemon/internal/app/contracts"
 	"github.com/gameap/daemon/pkg/shellquote"
 	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
 )
 
 var ErrEmptyCommand = errors.New("empty command")
@@ -187,7 +188,10 @@ func ExecWithWriterArgs(
 	}
 
 	var exitError *exec.ExitError
-	err = cmd.Run()
+	err = startCommand(cmd, options)
+	if err == nil {
+		err = cmd.Wait()
+	}
 	if err != nil && !errors.As(err, &exitError) {
 		return cmd.ProcessState.ExitCode(), errors.Wrap(err, "failed to execute command")
 	}
@@ -197,3 +201,19 @@ func ExecWithWriterArgs(
 
 	return cmd.ProcessState.ExitCode(), nil
 }
+
+// startCommand starts cmd and applies the process priority to it right
+// away, before it forks the actual server.
+func startCommand(cmd *exec.Cmd, options contracts.ExecutorOptions) error {
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	if !options.Priority.IsZero() {
+		if err := ApplyProcessPriority(cmd.Process.Pid, options.Priority); err != nil {
+			log.Warn(err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,104 @@
+//go:build linux
+
+package components
+
+import (
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/gameap/daemon/internal/app/contracts"
+	"github.com/pkg/errors"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	ioprioClassShift = 13
+	ioprioWhoProcess = 1
+
+	// defaultIOLevel is the level of a class given without one, as ionice
+	// does.
+	defaultIOLevel = 4
+)
+
+var (
+	ErrProcessPriority = errors.New("failed to apply process priority")
+	errUnknownIOClass  = errors.New("unknown io scheduling class")
+)
+
+var ioprioClasses = map[string]int{
+	"realtime":    1,
+	"best-effort": 2,
+	"idle":        3,
+}
+
+// ApplyProcessPriority sets the scheduling of the process pid. Every setting
+// is tried, the error lists the ones that failed, e.g. a negative nice
+// value without CAP_SYS_NICE.
+func ApplyProcessPriority(pid int, priority contracts.ProcessPriority) error {
+	var failed []string
+
+	if priority.Nice != nil {
+		if err := unix.Setpriority(unix.PRIO_PROCESS, pid, *priority.Nice); err != nil {
+			failed = append(failed, "nice: "+err.Error())
+		}
+	}
+
+	if len(priority.CPUAffinity) > 0 {
+		var set unix.CPUSet
+		for _, cpu := range priority.CPUAffinity {
+			set.Set(cpu)
+		}
+
+		if err := unix.SchedSetaffinity(pid, &set); err != nil {
+			failed = append(failed, "cpu affinity: "+err.Error())
+		}
+	}
+
+	if priority.IOClass != "" || priority.IOLevel != nil {
+		if err := setIOPriority(pid, priority.IOClass, priority.IOLevel); err != nil {
+			failed = append(failed, "io priority: "+err.Error())
+		}
+	}
+
+	if priority.OOMScoreAdj != nil {
+		path := "/proc/" + strconv.Itoa(pid) + "/oom_score_adj"
+		if err := os.WriteFile(path, []byte(strconv.Itoa(*priority.OOMScoreAdj)), 0); err != nil {
+			failed = append(failed, "oom score adjustment: "+err.Error())
+		}
+	}
+
+	if len(failed) > 0 {
+		return errors.Wrapf(ErrProcessPriority, "pid %d: %s", pid, strings.Join(failed, "; "))
+	}
+
+	return nil
+}
+
+func setIOPriority(pid int, class string, level *int) error {
+	if class == "" {
+		class = "best-effort"
+	}
+
+	classID, ok := ioprioClasses[class]
+	if !ok {
+		return errors.Wrapf(errUnknownIOClass, "%q", class)
+	}
+
+	data := defaultIOLevel
+	if level != nil {
+		data = *level
+	}
+	if class == "idle" {
+		data = 0
+	}
+
+	_, _, errno := unix.Syscall(
+		unix.SYS_IOPRIO_SET, ioprioWhoProcess, uintptr(pid), uintptr(classID<<ioprioClassShift|data),
+	)
+	if errno != 0 {
+		return errno
+	}
+
+	return nil
+}
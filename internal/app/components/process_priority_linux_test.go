@@ -0,0 +1,87 @@
+//go:build linux
+
+package components_test
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gameap/daemon/internal/app/components"
+	"github.com/gameap/daemon/internal/app/contracts"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sys/unix"
+)
+
+func givenSleepingProcess(t *testing.T) int {
+	t.Helper()
+
+	cmd := exec.Command("sleep", "30")
+	require.NoError(t, cmd.Start())
+	t.Cleanup(func() {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+	})
+
+	return cmd.Process.Pid
+}
+
+func TestApplyProcessPriority(t *testing.T) {
+	pid := givenSleepingProcess(t)
+	nice := 7
+	oomScoreAdj := 300
+
+	err := components.ApplyProcessPriority(pid, contracts.ProcessPriority{
+		Nice:        &nice,
+		CPUAffinity: []int{0},
+		IOClass:     "idle",
+		OOMScoreAdj: &oomScoreAdj,
+	})
+
+	require.NoError(t, err)
+
+	// The syscall returns 20 - nice.
+	prio, err := unix.Getpriority(unix.PRIO_PROCESS, pid)
+	require.NoError(t, err)
+	assert.Equal(t, 20-nice, prio)
+
+	var set unix.CPUSet
+	require.NoError(t, unix.SchedGetaffinity(pid, &set))
+	assert.Equal(t, 1, set.Count())
+	assert.True(t, set.IsSet(0))
+
+	content, err := os.ReadFile("/proc/" + strconv.Itoa(pid) + "/oom_score_adj")
+	require.NoError(t, err)
+	assert.Equal(t, strconv.Itoa(oomScoreAdj), strings.TrimSpace(string(content)))
+}
+
+func TestApplyProcessPriority_Failed(t *testing.T) {
+	level := 2
+
+	err := components.ApplyProcessPriority(givenSleepingProcess(t), contracts.ProcessPriority{
+		IOClass: "fast",
+		IOLevel: &level,
+	})
+
+	require.ErrorIs(t, err, components.ErrProcessPriority)
+	assert.Contains(t, err.Error(), "io priority")
+}
+
+func TestExec_Priority(t *testing.T) {
+	nice := 5
+
+	// The priority is applied right after the start, the shell waits so
+	// nice reports it.
+	result, code, err := components.Exec(context.Background(), `sh -c "sleep 0.5; nice"`, contracts.ExecutorOptions{
+		WorkDir:  t.TempDir(),
+		Priority: contracts.ProcessPriority{Nice: &nice},
+	})
+
+	require.NoError(t, err)
+	require.Equal(t, 0, code)
+	assert.Equal(t, "5", strings.TrimSpace(string(result)))
+}
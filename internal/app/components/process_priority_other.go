@@ -0,0 +1,10 @@
+//go:build !linux
+
+package components
+
+import "github.com/gameap/daemon/internal/app/contracts"
+
+// ApplyProcessPriority does nothing outside Linux.
+func ApplyProcessPriority(_ int, _ contracts.ProcessPriority) error {
+	return nil
+}
@@ -0,0 +1,166 @@
+package config
+
+import (
+	"path"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// BackupsConfig configures backups of game server directories.
+type BackupsConfig struct {
+	// Path is the directory the backups are written to, {work_path}/backups
+	// by default. Every server gets a subdirectory named after its ID.
+	Path string `yaml:"path"`
+
+	// Default is the policy of every server without an entry in Servers.
+	Default BackupPolicy `yaml:"default"`
+
+	// Servers holds the policies of individual servers keyed by server ID.
+	// They replace the default policy as a whole.
+	Servers map[int]BackupPolicy `yaml:"servers"`
+}
+
+// BackupPolicy describes what is backed up, how often and how long the
+// backups are kept.
+type BackupPolicy struct {
+	// Interval between two scheduled backups. Zero disables scheduled
+	// backups, a backup can still be made manually.
+	Interval time.Duration `yaml:"interval"`
+
+	// Include lists glob patterns of paths relative to the server directory
+	// to back up. Everything is backed up when it is empty.
+	Include []string `yaml:"include"`
+
+	// Exclude lists glob patterns of paths left out of the backup. A pattern
+	// without a slash matches the name at any depth, e.g. "*.log".
+	Exclude []string `yaml:"exclude"`
+
+	// Format is the archive format: tar.gz (default), tar.zst, tar.xz,
+	// tar.bz2, tar or zip.
+	Format string `yaml:"format"`
+
+	// KeepLast is the number of backups to keep, KeepFor is the maximum age
+	// of a backup. Zero disables the limit, the newest backup is never
+	// removed by the age limit.
+	KeepLast int           `yaml:"keep_last"`
+	KeepFor  time.Duration `yaml:"keep_for"`
+
+	// Prepare is what is done with a running server before the backup:
+	// "none" (default), "save" to send SaveCommand to the console and wait
+	// SaveWait, or "stop" to stop the server for the time of the backup.
+	Prepare     string        `yaml:"prepare"`
+	SaveCommand string        `yaml:"save_command"`
+	SaveWait    time.Duration `yaml:"save_wait"`
+}
+
+const (
+	BackupPrepareNone = "none"
+	BackupPrepareSave = "save"
+	BackupPrepareStop = "stop"
+)
+
+const (
+	BackupsDefaultDirName  = "backups"
+	BackupsDefaultFormat   = "tar.gz"
+	BackupsDefaultKeepLast = 7
+	BackupsDefaultSaveWait = 10 * time.Second
+)
+
+var backupFormats = []string{"tar.gz", "tar.zst", "tar.xz", "tar.bz2", "tar", "zip"}
+
+// Policy returns the backup policy of the server.
+func (b BackupsConfig) Policy(serverID int) BackupPolicy {
+	if policy, ok := b.Servers[serverID]; ok {
+		return policy
+	}
+
+	return b.Default
+}
+
+// Scheduled reports whether any server has scheduled backups.
+func (b BackupsConfig) Scheduled() bool {
+	if b.Default.Interval > 0 {
+		return true
+	}
+
+	for _, policy := range b.Servers {
+		if policy.Interval > 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (cfg *Config) initBackupsDefaults() {
+	if cfg.Backups.Path == "" && cfg.WorkPath != "" {
+		cfg.Backups.Path = filepath.Join(cfg.WorkPath, BackupsDefaultDirName)
+	}
+
+	cfg.Backups.Default.setDefaults()
+
+	for id, policy := range cfg.Backups.Servers {
+		policy.setDefaults()
+		cfg.Backups.Servers[id] = policy
+	}
+}
+
+func (p *BackupPolicy) setDefaults() {
+	if p.Format == "" {
+		p.Format = BackupsDefaultFormat
+	}
+	if p.KeepLast == 0 && p.KeepFor == 0 {
+		p.KeepLast = BackupsDefaultKeepLast
+	}
+	if p.Prepare == "" {
+		p.Prepare = BackupPrepareNone
+	}
+	if p.SaveWait <= 0 {
+		p.SaveWait = BackupsDefaultSaveWait
+	}
+}
+
+func (b BackupsConfig) validate() error {
+	if err := b.Default.validate(); err != nil {
+		return errors.WithMessage(err, "backups.default")
+	}
+
+	for id, policy := range b.Servers {
+		if err := policy.validate(); err != nil {
+			return errors.WithMessagef(err, "backups.servers.%d", id)
+		}
+	}
+
+	return nil
+}
+
+func (p BackupPolicy) validate() error {
+	if p.Format != "" && !slices.Contains(backupFormats, p.Format) {
+		return errors.Wrapf(ErrInvalidBackupFormat, "got %q", p.Format)
+	}
+
+	for _, pattern := range slices.Concat(p.Include, p.Exclude) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return errors.Wrapf(ErrInvalidBackupPattern, "got %q", pattern)
+		}
+	}
+
+	if p.KeepLast < 0 || p.KeepFor < 0 {
+		return ErrInvalidBackupRetention
+	}
+
+	switch p.Prepare {
+	case "", BackupPrepareNone, BackupPrepareStop:
+	case BackupPrepareSave:
+		if p.SaveCommand == "" {
+			return ErrNoBackupSaveCommand
+		}
+	default:
+		return errors.Wrapf(ErrInvalidBackupPrepare, "got %q", p.Prepare)
+	}
+
+	return nil
+}
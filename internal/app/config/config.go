@@ -48,6 +48,10 @@ type MetricsConfig struct {
 	Enabled            *bool         `yaml:"enabled"`
 	CollectionInterval time.Duration `yaml:"collection_interval"`
 	RetentionDuration  time.Duration `yaml:"retention_duration"`
+
+	// PrometheusListen is an optional address (host:port) of the Prometheus
+	// exporter. The exporter is not started when it is empty.
+	PrometheusListen string `yaml:"prometheus_listen"`
 }
 
 // IsEnabled reports whether metrics collection should run. Defaults to true
@@ -67,6 +71,59 @@ const (
 	MetricsMinCollectionInterval     = 1 * time.Second
 )
 
+// CrashLoopConfig controls how the servers loop restarts servers with
+// autostart enabled that keep crashing.
+type CrashLoopConfig struct {
+	// InitialBackoff is the delay before the second restart in a row, it is
+	// doubled for every next crash up to MaxBackoff. The first crash is
+	// restarted right away.
+	InitialBackoff time.Duration `yaml:"initial_backoff"`
+	MaxBackoff     time.Duration `yaml:"max_backoff"`
+
+	// MaxRestarts within Window suspend autostart of the server until it is
+	// started again from the panel. A negative value disables the limit.
+	MaxRestarts int           `yaml:"max_restarts"`
+	Window      time.Duration `yaml:"window"`
+
+	// StableUptime is how long a server has to run to reset the backoff.
+	StableUptime time.Duration `yaml:"stable_uptime"`
+}
+
+const (
+	CrashLoopDefaultInitialBackoff = 10 * time.Second
+	CrashLoopDefaultMaxBackoff     = 5 * time.Minute
+	CrashLoopDefaultMaxRestarts    = 5
+	CrashLoopDefaultWindow         = 10 * time.Minute
+	CrashLoopDefaultStableUptime   = 5 * time.Minute
+)
+
+// AdminAPIConfig configures the local API for node operators. It listens on
+// a Unix socket and, optionally, on a TCP address protected with the daemon
+// TLS certificates.
+type AdminAPIConfig struct {
+	Enabled bool `yaml:"enabled"`
+
+	// Socket is the path of the Unix socket, {state_path}/admin.sock by
+	// default. Only the daemon user can connect to it.
+	Socket string `yaml:"socket"`
+
+	// Listen is an optional TCP address (host:port). Clients must present a
+	// certificate signed by ClientCACertificateFile or by the panel CA with
+	// a name from ClientNames.
+	Listen string `yaml:"listen"`
+
+	// ClientCACertificateFile is the CA of operator client certificates. The
+	// panel CA is used when it is not set, it also signs the certificates of
+	// other nodes, so ClientNames is required then.
+	ClientCACertificateFile string `yaml:"client_ca_certificate_file"`
+
+	// ClientNames restricts client certificates to the listed common names
+	// and DNS names.
+	ClientNames []string `yaml:"client_names"`
+}
+
+const AdminAPIDefaultSocketName = "admin.sock"
+
 //nolint:govet
 type Config struct {
 	NodeID uint `yaml:"ds_id"`
@@ -100,6 +157,10 @@ type Config struct {
 	ToolsPath    string `yaml:"tools_path"`
 	SteamCMDPath string `yaml:"steamcmd_path"`
 
+	// StatePath is the directory where the daemon keeps its local state
+	// (servers, games and scheduled tasks) between restarts.
+	StatePath string `yaml:"state_path"`
+
 	SteamConfig SteamConfig `yaml:"steam_config"`
 
 	RemoteRepositoryReplacements RepositoryReplacements `yaml:"remote_repository_replacements"`
@@ -121,6 +182,22 @@ type Config struct {
 
 	Metrics MetricsConfig `yaml:"metrics"`
 
+	CrashLoop CrashLoopConfig `yaml:"crash_loop"`
+
+	AdminAPI AdminAPIConfig `yaml:"admin_api"`
+
+	Backups BackupsConfig `yaml:"backups"`
+
+	Query QueryConfig `yaml:"query"`
+
+	Readiness ReadinessConfig `yaml:"readiness"`
+
+	Health HealthConfig `yaml:"health"`
+
+	Countdown CountdownConfig `yaml:"countdown"`
+
+	SFTP SFTPConfig `yaml:"sftp"`
+
 	Users map[string]string `yaml:"users"`
 
 	// Windows specific settings
@@ -142,6 +219,14 @@ func (cfg *Config) Init() error {
 		cfg.ToolsPath = filepath.Join(cfg.WorkPath, "tools")
 	}
 
+	if cfg.StatePath == "" && cfg.WorkPath != "" {
+		cfg.StatePath = filepath.Join(cfg.WorkPath, ".gameap-daemon")
+	}
+
+	if cfg.AdminAPI.Socket == "" && cfg.StatePath != "" {
+		cfg.AdminAPI.Socket = filepath.Join(cfg.StatePath, AdminAPIDefaultSocketName)
+	}
+
 	if cfg.TaskManager.RunTaskPeriod == 0 {
 		cfg.TaskManager.RunTaskPeriod = 10 * time.Millisecond
 	}
@@ -181,10 +266,38 @@ func (cfg *Config) Init() error {
 	}
 
 	cfg.initMetricsDefaults()
+	cfg.initCrashLoopDefaults()
+	cfg.initBackupsDefaults()
+	cfg.initQueryDefaults()
+	cfg.initReadinessDefaults()
+	cfg.initHealthDefaults()
+	cfg.initCountdownDefaults()
+	cfg.initSFTPDefaults()
 
 	return cfg.validate()
 }
 
+func (cfg *Config) initCrashLoopDefaults() {
+	if cfg.CrashLoop.InitialBackoff <= 0 {
+		cfg.CrashLoop.InitialBackoff = CrashLoopDefaultInitialBackoff
+	}
+	if cfg.CrashLoop.MaxBackoff <= 0 {
+		cfg.CrashLoop.MaxBackoff = CrashLoopDefaultMaxBackoff
+	}
+	if cfg.CrashLoop.MaxBackoff < cfg.CrashLoop.InitialBackoff {
+		cfg.CrashLoop.MaxBackoff = cfg.CrashLoop.InitialBackoff
+	}
+	if cfg.CrashLoop.MaxRestarts == 0 {
+		cfg.CrashLoop.MaxRestarts = CrashLoopDefaultMaxRestarts
+	}
+	if cfg.CrashLoop.Window <= 0 {
+		cfg.CrashLoop.Window = CrashLoopDefaultWindow
+	}
+	if cfg.CrashLoop.StableUptime <= 0 {
+		cfg.CrashLoop.StableUptime = CrashLoopDefaultStableUptime
+	}
+}
+
 func (cfg *Config) initMetricsDefaults() {
 	if cfg.Metrics.CollectionInterval <= 0 {
 		cfg.Metrics.CollectionInterval = MetricsDefaultCollectionInterval
@@ -216,6 +329,18 @@ func (cfg *Config) validate() error {
 		return err
 	}
 
+	if err := cfg.validateAdminAPI(); err != nil {
+		return err
+	}
+
+	if err := cfg.Backups.validate(); err != nil {
+		return err
+	}
+
+	if err := cfg.SFTP.validate(); err != nil {
+		return err
+	}
+
 	if cfg.APIKey == "" {
 		return ErrEmptyAPIKey
 	}
@@ -273,6 +398,26 @@ func (cfg *Config) validateProcessManager() error {
 	return nil
 }
 
+func (cfg *Config) validateAdminAPI() error {
+	if !cfg.AdminAPI.Enabled {
+		return nil
+	}
+
+	if cfg.AdminAPI.Socket == "" && cfg.AdminAPI.Listen == "" {
+		return ErrNoAdminAPIListener
+	}
+
+	if cfg.AdminAPI.Listen != "" && cfg.IsInsecure() {
+		return ErrAdminAPIListenInsecure
+	}
+
+	if cfg.AdminAPI.Listen != "" && cfg.AdminAPI.ClientCACertificateFile == "" && len(cfg.AdminAPI.ClientNames) == 0 {
+		return ErrAdminAPIClientNotPinned
+	}
+
+	return nil
+}
+
 func (cfg *Config) CACertificatePEM() ([]byte, error) {
 	if cfg.CACertificate != "" {
 		return []byte(cfg.CACertificate), nil
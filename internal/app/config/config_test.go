@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -270,3 +271,216 @@ func givenValidConfig(t *testing.T) *Config {
 
 	return cfg
 }
+
+func TestInit_AdminAPISocketDefaultsToStatePath(t *testing.T) {
+	cfg := givenValidConfig(t)
+	cfg.AdminAPI.Enabled = true
+
+	err := cfg.Init()
+
+	require.NoError(t, err)
+	assert.Equal(t, "/tmp/config_test/.gameap-daemon/admin.sock", cfg.AdminAPI.Socket)
+}
+
+func TestValidate_AdminAPIListenRequiresTLS(t *testing.T) {
+	cfg := NewConfig()
+	cfg.NodeID = 1
+	cfg.APIKey = "api-key"
+	cfg.GRPC.Address = "panel.example.com:31718"
+	cfg.GRPC.Insecure = true
+	cfg.AdminAPI.Enabled = true
+	cfg.AdminAPI.Listen = "127.0.0.1:31719"
+
+	assert.ErrorIs(t, cfg.validate(), ErrAdminAPIListenInsecure)
+}
+
+func TestValidate_AdminAPIListenRequiresPinnedClients(t *testing.T) {
+	cfg := givenValidConfig(t)
+	cfg.AdminAPI.Enabled = true
+	cfg.AdminAPI.Listen = "127.0.0.1:31719"
+
+	require.ErrorIs(t, cfg.validate(), ErrAdminAPIClientNotPinned)
+
+	cfg.AdminAPI.ClientNames = []string{"operator"}
+	require.NoError(t, cfg.validate())
+
+	cfg.AdminAPI.ClientNames = nil
+	cfg.AdminAPI.ClientCACertificateFile = "/etc/gameap/operators-ca.crt"
+	assert.NoError(t, cfg.validate())
+}
+
+func TestInit_BackupsDefaults(t *testing.T) {
+	cfg := givenValidConfig(t)
+	cfg.Backups.Servers = map[int]BackupPolicy{
+		7: {Interval: time.Hour, KeepFor: 24 * time.Hour},
+	}
+
+	err := cfg.Init()
+
+	require.NoError(t, err)
+	assert.Equal(t, "/tmp/config_test/backups", cfg.Backups.Path)
+	assert.Equal(t, BackupPolicy{
+		Format:   BackupsDefaultFormat,
+		KeepLast: BackupsDefaultKeepLast,
+		Prepare:  BackupPrepareNone,
+		SaveWait: BackupsDefaultSaveWait,
+	}, cfg.Backups.Policy(1))
+	assert.Equal(t, BackupPolicy{
+		Interval: time.Hour,
+		Format:   BackupsDefaultFormat,
+		KeepFor:  24 * time.Hour,
+		Prepare:  BackupPrepareNone,
+		SaveWait: BackupsDefaultSaveWait,
+	}, cfg.Backups.Policy(7))
+	assert.True(t, cfg.Backups.Scheduled())
+}
+
+func TestValidate_BackupPolicy(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy BackupPolicy
+		err    error
+	}{
+		{
+			name:   "unknown format",
+			policy: BackupPolicy{Format: "rar"},
+			err:    ErrInvalidBackupFormat,
+		},
+		{
+			name:   "invalid pattern",
+			policy: BackupPolicy{Exclude: []string{"logs/["}},
+			err:    ErrInvalidBackupPattern,
+		},
+		{
+			name:   "negative retention",
+			policy: BackupPolicy{KeepLast: -1},
+			err:    ErrInvalidBackupRetention,
+		},
+		{
+			name:   "unknown prepare",
+			policy: BackupPolicy{Prepare: "pause"},
+			err:    ErrInvalidBackupPrepare,
+		},
+		{
+			name:   "save without command",
+			policy: BackupPolicy{Prepare: BackupPrepareSave},
+			err:    ErrNoBackupSaveCommand,
+		},
+		{
+			name:   "save with command",
+			policy: BackupPolicy{Prepare: BackupPrepareSave, SaveCommand: "save-all"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cfg := givenValidConfig(t)
+			cfg.Backups.Servers = map[int]BackupPolicy{3: test.policy}
+
+			err := cfg.Init()
+
+			if test.err == nil {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorIs(t, err, test.err)
+			assert.Contains(t, err.Error(), "backups.servers.3")
+		})
+	}
+}
+
+func TestInit_QueryDefaults(t *testing.T) {
+	cfg := givenValidConfig(t)
+	cfg.Query.Interval = time.Second
+	cfg.Query.Timeout = time.Minute
+
+	err := cfg.Init()
+
+	require.NoError(t, err)
+	assert.True(t, cfg.Query.IsEnabled())
+	assert.Equal(t, QueryMinInterval, cfg.Query.Interval)
+	assert.Equal(t, QueryMinInterval, cfg.Query.Timeout)
+}
+
+func TestInit_HealthDefaults(t *testing.T) {
+	cfg := givenValidConfig(t)
+	cfg.Health.Interval = time.Second
+
+	err := cfg.Init()
+
+	require.NoError(t, err)
+	assert.True(t, cfg.Health.IsEnabled())
+	assert.Equal(t, HealthMinInterval, cfg.Health.Interval)
+	assert.Equal(t, HealthMinInterval, cfg.Health.Timeout)
+	assert.Equal(t, HealthDefaultFailureThreshold, cfg.Health.FailureThreshold)
+}
+
+func TestInit_CountdownDefaults(t *testing.T) {
+	cfg := givenValidConfig(t)
+
+	err := cfg.Init()
+
+	require.NoError(t, err)
+	assert.Equal(t, CountdownDefaultMarks, cfg.Countdown.Marks)
+	assert.Empty(t, cfg.Countdown.Template)
+}
+
+func TestInit_SFTPDefaults(t *testing.T) {
+	cfg := givenValidConfig(t)
+	cfg.SFTP.Enabled = true
+
+	err := cfg.Init()
+
+	require.NoError(t, err)
+	assert.Equal(t, SFTPDefaultListen, cfg.SFTP.Listen)
+	assert.Equal(t, "/tmp/config_test/.gameap-daemon/sftp_host_key", cfg.SFTP.HostKey)
+	assert.Equal(t, SFTPDefaultIdleTimeout, cfg.SFTP.IdleTimeout)
+}
+
+func TestValidate_SFTPUsers(t *testing.T) {
+	tests := []struct {
+		name  string
+		users []SFTPUser
+		err   error
+	}{
+		{
+			name:  "no server",
+			users: []SFTPUser{{Username: "alice", PasswordHash: "hash"}},
+			err:   ErrInvalidSFTPUser,
+		},
+		{
+			name: "duplicate username",
+			users: []SFTPUser{
+				{Username: "alice", ServerID: 1, PasswordHash: "hash"},
+				{Username: "alice", ServerID: 2, PasswordHash: "hash"},
+			},
+			err: ErrDuplicateSFTPUser,
+		},
+		{
+			name:  "no credentials",
+			users: []SFTPUser{{Username: "alice", ServerID: 1}},
+			err:   ErrNoSFTPCredentials,
+		},
+		{
+			name:  "valid",
+			users: []SFTPUser{{Username: "alice", ServerID: 1, PublicKeys: []string{"ssh-ed25519 AAAA"}}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cfg := givenValidConfig(t)
+			cfg.SFTP.Enabled = true
+			cfg.SFTP.Users = test.users
+
+			err := cfg.Init()
+
+			if test.err == nil {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorIs(t, err, test.err)
+			assert.Contains(t, err.Error(), `user "alice"`)
+		})
+	}
+}
@@ -0,0 +1,32 @@
+package config
+
+import (
+	"slices"
+	"time"
+)
+
+// CountdownConfig controls the console messages warning the players of a
+// running server before it is stopped, restarted or updated by the panel or
+// a scheduled task.
+type CountdownConfig struct {
+	// Marks are the times before the action the message is sent at, the
+	// countdown takes as long as the longest of them.
+	Marks []time.Duration `yaml:"marks"`
+
+	// Template is the console command sent at every mark, e.g.
+	// "say Server {action}s in {time}". The countdown is off for games
+	// without a countdown_template metadata when it is empty.
+	Template string `yaml:"template"`
+
+	// CancelTemplate is the console command sent when the countdown is
+	// cancelled, nothing is sent when it is empty.
+	CancelTemplate string `yaml:"cancel_template"`
+}
+
+var CountdownDefaultMarks = []time.Duration{15 * time.Minute, 5 * time.Minute, time.Minute}
+
+func (cfg *Config) initCountdownDefaults() {
+	if len(cfg.Countdown.Marks) == 0 {
+		cfg.Countdown.Marks = slices.Clone(CountdownDefaultMarks)
+	}
+}
@@ -23,6 +23,26 @@ var (
 	ErrScopeOnlyForSystemD = errors.New(
 		"process_manager.config.scope is only valid for process_manager.name=systemd",
 	)
+	ErrNoAdminAPIListener = errors.New(
+		"admin_api is enabled but neither admin_api.socket nor admin_api.listen is set",
+	)
+	ErrAdminAPIListenInsecure = errors.New(
+		"admin_api.listen requires TLS, it cannot be used with an insecure panel connection",
+	)
+	ErrAdminAPIClientNotPinned = errors.New(
+		"admin_api.listen requires admin_api.client_ca_certificate_file or admin_api.client_names",
+	)
+	ErrInvalidBackupFormat = errors.New(
+		"backup format must be one of tar.gz, tar.zst, tar.xz, tar.bz2, tar or zip",
+	)
+	ErrInvalidBackupPattern          = errors.New("backup include and exclude must be valid glob patterns")
+	ErrInvalidBackupRetention        = errors.New("backup keep_last and keep_for must not be negative")
+	ErrInvalidBackupPrepare          = errors.New("backup prepare must be 'none', 'save' or 'stop'")
+	ErrNoBackupSaveCommand           = errors.New("backup prepare 'save' requires save_command")
+	ErrNoSFTPHostKey                 = errors.New("sftp is enabled but sftp.host_key is not set")
+	ErrInvalidSFTPUser               = errors.New("sftp user needs a username and a server_id")
+	ErrDuplicateSFTPUser             = errors.New("duplicate sftp username")
+	ErrNoSFTPCredentials             = errors.New("sftp user needs a password_hash or public_keys")
 	ErrEmptyReplacementKey           = errors.New("host key is empty")
 	ErrDuplicateReplacementKey       = errors.New("duplicate host key")
 	ErrNoReplacementTargets          = errors.New("no replacement targets")
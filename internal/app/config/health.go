@@ -0,0 +1,55 @@
+package config
+
+import "time"
+
+// HealthConfig controls the liveness checks of running servers. Only
+// servers with health probes in their game metadata are checked.
+type HealthConfig struct {
+	Enabled *bool `yaml:"enabled"`
+
+	// Interval between two checks of a server.
+	Interval time.Duration `yaml:"interval"`
+
+	// Timeout of a single check.
+	Timeout time.Duration `yaml:"timeout"`
+
+	// FailureThreshold is the number of failed checks in a row after which
+	// the server is restarted.
+	FailureThreshold int `yaml:"failure_threshold"`
+}
+
+const (
+	HealthDefaultInterval         = 30 * time.Second
+	HealthDefaultTimeout          = 10 * time.Second
+	HealthDefaultFailureThreshold = 3
+	HealthMinInterval             = 5 * time.Second
+)
+
+// IsEnabled reports whether servers are checked. Defaults to true when the
+// field is absent from the yaml config.
+func (h HealthConfig) IsEnabled() bool {
+	if h.Enabled == nil {
+		return true
+	}
+
+	return *h.Enabled
+}
+
+func (cfg *Config) initHealthDefaults() {
+	if cfg.Health.Interval <= 0 {
+		cfg.Health.Interval = HealthDefaultInterval
+	} else if cfg.Health.Interval < HealthMinInterval {
+		cfg.Health.Interval = HealthMinInterval
+	}
+
+	if cfg.Health.Timeout <= 0 {
+		cfg.Health.Timeout = HealthDefaultTimeout
+	}
+	if cfg.Health.Timeout > cfg.Health.Interval {
+		cfg.Health.Timeout = cfg.Health.Interval
+	}
+
+	if cfg.Health.FailureThreshold <= 0 {
+		cfg.Health.FailureThreshold = HealthDefaultFailureThreshold
+	}
+}
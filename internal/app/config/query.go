@@ -0,0 +1,46 @@
+package config
+
+import "time"
+
+// QueryConfig controls polling of running game servers over their query
+// protocols.
+type QueryConfig struct {
+	Enabled *bool `yaml:"enabled"`
+
+	// Interval between two queries of a server.
+	Interval time.Duration `yaml:"interval"`
+
+	// Timeout of a single query.
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+const (
+	QueryDefaultInterval = 30 * time.Second
+	QueryDefaultTimeout  = 3 * time.Second
+	QueryMinInterval     = 5 * time.Second
+)
+
+// IsEnabled reports whether servers are polled. Defaults to true when the
+// field is absent from the yaml config.
+func (q QueryConfig) IsEnabled() bool {
+	if q.Enabled == nil {
+		return true
+	}
+
+	return *q.Enabled
+}
+
+func (cfg *Config) initQueryDefaults() {
+	if cfg.Query.Interval <= 0 {
+		cfg.Query.Interval = QueryDefaultInterval
+	} else if cfg.Query.Interval < QueryMinInterval {
+		cfg.Query.Interval = QueryMinInterval
+	}
+
+	if cfg.Query.Timeout <= 0 {
+		cfg.Query.Timeout = QueryDefaultTimeout
+	}
+	if cfg.Query.Timeout > cfg.Query.Interval {
+		cfg.Query.Timeout = cfg.Query.Interval
+	}
+}
@@ -0,0 +1,19 @@
+package config
+
+import "time"
+
+// ReadinessConfig controls how long started servers are waited for to pass
+// their readiness probes.
+type ReadinessConfig struct {
+	// Timeout after which a starting server that is still not ready is
+	// unhealthy. Games can override it with the ready_timeout metadata.
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+const ReadinessDefaultTimeout = 5 * time.Minute
+
+func (cfg *Config) initReadinessDefaults() {
+	if cfg.Readiness.Timeout <= 0 {
+		cfg.Readiness.Timeout = ReadinessDefaultTimeout
+	}
+}
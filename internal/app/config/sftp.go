@@ -0,0 +1,96 @@
+package config
+
+import (
+	"path/filepath"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// SFTPConfig configures the built-in SFTP server. Every login is mapped to a
+// game server and confined to its directory.
+type SFTPConfig struct {
+	Enabled bool `yaml:"enabled"`
+
+	// Listen is the address (host:port) of the SSH server.
+	Listen string `yaml:"listen"`
+
+	// HostKey is the path of the SSH host private key, {state_path}/sftp_host_key
+	// by default. An ed25519 key is generated when the file does not exist.
+	HostKey string `yaml:"host_key"`
+
+	// IdleTimeout closes connections without any traffic for that long.
+	IdleTimeout time.Duration `yaml:"idle_timeout"`
+
+	// Users are the logins configured on the node. The panel adds logins
+	// with the sftp_* variables of the servers.
+	Users []SFTPUser `yaml:"users"`
+}
+
+// SFTPUser is a login of the SFTP server.
+type SFTPUser struct {
+	Username string `yaml:"username"`
+
+	// ServerID is the game server the user works with.
+	ServerID int `yaml:"server_id"`
+
+	// PasswordHash is a bcrypt hash of the password, the user cannot log in
+	// with a password without it.
+	PasswordHash string `yaml:"password_hash"`
+
+	// PublicKeys are the keys the user can log in with, in the
+	// authorized_keys format.
+	PublicKeys []string `yaml:"public_keys"`
+
+	// ReadOnly users can only download files.
+	ReadOnly bool `yaml:"read_only"`
+}
+
+const (
+	SFTPDefaultListen      = "0.0.0.0:2022"
+	SFTPDefaultHostKeyName = "sftp_host_key"
+	SFTPDefaultIdleTimeout = 15 * time.Minute
+)
+
+func (cfg *Config) initSFTPDefaults() {
+	if cfg.SFTP.Listen == "" {
+		cfg.SFTP.Listen = SFTPDefaultListen
+	}
+
+	if cfg.SFTP.HostKey == "" && cfg.StatePath != "" {
+		cfg.SFTP.HostKey = filepath.Join(cfg.StatePath, SFTPDefaultHostKeyName)
+	}
+
+	if cfg.SFTP.IdleTimeout <= 0 {
+		cfg.SFTP.IdleTimeout = SFTPDefaultIdleTimeout
+	}
+}
+
+func (c SFTPConfig) validate() error {
+	if !c.Enabled {
+		return nil
+	}
+
+	if c.HostKey == "" {
+		return ErrNoSFTPHostKey
+	}
+
+	usernames := make(map[string]struct{}, len(c.Users))
+
+	for _, u := range c.Users {
+		if u.Username == "" || u.ServerID <= 0 {
+			return errors.Wrapf(ErrInvalidSFTPUser, "user %q", u.Username)
+		}
+
+		if _, ok := usernames[u.Username]; ok {
+			return errors.Wrapf(ErrDuplicateSFTPUser, "user %q", u.Username)
+		}
+		usernames[u.Username] = struct{}{}
+
+		if u.PasswordHash == "" && len(u.PublicKeys) == 0 {
+			return errors.Wrapf(ErrNoSFTPCredentials, "user %q", u.Username)
+		}
+	}
+
+	return nil
+}
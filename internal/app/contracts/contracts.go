@@ -53,9 +53,20 @@ type ProcessManager interface {
 	Uninstall(ctx context.Context, server *domain.Server, out io.Writer) (domain.Result, error)
 
 	Start(ctx context.Context, server *domain.Server, out io.Writer) (domain.Result, error)
+	// Stop shuts the server down gracefully: where the manager supports it the
+	// server stop command is sent to the console first, then the process is
+	// terminated and, if it still does not exit, killed.
 	Stop(ctx context.Context, server *domain.Server, out io.Writer) (domain.Result, error)
+	// Kill forcibly terminates the server right away, skipping the graceful stages.
+	Kill(ctx context.Context, server *domain.Server, out io.Writer) (domain.Result, error)
 	Restart(ctx context.Context, server *domain.Server, out io.Writer) (domain.Result, error)
 	Status(ctx context.Context, server *domain.Server, out io.Writer) (domain.Result, error)
+
+	// Pause freezes every process of a running server without terminating it,
+	// so the in-memory game state survives until Unpause thaws it again.
+	Pause(ctx context.Context, server *domain.Server, out io.Writer) (domain.Result, error)
+	Unpause(ctx context.Context, server *domain.Server, out io.Writer) (domain.Result, error)
+
 	GetOutput(ctx context.Context, server *domain.Server, out io.Writer) (domain.Result, error)
 	SendInput(ctx context.Context, input string, server *domain.Server, out io.Writer) (domain.Result, error)
 
@@ -75,6 +86,13 @@ type ProcessManager interface {
 	HasOwnInstallation(server *domain.Server) bool
 }
 
+// ExitCodeReader is implemented by process managers that keep the exit code
+// of a server process after it stopped. The servers loop uses it to report
+// why a server crashed.
+type ExitCodeReader interface {
+	ExitCode(ctx context.Context, server *domain.Server) (int, bool)
+}
+
 type DomainPrimitiveValidator interface {
 	Validate() error
 }
@@ -85,4 +103,27 @@ type ExecutorOptions struct {
 	FallbackWorkDir string
 	UID             string
 	GID             string
+
+	// Priority is applied to the started process on Linux, the processes it
+	// forks afterwards inherit it.
+	Priority ProcessPriority
+}
+
+// ProcessPriority is the scheduling of a server process. Unset fields keep
+// what the process inherits from the daemon.
+type ProcessPriority struct {
+	Nice        *int
+	CPUAffinity []int
+
+	// IOClass is "realtime", "best-effort" or "idle", IOLevel 0 (highest)
+	// to 7 within the class.
+	IOClass string
+	IOLevel *int
+
+	OOMScoreAdj *int
+}
+
+// IsZero reports whether no setting is set.
+func (p ProcessPriority) IsZero() bool {
+	return p.Nice == nil && len(p.CPUAffinity) == 0 && p.IOClass == "" && p.IOLevel == nil && p.OOMScoreAdj == nil
 }
@@ -0,0 +1,447 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/gameap/daemon/internal/app/adminapi"
+	"github.com/gameap/daemon/internal/app/config"
+	"github.com/pkg/errors"
+	"github.com/urfave/cli/v2"
+)
+
+var (
+	errAdminAPIDisabled = errors.New(
+		"admin API is disabled in the daemon config, set admin_api.enabled or pass --socket",
+	)
+	errInvalidID   = errors.New("expected a numeric id")
+	errInvalidTime = errors.New("expected RFC 3339 or \"YYYY-MM-DD HH:MM:SS\" time")
+)
+
+func ctlCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "ctl",
+		Usage: "Manage the running daemon through its admin API socket",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "socket",
+				Usage: "Path to the admin API socket. Defaults to admin_api.socket from the daemon config",
+			},
+		},
+		Subcommands: []*cli.Command{
+			{
+				Name:   "status",
+				Usage:  "Show daemon status",
+				Action: ctlStatusAction,
+			},
+			{
+				Name:  "servers",
+				Usage: "Game servers",
+				Subcommands: []*cli.Command{
+					{
+						Name:   "list",
+						Usage:  "List game servers",
+						Action: ctlServersListAction,
+					},
+				},
+			},
+			{
+				Name:  "server",
+				Usage: "Manage a game server",
+				Subcommands: []*cli.Command{
+					ctlServerCommand("start", "Start the server"),
+					ctlServerCommand("stop", "Stop the server"),
+					ctlServerCommand("restart", "Restart the server"),
+					{
+						Name:      "status",
+						Usage:     "Show the server status",
+						ArgsUsage: "<id>",
+						Action:    ctlServerStatusAction,
+					},
+				},
+			},
+			{
+				Name:  "tasks",
+				Usage: "Daemon task queue",
+				Subcommands: []*cli.Command{
+					{
+						Name:   "list",
+						Usage:  "List tasks in progress",
+						Action: ctlTasksListAction,
+					},
+					{
+						Name:      "cancel",
+						Usage:     "Cancel a task",
+						ArgsUsage: "<id>",
+						Action:    ctlTasksCancelAction,
+					},
+				},
+			},
+			{
+				Name:  "backups",
+				Usage: "Server backups",
+				Subcommands: []*cli.Command{
+					{
+						Name:      "list",
+						Usage:     "List backups of the server",
+						ArgsUsage: "<id>",
+						Action:    ctlBackupsListAction,
+					},
+					{
+						Name:      "create",
+						Usage:     "Back up the server now",
+						ArgsUsage: "<id>",
+						Action:    ctlBackupsCreateAction,
+					},
+					{
+						Name:      "restore",
+						Usage:     "Restore the server from a backup",
+						ArgsUsage: "<id>",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name: "at",
+								Usage: "Restore the newest backup made not later than this time " +
+									"(RFC 3339 or local \"YYYY-MM-DD HH:MM:SS\"). Defaults to the newest backup",
+							},
+						},
+						Action: ctlBackupsRestoreAction,
+					},
+				},
+			},
+			{
+				Name:   "metrics",
+				Usage:  "Print the latest metrics snapshot as JSON",
+				Action: ctlMetricsAction,
+			},
+			{
+				Name:      "console",
+				Usage:     "Attach to the server console, Ctrl-D or Ctrl-C detaches",
+				ArgsUsage: "<id>",
+				Action:    ctlConsoleAction,
+			},
+		},
+	}
+}
+
+func ctlServerCommand(name, usage string) *cli.Command {
+	return &cli.Command{
+		Name:      name,
+		Usage:     usage,
+		ArgsUsage: "<id>",
+		Action: func(c *cli.Context) error {
+			client, id, err := ctlClientWithID(c)
+			if err != nil {
+				return err
+			}
+
+			result, err := client.ServerCommand(shutdownContext(c.Context), id, name)
+			if err != nil {
+				return err
+			}
+
+			if result.Output != "" {
+				fmt.Fprintln(c.App.Writer, result.Output)
+			}
+
+			if !result.Success {
+				return cli.Exit(fmt.Sprintf("server %d: %s failed", id, name), 1)
+			}
+
+			fmt.Fprintf(c.App.Writer, "server %d: %s succeeded, %s\n", id, name, processState(result.Server))
+
+			return nil
+		},
+	}
+}
+
+func ctlStatusAction(c *cli.Context) error {
+	client, err := ctlClient(c)
+	if err != nil {
+		return err
+	}
+
+	status, err := client.Status(c.Context)
+	if err != nil {
+		return err
+	}
+
+	panel := "disconnected"
+	if status.Connected {
+		panel = "connected"
+	}
+
+	w := tabwriter.NewWriter(c.App.Writer, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "Version:\t%s\n", status.Version)
+	fmt.Fprintf(w, "Uptime:\t%s\n", time.Duration(status.UptimeSeconds)*time.Second)
+	fmt.Fprintf(w, "Panel:\t%s\n", panel)
+	fmt.Fprintf(w, "Servers:\t%d\n", status.Servers)
+	fmt.Fprintf(w, "Tasks:\t%d working, %d waiting\n", status.Tasks.Working, status.Tasks.Waiting)
+
+	return w.Flush()
+}
+
+func ctlServersListAction(c *cli.Context) error {
+	client, err := ctlClient(c)
+	if err != nil {
+		return err
+	}
+
+	servers, err := client.Servers(c.Context)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(c.App.Writer, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tNAME\tADDRESS\tSTATE\tAUTOSTART")
+	for _, s := range servers {
+		fmt.Fprintf(w, "%d\t%s\t%s:%d\t%s\t%t\n", s.ID, s.Name, s.IP, s.ConnectPort, processState(s), s.AutoStart)
+	}
+
+	return w.Flush()
+}
+
+func ctlServerStatusAction(c *cli.Context) error {
+	client, id, err := ctlClientWithID(c)
+	if err != nil {
+		return err
+	}
+
+	s, err := client.Server(c.Context, id)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(c.App.Writer, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "ID:\t%d\n", s.ID)
+	fmt.Fprintf(w, "UUID:\t%s\n", s.UUID)
+	fmt.Fprintf(w, "Name:\t%s\n", s.Name)
+	fmt.Fprintf(w, "Address:\t%s:%d\n", s.IP, s.ConnectPort)
+	fmt.Fprintf(w, "State:\t%s\n", processState(s))
+	fmt.Fprintf(w, "Enabled:\t%t\n", s.Enabled)
+	fmt.Fprintf(w, "Blocked:\t%t\n", s.Blocked)
+	fmt.Fprintf(w, "Autostart:\t%t\n", s.AutoStart)
+	if s.LastStatusCheck != nil {
+		fmt.Fprintf(w, "Last check:\t%s\n", s.LastStatusCheck.Local().Format(time.DateTime))
+	}
+
+	return w.Flush()
+}
+
+func ctlTasksListAction(c *cli.Context) error {
+	client, err := ctlClient(c)
+	if err != nil {
+		return err
+	}
+
+	tasks, err := client.Tasks(c.Context)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(c.App.Writer, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tTASK\tSTATUS\tSERVER")
+	for _, t := range tasks.InProgress {
+		server := "-"
+		if t.ServerID > 0 {
+			server = strconv.Itoa(t.ServerID)
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", t.ID, t.Task, t.Status, server)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(c.App.Writer, "\n%d working, %d waiting\n", tasks.Working, tasks.Waiting)
+
+	return nil
+}
+
+func ctlTasksCancelAction(c *cli.Context) error {
+	client, id, err := ctlClientWithID(c)
+	if err != nil {
+		return err
+	}
+
+	if err := client.CancelTask(c.Context, id); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(c.App.Writer, "task %d cancelled\n", id)
+
+	return nil
+}
+
+func ctlBackupsListAction(c *cli.Context) error {
+	client, id, err := ctlClientWithID(c)
+	if err != nil {
+		return err
+	}
+
+	list, err := client.Backups(c.Context, id)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(c.App.Writer, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tCREATED\tSIZE")
+	for _, b := range list {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", b.Name, b.CreatedAt.Local().Format(time.DateTime), formatSize(b.Size))
+	}
+
+	return w.Flush()
+}
+
+func ctlBackupsCreateAction(c *cli.Context) error {
+	client, id, err := ctlClientWithID(c)
+	if err != nil {
+		return err
+	}
+
+	backup, err := client.Backup(shutdownContext(c.Context), id)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(c.App.Writer, "server %d: backup %s created, %s\n", id, backup.Name, formatSize(backup.Size))
+
+	return nil
+}
+
+func ctlBackupsRestoreAction(c *cli.Context) error {
+	var at time.Time
+	if v := c.String("at"); v != "" {
+		var err error
+		if at, err = parseCtlTime(v); err != nil {
+			return err
+		}
+	}
+
+	client, id, err := ctlClientWithID(c)
+	if err != nil {
+		return err
+	}
+
+	backup, err := client.Restore(shutdownContext(c.Context), id, at)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(
+		c.App.Writer, "server %d: restored from backup %s made at %s\n",
+		id, backup.Name, backup.CreatedAt.Local().Format(time.DateTime),
+	)
+
+	return nil
+}
+
+func parseCtlTime(v string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+
+	t, err := time.ParseInLocation(time.DateTime, v, time.Local)
+	if err != nil {
+		return time.Time{}, errors.Wrapf(errInvalidTime, "got %q", v)
+	}
+
+	return t, nil
+}
+
+func formatSize(size int64) string {
+	const unit = 1024
+
+	if size < unit {
+		return strconv.FormatInt(size, 10) + " B"
+	}
+
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
+
+func ctlMetricsAction(c *cli.Context) error {
+	client, err := ctlClient(c)
+	if err != nil {
+		return err
+	}
+
+	metrics, err := client.Metrics(c.Context)
+	if err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(c.App.Writer)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(metrics)
+}
+
+func ctlConsoleAction(c *cli.Context) error {
+	client, id, err := ctlClientWithID(c)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(c.App.ErrWriter, "Attached to server %d, press Ctrl-D or Ctrl-C to detach\n", id)
+
+	err = client.Console(shutdownContext(c.Context), id, os.Stdin, c.App.Writer)
+
+	fmt.Fprintln(c.App.ErrWriter, "Detached")
+
+	return err
+}
+
+func ctlClient(c *cli.Context) (*adminapi.Client, error) {
+	socket := c.String("socket")
+	if socket == "" {
+		cfg, err := config.Load(c.String("config"))
+		if err != nil {
+			return nil, err
+		}
+
+		if !cfg.AdminAPI.Enabled || cfg.AdminAPI.Socket == "" {
+			return nil, errAdminAPIDisabled
+		}
+
+		socket = cfg.AdminAPI.Socket
+	}
+
+	return adminapi.NewClient(socket), nil
+}
+
+func ctlClientWithID(c *cli.Context) (*adminapi.Client, int, error) {
+	id, err := strconv.Atoi(c.Args().First())
+	if err != nil || id <= 0 {
+		return nil, 0, errors.Wrapf(errInvalidID, "got %q", c.Args().First())
+	}
+
+	client, err := ctlClient(c)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return client, id, nil
+}
+
+func processState(s adminapi.ServerView) string {
+	switch {
+	case !s.Installed:
+		return "not installed"
+	case s.CrashLooping:
+		return "crash looping"
+	case s.Active && s.State != "":
+		return s.State
+	case s.Active:
+		return "running"
+	default:
+		return "stopped"
+	}
+}
@@ -8,12 +8,17 @@ import (
 	"context"
 	"sync"
 
+	"github.com/gameap/daemon/internal/app/adminapi"
+	"github.com/gameap/daemon/internal/app/backups"
 	"github.com/gameap/daemon/internal/app/config"
 	"github.com/gameap/daemon/internal/app/di/internal"
 	"github.com/gameap/daemon/internal/app/domain"
 	grpcclient "github.com/gameap/daemon/internal/app/grpc"
+	"github.com/gameap/daemon/internal/app/liveness"
 	"github.com/gameap/daemon/internal/app/metrics"
+	"github.com/gameap/daemon/internal/app/query"
 	"github.com/gameap/daemon/internal/app/services"
+	sftpserver "github.com/gameap/daemon/internal/app/sftp_server"
 	"github.com/sirupsen/logrus"
 )
 
@@ -138,6 +143,71 @@ func (c *Container) MetricsService(ctx context.Context) (*metrics.Service, error
 	return s, err
 }
 
+func (c *Container) AdminAPIServer(ctx context.Context) (*adminapi.Server, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	s := c.c.AdminAPIServer(ctx)
+	err := c.c.Error()
+	if err != nil {
+		return nil, err
+	}
+
+	return s, err
+}
+
+func (c *Container) BackupService(ctx context.Context) (*backups.Service, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	s := c.c.BackupService(ctx)
+	err := c.c.Error()
+	if err != nil {
+		return nil, err
+	}
+
+	return s, err
+}
+
+func (c *Container) QueryService(ctx context.Context) (*query.Service, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	s := c.c.QueryService(ctx)
+	err := c.c.Error()
+	if err != nil {
+		return nil, err
+	}
+
+	return s, err
+}
+
+func (c *Container) HealthService(ctx context.Context) (*liveness.Service, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	s := c.c.HealthService(ctx)
+	err := c.c.Error()
+	if err != nil {
+		return nil, err
+	}
+
+	return s, err
+}
+
+func (c *Container) SFTPServer(ctx context.Context) (*sftpserver.Server, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	s := c.c.SFTPServer(ctx)
+	err := c.c.Error()
+	if err != nil {
+		return nil, err
+	}
+
+	return s, err
+}
+
 func (c *Container) Close() {
 	c.mu.Lock()
 	defer c.mu.Unlock()
@@ -3,12 +3,20 @@ package internal
 import (
 	"context"
 
+	"github.com/gameap/daemon/internal/app/adminapi"
+	"github.com/gameap/daemon/internal/app/backups"
 	"github.com/gameap/daemon/internal/app/config"
 	"github.com/gameap/daemon/internal/app/contracts"
 	gameservercommands "github.com/gameap/daemon/internal/app/game_server_commands"
 	grpcclient "github.com/gameap/daemon/internal/app/grpc"
+	"github.com/gameap/daemon/internal/app/liveness"
 	"github.com/gameap/daemon/internal/app/metrics"
+	"github.com/gameap/daemon/internal/app/query"
+	"github.com/gameap/daemon/internal/app/rcon"
+	"github.com/gameap/daemon/internal/app/readiness"
+	"github.com/gameap/daemon/internal/app/serverlock"
 	"github.com/gameap/daemon/internal/app/services"
+	sftpserver "github.com/gameap/daemon/internal/app/sftp_server"
 	"github.com/sirupsen/logrus"
 
 	"github.com/gameap/daemon/internal/app/di/internal/definitions"
@@ -33,6 +41,12 @@ type Container struct {
 	serverStatusReporter *grpcclient.ServerStatusReporter
 	metricsService       *metrics.Service
 	serversScheduler     *serversscheduler.Scheduler
+	adminAPIServer       *adminapi.Server
+	backupService        *backups.Service
+	queryService         *query.Service
+	readinessChecker     *readiness.Checker
+	healthService        *liveness.Service
+	sftpServer           *sftpserver.Server
 
 	services     *ServicesContainer
 	repositories *RepositoryContainer
@@ -65,6 +79,8 @@ type ServicesContainer struct {
 	executor           contracts.Executor
 	extendableExecutor contracts.Executor
 	processManager     contracts.ProcessManager
+	serverConsole      *rcon.Console
+	serverLocks        *serverlock.Locks
 	gdTaskManager      *gdaemonscheduler.TaskManager
 }
 
@@ -103,23 +119,23 @@ func (c *Container) ServerCommandFactory(ctx context.Context) *gameservercommand
 	return c.serverCommandFactory
 }
 
-func (c *Container) GameStore() *grpcclient.GameStore {
+func (c *Container) GameStore(ctx context.Context) *grpcclient.GameStore {
 	if c.gameStore == nil {
-		c.gameStore = definitions.CreateGameStore()
+		c.gameStore = definitions.CreateGameStore(ctx, c)
 	}
 	return c.gameStore
 }
 
 func (c *Container) GatewayClient(ctx context.Context) *grpcclient.GatewayClient {
 	if c.gatewayClient == nil && c.err == nil {
-		c.gatewayClient = definitions.CreateGatewayClient(ctx, c, c.GameStore())
+		c.gatewayClient = definitions.CreateGatewayClient(ctx, c, c.GameStore(ctx))
 	}
 	return c.gatewayClient
 }
 
 func (c *Container) ConnectionManager(ctx context.Context) *grpcclient.ConnectionManager {
 	if c.connectionManager == nil && c.err == nil {
-		c.connectionManager = definitions.CreateConnectionManager(ctx, c, c.GameStore(), c.GatewayClient(ctx))
+		c.connectionManager = definitions.CreateConnectionManager(ctx, c, c.GameStore(ctx), c.GatewayClient(ctx))
 	}
 	return c.connectionManager
 }
@@ -146,6 +162,48 @@ func (c *Container) MetricsService(ctx context.Context) *metrics.Service {
 	return c.metricsService
 }
 
+func (c *Container) AdminAPIServer(ctx context.Context) *adminapi.Server {
+	if c.adminAPIServer == nil && c.err == nil {
+		c.adminAPIServer = definitions.CreateAdminAPIServer(ctx, c, c.ConnectionManager(ctx))
+	}
+	return c.adminAPIServer
+}
+
+func (c *Container) BackupService(ctx context.Context) *backups.Service {
+	if c.backupService == nil && c.err == nil {
+		c.backupService = definitions.CreateBackupService(ctx, c)
+	}
+	return c.backupService
+}
+
+func (c *Container) QueryService(ctx context.Context) *query.Service {
+	if c.queryService == nil && c.err == nil {
+		c.queryService = definitions.CreateQueryService(ctx, c)
+	}
+	return c.queryService
+}
+
+func (c *Container) ReadinessChecker(ctx context.Context) *readiness.Checker {
+	if c.readinessChecker == nil && c.err == nil {
+		c.readinessChecker = definitions.CreateReadinessChecker(ctx, c)
+	}
+	return c.readinessChecker
+}
+
+func (c *Container) HealthService(ctx context.Context) *liveness.Service {
+	if c.healthService == nil && c.err == nil {
+		c.healthService = definitions.CreateHealthService(ctx, c)
+	}
+	return c.healthService
+}
+
+func (c *Container) SFTPServer(ctx context.Context) *sftpserver.Server {
+	if c.sftpServer == nil && c.err == nil {
+		c.sftpServer = definitions.CreateSFTPServer(ctx, c)
+	}
+	return c.sftpServer
+}
+
 func (c *Container) ServersScheduler(_ context.Context) *serversscheduler.Scheduler {
 	return c.serversScheduler
 }
@@ -180,6 +238,22 @@ func (c *ServicesContainer) ProcessManager(ctx context.Context) contracts.Proces
 	return c.processManager
 }
 
+func (c *ServicesContainer) ServerConsole(ctx context.Context) *rcon.Console {
+	if c.serverConsole == nil && c.err == nil {
+		c.serverConsole = definitions.CreateServicesServerConsole(ctx, c)
+	}
+
+	return c.serverConsole
+}
+
+func (c *ServicesContainer) ServerLocks(ctx context.Context) *serverlock.Locks {
+	if c.serverLocks == nil && c.err == nil {
+		c.serverLocks = definitions.CreateServicesServerLocks(ctx, c)
+	}
+
+	return c.serverLocks
+}
+
 func (c *ServicesContainer) GdTaskManager(ctx context.Context) *gdaemonscheduler.TaskManager {
 	if c.gdTaskManager == nil && c.err == nil {
 		c.gdTaskManager = definitions.CreateServicesGdTaskManager(ctx, c)
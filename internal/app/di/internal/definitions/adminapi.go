@@ -0,0 +1,34 @@
+package definitions
+
+import (
+	"context"
+
+	"github.com/gameap/daemon/internal/app/adminapi"
+	grpcclient "github.com/gameap/daemon/internal/app/grpc"
+)
+
+func CreateAdminAPIServer(
+	ctx context.Context,
+	c Container,
+	connectionManager *grpcclient.ConnectionManager,
+) *adminapi.Server {
+	cfg := c.Cfg(ctx)
+
+	server := adminapi.NewServer(
+		cfg,
+		c.Repositories().ServerRepository(ctx),
+		c.ServerCommandFactory(ctx),
+		c.Services().GdTaskManager(ctx),
+		c.Services().ServerConsole(ctx),
+		connectionManager,
+	)
+
+	server.SetBackupManager(c.BackupService(ctx))
+	server.SetServerLocks(c.Services().ServerLocks(ctx))
+
+	if cfg.Metrics.IsEnabled() {
+		server.SetMetricsProvider(c.MetricsService(ctx))
+	}
+
+	return server
+}
@@ -0,0 +1,19 @@
+package definitions
+
+import (
+	"context"
+
+	"github.com/gameap/daemon/internal/app/backups"
+)
+
+func CreateBackupService(ctx context.Context, c Container) *backups.Service {
+	service := backups.NewService(
+		c.Cfg(ctx),
+		c.Repositories().ServerRepository(ctx),
+		c.ServerCommandFactory(ctx),
+		c.Services().ServerConsole(ctx),
+	)
+	service.SetServerLocks(c.Services().ServerLocks(ctx))
+
+	return service
+}
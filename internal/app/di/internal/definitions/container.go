@@ -14,12 +14,15 @@ func CreateProcessRunner(ctx context.Context, c Container) *services.Runner {
 		c.ServerCommandFactory(ctx),
 		c.Services().GdTaskManager(ctx),
 		c.Repositories().ServerRepository(ctx),
+		c.Services().ProcessManager(ctx),
 	)
 	if err != nil {
 		c.SetError(err)
 		return nil
 	}
 
+	processRunner.SetReadinessChecker(c.ReadinessChecker(ctx))
+
 	return processRunner
 }
 
@@ -34,10 +37,13 @@ func CreateCacheManager(ctx context.Context, c Container) contracts.Cache {
 }
 
 func CreateServerCommandFactory(ctx context.Context, c Container) *gameservercommands.ServerCommandFactory {
-	return gameservercommands.NewFactory(
+	factory := gameservercommands.NewFactory(
 		c.Cfg(ctx),
 		c.Repositories().ServerRepository(ctx),
 		c.Services().Executor(ctx),
 		c.Services().ProcessManager(ctx),
 	)
+	factory.SetReadinessTracker(c.ReadinessChecker(ctx))
+
+	return factory
 }
@@ -6,10 +6,16 @@ package definitions
 
 import (
 	"context"
+	"github.com/gameap/daemon/internal/app/backups"
 	"github.com/gameap/daemon/internal/app/config"
 	"github.com/gameap/daemon/internal/app/contracts"
 	gameservercommands "github.com/gameap/daemon/internal/app/game_server_commands"
+	"github.com/gameap/daemon/internal/app/liveness"
 	"github.com/gameap/daemon/internal/app/metrics"
+	"github.com/gameap/daemon/internal/app/query"
+	"github.com/gameap/daemon/internal/app/rcon"
+	"github.com/gameap/daemon/internal/app/readiness"
+	"github.com/gameap/daemon/internal/app/serverlock"
 	"github.com/gameap/daemon/internal/app/services"
 	"github.com/sirupsen/logrus"
 
@@ -28,6 +34,10 @@ type Container interface {
 	CacheManager(ctx context.Context) contracts.Cache
 	ServerCommandFactory(ctx context.Context) *gameservercommands.ServerCommandFactory
 	MetricsService(ctx context.Context) *metrics.Service
+	BackupService(ctx context.Context) *backups.Service
+	QueryService(ctx context.Context) *query.Service
+	ReadinessChecker(ctx context.Context) *readiness.Checker
+	HealthService(ctx context.Context) *liveness.Service
 
 	SetServersScheduler(s *serversscheduler.Scheduler)
 
@@ -40,6 +50,8 @@ type ServicesContainer interface {
 	ExtendableExecutor(ctx context.Context) contracts.Executor
 	GdTaskManager(ctx context.Context) *gdaemonscheduler.TaskManager
 	ProcessManager(ctx context.Context) contracts.ProcessManager
+	ServerConsole(ctx context.Context) *rcon.Console
+	ServerLocks(ctx context.Context) *serverlock.Locks
 }
 
 type RepositoryContainer interface {
@@ -2,14 +2,32 @@ package definitions
 
 import (
 	"context"
+	"path/filepath"
 
 	grpcclient "github.com/gameap/daemon/internal/app/grpc"
 	"github.com/gameap/daemon/internal/app/repositories"
 	serversscheduler "github.com/gameap/daemon/internal/app/servers_scheduler"
+	"github.com/gameap/daemon/internal/app/steam"
+	"github.com/gameap/daemon/pkg/logger"
+	"github.com/pkg/errors"
 )
 
-func CreateGameStore() *grpcclient.GameStore {
-	return grpcclient.NewGameStore()
+func CreateGameStore(ctx context.Context, c Container) *grpcclient.GameStore {
+	cfg := c.Cfg(ctx)
+	if cfg.StatePath == "" {
+		return grpcclient.NewGameStore()
+	}
+
+	gameStore := grpcclient.NewPersistentGameStore(filepath.Join(cfg.StatePath, grpcclient.GameStoreFileName))
+
+	restored, err := gameStore.Restore()
+	if err != nil {
+		logger.Warn(ctx, errors.WithMessage(err, "failed to restore games state"))
+	} else if restored > 0 {
+		logger.Infof(ctx, "Restored %d games and game mods from the local state", restored)
+	}
+
+	return gameStore
 }
 
 func CreateGatewayClient(ctx context.Context, c Container, gameStore *grpcclient.GameStore) *grpcclient.GatewayClient {
@@ -78,7 +96,7 @@ func CreateConnectionManager(
 	serverRepo := c.Repositories().ServerRepository(ctx).(*repositories.ServerRepository)
 	attachHandler := grpcclient.NewGRPCAttachHandler(
 		serverRepo,
-		c.Services().ProcessManager(ctx),
+		c.Services().ServerConsole(ctx),
 		client,
 	)
 	client.SetAttachHandler(attachHandler)
@@ -101,10 +119,29 @@ func CreateConnectionManager(
 
 	scheduler := serversscheduler.NewScheduler(
 		cfg,
-		c.ServerCommandFactory(ctx),
+		c.ServerCommandFactory(ctx).WithCountdown(c.Services().ServerConsole(ctx)),
 		serverRepo,
 		client,
 	)
+	scheduler.SetReadinessWaiter(c.ReadinessChecker(ctx))
+	scheduler.SetServerLocks(c.Services().ServerLocks(ctx))
+	scheduler.SetConditionSources(serversscheduler.ConditionSources{
+		Players: c.QueryService(ctx),
+		Updates: steam.NewUpdateChecker(cfg, c.Services().Executor(ctx)),
+		Metrics: c.Services().ProcessManager(ctx),
+	})
+	scheduler.SetPipelineServices(serversscheduler.PipelineServices{
+		Console:  c.Services().ServerConsole(ctx),
+		Backups:  c.BackupService(ctx),
+		Executor: c.Services().Executor(ctx),
+		Commands: c.ServerCommandFactory(ctx),
+	})
+	restored, err := scheduler.Restore(ctx)
+	if err != nil {
+		logger.Warn(ctx, errors.WithMessage(err, "failed to restore server tasks state"))
+	} else if restored > 0 {
+		logger.Infof(ctx, "Restored %d server tasks from the local state", restored)
+	}
 	client.SetServerTaskFlow(scheduler)
 	c.SetServersScheduler(scheduler)
 	c.ProcessRunner(ctx).SetServersScheduler(scheduler)
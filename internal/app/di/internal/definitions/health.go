@@ -0,0 +1,23 @@
+package definitions
+
+import (
+	"context"
+
+	"github.com/gameap/daemon/internal/app/liveness"
+	"github.com/gameap/daemon/internal/app/repositories"
+)
+
+func CreateHealthService(ctx context.Context, c Container) *liveness.Service {
+	cfg := c.Cfg(ctx)
+	serverRepo := c.Repositories().ServerRepository(ctx).(*repositories.ServerRepository)
+
+	return liveness.NewService(
+		serverRepo,
+		c.Services().ServerConsole(ctx),
+		c.ServerCommandFactory(ctx),
+		c.Services().ServerLocks(ctx),
+		cfg.Health.Interval,
+		cfg.Health.Timeout,
+		cfg.Health.FailureThreshold,
+	)
+}
@@ -17,13 +17,25 @@ func CreateMetricsService(ctx context.Context, c Container) *metrics.Service {
 
 	nodeCollector := metrics.NewNodeMetricsCollector(cfg)
 	serversCollector := metrics.NewServersMetricsCollector(serverRepo, pm)
+	statesCollector := metrics.NewServerStatesCollector(serverRepo)
 
-	return metrics.NewService(
+	service := metrics.NewService(
 		buffer,
 		cfg.Metrics.CollectionInterval,
 		nodeCollector,
 		serversCollector,
+		statesCollector,
 	)
+
+	if cfg.Query.IsEnabled() {
+		service.AddCollector(c.QueryService(ctx))
+	}
+
+	if cfg.Health.IsEnabled() {
+		service.AddCollector(c.HealthService(ctx))
+	}
+
+	return service
 }
 
 // AttachMetricsHandler wires the metrics service into the gateway client.
@@ -0,0 +1,15 @@
+package definitions
+
+import (
+	"context"
+
+	"github.com/gameap/daemon/internal/app/query"
+	"github.com/gameap/daemon/internal/app/repositories"
+)
+
+func CreateQueryService(ctx context.Context, c Container) *query.Service {
+	cfg := c.Cfg(ctx)
+	serverRepo := c.Repositories().ServerRepository(ctx).(*repositories.ServerRepository)
+
+	return query.NewService(serverRepo, cfg.Query.Interval, cfg.Query.Timeout)
+}
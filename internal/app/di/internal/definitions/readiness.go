@@ -0,0 +1,13 @@
+package definitions
+
+import (
+	"context"
+
+	"github.com/gameap/daemon/internal/app/readiness"
+)
+
+func CreateReadinessChecker(ctx context.Context, c Container) *readiness.Checker {
+	cfg := c.Cfg(ctx)
+
+	return readiness.NewChecker(c.Services().ProcessManager(ctx), cfg.Readiness.Timeout, cfg.Query.Timeout)
+}
@@ -2,11 +2,30 @@ package definitions
 
 import (
 	"context"
+	"path/filepath"
 
 	"github.com/gameap/daemon/internal/app/domain"
 	"github.com/gameap/daemon/internal/app/repositories"
+	"github.com/gameap/daemon/pkg/logger"
+	"github.com/pkg/errors"
 )
 
-func CreateRepositoriesServerRepository(_ context.Context, _ Container) domain.ServerRepository {
-	return repositories.NewServerRepository()
+func CreateRepositoriesServerRepository(ctx context.Context, c Container) domain.ServerRepository {
+	cfg := c.Cfg(ctx)
+	if cfg.StatePath == "" {
+		return repositories.NewServerRepository()
+	}
+
+	repo := repositories.NewPersistentServerRepository(
+		filepath.Join(cfg.StatePath, repositories.ServerStateFileName),
+	)
+
+	restored, err := repo.Restore(ctx)
+	if err != nil {
+		logger.Warn(ctx, errors.WithMessage(err, "failed to restore servers state, starting with an empty state"))
+	} else if restored > 0 {
+		logger.Infof(ctx, "Restored %d servers from the local state", restored)
+	}
+
+	return repo
 }
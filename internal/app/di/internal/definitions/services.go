@@ -7,6 +7,8 @@ import (
 	"github.com/gameap/daemon/internal/app/components/customhandlers"
 	"github.com/gameap/daemon/internal/app/contracts"
 	gdaemonscheduler "github.com/gameap/daemon/internal/app/gdaemon_scheduler"
+	"github.com/gameap/daemon/internal/app/rcon"
+	"github.com/gameap/daemon/internal/app/serverlock"
 	"github.com/gameap/daemon/internal/processmanager"
 )
 
@@ -29,7 +31,15 @@ func CreateServiceExtendableExecutor(ctx context.Context, c Container) contracts
 	executor.RegisterHandler(
 		"server-command",
 		customhandlers.NewCommandSender(
-			c.Services().ProcessManager(ctx),
+			c.Services().ServerConsole(ctx),
+			c.Repositories().ServerRepository(ctx),
+		).Handle,
+	)
+
+	executor.RegisterHandler(
+		"server-query",
+		customhandlers.NewServerQuery(
+			c.QueryService(ctx),
 			c.Repositories().ServerRepository(ctx),
 		).Handle,
 	)
@@ -52,11 +62,26 @@ func CreateServicesProcessManager(ctx context.Context, c Container) contracts.Pr
 	return pm
 }
 
+// CreateServicesServerConsole wraps the process manager for console input
+// and attach sessions, servers with RCON enabled are reached over RCON.
+func CreateServicesServerConsole(ctx context.Context, c Container) *rcon.Console {
+	return rcon.NewConsole(c.Services().ProcessManager(ctx))
+}
+
+// CreateServicesServerLocks creates the per-server locks shared by the panel
+// tasks, the scheduled server tasks and the admin API.
+func CreateServicesServerLocks(_ context.Context, _ Container) *serverlock.Locks {
+	return serverlock.New()
+}
+
 func CreateServicesGdTaskManager(ctx context.Context, c Container) *gdaemonscheduler.TaskManager {
-	return gdaemonscheduler.NewTaskManager(
+	manager := gdaemonscheduler.NewTaskManager(
 		c.CacheManager(ctx),
-		c.ServerCommandFactory(ctx),
+		c.ServerCommandFactory(ctx).WithCountdown(c.Services().ServerConsole(ctx)),
 		c.Services().ExtendableExecutor(ctx),
 		c.Cfg(ctx),
 	)
+	manager.SetServerLocks(c.Services().ServerLocks(ctx))
+
+	return manager
 }
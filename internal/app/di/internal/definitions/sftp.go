@@ -0,0 +1,11 @@
+package definitions
+
+import (
+	"context"
+
+	sftpserver "github.com/gameap/daemon/internal/app/sftp_server"
+)
+
+func CreateSFTPServer(ctx context.Context, c Container) *sftpserver.Server {
+	return sftpserver.NewServer(c.Cfg(ctx), c.Repositories().ServerRepository(ctx))
+}
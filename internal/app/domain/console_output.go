@@ -0,0 +1,34 @@
+package domain
+
+import (
+	"bufio"
+	"bytes"
+	"slices"
+)
+
+// ConsoleLines splits the console output of a server into lines.
+func ConsoleLines(output []byte) []string {
+	var lines []string
+
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+
+	return lines
+}
+
+// NewConsoleLines returns the lines of after that follow the output of
+// before. Consoles keep a limited scrollback, so the oldest lines of before
+// may be gone from after. Lines are compared by position: a line equal to
+// an earlier one is still new.
+func NewConsoleLines(before, after []string) []string {
+	for skip := range before {
+		kept := before[skip:]
+		if len(kept) <= len(after) && slices.Equal(kept, after[:len(kept)]) {
+			return after[len(kept):]
+		}
+	}
+
+	return after
+}
@@ -0,0 +1,28 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewConsoleLines(t *testing.T) {
+	tests := []struct {
+		name     string
+		before   []string
+		after    []string
+		expected []string
+	}{
+		{name: "appended", before: []string{"a", "pong"}, after: []string{"a", "pong", "pong"}, expected: []string{"pong"}},
+		{name: "unchanged", before: []string{"a", "pong"}, after: []string{"a", "pong"}, expected: []string{}},
+		{name: "scrolled", before: []string{"a", "pong"}, after: []string{"pong", "pong"}, expected: []string{"pong"}},
+		{name: "cleared", before: []string{"a"}, after: []string{"pong"}, expected: []string{"pong"}},
+		{name: "empty before", before: nil, after: []string{"pong"}, expected: []string{"pong"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, NewConsoleLines(tt.before, tt.after))
+		})
+	}
+}
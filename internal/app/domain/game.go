@@ -93,6 +93,18 @@ func (g *GameModVarTemplate) UnmarshalJSON(bytes []byte) error {
 	return nil
 }
 
+// MarshalJSON writes the template in the same shape the panel sends it, so
+// it can be read back with UnmarshalJSON.
+func (g GameModVarTemplate) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Var     string `json:"var"`
+		Default string `json:"default"`
+	}{
+		Var:     g.Key,
+		Default: g.DefaultValue,
+	})
+}
+
 type GameMod struct {
 	Name                   string               `json:"name"`
 	RemoteRepository       string               `json:"remote_repository"`
@@ -26,9 +26,10 @@ type GDTaskCommand string
 
 const (
 	GDTaskGameServerStart     GDTaskCommand = "gsstart"
-	GDTaskGameServerPause     GDTaskCommand = "gspause" // NOT Implemented
+	GDTaskGameServerPause     GDTaskCommand = "gspause"
+	GDTaskGameServerUnpause   GDTaskCommand = "gsunpause"
 	GDTaskGameServerStop      GDTaskCommand = "gsstop"
-	GDTaskGameServerKill      GDTaskCommand = "gskill" // NOT Implemented
+	GDTaskGameServerKill      GDTaskCommand = "gskill"
 	GDTaskGameServerRestart   GDTaskCommand = "gsrest"
 	GDTaskGameServerInstall   GDTaskCommand = "gsinst"
 	GDTaskGameServerReinstall GDTaskCommand = "gsreinst" // NOT Implemented
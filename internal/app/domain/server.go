@@ -2,6 +2,8 @@ package domain
 
 import (
 	"context"
+	"fmt"
+	"net"
 	"path/filepath"
 	"strconv"
 	"strings"
@@ -59,6 +61,7 @@ type Settings map[string]string
 type Server struct {
 	lastProcessCheck    time.Time
 	lastTaskCompletedAt time.Time
+	runStateChangedAt   time.Time
 	updatedAt           time.Time
 	mu                  *sync.RWMutex
 	changeset           *hashset.Set
@@ -77,6 +80,7 @@ type Server struct {
 	name                string
 	game                Game
 	gameMod             GameMod
+	runState            RunState
 	ramLimit            int64 // bytes
 	id                  int
 	connectPort         int
@@ -87,6 +91,7 @@ type Server struct {
 	processActive       bool
 	enabled             bool
 	blocked             bool
+	crashLooping        bool
 }
 
 func NewServer(
@@ -140,6 +145,7 @@ func NewServer(
 		forceStopCommand:    forceStopCommand,
 		restartCommand:      restartCommand,
 		processActive:       processActive,
+		runState:            runStateFromProcess(processActive, false),
 		lastProcessCheck:    lastProcessCheck,
 		vars:                vars,
 		settings:            settings,
@@ -232,6 +238,13 @@ func (s *Server) Blocked() bool {
 	return s.blocked
 }
 
+func (s *Server) Name() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.name
+}
+
 func (s *Server) UUID() string {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
@@ -326,6 +339,46 @@ func (s *Server) Vars() map[string]string {
 	return vars
 }
 
+// LookupVar returns the value of the key with priority:
+// 1. Server vars
+// 2. GameMod metadata
+// 3. Game metadata
+func (s *Server) LookupVar(key string) (string, bool) {
+	if val, ok := s.Vars()[key]; ok && val != "" {
+		return val, true
+	}
+
+	if val, ok := metadataValue(s.GameMod().Metadata, key); ok {
+		return val, true
+	}
+
+	return metadataValue(s.Game().Metadata, key)
+}
+
+// metadataValue returns the metadata value as a string, the panel sends
+// booleans and numbers as they are.
+func metadataValue(metadata map[string]any, key string) (string, bool) {
+	val, ok := metadata[key]
+	if !ok || val == nil {
+		return "", false
+	}
+
+	str := fmt.Sprint(val)
+
+	return str, str != ""
+}
+
+// DialAddress returns the host:port the daemon connects to the server port
+// on. Servers listening on all interfaces are reached over the loopback.
+func (s *Server) DialAddress(port int) string {
+	host := s.IP()
+	if ip := net.ParseIP(host); host == "" || (ip != nil && ip.IsUnspecified()) {
+		host = "127.0.0.1"
+	}
+
+	return net.JoinHostPort(host, strconv.Itoa(port))
+}
+
 func (s *Server) EnvironmentVars() map[string]string {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
@@ -420,6 +473,13 @@ func (s *Server) StopCommand() string {
 	return s.stopCommand
 }
 
+func (s *Server) ForceStopCommand() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.forceStopCommand
+}
+
 func (s *Server) RestartCommand() string {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
@@ -471,6 +531,15 @@ func (s *Server) SetStatus(processActive bool) {
 	s.lastProcessCheck = time.Now()
 	s.setValueIsChanged("status")
 
+	// A starting, unhealthy or paused server keeps its state while the
+	// process runs.
+	switch {
+	case !processActive:
+		s.setRunState(RunStateStopped)
+	case s.runState == RunStateStopped:
+		s.setRunState(RunStateRunning)
+	}
+
 	s.updatedAt = time.Now()
 }
 
@@ -507,6 +576,13 @@ func (s *Server) AffectStart() {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	if s.crashLooping {
+		s.crashLooping = false
+		s.setValueIsChanged("crashLooping")
+	}
+
+	s.setRunState(RunStateStarting)
+
 	autostart := s.readBoolSetting(s.setting(autostartSettingKey))
 	if autostart {
 		s.setSetting(autostartCurrentSettingKey, "1")
@@ -554,6 +630,25 @@ func (s *Server) IsActive() bool {
 	return s.processActive
 }
 
+// IsCrashLooping reports whether autostart of the server is suspended
+// because it crashed too many times in a row. It is cleared when the server
+// is started with the start command.
+func (s *Server) IsCrashLooping() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.crashLooping
+}
+
+func (s *Server) SetCrashLooping(crashLooping bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.crashLooping = crashLooping
+	s.setValueIsChanged("crashLooping")
+	s.updatedAt = time.Now()
+}
+
 func (s *Server) LastStatusCheck() time.Time {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
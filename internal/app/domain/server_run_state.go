@@ -0,0 +1,101 @@
+package domain
+
+import "time"
+
+// RunState is the lifecycle state of a running server. A started server is
+// starting until its readiness probes pass, the state of a server that was
+// already running when the daemon found it is running right away.
+type RunState string
+
+const (
+	RunStateStopped  RunState = "stopped"
+	RunStateStarting RunState = "starting"
+	RunStateRunning  RunState = "running"
+	// RunStateUnhealthy is a server whose process is running but which did
+	// not become ready in time.
+	RunStateUnhealthy RunState = "unhealthy"
+	// RunStatePaused is a server whose processes are frozen by the pause
+	// command, it does not answer probes until it is unpaused.
+	RunStatePaused RunState = "paused"
+)
+
+func runStateFromProcess(processActive, paused bool) RunState {
+	switch {
+	case processActive && paused:
+		return RunStatePaused
+	case processActive:
+		return RunStateRunning
+	default:
+		return RunStateStopped
+	}
+}
+
+func (s *Server) RunState() RunState {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.runState
+}
+
+// RunStateChangedAt is the time the server entered its current run state.
+func (s *Server) RunStateChangedAt() time.Time {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.runStateChangedAt
+}
+
+func (s *Server) SetRunState(state RunState) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.setRunState(state)
+}
+
+func (s *Server) setRunState(state RunState) {
+	if s.runState == state {
+		return
+	}
+
+	s.runState = state
+	s.runStateChangedAt = time.Now()
+	s.setValueIsChanged("runState")
+	s.updatedAt = time.Now()
+}
+
+// IsReady reports whether the server process is running and the server
+// passed its readiness probes.
+func (s *Server) IsReady() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.processActive && s.runState == RunStateRunning
+}
+
+// IsPaused reports whether the processes of the server are frozen.
+func (s *Server) IsPaused() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.runState == RunStatePaused
+}
+
+// AffectPause records that the processes of the running server are frozen.
+func (s *Server) AffectPause() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.processActive {
+		s.setRunState(RunStatePaused)
+	}
+}
+
+// AffectUnpause records that the processes of the paused server run again.
+func (s *Server) AffectUnpause() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.runState == RunStatePaused {
+		s.setRunState(RunStateRunning)
+	}
+}
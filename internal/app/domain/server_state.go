@@ -0,0 +1,131 @@
+package domain
+
+import (
+	"sync"
+	"time"
+
+	"github.com/emirpasic/gods/sets/hashset"
+)
+
+// ServerState is a serializable copy of a Server. It is what the daemon keeps
+// on disk to restore servers after a restart, before the panel pushes them
+// again.
+type ServerState struct {
+	ID                  int                `json:"id"`
+	Enabled             bool               `json:"enabled"`
+	InstallStatus       InstallationStatus `json:"install_status"`
+	Blocked             bool               `json:"blocked"`
+	Name                string             `json:"name"`
+	UUID                string             `json:"uuid"`
+	UUIDShort           string             `json:"uuid_short"`
+	Game                Game               `json:"game"`
+	GameMod             GameMod            `json:"game_mod"`
+	IP                  string             `json:"ip"`
+	ConnectPort         int                `json:"connect_port"`
+	QueryPort           int                `json:"query_port"`
+	RCONPort            int                `json:"rcon_port"`
+	RCONPassword        string             `json:"rcon_password"`
+	Dir                 string             `json:"dir"`
+	User                string             `json:"user"`
+	StartCommand        string             `json:"start_command"`
+	StopCommand         string             `json:"stop_command"`
+	ForceStopCommand    string             `json:"force_stop_command"`
+	RestartCommand      string             `json:"restart_command"`
+	ProcessActive       bool               `json:"process_active"`
+	CrashLooping        bool               `json:"crash_looping"`
+	Paused              bool               `json:"paused"`
+	LastProcessCheck    time.Time          `json:"last_process_check"`
+	LastTaskCompletedAt time.Time          `json:"last_task_completed_at"`
+	Vars                map[string]string  `json:"vars"`
+	Settings            Settings           `json:"settings"`
+	UpdatedAt           time.Time          `json:"updated_at"`
+	CPULimit            int                `json:"cpu_limit"`
+	RAMLimit            int64              `json:"ram_limit"`
+}
+
+func NewServerFromState(state ServerState) *Server {
+	settings := make(Settings, len(state.Settings))
+	for k, v := range state.Settings {
+		settings[k] = v
+	}
+
+	return &Server{
+		id:                  state.ID,
+		enabled:             state.Enabled,
+		installStatus:       state.InstallStatus,
+		blocked:             state.Blocked,
+		name:                state.Name,
+		uuid:                state.UUID,
+		uuidShort:           state.UUIDShort,
+		game:                state.Game,
+		gameMod:             state.GameMod,
+		ip:                  state.IP,
+		connectPort:         state.ConnectPort,
+		queryPort:           state.QueryPort,
+		rconPort:            state.RCONPort,
+		rconPassword:        state.RCONPassword,
+		dir:                 state.Dir,
+		user:                state.User,
+		startCommand:        state.StartCommand,
+		stopCommand:         state.StopCommand,
+		forceStopCommand:    state.ForceStopCommand,
+		restartCommand:      state.RestartCommand,
+		processActive:       state.ProcessActive,
+		runState:            runStateFromProcess(state.ProcessActive, state.Paused),
+		crashLooping:        state.CrashLooping,
+		lastProcessCheck:    state.LastProcessCheck,
+		lastTaskCompletedAt: state.LastTaskCompletedAt,
+		vars:                state.Vars,
+		settings:            settings,
+		updatedAt:           state.UpdatedAt,
+		cpuLimit:            state.CPULimit,
+		ramLimit:            state.RAMLimit,
+		changeset:           hashset.New(),
+		mu:                  &sync.RWMutex{},
+	}
+}
+
+func (s *Server) State() ServerState {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	// Settings are mutated in place by SetSetting, the copy keeps the state
+	// stable while it is being encoded.
+	settings := make(Settings, len(s.settings))
+	for k, v := range s.settings {
+		settings[k] = v
+	}
+
+	return ServerState{
+		ID:                  s.id,
+		Enabled:             s.enabled,
+		InstallStatus:       s.installStatus,
+		Blocked:             s.blocked,
+		Name:                s.name,
+		UUID:                s.uuid,
+		UUIDShort:           s.uuidShort,
+		Game:                s.game,
+		GameMod:             s.gameMod,
+		IP:                  s.ip,
+		ConnectPort:         s.connectPort,
+		QueryPort:           s.queryPort,
+		RCONPort:            s.rconPort,
+		RCONPassword:        s.rconPassword,
+		Dir:                 s.dir,
+		User:                s.user,
+		StartCommand:        s.startCommand,
+		StopCommand:         s.stopCommand,
+		ForceStopCommand:    s.forceStopCommand,
+		RestartCommand:      s.restartCommand,
+		ProcessActive:       s.processActive,
+		CrashLooping:        s.crashLooping,
+		Paused:              s.runState == RunStatePaused,
+		LastProcessCheck:    s.lastProcessCheck,
+		LastTaskCompletedAt: s.lastTaskCompletedAt,
+		Vars:                s.vars,
+		Settings:            settings,
+		UpdatedAt:           s.updatedAt,
+		CPULimit:            s.cpuLimit,
+		RAMLimit:            s.ramLimit,
+	}
+}
@@ -3,6 +3,8 @@ package domain
 import (
 	"sync"
 	"time"
+
+	"github.com/gameap/daemon/pkg/cron"
 )
 
 type ServerTaskCommand string
@@ -13,6 +15,12 @@ const (
 	ServerTaskRestart   ServerTaskCommand = "restart"
 	ServerTaskUpdate    ServerTaskCommand = "update"
 	ServerTaskReinstall ServerTaskCommand = "reinstall"
+
+	// The commands below are set by the task payload, the panel sends no
+	// task command for them.
+	ServerTaskConsoleCommand ServerTaskCommand = "console_command"
+	ServerTaskShellHook      ServerTaskCommand = "shell_hook"
+	ServerTaskArchive        ServerTaskCommand = "archive"
 )
 
 type ServerTaskOverlapPolicy int
@@ -44,6 +52,7 @@ type ServerTask struct {
 	executeDate  time.Time
 	repeat       int
 	repeatPeriod time.Duration
+	schedule     *cron.Schedule
 	counter      int
 
 	overlapPolicy ServerTaskOverlapPolicy
@@ -51,29 +60,33 @@ type ServerTask struct {
 
 	name      string
 	timezone  string
+	location  *time.Location
 	payload   string
 	enabled   bool
 	updatedAt time.Time
 }
 
+// ServerTaskOptions is also the form in which the scheduler keeps tasks on
+// disk, the server is resolved again by ServerID when the tasks are restored.
 type ServerTaskOptions struct {
-	ID            uint64
-	ServerID      uint64
-	NodeID        uint64
-	Version       uint64
-	Command       ServerTaskCommand
-	Server        *Server
-	ExecuteDate   time.Time
-	Repeat        int
-	RepeatPeriod  time.Duration
-	Counter       int
-	OverlapPolicy ServerTaskOverlapPolicy
-	CatchupPolicy ServerTaskCatchupPolicy
-	Name          string
-	Timezone      string
-	Payload       string
-	Enabled       bool
-	UpdatedAt     time.Time
+	ID            uint64                  `json:"id"`
+	ServerID      uint64                  `json:"server_id"`
+	NodeID        uint64                  `json:"node_id"`
+	Version       uint64                  `json:"version"`
+	Command       ServerTaskCommand       `json:"command"`
+	Server        *Server                 `json:"-"`
+	ExecuteDate   time.Time               `json:"execute_date"`
+	Repeat        int                     `json:"repeat"`
+	RepeatPeriod  time.Duration           `json:"repeat_period"`
+	Schedule      *cron.Schedule          `json:"schedule,omitempty"`
+	Counter       int                     `json:"counter"`
+	OverlapPolicy ServerTaskOverlapPolicy `json:"overlap_policy"`
+	CatchupPolicy ServerTaskCatchupPolicy `json:"catchup_policy"`
+	Name          string                  `json:"name"`
+	Timezone      string                  `json:"timezone"`
+	Payload       string                  `json:"payload"`
+	Enabled       bool                    `json:"enabled"`
+	UpdatedAt     time.Time               `json:"updated_at"`
 }
 
 func NewServerTask(opts ServerTaskOptions) *ServerTask {
@@ -88,11 +101,13 @@ func NewServerTask(opts ServerTaskOptions) *ServerTask {
 		executeDate:   opts.ExecuteDate,
 		repeat:        opts.Repeat,
 		repeatPeriod:  opts.RepeatPeriod,
+		schedule:      opts.Schedule,
 		counter:       opts.Counter,
 		overlapPolicy: opts.OverlapPolicy,
 		catchupPolicy: opts.CatchupPolicy,
 		name:          opts.Name,
 		timezone:      opts.Timezone,
+		location:      loadLocation(opts.Timezone),
 		payload:       opts.Payload,
 		enabled:       opts.Enabled,
 		updatedAt:     opts.UpdatedAt,
@@ -152,6 +167,15 @@ func (s *ServerTask) RepeatPeriod() time.Duration {
 	return s.repeatPeriod
 }
 
+// Schedule is the cron schedule of the task, nil for a task repeated every
+// RepeatPeriod.
+func (s *ServerTask) Schedule() *cron.Schedule {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.schedule
+}
+
 func (s *ServerTask) ExecuteDate() time.Time {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
@@ -258,6 +282,34 @@ func (s *ServerTask) IsActive() bool {
 	return s.enabled && s.canExecute()
 }
 
+// Options returns a copy of the task fields, NewServerTask(t.Options())
+// creates an equal task.
+func (s *ServerTask) Options() ServerTaskOptions {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return ServerTaskOptions{
+		ID:            s.id,
+		ServerID:      s.serverID,
+		NodeID:        s.nodeID,
+		Version:       s.version,
+		Command:       s.command,
+		Server:        s.server,
+		ExecuteDate:   s.executeDate,
+		Repeat:        s.repeat,
+		RepeatPeriod:  s.repeatPeriod,
+		Schedule:      s.schedule,
+		Counter:       s.counter,
+		OverlapPolicy: s.overlapPolicy,
+		CatchupPolicy: s.catchupPolicy,
+		Name:          s.name,
+		Timezone:      s.timezone,
+		Payload:       s.payload,
+		Enabled:       s.enabled,
+		UpdatedAt:     s.updatedAt,
+	}
+}
+
 // UpdateFromOptions atomically applies new fields received via gRPC delta.
 // Used by the scheduler when API pushes ServerTaskDelta.upserted; preserves
 // counter and mutex state.
@@ -275,16 +327,69 @@ func (s *ServerTask) UpdateFromOptions(opts ServerTaskOptions) {
 	s.executeDate = opts.ExecuteDate
 	s.repeat = opts.Repeat
 	s.repeatPeriod = opts.RepeatPeriod
+	s.schedule = opts.Schedule
 	s.counter = opts.Counter
 	s.overlapPolicy = opts.OverlapPolicy
 	s.catchupPolicy = opts.CatchupPolicy
 	s.name = opts.Name
+	if opts.Timezone != s.timezone {
+		s.location = loadLocation(opts.Timezone)
+	}
 	s.timezone = opts.Timezone
 	s.payload = opts.Payload
 	s.enabled = opts.Enabled
 	s.updatedAt = opts.UpdatedAt
 }
 
+// FollowsCalendar reports whether the task is due at wall clock times of its
+// timezone: it has a cron schedule or repeats every whole number of days.
+// The fire times of such a task are not a fixed period apart over DST changes.
+func (s *ServerTask) FollowsCalendar() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.followsCalendar()
+}
+
+// NextFireAt returns the first fire time of the schedule at or after t. The
+// zero time is returned for a task that is not repeated.
+func (s *ServerTask) NextFireAt(t time.Time) time.Time {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	switch {
+	case s.schedule != nil:
+		return s.nextFireAfter(t.Add(-time.Nanosecond))
+	case s.repeatPeriod <= 0:
+		return time.Time{}
+	}
+
+	next := s.executeDate
+	if !next.Before(t) {
+		return next
+	}
+
+	if s.followsCalendar() {
+		// Start close to t, the days of the period differ from 24 hours by
+		// an hour at most.
+		days := int(s.repeatPeriod / (24 * time.Hour))
+		periods := int(t.Sub(next)/s.repeatPeriod) - 1
+		if periods > 0 {
+			next = next.In(s.location).AddDate(0, 0, periods*days)
+		}
+
+		for next.Before(t) {
+			next = s.nextFireAfter(next)
+		}
+
+		return next
+	}
+
+	skips := int64(t.Sub(next)/s.repeatPeriod) + 1
+
+	return next.Add(time.Duration(skips) * s.repeatPeriod)
+}
+
 // repeatEndlessly, canExecute and prolongTask read mutable state directly and
 // must be called with s.mutex held.
 func (s *ServerTask) repeatEndlessly() bool {
@@ -292,9 +397,47 @@ func (s *ServerTask) repeatEndlessly() bool {
 }
 
 func (s *ServerTask) canExecute() bool {
+	// A cron schedule such as "0 0 30 2 *" is never due.
+	if s.schedule != nil && s.executeDate.IsZero() {
+		return false
+	}
+
 	return s.repeatEndlessly() || s.repeat > s.counter
 }
 
 func (s *ServerTask) prolongTask() {
-	s.executeDate = s.executeDate.Add(s.repeatPeriod)
+	s.executeDate = s.nextFireAfter(s.executeDate)
+}
+
+func (s *ServerTask) followsCalendar() bool {
+	return s.schedule != nil ||
+		(s.repeatPeriod > 0 && s.repeatPeriod%(24*time.Hour) == 0 && s.location != time.UTC)
+}
+
+// nextFireAfter returns the fire time following t. A whole number of days is
+// added to the wall clock of the task timezone, so "every day at 05:00" stays
+// at 05:00 over DST changes.
+func (s *ServerTask) nextFireAfter(t time.Time) time.Time {
+	switch {
+	case s.schedule != nil:
+		return s.schedule.Next(t.In(s.location))
+	case s.followsCalendar():
+		return t.In(s.location).AddDate(0, 0, int(s.repeatPeriod/(24*time.Hour)))
+	default:
+		return t.Add(s.repeatPeriod)
+	}
+}
+
+// loadLocation returns the IANA timezone, UTC when it is empty or unknown.
+func loadLocation(name string) *time.Location {
+	if name == "" {
+		return time.UTC
+	}
+
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return time.UTC
+	}
+
+	return loc
 }
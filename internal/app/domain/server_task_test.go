@@ -5,6 +5,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/gameap/daemon/pkg/cron"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -139,3 +140,80 @@ func TestServerTask_IsActive(t *testing.T) {
 		})
 	}
 }
+
+func TestServerTask_DailyPeriodKeepsWallClockOverDST(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip("no tzdata")
+	}
+
+	task := NewServerTask(ServerTaskOptions{
+		ExecuteDate:  time.Date(2026, 3, 28, 5, 0, 0, 0, berlin),
+		RepeatPeriod: 24 * time.Hour,
+		Timezone:     "Europe/Berlin",
+		Enabled:      true,
+	})
+
+	assert.True(t, task.FollowsCalendar())
+
+	task.IncreaseCountersAndTime()
+
+	assert.True(t, time.Date(2026, 3, 29, 5, 0, 0, 0, berlin).Equal(task.ExecuteDate()))
+	assert.True(t, time.Date(2026, 4, 2, 5, 0, 0, 0, berlin).Equal(
+		task.NextFireAt(time.Date(2026, 4, 1, 12, 0, 0, 0, berlin)),
+	))
+}
+
+func TestServerTask_PeriodWithoutTimezoneIsFixed(t *testing.T) {
+	executeDate := time.Date(2026, 3, 28, 4, 0, 0, 0, time.UTC)
+	task := NewServerTask(ServerTaskOptions{
+		ExecuteDate:  executeDate,
+		RepeatPeriod: 24 * time.Hour,
+		Enabled:      true,
+	})
+
+	assert.False(t, task.FollowsCalendar())
+
+	task.IncreaseCountersAndTime()
+
+	assert.Equal(t, executeDate.Add(24*time.Hour), task.ExecuteDate())
+	assert.Equal(t, executeDate.Add(72*time.Hour), task.NextFireAt(executeDate.Add(49*time.Hour)))
+}
+
+func TestServerTask_CronSchedule(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip("no tzdata")
+	}
+
+	schedule, err := cron.Parse("0 5 * * mon-fri")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	// Friday before the clocks move forward.
+	task := NewServerTask(ServerTaskOptions{
+		ExecuteDate: time.Date(2026, 3, 27, 5, 0, 0, 0, berlin),
+		Schedule:    schedule,
+		Timezone:    "Europe/Berlin",
+		Enabled:     true,
+	})
+
+	task.IncreaseCountersAndTime()
+
+	assert.True(t, time.Date(2026, 3, 30, 5, 0, 0, 0, berlin).Equal(task.ExecuteDate()))
+	assert.Equal(t, 1, task.Counter())
+	assert.True(t, task.IsActive())
+}
+
+func TestServerTask_CronScheduleNeverDue(t *testing.T) {
+	schedule, err := cron.Parse("0 0 30 2 *")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	task := NewServerTask(ServerTaskOptions{Schedule: schedule, Enabled: true})
+	task.SetExecuteDate(task.NextFireAt(time.Now()))
+
+	assert.False(t, task.IsActive())
+}
@@ -197,6 +197,58 @@ func TestServer_Vars_EmptyWhenNoSources(t *testing.T) {
 	assert.Empty(t, vars)
 }
 
+func TestServer_LookupVar(t *testing.T) {
+	server := newTestServerForVars(nil, map[string]string{"query_protocol": "source", "empty": ""}, Settings{})
+	server.gameMod.Metadata = map[string]any{"countdown": "60s", "empty": "mod"}
+	server.game.Metadata = map[string]any{"countdown": "5m", "rcon": true, "port": 27020, "null": nil}
+
+	tests := []struct {
+		key      string
+		expected string
+		found    bool
+	}{
+		{"query_protocol", "source", true},
+		{"countdown", "60s", true},
+		{"empty", "mod", true},
+		{"rcon", "true", true},
+		{"port", "27020", true},
+		{"null", "", false},
+		{"missing", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			val, ok := server.LookupVar(tt.key)
+
+			assert.Equal(t, tt.found, ok)
+			assert.Equal(t, tt.expected, val)
+		})
+	}
+}
+
+func TestServer_DialAddress(t *testing.T) {
+	tests := []struct {
+		ip       string
+		expected string
+	}{
+		{"", "127.0.0.1:27015"},
+		{"0.0.0.0", "127.0.0.1:27015"},
+		{"::", "127.0.0.1:27015"},
+		{"192.168.1.10", "192.168.1.10:27015"},
+		{"2001:db8::1", "[2001:db8::1]:27015"},
+		{"game.example.com", "game.example.com:27015"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ip, func(t *testing.T) {
+			server := newTestServerForVars(nil, nil, Settings{})
+			server.ip = tt.ip
+
+			assert.Equal(t, tt.expected, server.DialAddress(27015))
+		})
+	}
+}
+
 func TestServer_EnvironmentVars_NormalizesKeys(t *testing.T) {
 	server := newTestServerForVars(
 		[]GameModVarTemplate{
@@ -242,3 +294,50 @@ func TestServer_EnvironmentVars_AlwaysIncludesPortVars(t *testing.T) {
 	assert.Equal(t, "27016", envVars["QUERY_PORT"])
 	assert.Equal(t, "27017", envVars["RCON_PORT"])
 }
+
+func TestServer_RunState_FollowsStartAndProcessStatus(t *testing.T) {
+	server := newTestServerForVars(nil, nil, Settings{})
+	assert.Equal(t, RunStateStopped, server.RunState())
+
+	// A process found running without a start is running right away.
+	server.SetStatus(true)
+	assert.Equal(t, RunStateRunning, server.RunState())
+	assert.True(t, server.IsReady())
+
+	server.SetStatus(false)
+	assert.Equal(t, RunStateStopped, server.RunState())
+
+	server.AffectStart()
+	server.SetStatus(true)
+	assert.Equal(t, RunStateStarting, server.RunState())
+	assert.False(t, server.IsReady())
+	assert.True(t, server.IsValueModified("runState"))
+
+	server.SetRunState(RunStateUnhealthy)
+	server.SetStatus(true)
+	assert.Equal(t, RunStateUnhealthy, server.RunState())
+
+	server.SetStatus(false)
+	assert.Equal(t, RunStateStopped, server.RunState())
+}
+
+func TestServer_RunState_Paused(t *testing.T) {
+	server := newTestServerForVars(nil, nil, Settings{})
+
+	server.AffectPause()
+	assert.False(t, server.IsPaused(), "a stopped server is not paused")
+
+	server.SetStatus(true)
+	server.AffectPause()
+	server.SetStatus(true)
+	assert.True(t, server.IsPaused())
+	assert.False(t, server.IsReady())
+	assert.True(t, NewServerFromState(server.State()).IsPaused(), "the pause is kept in the local state")
+
+	server.AffectUnpause()
+	assert.Equal(t, RunStateRunning, server.RunState())
+
+	server.AffectPause()
+	server.SetStatus(false)
+	assert.Equal(t, RunStateStopped, server.RunState())
+}
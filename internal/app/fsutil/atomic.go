@@ -0,0 +1,65 @@
+package fsutil
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+)
+
+// WriteFileAtomic replaces name with data so that a crash at any point leaves
+// either the old or the new content on disk, never a truncated file. The data
+// is written to a temporary file in the same directory, synced and renamed
+// over name, then the directory itself is synced to persist the rename.
+func WriteFileAtomic(name string, data []byte, perm os.FileMode) error {
+	return WriteFileAtomicFunc(name, perm, func(f *os.File) error {
+		_, err := f.Write(data)
+
+		return err
+	})
+}
+
+// WriteFileAtomicFunc is WriteFileAtomic for content streamed by write into
+// the temporary file. Nothing is renamed when write fails.
+func WriteFileAtomicFunc(name string, perm os.FileMode, write func(f *os.File) error) error {
+	dir := filepath.Dir(name)
+
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(name)+".*.tmp")
+	if err != nil {
+		return errors.Wrap(err, "failed to create temporary file")
+	}
+	tmpName := tmp.Name()
+
+	// Removing an already renamed file is a harmless no-op.
+	defer func() {
+		_ = os.Remove(tmpName)
+	}()
+
+	if err = write(tmp); err != nil {
+		_ = tmp.Close()
+
+		return errors.Wrap(err, "failed to write temporary file")
+	}
+
+	if err = tmp.Chmod(perm); err != nil {
+		_ = tmp.Close()
+
+		return errors.Wrap(err, "failed to change temporary file mode")
+	}
+
+	if err = tmp.Sync(); err != nil {
+		_ = tmp.Close()
+
+		return errors.Wrap(err, "failed to sync temporary file")
+	}
+
+	if err = tmp.Close(); err != nil {
+		return errors.Wrap(err, "failed to close temporary file")
+	}
+
+	if err = os.Rename(tmpName, name); err != nil {
+		return errors.Wrap(err, "failed to replace file")
+	}
+
+	return syncDir(dir)
+}
@@ -0,0 +1,23 @@
+//go:build linux || darwin
+
+package fsutil
+
+import (
+	"os"
+
+	"github.com/pkg/errors"
+)
+
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return errors.Wrap(err, "failed to open directory")
+	}
+	defer d.Close()
+
+	if err = d.Sync(); err != nil {
+		return errors.Wrap(err, "failed to sync directory")
+	}
+
+	return nil
+}
@@ -0,0 +1,9 @@
+//go:build windows
+
+package fsutil
+
+// syncDir is a no-op on Windows: directories cannot be opened for syncing and
+// MoveFileEx used by os.Rename already replaces the file atomically.
+func syncDir(_ string) error {
+	return nil
+}
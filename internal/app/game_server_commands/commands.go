@@ -22,11 +22,22 @@ var nilLoadServerCommandFunc = func(_ domain.ServerCommand, _ *domain.Server) co
 	return nil
 }
 
+// ReadinessTracker is told about servers about to be started, so that the
+// console output of an earlier run does not make a server ready.
+type ReadinessTracker interface {
+	BeforeStart(ctx context.Context, server *domain.Server)
+}
+
 type ServerCommandFactory struct {
 	cfg            *config.Config
 	serverRepo     domain.ServerRepository
 	executor       contracts.Executor
 	processManager contracts.ProcessManager
+	readiness      ReadinessTracker
+
+	// countdownConsole sends the countdown messages of stop, restart and
+	// update commands, see WithCountdown.
+	countdownConsole contracts.ProcessManager
 }
 
 func NewFactory(
@@ -36,13 +47,28 @@ func NewFactory(
 	processManager contracts.ProcessManager,
 ) *ServerCommandFactory {
 	return &ServerCommandFactory{
-		cfg,
-		serverRepo,
-		executor,
-		processManager,
+		cfg:            cfg,
+		serverRepo:     serverRepo,
+		executor:       executor,
+		processManager: processManager,
 	}
 }
 
+func (factory *ServerCommandFactory) SetReadinessTracker(tracker ReadinessTracker) {
+	factory.readiness = tracker
+}
+
+// WithCountdown returns a factory whose stop, restart and update commands
+// warn the players of a running server before the action, the messages are
+// sent with the console. Commands the daemon runs on its own, e.g. restarts
+// of hung servers, are loaded from the factory without the countdown.
+func (factory *ServerCommandFactory) WithCountdown(console contracts.ProcessManager) *ServerCommandFactory {
+	f := *factory
+	f.countdownConsole = console
+
+	return &f
+}
+
 func (factory *ServerCommandFactory) LoadServerCommand(
 	cmd domain.ServerCommand,
 	server *domain.Server,
@@ -50,41 +76,70 @@ func (factory *ServerCommandFactory) LoadServerCommand(
 	switch cmd {
 	case domain.Start:
 		return factory.makeStartCommand(server, factory.LoadServerCommand)
-	case domain.Stop, domain.Kill:
-		return factory.makeStopCommand(server)
+	case domain.Stop:
+		return factory.withCountdown("stop", factory.makeStopCommand(server))
+	case domain.Kill:
+		return factory.makeKillCommand(server)
 	case domain.Restart:
-		return factory.makeRestartCommand(server)
+		return factory.withCountdown("restart", factory.makeRestartCommand(server))
 	case domain.Status:
 		return factory.makeStatusCommand(server)
 	case domain.Install:
 		return factory.makeInstallCommand(server)
 	case domain.Update:
-		return factory.makeUpdateCommand(server)
+		return factory.withCountdown("update", factory.makeUpdateCommand(server))
 	case domain.Reinstall:
 		return factory.makeReinstallCommand(server)
 	case domain.Delete:
 		return factory.makeDeleteCommand(server)
 	case domain.Pause:
+		return factory.makePauseCommand(server)
 	case domain.Unpause:
-		return newNotImplementedCommand(factory.cfg, factory.executor, factory.processManager)
+		return factory.makeUnpauseCommand(server)
 	}
 
 	return nil
 }
 
+func (factory *ServerCommandFactory) withCountdown(
+	action string,
+	cmd contracts.GameServerCommand,
+) contracts.GameServerCommand {
+	if factory.countdownConsole == nil {
+		return cmd
+	}
+
+	return newCountdownServer(factory.cfg, factory.executor, factory.countdownConsole, action, cmd)
+}
+
 func (factory *ServerCommandFactory) makeStartCommand(
 	_ *domain.Server,
 	lf LoadServerCommandFunc,
 ) contracts.GameServerCommand {
-	return newDefaultStartServer(factory.cfg, factory.executor, factory.processManager, lf)
+	cmd := newDefaultStartServer(factory.cfg, factory.executor, factory.processManager, lf)
+	cmd.readiness = factory.readiness
+
+	return cmd
 }
 
 func (factory *ServerCommandFactory) makeStopCommand(_ *domain.Server) contracts.GameServerCommand {
 	return newDefaultStopServer(factory.cfg, factory.executor, factory.processManager)
 }
 
+func (factory *ServerCommandFactory) makeKillCommand(_ *domain.Server) contracts.GameServerCommand {
+	return newDefaultKillServer(factory.cfg, factory.executor, factory.processManager)
+}
+
+func (factory *ServerCommandFactory) makePauseCommand(_ *domain.Server) contracts.GameServerCommand {
+	return newDefaultPauseServer(factory.cfg, factory.executor, factory.processManager)
+}
+
+func (factory *ServerCommandFactory) makeUnpauseCommand(_ *domain.Server) contracts.GameServerCommand {
+	return newDefaultUnpauseServer(factory.cfg, factory.executor, factory.processManager)
+}
+
 func (factory *ServerCommandFactory) makeRestartCommand(server *domain.Server) contracts.GameServerCommand {
-	return newDefaultRestartServer(
+	cmd := newDefaultRestartServer(
 		factory.cfg,
 		factory.executor,
 		factory.processManager,
@@ -92,6 +147,9 @@ func (factory *ServerCommandFactory) makeRestartCommand(server *domain.Server) c
 		factory.makeStopCommand(server),
 		factory.makeStartCommand(server, factory.LoadServerCommand),
 	)
+	cmd.readiness = factory.readiness
+
+	return cmd
 }
 
 func (factory *ServerCommandFactory) makeStatusCommand(_ *domain.Server) contracts.GameServerCommand {
@@ -258,29 +316,3 @@ func (c *commandList) Execute(ctx context.Context, server *domain.Server) error
 
 	return nil
 }
-
-type nilCommand struct {
-	bufCommand
-	message string
-	baseCommand
-	resultCode int
-}
-
-func (n *nilCommand) Execute(_ context.Context, _ *domain.Server) error {
-	n.SetComplete()
-	n.SetResult(n.resultCode)
-
-	_, _ = n.output.Write([]byte(n.message))
-
-	return nil
-}
-
-func newNotImplementedCommand(
-	cfg *config.Config, executor contracts.Executor, processManager contracts.ProcessManager,
-) *nilCommand {
-	return &nilCommand{
-		baseCommand: newBaseCommand(cfg, executor, processManager),
-		message:     "not implemented command",
-		resultCode:  ErrorResult,
-	}
-}
@@ -0,0 +1,269 @@
+package gameservercommands
+
+import (
+	"context"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gameap/daemon/internal/app/components"
+	"github.com/gameap/daemon/internal/app/config"
+	"github.com/gameap/daemon/internal/app/contracts"
+	"github.com/gameap/daemon/internal/app/domain"
+	"github.com/gameap/daemon/pkg/logger"
+	"github.com/pkg/errors"
+)
+
+// cancelMessageTimeout bounds sending the message of a cancelled countdown,
+// the context of the command is done by then.
+const cancelMessageTimeout = 10 * time.Second
+
+var errInvalidCountdownMarks = errors.New("invalid countdown marks")
+
+// countdownServer warns the players of a running server before running the
+// wrapped stop, restart or update command. The template is sent to the
+// server console at every mark, see config.CountdownConfig.
+type countdownServer struct {
+	baseCommand
+	bufCommand
+
+	action  string
+	console contracts.ProcessManager
+	command contracts.GameServerCommand
+
+	afterFn func(d time.Duration) <-chan time.Time
+
+	mu       sync.Mutex
+	started  bool
+	canceled bool
+	// stopCountdown cancels the countdown while it runs.
+	stopCountdown context.CancelFunc
+}
+
+func newCountdownServer(
+	cfg *config.Config,
+	executor contracts.Executor,
+	console contracts.ProcessManager,
+	action string,
+	command contracts.GameServerCommand,
+) *countdownServer {
+	return &countdownServer{
+		baseCommand: newBaseCommand(cfg, executor, console),
+		bufCommand:  bufCommand{output: components.NewSafeBuffer()},
+		action:      action,
+		console:     console,
+		command:     command,
+		afterFn:     time.After,
+	}
+}
+
+func (cmd *countdownServer) ReadOutput() []byte {
+	return append(cmd.bufCommand.ReadOutput(), cmd.command.ReadOutput()...)
+}
+
+func (cmd *countdownServer) Execute(ctx context.Context, server *domain.Server) error {
+	countdownCtx, stop := context.WithCancel(ctx)
+	defer stop()
+
+	err := cmd.setStopCountdown(stop)
+	if err == nil && server.IsActive() {
+		err = cmd.countdown(countdownCtx, server)
+	}
+	if err == nil {
+		err = cmd.start()
+	}
+	if err != nil {
+		cmd.SetResult(ErrorResult)
+		cmd.SetComplete()
+
+		return err
+	}
+
+	err = cmd.command.Execute(ctx, server)
+	cmd.SetResult(cmd.command.Result())
+	cmd.SetComplete()
+
+	return err
+}
+
+// CancelCountdown cancels the command during the countdown and reports
+// whether it did. Once the countdown is over the command runs to the end.
+func (cmd *countdownServer) CancelCountdown() bool {
+	cmd.mu.Lock()
+	defer cmd.mu.Unlock()
+
+	if cmd.started {
+		return false
+	}
+
+	cmd.canceled = true
+	if cmd.stopCountdown != nil {
+		cmd.stopCountdown()
+	}
+
+	return true
+}
+
+func (cmd *countdownServer) setStopCountdown(stop context.CancelFunc) error {
+	cmd.mu.Lock()
+	defer cmd.mu.Unlock()
+
+	if cmd.canceled {
+		return context.Canceled
+	}
+
+	cmd.stopCountdown = stop
+
+	return nil
+}
+
+// start ends the countdown, the wrapped command can't be canceled from now.
+func (cmd *countdownServer) start() error {
+	cmd.mu.Lock()
+	defer cmd.mu.Unlock()
+
+	if cmd.canceled {
+		return context.Canceled
+	}
+
+	cmd.started = true
+	cmd.stopCountdown = nil
+
+	return nil
+}
+
+// countdown sends the message of every mark and returns when the last mark
+// has passed, or with the context error when the context is done first.
+func (cmd *countdownServer) countdown(ctx context.Context, server *domain.Server) error {
+	template, ok := server.LookupVar("countdown_template_" + cmd.action)
+	if !ok {
+		template, ok = server.LookupVar("countdown_template")
+	}
+	if !ok {
+		template = cmd.cfg.Countdown.Template
+	}
+	if template == "" {
+		return nil
+	}
+
+	marks := cmd.cfg.Countdown.Marks
+	if val, ok := server.LookupVar("countdown_marks"); ok {
+		var err error
+		if marks, err = parseCountdownMarks(val); err != nil {
+			logger.Logger(ctx).WithError(err).Warn("Invalid countdown_marks, the config marks are used")
+			marks = cmd.cfg.Countdown.Marks
+		}
+	}
+
+	marks = normalizeCountdownMarks(marks)
+
+	for i, mark := range marks {
+		cmd.send(ctx, server, countdownMessage(template, cmd.action, mark))
+
+		next := time.Duration(0)
+		if i+1 < len(marks) {
+			next = marks[i+1]
+		}
+
+		select {
+		case <-ctx.Done():
+			cmd.cancel(ctx, server)
+			return ctx.Err()
+		case <-cmd.afterFn(mark - next):
+		}
+	}
+
+	return nil
+}
+
+func (cmd *countdownServer) cancel(ctx context.Context, server *domain.Server) {
+	template, ok := server.LookupVar("countdown_cancel_template")
+	if !ok {
+		template = cmd.cfg.Countdown.CancelTemplate
+	}
+	if template == "" {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), cancelMessageTimeout)
+	defer cancel()
+
+	cmd.send(ctx, server, countdownMessage(template, cmd.action, 0))
+}
+
+// send writes the message to the server console. A message that could not
+// be sent does not stop the countdown.
+func (cmd *countdownServer) send(ctx context.Context, server *domain.Server, message string) {
+	if _, err := cmd.console.SendInput(ctx, message, server, cmd.output); err != nil {
+		logger.Logger(ctx).WithError(err).Warn("Failed to send countdown message")
+	}
+}
+
+// countdownMessage fills in the placeholders of the template: {action} is
+// stop, restart or update, {minutes} and {seconds} are the time left rounded
+// up and {time} is the time left in words, e.g. "5 minutes".
+func countdownMessage(template, action string, left time.Duration) string {
+	return strings.NewReplacer(
+		"{action}", action,
+		"{minutes}", strconv.Itoa(int(math.Ceil(left.Minutes()))),
+		"{seconds}", strconv.Itoa(int(math.Ceil(left.Seconds()))),
+		"{time}", countdownTime(left),
+	).Replace(template)
+}
+
+func countdownTime(left time.Duration) string {
+	if left >= time.Minute && left%time.Minute == 0 {
+		return plural(int(left/time.Minute), "minute")
+	}
+
+	return plural(int(math.Ceil(left.Seconds())), "second")
+}
+
+func plural(n int, unit string) string {
+	if n == 1 {
+		return "1 " + unit
+	}
+
+	return strconv.Itoa(n) + " " + unit + "s"
+}
+
+// parseCountdownMarks parses a comma separated list of durations, e.g.
+// "10m,1m,10s". "0" turns the countdown off.
+func parseCountdownMarks(val string) ([]time.Duration, error) {
+	var marks []time.Duration
+
+	for _, part := range strings.Split(val, ",") {
+		part = strings.TrimSpace(part)
+		if part == "0" {
+			continue
+		}
+
+		mark, err := time.ParseDuration(part)
+		if err != nil || mark < 0 {
+			return nil, errors.Wrapf(errInvalidCountdownMarks, "%q", val)
+		}
+
+		marks = append(marks, mark)
+	}
+
+	return marks, nil
+}
+
+// normalizeCountdownMarks returns the positive marks, longest first, without
+// duplicates.
+func normalizeCountdownMarks(marks []time.Duration) []time.Duration {
+	result := make([]time.Duration, 0, len(marks))
+	for _, mark := range marks {
+		if mark > 0 {
+			result = append(result, mark)
+		}
+	}
+
+	slices.Sort(result)
+	slices.Reverse(result)
+
+	return slices.Compact(result)
+}
@@ -0,0 +1,289 @@
+package gameservercommands
+
+import (
+	"context"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gameap/daemon/internal/app/components"
+	"github.com/gameap/daemon/internal/app/config"
+	"github.com/gameap/daemon/internal/app/contracts"
+	"github.com/gameap/daemon/internal/app/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recordingConsole struct {
+	contracts.ProcessManager
+
+	mu     sync.Mutex
+	inputs []string
+}
+
+func (c *recordingConsole) SendInput(
+	_ context.Context, input string, _ *domain.Server, _ io.Writer,
+) (domain.Result, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.inputs = append(c.inputs, input)
+
+	return domain.SuccessResult, nil
+}
+
+type recordingCommand struct {
+	baseCommand
+	bufCommand
+
+	executed bool
+}
+
+func (c *recordingCommand) Execute(_ context.Context, _ *domain.Server) error {
+	c.executed = true
+	c.SetResult(SuccessResult)
+	c.SetComplete()
+
+	return nil
+}
+
+func givenCountdown(
+	t *testing.T, countdown config.CountdownConfig,
+) (*countdownServer, *recordingConsole, *recordingCommand) {
+	t.Helper()
+
+	cfg := &config.Config{Countdown: countdown}
+	console := &recordingConsole{}
+	inner := &recordingCommand{
+		baseCommand: newBaseCommand(cfg, nil, console),
+		bufCommand:  bufCommand{output: components.NewSafeBuffer()},
+	}
+
+	return newCountdownServer(cfg, nil, console, "restart", inner), console, inner
+}
+
+func TestCountdownServer_SendsMessagesAtMarks(t *testing.T) {
+	cmd, console, inner := givenCountdown(t, config.CountdownConfig{
+		Marks:    []time.Duration{time.Minute, 15 * time.Minute, 5 * time.Minute},
+		Template: "say Server {action}s in {time}",
+	})
+	var waits []time.Duration
+	cmd.afterFn = func(d time.Duration) <-chan time.Time {
+		waits = append(waits, d)
+		ch := make(chan time.Time, 1)
+		ch <- time.Time{}
+
+		return ch
+	}
+	server := givenServer(t, domain.Game{}, domain.GameMod{})
+	server.SetStatus(true)
+
+	err := cmd.Execute(context.Background(), server)
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"say Server restarts in 15 minutes",
+		"say Server restarts in 5 minutes",
+		"say Server restarts in 1 minute",
+	}, console.inputs)
+	assert.Equal(t, []time.Duration{10 * time.Minute, 4 * time.Minute, time.Minute}, waits)
+	assert.True(t, inner.executed)
+	assert.Equal(t, SuccessResult, cmd.Result())
+	assert.True(t, cmd.IsComplete())
+}
+
+func TestCountdownServer_GameMetadataOverridesConfig(t *testing.T) {
+	cmd, console, _ := givenCountdown(t, config.CountdownConfig{
+		Marks:    []time.Duration{15 * time.Minute},
+		Template: "say {minutes}",
+	})
+	cmd.afterFn = func(time.Duration) <-chan time.Time {
+		ch := make(chan time.Time, 1)
+		ch <- time.Time{}
+
+		return ch
+	}
+	server := givenServer(t, domain.Game{
+		Metadata: map[string]any{
+			"countdown_template":         "broadcast Restart in {seconds}s",
+			"countdown_template_restart": "broadcast {action} in {seconds}s",
+			"countdown_marks":            "30s, 10s",
+		},
+	}, domain.GameMod{})
+	server.SetStatus(true)
+
+	err := cmd.Execute(context.Background(), server)
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"broadcast restart in 30s", "broadcast restart in 10s"}, console.inputs)
+}
+
+func TestCountdownServer_Cancelled(t *testing.T) {
+	cmd, console, inner := givenCountdown(t, config.CountdownConfig{
+		Marks:          []time.Duration{5 * time.Minute, time.Minute},
+		Template:       "say Restart in {minutes} minutes",
+		CancelTemplate: "say Restart cancelled",
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	cmd.afterFn = func(time.Duration) <-chan time.Time {
+		cancel()
+
+		return nil
+	}
+	server := givenServer(t, domain.Game{}, domain.GameMod{})
+	server.SetStatus(true)
+
+	err := cmd.Execute(ctx, server)
+
+	require.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, []string{"say Restart in 5 minutes", "say Restart cancelled"}, console.inputs)
+	assert.False(t, inner.executed)
+	assert.Equal(t, ErrorResult, cmd.Result())
+}
+
+func TestCountdownServer_CancelCountdown(t *testing.T) {
+	cmd, console, inner := givenCountdown(t, config.CountdownConfig{
+		Marks:          []time.Duration{5 * time.Minute, time.Minute},
+		Template:       "say Restart in {minutes} minutes",
+		CancelTemplate: "say Restart cancelled",
+	})
+	canceled := make(chan bool, 1)
+	cmd.afterFn = func(time.Duration) <-chan time.Time {
+		canceled <- cmd.CancelCountdown()
+
+		return nil
+	}
+	server := givenServer(t, domain.Game{}, domain.GameMod{})
+	server.SetStatus(true)
+
+	err := cmd.Execute(context.Background(), server)
+
+	require.ErrorIs(t, err, context.Canceled)
+	assert.True(t, <-canceled)
+	assert.Equal(t, []string{"say Restart in 5 minutes", "say Restart cancelled"}, console.inputs)
+	assert.False(t, inner.executed)
+	assert.Equal(t, ErrorResult, cmd.Result())
+}
+
+func TestCountdownServer_CancelCountdownBeforeStart(t *testing.T) {
+	cmd, console, inner := givenCountdown(t, config.CountdownConfig{
+		Marks:    []time.Duration{time.Minute},
+		Template: "say Restart in {minutes} minutes",
+	})
+	server := givenServer(t, domain.Game{}, domain.GameMod{})
+	server.SetStatus(true)
+
+	require.True(t, cmd.CancelCountdown())
+	err := cmd.Execute(context.Background(), server)
+
+	require.ErrorIs(t, err, context.Canceled)
+	assert.Empty(t, console.inputs)
+	assert.False(t, inner.executed)
+}
+
+func TestCountdownServer_NotCanceledAfterCountdown(t *testing.T) {
+	cmd, _, inner := givenCountdown(t, config.CountdownConfig{
+		Marks:    []time.Duration{time.Minute},
+		Template: "say Restart in {minutes} minutes",
+	})
+	cmd.afterFn = func(time.Duration) <-chan time.Time {
+		ch := make(chan time.Time, 1)
+		ch <- time.Now()
+
+		return ch
+	}
+	server := givenServer(t, domain.Game{}, domain.GameMod{})
+	server.SetStatus(true)
+
+	require.NoError(t, cmd.Execute(context.Background(), server))
+
+	assert.True(t, inner.executed)
+	assert.False(t, cmd.CancelCountdown())
+}
+
+func TestCountdownServer_SkippedForStoppedServerOrWithoutTemplate(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		active   bool
+	}{
+		{"stopped server", "say {time}", false},
+		{"no template", "", true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cmd, console, inner := givenCountdown(t, config.CountdownConfig{
+				Marks:    []time.Duration{time.Minute},
+				Template: test.template,
+			})
+			cmd.afterFn = func(time.Duration) <-chan time.Time {
+				t.Fatal("unexpected wait")
+
+				return nil
+			}
+			server := givenServer(t, domain.Game{}, domain.GameMod{})
+			server.SetStatus(test.active)
+
+			err := cmd.Execute(context.Background(), server)
+
+			require.NoError(t, err)
+			assert.Empty(t, console.inputs)
+			assert.True(t, inner.executed)
+		})
+	}
+}
+
+func TestCountdownMessage(t *testing.T) {
+	tests := []struct {
+		left     time.Duration
+		expected string
+	}{
+		{15 * time.Minute, "stop 15 900 15 minutes"},
+		{time.Minute, "stop 1 60 1 minute"},
+		{90 * time.Second, "stop 2 90 90 seconds"},
+		{time.Second, "stop 1 1 1 second"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.left.String(), func(t *testing.T) {
+			message := countdownMessage("{action} {minutes} {seconds} {time}", "stop", test.left)
+
+			assert.Equal(t, test.expected, message)
+		})
+	}
+}
+
+func TestParseCountdownMarks(t *testing.T) {
+	marks, err := parseCountdownMarks("10m, 1m,30s")
+	require.NoError(t, err)
+	assert.Equal(t, []time.Duration{10 * time.Minute, time.Minute, 30 * time.Second}, marks)
+
+	marks, err = parseCountdownMarks("0")
+	require.NoError(t, err)
+	assert.Empty(t, marks)
+
+	_, err = parseCountdownMarks("10")
+	require.ErrorIs(t, err, errInvalidCountdownMarks)
+}
+
+func TestNormalizeCountdownMarks(t *testing.T) {
+	marks := normalizeCountdownMarks([]time.Duration{time.Minute, 0, 15 * time.Minute, time.Minute, 5 * time.Minute})
+
+	assert.Equal(t, []time.Duration{15 * time.Minute, 5 * time.Minute, time.Minute}, marks)
+}
+
+func TestFactoryWithCountdown(t *testing.T) {
+	cfg := &config.Config{}
+	factory := NewFactory(cfg, nil, nil, &recordingConsole{})
+	server := givenServer(t, domain.Game{}, domain.GameMod{})
+
+	withCountdown := factory.WithCountdown(&recordingConsole{})
+
+	assert.IsType(t, &defaultStopServer{}, factory.LoadServerCommand(domain.Stop, server))
+	assert.IsType(t, &countdownServer{}, withCountdown.LoadServerCommand(domain.Stop, server))
+	assert.IsType(t, &countdownServer{}, withCountdown.LoadServerCommand(domain.Restart, server))
+	assert.IsType(t, &countdownServer{}, withCountdown.LoadServerCommand(domain.Update, server))
+	assert.IsType(t, &defaultKillServer{}, withCountdown.LoadServerCommand(domain.Kill, server))
+}
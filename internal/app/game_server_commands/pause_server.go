@@ -0,0 +1,60 @@
+package gameservercommands
+
+import (
+	"context"
+
+	"github.com/gameap/daemon/internal/app/components"
+	"github.com/gameap/daemon/internal/app/config"
+	"github.com/gameap/daemon/internal/app/contracts"
+	"github.com/gameap/daemon/internal/app/domain"
+)
+
+type defaultPauseServer struct {
+	bufCommand
+	baseCommand
+}
+
+func newDefaultPauseServer(
+	cfg *config.Config, executor contracts.Executor, processManager contracts.ProcessManager,
+) *defaultPauseServer {
+	return &defaultPauseServer{
+		baseCommand: newBaseCommand(cfg, executor, processManager),
+		bufCommand:  bufCommand{output: components.NewSafeBuffer()},
+	}
+}
+
+func (cmd *defaultPauseServer) Execute(ctx context.Context, server *domain.Server) error {
+	result, err := cmd.processManager.Pause(ctx, server, cmd.output)
+	if err == nil && result == domain.SuccessResult {
+		server.AffectPause()
+	}
+	cmd.SetResult(int(result))
+	cmd.SetComplete()
+
+	return err
+}
+
+type defaultUnpauseServer struct {
+	bufCommand
+	baseCommand
+}
+
+func newDefaultUnpauseServer(
+	cfg *config.Config, executor contracts.Executor, processManager contracts.ProcessManager,
+) *defaultUnpauseServer {
+	return &defaultUnpauseServer{
+		baseCommand: newBaseCommand(cfg, executor, processManager),
+		bufCommand:  bufCommand{output: components.NewSafeBuffer()},
+	}
+}
+
+func (cmd *defaultUnpauseServer) Execute(ctx context.Context, server *domain.Server) error {
+	result, err := cmd.processManager.Unpause(ctx, server, cmd.output)
+	if err == nil && result == domain.SuccessResult {
+		server.AffectUnpause()
+	}
+	cmd.SetResult(int(result))
+	cmd.SetComplete()
+
+	return err
+}
@@ -16,6 +16,7 @@ type defaultRestartServer struct {
 	statusServer contracts.GameServerCommand
 	stopServer   contracts.GameServerCommand
 	startServer  contracts.GameServerCommand
+	readiness    ReadinessTracker
 	baseCommand
 }
 
@@ -45,10 +46,18 @@ func (cmd *defaultRestartServer) Execute(ctx context.Context, server *domain.Ser
 		return cmd.restartViaStopStart(ctx, server)
 	}
 
+	if cmd.readiness != nil {
+		cmd.readiness.BeforeStart(ctx, server)
+	}
+
 	result, err := cmd.processManager.Restart(ctx, server, cmd.output)
 	cmd.SetResult(int(result))
 	cmd.SetComplete()
 
+	if err == nil && result == domain.SuccessResult {
+		server.SetRunState(domain.RunStateStarting)
+	}
+
 	return err
 }
 
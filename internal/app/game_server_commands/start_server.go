@@ -15,6 +15,7 @@ type defaultStartServer struct {
 	startOutput       io.ReadWriter
 	updateCommand     contracts.GameServerCommand
 	loadServerCommand LoadServerCommandFunc
+	readiness         ReadinessTracker
 	baseCommand
 	enableUpdatingBefore bool
 }
@@ -49,6 +50,10 @@ func (cmd *defaultStartServer) Execute(ctx context.Context, server *domain.Serve
 		}
 	}
 
+	if cmd.readiness != nil {
+		cmd.readiness.BeforeStart(ctx, server)
+	}
+
 	result, err := cmd.processManager.Start(ctx, server, cmd.startOutput)
 	cmd.SetResult(int(result))
 	cmd.SetComplete()
@@ -32,3 +32,27 @@ func (cmd *defaultStopServer) Execute(ctx context.Context, server *domain.Server
 
 	return err
 }
+
+type defaultKillServer struct {
+	bufCommand
+	baseCommand
+}
+
+func newDefaultKillServer(
+	cfg *config.Config, executor contracts.Executor, processManager contracts.ProcessManager,
+) *defaultKillServer {
+	return &defaultKillServer{
+		baseCommand: newBaseCommand(cfg, executor, processManager),
+		bufCommand:  bufCommand{output: components.NewSafeBuffer()},
+	}
+}
+
+func (cmd *defaultKillServer) Execute(ctx context.Context, server *domain.Server) error {
+	server.AffectStop()
+
+	result, err := cmd.processManager.Kill(ctx, server, cmd.output)
+	cmd.SetResult(int(result))
+	cmd.SetComplete()
+
+	return err
+}
@@ -17,6 +17,7 @@ const (
 const (
 	GameServerStart     = "gsstart"
 	GameServerPause     = "gspause"
+	GameServerUnpause   = "gsunpause"
 	GameServerStop      = "gsstop"
 	GameServerKill      = "gskill"
 	GameServerRestart   = "gsrest"
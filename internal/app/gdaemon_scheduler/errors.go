@@ -2,4 +2,7 @@ package gdaemonscheduler
 
 import "errors"
 
-var ErrInvalidTaskError = errors.New("invalid task")
+var (
+	ErrInvalidTaskError  = errors.New("invalid task")
+	ErrTaskNotCancelable = errors.New("task is already running and can't be canceled")
+)
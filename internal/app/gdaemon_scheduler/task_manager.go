@@ -13,6 +13,7 @@ import (
 	"github.com/gameap/daemon/internal/app/contracts"
 	"github.com/gameap/daemon/internal/app/domain"
 	gameservercommands "github.com/gameap/daemon/internal/app/game_server_commands"
+	"github.com/gameap/daemon/internal/app/serverlock"
 	"github.com/gameap/daemon/pkg/logger"
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
@@ -26,6 +27,7 @@ type TaskStatusSender interface {
 var taskServerCommandMap = map[domain.GDTaskCommand]domain.ServerCommand{
 	domain.GDTaskGameServerStart:     domain.Start,
 	domain.GDTaskGameServerPause:     domain.Pause,
+	domain.GDTaskGameServerUnpause:   domain.Unpause,
 	domain.GDTaskGameServerStop:      domain.Stop,
 	domain.GDTaskGameServerKill:      domain.Kill,
 	domain.GDTaskGameServerRestart:   domain.Restart,
@@ -45,6 +47,7 @@ type TaskManager struct {
 	commandsInProgress   sync.Map
 	wg                   sync.WaitGroup
 	taskStatusSender     TaskStatusSender
+	serverLocks          *serverlock.Locks
 
 	// predecessorWaits maps a task ID to the moment its predecessor was first
 	// seen missing, bounding the wait by predecessorMissingTimeout.
@@ -73,26 +76,66 @@ func (manager *TaskManager) SetTaskStatusSender(sender TaskStatusSender) {
 	manager.taskStatusSender = sender
 }
 
+// SetServerLocks makes game server commands wait for the other work on their
+// server, e.g. a command run via the admin API.
+func (manager *TaskManager) SetServerLocks(locks *serverlock.Locks) {
+	manager.serverLocks = locks
+}
+
 func (manager *TaskManager) InsertTask(task *domain.GDTask) {
 	manager.queue.Insert([]*domain.GDTask{task})
 }
 
+// countdownCanceler is implemented by game server commands which announce
+// themselves with a countdown and can be canceled until it is over.
+type countdownCanceler interface {
+	CancelCountdown() bool
+}
+
+// CancelTask cancels a waiting task, or a working one whose command is still
+// in its countdown, and reports the canceled status to the panel.
 func (manager *TaskManager) CancelTask(taskID int) error {
 	task := manager.queue.FindByID(taskID)
 	if task == nil {
 		return errors.New("task not found")
 	}
 
+	working := task.IsWorking()
+
+	// The status is changed before the countdown is canceled, so the worker
+	// doesn't report the interrupted command as failed.
 	if err := task.SetStatus(domain.GDTaskStatusCanceled); err != nil {
 		return err
 	}
 
+	if working && !manager.cancelCountdown(taskID) {
+		if err := task.SetStatus(domain.GDTaskStatusWorking); err != nil {
+			return err
+		}
+
+		return ErrTaskNotCancelable
+	}
+
 	manager.queue.Remove(task)
 	manager.predecessorWaits.Delete(taskID)
+	manager.commandsInProgress.Delete(taskID)
+	manager.completed.Record(taskID, task.Status())
+	manager.notifyTaskStatus(task, "Task canceled")
 
 	return nil
 }
 
+func (manager *TaskManager) cancelCountdown(taskID int) bool {
+	c, ok := manager.commandsInProgress.Load(taskID)
+	if !ok {
+		return false
+	}
+
+	canceler, ok := c.(countdownCanceler)
+
+	return ok && canceler.CancelCountdown()
+}
+
 func (manager *TaskManager) Run(ctx context.Context) error {
 	go manager.RunWorker(ctx)
 
@@ -288,8 +331,20 @@ func (manager *TaskManager) evaluatePredecessorStatus(
 }
 
 func (manager *TaskManager) executeTask(ctx context.Context, task *domain.GDTask) error {
+	unlock := func() {}
+	if task.Task() != domain.GDTaskCommandExecute && task.Server() != nil && manager.serverLocks != nil {
+		var locked bool
+		unlock, locked = manager.serverLocks.TryLock(task.Server().ID(), true)
+		if !locked {
+			// The task stays waiting and is tried again on the next tick
+			logger.Debug(ctx, "Server is busy, the task waits")
+			return nil
+		}
+	}
+
 	err := task.SetStatus(domain.GDTaskStatusWorking)
 	if err != nil {
+		unlock()
 		return err
 	}
 
@@ -299,7 +354,7 @@ func (manager *TaskManager) executeTask(ctx context.Context, task *domain.GDTask
 		return manager.executeCommand(ctx, task)
 	}
 
-	return manager.executeGameCommand(ctx, task)
+	return manager.executeGameCommand(ctx, task, unlock)
 }
 
 func (manager *TaskManager) executeCommand(ctx context.Context, task *domain.GDTask) error {
@@ -342,10 +397,13 @@ func (manager *TaskManager) executeCommand(ctx context.Context, task *domain.GDT
 	return nil
 }
 
-func (manager *TaskManager) executeGameCommand(ctx context.Context, task *domain.GDTask) error {
+// executeGameCommand runs the command in the background, unlock releases the
+// server once the command is done.
+func (manager *TaskManager) executeGameCommand(ctx context.Context, task *domain.GDTask, unlock func()) error {
 	cmd, gameServerCmdExist := taskServerCommandMap[task.Task()]
 
 	if !gameServerCmdExist {
+		unlock()
 		return ErrInvalidTaskError
 	}
 
@@ -359,6 +417,7 @@ func (manager *TaskManager) executeGameCommand(ctx context.Context, task *domain
 
 	go func() {
 		defer manager.wg.Done()
+		defer unlock()
 		defer func() {
 			if r := recover(); r != nil {
 				logger.Logger(ctx).Errorf("panic in game command execution: %v", r)
@@ -369,11 +428,15 @@ func (manager *TaskManager) executeGameCommand(ctx context.Context, task *domain
 		taskCtx := ctx
 		if manager.config.TaskManager.TaskTimeout > 0 {
 			var cancel context.CancelFunc
-			taskCtx, cancel = context.WithTimeout(ctx, manager.config.TaskManager.TaskTimeout)
+			taskCtx, cancel = context.WithTimeout(taskCtx, manager.config.TaskManager.TaskTimeout)
 			defer cancel()
 		}
 
 		err := cmdFunc.Execute(taskCtx, task.Server())
+		if err != nil && task.Status() == domain.GDTaskStatusCanceled {
+			logger.Debug(ctx, "Task command canceled")
+			return
+		}
 		if err != nil {
 			logger.Warn(ctx, err)
 			output := append(cmdFunc.ReadOutput(), err.Error()...)
@@ -401,6 +464,10 @@ func (manager *TaskManager) proceedTask(ctx context.Context, task *domain.GDTask
 	// may drop anything arriving after it.
 	manager.notifyTaskOutput(task, output, isFinal)
 
+	if isFinal && task.Status() == domain.GDTaskStatusCanceled {
+		return nil
+	}
+
 	if isFinal {
 		manager.commandsInProgress.Delete(task.ID())
 
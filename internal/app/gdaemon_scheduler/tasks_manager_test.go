@@ -2,12 +2,15 @@ package gdaemonscheduler
 
 import (
 	"context"
+	"io"
 	"testing"
 	"time"
 
 	"github.com/gameap/daemon/internal/app/config"
+	"github.com/gameap/daemon/internal/app/contracts"
 	"github.com/gameap/daemon/internal/app/domain"
 	gameservercommands "github.com/gameap/daemon/internal/app/game_server_commands"
+	"github.com/gameap/daemon/internal/app/serverlock"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -140,6 +143,151 @@ func Test_proceedTask_SendsFinalOutputBeforeStatus(t *testing.T) {
 	}
 }
 
+func Test_CancelTask_CancelsRunningCountdown(t *testing.T) {
+	cfg := &config.Config{
+		Countdown: config.CountdownConfig{
+			Marks:    []time.Duration{time.Hour},
+			Template: "say Server stops in {time}",
+		},
+	}
+	console := &recordingConsole{sent: make(chan string, 1)}
+	factory := gameservercommands.NewFactory(cfg, nil, nil, nil).WithCountdown(console)
+	manager := NewTaskManager(nil, factory, nil, cfg)
+	sender := &recordingTaskStatusSender{}
+	manager.SetTaskStatusSender(sender)
+	server := givenServer()
+	server.SetStatus(true)
+	task := domain.NewGDTask(1, 0, server, domain.GDTaskGameServerStop, "", domain.GDTaskStatusWaiting)
+	manager.InsertTask(task)
+
+	require.NoError(t, manager.executeTask(context.Background(), task))
+	assert.Equal(t, "say Server stops in 60 minutes", <-console.sent)
+	require.NoError(t, manager.CancelTask(task.ID()))
+	manager.wg.Wait()
+
+	assert.Equal(t, domain.GDTaskStatusCanceled, task.Status())
+	assert.Equal(t, []string{
+		"status:" + string(domain.GDTaskStatusWorking),
+		"status:" + string(domain.GDTaskStatusCanceled),
+	}, sender.events)
+	_, running := manager.commandsInProgress.Load(task.ID())
+	assert.False(t, running)
+	assert.Nil(t, manager.queue.FindByID(task.ID()))
+}
+
+func Test_CancelTask_RunningCommandIsNotCanceled(t *testing.T) {
+	manager := NewTaskManager(nil, nil, nil, &config.Config{})
+	sender := &recordingTaskStatusSender{}
+	manager.SetTaskStatusSender(sender)
+	task := domain.NewGDTask(1, 0, nil, domain.GDTaskGameServerStop, "", domain.GDTaskStatusWorking)
+	manager.InsertTask(task)
+	manager.commandsInProgress.Store(task.ID(), &completedCommand{})
+
+	err := manager.CancelTask(task.ID())
+
+	require.ErrorIs(t, err, ErrTaskNotCancelable)
+	assert.Equal(t, domain.GDTaskStatusWorking, task.Status())
+	assert.Empty(t, sender.events)
+	assert.NotNil(t, manager.queue.FindByID(task.ID()))
+}
+
+func Test_CancelTask_WaitingTask(t *testing.T) {
+	manager := NewTaskManager(nil, nil, nil, &config.Config{})
+	sender := &recordingTaskStatusSender{}
+	manager.SetTaskStatusSender(sender)
+	task := domain.NewGDTask(1, 0, nil, domain.GDTaskGameServerStop, "", domain.GDTaskStatusWaiting)
+	manager.InsertTask(task)
+
+	require.NoError(t, manager.CancelTask(task.ID()))
+
+	assert.Equal(t, []string{"status:" + string(domain.GDTaskStatusCanceled)}, sender.events)
+	status, ok := manager.completed.Status(task.ID())
+	require.True(t, ok)
+	assert.Equal(t, domain.GDTaskStatusCanceled, status)
+}
+
+func Test_executeTask_WaitsForBusyServer(t *testing.T) {
+	cfg := &config.Config{
+		Countdown: config.CountdownConfig{
+			Marks:    []time.Duration{time.Hour},
+			Template: "say Server stops in {time}",
+		},
+	}
+	console := &recordingConsole{sent: make(chan string, 1)}
+	factory := gameservercommands.NewFactory(cfg, nil, nil, nil).WithCountdown(console)
+	manager := NewTaskManager(nil, factory, nil, cfg)
+	locks := serverlock.New()
+	manager.SetServerLocks(locks)
+	server := givenServer()
+	server.SetStatus(true)
+	task := domain.NewGDTask(1, 0, server, domain.GDTaskGameServerStop, "", domain.GDTaskStatusWaiting)
+	manager.InsertTask(task)
+
+	unlock, err := locks.Lock(context.Background(), server.ID(), true)
+	require.NoError(t, err)
+	require.NoError(t, manager.executeTask(context.Background(), task))
+	assert.Equal(t, domain.GDTaskStatusWaiting, task.Status())
+
+	unlock()
+	require.NoError(t, manager.executeTask(context.Background(), task))
+	assert.Equal(t, "say Server stops in 60 minutes", <-console.sent)
+	_, free := locks.TryLock(server.ID(), false)
+	assert.False(t, free)
+
+	require.NoError(t, manager.CancelTask(task.ID()))
+	manager.wg.Wait()
+
+	unlock, free = locks.TryLock(server.ID(), true)
+	require.True(t, free)
+	unlock()
+}
+
+type recordingConsole struct {
+	contracts.ProcessManager
+
+	sent chan string
+}
+
+func (c *recordingConsole) SendInput(
+	_ context.Context, input string, _ *domain.Server, _ io.Writer,
+) (domain.Result, error) {
+	c.sent <- input
+
+	return domain.SuccessResult, nil
+}
+
+func givenServer() *domain.Server {
+	return domain.NewServer(
+		1,
+		true,
+		domain.ServerInstalled,
+		false,
+		"test",
+		"9fd2e4d1-4a4e-4d3c-9a2f-5a4b5d8c9e01",
+		"9fd2e4d1",
+		domain.Game{},
+		domain.GameMod{},
+		"127.0.0.1",
+		27015,
+		27015,
+		27016,
+		"",
+		"",
+		"",
+		"./start.sh",
+		"",
+		"",
+		"",
+		true,
+		time.Time{},
+		nil,
+		domain.Settings{},
+		time.Time{},
+		0,
+		0,
+	)
+}
+
 type recordingTaskStatusSender struct {
 	events []string
 }
@@ -61,6 +61,7 @@ type FileHandler interface {
 type ServerHandler interface {
 	HandleServerUpdate(ctx context.Context, srv *pb.Server) error
 	HandleServerConfigUpdate(ctx context.Context, srv *pb.Server, settings []*pb.ServerSetting) error
+	HandleServersSync(ctx context.Context, servers []*pb.Server)
 }
 
 type TransferHandler interface {
@@ -132,6 +133,10 @@ type GatewayClient struct {
 	wg            sync.WaitGroup
 	shutdownDelay atomic.Pointer[time.Duration]
 	fileOpSem     *semaphore.Weighted
+
+	// online is set while the daemon is registered with the panel. Without
+	// it the daemon runs in offline mode on the locally saved state.
+	online atomic.Bool
 }
 
 func NewGatewayClient(
@@ -185,6 +190,8 @@ func (c *GatewayClient) Run(ctx context.Context, conn *grpc.ClientConn) error {
 
 	log.Info("Successfully registered with panel")
 
+	c.setOnline(true)
+
 	c.wg.Add(3)
 	go c.sendLoop(ctx)
 	go c.receiveLoop(ctx)
@@ -285,6 +292,11 @@ func (c *GatewayClient) processRegisterAck(ctx context.Context, ack *pb.Register
 		}
 	}
 
+	// RegisterAck carries all servers of the node
+	if ctx.Err() == nil {
+		c.serverHandler.HandleServersSync(ctx, ack.Servers)
+	}
+
 	for _, task := range ack.PendingTasks {
 		if ctx.Err() != nil {
 			break
@@ -763,6 +775,13 @@ func (c *GatewayClient) SendTaskOutput(taskID int, output []byte, isFinal bool)
 }
 
 func (c *GatewayClient) SendServerStatuses(statuses []*pb.ServerStatus) {
+	// Statuses are superseded by the next report, queueing them while offline
+	// would only fill the outbound buffer needed for task results.
+	if !c.IsOnline() {
+		log.WithField("count", len(statuses)).Debug("Offline mode, server statuses are not sent")
+		return
+	}
+
 	c.Send(&pb.DaemonMessage{
 		Payload: &pb.DaemonMessage_ServerStatuses{
 			ServerStatuses: &pb.ServerStatusBatch{
@@ -780,7 +799,26 @@ func (c *GatewayClient) PendingShutdownDelay() (time.Duration, bool) {
 	return 0, false
 }
 
+// IsOnline reports whether the daemon is registered with the panel.
+func (c *GatewayClient) IsOnline() bool {
+	return c.online.Load()
+}
+
+func (c *GatewayClient) setOnline(online bool) {
+	if c.online.Swap(online) == online {
+		return
+	}
+
+	if online {
+		log.Info("Panel connection established, leaving offline mode")
+	} else {
+		log.Warn("Panel connection lost, running in offline mode on the last known state")
+	}
+}
+
 func (c *GatewayClient) closeStream() {
+	c.setOnline(false)
+
 	if c.attachHandler != nil {
 		c.attachHandler.CloseAllSessions("daemon disconnected")
 	}
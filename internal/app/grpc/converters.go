@@ -98,6 +98,10 @@ func stringStatusToProto(status string) pb.DaemonTaskStatus {
 	}
 }
 
+// DomainServerToProtoStatus converts the server status for the panel. The
+// status only tells whether the server process runs, so the panel does not
+// start a starting or unhealthy server again. The run state is reported with
+// the gameap_server_run_state metric.
 func DomainServerToProtoStatus(server *domain.Server) *pb.ServerStatus {
 	return &pb.ServerStatus{
 		ServerId:  uint64(server.ID()),
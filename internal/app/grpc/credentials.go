@@ -10,6 +10,18 @@ import (
 )
 
 func NewTLSCredentials(cfg *config.Config) (credentials.TransportCredentials, error) {
+	tlsConfig, err := NewTLSConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return credentials.NewTLS(tlsConfig), nil
+}
+
+// NewTLSConfig loads the daemon certificate and the CA from the config. The
+// CA pool is set as RootCAs, servers that verify their clients against the
+// same CA copy it to ClientCAs.
+func NewTLSConfig(cfg *config.Config) (*tls.Config, error) {
 	caCert, err := cfg.CACertificatePEM()
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to read CA certificate")
@@ -41,5 +53,5 @@ func NewTLSCredentials(cfg *config.Config) (credentials.TransportCredentials, er
 		MinVersion:   tls.VersionTLS12,
 	}
 
-	return credentials.NewTLS(tlsConfig), nil
+	return tlsConfig, nil
 }
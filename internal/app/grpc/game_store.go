@@ -1,17 +1,33 @@
 package grpc
 
 import (
+	"sort"
 	"sync"
 
 	"github.com/gameap/daemon/internal/app/domain"
+	"github.com/gameap/daemon/internal/app/statefile"
 	pb "github.com/gameap/gameap/pkg/proto"
 	log "github.com/sirupsen/logrus"
 )
 
+const (
+	GameStoreFileName = "games.json"
+
+	gameStoreFileVersion = 1
+)
+
+type gameStoreState struct {
+	Games    []domain.Game    `json:"games"`
+	GameMods []domain.GameMod `json:"game_mods"`
+}
+
 type GameStore struct {
 	mu       sync.RWMutex
 	games    map[string]domain.Game
 	gameMods map[uint64]domain.GameMod
+
+	// stateFile is empty when games are kept in memory only.
+	stateFile string
 }
 
 func NewGameStore() *GameStore {
@@ -21,6 +37,49 @@ func NewGameStore() *GameStore {
 	}
 }
 
+// NewPersistentGameStore returns a store that saves the games and game mods
+// received from the panel to stateFile, so servers can be installed and
+// started while the panel is unreachable.
+func NewPersistentGameStore(stateFile string) *GameStore {
+	s := NewGameStore()
+	s.stateFile = stateFile
+
+	return s
+}
+
+// Restore loads the games and game mods saved by a previous daemon run. Entries
+// already received from the panel are kept.
+func (s *GameStore) Restore() (int, error) {
+	if s.stateFile == "" {
+		return 0, nil
+	}
+
+	var state gameStoreState
+	if _, err := statefile.Load(s.stateFile, gameStoreFileVersion, &state); err != nil {
+		return 0, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	restored := 0
+	for _, g := range state.Games {
+		if _, ok := s.games[g.Code]; !ok {
+			s.games[g.Code] = g
+			restored++
+		}
+	}
+
+	for _, m := range state.GameMods {
+		if _, ok := s.gameMods[uint64(m.ID)]; !ok {
+			s.gameMods[uint64(m.ID)] = m
+			restored++
+		}
+	}
+
+	return restored, nil
+}
+
 func (s *GameStore) UpdateGames(games []*pb.Game) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -30,6 +89,8 @@ func (s *GameStore) UpdateGames(games []*pb.Game) {
 	}
 
 	log.WithField("count", len(games)).Debug("Updated games in store")
+
+	s.persistLocked()
 }
 
 func (s *GameStore) UpdateGameMods(mods []*pb.GameMod) {
@@ -41,6 +102,8 @@ func (s *GameStore) UpdateGameMods(mods []*pb.GameMod) {
 	}
 
 	log.WithField("count", len(mods)).Debug("Updated game mods in store")
+
+	s.persistLocked()
 }
 
 func (s *GameStore) FindGame(code string) (domain.Game, bool) {
@@ -58,3 +121,32 @@ func (s *GameStore) FindGameMod(id uint64) (domain.GameMod, bool) {
 	m, ok := s.gameMods[id]
 	return m, ok
 }
+
+func (s *GameStore) persistLocked() {
+	if s.stateFile == "" {
+		return
+	}
+
+	state := gameStoreState{
+		Games:    make([]domain.Game, 0, len(s.games)),
+		GameMods: make([]domain.GameMod, 0, len(s.gameMods)),
+	}
+
+	for _, g := range s.games {
+		state.Games = append(state.Games, g)
+	}
+	sort.Slice(state.Games, func(i, j int) bool {
+		return state.Games[i].Code < state.Games[j].Code
+	})
+
+	for _, m := range s.gameMods {
+		state.GameMods = append(state.GameMods, m)
+	}
+	sort.Slice(state.GameMods, func(i, j int) bool {
+		return state.GameMods[i].ID < state.GameMods[j].ID
+	})
+
+	if err := statefile.Save(s.stateFile, gameStoreFileVersion, state); err != nil {
+		log.WithError(err).Warn("Failed to persist games")
+	}
+}
@@ -12,6 +12,7 @@ import (
 type ServerCacheRepository interface {
 	FindByIDFromCache(id int) (*domain.Server, bool)
 	SaveToCache(server *domain.Server)
+	RetainInCache(ids []int) []int
 }
 
 type GRPCServerHandler struct {
@@ -36,6 +37,19 @@ func (h *GRPCServerHandler) HandleServerConfigUpdate(
 	return h.handleServerProto(srv, parseProtoSettings(settings))
 }
 
+// HandleServersSync removes the servers that are missing from the full list
+// of the node servers sent by the panel.
+func (h *GRPCServerHandler) HandleServersSync(_ context.Context, servers []*pb.Server) {
+	ids := make([]int, 0, len(servers))
+	for _, srv := range servers {
+		ids = append(ids, int(srv.Id))
+	}
+
+	for _, id := range h.serverRepo.RetainInCache(ids) {
+		log.WithField("serverID", id).Info("Server removed, the panel no longer has it")
+	}
+}
+
 func (h *GRPCServerHandler) handleServerProto(srv *pb.Server, settings domain.Settings) error {
 	serverID := int(srv.Id)
 
@@ -0,0 +1,150 @@
+// Package liveness checks that running game servers still respond and
+// restarts the ones that stopped responding while their process is alive.
+// The checks of a server are set in its game metadata, see ProbesForServer.
+package liveness
+
+import (
+	"regexp"
+	"strconv"
+
+	"github.com/gameap/daemon/internal/app/domain"
+	"github.com/gameap/daemon/internal/app/query"
+	"github.com/pkg/errors"
+)
+
+// Server variable and game (mod) metadata keys of the health probes.
+const (
+	// QueryKey makes a query response a probe.
+	QueryKey = "health_query"
+
+	// TCPKey is a TCP port that has to accept connections, "true" for the
+	// connect port of the server.
+	TCPKey = "health_tcp"
+
+	// CommandKey is a console command sent as a heartbeat, e.g. "status".
+	CommandKey = "health_command"
+
+	// ExpectKey is a regular expression the reply to the heartbeat command
+	// has to match. Any new console output is a reply when it is not set.
+	ExpectKey = "health_expect"
+
+	// MaxMemoryKey is the memory usage in MiB above which the server is
+	// considered leaking.
+	MaxMemoryKey = "health_max_memory_mb"
+
+	// MaxCPUKey is the CPU usage in percent above which the server is
+	// considered stuck in a loop.
+	MaxCPUKey = "health_max_cpu_percent"
+
+	// FailureThresholdKey overrides the number of failed checks in a row
+	// that restart the server.
+	FailureThresholdKey = "health_failure_threshold"
+)
+
+var ErrInvalidProbe = errors.New("invalid health probe")
+
+// Probes are the health probes of a server.
+type Probes struct {
+	Query            bool
+	TCPPort          int
+	Command          string
+	Expect           *regexp.Regexp
+	MaxMemoryBytes   uint64
+	MaxCPUPercent    float64
+	FailureThreshold int
+}
+
+// Empty reports whether no probe is configured, such a server is not
+// checked.
+func (p Probes) Empty() bool {
+	return !p.Query && p.TCPPort == 0 && p.Command == "" && p.MaxMemoryBytes == 0 && p.MaxCPUPercent == 0
+}
+
+// ProbesForServer reads the probes of the server. Every key is looked up
+// with priority:
+// 1. Server vars
+// 2. GameMod metadata
+// 3. Game metadata
+func ProbesForServer(server *domain.Server, defaultThreshold int) (Probes, error) {
+	probes := Probes{FailureThreshold: defaultThreshold}
+
+	if val, ok := server.LookupVar(QueryKey); ok {
+		enabled, err := strconv.ParseBool(val)
+		if err != nil {
+			return Probes{}, errors.Wrapf(ErrInvalidProbe, "%s: %q", QueryKey, val)
+		}
+		if enabled && query.ProtocolForServer(server) == query.ProtocolNone {
+			return Probes{}, errors.Wrapf(ErrInvalidProbe, "%s: %s", QueryKey, query.ErrNoProtocol)
+		}
+		probes.Query = enabled
+	}
+
+	if val, ok := server.LookupVar(TCPKey); ok {
+		port, err := parseTCPPort(val, server.ConnectPort())
+		if err != nil {
+			return Probes{}, err
+		}
+		probes.TCPPort = port
+	}
+
+	if val, ok := server.LookupVar(CommandKey); ok {
+		probes.Command = val
+	}
+
+	if val, ok := server.LookupVar(ExpectKey); ok {
+		if probes.Command == "" {
+			return Probes{}, errors.Wrapf(ErrInvalidProbe, "%s without %s", ExpectKey, CommandKey)
+		}
+
+		expect, err := regexp.Compile(val)
+		if err != nil {
+			return Probes{}, errors.Wrapf(ErrInvalidProbe, "%s: %s", ExpectKey, err)
+		}
+		probes.Expect = expect
+	}
+
+	if val, ok := server.LookupVar(MaxMemoryKey); ok {
+		// Numbers of the metadata may come formatted as floats, e.g. 1e+06.
+		mb, err := strconv.ParseFloat(val, 64)
+		if err != nil || mb < 1 {
+			return Probes{}, errors.Wrapf(ErrInvalidProbe, "%s: %q", MaxMemoryKey, val)
+		}
+		probes.MaxMemoryBytes = uint64(mb) << 20
+	}
+
+	if val, ok := server.LookupVar(MaxCPUKey); ok {
+		percent, err := strconv.ParseFloat(val, 64)
+		if err != nil || percent <= 0 {
+			return Probes{}, errors.Wrapf(ErrInvalidProbe, "%s: %q", MaxCPUKey, val)
+		}
+		probes.MaxCPUPercent = percent
+	}
+
+	if val, ok := server.LookupVar(FailureThresholdKey); ok {
+		threshold, err := strconv.Atoi(val)
+		if err != nil || threshold <= 0 {
+			return Probes{}, errors.Wrapf(ErrInvalidProbe, "%s: %q", FailureThresholdKey, val)
+		}
+		probes.FailureThreshold = threshold
+	}
+
+	return probes, nil
+}
+
+func parseTCPPort(val string, connectPort int) (int, error) {
+	port := connectPort
+
+	if enabled, err := strconv.ParseBool(val); err == nil {
+		if !enabled {
+			return 0, nil
+		}
+	} else if port, err = strconv.Atoi(val); err != nil {
+		return 0, errors.Wrapf(ErrInvalidProbe, "%s: %q", TCPKey, val)
+	}
+
+	if port <= 0 || port > 65535 {
+		return 0, errors.Wrapf(ErrInvalidProbe, "%s: no port to check", TCPKey)
+	}
+
+	return port, nil
+}
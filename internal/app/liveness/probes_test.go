@@ -0,0 +1,96 @@
+package liveness
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gameap/daemon/internal/app/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func givenServer(game domain.Game, gameMod domain.GameMod, vars map[string]string) *domain.Server {
+	return domain.NewServer(
+		1,
+		true,
+		domain.ServerInstalled,
+		false,
+		"test",
+		"9fd2e4d1-4a4e-4d3c-9a2f-5a4b5d8c9e01",
+		"9fd2e4d1",
+		game,
+		gameMod,
+		"127.0.0.1",
+		27015,
+		27015,
+		27016,
+		"",
+		"servers/test",
+		"",
+		"./start.sh",
+		"",
+		"",
+		"",
+		true,
+		time.Time{},
+		vars,
+		domain.Settings{},
+		time.Time{},
+		0,
+		0,
+	)
+}
+
+func TestProbesForServer(t *testing.T) {
+	game := domain.Game{Engine: "minecraft", Metadata: map[string]any{
+		CommandKey:   "list",
+		ExpectKey:    `players online`,
+		MaxMemoryKey: float64(4096),
+	}}
+	gameMod := domain.GameMod{Metadata: map[string]any{TCPKey: true, FailureThresholdKey: 2}}
+
+	probes, err := ProbesForServer(givenServer(game, gameMod, map[string]string{MaxCPUKey: "190"}), 3)
+
+	require.NoError(t, err)
+	assert.Equal(t, "list", probes.Command)
+	require.NotNil(t, probes.Expect)
+	assert.True(t, probes.Expect.MatchString("There are 0 of a max of 20 players online:"))
+	assert.Equal(t, uint64(4096)<<20, probes.MaxMemoryBytes)
+	assert.InDelta(t, 190.0, probes.MaxCPUPercent, 0.001)
+	// "true" checks the connect port.
+	assert.Equal(t, 27015, probes.TCPPort)
+	assert.Equal(t, 2, probes.FailureThreshold)
+	assert.False(t, probes.Query)
+	assert.False(t, probes.Empty())
+}
+
+func TestProbesForServer_NoProbes(t *testing.T) {
+	probes, err := ProbesForServer(givenServer(domain.Game{}, domain.GameMod{}, nil), 3)
+
+	require.NoError(t, err)
+	assert.True(t, probes.Empty())
+	assert.Equal(t, 3, probes.FailureThreshold)
+}
+
+func TestProbesForServer_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		vars map[string]string
+	}{
+		{"expect_without_command", map[string]string{ExpectKey: "ok"}},
+		{"invalid_expect", map[string]string{CommandKey: "status", ExpectKey: "("}},
+		{"invalid_tcp", map[string]string{TCPKey: "http"}},
+		{"invalid_memory", map[string]string{MaxMemoryKey: "lots"}},
+		{"invalid_cpu", map[string]string{MaxCPUKey: "-1"}},
+		{"invalid_threshold", map[string]string{FailureThresholdKey: "0"}},
+		{"query_without_protocol", map[string]string{QueryKey: "true"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := ProbesForServer(givenServer(domain.Game{}, domain.GameMod{}, test.vars), 3)
+
+			require.ErrorIs(t, err, ErrInvalidProbe)
+		})
+	}
+}
@@ -0,0 +1,486 @@
+package liveness
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gameap/daemon/internal/app/contracts"
+	"github.com/gameap/daemon/internal/app/domain"
+	"github.com/gameap/daemon/internal/app/query"
+	"github.com/gameap/daemon/internal/app/serverlock"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	// maxConcurrentChecks bounds the servers checked at the same time.
+	maxConcurrentChecks = 16
+
+	// heartbeatWait is how long the console is waited for the reply to the
+	// heartbeat command when it is not returned by the command itself.
+	heartbeatWait = 2 * time.Second
+
+	// restartTimeout bounds the restart of a server, the graceful stop of
+	// a hung server usually ends with killing it.
+	restartTimeout = 5 * time.Minute
+
+	// Names of the process manager metrics the thresholds are checked on.
+	metricCPUUsagePercent = "gameap_server_cpu_usage_percent"
+	metricMemoryUsage     = "gameap_server_memory_usage_bytes"
+)
+
+const (
+	MetricHealthUp       = "gameap_server_health_up"
+	MetricHealthRestarts = "gameap_server_health_restarts_total"
+)
+
+var (
+	errNoHeartbeat = errors.New("no reply to the heartbeat command")
+	errThreshold   = errors.New("threshold exceeded")
+)
+
+// ServerLister exposes the cached set of servers known to the daemon.
+// Implemented by *repositories.ServerRepository.
+type ServerLister interface {
+	IDsFromCache() []int
+	FindByIDFromCache(id int) (*domain.Server, bool)
+}
+
+type CommandLoader interface {
+	LoadServerCommand(cmd domain.ServerCommand, server *domain.Server) contracts.GameServerCommand
+}
+
+// state is what is known about the health of a server.
+type state struct {
+	uuid       string
+	failures   int
+	reason     string
+	restarts   uint64
+	restarting bool
+}
+
+// Service checks the running servers with health probes. A server failing
+// the checks FailureThreshold times in a row is restarted.
+type Service struct {
+	servers  ServerLister
+	console  contracts.ProcessManager
+	commands CommandLoader
+	locks    *serverlock.Locks
+	interval time.Duration
+	timeout  time.Duration
+	// threshold is the default number of failed checks that restart a server.
+	threshold int
+
+	queryFn       func(ctx context.Context, server *domain.Server, full bool) (*query.Result, error)
+	nowFn         func() time.Time
+	heartbeatWait time.Duration
+
+	mu     sync.Mutex
+	states map[int]*state
+}
+
+// NewService creates the service. The console is the process manager or its
+// RCON decorator, heartbeat commands are sent and console output is read
+// through it. The server locks keep the restarts apart from the other work on
+// the servers.
+func NewService(
+	servers ServerLister,
+	console contracts.ProcessManager,
+	commands CommandLoader,
+	locks *serverlock.Locks,
+	interval, timeout time.Duration,
+	threshold int,
+) *Service {
+	return &Service{
+		servers:       servers,
+		console:       console,
+		commands:      commands,
+		locks:         locks,
+		interval:      interval,
+		timeout:       timeout,
+		threshold:     threshold,
+		queryFn:       query.QueryServer,
+		nowFn:         time.Now,
+		heartbeatWait: heartbeatWait,
+		states:        make(map[int]*state),
+	}
+}
+
+// Run checks the servers until ctx is done.
+func (s *Service) Run(ctx context.Context) error {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			s.checkAll(ctx)
+		}
+	}
+}
+
+func (s *Service) checkAll(ctx context.Context) {
+	g := errgroup.Group{}
+	g.SetLimit(maxConcurrentChecks)
+
+	checked := make(map[int]struct{})
+
+	for _, id := range s.servers.IDsFromCache() {
+		server, ok := s.servers.FindByIDFromCache(id)
+		if !ok || server == nil {
+			continue
+		}
+
+		if s.isRestarting(id) {
+			checked[id] = struct{}{}
+			continue
+		}
+
+		// A paused server cannot answer the probes, its failures so far are
+		// dropped.
+		if server.IsPaused() {
+			continue
+		}
+
+		// Starting servers are the readiness probes' business. The state of
+		// a server that was restarted is kept, so the restarts counter keeps
+		// counting.
+		if !server.IsReady() {
+			if server.RunState() != domain.RunStateStopped {
+				checked[id] = struct{}{}
+			}
+
+			continue
+		}
+
+		probes, err := ProbesForServer(server, s.threshold)
+		if err != nil {
+			log.WithError(err).WithField("gameServerID", id).Warn("Health probes of the server are ignored")
+			continue
+		}
+		if probes.Empty() {
+			continue
+		}
+
+		checked[id] = struct{}{}
+
+		g.Go(func() error {
+			s.check(ctx, server, probes)
+			return nil
+		})
+	}
+
+	_ = g.Wait()
+
+	// Stopped servers and servers without probes are not reported.
+	s.mu.Lock()
+	for id := range s.states {
+		if _, ok := checked[id]; !ok {
+			delete(s.states, id)
+		}
+	}
+	s.mu.Unlock()
+}
+
+func (s *Service) check(ctx context.Context, server *domain.Server, probes Probes) {
+	checkCtx, cancel := context.WithTimeout(ctx, s.timeout)
+	err := s.probe(checkCtx, server, probes)
+	cancel()
+
+	if ctx.Err() != nil {
+		return
+	}
+
+	id := server.ID()
+	logger := log.WithField("gameServerID", id)
+
+	s.mu.Lock()
+	st := s.stateLocked(id, server.UUID())
+
+	if err == nil {
+		if st.failures > 0 {
+			logger.Info("Server responds to health checks again")
+		}
+		st.failures, st.reason = 0, ""
+		s.mu.Unlock()
+
+		return
+	}
+
+	st.failures++
+	st.reason = err.Error()
+	failures := st.failures
+	s.mu.Unlock()
+
+	logger = logger.WithFields(log.Fields{
+		"reason":    err.Error(),
+		"failures":  failures,
+		"threshold": probes.FailureThreshold,
+	})
+
+	if failures < probes.FailureThreshold {
+		logger.Info("Server health check failed")
+		return
+	}
+
+	// A server busy with a panel task, a scheduled task or an admin API
+	// command is not restarted under it, the failures are kept and the next
+	// check tries again.
+	unlock, ok := s.locks.TryLock(id, true)
+	if !ok {
+		logger.Info("Server is not responding, it is restarted once it is not busy")
+		return
+	}
+
+	// The server may have been paused between the probes and the lock.
+	if server.IsPaused() {
+		unlock()
+		return
+	}
+
+	s.mu.Lock()
+	st = s.stateLocked(id, server.UUID())
+	st.restarting = true
+	st.restarts++
+	s.mu.Unlock()
+
+	logger.Warn("Server is not responding, restarting it")
+
+	go s.restart(ctx, server, err, unlock)
+}
+
+// restart restarts the server holding its lock, unlock releases it.
+func (s *Service) restart(ctx context.Context, server *domain.Server, reason error, unlock func()) {
+	defer unlock()
+	defer func() {
+		s.mu.Lock()
+		if st, ok := s.states[server.ID()]; ok {
+			st.restarting = false
+			st.failures = 0
+		}
+		s.mu.Unlock()
+	}()
+
+	server.SetRunState(domain.RunStateUnhealthy)
+
+	cmd := s.commands.LoadServerCommand(domain.Restart, server)
+	if cmd == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, restartTimeout)
+	defer cancel()
+
+	logger := log.WithField("gameServerID", server.ID()).WithField("reason", reason.Error())
+
+	if err := cmd.Execute(ctx, server); err != nil {
+		logger.WithError(err).Error("Failed to restart not responding server")
+		return
+	}
+
+	if cmd.Result() != int(domain.SuccessResult) {
+		logger.WithField("output", string(cmd.ReadOutput())).Error("Failed to restart not responding server")
+		return
+	}
+
+	logger.Info("Not responding server restarted")
+}
+
+// probe runs every probe of the server, the first failure is returned.
+func (s *Service) probe(ctx context.Context, server *domain.Server, probes Probes) error {
+	if probes.TCPPort != 0 {
+		if err := dialTCP(ctx, server, probes.TCPPort); err != nil {
+			return err
+		}
+	}
+
+	if probes.Query {
+		if _, err := s.queryFn(ctx, server, false); err != nil {
+			return errors.WithMessage(err, "query")
+		}
+	}
+
+	if probes.MaxMemoryBytes != 0 || probes.MaxCPUPercent != 0 {
+		if err := s.checkThresholds(ctx, server, probes); err != nil {
+			return err
+		}
+	}
+
+	if probes.Command != "" {
+		if err := s.heartbeat(ctx, server, probes); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *Service) checkThresholds(ctx context.Context, server *domain.Server, probes Probes) error {
+	metrics, err := s.console.Metrics(ctx, server)
+	if err != nil {
+		// The process manager not reporting metrics does not make the
+		// server unhealthy.
+		log.WithError(err).WithField("gameServerID", server.ID()).Debug("Failed to read server metrics")
+		return nil
+	}
+
+	for _, m := range metrics {
+		value := m.Value.AsFloat64()
+
+		switch {
+		case m.Name == metricMemoryUsage && probes.MaxMemoryBytes != 0 && value > float64(probes.MaxMemoryBytes):
+			return errors.Wrapf(errThreshold, "memory usage %d MiB, at most %d MiB",
+				uint64(value)>>20, probes.MaxMemoryBytes>>20)
+		case m.Name == metricCPUUsagePercent && probes.MaxCPUPercent != 0 && value > probes.MaxCPUPercent:
+			return errors.Wrapf(errThreshold, "cpu usage %.1f%%, at most %.1f%%", value, probes.MaxCPUPercent)
+		}
+	}
+
+	return nil
+}
+
+// heartbeat sends the command and waits for the reply. RCON returns the
+// reply right away, otherwise it is looked for in new console lines.
+func (s *Service) heartbeat(ctx context.Context, server *domain.Server, probes Probes) error {
+	before, err := s.outputLines(ctx, server)
+	if err != nil {
+		return errors.WithMessage(err, "failed to read console output")
+	}
+
+	var reply bytes.Buffer
+
+	if _, err := s.console.SendInput(ctx, probes.Command, server, &reply); err != nil {
+		return errors.WithMessage(err, "failed to send heartbeat command")
+	}
+
+	if replied(domain.ConsoleLines(reply.Bytes()), probes.Expect) {
+		return nil
+	}
+
+	select {
+	case <-ctx.Done():
+		return errNoHeartbeat
+	case <-time.After(s.heartbeatWait):
+	}
+
+	after, err := s.outputLines(ctx, server)
+	if err != nil {
+		return errors.WithMessage(err, "failed to read console output")
+	}
+
+	if replied(domain.NewConsoleLines(before, after), probes.Expect) {
+		return nil
+	}
+
+	return errNoHeartbeat
+}
+
+// replied reports whether a line matches the expected reply.
+func replied(lines []string, expect *regexp.Regexp) bool {
+	for _, line := range lines {
+		if line == "" {
+			continue
+		}
+
+		if expect == nil || expect.MatchString(line) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (s *Service) outputLines(ctx context.Context, server *domain.Server) ([]string, error) {
+	var out bytes.Buffer
+
+	if _, err := s.console.GetOutput(ctx, server, &out); err != nil {
+		return nil, err
+	}
+
+	return domain.ConsoleLines(out.Bytes()), nil
+}
+
+func dialTCP(ctx context.Context, server *domain.Server, port int) error {
+	var d net.Dialer
+
+	conn, err := d.DialContext(ctx, "tcp", server.DialAddress(port))
+	if err != nil {
+		return errors.Wrapf(err, "tcp port %d", port)
+	}
+	_ = conn.Close()
+
+	return nil
+}
+
+func (s *Service) isRestarting(id int) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	st, ok := s.states[id]
+
+	return ok && st.restarting
+}
+
+func (s *Service) stateLocked(id int, uuid string) *state {
+	st, ok := s.states[id]
+	if !ok {
+		st = &state{uuid: uuid}
+		s.states[id] = st
+	}
+
+	return st
+}
+
+// Collect reports the health of the checked servers.
+func (s *Service) Collect(_ context.Context) ([]domain.Metric, error) {
+	now := s.nowFn()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	out := make([]domain.Metric, 0, len(s.states)*2)
+
+	for id, st := range s.states {
+		labels := func() map[string]string {
+			l := map[string]string{"server_id": strconv.Itoa(id)}
+			if st.uuid != "" {
+				l["server_uuid"] = st.uuid
+			}
+
+			return l
+		}
+
+		var up uint64
+		if st.failures == 0 && !st.restarting {
+			up = 1
+		}
+
+		out = append(out,
+			domain.Metric{
+				Name:      MetricHealthUp,
+				Type:      domain.MetricTypeGauge,
+				Labels:    labels(),
+				Timestamp: now,
+				Value:     domain.Uint64Value(up),
+			},
+			domain.Metric{
+				Name:      MetricHealthRestarts,
+				Type:      domain.MetricTypeCounter,
+				Unit:      domain.MetricUnitCount,
+				Labels:    labels(),
+				Timestamp: now,
+				Value:     domain.Uint64Value(st.restarts),
+			},
+		)
+	}
+
+	return out, nil
+}
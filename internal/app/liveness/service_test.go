@@ -0,0 +1,303 @@
+package liveness
+
+import (
+	"context"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gameap/daemon/internal/app/contracts"
+	"github.com/gameap/daemon/internal/app/domain"
+	"github.com/gameap/daemon/internal/app/serverlock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeProcessManager struct {
+	contracts.ProcessManager
+
+	mu      sync.Mutex
+	output  string
+	replies map[string]string
+	metrics []domain.Metric
+}
+
+func (pm *fakeProcessManager) GetOutput(_ context.Context, _ *domain.Server, out io.Writer) (domain.Result, error) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	_, _ = io.WriteString(out, pm.output)
+
+	return domain.SuccessResult, nil
+}
+
+func (pm *fakeProcessManager) SendInput(
+	_ context.Context, input string, _ *domain.Server, _ io.Writer,
+) (domain.Result, error) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	// The reply shows up in the console output as with tmux.
+	pm.output += pm.replies[input]
+
+	return domain.SuccessResult, nil
+}
+
+func (pm *fakeProcessManager) Metrics(_ context.Context, _ *domain.Server) ([]domain.Metric, error) {
+	return pm.metrics, nil
+}
+
+type fakeServers struct {
+	servers map[int]*domain.Server
+}
+
+func (s *fakeServers) IDsFromCache() []int {
+	ids := make([]int, 0, len(s.servers))
+	for id := range s.servers {
+		ids = append(ids, id)
+	}
+
+	return ids
+}
+
+func (s *fakeServers) FindByIDFromCache(id int) (*domain.Server, bool) {
+	server, ok := s.servers[id]
+
+	return server, ok
+}
+
+type fakeCommand struct {
+	executed chan *domain.Server
+}
+
+func (c *fakeCommand) Execute(_ context.Context, server *domain.Server) error {
+	c.executed <- server
+
+	return nil
+}
+
+func (c *fakeCommand) ReadOutput() []byte { return nil }
+func (c *fakeCommand) Result() int        { return int(domain.SuccessResult) }
+func (c *fakeCommand) IsComplete() bool   { return true }
+
+type fakeCommands struct {
+	restart *fakeCommand
+}
+
+func (f *fakeCommands) LoadServerCommand(cmd domain.ServerCommand, _ *domain.Server) contracts.GameServerCommand {
+	if cmd != domain.Restart {
+		return nil
+	}
+
+	return f.restart
+}
+
+func givenService(
+	pm *fakeProcessManager, server *domain.Server,
+) (*Service, *fakeCommand) {
+	restart := &fakeCommand{executed: make(chan *domain.Server, 1)}
+	service := NewService(
+		&fakeServers{servers: map[int]*domain.Server{server.ID(): server}},
+		pm,
+		&fakeCommands{restart: restart},
+		serverlock.New(),
+		time.Minute,
+		time.Second,
+		2,
+	)
+	service.heartbeatWait = 10 * time.Millisecond
+
+	return service, restart
+}
+
+func metricValue(t *testing.T, service *Service, name string) float64 {
+	t.Helper()
+
+	metrics, err := service.Collect(context.Background())
+	require.NoError(t, err)
+
+	for _, m := range metrics {
+		if m.Name == name {
+			return m.Value.AsFloat64()
+		}
+	}
+
+	t.Fatalf("metric %s is not collected", name)
+
+	return 0
+}
+
+func TestService_Heartbeat(t *testing.T) {
+	pm := &fakeProcessManager{
+		output:  "There are 0 of a max of 20 players online:\n",
+		replies: map[string]string{"list": "There are 1 of a max of 20 players online: player\n"},
+	}
+	server := givenServer(domain.Game{}, domain.GameMod{}, map[string]string{
+		CommandKey: "list",
+		ExpectKey:  "players online",
+	})
+	service, _ := givenService(pm, server)
+
+	service.checkAll(context.Background())
+
+	assert.InDelta(t, 1.0, metricValue(t, service, MetricHealthUp), 0)
+}
+
+func TestService_Heartbeat_StableReply(t *testing.T) {
+	pm := &fakeProcessManager{replies: map[string]string{"ping": "pong\n"}}
+	server := givenServer(domain.Game{}, domain.GameMod{}, map[string]string{
+		CommandKey: "ping",
+		ExpectKey:  "^pong$",
+	})
+	service, restart := givenService(pm, server)
+
+	for range 3 {
+		service.checkAll(context.Background())
+
+		assert.InDelta(t, 1.0, metricValue(t, service, MetricHealthUp), 0)
+	}
+	assert.Empty(t, restart.executed)
+}
+
+func TestService_HeartbeatWithoutReply_RestartsAfterThreshold(t *testing.T) {
+	// The reply of the previous check is still in the console output.
+	pm := &fakeProcessManager{output: "There are 0 of a max of 20 players online:\n"}
+	server := givenServer(domain.Game{}, domain.GameMod{}, map[string]string{
+		CommandKey: "list",
+		ExpectKey:  "players online",
+	})
+	service, restart := givenService(pm, server)
+
+	service.checkAll(context.Background())
+
+	assert.InDelta(t, 0.0, metricValue(t, service, MetricHealthUp), 0)
+	assert.Empty(t, restart.executed)
+
+	service.checkAll(context.Background())
+
+	select {
+	case restarted := <-restart.executed:
+		assert.Same(t, server, restarted)
+	case <-time.After(time.Second):
+		t.Fatal("server is not restarted")
+	}
+
+	assert.Equal(t, domain.RunStateUnhealthy, server.RunState())
+	assert.InDelta(t, 1.0, metricValue(t, service, MetricHealthRestarts), 0)
+
+	// The restarted server is starting again, its restarts are not forgotten.
+	require.Eventually(t, func() bool { return !service.isRestarting(server.ID()) }, time.Second, time.Millisecond)
+	server.AffectStart()
+	service.checkAll(context.Background())
+
+	assert.InDelta(t, 1.0, metricValue(t, service, MetricHealthRestarts), 0)
+}
+
+func TestService_BusyServerIsRestartedLater(t *testing.T) {
+	pm := &fakeProcessManager{}
+	server := givenServer(domain.Game{}, domain.GameMod{}, map[string]string{
+		CommandKey: "list",
+		ExpectKey:  "players online",
+	})
+	service, restart := givenService(pm, server)
+	unlock, err := service.locks.Lock(context.Background(), server.ID(), false)
+	require.NoError(t, err)
+
+	for range 3 {
+		service.checkAll(context.Background())
+	}
+
+	assert.Empty(t, restart.executed)
+	assert.Equal(t, domain.RunStateRunning, server.RunState())
+	assert.InDelta(t, 0.0, metricValue(t, service, MetricHealthRestarts), 0)
+
+	unlock()
+	service.checkAll(context.Background())
+
+	select {
+	case <-restart.executed:
+	case <-time.After(time.Second):
+		t.Fatal("server is not restarted once the lock is released")
+	}
+	require.Eventually(t, func() bool { return !service.isRestarting(server.ID()) }, time.Second, time.Millisecond)
+	_, free := service.locks.TryLock(server.ID(), true)
+	assert.True(t, free, "the restart releases the lock")
+}
+
+func TestService_PausedServerIsNotRestarted(t *testing.T) {
+	pm := &fakeProcessManager{}
+	server := givenServer(domain.Game{}, domain.GameMod{}, map[string]string{
+		CommandKey: "list",
+		ExpectKey:  "players online",
+	})
+	service, restart := givenService(pm, server)
+
+	service.checkAll(context.Background())
+	require.InDelta(t, 0.0, metricValue(t, service, MetricHealthUp), 0)
+
+	server.AffectPause()
+	for range 3 {
+		service.checkAll(context.Background())
+	}
+
+	assert.Empty(t, restart.executed)
+	assert.Equal(t, domain.RunStatePaused, server.RunState())
+	metrics, err := service.Collect(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, metrics, "the failures before the pause are dropped")
+}
+
+func TestService_TCP(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	port := l.Addr().(*net.TCPAddr).Port
+
+	server := givenServer(domain.Game{}, domain.GameMod{}, map[string]string{TCPKey: strconv.Itoa(port)})
+	service, _ := givenService(&fakeProcessManager{}, server)
+
+	service.checkAll(context.Background())
+	assert.InDelta(t, 1.0, metricValue(t, service, MetricHealthUp), 0)
+
+	require.NoError(t, l.Close())
+
+	service.checkAll(context.Background())
+	assert.InDelta(t, 0.0, metricValue(t, service, MetricHealthUp), 0)
+}
+
+func TestService_MemoryThreshold(t *testing.T) {
+	pm := &fakeProcessManager{metrics: []domain.Metric{
+		{Name: metricMemoryUsage, Value: domain.Uint64Value(3 << 30)},
+		{Name: metricCPUUsagePercent, Value: domain.Float64Value(12.5)},
+	}}
+	server := givenServer(domain.Game{}, domain.GameMod{}, map[string]string{MaxMemoryKey: "2048"})
+	service, _ := givenService(pm, server)
+
+	err := service.probe(context.Background(), server, mustProbes(t, server))
+
+	require.ErrorIs(t, err, errThreshold)
+	assert.Contains(t, err.Error(), "memory usage 3072 MiB")
+}
+
+func TestService_SkipsServersNotReady(t *testing.T) {
+	server := givenServer(domain.Game{}, domain.GameMod{}, map[string]string{CommandKey: "list"})
+	server.AffectStart()
+	service, _ := givenService(&fakeProcessManager{}, server)
+
+	service.checkAll(context.Background())
+
+	metrics, err := service.Collect(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, metrics)
+}
+
+func mustProbes(t *testing.T, server *domain.Server) Probes {
+	t.Helper()
+
+	probes, err := ProbesForServer(server, 2)
+	require.NoError(t, err)
+
+	return probes
+}
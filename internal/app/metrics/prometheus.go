@@ -0,0 +1,202 @@
+package metrics
+
+import (
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gameap/daemon/internal/app/domain"
+)
+
+// PrometheusContentType is the content type of the text exposition format
+// written by WritePrometheusText.
+const PrometheusContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+var unitNames = map[domain.MetricUnit]string{
+	domain.MetricUnitCount:        "count",
+	domain.MetricUnitPercent:      "percent",
+	domain.MetricUnitRatio:        "ratio",
+	domain.MetricUnitBytes:        "bytes",
+	domain.MetricUnitBits:         "bits",
+	domain.MetricUnitSeconds:      "seconds",
+	domain.MetricUnitMilliseconds: "milliseconds",
+	domain.MetricUnitMicroseconds: "microseconds",
+	domain.MetricUnitNanoseconds:  "nanoseconds",
+	domain.MetricUnitHertz:        "hertz",
+	domain.MetricUnitCelsius:      "celsius",
+	domain.MetricUnitWatts:        "watts",
+	domain.MetricUnitVolts:        "volts",
+	domain.MetricUnitRPM:          "rpm",
+}
+
+// WritePrometheusText renders samples in the Prometheus text exposition
+// format. Samples are grouped into families by name, every family gets HELP
+// and TYPE lines built from the metric type and unit. Timestamps are omitted,
+// the scrape time is used instead.
+func WritePrometheusText(w io.Writer, samples []domain.Metric) error {
+	families := make(map[string][]domain.Metric)
+	for _, m := range samples {
+		if !m.Value.IsSet() {
+			continue
+		}
+
+		name := sanitizeMetricName(m.Name)
+		families[name] = append(families[name], m)
+	}
+
+	names := make([]string, 0, len(families))
+	for name := range families {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+
+	for _, name := range names {
+		family := families[name]
+		sort.Slice(family, func(i, j int) bool {
+			return family[i].SeriesKey() < family[j].SeriesKey()
+		})
+
+		first := family[0]
+
+		b.WriteString("# HELP ")
+		b.WriteString(name)
+		b.WriteByte(' ')
+		b.WriteString(escapeHelp(prometheusHelp(first)))
+		b.WriteString("\n# TYPE ")
+		b.WriteString(name)
+		b.WriteByte(' ')
+		b.WriteString(prometheusType(first.Type))
+		b.WriteByte('\n')
+
+		for _, m := range family {
+			b.WriteString(name)
+			writeLabels(&b, m.Labels)
+			b.WriteByte(' ')
+			b.WriteString(formatValue(m.Value))
+			b.WriteByte('\n')
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+
+	return err
+}
+
+func prometheusType(t domain.MetricType) string {
+	switch t {
+	case domain.MetricTypeGauge:
+		return "gauge"
+	case domain.MetricTypeCounter:
+		return "counter"
+	default:
+		return "untyped"
+	}
+}
+
+// prometheusHelp describes the metric with its name and unit, e.g.
+// "Node cpu usage percent (percent, gauge)".
+func prometheusHelp(m domain.Metric) string {
+	text := strings.ReplaceAll(strings.TrimPrefix(m.Name, "gameap_"), "_", " ")
+	if text != "" {
+		text = strings.ToUpper(text[:1]) + text[1:]
+	}
+
+	details := prometheusType(m.Type)
+	if unit, ok := unitNames[m.Unit]; ok {
+		details = unit + ", " + details
+	}
+
+	return text + " (" + details + ")"
+}
+
+func writeLabels(b *strings.Builder, labels map[string]string) {
+	if len(labels) == 0 {
+		return
+	}
+
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	b.WriteByte('{')
+	for i, k := range keys {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(sanitizeLabelName(k))
+		b.WriteString(`="`)
+		b.WriteString(escapeLabelValue(labels[k]))
+		b.WriteByte('"')
+	}
+	b.WriteByte('}')
+}
+
+func formatValue(v domain.MetricValue) string {
+	switch {
+	case v.IsUint64():
+		return strconv.FormatUint(v.Uint64(), 10)
+	case v.IsInt64():
+		return strconv.FormatInt(v.Int64(), 10)
+	}
+
+	f := v.Float64()
+	switch {
+	case math.IsNaN(f):
+		return "NaN"
+	case math.IsInf(f, 1):
+		return "+Inf"
+	case math.IsInf(f, -1):
+		return "-Inf"
+	default:
+		return strconv.FormatFloat(f, 'g', -1, 64)
+	}
+}
+
+// sanitizeMetricName replaces characters that are not allowed in metric
+// names with underscores.
+func sanitizeMetricName(name string) string {
+	return sanitizeName(name, true)
+}
+
+func sanitizeLabelName(name string) string {
+	return sanitizeName(name, false)
+}
+
+func sanitizeName(name string, allowColon bool) string {
+	if name == "" {
+		return "_"
+	}
+
+	b := []byte(name)
+	for i, c := range b {
+		valid := c == '_' ||
+			(c >= 'a' && c <= 'z') ||
+			(c >= 'A' && c <= 'Z') ||
+			(i > 0 && c >= '0' && c <= '9') ||
+			(allowColon && c == ':')
+		if !valid {
+			b[i] = '_'
+		}
+	}
+
+	return string(b)
+}
+
+var (
+	helpEscaper       = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelValueEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func escapeHelp(s string) string {
+	return helpEscaper.Replace(s)
+}
+
+func escapeLabelValue(s string) string {
+	return labelValueEscaper.Replace(s)
+}
@@ -0,0 +1,111 @@
+package metrics
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/gameap/daemon/internal/app/domain"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	PrometheusMetricsPath = "/metrics"
+
+	prometheusReadHeaderTimeout = 10 * time.Second
+	prometheusShutdownTimeout   = 5 * time.Second
+
+	// A series is exported while its last point is not older than this many
+	// collection intervals. Series of stopped servers stay in the buffer for
+	// the whole retention and would otherwise be scraped as if they were live.
+	prometheusStaleIntervals = 3
+)
+
+// PrometheusExporter serves the latest point of every series from the
+// buffer on /metrics.
+type PrometheusExporter struct {
+	buffer     *Buffer
+	listen     string
+	staleAfter time.Duration
+	nowFn      func() time.Time
+}
+
+func NewPrometheusExporter(buffer *Buffer, listen string, collectionInterval time.Duration) *PrometheusExporter {
+	return &PrometheusExporter{
+		buffer:     buffer,
+		listen:     listen,
+		staleAfter: collectionInterval * prometheusStaleIntervals,
+		nowFn:      time.Now,
+	}
+}
+
+func (e *PrometheusExporter) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET "+PrometheusMetricsPath, e.handleMetrics)
+
+	return mux
+}
+
+// Run serves the exporter until ctx is done.
+func (e *PrometheusExporter) Run(ctx context.Context) error {
+	lc := net.ListenConfig{}
+
+	l, err := lc.Listen(ctx, "tcp", e.listen)
+	if err != nil {
+		return errors.Wrapf(err, "failed to listen on %s", e.listen)
+	}
+
+	srv := &http.Server{
+		Handler:           e.Handler(),
+		ReadHeaderTimeout: prometheusReadHeaderTimeout,
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- srv.Serve(l)
+	}()
+
+	log.WithField("address", l.Addr().String()).Info("Prometheus exporter listening")
+
+	select {
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), prometheusShutdownTimeout)
+		defer cancel()
+
+		return srv.Shutdown(shutdownCtx)
+	case err := <-errCh:
+		if errors.Is(err, http.ErrServerClosed) {
+			return nil
+		}
+
+		return errors.Wrap(err, "prometheus exporter stopped")
+	}
+}
+
+func (e *PrometheusExporter) handleMetrics(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", PrometheusContentType)
+
+	if err := WritePrometheusText(w, e.current()); err != nil {
+		log.WithError(err).Debug("failed to write prometheus metrics")
+	}
+}
+
+func (e *PrometheusExporter) current() []domain.Metric {
+	samples := e.buffer.Current()
+	if e.staleAfter <= 0 {
+		return samples
+	}
+
+	cutoff := e.nowFn().Add(-e.staleAfter)
+
+	fresh := samples[:0]
+	for _, m := range samples {
+		if !m.Timestamp.Before(cutoff) {
+			fresh = append(fresh, m)
+		}
+	}
+
+	return fresh
+}
@@ -0,0 +1,109 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gameap/daemon/internal/app/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWritePrometheusText(t *testing.T) {
+	ts := time.Now()
+	samples := []domain.Metric{
+		{
+			Name:      "gameap_server_memory_usage_bytes",
+			Type:      domain.MetricTypeGauge,
+			Unit:      domain.MetricUnitBytes,
+			Labels:    map[string]string{"server_id": "2", "name": `de "dust"`},
+			Timestamp: ts,
+			Value:     domain.Uint64Value(2048),
+		},
+		{
+			Name:      "gameap_server_memory_usage_bytes",
+			Type:      domain.MetricTypeGauge,
+			Unit:      domain.MetricUnitBytes,
+			Labels:    map[string]string{"server_id": "1"},
+			Timestamp: ts,
+			Value:     domain.Uint64Value(1024),
+		},
+		{
+			Name:      "gameap_node_network_receive_bytes_total",
+			Type:      domain.MetricTypeCounter,
+			Unit:      domain.MetricUnitBytes,
+			Labels:    map[string]string{"interface": "eth0"},
+			Timestamp: ts,
+			Value:     domain.Uint64Value(42),
+		},
+		{
+			Name:      "gameap_node_cpu_usage_percent",
+			Type:      domain.MetricTypeGauge,
+			Unit:      domain.MetricUnitPercent,
+			Timestamp: ts,
+			Value:     domain.Float64Value(12.5),
+		},
+		{
+			Name:      "gameap_node_unset",
+			Timestamp: ts,
+		},
+	}
+
+	var b strings.Builder
+	require.NoError(t, WritePrometheusText(&b, samples))
+
+	assert.Equal(t, `# HELP gameap_node_cpu_usage_percent Node cpu usage percent (percent, gauge)
+# TYPE gameap_node_cpu_usage_percent gauge
+gameap_node_cpu_usage_percent 12.5
+# HELP gameap_node_network_receive_bytes_total Node network receive bytes total (bytes, counter)
+# TYPE gameap_node_network_receive_bytes_total counter
+gameap_node_network_receive_bytes_total{interface="eth0"} 42
+# HELP gameap_server_memory_usage_bytes Server memory usage bytes (bytes, gauge)
+# TYPE gameap_server_memory_usage_bytes gauge
+gameap_server_memory_usage_bytes{name="de \"dust\"",server_id="2"} 2048
+gameap_server_memory_usage_bytes{server_id="1"} 1024
+`, b.String())
+}
+
+func TestSanitizeName(t *testing.T) {
+	assert.Equal(t, "gameap_node_load1", sanitizeMetricName("gameap_node_load1"))
+	assert.Equal(t, "gameap_node_cpu_usage", sanitizeMetricName("gameap.node-cpu usage"))
+	assert.Equal(t, "_xx", sanitizeLabelName("1xx"))
+	assert.Equal(t, "a_b", sanitizeLabelName("a:b"))
+}
+
+func TestPrometheusExporter_SkipsStaleSeries(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	buffer := NewBuffer(10 * time.Minute)
+	buffer.Append([]domain.Metric{
+		{
+			Name:      "gameap_server_up",
+			Type:      domain.MetricTypeGauge,
+			Labels:    map[string]string{"server_id": "1"},
+			Timestamp: now.Add(-time.Minute),
+			Value:     domain.Uint64Value(1),
+		},
+		{
+			Name:      "gameap_server_up",
+			Type:      domain.MetricTypeGauge,
+			Labels:    map[string]string{"server_id": "2"},
+			Timestamp: now.Add(-5 * time.Second),
+			Value:     domain.Uint64Value(1),
+		},
+	})
+
+	exporter := NewPrometheusExporter(buffer, "", 5*time.Second)
+	exporter.nowFn = func() time.Time { return now }
+
+	rec := httptest.NewRecorder()
+	exporter.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, PrometheusMetricsPath, nil))
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, PrometheusContentType, rec.Header().Get("Content-Type"))
+	assert.Contains(t, rec.Body.String(), `gameap_server_up{server_id="2"} 1`)
+	assert.NotContains(t, rec.Body.String(), `server_id="1"`)
+}
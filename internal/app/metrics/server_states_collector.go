@@ -0,0 +1,82 @@
+package metrics
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/gameap/daemon/internal/app/domain"
+)
+
+const (
+	// serverMetricRunState is 1 for the current run state of a server, given
+	// in the state label: stopped, starting, running or unhealthy. The
+	// status message to the panel only tells whether the process runs.
+	serverMetricRunState = "gameap_server_run_state"
+
+	// serverMetricCrashLooping is 1 while autostart of a server is suspended
+	// because it crashed too many times in a row.
+	serverMetricCrashLooping = "gameap_server_crash_looping"
+
+	labelState = "state"
+)
+
+// ServerStatesCollector reports the state of every known server as seen by
+// the daemon, the process manager is not asked.
+type ServerStatesCollector struct {
+	servers ServerLister
+	nowFn   func() time.Time
+}
+
+func NewServerStatesCollector(servers ServerLister) *ServerStatesCollector {
+	return &ServerStatesCollector{servers: servers, nowFn: time.Now}
+}
+
+func (c *ServerStatesCollector) Collect(_ context.Context) ([]domain.Metric, error) {
+	now := c.nowFn()
+	ids := c.servers.IDsFromCache()
+	out := make([]domain.Metric, 0, 2*len(ids))
+
+	for _, id := range ids {
+		server, ok := c.servers.FindByIDFromCache(id)
+		if !ok || server == nil {
+			continue
+		}
+
+		stateLabels := serverLabels(server)
+		stateLabels[labelState] = string(server.RunState())
+
+		var crashLooping uint64
+		if server.IsCrashLooping() {
+			crashLooping = 1
+		}
+
+		out = append(out,
+			domain.Metric{
+				Name:      serverMetricRunState,
+				Type:      domain.MetricTypeGauge,
+				Labels:    stateLabels,
+				Timestamp: now,
+				Value:     domain.Uint64Value(1),
+			},
+			domain.Metric{
+				Name:      serverMetricCrashLooping,
+				Type:      domain.MetricTypeGauge,
+				Labels:    serverLabels(server),
+				Timestamp: now,
+				Value:     domain.Uint64Value(crashLooping),
+			},
+		)
+	}
+
+	return out, nil
+}
+
+func serverLabels(server *domain.Server) map[string]string {
+	labels := map[string]string{labelServerID: strconv.Itoa(server.ID())}
+	if server.UUID() != "" {
+		labels["server_uuid"] = server.UUID()
+	}
+
+	return labels
+}
@@ -0,0 +1,50 @@
+package metrics
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gameap/daemon/internal/app/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServerStatesCollector_RunState(t *testing.T) {
+	starting := newTestServer(1, "uuid-1")
+	starting.AffectStart()
+	running := newTestServer(2, "uuid-2")
+
+	c := NewServerStatesCollector(&fakeServerLister{servers: map[int]*domain.Server{1: starting, 2: running}})
+	got, err := c.Collect(context.Background())
+
+	require.NoError(t, err)
+	states := make(map[string]string)
+	for _, m := range got {
+		if m.Name != serverMetricRunState {
+			continue
+		}
+		assert.InDelta(t, 1.0, m.Value.AsFloat64(), 0)
+		states[m.Labels[labelServerID]] = m.Labels[labelState]
+	}
+	assert.Equal(t, map[string]string{"1": "starting", "2": "running"}, states)
+}
+
+func TestServerStatesCollector_CrashLooping(t *testing.T) {
+	looping := newTestServer(1, "uuid-1")
+	looping.SetCrashLooping(true)
+	healthy := newTestServer(2, "uuid-2")
+
+	c := NewServerStatesCollector(&fakeServerLister{servers: map[int]*domain.Server{1: looping, 2: healthy}})
+	got, err := c.Collect(context.Background())
+
+	require.NoError(t, err)
+	values := make(map[string]float64)
+	for _, m := range got {
+		if m.Name != serverMetricCrashLooping {
+			continue
+		}
+		assert.Equal(t, "uuid-"+m.Labels[labelServerID], m.Labels["server_uuid"])
+		values[m.Labels[labelServerID]] = m.Value.AsFloat64()
+	}
+	assert.Equal(t, map[string]float64{"1": 1, "2": 0}, values)
+}
@@ -50,12 +50,21 @@ func (f *fakeProcessManager) Start(_ context.Context, _ *domain.Server, _ io.Wri
 func (f *fakeProcessManager) Stop(_ context.Context, _ *domain.Server, _ io.Writer) (domain.Result, error) {
 	return domain.SuccessResult, nil
 }
+func (f *fakeProcessManager) Kill(_ context.Context, _ *domain.Server, _ io.Writer) (domain.Result, error) {
+	return domain.SuccessResult, nil
+}
 func (f *fakeProcessManager) Restart(_ context.Context, _ *domain.Server, _ io.Writer) (domain.Result, error) {
 	return domain.SuccessResult, nil
 }
 func (f *fakeProcessManager) Status(_ context.Context, _ *domain.Server, _ io.Writer) (domain.Result, error) {
 	return domain.SuccessResult, nil
 }
+func (f *fakeProcessManager) Pause(_ context.Context, _ *domain.Server, _ io.Writer) (domain.Result, error) {
+	return domain.SuccessResult, nil
+}
+func (f *fakeProcessManager) Unpause(_ context.Context, _ *domain.Server, _ io.Writer) (domain.Result, error) {
+	return domain.SuccessResult, nil
+}
 func (f *fakeProcessManager) GetOutput(_ context.Context, _ *domain.Server, _ io.Writer) (domain.Result, error) {
 	return domain.SuccessResult, nil
 }
@@ -0,0 +1,354 @@
+package query
+
+import (
+	"context"
+	"math"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	a2sSinglePacket = 0xFFFFFFFF
+	a2sSplitPacket  = 0xFFFFFFFE
+
+	a2sInfoRequest    = 0x54
+	a2sPlayerRequest  = 0x55
+	a2sRulesRequest   = 0x56
+	a2sChallenge      = 0x41
+	a2sInfoResponse   = 0x49
+	a2sGoldSrcInfo    = 0x6D
+	a2sPlayerResponse = 0x44
+	a2sRulesResponse  = 0x45
+
+	a2sCompressedFlag = 0x80000000
+
+	// maxPlayerSeconds bounds the connection time of a player, servers of
+	// some games send garbage there.
+	maxPlayerSeconds = 365 * 24 * 60 * 60
+
+	// a2sRulesWait is how long the optional A2S_RULES answer is waited for.
+	a2sRulesWait = 500 * time.Millisecond
+
+	// a2sMaxChallenges bounds the challenge round trips of one request.
+	a2sMaxChallenges = 3
+)
+
+var a2sInfoPayload = []byte("Source Engine Query\x00")
+
+var errA2SCompressed = errors.New("compressed A2S responses are not supported")
+
+// A2SClient implements the Valve server query protocol. GoldSrc selects the
+// split packet header of GoldSrc servers, both Source and obsolete GoldSrc
+// info responses are understood either way.
+type A2SClient struct {
+	GoldSrc bool
+}
+
+func (c *A2SClient) Query(ctx context.Context, address string, full bool) (*Result, error) {
+	conn, err := dial(ctx, "udp", address)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	started := time.Now()
+
+	info, err := c.request(conn, a2sInfoRequest, a2sInfoPayload)
+	if err != nil {
+		return nil, errors.WithMessage(err, "A2S_INFO")
+	}
+
+	result := &Result{
+		Protocol: ProtocolA2S,
+		Latency:  time.Since(started),
+	}
+	if c.GoldSrc {
+		result.Protocol = ProtocolGoldSrc
+	}
+
+	if err := parseA2SInfo(info, result); err != nil {
+		return nil, err
+	}
+
+	if !full {
+		result.QueriedAt = time.Now()
+		return result, nil
+	}
+
+	players, err := c.request(conn, a2sPlayerRequest, nil)
+	if err != nil {
+		return nil, errors.WithMessage(err, "A2S_PLAYER")
+	}
+	if result.Players, err = parseA2SPlayers(players); err != nil {
+		return nil, err
+	}
+
+	// Many servers have rules disabled and never answer, a missing answer
+	// is not an error of the query and is not waited for long.
+	deadline := time.Now().Add(a2sRulesWait)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	_ = conn.SetDeadline(deadline)
+
+	if rules, err := c.request(conn, a2sRulesRequest, nil); err == nil {
+		result.Rules, _ = parseA2SRules(rules)
+	}
+
+	result.QueriedAt = time.Now()
+
+	return result, nil
+}
+
+// request sends the request and answers challenges. A2S_PLAYER and
+// A2S_RULES start with the -1 challenge, A2S_INFO is challenged by servers
+// updated after December 2020.
+func (c *A2SClient) request(conn net.Conn, kind byte, payload []byte) ([]byte, error) {
+	var challenge []byte
+	if kind != a2sInfoRequest {
+		challenge = []byte{0xFF, 0xFF, 0xFF, 0xFF}
+	}
+
+	for range a2sMaxChallenges {
+		packet := make([]byte, 0, 5+len(payload)+len(challenge))
+		packet = append(packet, 0xFF, 0xFF, 0xFF, 0xFF, kind)
+		packet = append(packet, payload...)
+		packet = append(packet, challenge...)
+
+		if _, err := conn.Write(packet); err != nil {
+			return nil, errors.Wrap(err, "failed to send query")
+		}
+
+		response, err := c.readResponse(conn)
+		if err != nil {
+			return nil, err
+		}
+		if len(response) == 0 {
+			return nil, ErrInvalidResponse
+		}
+
+		if response[0] != a2sChallenge {
+			return response, nil
+		}
+
+		if len(response) < 5 {
+			return nil, errors.Wrap(ErrInvalidResponse, "short challenge")
+		}
+		challenge = response[1:5]
+	}
+
+	return nil, errors.Wrap(ErrInvalidResponse, "too many challenges")
+}
+
+// readResponse reads a response and puts split packets together. The
+// returned payload starts with the response type byte.
+func (c *A2SClient) readResponse(conn net.Conn) ([]byte, error) {
+	buf := make([]byte, maxPacketSize)
+
+	var (
+		id    uint32
+		parts [][]byte
+		got   int
+	)
+
+	for {
+		n, err := conn.Read(buf)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read query response")
+		}
+
+		r := packetReader{buf: buf[:n]}
+
+		switch r.uint32() {
+		case a2sSinglePacket:
+			if r.err {
+				return nil, ErrInvalidResponse
+			}
+			if parts == nil {
+				return append([]byte(nil), r.buf...), nil
+			}
+			// A stray answer to an earlier request, the split one goes on.
+			continue
+		case a2sSplitPacket:
+		default:
+			return nil, errors.Wrap(ErrInvalidResponse, "unknown packet header")
+		}
+
+		packetID := r.uint32()
+		if packetID&a2sCompressedFlag != 0 {
+			return nil, errA2SCompressed
+		}
+
+		var total, number int
+		if c.GoldSrc {
+			b := r.byte()
+			total, number = int(b&0x0F), int(b>>4)
+		} else {
+			total, number = int(r.byte()), int(r.byte())
+			r.uint16() // max packet size
+		}
+
+		if r.err || total == 0 || number >= total {
+			return nil, errors.Wrap(ErrInvalidResponse, "bad split packet")
+		}
+
+		if parts == nil {
+			id = packetID
+			parts = make([][]byte, total)
+		}
+		if packetID != id || total != len(parts) {
+			continue
+		}
+
+		if parts[number] == nil {
+			parts[number] = append([]byte(nil), r.buf...)
+			got++
+		}
+
+		if got == len(parts) {
+			break
+		}
+	}
+
+	var payload []byte
+	for _, part := range parts {
+		payload = append(payload, part...)
+	}
+
+	r := packetReader{buf: payload}
+	if r.uint32() != a2sSinglePacket {
+		return nil, errors.Wrap(ErrInvalidResponse, "bad split payload header")
+	}
+
+	return r.buf, nil
+}
+
+func parseA2SInfo(payload []byte, result *Result) error {
+	r := packetReader{buf: payload}
+
+	switch r.byte() {
+	case a2sInfoResponse:
+		parseSourceInfo(&r, result)
+	case a2sGoldSrcInfo:
+		parseGoldSrcInfo(&r, result)
+	default:
+		return errors.Wrap(ErrInvalidResponse, "unexpected A2S_INFO response type")
+	}
+
+	if r.err {
+		return errors.Wrap(ErrInvalidResponse, "truncated A2S_INFO response")
+	}
+
+	return nil
+}
+
+func parseSourceInfo(r *packetReader, result *Result) {
+	r.byte() // protocol
+	result.Name = r.string()
+	result.Map = r.string()
+	r.string() // folder
+	result.Game = r.string()
+	appID := r.uint16()
+	result.PlayersOnline = int(r.byte())
+	result.PlayersMax = int(r.byte())
+	result.Bots = int(r.byte())
+	r.skip(4) // server type, environment, visibility, VAC
+
+	// The Ship adds its game mode fields.
+	if appID >= 2400 && appID <= 2412 {
+		r.skip(3)
+	}
+
+	result.Version = r.string()
+}
+
+// parseGoldSrcInfo reads the obsolete GoldSrc response still sent by some
+// old servers. It has no version, the protocol number is used instead.
+func parseGoldSrcInfo(r *packetReader, result *Result) {
+	r.string() // address
+	result.Name = r.string()
+	result.Map = r.string()
+	r.string() // folder
+	result.Game = r.string()
+	result.PlayersOnline = int(r.byte())
+	result.PlayersMax = int(r.byte())
+	result.Version = strconv.Itoa(int(r.byte()))
+	r.skip(3) // server type, environment, visibility
+
+	if r.byte() == 1 { // half-life mod
+		r.string() // link
+		r.string() // download link
+		r.skip(1)
+		r.skip(4) // version
+		r.skip(4) // size
+		r.skip(2) // type, dll
+	}
+
+	r.skip(1) // VAC
+	result.Bots = int(r.byte())
+}
+
+func parseA2SPlayers(payload []byte) ([]Player, error) {
+	r := packetReader{buf: payload}
+
+	if r.byte() != a2sPlayerResponse {
+		return nil, errors.Wrap(ErrInvalidResponse, "unexpected A2S_PLAYER response type")
+	}
+
+	count := int(r.byte())
+	players := make([]Player, 0, count)
+
+	// The count overflows on servers with more than 255 players, so the
+	// entries are read until the end of the packet.
+	for !r.empty() {
+		r.byte() // index
+		name := r.string()
+		score := r.int32()
+		duration := r.float32()
+
+		if r.err {
+			return nil, errors.Wrap(ErrInvalidResponse, "truncated A2S_PLAYER response")
+		}
+
+		seconds := float64(duration)
+		if math.IsNaN(seconds) || seconds < 0 || seconds > maxPlayerSeconds {
+			seconds = 0
+		}
+
+		players = append(players, Player{
+			Name:     name,
+			Score:    int(score),
+			Duration: time.Duration(seconds * float64(time.Second)),
+		})
+	}
+
+	return players, nil
+}
+
+func parseA2SRules(payload []byte) (map[string]string, error) {
+	r := packetReader{buf: payload}
+
+	if r.byte() != a2sRulesResponse {
+		return nil, errors.Wrap(ErrInvalidResponse, "unexpected A2S_RULES response type")
+	}
+
+	count := int(r.uint16())
+	rules := make(map[string]string, count)
+
+	for range count {
+		name := r.string()
+		value := r.string()
+
+		if r.err {
+			// Some servers cut the rules, the ones read are still valid.
+			break
+		}
+
+		rules[name] = value
+	}
+
+	return rules, nil
+}
@@ -0,0 +1,232 @@
+package query
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testChallenge = []byte{0x11, 0x22, 0x33, 0x44}
+
+type a2sPacket struct {
+	bytes.Buffer
+}
+
+func (p *a2sPacket) str(s string) *a2sPacket {
+	p.WriteString(s)
+	p.WriteByte(0)
+
+	return p
+}
+
+func (p *a2sPacket) u8(b ...byte) *a2sPacket {
+	p.Write(b)
+
+	return p
+}
+
+func (p *a2sPacket) le(v any) *a2sPacket {
+	_ = binary.Write(&p.Buffer, binary.LittleEndian, v)
+
+	return p
+}
+
+func newA2SPacket(kind byte) *a2sPacket {
+	p := &a2sPacket{}
+	p.Write([]byte{0xFF, 0xFF, 0xFF, 0xFF, kind})
+
+	return p
+}
+
+func challengeResponse() []byte {
+	return append([]byte{0xFF, 0xFF, 0xFF, 0xFF, a2sChallenge}, testChallenge...)
+}
+
+func sourceInfo() []byte {
+	return newA2SPacket(a2sInfoResponse).
+		u8(17).
+		str("Test Server").
+		str("de_dust2").
+		str("csgo").
+		str("Counter-Strike").
+		le(uint16(730)).
+		u8(5, 24, 2, 'd', 'l', 0, 1).
+		str("1.38.7.9").
+		u8(0).
+		Bytes()
+}
+
+// splitSource cuts the payload into Source multi-packet datagrams.
+func splitSource(payload []byte, size int) [][]byte {
+	total := (len(payload) + size - 1) / size
+
+	packets := make([][]byte, 0, total)
+	for i := range total {
+		p := &a2sPacket{}
+		p.le(uint32(a2sSplitPacket)).le(uint32(7)).u8(byte(total), byte(i)).le(uint16(1248))
+		p.Write(payload[i*size : min((i+1)*size, len(payload))])
+		packets = append(packets, p.Bytes())
+	}
+
+	return packets
+}
+
+func TestA2SClient_Query(t *testing.T) {
+	players := newA2SPacket(a2sPlayerResponse).u8(2).
+		u8(0).str("alice").le(int32(12)).le(float32(61.5)).
+		u8(1).str("bob").le(int32(-1)).le(float32(3)).
+		Bytes()
+
+	rules := newA2SPacket(a2sRulesResponse).le(uint16(2)).
+		str("mp_timelimit").str("30").
+		str("sv_gravity").str("800").
+		Bytes()
+
+	address := serveUDP(t, func(request []byte) [][]byte {
+		switch request[4] {
+		case a2sInfoRequest:
+			if !bytes.HasSuffix(request, testChallenge) {
+				return [][]byte{challengeResponse()}
+			}
+			return [][]byte{sourceInfo()}
+		case a2sPlayerRequest:
+			if !bytes.HasSuffix(request, testChallenge) {
+				return [][]byte{challengeResponse()}
+			}
+			// Out of order parts are put together by their numbers.
+			parts := splitSource(players, 10)
+			parts[0], parts[1] = parts[1], parts[0]
+			return parts
+		case a2sRulesRequest:
+			return [][]byte{rules}
+		}
+		return nil
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	result, err := (&A2SClient{}).Query(ctx, address, true)
+
+	require.NoError(t, err)
+	assert.Equal(t, ProtocolA2S, result.Protocol)
+	assert.Equal(t, "Test Server", result.Name)
+	assert.Equal(t, "de_dust2", result.Map)
+	assert.Equal(t, "Counter-Strike", result.Game)
+	assert.Equal(t, "1.38.7.9", result.Version)
+	assert.Equal(t, 5, result.PlayersOnline)
+	assert.Equal(t, 24, result.PlayersMax)
+	assert.Equal(t, 2, result.Bots)
+	assert.Equal(t, []Player{
+		{Name: "alice", Score: 12, Duration: 61500 * time.Millisecond},
+		{Name: "bob", Score: -1, Duration: 3 * time.Second},
+	}, result.Players)
+	assert.Equal(t, map[string]string{"mp_timelimit": "30", "sv_gravity": "800"}, result.Rules)
+	assert.False(t, result.QueriedAt.IsZero())
+}
+
+func TestA2SClient_QueryInfoOnly(t *testing.T) {
+	requests := 0
+	address := serveUDP(t, func(_ []byte) [][]byte {
+		requests++
+		return [][]byte{sourceInfo()}
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	result, err := (&A2SClient{}).Query(ctx, address, false)
+
+	require.NoError(t, err)
+	assert.Equal(t, "de_dust2", result.Map)
+	assert.Nil(t, result.Players)
+	assert.Equal(t, 1, requests)
+}
+
+func TestA2SClient_QueryGoldSrc(t *testing.T) {
+	info := newA2SPacket(a2sGoldSrcInfo).
+		str("127.0.0.1:27015").
+		str("Old HLDS").
+		str("crossfire").
+		str("valve").
+		str("Half-Life").
+		u8(3, 16, 47, 'd', 'l', 0).
+		u8(1).str("http://example.com").str("").u8(0).le(uint32(1)).le(uint32(0)).u8(0, 0).
+		u8(0, 1).
+		Bytes()
+
+	players := newA2SPacket(a2sPlayerResponse).u8(1).
+		u8(0).str("gordon").le(int32(3)).le(float32(10)).
+		Bytes()
+
+	address := serveUDP(t, func(request []byte) [][]byte {
+		switch request[4] {
+		case a2sInfoRequest:
+			return [][]byte{info}
+		case a2sPlayerRequest:
+			// GoldSrc split header: number in the upper, total in the lower
+			// four bits.
+			var packets [][]byte
+			for i, part := range [][]byte{players[:8], players[8:]} {
+				p := &a2sPacket{}
+				p.le(uint32(a2sSplitPacket)).le(uint32(3)).u8(byte(i<<4 | 2))
+				p.Write(part)
+				packets = append(packets, p.Bytes())
+			}
+			return packets
+		}
+		return nil
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	result, err := (&A2SClient{GoldSrc: true}).Query(ctx, address, true)
+
+	require.NoError(t, err)
+	assert.Equal(t, ProtocolGoldSrc, result.Protocol)
+	assert.Equal(t, "Old HLDS", result.Name)
+	assert.Equal(t, "crossfire", result.Map)
+	assert.Equal(t, "47", result.Version)
+	assert.Equal(t, 3, result.PlayersOnline)
+	assert.Equal(t, 16, result.PlayersMax)
+	assert.Equal(t, 1, result.Bots)
+	assert.Equal(t, []Player{{Name: "gordon", Score: 3, Duration: 10 * time.Second}}, result.Players)
+	assert.Nil(t, result.Rules)
+}
+
+func TestA2SClient_QueryTimeout(t *testing.T) {
+	address := serveUDP(t, func(_ []byte) [][]byte { return nil })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	_, err := (&A2SClient{}).Query(ctx, address, false)
+
+	require.Error(t, err)
+}
+
+func TestParseA2SInfo_Truncated(t *testing.T) {
+	info := sourceInfo()
+
+	err := parseA2SInfo(info[4:len(info)-12], &Result{})
+
+	require.ErrorIs(t, err, ErrInvalidResponse)
+}
+
+func TestParseA2SPlayers_InvalidDuration(t *testing.T) {
+	payload := newA2SPacket(a2sPlayerResponse).u8(1).
+		u8(0).str("x").le(int32(0)).le(float32(math.Inf(1))).
+		Bytes()
+
+	players, err := parseA2SPlayers(payload[4:])
+
+	require.NoError(t, err)
+	assert.Equal(t, []Player{{Name: "x"}}, players)
+}
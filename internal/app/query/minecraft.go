@@ -0,0 +1,189 @@
+package query
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// minecraftProtocolVersion -1 asks for the status without naming the
+	// client version, servers answer with their own.
+	minecraftProtocolVersion = -1
+	minecraftStatusState     = 1
+
+	minecraftHandshakePacket = 0x00
+	minecraftStatusPacket    = 0x00
+
+	// minecraftMaxResponse bounds the status JSON, servers with big favicons
+	// stay far below it.
+	minecraftMaxResponse = 1 << 20
+)
+
+// MinecraftClient implements the Minecraft Server List Ping (Java Edition,
+// 1.7 and newer). The player list is the sample the server chooses to send.
+type MinecraftClient struct{}
+
+type minecraftStatus struct {
+	Version struct {
+		Name string `json:"name"`
+	} `json:"version"`
+	Players struct {
+		Max    int `json:"max"`
+		Online int `json:"online"`
+		Sample []struct {
+			Name string `json:"name"`
+		} `json:"sample"`
+	} `json:"players"`
+	Description json.RawMessage `json:"description"`
+}
+
+func (c *MinecraftClient) Query(ctx context.Context, address string, _ bool) (*Result, error) {
+	host, portStr, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid address")
+	}
+
+	port, err := strconv.ParseUint(portStr, 10, 16)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid port")
+	}
+
+	conn, err := dial(ctx, "tcp", address)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	started := time.Now()
+
+	var handshake []byte
+	handshake = binary.AppendUvarint(handshake, minecraftHandshakePacket)
+	handshake = appendVarint(handshake, minecraftProtocolVersion)
+	handshake = binary.AppendUvarint(handshake, uint64(len(host)))
+	handshake = append(handshake, host...)
+	handshake = binary.BigEndian.AppendUint16(handshake, uint16(port))
+	handshake = binary.AppendUvarint(handshake, minecraftStatusState)
+
+	request := appendPacket(nil, handshake)
+	request = appendPacket(request, []byte{minecraftStatusPacket})
+
+	if _, err := conn.Write(request); err != nil {
+		return nil, errors.Wrap(err, "failed to send query")
+	}
+
+	payload, err := readMinecraftPacket(bufio.NewReader(conn))
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{
+		Protocol: ProtocolMinecraft,
+		Latency:  time.Since(started),
+	}
+
+	if err := parseMinecraftStatus(payload, result); err != nil {
+		return nil, err
+	}
+
+	result.QueriedAt = time.Now()
+
+	return result, nil
+}
+
+func readMinecraftPacket(r *bufio.Reader) ([]byte, error) {
+	length, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read query response")
+	}
+	if length == 0 || length > minecraftMaxResponse {
+		return nil, errors.Wrapf(ErrInvalidResponse, "packet length %d", length)
+	}
+
+	packet := make([]byte, length)
+	if _, err := io.ReadFull(r, packet); err != nil {
+		return nil, errors.Wrap(err, "failed to read query response")
+	}
+
+	pr := bytes.NewReader(packet)
+
+	id, err := binary.ReadUvarint(pr)
+	if err != nil || id != minecraftStatusPacket {
+		return nil, errors.Wrap(ErrInvalidResponse, "unexpected packet id")
+	}
+
+	size, err := binary.ReadUvarint(pr)
+	if err != nil || size > uint64(pr.Len()) {
+		return nil, errors.Wrap(ErrInvalidResponse, "bad status length")
+	}
+
+	payload := make([]byte, size)
+	_, _ = io.ReadFull(pr, payload)
+
+	return payload, nil
+}
+
+func parseMinecraftStatus(payload []byte, result *Result) error {
+	var status minecraftStatus
+	if err := json.Unmarshal(payload, &status); err != nil {
+		return errors.Wrap(ErrInvalidResponse, err.Error())
+	}
+
+	result.Name = minecraftText(status.Description)
+	result.Version = status.Version.Name
+	result.PlayersOnline = status.Players.Online
+	result.PlayersMax = status.Players.Max
+
+	result.Players = make([]Player, 0, len(status.Players.Sample))
+	for _, p := range status.Players.Sample {
+		result.Players = append(result.Players, Player{Name: p.Name})
+	}
+
+	return nil
+}
+
+// minecraftText flattens the description, either a plain string or a chat
+// component with nested "extra" components.
+func minecraftText(raw json.RawMessage) string {
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		return s
+	}
+
+	var component struct {
+		Text  string            `json:"text"`
+		Extra []json.RawMessage `json:"extra"`
+	}
+	if json.Unmarshal(raw, &component) != nil {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString(component.Text)
+	for _, extra := range component.Extra {
+		b.WriteString(minecraftText(extra))
+	}
+
+	return b.String()
+}
+
+// appendVarint appends a Minecraft VarInt: the two's complement of v as an
+// unsigned LEB128 of 32 bits.
+func appendVarint(b []byte, v int32) []byte {
+	return binary.AppendUvarint(b, uint64(uint32(v))) //nolint:gosec // two's complement on the wire
+}
+
+func appendPacket(b, packet []byte) []byte {
+	b = binary.AppendUvarint(b, uint64(len(packet)))
+
+	return append(b, packet...)
+}
@@ -0,0 +1,112 @@
+package query
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const minecraftTestStatus = `{
+	"version": {"name": "1.21.1", "protocol": 767},
+	"players": {"max": 20, "online": 2, "sample": [{"name": "Steve", "id": "1"}, {"name": "Alex", "id": "2"}]},
+	"description": {"text": "A ", "extra": [{"text": "Minecraft"}, " Server"]}
+}`
+
+// serveMinecraft starts a stand-in Minecraft server answering one status
+// request per connection. The handshake it got is sent to handshakes.
+func serveMinecraft(t *testing.T, handshakes chan<- []byte) string {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = l.Close() })
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+
+			go func() {
+				defer conn.Close()
+
+				r := bufio.NewReader(conn)
+
+				handshake, err := readTestPacket(r)
+				if err != nil {
+					return
+				}
+				if _, err := readTestPacket(r); err != nil {
+					return
+				}
+
+				handshakes <- handshake
+
+				payload := binary.AppendUvarint([]byte{minecraftStatusPacket}, uint64(len(minecraftTestStatus)))
+				payload = append(payload, minecraftTestStatus...)
+
+				_, _ = conn.Write(appendPacket(nil, payload))
+			}()
+		}
+	}()
+
+	return l.Addr().String()
+}
+
+func readTestPacket(r *bufio.Reader) ([]byte, error) {
+	length, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, err
+	}
+
+	packet := make([]byte, length)
+	_, err = io.ReadFull(r, packet)
+
+	return packet, err
+}
+
+func TestMinecraftClient_Query(t *testing.T) {
+	handshakes := make(chan []byte, 1)
+	address := serveMinecraft(t, handshakes)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	result, err := (&MinecraftClient{}).Query(ctx, address, true)
+
+	require.NoError(t, err)
+	assert.Equal(t, ProtocolMinecraft, result.Protocol)
+	assert.Equal(t, "A Minecraft Server", result.Name)
+	assert.Equal(t, "1.21.1", result.Version)
+	assert.Equal(t, 2, result.PlayersOnline)
+	assert.Equal(t, 20, result.PlayersMax)
+	assert.Equal(t, []Player{{Name: "Steve"}, {Name: "Alex"}}, result.Players)
+
+	_, portStr, _ := net.SplitHostPort(address)
+	port, err := strconv.Atoi(portStr)
+	require.NoError(t, err)
+
+	handshake := <-handshakes
+
+	// id 0, protocol -1, host, port, next state 1
+	expected := []byte{0x00, 0xFF, 0xFF, 0xFF, 0xFF, 0x0F, 9}
+	expected = append(expected, "127.0.0.1"...)
+	expected = binary.BigEndian.AppendUint16(expected, uint16(port))
+	expected = append(expected, 0x01)
+	assert.Equal(t, expected, handshake)
+}
+
+func TestMinecraftText(t *testing.T) {
+	assert.Equal(t, "plain", minecraftText([]byte(`"plain"`)))
+	assert.Equal(t, "ab", minecraftText([]byte(`{"text":"a","extra":[{"text":"b"}]}`)))
+	assert.Empty(t, minecraftText([]byte(`42`)))
+}
@@ -0,0 +1,90 @@
+package query
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+)
+
+// packetReader reads little-endian fields of a query response. A read past
+// the end of the packet sets the error flag and returns zero values, so a
+// response is parsed with a single error check at the end.
+type packetReader struct {
+	buf []byte
+	err bool
+}
+
+func (r *packetReader) byte() byte {
+	if len(r.buf) < 1 {
+		r.err = true
+		return 0
+	}
+
+	b := r.buf[0]
+	r.buf = r.buf[1:]
+
+	return b
+}
+
+func (r *packetReader) uint16() uint16 {
+	if len(r.buf) < 2 {
+		r.err = true
+		return 0
+	}
+
+	v := binary.LittleEndian.Uint16(r.buf)
+	r.buf = r.buf[2:]
+
+	return v
+}
+
+func (r *packetReader) uint32() uint32 {
+	if len(r.buf) < 4 {
+		r.err = true
+		return 0
+	}
+
+	v := binary.LittleEndian.Uint32(r.buf)
+	r.buf = r.buf[4:]
+
+	return v
+}
+
+func (r *packetReader) int32() int32 {
+	return int32(r.uint32()) //nolint:gosec // reinterpreting the wire value
+}
+
+func (r *packetReader) float32() float32 {
+	return math.Float32frombits(r.uint32())
+}
+
+// string reads a null-terminated string.
+func (r *packetReader) string() string {
+	i := bytes.IndexByte(r.buf, 0)
+	if i < 0 {
+		r.err = true
+		r.buf = nil
+
+		return ""
+	}
+
+	s := string(r.buf[:i])
+	r.buf = r.buf[i+1:]
+
+	return s
+}
+
+func (r *packetReader) skip(n int) {
+	if len(r.buf) < n {
+		r.err = true
+		r.buf = nil
+
+		return
+	}
+
+	r.buf = r.buf[n:]
+}
+
+func (r *packetReader) empty() bool {
+	return len(r.buf) == 0
+}
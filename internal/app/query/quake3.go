@@ -0,0 +1,123 @@
+package query
+
+import (
+	"bytes"
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	quake3StatusRequest  = []byte("\xff\xff\xff\xffgetstatus\n")
+	quake3StatusResponse = []byte("\xff\xff\xff\xffstatusResponse\n")
+)
+
+// Quake3Client implements the Quake 3 getstatus query, also answered by
+// most id Tech 3 games (Urban Terror, Wolfenstein: Enemy Territory,
+// Call of Duty and others).
+type Quake3Client struct{}
+
+func (c *Quake3Client) Query(ctx context.Context, address string, _ bool) (*Result, error) {
+	conn, err := dial(ctx, "udp", address)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	started := time.Now()
+
+	response, err := exchangeUDP(conn, quake3StatusRequest)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{
+		Protocol: ProtocolQuake3,
+		Latency:  time.Since(started),
+	}
+
+	if err := parseQuake3Status(response, result); err != nil {
+		return nil, err
+	}
+
+	result.QueriedAt = time.Now()
+
+	return result, nil
+}
+
+// parseQuake3Status reads the "\key\value" info string and the player lines
+// of the form `<score> <ping> "<name>"`. Players with zero ping are bots.
+func parseQuake3Status(response []byte, result *Result) error {
+	body, ok := bytes.CutPrefix(response, quake3StatusResponse)
+	if !ok {
+		return errors.Wrap(ErrInvalidResponse, "unexpected getstatus response")
+	}
+
+	lines := strings.Split(strings.TrimRight(string(body), "\n"), "\n")
+
+	rules := parseQuake3Info(lines[0])
+
+	result.Rules = rules
+	result.Name = stripQuake3Colors(rules["sv_hostname"])
+	result.Map = rules["mapname"]
+	result.Game = rules["gamename"]
+	result.Version = rules["version"]
+	result.PlayersMax, _ = strconv.Atoi(rules["sv_maxclients"])
+
+	result.Players = make([]Player, 0, len(lines)-1)
+
+	for _, line := range lines[1:] {
+		fields := strings.SplitN(line, " ", 3)
+		if len(fields) < 3 {
+			continue
+		}
+
+		score, _ := strconv.Atoi(fields[0])
+		ping, _ := strconv.Atoi(fields[1])
+
+		if ping == 0 {
+			result.Bots++
+		}
+
+		result.Players = append(result.Players, Player{
+			Name:  stripQuake3Colors(strings.Trim(fields[2], `"`)),
+			Score: score,
+		})
+	}
+
+	result.PlayersOnline = len(result.Players)
+
+	return nil
+}
+
+func parseQuake3Info(info string) map[string]string {
+	parts := strings.Split(strings.TrimPrefix(info, `\`), `\`)
+
+	rules := make(map[string]string, len(parts)/2)
+	for i := 0; i+1 < len(parts); i += 2 {
+		rules[parts[i]] = parts[i+1]
+	}
+
+	return rules
+}
+
+// stripQuake3Colors removes ^N color codes from names.
+func stripQuake3Colors(s string) string {
+	if !strings.Contains(s, "^") {
+		return s
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '^' && i+1 < len(s) && s[i+1] != '^' {
+			i++
+			continue
+		}
+		b.WriteByte(s[i])
+	}
+
+	return b.String()
+}
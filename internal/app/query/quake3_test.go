@@ -0,0 +1,48 @@
+package query
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQuake3Client_Query(t *testing.T) {
+	response := "\xff\xff\xff\xffstatusResponse\n" +
+		`\sv_hostname\^1Red ^7Arena\mapname\q3dm17\sv_maxclients\16\version\ioq3 1.36\gamename\baseq3` + "\n" +
+		`20 48 "^2Sarge"` + "\n" +
+		`5 0 "Bot"` + "\n"
+
+	address := serveUDP(t, func(request []byte) [][]byte {
+		if !bytes.Equal(request, quake3StatusRequest) {
+			return nil
+		}
+		return [][]byte{[]byte(response)}
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	result, err := (&Quake3Client{}).Query(ctx, address, false)
+
+	require.NoError(t, err)
+	assert.Equal(t, ProtocolQuake3, result.Protocol)
+	assert.Equal(t, "Red Arena", result.Name)
+	assert.Equal(t, "q3dm17", result.Map)
+	assert.Equal(t, "baseq3", result.Game)
+	assert.Equal(t, "ioq3 1.36", result.Version)
+	assert.Equal(t, 2, result.PlayersOnline)
+	assert.Equal(t, 16, result.PlayersMax)
+	assert.Equal(t, 1, result.Bots)
+	assert.Equal(t, []Player{{Name: "Sarge", Score: 20}, {Name: "Bot", Score: 5}}, result.Players)
+	assert.Equal(t, "q3dm17", result.Rules["mapname"])
+}
+
+func TestParseQuake3Status_InvalidResponse(t *testing.T) {
+	err := parseQuake3Status([]byte("\xff\xff\xff\xffprint\nbad"), &Result{})
+
+	require.ErrorIs(t, err, ErrInvalidResponse)
+}
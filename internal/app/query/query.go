@@ -0,0 +1,191 @@
+// Package query asks running game servers for their state over the game
+// query protocols: Valve A2S (Source and GoldSrc), Minecraft Server List
+// Ping and Quake 3 status. The protocol of a server is taken from the game
+// metadata, see ProtocolForServer.
+package query
+
+import (
+	"context"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/gameap/daemon/internal/app/domain"
+	"github.com/pkg/errors"
+)
+
+type Protocol string
+
+const (
+	ProtocolNone      Protocol = ""
+	ProtocolA2S       Protocol = "a2s"
+	ProtocolGoldSrc   Protocol = "goldsrc"
+	ProtocolMinecraft Protocol = "minecraft"
+	ProtocolQuake3    Protocol = "quake3"
+)
+
+// ProtocolKey is the server variable and game (mod) metadata key that selects
+// the query protocol of a server.
+const ProtocolKey = "query_protocol"
+
+// maxPacketSize is the largest UDP datagram game servers send.
+const maxPacketSize = 65535
+
+var (
+	ErrUnknownProtocol = errors.New("unknown query protocol")
+	ErrNoProtocol      = errors.New("query protocol is not set for the game")
+	ErrNoQueryPort     = errors.New("server has no query port")
+	ErrInvalidResponse = errors.New("invalid query response")
+)
+
+// engineProtocols maps game engines to the protocol used when the game
+// metadata has no query_protocol.
+var engineProtocols = map[string]Protocol{
+	"source":     ProtocolA2S,
+	"goldsource": ProtocolGoldSrc,
+	"goldsrc":    ProtocolGoldSrc,
+	"minecraft":  ProtocolMinecraft,
+	"quake3":     ProtocolQuake3,
+	"idtech3":    ProtocolQuake3,
+}
+
+// Player is a player currently on the server. Score and Duration are zero
+// when the protocol does not report them.
+type Player struct {
+	Name     string        `json:"name"`
+	Score    int           `json:"score"`
+	Duration time.Duration `json:"duration"`
+}
+
+// Result is the server state reported by a query.
+type Result struct {
+	Protocol      Protocol          `json:"protocol"`
+	Name          string            `json:"name"`
+	Map           string            `json:"map"`
+	Game          string            `json:"game,omitempty"`
+	Version       string            `json:"version"`
+	PlayersOnline int               `json:"players_online"`
+	PlayersMax    int               `json:"players_max"`
+	Bots          int               `json:"bots"`
+	Players       []Player          `json:"players"`
+	Rules         map[string]string `json:"rules,omitempty"`
+	Latency       time.Duration     `json:"latency"`
+	QueriedAt     time.Time         `json:"queried_at"`
+}
+
+// Client queries a server at the address (host:port). A full query also
+// fills Players and Rules where the protocol needs extra requests for them.
+type Client interface {
+	Query(ctx context.Context, address string, full bool) (*Result, error)
+}
+
+// NewClient returns the client of the protocol.
+func NewClient(protocol Protocol) (Client, error) {
+	switch protocol {
+	case ProtocolA2S:
+		return &A2SClient{}, nil
+	case ProtocolGoldSrc:
+		return &A2SClient{GoldSrc: true}, nil
+	case ProtocolMinecraft:
+		return &MinecraftClient{}, nil
+	case ProtocolQuake3:
+		return &Quake3Client{}, nil
+	case ProtocolNone:
+		return nil, ErrNoProtocol
+	default:
+		return nil, errors.Wrapf(ErrUnknownProtocol, "got %q", protocol)
+	}
+}
+
+// ProtocolForServer returns the query protocol of the server with priority:
+// 1. Server vars
+// 2. GameMod metadata
+// 3. Game metadata
+// 4. Game engine
+func ProtocolForServer(server *domain.Server) Protocol {
+	if val, ok := server.LookupVar(ProtocolKey); ok {
+		return Protocol(strings.ToLower(val))
+	}
+
+	engine := strings.ToLower(strings.ReplaceAll(server.Game().Engine, " ", ""))
+
+	return engineProtocols[engine]
+}
+
+// Address returns the host:port the server answers queries on. The query
+// port falls back to the connect port, servers listening on all interfaces
+// are queried over the loopback.
+func Address(server *domain.Server) (string, error) {
+	port := server.QueryPort()
+	if port == 0 {
+		port = server.ConnectPort()
+	}
+	if port == 0 {
+		return "", ErrNoQueryPort
+	}
+
+	return server.DialAddress(port), nil
+}
+
+// QueryServer queries the server with its protocol.
+func QueryServer(ctx context.Context, server *domain.Server, full bool) (*Result, error) {
+	client, err := NewClient(ProtocolForServer(server))
+	if err != nil {
+		return nil, err
+	}
+
+	address, err := Address(server)
+	if err != nil {
+		return nil, err
+	}
+
+	return client.Query(ctx, address, full)
+}
+
+// exchangeUDP sends the request and reads one datagram of the response.
+func exchangeUDP(conn net.Conn, request []byte) ([]byte, error) {
+	if _, err := conn.Write(request); err != nil {
+		return nil, errors.Wrap(err, "failed to send query")
+	}
+
+	buf := make([]byte, maxPacketSize)
+
+	n, err := conn.Read(buf)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read query response")
+	}
+
+	return buf[:n], nil
+}
+
+// dial connects to the address. The deadline of the connection is taken
+// from ctx and the connection is interrupted when ctx is done.
+func dial(ctx context.Context, network, address string) (net.Conn, error) {
+	var d net.Dialer
+
+	conn, err := d.DialContext(ctx, network, address)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to dial %s", address)
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	stop := context.AfterFunc(ctx, func() {
+		_ = conn.SetDeadline(time.Now())
+	})
+
+	return &ctxConn{Conn: conn, stop: stop}, nil
+}
+
+type ctxConn struct {
+	net.Conn
+	stop func() bool
+}
+
+func (c *ctxConn) Close() error {
+	c.stop()
+
+	return c.Conn.Close()
+}
@@ -0,0 +1,118 @@
+package query
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/gameap/daemon/internal/app/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// serveUDP starts a stand-in game server on the loopback. The handler
+// returns the datagrams sent back for a request.
+func serveUDP(t *testing.T, handler func(request []byte) [][]byte) string {
+	t.Helper()
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+
+	go func() {
+		buf := make([]byte, maxPacketSize)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+
+			for _, packet := range handler(append([]byte(nil), buf[:n]...)) {
+				_, _ = conn.WriteTo(packet, addr)
+			}
+		}
+	}()
+
+	return conn.LocalAddr().String()
+}
+
+func givenServer(id int, ip string, queryPort int, game domain.Game, vars map[string]string) *domain.Server {
+	return domain.NewServer(
+		id,
+		true,
+		domain.ServerInstalled,
+		false,
+		"test",
+		"9fd2e4d1-4a4e-4d3c-9a2f-5a4b5d8c9e01",
+		"9fd2e4d1",
+		game,
+		domain.GameMod{ID: 2, Name: "default"},
+		ip,
+		27015,
+		queryPort,
+		27016,
+		"rconpass",
+		"servers/test",
+		"",
+		"./start.sh",
+		"",
+		"",
+		"",
+		false,
+		time.Time{},
+		vars,
+		domain.Settings{},
+		time.Time{},
+		0,
+		0,
+	)
+}
+
+func TestProtocolForServer(t *testing.T) {
+	tests := []struct {
+		name     string
+		game     domain.Game
+		vars     map[string]string
+		expected Protocol
+	}{
+		{
+			name:     "engine",
+			game:     domain.Game{Engine: "GoldSource"},
+			expected: ProtocolGoldSrc,
+		},
+		{
+			name:     "game metadata",
+			game:     domain.Game{Engine: "source", Metadata: map[string]any{ProtocolKey: "quake3"}},
+			expected: ProtocolQuake3,
+		},
+		{
+			name:     "server var",
+			game:     domain.Game{Engine: "source", Metadata: map[string]any{ProtocolKey: "quake3"}},
+			vars:     map[string]string{ProtocolKey: "Minecraft"},
+			expected: ProtocolMinecraft,
+		},
+		{
+			name:     "unknown engine",
+			game:     domain.Game{Engine: "unreal"},
+			expected: ProtocolNone,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := givenServer(1, "127.0.0.1", 27015, test.game, test.vars)
+
+			assert.Equal(t, test.expected, ProtocolForServer(server))
+		})
+	}
+}
+
+func TestAddress(t *testing.T) {
+	address, err := Address(givenServer(1, "0.0.0.0", 27017, domain.Game{}, nil))
+	require.NoError(t, err)
+	assert.Equal(t, "127.0.0.1:27017", address)
+
+	address, err = Address(givenServer(1, "192.0.2.10", 0, domain.Game{}, nil))
+	require.NoError(t, err)
+	assert.Equal(t, "192.0.2.10:27015", address)
+}
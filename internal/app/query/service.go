@@ -0,0 +1,206 @@
+package query
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gameap/daemon/internal/app/domain"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/sync/errgroup"
+)
+
+// maxConcurrentQueries bounds the servers queried at the same time.
+const maxConcurrentQueries = 16
+
+const (
+	MetricQueryUp       = "gameap_server_query_up"
+	MetricQueryLatency  = "gameap_server_query_latency_seconds"
+	MetricPlayersOnline = "gameap_server_players_online"
+	MetricPlayersMax    = "gameap_server_players_max"
+	MetricBots          = "gameap_server_bots"
+	MetricServerInfo    = "gameap_server_info"
+)
+
+// ServerLister exposes the cached set of servers known to the daemon.
+// Implemented by *repositories.ServerRepository.
+type ServerLister interface {
+	IDsFromCache() []int
+	FindByIDFromCache(id int) (*domain.Server, bool)
+}
+
+// state is the outcome of the last poll of a server, result is nil when the
+// query failed.
+type state struct {
+	result *Result
+	uuid   string
+}
+
+// Service polls the active servers with a query protocol and keeps the
+// latest result of every server. It is a metrics collector of the results.
+type Service struct {
+	servers  ServerLister
+	interval time.Duration
+	timeout  time.Duration
+	queryFn  func(ctx context.Context, server *domain.Server, full bool) (*Result, error)
+	nowFn    func() time.Time
+
+	mu     sync.RWMutex
+	states map[int]state
+}
+
+func NewService(servers ServerLister, interval, timeout time.Duration) *Service {
+	return &Service{
+		servers:  servers,
+		interval: interval,
+		timeout:  timeout,
+		queryFn:  QueryServer,
+		nowFn:    time.Now,
+		states:   make(map[int]state),
+	}
+}
+
+// Run polls the servers until ctx is done.
+func (s *Service) Run(ctx context.Context) error {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		s.poll(ctx)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Service) poll(ctx context.Context) {
+	g := errgroup.Group{}
+	g.SetLimit(maxConcurrentQueries)
+
+	polled := make(map[int]struct{})
+
+	for _, id := range s.servers.IDsFromCache() {
+		server, ok := s.servers.FindByIDFromCache(id)
+		if !ok || server == nil || !server.IsActive() || ProtocolForServer(server) == ProtocolNone {
+			continue
+		}
+
+		polled[id] = struct{}{}
+
+		g.Go(func() error {
+			queryCtx, cancel := context.WithTimeout(ctx, s.timeout)
+			defer cancel()
+
+			result, err := s.queryFn(queryCtx, server, false)
+			if err != nil {
+				log.WithError(err).WithField("gameServerID", id).Debug("Server query failed")
+			}
+
+			s.mu.Lock()
+			s.states[id] = state{result: result, uuid: server.UUID()}
+			s.mu.Unlock()
+
+			return nil
+		})
+	}
+
+	_ = g.Wait()
+
+	// Stopped servers and servers without a protocol are not reported.
+	s.mu.Lock()
+	for id := range s.states {
+		if _, ok := polled[id]; !ok {
+			delete(s.states, id)
+		}
+	}
+	s.mu.Unlock()
+}
+
+// Query queries the server right away with players and rules.
+func (s *Service) Query(ctx context.Context, server *domain.Server) (*Result, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	result, err := s.queryFn(ctx, server, true)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to query server %d", server.ID())
+	}
+
+	return result, nil
+}
+
+// Latest returns the result of the last poll of the server. It reports
+// false when the server was not polled or the query failed.
+func (s *Service) Latest(serverID int) (*Result, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	st, ok := s.states[serverID]
+	if !ok || st.result == nil {
+		return nil, false
+	}
+
+	return st.result, true
+}
+
+// Collect reports the latest poll results. Samples are stamped with the
+// collection time, the poll interval is usually longer than the metrics
+// collection interval.
+func (s *Service) Collect(_ context.Context) ([]domain.Metric, error) {
+	now := s.nowFn()
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	out := make([]domain.Metric, 0, len(s.states)*6)
+
+	for id, st := range s.states {
+		labels := func(extra ...string) map[string]string {
+			l := map[string]string{"server_id": strconv.Itoa(id)}
+			if st.uuid != "" {
+				l["server_uuid"] = st.uuid
+			}
+			for i := 0; i+1 < len(extra); i += 2 {
+				l[extra[i]] = extra[i+1]
+			}
+
+			return l
+		}
+
+		gauge := func(name string, unit domain.MetricUnit, value domain.MetricValue, extra ...string) {
+			out = append(out, domain.Metric{
+				Name:      name,
+				Type:      domain.MetricTypeGauge,
+				Unit:      unit,
+				Labels:    labels(extra...),
+				Timestamp: now,
+				Value:     value,
+			})
+		}
+
+		if st.result == nil {
+			gauge(MetricQueryUp, domain.MetricUnitUnspecified, domain.Uint64Value(0))
+			continue
+		}
+
+		r := st.result
+
+		gauge(MetricQueryUp, domain.MetricUnitUnspecified, domain.Uint64Value(1))
+		gauge(MetricQueryLatency, domain.MetricUnitSeconds, domain.Float64Value(r.Latency.Seconds()))
+		gauge(MetricPlayersOnline, domain.MetricUnitCount, domain.Int64Value(int64(r.PlayersOnline)))
+		gauge(MetricPlayersMax, domain.MetricUnitCount, domain.Int64Value(int64(r.PlayersMax)))
+		gauge(MetricBots, domain.MetricUnitCount, domain.Int64Value(int64(r.Bots)))
+		gauge(MetricServerInfo, domain.MetricUnitUnspecified, domain.Uint64Value(1),
+			"protocol", string(r.Protocol),
+			"map", r.Map,
+			"version", r.Version,
+		)
+	}
+
+	return out, nil
+}
@@ -0,0 +1,144 @@
+package query
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gameap/daemon/internal/app/domain"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var errTestTimeout = errors.New("i/o timeout")
+
+type fakeServerLister struct {
+	servers map[int]*domain.Server
+}
+
+func (f *fakeServerLister) IDsFromCache() []int {
+	ids := make([]int, 0, len(f.servers))
+	for id := range f.servers {
+		ids = append(ids, id)
+	}
+
+	return ids
+}
+
+func (f *fakeServerLister) FindByIDFromCache(id int) (*domain.Server, bool) {
+	s, ok := f.servers[id]
+
+	return s, ok
+}
+
+func givenActiveServer(id int, engine string) *domain.Server {
+	server := givenServer(id, "127.0.0.1", 27015+id, domain.Game{Engine: engine}, nil)
+	server.SetStatus(true)
+
+	return server
+}
+
+func TestService_PollAndCollect(t *testing.T) {
+	servers := &fakeServerLister{servers: map[int]*domain.Server{
+		1: givenActiveServer(1, "source"),
+		2: givenActiveServer(2, "minecraft"),
+		3: givenActiveServer(3, "unreal"),
+	}}
+
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+
+	service := NewService(servers, time.Minute, time.Second)
+	service.nowFn = func() time.Time { return now }
+	service.queryFn = func(_ context.Context, server *domain.Server, full bool) (*Result, error) {
+		assert.False(t, full)
+
+		if server.Game().Engine == "minecraft" {
+			return nil, errTestTimeout
+		}
+
+		return &Result{
+			Protocol:      ProtocolA2S,
+			Map:           "de_dust2",
+			Version:       "1.0",
+			PlayersOnline: 5,
+			PlayersMax:    24,
+			Latency:       20 * time.Millisecond,
+		}, nil
+	}
+
+	service.poll(context.Background())
+
+	result, ok := service.Latest(1)
+	require.True(t, ok)
+	assert.Equal(t, "de_dust2", result.Map)
+
+	_, ok = service.Latest(2)
+	assert.False(t, ok)
+	_, ok = service.Latest(3)
+	assert.False(t, ok)
+
+	samples, err := service.Collect(context.Background())
+	require.NoError(t, err)
+
+	values := make(map[string]float64)
+	for _, m := range samples {
+		assert.Equal(t, now, m.Timestamp)
+		assert.Equal(t, "9fd2e4d1-4a4e-4d3c-9a2f-5a4b5d8c9e01", m.Labels["server_uuid"])
+		values[m.Name+"/"+m.Labels["server_id"]] = m.Value.AsFloat64()
+
+		if m.Name == MetricServerInfo {
+			assert.Equal(t, "de_dust2", m.Labels["map"])
+			assert.Equal(t, "1.0", m.Labels["version"])
+			assert.Equal(t, "a2s", m.Labels["protocol"])
+		}
+	}
+
+	assert.Equal(t, map[string]float64{
+		"gameap_server_query_up/1":              1,
+		"gameap_server_query_latency_seconds/1": 0.02,
+		"gameap_server_players_online/1":        5,
+		"gameap_server_players_max/1":           24,
+		"gameap_server_bots/1":                  0,
+		"gameap_server_info/1":                  1,
+		"gameap_server_query_up/2":              0,
+	}, values)
+}
+
+func TestService_PollForgetsStoppedServers(t *testing.T) {
+	server := givenActiveServer(1, "source")
+	servers := &fakeServerLister{servers: map[int]*domain.Server{1: server}}
+
+	service := NewService(servers, time.Minute, time.Second)
+	service.queryFn = func(_ context.Context, _ *domain.Server, _ bool) (*Result, error) {
+		return &Result{Protocol: ProtocolA2S}, nil
+	}
+
+	service.poll(context.Background())
+	_, ok := service.Latest(1)
+	require.True(t, ok)
+
+	server.SetStatus(false)
+	service.poll(context.Background())
+
+	_, ok = service.Latest(1)
+	assert.False(t, ok)
+
+	samples, err := service.Collect(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, samples)
+}
+
+func TestService_Query(t *testing.T) {
+	service := NewService(&fakeServerLister{}, time.Minute, 50*time.Millisecond)
+	service.queryFn = func(ctx context.Context, _ *domain.Server, full bool) (*Result, error) {
+		assert.True(t, full)
+		<-ctx.Done()
+
+		return nil, ctx.Err()
+	}
+
+	_, err := service.Query(context.Background(), givenActiveServer(1, "source"))
+
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
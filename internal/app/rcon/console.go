@@ -0,0 +1,256 @@
+package rcon
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gameap/daemon/internal/app/contracts"
+	"github.com/gameap/daemon/internal/app/domain"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// commandTimeout bounds connecting and running a single command.
+const commandTimeout = 10 * time.Second
+
+// Console sends console input of servers with RCON enabled over RCON and
+// passes the rest to the process manager. The console output is streamed by
+// the process manager for all servers. Other process manager calls go
+// to the process manager as is.
+type Console struct {
+	contracts.ProcessManager
+
+	dialFn func(ctx context.Context, server *domain.Server) (Conn, error)
+}
+
+func NewConsole(pm contracts.ProcessManager) *Console {
+	return &Console{
+		ProcessManager: pm,
+		dialFn:         DialServer,
+	}
+}
+
+// SendInput runs the command over RCON and writes its reply to out. The
+// command goes to the process manager when the remote console can't be
+// connected to or rejects the password.
+func (c *Console) SendInput(
+	ctx context.Context, input string, server *domain.Server, out io.Writer,
+) (domain.Result, error) {
+	if !Enabled(server) {
+		return c.ProcessManager.SendInput(ctx, input, server, out)
+	}
+
+	execCtx, cancel := context.WithTimeout(ctx, commandTimeout)
+	defer cancel()
+
+	conn, err := c.dialFn(execCtx, server)
+	if err != nil {
+		c.logFallback(server, err)
+		return c.ProcessManager.SendInput(ctx, input, server, out)
+	}
+	defer conn.Close()
+
+	reply, err := conn.Exec(execCtx, input)
+	if errors.Is(err, ErrAuthFailed) {
+		c.logFallback(server, err)
+		return c.ProcessManager.SendInput(ctx, input, server, out)
+	}
+	if err != nil {
+		return domain.ErrorResult, errors.WithMessage(err, "failed to run rcon command")
+	}
+
+	if err := writeReply(out, reply); err != nil {
+		return domain.ErrorResult, err
+	}
+
+	return domain.SuccessResult, nil
+}
+
+// Attach streams the server console by the process manager and runs every
+// line read from in as an RCON command, the replies are written to out along
+// with the console output. A line goes to the process manager when the remote
+// console can't be connected to. Servers without RCON are attached by the
+// process manager.
+func (c *Console) Attach(ctx context.Context, server *domain.Server, in io.Reader, out io.Writer) error {
+	if !Enabled(server) {
+		return c.ProcessManager.Attach(ctx, server, in, out)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	w := &sessionWriter{w: out}
+	defer w.close()
+
+	// The input of the process manager is kept open while in is read, the
+	// lines themselves go over RCON.
+	pmIn, pmInWriter := io.Pipe()
+	defer pmInWriter.Close()
+
+	inputDone := make(chan error, 1)
+	go func() {
+		inputDone <- c.runInput(ctx, server, in, w)
+		_ = pmInWriter.Close()
+	}()
+
+	if err := c.ProcessManager.Attach(ctx, server, pmIn, w); err != nil {
+		return err
+	}
+
+	// The input reader may block until the caller closes it, it is not
+	// waited for once the process manager is detached.
+	select {
+	case err := <-inputDone:
+		return err
+	default:
+		return nil
+	}
+}
+
+func (c *Console) runInput(ctx context.Context, server *domain.Server, in io.Reader, out io.Writer) error {
+	var conn Conn
+
+	defer func() {
+		if conn != nil {
+			_ = conn.Close()
+		}
+	}()
+
+	scanner := bufio.NewScanner(in)
+
+	for scanner.Scan() {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		command := strings.TrimSpace(scanner.Text())
+		if command == "" {
+			continue
+		}
+
+		reply, err := c.exec(ctx, server, &conn, command)
+
+		var connErr *connectError
+		if errors.As(err, &connErr) {
+			c.logFallback(server, err)
+
+			_, err = c.ProcessManager.SendInput(ctx, command, server, out)
+			if err == nil {
+				continue
+			}
+		}
+
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+
+			log.WithError(err).WithField("gameServerID", server.ID()).Debug("RCON command failed")
+			reply = err.Error()
+		}
+
+		if err := writeReply(out, reply); err != nil {
+			return err
+		}
+	}
+
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	return scanner.Err()
+}
+
+func (c *Console) logFallback(server *domain.Server, err error) {
+	log.WithError(err).
+		WithField("gameServerID", server.ID()).
+		Debug("RCON is unavailable, the command is sent to the process manager")
+}
+
+// connectError is a failure to connect to the remote console or to log in,
+// the command was not run.
+type connectError struct {
+	err error
+}
+
+func (e *connectError) Error() string {
+	return e.err.Error()
+}
+
+func (e *connectError) Unwrap() error {
+	return e.err
+}
+
+// sessionWriter serializes the console output and the command replies of an
+// attach session and drops writes once the session is over.
+type sessionWriter struct {
+	mu     sync.Mutex
+	w      io.Writer
+	closed bool
+}
+
+func (w *sessionWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return 0, io.ErrClosedPipe
+	}
+
+	return w.w.Write(p)
+}
+
+func (w *sessionWriter) close() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.closed = true
+}
+
+// exec runs the command on the session connection. A broken connection is
+// dialed again once, the server may have been restarted in between.
+func (c *Console) exec(ctx context.Context, server *domain.Server, conn *Conn, command string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, commandTimeout)
+	defer cancel()
+
+	for attempt := 0; ; attempt++ {
+		if *conn == nil {
+			var err error
+			if *conn, err = c.dialFn(ctx, server); err != nil {
+				return "", &connectError{err: errors.WithMessage(err, "failed to connect to rcon")}
+			}
+		}
+
+		reply, err := (*conn).Exec(ctx, command)
+		if err == nil {
+			return reply, nil
+		}
+
+		_ = (*conn).Close()
+		*conn = nil
+
+		if errors.Is(err, ErrAuthFailed) {
+			return "", &connectError{err: err}
+		}
+
+		if attempt > 0 || ctx.Err() != nil {
+			return "", err
+		}
+	}
+}
+
+func writeReply(out io.Writer, reply string) error {
+	if reply != "" && !strings.HasSuffix(reply, "\n") {
+		reply += "\n"
+	}
+
+	if _, err := io.WriteString(out, reply); err != nil {
+		return errors.Wrap(err, "failed to write rcon reply")
+	}
+
+	return nil
+}
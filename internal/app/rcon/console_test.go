@@ -0,0 +1,238 @@
+package rcon
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gameap/daemon/internal/app/contracts"
+	"github.com/gameap/daemon/internal/app/domain"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var errTestBroken = errors.New("connection reset")
+
+type fakeProcessManager struct {
+	contracts.ProcessManager
+
+	inputs   []string
+	attached bool
+}
+
+func (pm *fakeProcessManager) SendInput(
+	_ context.Context, input string, _ *domain.Server, _ io.Writer,
+) (domain.Result, error) {
+	pm.inputs = append(pm.inputs, input)
+
+	return domain.SuccessResult, nil
+}
+
+// Attach writes the console output and returns when the input is closed,
+// the input itself is discarded.
+func (pm *fakeProcessManager) Attach(_ context.Context, _ *domain.Server, in io.Reader, out io.Writer) error {
+	pm.attached = true
+
+	if _, err := io.WriteString(out, "console output\n"); err != nil {
+		return err
+	}
+
+	_, err := io.Copy(io.Discard, in)
+
+	return err
+}
+
+type fakeConn struct {
+	commands []string
+	failOn   string
+	closed   bool
+}
+
+func (c *fakeConn) Exec(_ context.Context, command string) (string, error) {
+	if command == c.failOn {
+		c.failOn = ""
+		return "", errTestBroken
+	}
+
+	c.commands = append(c.commands, command)
+
+	return "reply to " + command, nil
+}
+
+func (c *fakeConn) Close() error {
+	c.closed = true
+
+	return nil
+}
+
+func givenServer(vars map[string]string, rconPassword string) *domain.Server {
+	return domain.NewServer(
+		1,
+		true,
+		domain.ServerInstalled,
+		false,
+		"test",
+		"9fd2e4d1-4a4e-4d3c-9a2f-5a4b5d8c9e01",
+		"9fd2e4d1",
+		domain.Game{Engine: "source"},
+		domain.GameMod{ID: 2, Name: "default"},
+		"0.0.0.0",
+		27015,
+		27015,
+		27016,
+		rconPassword,
+		"servers/test",
+		"",
+		"./start.sh",
+		"",
+		"",
+		"",
+		false,
+		time.Time{},
+		vars,
+		domain.Settings{},
+		time.Time{},
+		0,
+		0,
+	)
+}
+
+func TestEnabled(t *testing.T) {
+	assert.False(t, Enabled(givenServer(nil, "secret")))
+	assert.False(t, Enabled(givenServer(map[string]string{ProtocolKey: "source"}, "")))
+	assert.True(t, Enabled(givenServer(map[string]string{ProtocolKey: "Source"}, "secret")))
+}
+
+func TestConsole_SendInput_FallsBackToProcessManager(t *testing.T) {
+	pm := &fakeProcessManager{}
+	console := NewConsole(pm)
+	console.dialFn = func(_ context.Context, _ *domain.Server) (Conn, error) {
+		t.Fatal("unexpected dial")
+		return nil, nil
+	}
+
+	result, err := console.SendInput(context.Background(), "status", givenServer(nil, "secret"), io.Discard)
+
+	require.NoError(t, err)
+	assert.Equal(t, domain.SuccessResult, result)
+	assert.Equal(t, []string{"status"}, pm.inputs)
+}
+
+func TestConsole_SendInput_OverRCON(t *testing.T) {
+	pm := &fakeProcessManager{}
+	conn := &fakeConn{}
+	console := NewConsole(pm)
+	console.dialFn = func(_ context.Context, _ *domain.Server) (Conn, error) {
+		return conn, nil
+	}
+	out := &bytes.Buffer{}
+
+	server := givenServer(map[string]string{ProtocolKey: "source"}, "secret")
+	result, err := console.SendInput(context.Background(), "status", server, out)
+
+	require.NoError(t, err)
+	assert.Equal(t, domain.SuccessResult, result)
+	assert.Equal(t, "reply to status\n", out.String())
+	assert.Empty(t, pm.inputs)
+	assert.True(t, conn.closed)
+}
+
+func TestConsole_Attach_OverRCON(t *testing.T) {
+	pm := &fakeProcessManager{}
+	conns := []*fakeConn{{failOn: "users"}, {}}
+	dials := 0
+	console := NewConsole(pm)
+	console.dialFn = func(_ context.Context, _ *domain.Server) (Conn, error) {
+		conn := conns[dials]
+		dials++
+
+		return conn, nil
+	}
+	out := &bytes.Buffer{}
+	in, inWriter := io.Pipe()
+	go func() {
+		// The replies follow the console output of the process manager.
+		time.Sleep(10 * time.Millisecond)
+		_, _ = io.WriteString(inWriter, "status\n\nusers\nmaps *\n")
+		_ = inWriter.Close()
+	}()
+
+	server := givenServer(map[string]string{ProtocolKey: "source"}, "secret")
+	err := console.Attach(context.Background(), server, in, out)
+
+	require.NoError(t, err)
+	assert.True(t, pm.attached)
+	assert.Equal(t, "console output\nreply to status\nreply to users\nreply to maps *\n", out.String())
+	// The broken connection is dialed again once.
+	assert.Equal(t, 2, dials)
+	assert.Equal(t, []string{"status"}, conns[0].commands)
+	assert.Equal(t, []string{"users", "maps *"}, conns[1].commands)
+	assert.True(t, conns[0].closed)
+	assert.True(t, conns[1].closed)
+}
+
+func TestConsole_Attach_FallsBackToProcessManager(t *testing.T) {
+	pm := &fakeProcessManager{}
+	console := NewConsole(pm)
+
+	err := console.Attach(context.Background(), givenServer(nil, ""), strings.NewReader(""), io.Discard)
+
+	require.NoError(t, err)
+	assert.True(t, pm.attached)
+}
+
+func TestConsole_SendInput_RCONUnavailable(t *testing.T) {
+	tests := []struct {
+		name   string
+		dialFn func(ctx context.Context, server *domain.Server) (Conn, error)
+	}{
+		{
+			name: "dial_failed",
+			dialFn: func(_ context.Context, _ *domain.Server) (Conn, error) {
+				return nil, errTestBroken
+			},
+		},
+		{
+			name: "auth_failed",
+			dialFn: func(_ context.Context, _ *domain.Server) (Conn, error) {
+				return nil, ErrAuthFailed
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			pm := &fakeProcessManager{}
+			console := NewConsole(pm)
+			console.dialFn = test.dialFn
+
+			server := givenServer(map[string]string{ProtocolKey: "source"}, "secret")
+			result, err := console.SendInput(context.Background(), "status", server, io.Discard)
+
+			require.NoError(t, err)
+			assert.Equal(t, domain.SuccessResult, result)
+			assert.Equal(t, []string{"status"}, pm.inputs)
+		})
+	}
+}
+
+func TestConsole_Attach_RCONUnavailable(t *testing.T) {
+	pm := &fakeProcessManager{}
+	console := NewConsole(pm)
+	console.dialFn = func(_ context.Context, _ *domain.Server) (Conn, error) {
+		return nil, ErrAuthFailed
+	}
+	out := &bytes.Buffer{}
+
+	server := givenServer(map[string]string{ProtocolKey: "source"}, "secret")
+	err := console.Attach(context.Background(), server, strings.NewReader("status\nusers\n"), out)
+
+	require.NoError(t, err)
+	assert.True(t, pm.attached)
+	assert.Equal(t, []string{"status", "users"}, pm.inputs)
+	assert.Equal(t, "console output\n", out.String())
+}
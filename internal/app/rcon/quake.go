@@ -0,0 +1,193 @@
+package rcon
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// quakeReplyGap is how long more reply datagrams are waited for after
+	// the last one, the protocol does not mark the end of a reply.
+	quakeReplyGap = 250 * time.Millisecond
+
+	quakeMaxPacket = 65535
+)
+
+var (
+	quakeHeader        = []byte("\xff\xff\xff\xff")
+	quake3Print        = []byte("\xff\xff\xff\xffprint\n")
+	goldSrcPrint       = []byte("\xff\xff\xff\xffl")
+	goldSrcChallenge   = []byte("\xff\xff\xff\xffchallenge rcon\n")
+	goldSrcChallengeOK = []byte("\xff\xff\xff\xffchallenge rcon ")
+
+	errBadChallenge = errors.New("bad rcon challenge")
+
+	// Replies to a wrong password: "Bad rconpassword." in Quake 3 and
+	// "Bad rcon_password." in GoldSrc.
+	quakeBadPassword = []string{"Bad rcon", "Invalid password"}
+)
+
+// quakeConn speaks the connectionless rcon of Quake 3 and GoldSrc, every
+// command carries the password. GoldSrc asks for a challenge first.
+type quakeConn struct {
+	conn     net.Conn
+	password string
+	goldSrc  bool
+
+	mu        sync.Mutex
+	challenge string
+}
+
+func dialQuake(ctx context.Context, address, password string, goldSrc bool) (*quakeConn, error) {
+	conn, err := dial(ctx, "udp", address)
+	if err != nil {
+		return nil, err
+	}
+
+	return &quakeConn{conn: conn, password: password, goldSrc: goldSrc}, nil
+}
+
+func (c *quakeConn) Exec(ctx context.Context, command string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var reply string
+
+	err := withDeadline(ctx, c.conn, func() error {
+		var err error
+		reply, err = c.exec(ctx, command)
+		if errors.Is(err, errBadChallenge) {
+			// The challenge expired, e.g. after a map change.
+			reply, err = c.exec(ctx, command)
+		}
+
+		return err
+	})
+
+	return reply, err
+}
+
+func (c *quakeConn) exec(ctx context.Context, command string) (string, error) {
+	var request []byte
+
+	if c.goldSrc {
+		if c.challenge == "" {
+			if err := c.getChallenge(ctx); err != nil {
+				return "", err
+			}
+		}
+
+		request = []byte(string(quakeHeader) + "rcon " + c.challenge + ` "` + c.password + `" ` + command + "\n")
+	} else {
+		request = []byte(string(quakeHeader) + "rcon " + c.password + " " + command)
+	}
+
+	if _, err := c.conn.Write(request); err != nil {
+		return "", errors.Wrap(err, "failed to send rcon command")
+	}
+
+	prefix := quake3Print
+	if c.goldSrc {
+		prefix = goldSrcPrint
+	}
+
+	reply, err := c.readReply(ctx, prefix)
+	if err != nil {
+		return "", err
+	}
+
+	if c.goldSrc && strings.HasPrefix(reply, "Bad challenge") {
+		c.challenge = ""
+		return "", errBadChallenge
+	}
+
+	for _, bad := range quakeBadPassword {
+		if strings.HasPrefix(reply, bad) {
+			c.challenge = ""
+			return "", ErrAuthFailed
+		}
+	}
+
+	return reply, nil
+}
+
+func (c *quakeConn) getChallenge(ctx context.Context) error {
+	// readReply of the previous command leaves the reply gap deadline.
+	deadline, _ := ctx.Deadline()
+	_ = c.conn.SetReadDeadline(deadline)
+
+	if _, err := c.conn.Write(goldSrcChallenge); err != nil {
+		return errors.Wrap(err, "failed to request rcon challenge")
+	}
+
+	buf := make([]byte, quakeMaxPacket)
+
+	n, err := c.conn.Read(buf)
+	if err != nil {
+		return errors.Wrap(err, "failed to read rcon challenge")
+	}
+
+	challenge, ok := bytes.CutPrefix(buf[:n], goldSrcChallengeOK)
+	if !ok {
+		return errors.Wrap(ErrInvalidResponse, "unexpected challenge response")
+	}
+
+	c.challenge = string(bytes.TrimSpace(bytes.TrimRight(challenge, "\x00")))
+
+	return nil
+}
+
+// readReply reads the first reply datagram within the deadline of ctx, then
+// the next ones while they keep coming.
+func (c *quakeConn) readReply(ctx context.Context, prefix []byte) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+
+	deadline, hasDeadline := ctx.Deadline()
+	_ = c.conn.SetReadDeadline(deadline)
+
+	buf := make([]byte, quakeMaxPacket)
+
+	var (
+		reply strings.Builder
+		got   bool
+	)
+
+	for {
+		n, err := c.conn.Read(buf)
+		if err != nil {
+			var netErr net.Error
+			if got && errors.As(err, &netErr) && netErr.Timeout() {
+				break
+			}
+
+			return "", errors.Wrap(err, "failed to read rcon reply")
+		}
+
+		body, ok := bytes.CutPrefix(buf[:n], prefix)
+		if !ok {
+			continue
+		}
+
+		got = true
+		reply.Write(bytes.TrimRight(body, "\x00"))
+
+		gap := time.Now().Add(quakeReplyGap)
+		if !hasDeadline || gap.Before(deadline) {
+			_ = c.conn.SetReadDeadline(gap)
+		}
+	}
+
+	return reply.String(), nil
+}
+
+func (c *quakeConn) Close() error {
+	return c.conn.Close()
+}
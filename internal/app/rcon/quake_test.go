@@ -0,0 +1,127 @@
+package rcon
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// serveUDP starts a stand-in server on the loopback. The handler returns
+// the datagrams sent back for a request.
+func serveUDP(t *testing.T, handler func(request string) []string) string {
+	t.Helper()
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+
+	go func() {
+		buf := make([]byte, quakeMaxPacket)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+
+			for _, packet := range handler(string(buf[:n])) {
+				_, _ = conn.WriteTo([]byte(packet), addr)
+			}
+		}
+	}()
+
+	return conn.LocalAddr().String()
+}
+
+func TestQuakeConn_Quake3(t *testing.T) {
+	address := serveUDP(t, func(request string) []string {
+		command, ok := strings.CutPrefix(request, "\xff\xff\xff\xffrcon secret ")
+		if !ok {
+			return []string{"\xff\xff\xff\xffprint\nBad rconpassword.\n"}
+		}
+		if command == "status" {
+			return []string{
+				"\xff\xff\xff\xffprint\nmap: q3dm17\n",
+				"\xff\xff\xff\xffprint\nnum score ping name\n",
+			}
+		}
+		return []string{"\xff\xff\xff\xffprint\n"}
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	conn, err := Dial(ctx, ProtocolQuake3, address, "secret")
+	require.NoError(t, err)
+	defer conn.Close()
+
+	reply, err := conn.Exec(ctx, "status")
+	require.NoError(t, err)
+	assert.Equal(t, "map: q3dm17\nnum score ping name\n", reply)
+
+	reply, err = conn.Exec(ctx, "say hi")
+	require.NoError(t, err)
+	assert.Empty(t, reply)
+
+	bad, err := Dial(ctx, ProtocolQuake3, address, "wrong")
+	require.NoError(t, err)
+	defer bad.Close()
+
+	_, err = bad.Exec(ctx, "status")
+	require.ErrorIs(t, err, ErrAuthFailed)
+}
+
+func TestQuakeConn_GoldSrcChallenge(t *testing.T) {
+	var challenges atomic.Int32
+
+	address := serveUDP(t, func(request string) []string {
+		if request == "\xff\xff\xff\xffchallenge rcon\n" {
+			n := challenges.Add(1)
+			return []string{"\xff\xff\xff\xffchallenge rcon " + strconv.Itoa(int(n)) + "\n\x00"}
+		}
+
+		// The first challenge expires right away.
+		if strings.HasPrefix(request, "\xff\xff\xff\xffrcon 1 ") {
+			return []string{"\xff\xff\xff\xfflBad challenge.\n\x00"}
+		}
+
+		command, ok := strings.CutPrefix(request, "\xff\xff\xff\xffrcon 2 \"secret\" ")
+		if !ok {
+			return []string{"\xff\xff\xff\xfflBad rcon_password.\n\x00"}
+		}
+
+		return []string{"\xff\xff\xff\xffl" + strings.TrimSpace(command) + " done\n\x00"}
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	conn, err := Dial(ctx, ProtocolGoldSrc, address, "secret")
+	require.NoError(t, err)
+	defer conn.Close()
+
+	reply, err := conn.Exec(ctx, "changelevel crossfire")
+	require.NoError(t, err)
+	assert.Equal(t, "changelevel crossfire done\n", reply)
+	assert.Equal(t, int32(2), challenges.Load())
+}
+
+func TestQuakeConn_Timeout(t *testing.T) {
+	address := serveUDP(t, func(_ string) []string { return nil })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	conn, err := Dial(ctx, ProtocolQuake3, address, "secret")
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = conn.Exec(ctx, "status")
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
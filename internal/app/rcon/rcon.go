@@ -0,0 +1,134 @@
+// Package rcon sends console commands to game servers over their remote
+// console protocols: Source RCON, Minecraft RCON and Quake style UDP rcon.
+// Unlike writing to the process stdin, a command sent over RCON returns its
+// textual reply.
+package rcon
+
+import (
+	"context"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/gameap/daemon/internal/app/domain"
+	"github.com/pkg/errors"
+)
+
+type Protocol string
+
+const (
+	ProtocolNone      Protocol = ""
+	ProtocolSource    Protocol = "source"
+	ProtocolMinecraft Protocol = "minecraft"
+	ProtocolQuake3    Protocol = "quake3"
+	ProtocolGoldSrc   Protocol = "goldsrc"
+)
+
+// ProtocolKey is the server variable and game (mod) metadata key that selects
+// the RCON protocol of a server.
+const ProtocolKey = "rcon_protocol"
+
+var (
+	ErrUnknownProtocol = errors.New("unknown rcon protocol")
+	ErrAuthFailed      = errors.New("rcon authentication failed")
+	ErrInvalidResponse = errors.New("invalid rcon response")
+	ErrNotConfigured   = errors.New("rcon is not configured for the server")
+)
+
+// Conn is an open remote console of a server.
+type Conn interface {
+	// Exec runs the command and returns its reply.
+	Exec(ctx context.Context, command string) (string, error)
+	Close() error
+}
+
+// Dial opens the remote console of the server at the address (host:port).
+// TCP protocols authenticate right away.
+func Dial(ctx context.Context, protocol Protocol, address, password string) (Conn, error) {
+	switch protocol {
+	case ProtocolSource:
+		return dialSource(ctx, address, password, false)
+	case ProtocolMinecraft:
+		return dialSource(ctx, address, password, true)
+	case ProtocolQuake3:
+		return dialQuake(ctx, address, password, false)
+	case ProtocolGoldSrc:
+		return dialQuake(ctx, address, password, true)
+	case ProtocolNone:
+		return nil, ErrNotConfigured
+	default:
+		return nil, errors.Wrapf(ErrUnknownProtocol, "got %q", protocol)
+	}
+}
+
+// ProtocolForServer returns the RCON protocol of the server with priority:
+// 1. Server vars
+// 2. GameMod metadata
+// 3. Game metadata
+func ProtocolForServer(server *domain.Server) Protocol {
+	if val, ok := server.LookupVar(ProtocolKey); ok {
+		return Protocol(strings.ToLower(val))
+	}
+
+	return ProtocolNone
+}
+
+// Enabled reports whether commands to the server go over RCON: the game
+// selects a protocol and the server has an RCON port and password.
+func Enabled(server *domain.Server) bool {
+	return ProtocolForServer(server) != ProtocolNone &&
+		server.RCONPort() != 0 &&
+		server.RCONPassword() != ""
+}
+
+// DialServer opens the remote console of the server.
+func DialServer(ctx context.Context, server *domain.Server) (Conn, error) {
+	if !Enabled(server) {
+		return nil, ErrNotConfigured
+	}
+
+	address := server.DialAddress(server.RCONPort())
+
+	return Dial(ctx, ProtocolForServer(server), address, server.RCONPassword())
+}
+
+// dial connects to the address within the deadline of ctx.
+func dial(ctx context.Context, network, address string) (net.Conn, error) {
+	var d net.Dialer
+
+	conn, err := d.DialContext(ctx, network, address)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to dial %s", address)
+	}
+
+	return conn, nil
+}
+
+// withDeadline sets the deadline of the connection from ctx for the time of
+// fn and interrupts fn when ctx is done.
+func withDeadline(ctx context.Context, conn net.Conn, fn func() error) error {
+	// No deadline in ctx clears the deadline of a previous command.
+	deadline, hasDeadline := ctx.Deadline()
+	_ = conn.SetDeadline(deadline)
+
+	stop := context.AfterFunc(ctx, func() {
+		_ = conn.SetDeadline(time.Now())
+	})
+	defer stop()
+
+	err := fn()
+	if err == nil {
+		return nil
+	}
+
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	// The connection deadline may pass just before ctx is done.
+	if hasDeadline && !time.Now().Before(deadline) {
+		return context.DeadlineExceeded
+	}
+
+	return err
+}
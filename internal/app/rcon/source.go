@@ -0,0 +1,212 @@
+package rcon
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	sourceAuth          = 3
+	sourceAuthResponse  = 2
+	sourceExecCommand   = 2
+	sourceResponseValue = 0
+
+	// sourceHeaderSize is the id and type fields plus the two terminating
+	// null bytes counted in the packet size.
+	sourceHeaderSize = 10
+
+	// sourceMaxPacket is the largest packet size accepted from a server.
+	sourceMaxPacket = 1 << 16
+
+	// minecraftMaxCommand is the longest command Minecraft accepts.
+	minecraftMaxCommand = 1446
+)
+
+var errCommandTooLong = errors.New("command is too long")
+
+// sourceConn speaks the Source RCON protocol, also implemented by Minecraft.
+// Commands of one connection are executed one at a time.
+type sourceConn struct {
+	conn      net.Conn
+	r         *bufio.Reader
+	minecraft bool
+
+	mu     sync.Mutex
+	nextID int32
+}
+
+type sourcePacket struct {
+	id   int32
+	kind int32
+	body string
+}
+
+func dialSource(ctx context.Context, address, password string, minecraft bool) (*sourceConn, error) {
+	conn, err := dial(ctx, "tcp", address)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &sourceConn{
+		conn:      conn,
+		r:         bufio.NewReader(conn),
+		minecraft: minecraft,
+		nextID:    1,
+	}
+
+	if err := withDeadline(ctx, conn, func() error { return c.auth(password) }); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+
+	return c, nil
+}
+
+func (c *sourceConn) auth(password string) error {
+	id := c.id()
+
+	if err := c.write(sourcePacket{id: id, kind: sourceAuth, body: password}); err != nil {
+		return err
+	}
+
+	// Source servers send an empty response value before the auth
+	// response, Minecraft sends the auth response only.
+	for {
+		p, err := c.read()
+		if err != nil {
+			return err
+		}
+
+		if p.kind != sourceAuthResponse {
+			continue
+		}
+		// A wrong password is answered with id -1.
+		if p.id != id {
+			return ErrAuthFailed
+		}
+
+		return nil
+	}
+}
+
+func (c *sourceConn) Exec(ctx context.Context, command string) (string, error) {
+	if c.minecraft && len(command) > minecraftMaxCommand {
+		return "", errors.Wrapf(errCommandTooLong, "%d bytes", len(command))
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var reply string
+
+	err := withDeadline(ctx, c.conn, func() error {
+		var err error
+		reply, err = c.exec(command)
+
+		return err
+	})
+
+	return reply, err
+}
+
+// exec sends the command. A Source server may split a long reply into
+// several packets, so an empty response value packet is sent after the
+// command: the server mirrors it once the whole reply is sent. Minecraft
+// replies with one packet.
+func (c *sourceConn) exec(command string) (string, error) {
+	id := c.id()
+
+	if err := c.write(sourcePacket{id: id, kind: sourceExecCommand, body: command}); err != nil {
+		return "", err
+	}
+
+	var endID int32
+	if !c.minecraft {
+		endID = c.id()
+		if err := c.write(sourcePacket{id: endID, kind: sourceResponseValue}); err != nil {
+			return "", err
+		}
+	}
+
+	var reply strings.Builder
+
+	for {
+		p, err := c.read()
+		if err != nil {
+			return "", err
+		}
+
+		switch {
+		case p.id == id && p.kind == sourceResponseValue:
+			reply.WriteString(p.body)
+			if c.minecraft {
+				return reply.String(), nil
+			}
+		case p.id == endID && !c.minecraft:
+			return reply.String(), nil
+		}
+
+		// Anything else is left from an earlier command, e.g. the second
+		// packet some servers send in reply to the end marker.
+	}
+}
+
+func (c *sourceConn) id() int32 {
+	id := c.nextID
+	c.nextID++
+	if c.nextID <= 0 {
+		c.nextID = 1
+	}
+
+	return id
+}
+
+func (c *sourceConn) write(p sourcePacket) error {
+	buf := make([]byte, 0, 4+sourceHeaderSize+len(p.body))
+	buf = binary.LittleEndian.AppendUint32(buf, uint32(sourceHeaderSize+len(p.body))) //nolint:gosec
+	buf = binary.LittleEndian.AppendUint32(buf, uint32(p.id))                         //nolint:gosec
+	buf = binary.LittleEndian.AppendUint32(buf, uint32(p.kind))                       //nolint:gosec
+	buf = append(buf, p.body...)
+	buf = append(buf, 0, 0)
+
+	if _, err := c.conn.Write(buf); err != nil {
+		return errors.Wrap(err, "failed to send rcon packet")
+	}
+
+	return nil
+}
+
+func (c *sourceConn) read() (sourcePacket, error) {
+	var header [12]byte
+	if _, err := io.ReadFull(c.r, header[:]); err != nil {
+		return sourcePacket{}, errors.Wrap(err, "failed to read rcon packet")
+	}
+
+	size := int32(binary.LittleEndian.Uint32(header[0:])) //nolint:gosec
+	if size < sourceHeaderSize || size > sourceMaxPacket {
+		return sourcePacket{}, errors.Wrapf(ErrInvalidResponse, "packet size %d", size)
+	}
+
+	body := make([]byte, size-8)
+	if _, err := io.ReadFull(c.r, body); err != nil {
+		return sourcePacket{}, errors.Wrap(err, "failed to read rcon packet")
+	}
+
+	return sourcePacket{
+		id:   int32(binary.LittleEndian.Uint32(header[4:])), //nolint:gosec
+		kind: int32(binary.LittleEndian.Uint32(header[8:])), //nolint:gosec
+		body: string(bytes.TrimRight(body, "\x00")),
+	}, nil
+}
+
+func (c *sourceConn) Close() error {
+	return c.conn.Close()
+}
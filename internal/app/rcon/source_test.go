@@ -0,0 +1,131 @@
+package rcon
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// serveSource starts a stand-in Source RCON server. Replies to commands are
+// split into packets of chunk bytes. In the Minecraft mode the auth is not
+// preceded by an empty response and the end marker is not mirrored.
+func serveSource(t *testing.T, password string, minecraft bool, chunk int) string {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = l.Close() })
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+
+			go handleSourceConn(conn, password, minecraft, chunk)
+		}
+	}()
+
+	return l.Addr().String()
+}
+
+func handleSourceConn(conn net.Conn, password string, minecraft bool, chunk int) {
+	defer conn.Close()
+
+	c := &sourceConn{conn: conn, r: bufio.NewReader(conn)}
+
+	for {
+		p, err := c.read()
+		if err != nil {
+			return
+		}
+
+		switch p.kind {
+		case sourceAuth:
+			if !minecraft {
+				_ = c.write(sourcePacket{id: p.id, kind: sourceResponseValue})
+			}
+			id := p.id
+			if p.body != password {
+				id = -1
+			}
+			_ = c.write(sourcePacket{id: id, kind: sourceAuthResponse})
+		case sourceExecCommand:
+			reply := "echo: " + p.body
+			if p.body == "cvarlist" {
+				reply = strings.Repeat("x", 3*chunk+5)
+			}
+			for len(reply) > chunk {
+				_ = c.write(sourcePacket{id: p.id, kind: sourceResponseValue, body: reply[:chunk]})
+				reply = reply[chunk:]
+			}
+			_ = c.write(sourcePacket{id: p.id, kind: sourceResponseValue, body: reply})
+		case sourceResponseValue:
+			if !minecraft {
+				_ = c.write(sourcePacket{id: p.id, kind: sourceResponseValue})
+				_ = c.write(sourcePacket{id: p.id, kind: sourceResponseValue, body: "\x00\x01\x00\x00"})
+			}
+		}
+	}
+}
+
+func TestSourceConn_Exec(t *testing.T) {
+	address := serveSource(t, "secret", false, 16)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	conn, err := Dial(ctx, ProtocolSource, address, "secret")
+	require.NoError(t, err)
+	defer conn.Close()
+
+	reply, err := conn.Exec(ctx, "status")
+	require.NoError(t, err)
+	assert.Equal(t, "echo: status", reply)
+
+	// A reply split into packets is put together.
+	reply, err = conn.Exec(ctx, "cvarlist")
+	require.NoError(t, err)
+	assert.Equal(t, strings.Repeat("x", 53), reply)
+
+	// The leftovers of the end marker do not leak into the next reply.
+	reply, err = conn.Exec(ctx, "users")
+	require.NoError(t, err)
+	assert.Equal(t, "echo: users", reply)
+}
+
+func TestSourceConn_Minecraft(t *testing.T) {
+	address := serveSource(t, "secret", true, 4096)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	conn, err := Dial(ctx, ProtocolMinecraft, address, "secret")
+	require.NoError(t, err)
+	defer conn.Close()
+
+	reply, err := conn.Exec(ctx, "list")
+	require.NoError(t, err)
+	assert.Equal(t, "echo: list", reply)
+
+	_, err = conn.Exec(ctx, strings.Repeat("a", minecraftMaxCommand+1))
+	require.ErrorIs(t, err, errCommandTooLong)
+}
+
+func TestSourceConn_AuthFailed(t *testing.T) {
+	address := serveSource(t, "secret", false, 4096)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	_, err := Dial(ctx, ProtocolSource, address, "wrong")
+
+	require.ErrorIs(t, err, ErrAuthFailed)
+}
@@ -0,0 +1,218 @@
+package readiness
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/gameap/daemon/internal/app/contracts"
+	"github.com/gameap/daemon/internal/app/domain"
+	"github.com/gameap/daemon/internal/app/query"
+	"github.com/gameap/daemon/pkg/logger"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// waitInterval is the time between two rounds of probes in WaitReady.
+	waitInterval = 2 * time.Second
+
+	dialTimeout = time.Second
+)
+
+// Checker runs the readiness probes of starting servers and moves them to
+// running or, once the timeout passes, to unhealthy.
+type Checker struct {
+	pm           contracts.ProcessManager
+	timeout      time.Duration
+	queryTimeout time.Duration
+
+	queryFn func(ctx context.Context, server *domain.Server, full bool) (*query.Result, error)
+	nowFn   func() time.Time
+
+	mu sync.Mutex
+	// baselines are the console lines of servers before they were started.
+	baselines map[int][]string
+}
+
+func NewChecker(pm contracts.ProcessManager, timeout, queryTimeout time.Duration) *Checker {
+	return &Checker{
+		pm:           pm,
+		timeout:      timeout,
+		queryTimeout: queryTimeout,
+		queryFn:      query.QueryServer,
+		nowFn:        time.Now,
+		baselines:    make(map[int][]string),
+	}
+}
+
+// BeforeStart remembers the console output of a server with a console
+// probe. Process managers keeping the output of an earlier run would make
+// the server ready right away otherwise.
+func (c *Checker) BeforeStart(ctx context.Context, server *domain.Server) {
+	c.forget(server.ID())
+
+	probes, err := ProbesForServer(server, c.timeout)
+	if err != nil || probes.Pattern == nil {
+		return
+	}
+
+	lines, err := c.outputLines(ctx, server)
+	if err != nil {
+		logger.WithError(ctx, err).Debug("Failed to read console output before start")
+		return
+	}
+
+	c.mu.Lock()
+	c.baselines[server.ID()] = lines
+	c.mu.Unlock()
+}
+
+// Check runs the probes of a starting server once and returns its run state
+// afterwards. Servers in other states are not probed.
+func (c *Checker) Check(ctx context.Context, server *domain.Server) domain.RunState {
+	if server.RunState() != domain.RunStateStarting {
+		return server.RunState()
+	}
+
+	probes, err := ProbesForServer(server, c.timeout)
+	if err != nil {
+		logger.Warn(ctx, errors.WithMessage(err, "readiness probes of the server are ignored"))
+		c.ready(ctx, server)
+
+		return server.RunState()
+	}
+
+	if probes.Empty() || c.passes(ctx, server, probes) {
+		c.ready(ctx, server)
+
+		return server.RunState()
+	}
+
+	if starting := c.nowFn().Sub(server.RunStateChangedAt()); starting >= probes.Timeout {
+		server.SetRunState(domain.RunStateUnhealthy)
+		c.forget(server.ID())
+
+		logger.WithField(ctx, "timeout", probes.Timeout).Warn("Server did not become ready in time")
+	}
+
+	return server.RunState()
+}
+
+// WaitReady probes a starting server until it is ready. It fails when the
+// server becomes unhealthy, stops or is paused.
+func (c *Checker) WaitReady(ctx context.Context, server *domain.Server) error {
+	for {
+		switch c.Check(ctx, server) {
+		case domain.RunStateRunning:
+			return nil
+		case domain.RunStateUnhealthy:
+			return ErrNotReady
+		case domain.RunStateStopped:
+			return ErrStopped
+		case domain.RunStatePaused:
+			return ErrPaused
+		case domain.RunStateStarting:
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(waitInterval):
+		}
+	}
+}
+
+func (c *Checker) ready(ctx context.Context, server *domain.Server) {
+	startup := c.nowFn().Sub(server.RunStateChangedAt())
+
+	server.SetRunState(domain.RunStateRunning)
+	c.forget(server.ID())
+
+	logger.WithFields(ctx, log.Fields{"startup": startup.Round(time.Second)}).Info("Server is ready")
+}
+
+func (c *Checker) passes(ctx context.Context, server *domain.Server, probes Probes) bool {
+	if probes.Port != 0 && !portBound(ctx, server, probes.Network, probes.Port) {
+		return false
+	}
+
+	if probes.Query {
+		ctx, cancel := context.WithTimeout(ctx, c.queryTimeout)
+		defer cancel()
+
+		if _, err := c.queryFn(ctx, server, false); err != nil {
+			return false
+		}
+	}
+
+	if probes.Pattern != nil {
+		return c.consoleMatches(ctx, server, probes)
+	}
+
+	return true
+}
+
+func (c *Checker) consoleMatches(ctx context.Context, server *domain.Server, probes Probes) bool {
+	lines, err := c.outputLines(ctx, server)
+	if err != nil {
+		logger.WithError(ctx, err).Debug("Failed to read console output")
+		return false
+	}
+
+	c.mu.Lock()
+	baseline := c.baselines[server.ID()]
+	c.mu.Unlock()
+
+	for _, line := range domain.NewConsoleLines(baseline, lines) {
+		if probes.Pattern.MatchString(line) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (c *Checker) outputLines(ctx context.Context, server *domain.Server) ([]string, error) {
+	var out bytes.Buffer
+
+	if _, err := c.pm.GetOutput(ctx, server, &out); err != nil {
+		return nil, err
+	}
+
+	return domain.ConsoleLines(out.Bytes()), nil
+}
+
+func (c *Checker) forget(serverID int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.baselines, serverID)
+}
+
+// portBound reports whether the server listens on the port. A TCP port is
+// connected to. UDP has no handshake, the port is looked up in the socket
+// table instead: binding it to find out could take it from the starting
+// server.
+func portBound(ctx context.Context, server *domain.Server, network string, port int) bool {
+	if network == "udp" {
+		bound, err := udpPortBound(ctx, port)
+		if err != nil {
+			logger.WithError(ctx, err).Debug("Failed to look up udp sockets")
+		}
+
+		return bound
+	}
+
+	d := net.Dialer{Timeout: dialTimeout}
+
+	conn, err := d.DialContext(ctx, "tcp", server.DialAddress(port))
+	if err != nil {
+		return false
+	}
+	_ = conn.Close()
+
+	return true
+}
@@ -0,0 +1,154 @@
+package readiness
+
+import (
+	"context"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gameap/daemon/internal/app/contracts"
+	"github.com/gameap/daemon/internal/app/domain"
+	"github.com/gameap/daemon/internal/app/query"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var errTestTimeout = errors.New("i/o timeout")
+
+type fakeProcessManager struct {
+	contracts.ProcessManager
+
+	mu     sync.Mutex
+	output string
+}
+
+func (pm *fakeProcessManager) GetOutput(_ context.Context, _ *domain.Server, out io.Writer) (domain.Result, error) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	_, _ = io.WriteString(out, pm.output)
+
+	return domain.SuccessResult, nil
+}
+
+func (pm *fakeProcessManager) print(output string) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	pm.output += output
+}
+
+func givenStartingServer(vars map[string]string) *domain.Server {
+	server := givenServer(domain.Game{Engine: "minecraft"}, domain.GameMod{}, vars)
+	server.AffectStart()
+	server.SetStatus(true)
+
+	return server
+}
+
+func TestChecker_Console(t *testing.T) {
+	pm := &fakeProcessManager{output: "[Server thread/INFO]: Done (2.1s)! For help\n[Server thread/INFO]: Stopping\n"}
+	checker := NewChecker(pm, time.Minute, time.Second)
+	server := givenStartingServer(map[string]string{PatternKey: `Done \(.*\)! For help`})
+
+	// The output of the previous run is kept by the process manager.
+	checker.BeforeStart(context.Background(), server)
+	pm.print("[Server thread/INFO]: Preparing level \"world\"\n")
+
+	assert.Equal(t, domain.RunStateStarting, checker.Check(context.Background(), server))
+
+	pm.print("[Server thread/INFO]: Done (4.7s)! For help\n")
+
+	assert.Equal(t, domain.RunStateRunning, checker.Check(context.Background(), server))
+	assert.True(t, server.IsReady())
+}
+
+func TestChecker_Console_SameLineAsPreviousRun(t *testing.T) {
+	pm := &fakeProcessManager{output: "VAC secure mode is activated.\nServer stopped\n"}
+	checker := NewChecker(pm, time.Minute, time.Second)
+	server := givenStartingServer(map[string]string{PatternKey: `^VAC secure mode is activated\.$`})
+
+	checker.BeforeStart(context.Background(), server)
+
+	assert.Equal(t, domain.RunStateStarting, checker.Check(context.Background(), server))
+
+	pm.print("VAC secure mode is activated.\n")
+
+	assert.Equal(t, domain.RunStateRunning, checker.Check(context.Background(), server))
+}
+
+func TestChecker_Port(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	port := l.Addr().(*net.TCPAddr).Port
+
+	checker := NewChecker(&fakeProcessManager{}, time.Minute, time.Second)
+	server := givenStartingServer(map[string]string{PortKey: "tcp:" + strconv.Itoa(port)})
+
+	assert.Equal(t, domain.RunStateRunning, checker.Check(context.Background(), server))
+
+	require.NoError(t, l.Close())
+	server.AffectStart()
+
+	assert.Equal(t, domain.RunStateStarting, checker.Check(context.Background(), server))
+}
+
+func TestChecker_UDPPort(t *testing.T) {
+	conn, err := net.ListenPacket("udp", ":0")
+	require.NoError(t, err)
+	port := conn.LocalAddr().(*net.UDPAddr).Port
+
+	checker := NewChecker(&fakeProcessManager{}, time.Minute, time.Second)
+	server := givenStartingServer(map[string]string{PortKey: "udp:" + strconv.Itoa(port)})
+
+	assert.Equal(t, domain.RunStateRunning, checker.Check(context.Background(), server))
+
+	require.NoError(t, conn.Close())
+	server.AffectStart()
+
+	assert.Equal(t, domain.RunStateStarting, checker.Check(context.Background(), server))
+}
+
+func TestChecker_QueryTimesOut(t *testing.T) {
+	checker := NewChecker(&fakeProcessManager{}, time.Minute, time.Second)
+	checker.queryFn = func(_ context.Context, _ *domain.Server, _ bool) (*query.Result, error) {
+		return nil, errTestTimeout
+	}
+	server := givenStartingServer(map[string]string{QueryKey: "1", TimeoutKey: "2m"})
+
+	checker.nowFn = func() time.Time { return server.RunStateChangedAt().Add(time.Minute) }
+	assert.Equal(t, domain.RunStateStarting, checker.Check(context.Background(), server))
+
+	checker.nowFn = func() time.Time { return server.RunStateChangedAt().Add(2 * time.Minute) }
+	assert.Equal(t, domain.RunStateUnhealthy, checker.Check(context.Background(), server))
+
+	err := checker.WaitReady(context.Background(), server)
+	require.ErrorIs(t, err, ErrNotReady)
+}
+
+func TestChecker_NoProbes(t *testing.T) {
+	checker := NewChecker(&fakeProcessManager{}, time.Minute, time.Second)
+	server := givenStartingServer(nil)
+
+	err := checker.WaitReady(context.Background(), server)
+
+	require.NoError(t, err)
+	assert.Equal(t, domain.RunStateRunning, server.RunState())
+}
+
+func TestChecker_WaitReady_Canceled(t *testing.T) {
+	checker := NewChecker(&fakeProcessManager{}, time.Minute, time.Second)
+	server := givenStartingServer(map[string]string{PatternKey: "never"})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	err := checker.WaitReady(ctx, server)
+
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, domain.RunStateStarting, server.RunState())
+}
@@ -0,0 +1,123 @@
+// Package readiness tells when a started game server is actually joinable.
+// A server stays starting until every readiness probe configured for its
+// game passes: a line of the console output matches a pattern, a port is
+// bound or the server answers a query.
+package readiness
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gameap/daemon/internal/app/domain"
+	"github.com/gameap/daemon/internal/app/query"
+	"github.com/pkg/errors"
+)
+
+// Server variable and game (mod) metadata keys of the readiness probes.
+const (
+	// PatternKey is a regular expression matched against new console lines,
+	// e.g. `Done \(.*\)! For help`.
+	PatternKey = "ready_console_regex"
+
+	// PortKey is the port that has to be bound: "tcp" or "udp" for the
+	// connect port of the server, or "tcp:<port>" and "udp:<port>".
+	PortKey = "ready_port"
+
+	// QueryKey makes a successful query response a probe.
+	QueryKey = "ready_query"
+
+	// TimeoutKey overrides the readiness timeout, e.g. "10m".
+	TimeoutKey = "ready_timeout"
+)
+
+var (
+	ErrInvalidProbe = errors.New("invalid readiness probe")
+	ErrNotReady     = errors.New("server did not become ready")
+	ErrStopped      = errors.New("server stopped while starting")
+	ErrPaused       = errors.New("server paused while starting")
+)
+
+// Probes are the readiness probes of a server.
+type Probes struct {
+	Pattern *regexp.Regexp
+	Network string
+	Port    int
+	Query   bool
+	Timeout time.Duration
+}
+
+// Empty reports whether no probe is configured, such a server is ready as
+// soon as its process runs.
+func (p Probes) Empty() bool {
+	return p.Pattern == nil && p.Port == 0 && !p.Query
+}
+
+// ProbesForServer reads the probes of the server. Every key is looked up
+// with priority:
+// 1. Server vars
+// 2. GameMod metadata
+// 3. Game metadata
+func ProbesForServer(server *domain.Server, defaultTimeout time.Duration) (Probes, error) {
+	probes := Probes{Timeout: defaultTimeout}
+
+	if val, ok := server.LookupVar(PatternKey); ok {
+		pattern, err := regexp.Compile(val)
+		if err != nil {
+			return Probes{}, errors.Wrapf(ErrInvalidProbe, "%s: %s", PatternKey, err)
+		}
+		probes.Pattern = pattern
+	}
+
+	if val, ok := server.LookupVar(PortKey); ok {
+		network, port, err := parsePort(val, server.ConnectPort())
+		if err != nil {
+			return Probes{}, err
+		}
+		probes.Network, probes.Port = network, port
+	}
+
+	if val, ok := server.LookupVar(QueryKey); ok {
+		enabled, err := strconv.ParseBool(val)
+		if err != nil {
+			return Probes{}, errors.Wrapf(ErrInvalidProbe, "%s: %q", QueryKey, val)
+		}
+		if enabled && query.ProtocolForServer(server) == query.ProtocolNone {
+			return Probes{}, errors.Wrapf(ErrInvalidProbe, "%s: %s", QueryKey, query.ErrNoProtocol)
+		}
+		probes.Query = enabled
+	}
+
+	if val, ok := server.LookupVar(TimeoutKey); ok {
+		timeout, err := time.ParseDuration(val)
+		if err != nil || timeout <= 0 {
+			return Probes{}, errors.Wrapf(ErrInvalidProbe, "%s: %q", TimeoutKey, val)
+		}
+		probes.Timeout = timeout
+	}
+
+	return probes, nil
+}
+
+func parsePort(val string, connectPort int) (string, int, error) {
+	network, portValue, hasPort := strings.Cut(strings.ToLower(val), ":")
+	if network != "tcp" && network != "udp" {
+		return "", 0, errors.Wrapf(ErrInvalidProbe, "%s: unknown network in %q", PortKey, val)
+	}
+
+	port := connectPort
+	if hasPort {
+		var err error
+		port, err = strconv.Atoi(portValue)
+		if err != nil {
+			return "", 0, errors.Wrapf(ErrInvalidProbe, "%s: invalid port in %q", PortKey, val)
+		}
+	}
+
+	if port <= 0 || port > 65535 {
+		return "", 0, errors.Wrapf(ErrInvalidProbe, "%s: no port to check", PortKey)
+	}
+
+	return network, port, nil
+}
@@ -0,0 +1,92 @@
+package readiness
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gameap/daemon/internal/app/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func givenServer(game domain.Game, gameMod domain.GameMod, vars map[string]string) *domain.Server {
+	return domain.NewServer(
+		1,
+		true,
+		domain.ServerInstalled,
+		false,
+		"test",
+		"9fd2e4d1-4a4e-4d3c-9a2f-5a4b5d8c9e01",
+		"9fd2e4d1",
+		game,
+		gameMod,
+		"127.0.0.1",
+		27015,
+		27015,
+		27016,
+		"",
+		"servers/test",
+		"",
+		"./start.sh",
+		"",
+		"",
+		"",
+		false,
+		time.Time{},
+		vars,
+		domain.Settings{},
+		time.Time{},
+		0,
+		0,
+	)
+}
+
+func TestProbesForServer(t *testing.T) {
+	game := domain.Game{Engine: "minecraft", Metadata: map[string]any{
+		PatternKey: `Done \(.*\)! For help`,
+		QueryKey:   true,
+		TimeoutKey: "10m",
+	}}
+	gameMod := domain.GameMod{Metadata: map[string]any{PortKey: "udp:19132"}}
+
+	probes, err := ProbesForServer(givenServer(game, gameMod, map[string]string{PortKey: "tcp"}), time.Minute)
+
+	require.NoError(t, err)
+	require.NotNil(t, probes.Pattern)
+	assert.True(t, probes.Pattern.MatchString(`[Server thread/INFO]: Done (3.2s)! For help, type "help"`))
+	// Server vars override the game mod metadata, the connect port is the default.
+	assert.Equal(t, "tcp", probes.Network)
+	assert.Equal(t, 27015, probes.Port)
+	assert.True(t, probes.Query)
+	assert.Equal(t, 10*time.Minute, probes.Timeout)
+	assert.False(t, probes.Empty())
+}
+
+func TestProbesForServer_Empty(t *testing.T) {
+	probes, err := ProbesForServer(givenServer(domain.Game{}, domain.GameMod{}, nil), time.Minute)
+
+	require.NoError(t, err)
+	assert.True(t, probes.Empty())
+	assert.Equal(t, time.Minute, probes.Timeout)
+}
+
+func TestProbesForServer_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		vars map[string]string
+	}{
+		{name: "pattern", vars: map[string]string{PatternKey: "Done ("}},
+		{name: "network", vars: map[string]string{PortKey: "sctp"}},
+		{name: "port", vars: map[string]string{PortKey: "udp:port"}},
+		{name: "query without protocol", vars: map[string]string{QueryKey: "true"}},
+		{name: "timeout", vars: map[string]string{TimeoutKey: "soon"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := ProbesForServer(givenServer(domain.Game{}, domain.GameMod{}, test.vars), time.Minute)
+
+			require.ErrorIs(t, err, ErrInvalidProbe)
+		})
+	}
+}
@@ -0,0 +1,64 @@
+//go:build linux
+
+package readiness
+
+import (
+	"bufio"
+	"context"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+var procNetUDP = []string{"/proc/net/udp", "/proc/net/udp6"}
+
+// udpPortBound reports whether a UDP socket is bound to the local port.
+func udpPortBound(_ context.Context, port int) (bool, error) {
+	var lastErr error
+
+	for _, path := range procNetUDP {
+		bound, err := procNetPortBound(path, port)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if bound {
+			return true, nil
+		}
+	}
+
+	return false, lastErr
+}
+
+// procNetPortBound looks for the port in the local_address column of a
+// /proc/net socket table, such as "0100007F:6987".
+func procNetPortBound(path string, port int) (bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to open %s", path)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Scan() // header
+
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+
+		_, hexPort, ok := strings.Cut(fields[1], ":")
+		if !ok {
+			continue
+		}
+
+		if p, err := strconv.ParseUint(hexPort, 16, 16); err == nil && int(p) == port {
+			return true, nil
+		}
+	}
+
+	return false, errors.Wrapf(scanner.Err(), "failed to read %s", path)
+}
@@ -0,0 +1,26 @@
+//go:build !linux
+
+package readiness
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	psnet "github.com/shirou/gopsutil/v4/net"
+)
+
+// udpPortBound reports whether a UDP socket is bound to the local port.
+func udpPortBound(ctx context.Context, port int) (bool, error) {
+	conns, err := psnet.ConnectionsWithoutUidsWithContext(ctx, "udp")
+	if err != nil {
+		return false, errors.Wrap(err, "failed to list udp sockets")
+	}
+
+	for _, conn := range conns {
+		if int(conn.Laddr.Port) == port {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
@@ -6,17 +6,53 @@ import (
 	"time"
 
 	"github.com/gameap/daemon/internal/app/domain"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
 )
 
 type ServerRepository struct {
 	servers     sync.Map
 	lastUpdated sync.Map
+
+	// store is nil when servers are kept in memory only.
+	store *serverStateStore
 }
 
 func NewServerRepository() *ServerRepository {
 	return &ServerRepository{}
 }
 
+// NewPersistentServerRepository returns a repository that mirrors the cached
+// servers to stateFile. Call Restore to load the servers saved by a previous
+// daemon run.
+func NewPersistentServerRepository(stateFile string) *ServerRepository {
+	return &ServerRepository{
+		store: newServerStateStore(stateFile),
+	}
+}
+
+// Restore loads the servers saved by a previous daemon run into the cache.
+// Servers already in the cache are kept, they are newer than the saved ones.
+func (repo *ServerRepository) Restore(_ context.Context) (int, error) {
+	if repo.store == nil {
+		return 0, nil
+	}
+
+	states, err := repo.store.load()
+	if err != nil {
+		return 0, err
+	}
+
+	restored := 0
+	for _, state := range states {
+		if _, loaded := repo.servers.LoadOrStore(state.ID, domain.NewServerFromState(state)); !loaded {
+			restored++
+		}
+	}
+
+	return restored, nil
+}
+
 func (repo *ServerRepository) IDsFromCache() []int {
 	var ids []int
 	repo.servers.Range(func(key, _ interface{}) bool {
@@ -41,8 +77,10 @@ func (repo *ServerRepository) FindByID(_ context.Context, id int) (*domain.Serve
 	return server, nil
 }
 
-func (repo *ServerRepository) Save(_ context.Context, _ *domain.Server) error {
-	return nil
+func (repo *ServerRepository) Save(_ context.Context, server *domain.Server) error {
+	repo.servers.Store(server.ID(), server)
+
+	return repo.persistServer(server)
 }
 
 func (repo *ServerRepository) FindByIDFromCache(id int) (*domain.Server, bool) {
@@ -57,6 +95,71 @@ func (repo *ServerRepository) FindByIDFromCache(id int) (*domain.Server, bool) {
 func (repo *ServerRepository) SaveToCache(server *domain.Server) {
 	repo.servers.Store(server.ID(), server)
 	repo.lastUpdated.Store(server.ID(), time.Now())
+
+	if err := repo.persistServer(server); err != nil {
+		log.WithError(err).WithField("serverID", server.ID()).Warn("failed to persist server state")
+	}
+}
+
+// RetainInCache removes the servers that are not in ids from the cache and
+// returns the removed IDs. The panel sends all servers of the node on
+// connect, the servers it no longer has are not managed by the daemon.
+func (repo *ServerRepository) RetainInCache(ids []int) []int {
+	keep := make(map[int]struct{}, len(ids))
+	for _, id := range ids {
+		keep[id] = struct{}{}
+	}
+
+	var removed []int
+	for _, id := range repo.IDsFromCache() {
+		if _, ok := keep[id]; ok {
+			continue
+		}
+
+		repo.servers.Delete(id)
+		repo.lastUpdated.Delete(id)
+		removed = append(removed, id)
+	}
+
+	if len(removed) == 0 {
+		return nil
+	}
+
+	if err := repo.persist(); err != nil {
+		log.WithError(err).WithField("serverIDs", removed).Warn("failed to persist server state")
+	}
+
+	return removed
+}
+
+// persistServer writes the cached servers when the saved server changed since
+// it was last written.
+func (repo *ServerRepository) persistServer(server *domain.Server) error {
+	if repo.store == nil {
+		return nil
+	}
+
+	return errors.WithMessage(repo.store.saveChanged(server, repo.cachedServers), "failed to persist servers")
+}
+
+func (repo *ServerRepository) persist() error {
+	if repo.store == nil {
+		return nil
+	}
+
+	return errors.WithMessage(repo.store.save(repo.cachedServers()), "failed to persist servers")
+}
+
+func (repo *ServerRepository) cachedServers() []*domain.Server {
+	var servers []*domain.Server
+	repo.servers.Range(func(_, value interface{}) bool {
+		if server, ok := value.(*domain.Server); ok {
+			servers = append(servers, server)
+		}
+		return true
+	})
+
+	return servers
 }
 
 func (repo *ServerRepository) CountOnlineServers() int {
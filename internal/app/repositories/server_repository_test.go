@@ -0,0 +1,201 @@
+package repositories
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gameap/daemon/internal/app/domain"
+	"github.com/gameap/daemon/internal/app/statefile"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestServer(id int) *domain.Server {
+	return domain.NewServer(
+		id,
+		true,
+		domain.ServerInstalled,
+		false,
+		"test",
+		"9fd2e4d1-4a4e-4d3c-9a2f-5a4b5d8c9e01",
+		"9fd2e4d1",
+		domain.Game{Code: "cstrike", StartCode: "cstrike"},
+		domain.GameMod{
+			ID:   2,
+			Name: "public",
+			Vars: []domain.GameModVarTemplate{{Key: "maxplayers", DefaultValue: "32"}},
+		},
+		"127.0.0.1",
+		27015,
+		27016,
+		27017,
+		"rconpass",
+		"servers/test",
+		"gameap",
+		"./hlds_run",
+		"quit",
+		"",
+		"",
+		false,
+		time.Time{},
+		map[string]string{"default_map": "de_dust2"},
+		domain.Settings{"autostart": "1"},
+		time.Time{},
+		1000,
+		1024,
+	)
+}
+
+func TestServerRepository_RestoreAfterRestart(t *testing.T) {
+	ctx := context.Background()
+	stateFile := filepath.Join(t.TempDir(), "state", ServerStateFileName)
+
+	repo := NewPersistentServerRepository(stateFile)
+	server := newTestServer(1)
+	repo.SaveToCache(server)
+
+	server.SetStatus(true)
+	server.AffectStart()
+	server.NoticeTaskCompleted()
+	require.NoError(t, repo.Save(ctx, server))
+
+	info, err := os.Stat(stateFile)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	restartedRepo := NewPersistentServerRepository(stateFile)
+	restored, err := restartedRepo.Restore(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, restored)
+
+	got, err := restartedRepo.FindByID(ctx, 1)
+	require.NoError(t, err)
+	require.NotNil(t, got)
+	assert.True(t, got.IsActive())
+	assert.True(t, got.AutoStart())
+	assert.Equal(t, "1", got.Setting("autostart_current"))
+	assert.Equal(t, domain.InstallationStatus(domain.ServerInstalled), got.InstallationStatus())
+	assert.WithinDuration(t, server.LastTaskCompletedAt(), got.LastTaskCompletedAt(), time.Millisecond)
+	assert.Equal(t, server.Vars(), got.Vars())
+	assert.Equal(t, server.GameMod(), got.GameMod())
+	assert.Equal(t, "quit", got.StopCommand())
+	assert.Equal(t, int64(1024), got.RAMLimit())
+}
+
+func TestServerRepository_RestoreMissingFile(t *testing.T) {
+	repo := NewPersistentServerRepository(filepath.Join(t.TempDir(), ServerStateFileName))
+
+	restored, err := repo.Restore(context.Background())
+
+	require.NoError(t, err)
+	assert.Equal(t, 0, restored)
+	assert.Empty(t, repo.IDsFromCache())
+}
+
+func TestServerRepository_RestoreCorruptedFile(t *testing.T) {
+	stateFile := filepath.Join(t.TempDir(), ServerStateFileName)
+	require.NoError(t, os.WriteFile(stateFile, []byte(`{"version":1,"data":[{"id":`), 0o600))
+	repo := NewPersistentServerRepository(stateFile)
+
+	_, err := repo.Restore(context.Background())
+
+	require.Error(t, err)
+	assert.Empty(t, repo.IDsFromCache())
+
+	// The next save replaces the corrupted file.
+	require.NoError(t, repo.Save(context.Background(), newTestServer(1)))
+	restored, err := NewPersistentServerRepository(stateFile).Restore(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, restored)
+}
+
+func TestServerRepository_RestoreUnsupportedVersion(t *testing.T) {
+	stateFile := filepath.Join(t.TempDir(), ServerStateFileName)
+	require.NoError(t, os.WriteFile(stateFile, []byte(`{"version":100,"data":[]}`), 0o600))
+
+	_, err := NewPersistentServerRepository(stateFile).Restore(context.Background())
+
+	require.ErrorIs(t, err, statefile.ErrUnsupportedVersion)
+}
+
+func TestServerRepository_SaveSkipsStatusCheckOnlyChanges(t *testing.T) {
+	ctx := context.Background()
+	stateFile := filepath.Join(t.TempDir(), ServerStateFileName)
+	repo := NewPersistentServerRepository(stateFile)
+	server := newTestServer(1)
+	require.NoError(t, repo.Save(ctx, server))
+	require.NoError(t, os.Remove(stateFile))
+
+	// Only the check timestamps are changed by a repeated status.
+	server.SetStatus(false)
+	require.NoError(t, repo.Save(ctx, server))
+	assert.NoFileExists(t, stateFile)
+
+	server.SetStatus(true)
+	require.NoError(t, repo.Save(ctx, server))
+	assert.FileExists(t, stateFile)
+}
+
+func TestServerRepository_SaveChecksOnlySavedServer(t *testing.T) {
+	ctx := context.Background()
+	stateFile := filepath.Join(t.TempDir(), ServerStateFileName)
+	repo := NewPersistentServerRepository(stateFile)
+	first, second := newTestServer(1), newTestServer(2)
+	require.NoError(t, repo.Save(ctx, first))
+	require.NoError(t, repo.Save(ctx, second))
+	require.NoError(t, os.Remove(stateFile))
+
+	// The change of the second server is written with the next change of a
+	// server, saving the unchanged first one does not look at it.
+	second.SetStatus(true)
+	require.NoError(t, repo.Save(ctx, first))
+	assert.NoFileExists(t, stateFile)
+
+	first.SetStatus(true)
+	require.NoError(t, repo.Save(ctx, first))
+
+	restoredRepo := NewPersistentServerRepository(stateFile)
+	_, err := restoredRepo.Restore(ctx)
+	require.NoError(t, err)
+	restored, ok := restoredRepo.FindByIDFromCache(2)
+	require.True(t, ok)
+	assert.True(t, restored.IsActive())
+
+	// The written state of the second server is up to date.
+	require.NoError(t, os.Remove(stateFile))
+	require.NoError(t, repo.Save(ctx, second))
+	assert.NoFileExists(t, stateFile)
+}
+
+func TestServerRepository_RetainInCache(t *testing.T) {
+	stateFile := filepath.Join(t.TempDir(), ServerStateFileName)
+	repo := NewPersistentServerRepository(stateFile)
+	for id := 1; id <= 3; id++ {
+		repo.SaveToCache(newTestServer(id))
+	}
+
+	removed := repo.RetainInCache([]int{1, 3, 4})
+
+	assert.Equal(t, []int{2}, removed)
+	assert.ElementsMatch(t, []int{1, 3}, repo.IDsFromCache())
+
+	restoredRepo := NewPersistentServerRepository(stateFile)
+	_, err := restoredRepo.Restore(context.Background())
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []int{1, 3}, restoredRepo.IDsFromCache())
+}
+
+func TestServerRepository_InMemory(t *testing.T) {
+	repo := NewServerRepository()
+	server := newTestServer(1)
+
+	require.NoError(t, repo.Save(context.Background(), server))
+	restored, err := repo.Restore(context.Background())
+
+	require.NoError(t, err)
+	assert.Equal(t, 0, restored)
+	assert.Equal(t, []int{1}, repo.IDsFromCache())
+}
@@ -0,0 +1,128 @@
+package repositories
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gameap/daemon/internal/app/domain"
+	"github.com/gameap/daemon/internal/app/statefile"
+	"github.com/pkg/errors"
+)
+
+const (
+	ServerStateFileName = "servers.json"
+
+	serverStateFileVersion = 1
+)
+
+// serverStateStore keeps server states in a single JSON file. The file is
+// only rewritten when a server changed in a way that matters after a restart,
+// the status loop touches the check timestamps of every server on each tick.
+type serverStateStore struct {
+	path string
+
+	mu      sync.Mutex
+	written map[int][]byte
+}
+
+func newServerStateStore(path string) *serverStateStore {
+	return &serverStateStore{
+		path:    path,
+		written: make(map[int][]byte),
+	}
+}
+
+func (s *serverStateStore) load() ([]domain.ServerState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var states []domain.ServerState
+	if _, err := statefile.Load(s.path, serverStateFileVersion, &states); err != nil {
+		return nil, err
+	}
+
+	for _, state := range states {
+		fp, err := stateFingerprint(state)
+		if err != nil {
+			return nil, err
+		}
+		s.written[state.ID] = fp
+	}
+
+	return states, nil
+}
+
+// saveChanged writes the states of the servers when the state of the changed
+// server differs from the written one. Only the changed server is encoded
+// otherwise, the servers are saved one by one on every status check.
+func (s *serverStateStore) saveChanged(changed *domain.Server, servers func() []*domain.Server) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	fp, err := stateFingerprint(changed.State())
+	if err != nil {
+		return err
+	}
+
+	if written, ok := s.written[changed.ID()]; ok && bytes.Equal(written, fp) {
+		return nil
+	}
+
+	return s.write(servers())
+}
+
+// save writes the states of the servers.
+func (s *serverStateStore) save(servers []*domain.Server) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.write(servers)
+}
+
+// write takes the states under the lock, so a concurrent save can't replace
+// the file with older states.
+func (s *serverStateStore) write(servers []*domain.Server) error {
+	states := make([]domain.ServerState, 0, len(servers))
+	fingerprints := make(map[int][]byte, len(servers))
+
+	for _, server := range servers {
+		state := server.State()
+
+		fp, err := stateFingerprint(state)
+		if err != nil {
+			return err
+		}
+
+		states = append(states, state)
+		fingerprints[state.ID] = fp
+	}
+
+	sort.Slice(states, func(i, j int) bool {
+		return states[i].ID < states[j].ID
+	})
+
+	if err := statefile.Save(s.path, serverStateFileVersion, states); err != nil {
+		return err
+	}
+
+	s.written = fingerprints
+
+	return nil
+}
+
+// stateFingerprint encodes the state without the timestamps that change on
+// every status check.
+func stateFingerprint(state domain.ServerState) ([]byte, error) {
+	state.LastProcessCheck = time.Time{}
+	state.UpdatedAt = time.Time{}
+
+	fp, err := json.Marshal(state)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to encode server state")
+	}
+
+	return fp, nil
+}
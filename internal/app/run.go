@@ -13,6 +13,7 @@ import (
 	"github.com/gameap/daemon/internal/app/config"
 	"github.com/gameap/daemon/internal/app/di"
 	"github.com/gameap/daemon/internal/app/domain"
+	"github.com/gameap/daemon/internal/app/metrics"
 	loggerpkg "github.com/gameap/daemon/pkg/logger"
 	log "github.com/sirupsen/logrus"
 	"github.com/urfave/cli/v2"
@@ -81,8 +82,10 @@ func Run(args []string) {
 				},
 				Action: enrollAction,
 			},
+			ctlCommand(),
 		},
 	}
+	app.Commands = append(app.Commands, supervisorCommands()...)
 
 	err := app.Run(args)
 	if err != nil {
@@ -167,6 +170,60 @@ func initialize(c *cli.Context) error {
 			"interval":  cfg.Metrics.CollectionInterval,
 			"retention": cfg.Metrics.RetentionDuration,
 		}).Info("Starting metrics collector")
+
+		if cfg.Metrics.PrometheusListen != "" {
+			exporter := metrics.NewPrometheusExporter(
+				metricsService.Buffer(),
+				cfg.Metrics.PrometheusListen,
+				cfg.Metrics.CollectionInterval,
+			)
+			group.Go(func() error { return exporter.Run(ctx) })
+		}
+	}
+
+	if cfg.Query.IsEnabled() {
+		queryService, err := container.QueryService(ctx)
+		if err != nil {
+			return err
+		}
+		group.Go(func() error { return queryService.Run(ctx) })
+		log.WithField("interval", cfg.Query.Interval).Info("Starting game server queries")
+	}
+
+	if cfg.Health.IsEnabled() {
+		healthService, err := container.HealthService(ctx)
+		if err != nil {
+			return err
+		}
+		group.Go(func() error { return healthService.Run(ctx) })
+		log.WithField("interval", cfg.Health.Interval).Info("Starting server health checks")
+	}
+
+	if cfg.Backups.Scheduled() {
+		backupService, err := container.BackupService(ctx)
+		if err != nil {
+			return err
+		}
+		group.Go(func() error { return backupService.Run(ctx) })
+		log.WithField("path", cfg.Backups.Path).Info("Starting scheduled backups")
+	}
+
+	if cfg.AdminAPI.Enabled {
+		adminAPIServer, err := container.AdminAPIServer(ctx)
+		if err != nil {
+			return err
+		}
+		group.Go(func() error { return adminAPIServer.Run(ctx) })
+		log.Info("Starting admin API")
+	}
+
+	if cfg.SFTP.Enabled {
+		sftpServer, err := container.SFTPServer(ctx)
+		if err != nil {
+			return err
+		}
+		group.Go(func() error { return sftpServer.Run(ctx) })
+		log.WithField("address", cfg.SFTP.Listen).Info("Starting SFTP server")
 	}
 
 	log.Info("Running in gRPC mode")
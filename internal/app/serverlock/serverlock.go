@@ -0,0 +1,83 @@
+// Package serverlock keeps the work on a game server done by the panel
+// tasks, the scheduled server tasks, the admin API, the backups and the health
+// check restarts apart. Exclusive work, such as a start or a stop, runs alone
+// on the server. Shared work runs side by side with other shared work.
+package serverlock
+
+import (
+	"context"
+	"sync"
+
+	"golang.org/x/sync/semaphore"
+)
+
+// maxShared bounds the shared work running on one server side by side, an
+// exclusive lock takes all of it.
+const maxShared = 1 << 16
+
+type Locks struct {
+	mu    sync.Mutex
+	locks map[int]*semaphore.Weighted
+}
+
+func New() *Locks {
+	return &Locks{
+		locks: make(map[int]*semaphore.Weighted),
+	}
+}
+
+// Lock waits until the work may run on the server and returns the function
+// releasing it.
+func (l *Locks) Lock(ctx context.Context, serverID int, exclusive bool) (func(), error) {
+	sem, n := l.semaphore(serverID, exclusive)
+
+	if err := sem.Acquire(ctx, n); err != nil {
+		return nil, err
+	}
+
+	return func() { sem.Release(n) }, nil
+}
+
+// TryLock is Lock without waiting, it reports false when the server is busy.
+func (l *Locks) TryLock(serverID int, exclusive bool) (func(), bool) {
+	sem, n := l.semaphore(serverID, exclusive)
+
+	if !sem.TryAcquire(n) {
+		return nil, false
+	}
+
+	return func() { sem.Release(n) }, true
+}
+
+func (l *Locks) semaphore(serverID int, exclusive bool) (*semaphore.Weighted, int64) {
+	l.mu.Lock()
+	sem, ok := l.locks[serverID]
+	if !ok {
+		sem = semaphore.NewWeighted(maxShared)
+		l.locks[serverID] = sem
+	}
+	l.mu.Unlock()
+
+	if exclusive {
+		return sem, maxShared
+	}
+
+	return sem, 1
+}
+
+type heldKey struct{}
+
+// WithHeld marks the server locked exclusively by the caller of ctx, so the
+// work it runs on the server, such as a pipeline backup, does not wait for the
+// lock of the caller.
+func WithHeld(ctx context.Context, serverID int) context.Context {
+	return context.WithValue(ctx, heldKey{}, serverID)
+}
+
+// Held reports whether the caller of ctx holds the server locked
+// exclusively, see WithHeld.
+func Held(ctx context.Context, serverID int) bool {
+	id, ok := ctx.Value(heldKey{}).(int)
+
+	return ok && id == serverID
+}
@@ -0,0 +1,63 @@
+package serverlock
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocks_SharedRunSideBySide(t *testing.T) {
+	locks := New()
+
+	unlock1, ok := locks.TryLock(1, false)
+	require.True(t, ok)
+	unlock2, ok := locks.TryLock(1, false)
+	require.True(t, ok)
+
+	_, ok = locks.TryLock(1, true)
+	assert.False(t, ok)
+
+	unlock1()
+	unlock2()
+
+	unlock, ok := locks.TryLock(1, true)
+	require.True(t, ok)
+	unlock()
+}
+
+func TestLocks_ExclusiveRunsAlone(t *testing.T) {
+	locks := New()
+
+	unlock, err := locks.Lock(context.Background(), 1, true)
+	require.NoError(t, err)
+
+	_, ok := locks.TryLock(1, false)
+	assert.False(t, ok)
+
+	// Other servers are not affected.
+	unlockOther, ok := locks.TryLock(2, true)
+	require.True(t, ok)
+	unlockOther()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = locks.Lock(ctx, 1, true)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	unlock()
+
+	unlock, err = locks.Lock(context.Background(), 1, false)
+	require.NoError(t, err)
+	unlock()
+}
+
+func TestHeld(t *testing.T) {
+	ctx := WithHeld(context.Background(), 1)
+
+	assert.True(t, Held(ctx, 1))
+	assert.False(t, Held(ctx, 2))
+	assert.False(t, Held(context.Background(), 1))
+}
@@ -0,0 +1,175 @@
+package serversloop
+
+import (
+	"sync"
+	"time"
+
+	"github.com/gameap/daemon/internal/app/config"
+)
+
+type startDecision int
+
+const (
+	startAllowed startDecision = iota
+	// startPostponed means the server crashed recently and the backoff delay
+	// is not over yet.
+	startPostponed
+	// startSuspended means the server was restarted too many times within
+	// the window and autostart must be suspended.
+	startSuspended
+)
+
+type crash struct {
+	uptime    time.Duration
+	count     int
+	restartIn time.Duration
+}
+
+// serverRuns is what the tracker knows about the runs of one server.
+type serverRuns struct {
+	running     bool
+	startedAt   time.Time
+	crashes     int
+	restarts    []time.Time
+	nextStartAt time.Time
+}
+
+// crashTracker follows the process state of servers between loop ticks to
+// tell crashes apart from regular stops and to rate limit autostart
+// restarts.
+type crashTracker struct {
+	cfg   config.CrashLoopConfig
+	nowFn func() time.Time
+
+	mu      sync.Mutex
+	servers map[int]*serverRuns
+}
+
+func newCrashTracker(cfg config.CrashLoopConfig) *crashTracker {
+	return &crashTracker{
+		cfg:     cfg,
+		nowFn:   time.Now,
+		servers: make(map[int]*serverRuns),
+	}
+}
+
+// observe records the process state from the latest status check. A server
+// that was running (or was just started by the loop) and is found down while
+// autostart is still enabled is reported as a crash. Stops requested by the
+// user disable autostart, so they are not counted.
+func (t *crashTracker) observe(serverID int, active, autostart bool) (crash, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.nowFn()
+	runs := t.runs(serverID)
+
+	if active {
+		if !runs.running {
+			runs.running = true
+			runs.startedAt = now
+		}
+
+		if runs.crashes > 0 && now.Sub(runs.startedAt) >= t.cfg.StableUptime {
+			runs.crashes = 0
+		}
+
+		return crash{}, false
+	}
+
+	if !runs.running {
+		return crash{}, false
+	}
+
+	runs.running = false
+
+	if !autostart {
+		return crash{}, false
+	}
+
+	uptime := now.Sub(runs.startedAt)
+	if uptime >= t.cfg.StableUptime {
+		runs.crashes = 0
+	}
+
+	runs.crashes++
+	restartIn := t.backoff(runs.crashes)
+	runs.nextStartAt = now.Add(restartIn)
+
+	return crash{
+		uptime:    uptime,
+		count:     runs.crashes,
+		restartIn: restartIn,
+	}, true
+}
+
+// allowStart decides whether the loop may start the server now. When the
+// start is allowed it is recorded as a restart and the server is considered
+// running, so a process that dies before the next check is still noticed.
+func (t *crashTracker) allowStart(serverID int) startDecision {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.nowFn()
+	runs := t.runs(serverID)
+
+	if now.Before(runs.nextStartAt) {
+		return startPostponed
+	}
+
+	cutoff := now.Add(-t.cfg.Window)
+	kept := runs.restarts[:0]
+	for _, at := range runs.restarts {
+		if at.After(cutoff) {
+			kept = append(kept, at)
+		}
+	}
+	runs.restarts = kept
+
+	if t.cfg.MaxRestarts > 0 && runs.crashes > 0 && len(runs.restarts) >= t.cfg.MaxRestarts {
+		return startSuspended
+	}
+
+	runs.restarts = append(runs.restarts, now)
+	runs.running = true
+	runs.startedAt = now
+
+	return startAllowed
+}
+
+// reset forgets the history of the server. It is called once autostart is
+// suspended, so a manual start begins with a clean record.
+func (t *crashTracker) reset(serverID int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	delete(t.servers, serverID)
+}
+
+// backoff returns the delay before restarting after the given number of
+// consecutive crashes. The first crash is restarted right away.
+func (t *crashTracker) backoff(crashes int) time.Duration {
+	if crashes <= 1 {
+		return 0
+	}
+
+	d := t.cfg.InitialBackoff
+	for i := 2; i < crashes; i++ {
+		d *= 2
+		if d >= t.cfg.MaxBackoff {
+			return t.cfg.MaxBackoff
+		}
+	}
+
+	return min(d, t.cfg.MaxBackoff)
+}
+
+func (t *crashTracker) runs(serverID int) *serverRuns {
+	runs, ok := t.servers[serverID]
+	if !ok {
+		runs = &serverRuns{}
+		t.servers[serverID] = runs
+	}
+
+	return runs
+}
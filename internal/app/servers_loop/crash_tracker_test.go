@@ -0,0 +1,128 @@
+package serversloop
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gameap/daemon/internal/app/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+func newTestCrashTracker(cfg config.CrashLoopConfig) (*crashTracker, *fakeClock) {
+	clock := &fakeClock{now: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)}
+	tracker := newCrashTracker(cfg)
+	tracker.nowFn = clock.Now
+
+	return tracker, clock
+}
+
+func testCrashLoopConfig() config.CrashLoopConfig {
+	return config.CrashLoopConfig{
+		InitialBackoff: 10 * time.Second,
+		MaxBackoff:     40 * time.Second,
+		MaxRestarts:    3,
+		Window:         10 * time.Minute,
+		StableUptime:   5 * time.Minute,
+	}
+}
+
+func TestCrashTracker_Backoff(t *testing.T) {
+	tracker, _ := newTestCrashTracker(testCrashLoopConfig())
+
+	assert.Equal(t, time.Duration(0), tracker.backoff(1))
+	assert.Equal(t, 10*time.Second, tracker.backoff(2))
+	assert.Equal(t, 20*time.Second, tracker.backoff(3))
+	assert.Equal(t, 40*time.Second, tracker.backoff(4))
+	assert.Equal(t, 40*time.Second, tracker.backoff(10))
+}
+
+func TestCrashTracker_StopWithoutAutostartIsNotCrash(t *testing.T) {
+	tracker, clock := newTestCrashTracker(testCrashLoopConfig())
+
+	_, crashed := tracker.observe(1, true, true)
+	require.False(t, crashed)
+
+	clock.Advance(time.Minute)
+	_, crashed = tracker.observe(1, false, false)
+
+	assert.False(t, crashed)
+}
+
+func TestCrashTracker_CrashesAreBackedOff(t *testing.T) {
+	tracker, clock := newTestCrashTracker(testCrashLoopConfig())
+
+	require.Equal(t, startAllowed, tracker.allowStart(1))
+
+	clock.Advance(5 * time.Second)
+	c, crashed := tracker.observe(1, false, true)
+	require.True(t, crashed)
+	assert.Equal(t, 1, c.count)
+	assert.Equal(t, 5*time.Second, c.uptime)
+	assert.Equal(t, time.Duration(0), c.restartIn)
+	require.Equal(t, startAllowed, tracker.allowStart(1))
+
+	clock.Advance(5 * time.Second)
+	c, crashed = tracker.observe(1, false, true)
+	require.True(t, crashed)
+	assert.Equal(t, 2, c.count)
+	assert.Equal(t, 10*time.Second, c.restartIn)
+	assert.Equal(t, startPostponed, tracker.allowStart(1))
+
+	clock.Advance(10 * time.Second)
+	assert.Equal(t, startAllowed, tracker.allowStart(1))
+}
+
+func TestCrashTracker_TooManyRestartsSuspend(t *testing.T) {
+	tracker, clock := newTestCrashTracker(testCrashLoopConfig())
+
+	for i := 0; i < 3; i++ {
+		require.Equal(t, startAllowed, tracker.allowStart(1))
+		clock.Advance(time.Second)
+
+		_, crashed := tracker.observe(1, false, true)
+		require.True(t, crashed)
+		clock.Advance(time.Minute)
+	}
+
+	assert.Equal(t, startSuspended, tracker.allowStart(1))
+
+	tracker.reset(1)
+	assert.Equal(t, startAllowed, tracker.allowStart(1))
+}
+
+func TestCrashTracker_StableUptimeResetsBackoff(t *testing.T) {
+	tracker, clock := newTestCrashTracker(testCrashLoopConfig())
+
+	require.Equal(t, startAllowed, tracker.allowStart(1))
+	clock.Advance(time.Second)
+	_, _ = tracker.observe(1, false, true)
+	require.Equal(t, startAllowed, tracker.allowStart(1))
+	clock.Advance(time.Second)
+	c, _ := tracker.observe(1, false, true)
+	require.Equal(t, 2, c.count)
+
+	clock.Advance(c.restartIn)
+	require.Equal(t, startAllowed, tracker.allowStart(1))
+	clock.Advance(6 * time.Minute)
+	_, crashed := tracker.observe(1, true, true)
+	require.False(t, crashed)
+
+	clock.Advance(time.Second)
+	c, crashed = tracker.observe(1, false, true)
+	require.True(t, crashed)
+	assert.Equal(t, 1, c.count)
+	assert.Equal(t, time.Duration(0), c.restartIn)
+}
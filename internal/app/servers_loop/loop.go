@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/gameap/daemon/internal/app/config"
+	"github.com/gameap/daemon/internal/app/contracts"
 	"github.com/gameap/daemon/internal/app/domain"
 	commands "github.com/gameap/daemon/internal/app/game_server_commands"
 	"github.com/gameap/daemon/pkg/logger"
@@ -34,13 +35,20 @@ type ServerStatusReporter interface {
 	Report(server *domain.Server)
 }
 
+type ReadinessChecker interface {
+	Check(ctx context.Context, server *domain.Server) domain.RunState
+}
+
 type ServersLoop struct {
 	cfg                  *config.Config
 	serverRepo           domain.ServerRepository
 	serverCommandFactory *commands.ServerCommandFactory
 	statusReporter       ServerStatusReporter
+	readinessChecker     ReadinessChecker
+	exitCodeReader       contracts.ExitCodeReader
 
 	skipCounter skipCounter
+	crashes     *crashTracker
 }
 
 func NewServersLoop(
@@ -54,6 +62,7 @@ func NewServersLoop(
 		serverCommandFactory: serverCommandFactory,
 
 		skipCounter: skipCounter{},
+		crashes:     newCrashTracker(cfg.CrashLoop),
 	}
 }
 
@@ -61,6 +70,17 @@ func (l *ServersLoop) SetStatusReporter(reporter ServerStatusReporter) {
 	l.statusReporter = reporter
 }
 
+// SetReadinessChecker enables readiness probes of started servers. Without
+// it a started server is running as soon as its process is.
+func (l *ServersLoop) SetReadinessChecker(checker ReadinessChecker) {
+	l.readinessChecker = checker
+}
+
+// SetExitCodeReader enables logging of exit codes of crashed servers.
+func (l *ServersLoop) SetExitCodeReader(reader contracts.ExitCodeReader) {
+	l.exitCodeReader = reader
+}
+
 func (l *ServersLoop) Run(ctx context.Context) error {
 	return l.loop(ctx)
 }
@@ -106,6 +126,7 @@ func (l *ServersLoop) tick(ctx context.Context) {
 
 		err = l.pipeline(ctxWithServer, server, []pipelineHandler{
 			l.checkStatus,
+			l.trackCrashes,
 			l.startIfNeeded,
 			l.save,
 		})
@@ -146,6 +167,10 @@ func (l *ServersLoop) checkStatus(ctx context.Context, server *domain.Server) er
 
 	server.SetStatus(statusCmd.Result() == commands.SuccessResult)
 
+	if server.RunState() == domain.RunStateStarting {
+		l.checkReadiness(ctx, server)
+	}
+
 	if l.statusReporter != nil {
 		l.statusReporter.Report(server)
 	}
@@ -153,12 +178,44 @@ func (l *ServersLoop) checkStatus(ctx context.Context, server *domain.Server) er
 	return nil
 }
 
+func (l *ServersLoop) checkReadiness(ctx context.Context, server *domain.Server) {
+	if l.readinessChecker == nil {
+		server.SetRunState(domain.RunStateRunning)
+		return
+	}
+
+	ctxWithTimeout, cancel := context.WithTimeout(ctx, commandTimeout)
+	defer cancel()
+
+	l.readinessChecker.Check(ctxWithTimeout, server)
+}
+
 func (l *ServersLoop) startIfNeeded(ctx context.Context, server *domain.Server) error {
 	if server.InstallationStatus() != domain.ServerInstalled {
 		return nil
 	}
 
-	if server.IsActive() || !server.AutoStart() {
+	if server.IsActive() || server.IsPaused() || !server.AutoStart() || server.IsCrashLooping() {
+		return nil
+	}
+
+	switch l.crashes.allowStart(server.ID()) {
+	case startAllowed:
+	case startPostponed:
+		return nil
+	case startSuspended:
+		l.crashes.reset(server.ID())
+		server.SetCrashLooping(true)
+
+		logger.WithFields(ctx, log.Fields{
+			"maxRestarts": l.cfg.CrashLoop.MaxRestarts,
+			"window":      l.cfg.CrashLoop.Window,
+		}).Warn("Server is crash looping, autostart is suspended until the server is started manually")
+
+		if l.statusReporter != nil {
+			l.statusReporter.Report(server)
+		}
+
 		return nil
 	}
 
@@ -177,6 +234,37 @@ func (l *ServersLoop) startIfNeeded(ctx context.Context, server *domain.Server)
 	return l.checkStatus(ctx, server)
 }
 
+func (l *ServersLoop) trackCrashes(ctx context.Context, server *domain.Server) error {
+	// A paused server is not running, but it has not crashed either.
+	if server.InstallationStatus() != domain.ServerInstalled || server.IsCrashLooping() || server.IsPaused() {
+		return nil
+	}
+
+	c, crashed := l.crashes.observe(server.ID(), server.IsActive(), server.AutoStart())
+	if !crashed {
+		return nil
+	}
+
+	fields := log.Fields{
+		"uptime":    c.uptime.Round(time.Second),
+		"crashes":   c.count,
+		"restartIn": c.restartIn,
+	}
+
+	if l.exitCodeReader != nil {
+		ctxWithTimeout, cancel := context.WithTimeout(ctx, commandTimeout)
+		defer cancel()
+
+		if code, ok := l.exitCodeReader.ExitCode(ctxWithTimeout, server); ok {
+			fields["exitCode"] = code
+		}
+	}
+
+	logger.WithFields(ctx, fields).Warn("Server process exited unexpectedly")
+
+	return nil
+}
+
 func (l *ServersLoop) save(ctx context.Context, server *domain.Server) error {
 	if server.InstallationStatus() != domain.ServerInstalled {
 		return nil
@@ -190,6 +278,11 @@ func (l *ServersLoop) canSkipped(_ context.Context, server *domain.Server) bool
 		return false
 	}
 
+	// Starting servers are probed on every tick until they are ready.
+	if server.RunState() == domain.RunStateStarting {
+		return false
+	}
+
 	if !l.skipCounter.Exists(server.ID()) {
 		l.skipCounter.Init(server.ID())
 		return false
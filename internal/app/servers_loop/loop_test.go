@@ -0,0 +1,80 @@
+package serversloop
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gameap/daemon/internal/app/config"
+	"github.com/gameap/daemon/internal/app/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type reportedStatus struct {
+	serverID     int
+	active       bool
+	crashLooping bool
+}
+
+type recordingReporter struct {
+	reported []reportedStatus
+}
+
+func (r *recordingReporter) Report(server *domain.Server) {
+	r.reported = append(r.reported, reportedStatus{
+		serverID:     server.ID(),
+		active:       server.IsActive(),
+		crashLooping: server.IsCrashLooping(),
+	})
+}
+
+func TestServersLoop_CrashLoopIsReported(t *testing.T) {
+	cfg := &config.Config{CrashLoop: testCrashLoopConfig()}
+	reporter := &recordingReporter{}
+	loop := NewServersLoop(nil, nil, cfg)
+	loop.SetStatusReporter(reporter)
+
+	tracker, clock := newTestCrashTracker(cfg.CrashLoop)
+	loop.crashes = tracker
+	for i := 0; i < cfg.CrashLoop.MaxRestarts; i++ {
+		require.Equal(t, startAllowed, tracker.allowStart(1))
+		clock.Advance(time.Second)
+		_, crashed := tracker.observe(1, false, true)
+		require.True(t, crashed)
+		clock.Advance(time.Minute)
+	}
+
+	server := givenAutostartServer(1)
+	err := loop.startIfNeeded(context.Background(), server)
+
+	require.NoError(t, err)
+	assert.True(t, server.IsCrashLooping())
+	assert.Equal(t, []reportedStatus{{serverID: 1, active: false, crashLooping: true}}, reporter.reported)
+}
+
+func givenAutostartServer(id int) *domain.Server {
+	return domain.NewServer(
+		id,
+		true,
+		domain.ServerInstalled,
+		false,
+		"test",
+		"uuid",
+		"uuid",
+		domain.Game{},
+		domain.GameMod{},
+		"127.0.0.1",
+		27015, 27016, 27020,
+		"",
+		"/tmp",
+		"gameap",
+		"start", "stop", "kill", "restart",
+		false,
+		time.Now(),
+		nil,
+		domain.Settings{"autostart": "1"},
+		time.Now(),
+		0, 0,
+	)
+}
@@ -0,0 +1,253 @@
+package serversscheduler
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"os/user"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	daemonarchive "github.com/gameap/daemon/internal/app/archive"
+	"github.com/gameap/daemon/internal/app/contracts"
+	"github.com/gameap/daemon/internal/app/domain"
+	"github.com/gameap/daemon/internal/app/fsutil"
+	"github.com/gameap/daemon/pkg/logger"
+	pb "github.com/gameap/gameap/pkg/proto"
+	"github.com/pkg/errors"
+)
+
+const defaultArchiveDestination = "archives/{datetime}.tar.gz"
+
+var errHookFailed = errors.New("shell hook failed")
+
+var archivePlaceholders = regexp.MustCompile(`\{(date|time|datetime)\}`)
+
+// archiveSpec is what an archive step archives. The paths and the
+// destination are relative to the server directory.
+type archiveSpec struct {
+	Paths []string `json:"paths"`
+
+	// Destination is the path of the archive, {date}, {time} and {datetime}
+	// are replaced with the UTC time the archive is created at. The archive
+	// format follows the extension.
+	Destination string `json:"destination"`
+
+	// Keep is how many archives matching the destination are kept, the
+	// oldest are removed. All of them are kept when it is not set.
+	Keep int `json:"keep"`
+}
+
+func (a archiveSpec) destinationTemplate() string {
+	if a.Destination == "" {
+		return defaultArchiveDestination
+	}
+
+	return a.Destination
+}
+
+func (a archiveSpec) validate() error {
+	if len(a.Paths) == 0 {
+		return errors.Wrap(errInvalidStep, "archive step without paths")
+	}
+
+	if a.Keep < 0 {
+		return errors.Wrap(errInvalidStep, "negative archive keep")
+	}
+
+	destination := a.destinationTemplate()
+	if daemonarchive.FormatFromExtension(destination) == pb.ArchiveFormat_ARCHIVE_FORMAT_UNSPECIFIED {
+		return errors.Wrapf(errInvalidStep, "unknown archive format of %q", destination)
+	}
+
+	return nil
+}
+
+// runAction runs the console command, shell hook or archive of a task
+// payload, a pipeline of one step without the step header.
+func (s *Scheduler) runAction(ctx context.Context, server *domain.Server, step pipelineStep) ([]byte, error) {
+	p := &pipeline{s: s, server: server, wasActive: server.IsActive()}
+
+	err := p.run(ctx, step)
+
+	return p.out.Bytes(), err
+}
+
+// runHook runs the command line in the server directory as the server user.
+// The short codes of the server such as {dir} and {id} are replaced.
+func (p *pipeline) runHook(ctx context.Context, hook string) error {
+	executor := p.s.pipelineServices.Executor
+	if executor == nil {
+		return errors.Wrap(errNotAvailable, "executor")
+	}
+
+	options, err := p.hookOptions()
+	if err != nil {
+		return err
+	}
+
+	command := domain.ReplaceShortCodes(hook, p.s.cfg, p.server)
+
+	code, err := executor.ExecWithWriter(ctx, command, &p.out, options)
+	if err != nil {
+		return err
+	}
+
+	if code != 0 {
+		return errors.Wrapf(errHookFailed, "exit code %d", code)
+	}
+
+	return nil
+}
+
+func (p *pipeline) hookOptions() (contracts.ExecutorOptions, error) {
+	options := contracts.ExecutorOptions{
+		WorkDir: p.server.WorkDir(p.s.cfg),
+	}
+
+	if os.Geteuid() == 0 && p.server.User() != "" {
+		systemUser, err := user.Lookup(p.server.User())
+		if err != nil {
+			return options, errors.Wrap(err, "failed to lookup user")
+		}
+
+		options.UID = systemUser.Uid
+		options.GID = systemUser.Gid
+	}
+
+	return options, nil
+}
+
+// makeArchive archives the paths of the server directory and removes the
+// archives beyond the ones to keep.
+func (p *pipeline) makeArchive(ctx context.Context, spec archiveSpec) error {
+	workDir := p.server.WorkDir(p.s.cfg)
+	destination := archiveName(spec.destinationTemplate(), p.s.now())
+
+	params := &pb.CreateArchiveParams{
+		ArchivePath: destination,
+		BasePath:    ".",
+		Sources:     spec.Paths,
+		Overwrite:   true,
+		// Server directories are not bounded by the limits meant for
+		// panel requests.
+		MaxTotalBytes: math.MaxUint64,
+		MaxFiles:      math.MaxUint32,
+	}
+	if os.Geteuid() == 0 {
+		params.OwnerUser = p.server.User()
+	}
+
+	result, err := daemonarchive.Create(ctx, workDir, params, nil)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(&p.out, "Archive %s created, %d files, %d bytes\n",
+		destination, result.FilesProcessed, result.ArchiveSize)
+
+	if spec.Keep == 0 {
+		return nil
+	}
+
+	removed, err := pruneArchives(workDir, spec.destinationTemplate(), spec.Keep)
+	for _, name := range removed {
+		fmt.Fprintf(&p.out, "Archive %s removed\n", name)
+	}
+	if err != nil {
+		// The archive is created, the old ones are removed next time.
+		logger.Logger(ctx).WithError(err).Warn("Failed to remove old archives")
+	}
+
+	return nil
+}
+
+// archiveName replaces the time placeholders of the destination.
+func archiveName(destination string, t time.Time) string {
+	t = t.UTC()
+
+	return strings.NewReplacer(
+		"{date}", t.Format("2006-01-02"),
+		"{time}", t.Format("15-04-05"),
+		"{datetime}", t.Format("2006-01-02_15-04-05"),
+	).Replace(destination)
+}
+
+// archivePattern returns the glob matching every name of the destination.
+func archivePattern(destination string) string {
+	var b strings.Builder
+
+	last := 0
+	for _, loc := range archivePlaceholders.FindAllStringIndex(destination, -1) {
+		b.WriteString(globEscape(destination[last:loc[0]]))
+		b.WriteString("*")
+		last = loc[1]
+	}
+	b.WriteString(globEscape(destination[last:]))
+
+	return b.String()
+}
+
+func globEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `*`, `\*`, `?`, `\?`, `[`, `\[`).Replace(s)
+}
+
+// pruneArchives removes the archives of the destination beyond the newest
+// keep ones and returns the removed names.
+func pruneArchives(workDir, destination string, keep int) ([]string, error) {
+	rel, err := fsutil.RootRel(destination)
+	if err != nil {
+		return nil, err
+	}
+
+	root, err := os.OpenRoot(workDir)
+	if err != nil {
+		return nil, errors.Wrap(err, "work directory unavailable")
+	}
+	defer root.Close()
+
+	matches, err := fs.Glob(root.FS(), archivePattern(rel))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list archives")
+	}
+	if len(matches) <= keep {
+		return nil, nil
+	}
+
+	type archiveFile struct {
+		name    string
+		modTime time.Time
+	}
+
+	files := make([]archiveFile, 0, len(matches))
+	for _, name := range matches {
+		info, err := root.Stat(name)
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		files = append(files, archiveFile{name: name, modTime: info.ModTime()})
+	}
+
+	// Newest first, the names break ties of archives made the same second.
+	slices.SortFunc(files, func(a, b archiveFile) int {
+		if c := b.modTime.Compare(a.modTime); c != 0 {
+			return c
+		}
+
+		return strings.Compare(b.name, a.name)
+	})
+
+	var removed []string
+	for _, file := range files[min(keep, len(files)):] {
+		if err := root.Remove(file.name); err != nil {
+			return removed, errors.Wrapf(err, "failed to remove archive %q", file.name)
+		}
+		removed = append(removed, file.name)
+	}
+
+	return removed, nil
+}
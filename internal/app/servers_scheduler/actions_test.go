@@ -0,0 +1,121 @@
+package serversscheduler
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gameap/daemon/internal/app/config"
+	"github.com/gameap/daemon/internal/app/contracts"
+	pb "github.com/gameap/gameap/pkg/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recordingExecutor struct {
+	contracts.Executor
+
+	command string
+	options contracts.ExecutorOptions
+	code    int
+}
+
+func (e *recordingExecutor) ExecWithWriter(
+	_ context.Context, command string, out io.Writer, options contracts.ExecutorOptions,
+) (int, error) {
+	e.command = command
+	e.options = options
+	_, _ = io.WriteString(out, "hook done\n")
+
+	return e.code, nil
+}
+
+func TestAction_ConsoleCommand(t *testing.T) {
+	server := givenRunningServer()
+	scheduler, sender, rec, _ := givenPipelineScheduler(t, server, nil, `{"console_command": "save-all"}`)
+
+	scheduler.tick(context.Background())
+	waitForFinished(t, sender, 1)
+
+	finished := sender.Finished()
+	require.Len(t, finished, 1)
+	assert.Equal(t, pb.ServerTaskExecutionStatus_SERVER_TASK_EXECUTION_STATUS_SUCCESS, finished[0].Status)
+	assert.Equal(t, []string{"console: save-all"}, rec.Calls())
+	assert.Equal(t, "reply to save-all\n", string(finished[0].OutputInline))
+}
+
+func TestAction_ShellHook(t *testing.T) {
+	tests := []struct {
+		name   string
+		code   int
+		status pb.ServerTaskExecutionStatus
+		errMsg string
+	}{
+		{"success", 0, pb.ServerTaskExecutionStatus_SERVER_TASK_EXECUTION_STATUS_SUCCESS, ""},
+		{"failure", 3, pb.ServerTaskExecutionStatus_SERVER_TASK_EXECUTION_STATUS_FAILED, "exit code 3: shell hook failed"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := givenRunningServer()
+			scheduler, sender, _, _ := givenPipelineScheduler(t, server, nil, `{"shell_hook": "./rotate.sh {id}"}`)
+			scheduler.cfg = &config.Config{WorkPath: "/srv"}
+			executor := &recordingExecutor{code: test.code}
+			scheduler.pipelineServices.Executor = executor
+
+			scheduler.tick(context.Background())
+			waitForFinished(t, sender, 1)
+
+			finished := sender.Finished()
+			require.Len(t, finished, 1)
+			assert.Equal(t, test.status, finished[0].Status)
+			assert.Equal(t, test.errMsg, finished[0].ErrorMessage)
+			assert.Equal(t, "./rotate.sh 42", executor.command)
+			assert.Equal(t, filepath.Clean("/srv/srv/test/42"), executor.options.WorkDir)
+			assert.Equal(t, "hook done\n", string(finished[0].OutputInline))
+		})
+	}
+}
+
+func TestAction_Archive(t *testing.T) {
+	workPath := t.TempDir()
+	serverDir := filepath.Join(workPath, "srv", "test", "42")
+	require.NoError(t, os.MkdirAll(filepath.Join(serverDir, "world"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(serverDir, "world", "level.dat"), []byte("level"), 0o600))
+
+	server := givenRunningServer()
+	scheduler, sender, _, _ := givenPipelineScheduler(t, server, nil, `{"archive": {
+		"paths": ["world"], "destination": "archives/world-{datetime}.tar.gz", "keep": 1
+	}}`)
+	scheduler.cfg = &config.Config{WorkPath: workPath}
+	require.NoError(t, os.MkdirAll(filepath.Join(serverDir, "archives"), 0o755))
+	old := filepath.Join(serverDir, "archives", "world-2026-05-11_12-00-00.tar.gz")
+	require.NoError(t, os.WriteFile(old, []byte("old"), 0o600))
+	require.NoError(t, os.Chtimes(old, time.Now().Add(-time.Hour), time.Now().Add(-time.Hour)))
+
+	scheduler.tick(context.Background())
+	waitForFinished(t, sender, 1)
+
+	finished := sender.Finished()
+	require.Len(t, finished, 1)
+	assert.Equal(t, pb.ServerTaskExecutionStatus_SERVER_TASK_EXECUTION_STATUS_SUCCESS, finished[0].Status)
+	assert.FileExists(t, filepath.Join(serverDir, "archives", "world-2026-05-12_12-00-00.tar.gz"))
+	assert.NoFileExists(t, old)
+	assert.Contains(t, string(finished[0].OutputInline), "Archive archives/world-2026-05-11_12-00-00.tar.gz removed\n")
+}
+
+func TestArchiveName(t *testing.T) {
+	at := time.Date(2026, 5, 12, 3, 4, 5, 0, time.UTC)
+
+	name := archiveName("archives/{date}/world-{time}-{datetime}.zip", at)
+
+	assert.Equal(t, "archives/2026-05-12/world-03-04-05-2026-05-12_03-04-05.zip", name)
+}
+
+func TestArchivePattern(t *testing.T) {
+	assert.Equal(t, "archives/*/world-*.zip", archivePattern("archives/{date}/world-{time}.zip"))
+	assert.Equal(t, `backup\[1\]-*.tar`, archivePattern("backup[1]-{datetime}.tar"))
+}
@@ -0,0 +1,237 @@
+package serversscheduler
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/gameap/daemon/internal/app/domain"
+	"github.com/gameap/daemon/internal/app/query"
+	"github.com/gameap/daemon/pkg/logger"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// defaultRetryInterval is how often deferred conditions are checked
+	// again when the task sets no retry interval.
+	defaultRetryInterval = time.Minute
+
+	minRetryInterval = time.Second
+
+	// metricMemoryUsage is the process manager metric of the server memory.
+	metricMemoryUsage = "gameap_server_memory_usage_bytes"
+)
+
+// taskConditions are the preconditions of a task command, checked when the
+// task fires. Every set condition has to be met.
+type taskConditions struct {
+	// NoPlayers requires no players on the server, bots are not counted.
+	NoPlayers bool `json:"no_players"`
+
+	// UpdateAvailable requires a newer build of the game on Steam.
+	UpdateAvailable bool `json:"update_available"`
+
+	// MinUptime requires the server to be running at least this long.
+	MinUptime duration `json:"min_uptime"`
+
+	// MinMemoryMB requires the server to use more memory, in MiB.
+	MinMemoryMB float64 `json:"min_memory_mb"`
+
+	// Defer checks unmet conditions again every RetryInterval for at most
+	// this long after the task fired, instead of skipping the run right away.
+	Defer         duration `json:"defer"`
+	RetryInterval duration `json:"retry_interval"`
+}
+
+func (c *taskConditions) retryInterval() time.Duration {
+	interval := time.Duration(c.RetryInterval)
+	if interval <= 0 {
+		return defaultRetryInterval
+	}
+
+	return max(interval, minRetryInterval)
+}
+
+// PlayersQuerier queries a server for its players. Implemented by
+// *query.Service.
+type PlayersQuerier interface {
+	Query(ctx context.Context, server *domain.Server) (*query.Result, error)
+}
+
+// UpdateChecker reports whether a newer build of the server game is
+// available. Implemented by *steam.UpdateChecker.
+type UpdateChecker interface {
+	UpdateAvailable(ctx context.Context, server *domain.Server) (bool, error)
+}
+
+// MetricsReader reads the resource usage of a server. Implemented by the
+// process manager.
+type MetricsReader interface {
+	Metrics(ctx context.Context, server *domain.Server) ([]domain.Metric, error)
+}
+
+// ConditionSources are what task conditions are checked with. A condition
+// without its source is never met.
+type ConditionSources struct {
+	Players PlayersQuerier
+	Updates UpdateChecker
+	Metrics MetricsReader
+}
+
+// SetConditionSources enables the task conditions that need them.
+func (s *Scheduler) SetConditionSources(sources ConditionSources) {
+	s.conditionSources = sources
+}
+
+// awaitConditions returns why the conditions are not met, an empty reason
+// when they are. Deferred conditions are checked until they are met or the
+// deadline passes. The error is set when ctx is done while waiting.
+func (s *Scheduler) awaitConditions(
+	ctx context.Context, rec *executionRecord, conds *taskConditions, server *domain.Server,
+) (string, error) {
+	deadline := rec.startedAt.Add(time.Duration(conds.Defer))
+
+	for {
+		reason := s.unmetCondition(ctx, conds, server)
+		if reason == "" {
+			return "", nil
+		}
+
+		wait := conds.retryInterval()
+		if conds.Defer <= 0 {
+			return "condition not met: " + reason, nil
+		}
+		if s.now().Add(wait).After(deadline) {
+			return "condition not met until the deadline: " + reason, nil
+		}
+
+		logger.Logger(ctx).WithFields(log.Fields{
+			"task_id": rec.taskID,
+			"reason":  reason,
+			"retry":   wait,
+		}).Debug("Server task deferred")
+
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+// unmetCondition returns the reason of the first unmet condition.
+func (s *Scheduler) unmetCondition(ctx context.Context, conds *taskConditions, server *domain.Server) string {
+	if conds.NoPlayers {
+		if reason := s.checkNoPlayers(ctx, server); reason != "" {
+			return reason
+		}
+	}
+
+	if conds.MinUptime > 0 {
+		if reason := s.checkUptime(server, time.Duration(conds.MinUptime)); reason != "" {
+			return reason
+		}
+	}
+
+	if conds.MinMemoryMB > 0 {
+		if reason := s.checkMemory(ctx, server, conds.MinMemoryMB); reason != "" {
+			return reason
+		}
+	}
+
+	if conds.UpdateAvailable {
+		if reason := s.checkUpdate(ctx, server); reason != "" {
+			return reason
+		}
+	}
+
+	return ""
+}
+
+func (s *Scheduler) checkNoPlayers(ctx context.Context, server *domain.Server) string {
+	// A stopped server has no players.
+	if !server.IsReady() {
+		return ""
+	}
+
+	if s.conditionSources.Players == nil {
+		return "players are not known"
+	}
+
+	result, err := s.conditionSources.Players.Query(ctx, server)
+	if err != nil {
+		return "players are not known: " + err.Error()
+	}
+
+	if players := result.PlayersOnline - result.Bots; players > 0 {
+		return fmt.Sprintf("%d players online", players)
+	}
+
+	return ""
+}
+
+func (s *Scheduler) checkUptime(server *domain.Server, minUptime time.Duration) string {
+	if !server.IsReady() {
+		return "server is not running"
+	}
+
+	// A server running since before the daemon started is counted from the
+	// daemon start.
+	since := server.RunStateChangedAt()
+	if since.Before(domain.StartTime) {
+		since = domain.StartTime
+	}
+
+	if uptime := s.now().Sub(since); uptime < minUptime {
+		return fmt.Sprintf("uptime %s, less than %s", uptime.Round(time.Second), minUptime)
+	}
+
+	return ""
+}
+
+func (s *Scheduler) checkMemory(ctx context.Context, server *domain.Server, minMemoryMB float64) string {
+	if !server.IsReady() {
+		return "server is not running"
+	}
+
+	if s.conditionSources.Metrics == nil {
+		return "memory usage is not known"
+	}
+
+	metrics, err := s.conditionSources.Metrics.Metrics(ctx, server)
+	if err != nil {
+		return "memory usage is not known: " + err.Error()
+	}
+
+	for _, m := range metrics {
+		if m.Name != metricMemoryUsage {
+			continue
+		}
+
+		usedMB := m.Value.AsFloat64() / (1 << 20)
+		if usedMB <= minMemoryMB {
+			return fmt.Sprintf("memory usage %.0f MiB, at most %.0f MiB", usedMB, minMemoryMB)
+		}
+
+		return ""
+	}
+
+	return "memory usage is not known"
+}
+
+func (s *Scheduler) checkUpdate(ctx context.Context, server *domain.Server) string {
+	if s.conditionSources.Updates == nil {
+		return "updates are not checked"
+	}
+
+	available, err := s.conditionSources.Updates.UpdateAvailable(ctx, server)
+	if err != nil {
+		return "update check failed: " + err.Error()
+	}
+
+	if !available {
+		return "no update available"
+	}
+
+	return ""
+}
@@ -4,8 +4,10 @@ import (
 	"context"
 	"time"
 
+	"github.com/gameap/daemon/internal/app/contracts"
 	"github.com/gameap/daemon/internal/app/domain"
 	gameservercommands "github.com/gameap/daemon/internal/app/game_server_commands"
+	"github.com/gameap/daemon/internal/app/serverlock"
 	"github.com/gameap/daemon/pkg/logger"
 	pb "github.com/gameap/gameap/pkg/proto"
 	log "github.com/sirupsen/logrus"
@@ -25,24 +27,91 @@ func (s *Scheduler) executeNow(parent context.Context, rec *executionRecord, ser
 
 	s.sendStarted(rec)
 
-	domainCmd, ok := mapProtoCommandToDomain(rec.command)
-	if !ok {
+	payload, err := parsePayload(rec.payload)
+	if err == nil {
+		err = payload.validate()
+	}
+	if err != nil {
 		s.sendFinished(rec,
 			pb.ServerTaskExecutionStatus_SERVER_TASK_EXECUTION_STATUS_FAILED,
-			"unknown server task command", nil, s.now())
+			err.Error(), nil, s.now())
 		return
 	}
 
-	cmd := s.commandLoader.LoadServerCommand(domainCmd, server)
-	if cmd == nil {
+	// A pipeline runs its steps and an action runs its step instead of the
+	// task command.
+	isPipeline := len(payload.Steps) > 0
+	action, isAction := payload.action()
+
+	var cmd contracts.GameServerCommand
+	if !isPipeline && !isAction {
+		domainCmd, ok := mapProtoCommandToDomain(rec.command)
+		if !ok {
+			s.sendFinished(rec,
+				pb.ServerTaskExecutionStatus_SERVER_TASK_EXECUTION_STATUS_FAILED,
+				"unknown server task command", nil, s.now())
+			return
+		}
+
+		cmd = s.commandLoader.LoadServerCommand(domainCmd, server)
+		if cmd == nil {
+			s.sendFinished(rec,
+				pb.ServerTaskExecutionStatus_SERVER_TASK_EXECUTION_STATUS_FAILED,
+				errNoServerCommand.Error(), nil, s.now())
+			return
+		}
+	}
+
+	if payload.Conditions != nil {
+		reason, err := s.awaitConditions(ctx, rec, payload.Conditions, server)
+		if err != nil {
+			s.sendFinished(rec,
+				pb.ServerTaskExecutionStatus_SERVER_TASK_EXECUTION_STATUS_CANCELED,
+				"", nil, s.now())
+			return
+		}
+		if reason != "" {
+			logger.Logger(parent).WithFields(log.Fields{
+				"task_id": rec.taskID,
+				"reason":  reason,
+			}).Info("Server task skipped")
+			s.sendFinishedSkipped(rec, reason)
+			return
+		}
+	}
+
+	unlock, err := s.lockServer(ctx, rec.serverID, isPipeline)
+	if err != nil {
 		s.sendFinished(rec,
-			pb.ServerTaskExecutionStatus_SERVER_TASK_EXECUTION_STATUS_FAILED,
-			"no server command implementation", nil, s.now())
+			pb.ServerTaskExecutionStatus_SERVER_TASK_EXECUTION_STATUS_CANCELED,
+			"", nil, s.now())
 		return
 	}
+	defer unlock()
+
+	if isPipeline {
+		// The backup steps run under the lock of the pipeline.
+		ctx = serverlock.WithHeld(ctx, int(rec.serverID))
+	}
 
-	err := cmd.Execute(ctx, server)
-	output := cmd.ReadOutput()
+	var (
+		output []byte
+		failed bool
+	)
+
+	switch {
+	case isPipeline:
+		output, err = s.runPipeline(ctx, server, payload.Steps)
+	case isAction:
+		output, err = s.runAction(ctx, server, action)
+	default:
+		err = cmd.Execute(ctx, server)
+		if err == nil && s.readiness != nil && server.RunState() == domain.RunStateStarting {
+			err = s.readiness.WaitReady(ctx, server)
+		}
+		output = cmd.ReadOutput()
+		failed = cmd.Result() == gameservercommands.ErrorResult
+	}
 	finishedAt := s.now()
 
 	status := pb.ServerTaskExecutionStatus_SERVER_TASK_EXECUTION_STATUS_SUCCESS
@@ -56,7 +125,7 @@ func (s *Scheduler) executeNow(parent context.Context, rec *executionRecord, ser
 		errMsg = truncateError(err.Error())
 		logger.Logger(parent).WithError(err).WithField("task_id", rec.taskID).
 			Warn("Server task command failed")
-	case cmd.Result() == gameservercommands.ErrorResult:
+	case failed:
 		status = pb.ServerTaskExecutionStatus_SERVER_TASK_EXECUTION_STATUS_FAILED
 	}
 
@@ -83,11 +152,11 @@ func (s *Scheduler) sendStarted(rec *executionRecord) {
 	})
 }
 
-func (s *Scheduler) sendFinishedSkipped(rec *executionRecord) {
+func (s *Scheduler) sendFinishedSkipped(rec *executionRecord, reason string) {
 	now := s.now()
 	s.sendFinished(rec,
 		pb.ServerTaskExecutionStatus_SERVER_TASK_EXECUTION_STATUS_SKIPPED,
-		"overlap policy SKIP: previous execution still running", nil, now)
+		reason, nil, now)
 }
 
 func (s *Scheduler) sendFinished(
@@ -0,0 +1,185 @@
+package serversscheduler
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gameap/daemon/internal/app/domain"
+	"github.com/gameap/daemon/pkg/cron"
+	pb "github.com/gameap/gameap/pkg/proto"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+var errInvalidPayload = errors.New("invalid task payload")
+
+// taskPayload is the part of the task payload the scheduler understands. The
+// payload is a JSON object, or plain text which is the console command.
+type taskPayload struct {
+	// Schedule is a cron schedule evaluated in the task timezone, it takes
+	// the place of the repeat period.
+	Schedule string `json:"schedule"`
+
+	// Conditions have to be met for the task command to run.
+	Conditions *taskConditions `json:"conditions"`
+
+	// Steps make the task a pipeline, they run in order instead of the task
+	// command.
+	Steps []pipelineStep `json:"steps"`
+
+	// ConsoleCommand, ShellHook and Archive run instead of the task command,
+	// the panel has no task command for them. At most one of them and the
+	// steps is set.
+	ConsoleCommand string       `json:"console_command"`
+	ShellHook      string       `json:"shell_hook"`
+	Archive        *archiveSpec `json:"archive"`
+
+	// Timeout of the console command, shell hook or archive, none when it
+	// is not set.
+	Timeout duration `json:"timeout"`
+}
+
+func parsePayload(payload string) (taskPayload, error) {
+	var p taskPayload
+
+	payload = strings.TrimSpace(payload)
+	if !strings.HasPrefix(payload, "{") {
+		p.ConsoleCommand = payload
+
+		return p, nil
+	}
+
+	if err := json.Unmarshal([]byte(payload), &p); err != nil {
+		return taskPayload{}, errors.Wrap(errInvalidPayload, err.Error())
+	}
+
+	return p, nil
+}
+
+// action returns the console command, shell hook or archive of the payload
+// as a pipeline step.
+func (p taskPayload) action() (pipelineStep, bool) {
+	step := pipelineStep{Timeout: p.Timeout}
+
+	switch {
+	case p.ConsoleCommand != "":
+		step.Type = stepCommand
+		step.Command = p.ConsoleCommand
+	case p.ShellHook != "":
+		step.Type = stepShellHook
+		step.Hook = p.ShellHook
+	case p.Archive != nil:
+		step.Type = stepArchive
+		step.archiveSpec = *p.Archive
+	default:
+		return pipelineStep{}, false
+	}
+
+	return step, true
+}
+
+// command returns the task command of the payload action, empty for a
+// payload without one.
+func (p taskPayload) command() domain.ServerTaskCommand {
+	step, ok := p.action()
+	if !ok {
+		return ""
+	}
+
+	switch step.Type {
+	case stepCommand:
+		return domain.ServerTaskConsoleCommand
+	case stepShellHook:
+		return domain.ServerTaskShellHook
+	default:
+		return domain.ServerTaskArchive
+	}
+}
+
+func (p taskPayload) validate() error {
+	set := 0
+	for _, ok := range []bool{p.ConsoleCommand != "", p.ShellHook != "", p.Archive != nil, len(p.Steps) > 0} {
+		if ok {
+			set++
+		}
+	}
+	if set > 1 {
+		return errors.Wrap(errInvalidPayload, "console_command, shell_hook, archive and steps exclude each other")
+	}
+
+	if step, ok := p.action(); ok {
+		return step.validate()
+	}
+
+	return validateSteps(p.Steps)
+}
+
+// duration is a duration in a task payload, either a string such as "6h" or
+// a number of seconds.
+type duration time.Duration
+
+func (d *duration) UnmarshalJSON(b []byte) error {
+	if s, err := strconv.Unquote(string(b)); err == nil {
+		parsed, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		*d = duration(parsed)
+
+		return nil
+	}
+
+	seconds, err := strconv.ParseFloat(string(b), 64)
+	if err != nil {
+		return errors.Wrapf(errInvalidPayload, "invalid duration %s", b)
+	}
+	*d = duration(seconds * float64(time.Second))
+
+	return nil
+}
+
+// scheduleFromProto returns the cron schedule of the task, nil for a task
+// repeated every period. A task with an invalid schedule falls back to the
+// repeat period.
+func scheduleFromProto(t *pb.ServerTask) *cron.Schedule {
+	logger := log.WithField("task_id", t.GetId())
+
+	if tz := t.GetTimezone(); tz != "" {
+		if _, err := time.LoadLocation(tz); err != nil {
+			logger.WithError(err).Warn("Unknown server task timezone, UTC is used")
+		}
+	}
+
+	payload, err := parsePayload(t.GetPayload())
+	if err != nil {
+		logger.WithError(err).Warn("Invalid server task payload")
+		return nil
+	}
+
+	spec := payload.Schedule
+	if spec == "" {
+		return nil
+	}
+
+	schedule, err := cron.Parse(spec)
+	if err != nil {
+		logger.WithError(err).Warn("Invalid server task schedule, the repeat period is used")
+		return nil
+	}
+
+	return schedule
+}
+
+// taskCommandFromProto returns the task command, the one of the payload
+// action when the payload has an action.
+func taskCommandFromProto(t *pb.ServerTask) domain.ServerTaskCommand {
+	if payload, err := parsePayload(t.GetPayload()); err == nil {
+		if command := payload.command(); command != "" {
+			return command
+		}
+	}
+
+	return mapProtoCommandToTaskCommand(t.GetCommand())
+}
@@ -0,0 +1,110 @@
+package serversscheduler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gameap/daemon/internal/app/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePayload(t *testing.T) {
+	payload, err := parsePayload(`{
+		"schedule": "@daily",
+		"conditions": {"no_players": true, "min_uptime": "6h", "defer": 1800, "min_memory_mb": 4096}
+	}`)
+
+	require.NoError(t, err)
+	assert.Equal(t, "@daily", payload.Schedule)
+	require.NotNil(t, payload.Conditions)
+	assert.True(t, payload.Conditions.NoPlayers)
+	assert.Equal(t, duration(6*time.Hour), payload.Conditions.MinUptime)
+	assert.Equal(t, duration(30*time.Minute), payload.Conditions.Defer)
+	assert.Equal(t, defaultRetryInterval, payload.Conditions.retryInterval())
+	assert.InDelta(t, 4096.0, payload.Conditions.MinMemoryMB, 0)
+}
+
+func TestParsePayload_NotJSON(t *testing.T) {
+	payload, err := parsePayload("say Restart in 5 minutes")
+
+	require.NoError(t, err)
+	assert.Empty(t, payload.Schedule)
+	assert.Nil(t, payload.Conditions)
+	assert.Equal(t, "say Restart in 5 minutes", payload.ConsoleCommand)
+	assert.Equal(t, domain.ServerTaskConsoleCommand, payload.command())
+}
+
+func TestParsePayload_Empty(t *testing.T) {
+	payload, err := parsePayload("  ")
+
+	require.NoError(t, err)
+	assert.Empty(t, payload.command())
+}
+
+func TestParsePayload_Invalid(t *testing.T) {
+	for _, payload := range []string{
+		`{"schedule": `,
+		`{"conditions": {"min_uptime": "six hours"}}`,
+		`{"conditions": {"defer": true}}`,
+	} {
+		_, err := parsePayload(payload)
+
+		require.ErrorIs(t, err, errInvalidPayload, payload)
+	}
+}
+
+func TestParsePayload_Action(t *testing.T) {
+	tests := []struct {
+		payload string
+		step    pipelineStep
+		command domain.ServerTaskCommand
+	}{
+		{
+			`{"console_command": "save-all"}`,
+			pipelineStep{Type: stepCommand, Command: "save-all"},
+			domain.ServerTaskConsoleCommand,
+		},
+		{
+			`{"shell_hook": "./hooks/rotate.sh {id}", "timeout": "5m"}`,
+			pipelineStep{Type: stepShellHook, Hook: "./hooks/rotate.sh {id}", Timeout: duration(5 * time.Minute)},
+			domain.ServerTaskShellHook,
+		},
+		{
+			`{"archive": {"paths": ["world"], "destination": "archives/world-{date}.zip", "keep": 7}}`,
+			pipelineStep{Type: stepArchive, archiveSpec: archiveSpec{
+				Paths: []string{"world"}, Destination: "archives/world-{date}.zip", Keep: 7,
+			}},
+			domain.ServerTaskArchive,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(string(test.command), func(t *testing.T) {
+			payload, err := parsePayload(test.payload)
+			require.NoError(t, err)
+			require.NoError(t, payload.validate())
+
+			step, ok := payload.action()
+
+			require.True(t, ok)
+			assert.Equal(t, test.step, step)
+			assert.Equal(t, test.command, payload.command())
+		})
+	}
+}
+
+func TestTaskPayload_Validate_Invalid(t *testing.T) {
+	for _, payload := range []string{
+		`{"console_command": "save-all", "shell_hook": "./save.sh"}`,
+		`{"archive": {"paths": ["world"]}, "steps": [{"type": "stop"}]}`,
+		`{"archive": {"paths": []}}`,
+		`{"archive": {"paths": ["world"], "destination": "world.bin"}}`,
+		`{"steps": [{"type": "shell_hook"}]}`,
+	} {
+		p, err := parsePayload(payload)
+		require.NoError(t, err, payload)
+
+		assert.Error(t, p.validate(), payload)
+	}
+}
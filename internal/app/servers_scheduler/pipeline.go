@@ -0,0 +1,343 @@
+package serversscheduler
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/gameap/daemon/internal/app/backups"
+	"github.com/gameap/daemon/internal/app/contracts"
+	"github.com/gameap/daemon/internal/app/domain"
+	gameservercommands "github.com/gameap/daemon/internal/app/game_server_commands"
+	"github.com/pkg/errors"
+)
+
+// Pipeline step types.
+const (
+	stepCommand   = "command"
+	stepWait      = "wait"
+	stepStop      = "stop"
+	stepStart     = "start"
+	stepBackup    = "backup"
+	stepUpdate    = "update"
+	stepWaitReady = "wait_ready"
+	stepShellHook = "shell_hook"
+	stepArchive   = "archive"
+)
+
+// What a pipeline does when a step fails.
+const (
+	onFailureAbort    = "abort"
+	onFailureContinue = "continue"
+	onFailureRollback = "rollback"
+)
+
+var (
+	errInvalidStep     = errors.New("invalid pipeline step")
+	errStepTimeout     = errors.New("step timed out")
+	errCommandFailed   = errors.New("server command failed")
+	errNotAvailable    = errors.New("not available")
+	errNoServerCommand = errors.New("no server command implementation")
+)
+
+// pipelineStep is one step of a pipeline task, the steps of the task payload
+// run in order instead of the task command.
+type pipelineStep struct {
+	Type string `json:"type"`
+
+	// Command is the console command of a command step.
+	Command string `json:"command"`
+
+	// Hook is the command line of a shell_hook step.
+	Hook string `json:"hook"`
+
+	// The paths, destination and keep of an archive step.
+	archiveSpec
+
+	// Duration of a wait step.
+	Duration duration `json:"duration"`
+
+	// Timeout of the step, none when it is not set.
+	Timeout duration `json:"timeout"`
+
+	// OnFailure is abort (the default), continue or rollback.
+	OnFailure string `json:"on_failure"`
+}
+
+func (step pipelineStep) String() string {
+	switch step.Type {
+	case stepCommand:
+		return step.Type + " " + step.Command
+	case stepShellHook:
+		return step.Type + " " + step.Hook
+	case stepArchive:
+		return step.Type + " " + step.destinationTemplate()
+	case stepWait:
+		return step.Type + " " + time.Duration(step.Duration).String()
+	default:
+		return step.Type
+	}
+}
+
+func (step pipelineStep) validate() error {
+	switch step.Type {
+	case stepCommand:
+		if step.Command == "" {
+			return errors.Wrap(errInvalidStep, "command step without command")
+		}
+	case stepWait:
+		if step.Duration <= 0 {
+			return errors.Wrap(errInvalidStep, "wait step without duration")
+		}
+	case stepShellHook:
+		if step.Hook == "" {
+			return errors.Wrap(errInvalidStep, "shell_hook step without hook")
+		}
+	case stepArchive:
+		if err := step.archiveSpec.validate(); err != nil {
+			return err
+		}
+	case stepStop, stepStart, stepBackup, stepUpdate, stepWaitReady:
+	default:
+		return errors.Wrapf(errInvalidStep, "unknown step type %q", step.Type)
+	}
+
+	switch step.OnFailure {
+	case "", onFailureAbort, onFailureContinue, onFailureRollback:
+	default:
+		return errors.Wrapf(errInvalidStep, "unknown on_failure %q", step.OnFailure)
+	}
+
+	return nil
+}
+
+func validateSteps(steps []pipelineStep) error {
+	for i, step := range steps {
+		if err := step.validate(); err != nil {
+			return errors.WithMessagef(err, "step %d", i+1)
+		}
+	}
+
+	return nil
+}
+
+// InputSender sends console commands to a server. Implemented by
+// *rcon.Console.
+type InputSender interface {
+	SendInput(ctx context.Context, input string, server *domain.Server, out io.Writer) (domain.Result, error)
+}
+
+// BackupService backs up and restores servers. Implemented by
+// *backups.Service.
+type BackupService interface {
+	Backup(ctx context.Context, serverID int) (backups.Backup, error)
+	Restore(ctx context.Context, serverID int, at time.Time) (backups.Backup, error)
+}
+
+// PipelineServices are what pipeline steps run with. A step without its
+// service fails.
+type PipelineServices struct {
+	Console InputSender
+	Backups BackupService
+
+	// Executor runs the shell hooks.
+	Executor contracts.Executor
+
+	// Commands loads the stop, start and update commands of the steps, the
+	// scheduler command loader when it is not set.
+	Commands CommandLoader
+}
+
+// SetPipelineServices enables the pipeline steps that need them.
+func (s *Scheduler) SetPipelineServices(services PipelineServices) {
+	s.pipelineServices = services
+}
+
+// lockServer waits until the execution may run on the server and returns the
+// function releasing it. A pipeline runs alone, other executions of the
+// server run side by side.
+func (s *Scheduler) lockServer(ctx context.Context, serverID uint64, exclusive bool) (func(), error) {
+	return s.serverLocks.Lock(ctx, int(serverID), exclusive)
+}
+
+type pipeline struct {
+	s      *Scheduler
+	server *domain.Server
+	out    bytes.Buffer
+
+	// wasActive is whether the server ran when the pipeline started, a
+	// rollback brings it back to that.
+	wasActive bool
+
+	// backup is the last backup made by the pipeline, a rollback restores it.
+	backup *backups.Backup
+}
+
+// runPipeline runs the steps in order and returns their combined output.
+// The error is set when a step fails and its on_failure is not continue.
+func (s *Scheduler) runPipeline(
+	ctx context.Context, server *domain.Server, steps []pipelineStep,
+) ([]byte, error) {
+	p := &pipeline{s: s, server: server, wasActive: server.IsActive()}
+
+	for i, step := range steps {
+		fmt.Fprintf(&p.out, "==> Step %d/%d: %s\n", i+1, len(steps), step)
+
+		err := p.run(ctx, step)
+		if err == nil {
+			continue
+		}
+		if ctx.Err() != nil {
+			return p.out.Bytes(), ctx.Err()
+		}
+
+		fmt.Fprintf(&p.out, "Step failed: %s\n", err)
+		err = errors.WithMessagef(err, "step %d (%s) failed", i+1, step.Type)
+
+		switch step.OnFailure {
+		case onFailureContinue:
+			continue
+		case onFailureRollback:
+			fmt.Fprintln(&p.out, "==> Rollback")
+
+			if rbErr := p.rollback(ctx); rbErr != nil {
+				fmt.Fprintf(&p.out, "Rollback failed: %s\n", rbErr)
+				return p.out.Bytes(), errors.WithMessagef(err, "rollback failed: %s", rbErr)
+			}
+
+			return p.out.Bytes(), errors.WithMessage(err, "rolled back")
+		default:
+			return p.out.Bytes(), err
+		}
+	}
+
+	return p.out.Bytes(), nil
+}
+
+func (p *pipeline) run(ctx context.Context, step pipelineStep) error {
+	stepCtx := ctx
+	if step.Timeout > 0 {
+		var cancel context.CancelFunc
+		stepCtx, cancel = context.WithTimeout(ctx, time.Duration(step.Timeout))
+		defer cancel()
+	}
+
+	err := p.runStep(stepCtx, step)
+	if err != nil && ctx.Err() == nil && errors.Is(stepCtx.Err(), context.DeadlineExceeded) {
+		return errors.Wrapf(errStepTimeout, "after %s", time.Duration(step.Timeout))
+	}
+
+	return err
+}
+
+func (p *pipeline) runStep(ctx context.Context, step pipelineStep) error {
+	switch step.Type {
+	case stepCommand:
+		return p.sendCommand(ctx, step.Command)
+	case stepWait:
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Duration(step.Duration)):
+			return nil
+		}
+	case stepStop:
+		return p.serverCommand(ctx, domain.Stop)
+	case stepStart:
+		return p.serverCommand(ctx, domain.Start)
+	case stepUpdate:
+		return p.serverCommand(ctx, domain.Update)
+	case stepBackup:
+		return p.makeBackup(ctx)
+	case stepShellHook:
+		return p.runHook(ctx, step.Hook)
+	case stepArchive:
+		return p.makeArchive(ctx, step.archiveSpec)
+	case stepWaitReady:
+		if p.s.readiness == nil {
+			return nil
+		}
+
+		return p.s.readiness.WaitReady(ctx, p.server)
+	}
+
+	return errors.Wrapf(errInvalidStep, "unknown step type %q", step.Type)
+}
+
+func (p *pipeline) sendCommand(ctx context.Context, command string) error {
+	console := p.s.pipelineServices.Console
+	if console == nil {
+		return errors.Wrap(errNotAvailable, "server console")
+	}
+
+	_, err := console.SendInput(ctx, command, p.server, &p.out)
+
+	return err
+}
+
+func (p *pipeline) serverCommand(ctx context.Context, command domain.ServerCommand) error {
+	loader := p.s.pipelineServices.Commands
+	if loader == nil {
+		loader = p.s.commandLoader
+	}
+
+	cmd := loader.LoadServerCommand(command, p.server)
+	if cmd == nil {
+		return errNoServerCommand
+	}
+
+	err := cmd.Execute(ctx, p.server)
+	p.out.Write(cmd.ReadOutput())
+	if err != nil {
+		return err
+	}
+
+	if cmd.Result() != gameservercommands.SuccessResult {
+		return errCommandFailed
+	}
+
+	p.server.NoticeTaskCompleted()
+
+	return nil
+}
+
+func (p *pipeline) makeBackup(ctx context.Context) error {
+	service := p.s.pipelineServices.Backups
+	if service == nil {
+		return errors.Wrap(errNotAvailable, "backups")
+	}
+
+	backup, err := service.Backup(ctx, p.server.ID())
+	if err != nil {
+		return err
+	}
+
+	p.backup = &backup
+	fmt.Fprintf(&p.out, "Backup %s created\n", backup.Name)
+
+	return nil
+}
+
+// rollback restores the backup made by the pipeline and starts or stops the
+// server as it was before the pipeline.
+func (p *pipeline) rollback(ctx context.Context) error {
+	if p.backup != nil {
+		backup, err := p.s.pipelineServices.Backups.Restore(ctx, p.server.ID(), p.backup.CreatedAt)
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(&p.out, "Backup %s restored\n", backup.Name)
+	}
+
+	switch {
+	case p.wasActive && !p.server.IsActive():
+		return p.serverCommand(ctx, domain.Start)
+	case !p.wasActive && p.server.IsActive():
+		return p.serverCommand(ctx, domain.Stop)
+	}
+
+	return nil
+}
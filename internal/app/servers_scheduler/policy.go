@@ -74,10 +74,11 @@ func protoToTaskOptions(t *pb.ServerTask, server *domain.Server) domain.ServerTa
 		ServerID:      t.GetServerId(),
 		NodeID:        t.GetNodeId(),
 		Version:       t.GetVersion(),
-		Command:       mapProtoCommandToTaskCommand(t.GetCommand()),
+		Command:       taskCommandFromProto(t),
 		Server:        server,
 		Repeat:        int(t.GetRepeatCount()),
 		RepeatPeriod:  t.GetRepeatPeriod().AsDuration(),
+		Schedule:      scheduleFromProto(t),
 		Counter:       int(t.GetCounter()),
 		OverlapPolicy: mapProtoOverlapPolicy(t.GetOverlapPolicy()),
 		CatchupPolicy: mapProtoCatchupPolicy(t.GetCatchupPolicy()),
@@ -137,8 +138,49 @@ func applyCatchupOnApply(t *domain.ServerTask, now time.Time) {
 		return
 	}
 
-	next := nextFireAfter(executeDate, t.RepeatPeriod(), t.CatchupPolicy(), now)
+	var next time.Time
+	if t.FollowsCalendar() && t.CatchupPolicy() != domain.ServerTaskCatchupRunOnce {
+		// Whole periods do not line up with the wall clock over DST changes.
+		next = t.NextFireAt(now)
+	} else {
+		next = nextFireAfter(executeDate, t.RepeatPeriod(), t.CatchupPolicy(), now)
+	}
 	if !next.IsZero() {
 		t.SetExecuteDate(next)
 	}
 }
+
+// alignToSchedule moves the execute date of a cron task from the panel to the
+// first time of its schedule at or after it, or after now when the panel sends
+// no execute date. The daemon keeps the cadence of the task from there.
+func alignToSchedule(opts *domain.ServerTaskOptions, now time.Time) {
+	if opts.Schedule == nil {
+		return
+	}
+
+	from := opts.ExecuteDate
+	if from.IsZero() {
+		from = now
+	}
+
+	opts.ExecuteDate = domain.NewServerTask(*opts).NextFireAt(from)
+}
+
+// keepLocalProgress reconciles a task from a panel snapshot with the cached
+// one. While the daemon was offline it kept firing tasks and moving their
+// executeDate forward, but the panel snapshot still carries the old schedule.
+// For an unchanged task (same version) the local progress wins, otherwise a
+// catchup on apply would run the task a second time.
+func keepLocalProgress(cached *domain.ServerTask, opts *domain.ServerTaskOptions) {
+	if cached.Version() != opts.Version {
+		return
+	}
+
+	if executeDate := cached.ExecuteDate(); executeDate.After(opts.ExecuteDate) {
+		opts.ExecuteDate = executeDate
+	}
+
+	if counter := cached.Counter(); counter > opts.Counter {
+		opts.Counter = counter
+	}
+}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/gameap/daemon/internal/app/config"
 	"github.com/gameap/daemon/internal/app/domain"
+	"github.com/gameap/daemon/internal/app/serverlock"
 	"github.com/gameap/daemon/pkg/logger"
 	pb "github.com/gameap/gameap/pkg/proto"
 	"github.com/rs/xid"
@@ -21,14 +22,25 @@ type Scheduler struct {
 	commandLoader CommandLoader
 	serverRepo    domain.ServerRepository
 	sender        ServerTaskSender
+	readiness     ReadinessWaiter
+
+	conditionSources ConditionSources
+	pipelineServices PipelineServices
 
 	cache  *taskCache
 	resync *resyncTrigger
 
+	// persistMu orders the writes of the tasks state file.
+	persistMu sync.Mutex
+
 	mu       sync.Mutex
 	inFlight map[uint64]*runningTask
 	byExecID map[string]*executionRecord
 
+	// serverLocks keep pipelines apart from the other executions of their
+	// server, see lockServer.
+	serverLocks ServerLocker
+
 	nowFn func() time.Time
 }
 
@@ -46,12 +58,25 @@ func NewScheduler(
 		cache:         newTaskCache(),
 		inFlight:      make(map[uint64]*runningTask),
 		byExecID:      make(map[string]*executionRecord),
+		serverLocks:   serverlock.New(),
 		nowFn:         time.Now,
 	}
 	s.resync = newResyncTrigger(sender, s.now)
 	return s
 }
 
+// SetServerLocks shares the server locks with the panel tasks and the admin
+// API, so the executions do not run alongside their work on the server.
+func (s *Scheduler) SetServerLocks(locks ServerLocker) {
+	s.serverLocks = locks
+}
+
+// SetReadinessWaiter makes executions that start a server finish only once
+// the server is ready, so queued executions of the task wait for it too.
+func (s *Scheduler) SetReadinessWaiter(waiter ReadinessWaiter) {
+	s.readiness = waiter
+}
+
 func (s *Scheduler) now() time.Time {
 	return s.nowFn()
 }
@@ -72,6 +97,13 @@ func (s *Scheduler) Run(ctx context.Context) error {
 
 func (s *Scheduler) tick(ctx context.Context) {
 	now := s.now()
+	fired := false
+
+	defer func() {
+		if fired {
+			s.persist()
+		}
+	}()
 
 	for _, task := range s.cache.Snapshot() {
 		if ctx.Err() != nil {
@@ -98,6 +130,7 @@ func (s *Scheduler) tick(ctx context.Context) {
 		}
 
 		s.fire(ctx, task, server)
+		fired = true
 	}
 }
 
@@ -135,7 +168,7 @@ func (s *Scheduler) fire(ctx context.Context, task *domain.ServerTask, server *d
 			s.mu.Unlock()
 			task.IncreaseCountersAndTime()
 			s.sendStarted(rec)
-			s.sendFinishedSkipped(rec)
+			s.sendFinishedSkipped(rec, "overlap policy SKIP: previous execution still running")
 			s.cleanupFinished(rec.execID)
 			return
 		}
@@ -158,8 +191,10 @@ func (s *Scheduler) ApplySnapshot(snap *pb.ServerTaskSnapshot) {
 	tasks := make([]*domain.ServerTask, 0, len(snap.GetTasks()))
 	for _, pt := range snap.GetTasks() {
 		opts := protoToTaskOptions(pt, s.resolveServer(pt.GetServerId()))
+		alignToSchedule(&opts, now)
 		var t *domain.ServerTask
 		if cached := s.cache.Get(opts.ID); cached != nil {
+			keepLocalProgress(cached, &opts)
 			cached.UpdateFromOptions(opts)
 			t = cached
 		} else {
@@ -170,6 +205,7 @@ func (s *Scheduler) ApplySnapshot(snap *pb.ServerTaskSnapshot) {
 	}
 
 	s.cache.Replace(tasks)
+	s.persist()
 
 	log.WithFields(log.Fields{
 		"count":            len(tasks),
@@ -217,7 +253,9 @@ func (s *Scheduler) applyUpsert(pt *pb.ServerTask) {
 		s.resync.Trigger()
 	}
 
+	now := s.now()
 	opts := protoToTaskOptions(pt, s.resolveServer(pt.GetServerId()))
+	alignToSchedule(&opts, now)
 	var t *domain.ServerTask
 	if cached != nil {
 		cached.UpdateFromOptions(opts)
@@ -225,8 +263,9 @@ func (s *Scheduler) applyUpsert(pt *pb.ServerTask) {
 	} else {
 		t = domain.NewServerTask(opts)
 	}
-	applyCatchupOnApply(t, s.now())
+	applyCatchupOnApply(t, now)
 	s.cache.Put(t)
+	s.persist()
 }
 
 func (s *Scheduler) applyDeleted(deleted *pb.ServerTaskDeleted) {
@@ -238,6 +277,7 @@ func (s *Scheduler) applyDeleted(deleted *pb.ServerTaskDeleted) {
 		return
 	}
 	s.cache.Delete(deleted.GetId())
+	s.persist()
 }
 
 func (s *Scheduler) CancelExecution(req *pb.ServerTaskExecutionCancel) {
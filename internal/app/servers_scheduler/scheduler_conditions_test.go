@@ -0,0 +1,198 @@
+package serversscheduler
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gameap/daemon/internal/app/domain"
+	"github.com/gameap/daemon/internal/app/query"
+	pb "github.com/gameap/gameap/pkg/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakePlayersQuerier reports the players of the next query from the list,
+// the last one is repeated.
+type fakePlayersQuerier struct {
+	mu      sync.Mutex
+	players []int
+}
+
+func (q *fakePlayersQuerier) Query(_ context.Context, _ *domain.Server) (*query.Result, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	players := q.players[0]
+	if len(q.players) > 1 {
+		q.players = q.players[1:]
+	}
+
+	return &query.Result{PlayersOnline: players + 1, Bots: 1}, nil
+}
+
+type fakeUpdateChecker struct {
+	available bool
+}
+
+func (c *fakeUpdateChecker) UpdateAvailable(_ context.Context, _ *domain.Server) (bool, error) {
+	return c.available, nil
+}
+
+func givenConditionalTask(now time.Time, server *domain.Server, payload string) *domain.ServerTask {
+	return domain.NewServerTask(domain.ServerTaskOptions{
+		ID:           1,
+		ServerID:     uint64(server.ID()),
+		Version:      1,
+		Command:      domain.ServerTaskRestart,
+		ExecuteDate:  now.Add(-time.Second),
+		RepeatPeriod: time.Hour,
+		Payload:      payload,
+		Enabled:      true,
+		Server:       server,
+	})
+}
+
+func givenRunningServer() *domain.Server {
+	server := newServerForTask(42)
+	server.SetStatus(true)
+
+	return server
+}
+
+func TestConditions_PlayersOnline_Skipped(t *testing.T) {
+	now := time.Date(2026, 5, 12, 12, 0, 0, 0, time.UTC)
+	server := givenRunningServer()
+	sender := newFakeSender()
+	scheduler := newTestScheduler(&fakeLoader{cmd: &fakeCommand{}}, newFakeServerRepo(server), sender)
+	scheduler.SetConditionSources(ConditionSources{Players: &fakePlayersQuerier{players: []int{2}}})
+	freezeTime(scheduler, now)
+	scheduler.cache.Put(givenConditionalTask(now, server, `{"conditions": {"no_players": true}}`))
+
+	scheduler.tick(context.Background())
+	waitForFinished(t, sender, 1)
+
+	finished := sender.Finished()
+	require.Len(t, finished, 1)
+	assert.Equal(t, pb.ServerTaskExecutionStatus_SERVER_TASK_EXECUTION_STATUS_SKIPPED, finished[0].Status)
+	assert.Equal(t, "condition not met: 2 players online", finished[0].ErrorMessage)
+}
+
+func TestConditions_Met_Executed(t *testing.T) {
+	now := time.Date(2026, 5, 12, 12, 0, 0, 0, time.UTC)
+	server := givenRunningServer()
+	sender := newFakeSender()
+	scheduler := newTestScheduler(&fakeLoader{cmd: &fakeCommand{}}, newFakeServerRepo(server), sender)
+	scheduler.SetConditionSources(ConditionSources{
+		Players: &fakePlayersQuerier{players: []int{0}},
+		Updates: &fakeUpdateChecker{available: true},
+	})
+	freezeTime(scheduler, now)
+	scheduler.cache.Put(givenConditionalTask(now, server,
+		`{"conditions": {"no_players": true, "update_available": true}}`))
+
+	scheduler.tick(context.Background())
+	waitForFinished(t, sender, 1)
+
+	finished := sender.Finished()
+	require.Len(t, finished, 1)
+	assert.Equal(t, pb.ServerTaskExecutionStatus_SERVER_TASK_EXECUTION_STATUS_SUCCESS, finished[0].Status)
+}
+
+func TestConditions_NoSource_Skipped(t *testing.T) {
+	now := time.Date(2026, 5, 12, 12, 0, 0, 0, time.UTC)
+	server := givenRunningServer()
+	sender := newFakeSender()
+	scheduler := newTestScheduler(&fakeLoader{cmd: &fakeCommand{}}, newFakeServerRepo(server), sender)
+	freezeTime(scheduler, now)
+	scheduler.cache.Put(givenConditionalTask(now, server, `{"conditions": {"update_available": true}}`))
+
+	scheduler.tick(context.Background())
+	waitForFinished(t, sender, 1)
+
+	finished := sender.Finished()
+	require.Len(t, finished, 1)
+	assert.Equal(t, pb.ServerTaskExecutionStatus_SERVER_TASK_EXECUTION_STATUS_SKIPPED, finished[0].Status)
+	assert.Equal(t, "condition not met: updates are not checked", finished[0].ErrorMessage)
+}
+
+func TestConditions_Deferred_RunsOnceMet(t *testing.T) {
+	server := givenRunningServer()
+	sender := newFakeSender()
+	scheduler := newTestScheduler(&fakeLoader{cmd: &fakeCommand{}}, newFakeServerRepo(server), sender)
+	scheduler.SetConditionSources(ConditionSources{Players: &fakePlayersQuerier{players: []int{3, 0}}})
+	scheduler.cache.Put(givenConditionalTask(time.Now(), server,
+		`{"conditions": {"no_players": true, "defer": "1m", "retry_interval": "1s"}}`))
+
+	scheduler.tick(context.Background())
+	waitForFinished(t, sender, 1)
+
+	finished := sender.Finished()
+	require.Len(t, finished, 1)
+	assert.Equal(t, pb.ServerTaskExecutionStatus_SERVER_TASK_EXECUTION_STATUS_SUCCESS, finished[0].Status)
+}
+
+func TestConditions_Deferred_SkippedAtDeadline(t *testing.T) {
+	server := givenRunningServer()
+	sender := newFakeSender()
+	scheduler := newTestScheduler(&fakeLoader{cmd: &fakeCommand{}}, newFakeServerRepo(server), sender)
+	scheduler.SetConditionSources(ConditionSources{Players: &fakePlayersQuerier{players: []int{1}}})
+	scheduler.cache.Put(givenConditionalTask(time.Now(), server,
+		`{"conditions": {"no_players": true, "defer": "1s", "retry_interval": "1s"}}`))
+
+	scheduler.tick(context.Background())
+	waitForFinished(t, sender, 1)
+
+	finished := sender.Finished()
+	require.Len(t, finished, 1)
+	assert.Equal(t, pb.ServerTaskExecutionStatus_SERVER_TASK_EXECUTION_STATUS_SKIPPED, finished[0].Status)
+	assert.Equal(t, "condition not met until the deadline: 1 players online", finished[0].ErrorMessage)
+}
+
+func TestConditions_InvalidPayload_Failed(t *testing.T) {
+	now := time.Date(2026, 5, 12, 12, 0, 0, 0, time.UTC)
+	server := givenRunningServer()
+	sender := newFakeSender()
+	scheduler := newTestScheduler(&fakeLoader{cmd: &fakeCommand{}}, newFakeServerRepo(server), sender)
+	freezeTime(scheduler, now)
+	scheduler.cache.Put(givenConditionalTask(now, server, `{"conditions": {"min_uptime": "long"}}`))
+
+	scheduler.tick(context.Background())
+	waitForFinished(t, sender, 1)
+
+	finished := sender.Finished()
+	require.Len(t, finished, 1)
+	assert.Equal(t, pb.ServerTaskExecutionStatus_SERVER_TASK_EXECUTION_STATUS_FAILED, finished[0].Status)
+}
+
+func TestConditions_Uptime(t *testing.T) {
+	now := time.Now()
+	server := givenRunningServer()
+	scheduler := newTestScheduler(&fakeLoader{cmd: &fakeCommand{}}, newFakeServerRepo(server), newFakeSender())
+	freezeTime(scheduler, now)
+
+	assert.Contains(t, scheduler.checkUptime(server, time.Hour), "less than 1h0m0s")
+
+	freezeTime(scheduler, now.Add(2*time.Hour))
+
+	assert.Empty(t, scheduler.checkUptime(server, time.Hour))
+	assert.Equal(t, "server is not running", scheduler.checkUptime(newServerForTask(43), time.Hour))
+}
+
+func TestConditions_Memory(t *testing.T) {
+	server := givenRunningServer()
+	scheduler := newTestScheduler(&fakeLoader{cmd: &fakeCommand{}}, newFakeServerRepo(server), newFakeSender())
+	scheduler.SetConditionSources(ConditionSources{Metrics: fakeMetricsReader{
+		{Name: metricMemoryUsage, Value: domain.Uint64Value(3 << 30)},
+	}})
+
+	assert.Empty(t, scheduler.checkMemory(context.Background(), server, 2048))
+	assert.Equal(t, "memory usage 3072 MiB, at most 4096 MiB", scheduler.checkMemory(context.Background(), server, 4096))
+}
+
+type fakeMetricsReader []domain.Metric
+
+func (r fakeMetricsReader) Metrics(_ context.Context, _ *domain.Server) ([]domain.Metric, error) {
+	return r, nil
+}
@@ -0,0 +1,316 @@
+package serversscheduler
+
+import (
+	"context"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gameap/daemon/internal/app/backups"
+	"github.com/gameap/daemon/internal/app/contracts"
+	"github.com/gameap/daemon/internal/app/domain"
+	gameservercommands "github.com/gameap/daemon/internal/app/game_server_commands"
+	"github.com/gameap/daemon/internal/app/serverlock"
+	pb "github.com/gameap/gameap/pkg/proto"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var errConsole = errors.New("console is closed")
+
+var commandNames = map[domain.ServerCommand]string{
+	domain.Stop:   "stop",
+	domain.Start:  "start",
+	domain.Update: "update",
+}
+
+// pipelineRecorder records the steps a pipeline runs, every fake below
+// appends to it.
+type pipelineRecorder struct {
+	mu    sync.Mutex
+	calls []string
+}
+
+func (r *pipelineRecorder) record(call string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.calls = append(r.calls, call)
+}
+
+func (r *pipelineRecorder) Calls() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]string(nil), r.calls...)
+}
+
+type recordingLoader struct {
+	rec     *pipelineRecorder
+	results map[domain.ServerCommand]int
+}
+
+func (l *recordingLoader) LoadServerCommand(cmd domain.ServerCommand, _ *domain.Server) contracts.GameServerCommand {
+	return &recordedCommand{rec: l.rec, cmd: cmd, result: l.results[cmd]}
+}
+
+type recordedCommand struct {
+	rec    *pipelineRecorder
+	cmd    domain.ServerCommand
+	result int
+}
+
+func (c *recordedCommand) Execute(_ context.Context, server *domain.Server) error {
+	c.rec.record(commandNames[c.cmd])
+
+	switch c.cmd {
+	case domain.Stop:
+		server.SetStatus(false)
+	case domain.Start:
+		server.SetStatus(true)
+	}
+
+	return nil
+}
+
+func (c *recordedCommand) Result() int {
+	if c.result == 0 {
+		return gameservercommands.SuccessResult
+	}
+
+	return c.result
+}
+
+func (c *recordedCommand) IsComplete() bool   { return true }
+func (c *recordedCommand) ReadOutput() []byte { return []byte(commandNames[c.cmd] + " done\n") }
+
+type recordingConsole struct {
+	rec *pipelineRecorder
+	err error
+}
+
+func (c *recordingConsole) SendInput(
+	_ context.Context, input string, _ *domain.Server, out io.Writer,
+) (domain.Result, error) {
+	c.rec.record("console: " + input)
+	if c.err != nil {
+		return domain.ErrorResult, c.err
+	}
+
+	_, _ = io.WriteString(out, "reply to "+input+"\n")
+
+	return domain.SuccessResult, nil
+}
+
+type recordingBackups struct {
+	rec       *pipelineRecorder
+	createdAt time.Time
+	restored  time.Time
+}
+
+func (b *recordingBackups) Backup(_ context.Context, serverID int) (backups.Backup, error) {
+	b.rec.record("backup")
+
+	return backups.Backup{ServerID: serverID, Name: "backup.tar.gz", CreatedAt: b.createdAt}, nil
+}
+
+func (b *recordingBackups) Restore(_ context.Context, serverID int, at time.Time) (backups.Backup, error) {
+	b.rec.record("restore")
+	b.restored = at
+
+	return backups.Backup{ServerID: serverID, Name: "backup.tar.gz", CreatedAt: at}, nil
+}
+
+func givenPipelineScheduler(
+	t *testing.T, server *domain.Server, results map[domain.ServerCommand]int, payload string,
+) (*Scheduler, *fakeSender, *pipelineRecorder, *recordingBackups) {
+	t.Helper()
+
+	now := time.Date(2026, 5, 12, 12, 0, 0, 0, time.UTC)
+	rec := &pipelineRecorder{}
+	sender := newFakeSender()
+	backupService := &recordingBackups{rec: rec, createdAt: now.Add(-time.Minute)}
+
+	scheduler := newTestScheduler(&fakeLoader{cmd: &fakeCommand{}}, newFakeServerRepo(server), sender)
+	scheduler.SetPipelineServices(PipelineServices{
+		Console:  &recordingConsole{rec: rec},
+		Backups:  backupService,
+		Commands: &recordingLoader{rec: rec, results: results},
+	})
+	freezeTime(scheduler, now)
+	scheduler.cache.Put(givenConditionalTask(now, server, payload))
+
+	return scheduler, sender, rec, backupService
+}
+
+func TestPipeline_RunsStepsInOrder(t *testing.T) {
+	server := givenRunningServer()
+	scheduler, sender, rec, _ := givenPipelineScheduler(t, server, nil, `{"steps": [
+		{"type": "command", "command": "say Restarting"},
+		{"type": "wait", "duration": "10ms"},
+		{"type": "stop"},
+		{"type": "backup"},
+		{"type": "update"},
+		{"type": "start"},
+		{"type": "wait_ready", "timeout": "1m"}
+	]}`)
+
+	scheduler.tick(context.Background())
+	waitForFinished(t, sender, 1)
+
+	finished := sender.Finished()
+	require.Len(t, finished, 1)
+	assert.Equal(t, pb.ServerTaskExecutionStatus_SERVER_TASK_EXECUTION_STATUS_SUCCESS, finished[0].Status)
+	assert.Equal(t, []string{"console: say Restarting", "stop", "backup", "update", "start"}, rec.Calls())
+
+	output := string(finished[0].OutputInline)
+	assert.Contains(t, output, "==> Step 1/7: command say Restarting\nreply to say Restarting\n")
+	assert.Contains(t, output, "==> Step 4/7: backup\nBackup backup.tar.gz created\n")
+	assert.Contains(t, output, "==> Step 6/7: start\nstart done\n")
+}
+
+func TestPipeline_FailedStepAborts(t *testing.T) {
+	server := givenRunningServer()
+	results := map[domain.ServerCommand]int{domain.Update: gameservercommands.ErrorResult}
+	scheduler, sender, rec, _ := givenPipelineScheduler(t, server, results, `{"steps": [
+		{"type": "stop"},
+		{"type": "update"},
+		{"type": "start"}
+	]}`)
+
+	scheduler.tick(context.Background())
+	waitForFinished(t, sender, 1)
+
+	finished := sender.Finished()
+	require.Len(t, finished, 1)
+	assert.Equal(t, pb.ServerTaskExecutionStatus_SERVER_TASK_EXECUTION_STATUS_FAILED, finished[0].Status)
+	assert.Equal(t, "step 2 (update) failed: server command failed", finished[0].ErrorMessage)
+	assert.Equal(t, []string{"stop", "update"}, rec.Calls())
+}
+
+func TestPipeline_FailedStepContinues(t *testing.T) {
+	server := givenRunningServer()
+	scheduler, sender, rec, _ := givenPipelineScheduler(t, server, nil, `{"steps": [
+		{"type": "command", "command": "say Bye", "on_failure": "continue"},
+		{"type": "stop"}
+	]}`)
+	scheduler.pipelineServices.Console = &recordingConsole{rec: rec, err: errConsole}
+
+	scheduler.tick(context.Background())
+	waitForFinished(t, sender, 1)
+
+	finished := sender.Finished()
+	require.Len(t, finished, 1)
+	assert.Equal(t, pb.ServerTaskExecutionStatus_SERVER_TASK_EXECUTION_STATUS_SUCCESS, finished[0].Status)
+	assert.Equal(t, []string{"console: say Bye", "stop"}, rec.Calls())
+	assert.Contains(t, string(finished[0].OutputInline), "Step failed: console is closed\n")
+}
+
+func TestPipeline_FailedStepRollsBack(t *testing.T) {
+	server := givenRunningServer()
+	results := map[domain.ServerCommand]int{domain.Update: gameservercommands.ErrorResult}
+	scheduler, sender, rec, backupService := givenPipelineScheduler(t, server, results, `{"steps": [
+		{"type": "stop"},
+		{"type": "backup"},
+		{"type": "update", "on_failure": "rollback"},
+		{"type": "start"}
+	]}`)
+
+	scheduler.tick(context.Background())
+	waitForFinished(t, sender, 1)
+
+	finished := sender.Finished()
+	require.Len(t, finished, 1)
+	assert.Equal(t, pb.ServerTaskExecutionStatus_SERVER_TASK_EXECUTION_STATUS_FAILED, finished[0].Status)
+	assert.Equal(t, "rolled back: step 3 (update) failed: server command failed", finished[0].ErrorMessage)
+	assert.Equal(t, []string{"stop", "backup", "update", "restore", "start"}, rec.Calls())
+	assert.Equal(t, backupService.createdAt, backupService.restored)
+	assert.True(t, server.IsActive())
+}
+
+func TestPipeline_InvalidSteps_Failed(t *testing.T) {
+	server := givenRunningServer()
+	scheduler, sender, rec, _ := givenPipelineScheduler(t, server, nil, `{"steps": [
+		{"type": "stop"},
+		{"type": "reboot"}
+	]}`)
+
+	scheduler.tick(context.Background())
+	waitForFinished(t, sender, 1)
+
+	finished := sender.Finished()
+	require.Len(t, finished, 1)
+	assert.Equal(t, pb.ServerTaskExecutionStatus_SERVER_TASK_EXECUTION_STATUS_FAILED, finished[0].Status)
+	assert.Equal(t, `step 2: unknown step type "reboot": invalid pipeline step`, finished[0].ErrorMessage)
+	assert.Empty(t, rec.Calls())
+}
+
+func TestPipeline_StepTimeout(t *testing.T) {
+	server := givenRunningServer()
+	scheduler, sender, _, _ := givenPipelineScheduler(t, server, nil, `{"steps": [
+		{"type": "wait", "duration": "1m", "timeout": "10ms"}
+	]}`)
+
+	scheduler.tick(context.Background())
+	waitForFinished(t, sender, 1)
+
+	finished := sender.Finished()
+	require.Len(t, finished, 1)
+	assert.Equal(t, pb.ServerTaskExecutionStatus_SERVER_TASK_EXECUTION_STATUS_FAILED, finished[0].Status)
+	assert.Equal(t, "step 1 (wait) failed: after 10ms: step timed out", finished[0].ErrorMessage)
+}
+
+func TestLockServer_PipelineRunsAlone(t *testing.T) {
+	scheduler := newTestScheduler(&fakeLoader{cmd: &fakeCommand{}}, newFakeServerRepo(), newFakeSender())
+
+	unlockShared, err := scheduler.lockServer(context.Background(), 1, false)
+	require.NoError(t, err)
+	unlockOther, err := scheduler.lockServer(context.Background(), 1, false)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err = scheduler.lockServer(ctx, 1, true)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	unlockShared()
+	unlockOther()
+
+	unlockPipeline, err := scheduler.lockServer(context.Background(), 1, true)
+	require.NoError(t, err)
+
+	ctx, cancel = context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err = scheduler.lockServer(ctx, 1, false)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	_, err = scheduler.lockServer(context.Background(), 2, false)
+	require.NoError(t, err, "other servers are not locked")
+
+	unlockPipeline()
+}
+
+func TestLockServer_SharedWithPanelTasks(t *testing.T) {
+	scheduler := newTestScheduler(&fakeLoader{cmd: &fakeCommand{}}, newFakeServerRepo(), newFakeSender())
+	locks := serverlock.New()
+	scheduler.SetServerLocks(locks)
+
+	unlockTask, err := locks.Lock(context.Background(), 1, true)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err = scheduler.lockServer(ctx, 1, false)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	unlockTask()
+
+	unlock, err := scheduler.lockServer(context.Background(), 1, false)
+	require.NoError(t, err)
+	_, free := locks.TryLock(1, true)
+	assert.False(t, free, "the panel task waits for the execution")
+
+	unlock()
+}
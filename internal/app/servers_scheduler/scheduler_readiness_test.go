@@ -0,0 +1,91 @@
+package serversscheduler
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gameap/daemon/internal/app/domain"
+	pb "github.com/gameap/gameap/pkg/proto"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var errTestNotReady = errors.New("server did not become ready")
+
+type fakeReadinessWaiter struct {
+	mu    sync.Mutex
+	err   error
+	calls int
+}
+
+func (w *fakeReadinessWaiter) WaitReady(_ context.Context, _ *domain.Server) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.calls++
+
+	return w.err
+}
+
+func (w *fakeReadinessWaiter) Calls() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.calls
+}
+
+func givenStartTask(now time.Time, server *domain.Server) *domain.ServerTask {
+	return domain.NewServerTask(domain.ServerTaskOptions{
+		ID:           1,
+		ServerID:     uint64(server.ID()),
+		Version:      1,
+		Command:      domain.ServerTaskStart,
+		ExecuteDate:  now.Add(-time.Second),
+		RepeatPeriod: time.Hour,
+		Enabled:      true,
+		Server:       server,
+	})
+}
+
+func TestExecute_StartedServer_WaitsForReadiness(t *testing.T) {
+	now := time.Date(2026, 5, 12, 12, 0, 0, 0, time.UTC)
+	server := newServerForTask(42)
+	server.AffectStart()
+	waiter := &fakeReadinessWaiter{err: errTestNotReady}
+	sender := newFakeSender()
+	scheduler := newTestScheduler(&fakeLoader{cmd: &fakeCommand{}}, newFakeServerRepo(server), sender)
+	scheduler.SetReadinessWaiter(waiter)
+	freezeTime(scheduler, now)
+	scheduler.cache.Put(givenStartTask(now, server))
+
+	scheduler.tick(context.Background())
+	waitForFinished(t, sender, 1)
+
+	finished := sender.Finished()
+	require.Len(t, finished, 1)
+	assert.Equal(t, pb.ServerTaskExecutionStatus_SERVER_TASK_EXECUTION_STATUS_FAILED, finished[0].Status)
+	assert.Equal(t, errTestNotReady.Error(), finished[0].ErrorMessage)
+	assert.Equal(t, 1, waiter.Calls())
+}
+
+func TestExecute_RunningServer_DoesNotWait(t *testing.T) {
+	now := time.Date(2026, 5, 12, 12, 0, 0, 0, time.UTC)
+	server := newServerForTask(42)
+	waiter := &fakeReadinessWaiter{err: errTestNotReady}
+	sender := newFakeSender()
+	scheduler := newTestScheduler(&fakeLoader{cmd: &fakeCommand{}}, newFakeServerRepo(server), sender)
+	scheduler.SetReadinessWaiter(waiter)
+	freezeTime(scheduler, now)
+	scheduler.cache.Put(givenStartTask(now, server))
+
+	scheduler.tick(context.Background())
+	waitForFinished(t, sender, 1)
+
+	finished := sender.Finished()
+	require.Len(t, finished, 1)
+	assert.Equal(t, pb.ServerTaskExecutionStatus_SERVER_TASK_EXECUTION_STATUS_SUCCESS, finished[0].Status)
+	assert.Zero(t, waiter.Calls())
+}
@@ -0,0 +1,123 @@
+package serversscheduler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	pb "github.com/gameap/gameap/pkg/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func mustLoadLocation(t *testing.T, name string) *time.Location {
+	t.Helper()
+
+	loc, err := time.LoadLocation(name)
+	require.NoError(t, err)
+
+	return loc
+}
+
+func TestSchedule_WithoutExecuteDate_AlignedToFirstSlot(t *testing.T) {
+	berlin := mustLoadLocation(t, "Europe/Berlin")
+	now := time.Date(2026, 3, 27, 12, 0, 0, 0, time.UTC) // Friday
+	scheduler := newTestScheduler(&fakeLoader{cmd: &fakeCommand{}}, newFakeServerRepo(newServerForTask(42)), newFakeSender())
+	freezeTime(scheduler, now)
+
+	scheduler.ApplySnapshot(&pb.ServerTaskSnapshot{
+		Tasks: []*pb.ServerTask{{
+			Id:       1,
+			ServerId: 42,
+			Version:  1,
+			Command:  pb.ServerTaskCommand_SERVER_TASK_COMMAND_RESTART,
+			Timezone: "Europe/Berlin",
+			Payload:  `{"schedule": "0 5 * * mon-fri"}`,
+			Enabled:  true,
+		}},
+	})
+
+	cached := scheduler.cache.Get(1)
+	require.NotNil(t, cached)
+	require.NotNil(t, cached.Schedule())
+	// Monday, the clocks moved forward on Sunday.
+	assert.True(t, time.Date(2026, 3, 30, 5, 0, 0, 0, berlin).Equal(cached.ExecuteDate()))
+}
+
+func TestSchedule_Skip_OnSnapshotApply_ShiftsToNextSlot(t *testing.T) {
+	berlin := mustLoadLocation(t, "Europe/Berlin")
+	now := time.Date(2026, 3, 31, 12, 0, 0, 0, time.UTC)
+	scheduler := newTestScheduler(&fakeLoader{cmd: &fakeCommand{}}, newFakeServerRepo(newServerForTask(42)), newFakeSender())
+	freezeTime(scheduler, now)
+
+	scheduler.ApplySnapshot(&pb.ServerTaskSnapshot{
+		Tasks: []*pb.ServerTask{{
+			Id:            1,
+			ServerId:      42,
+			Version:       1,
+			Command:       pb.ServerTaskCommand_SERVER_TASK_COMMAND_RESTART,
+			ExecuteDate:   timestamppb.New(time.Date(2026, 3, 20, 5, 0, 0, 0, berlin)),
+			RepeatPeriod:  durationpb.New(24 * time.Hour),
+			CatchupPolicy: pb.ServerTaskCatchupPolicy_SERVER_TASK_CATCHUP_POLICY_SKIP,
+			Timezone:      "Europe/Berlin",
+			Enabled:       true,
+		}},
+	})
+
+	cached := scheduler.cache.Get(1)
+	require.NotNil(t, cached)
+	// The daily period keeps 05:00 of the timezone over the DST change.
+	assert.True(t, time.Date(2026, 4, 1, 5, 0, 0, 0, berlin).Equal(cached.ExecuteDate()))
+}
+
+func TestSchedule_Tick_ProlongsToNextSlot(t *testing.T) {
+	now := time.Date(2026, 5, 12, 5, 0, 0, 0, time.UTC)
+	loader := &fakeLoader{cmd: &fakeCommand{}}
+	scheduler := newTestScheduler(loader, newFakeServerRepo(newServerForTask(42)), newFakeSender())
+	freezeTime(scheduler, now)
+
+	scheduler.ApplySnapshot(&pb.ServerTaskSnapshot{
+		Tasks: []*pb.ServerTask{{
+			Id:          1,
+			ServerId:    42,
+			Version:     1,
+			Command:     pb.ServerTaskCommand_SERVER_TASK_COMMAND_RESTART,
+			ExecuteDate: timestamppb.New(now),
+			Payload:     `{"schedule": "0 5 * * *"}`,
+			Enabled:     true,
+		}},
+	})
+
+	scheduler.tick(context.Background())
+
+	cached := scheduler.cache.Get(1)
+	require.NotNil(t, cached)
+	assert.True(t, now.Add(24*time.Hour).Equal(cached.ExecuteDate()))
+	assert.Eventually(t, func() bool { return loader.Calls() == 1 }, time.Second, 10*time.Millisecond)
+}
+
+func TestSchedule_Invalid_FallsBackToRepeatPeriod(t *testing.T) {
+	now := time.Date(2026, 5, 12, 12, 0, 0, 0, time.UTC)
+	scheduler := newTestScheduler(&fakeLoader{cmd: &fakeCommand{}}, newFakeServerRepo(newServerForTask(42)), newFakeSender())
+	freezeTime(scheduler, now)
+
+	scheduler.ApplySnapshot(&pb.ServerTaskSnapshot{
+		Tasks: []*pb.ServerTask{{
+			Id:           1,
+			ServerId:     42,
+			Version:      1,
+			Command:      pb.ServerTaskCommand_SERVER_TASK_COMMAND_RESTART,
+			ExecuteDate:  timestamppb.New(now.Add(time.Minute)),
+			RepeatPeriod: durationpb.New(time.Hour),
+			Payload:      `{"schedule": "0 25 * * *"}`,
+			Enabled:      true,
+		}},
+	})
+
+	cached := scheduler.cache.Get(1)
+	require.NotNil(t, cached)
+	assert.Nil(t, cached.Schedule())
+	assert.Equal(t, now.Add(time.Minute), cached.ExecuteDate())
+}
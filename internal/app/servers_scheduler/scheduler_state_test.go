@@ -0,0 +1,136 @@
+package serversscheduler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gameap/daemon/internal/app/config"
+	pb "github.com/gameap/gameap/pkg/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func newPersistentTestScheduler(t *testing.T, repo *fakeServerRepo, statePath string) *Scheduler {
+	t.Helper()
+
+	cfg := &config.Config{StatePath: statePath}
+
+	return NewScheduler(cfg, &fakeLoader{cmd: &fakeCommand{}}, repo, newFakeSender())
+}
+
+func restartTask(executeDate time.Time, version uint64) *pb.ServerTask {
+	return &pb.ServerTask{
+		Id:            1,
+		ServerId:      42,
+		Version:       version,
+		Command:       pb.ServerTaskCommand_SERVER_TASK_COMMAND_RESTART,
+		ExecuteDate:   timestamppb.New(executeDate),
+		RepeatPeriod:  durationpb.New(time.Hour),
+		OverlapPolicy: pb.ServerTaskOverlapPolicy_SERVER_TASK_OVERLAP_POLICY_SKIP,
+		CatchupPolicy: pb.ServerTaskCatchupPolicy_SERVER_TASK_CATCHUP_POLICY_RUN_ONCE,
+		Enabled:       true,
+	}
+}
+
+func TestRestore_LoadsTasksSavedByPreviousRun(t *testing.T) {
+	now := time.Date(2026, 5, 12, 12, 0, 0, 0, time.UTC)
+	statePath := t.TempDir()
+	repo := newFakeServerRepo(newServerForTask(42))
+
+	first := newPersistentTestScheduler(t, repo, statePath)
+	freezeTime(first, now)
+	first.ApplySnapshot(&pb.ServerTaskSnapshot{
+		Tasks: []*pb.ServerTask{restartTask(now.Add(30*time.Minute), 1)},
+	})
+
+	second := newPersistentTestScheduler(t, repo, statePath)
+	freezeTime(second, now)
+	restored, err := second.Restore(context.Background())
+
+	require.NoError(t, err)
+	assert.Equal(t, 1, restored)
+	cached := second.cache.Get(1)
+	require.NotNil(t, cached)
+	assert.Equal(t, now.Add(30*time.Minute), cached.ExecuteDate().UTC())
+	assert.Equal(t, repo.servers[42], cached.Server())
+}
+
+func TestRestore_AppliesCatchupToTasksDueWhileDown(t *testing.T) {
+	now := time.Date(2026, 5, 12, 12, 0, 0, 0, time.UTC)
+	statePath := t.TempDir()
+	repo := newFakeServerRepo(newServerForTask(42))
+
+	first := newPersistentTestScheduler(t, repo, statePath)
+	freezeTime(first, now)
+	first.ApplySnapshot(&pb.ServerTaskSnapshot{
+		Tasks: []*pb.ServerTask{restartTask(now.Add(30*time.Minute), 1)},
+	})
+
+	restartedAt := now.Add(3 * time.Hour)
+	second := newPersistentTestScheduler(t, repo, statePath)
+	freezeTime(second, restartedAt)
+	_, err := second.Restore(context.Background())
+
+	require.NoError(t, err)
+	assert.Equal(t, restartedAt, second.cache.Get(1).ExecuteDate())
+}
+
+func TestRestore_SkippedAfterPanelSnapshot(t *testing.T) {
+	now := time.Date(2026, 5, 12, 12, 0, 0, 0, time.UTC)
+	scheduler := newPersistentTestScheduler(t, newFakeServerRepo(newServerForTask(42)), t.TempDir())
+	freezeTime(scheduler, now)
+
+	fromPanel := restartTask(now.Add(30*time.Minute), 1)
+	fromPanel.Id = 2
+	scheduler.ApplySnapshot(&pb.ServerTaskSnapshot{Tasks: []*pb.ServerTask{fromPanel}})
+
+	restored, err := scheduler.Restore(context.Background())
+
+	require.NoError(t, err)
+	assert.Equal(t, 0, restored)
+	assert.Equal(t, 1, scheduler.cache.Len())
+	assert.NotNil(t, scheduler.cache.Get(2))
+}
+
+func TestApplySnapshot_KeepsProgressMadeOffline(t *testing.T) {
+	now := time.Date(2026, 5, 12, 12, 0, 0, 0, time.UTC)
+	scheduler := newTestScheduler(&fakeLoader{cmd: &fakeCommand{}}, newFakeServerRepo(newServerForTask(42)), newFakeSender())
+	freezeTime(scheduler, now)
+
+	panelDate := now.Add(-3 * time.Hour)
+	scheduler.ApplySnapshot(&pb.ServerTaskSnapshot{
+		Tasks: []*pb.ServerTask{restartTask(now.Add(time.Hour), 1)},
+	})
+	scheduler.cache.Get(1).IncreaseCountersAndTime()
+
+	// The panel did not see the runs made while the daemon was offline.
+	scheduler.ApplySnapshot(&pb.ServerTaskSnapshot{
+		Tasks: []*pb.ServerTask{restartTask(panelDate, 1)},
+	})
+
+	cached := scheduler.cache.Get(1)
+	assert.Equal(t, now.Add(2*time.Hour), cached.ExecuteDate())
+	assert.Equal(t, 1, cached.Counter())
+}
+
+func TestApplySnapshot_ChangedTaskReplacesLocalProgress(t *testing.T) {
+	now := time.Date(2026, 5, 12, 12, 0, 0, 0, time.UTC)
+	scheduler := newTestScheduler(&fakeLoader{cmd: &fakeCommand{}}, newFakeServerRepo(newServerForTask(42)), newFakeSender())
+	freezeTime(scheduler, now)
+
+	scheduler.ApplySnapshot(&pb.ServerTaskSnapshot{
+		Tasks: []*pb.ServerTask{restartTask(now.Add(time.Hour), 1)},
+	})
+	scheduler.cache.Get(1).IncreaseCountersAndTime()
+
+	scheduler.ApplySnapshot(&pb.ServerTaskSnapshot{
+		Tasks: []*pb.ServerTask{restartTask(now.Add(10*time.Minute), 2)},
+	})
+
+	cached := scheduler.cache.Get(1)
+	assert.Equal(t, now.Add(10*time.Minute), cached.ExecuteDate())
+	assert.Equal(t, 0, cached.Counter())
+}
@@ -0,0 +1,85 @@
+package serversscheduler
+
+import (
+	"context"
+	"path/filepath"
+	"sort"
+
+	"github.com/gameap/daemon/internal/app/domain"
+	"github.com/gameap/daemon/internal/app/statefile"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	ServerTasksFileName = "server_tasks.json"
+
+	serverTasksFileVersion = 1
+)
+
+func (s *Scheduler) stateFile() string {
+	if s.cfg == nil || s.cfg.StatePath == "" {
+		return ""
+	}
+
+	return filepath.Join(s.cfg.StatePath, ServerTasksFileName)
+}
+
+// Restore loads the tasks saved by a previous daemon run, so scheduled tasks
+// keep firing when the panel is unreachable at startup. Tasks that were due
+// while the daemon was down go through the catchup policy. Nothing is loaded
+// once a snapshot from the panel has been applied.
+func (s *Scheduler) Restore(_ context.Context) (int, error) {
+	path := s.stateFile()
+	if path == "" {
+		return 0, nil
+	}
+
+	var saved []domain.ServerTaskOptions
+	if _, err := statefile.Load(path, serverTasksFileVersion, &saved); err != nil {
+		return 0, err
+	}
+
+	if s.cache.Len() > 0 {
+		return 0, nil
+	}
+
+	now := s.now()
+	tasks := make([]*domain.ServerTask, 0, len(saved))
+	for _, opts := range saved {
+		opts.Server = s.resolveServer(opts.ServerID)
+
+		t := domain.NewServerTask(opts)
+		applyCatchupOnApply(t, now)
+		tasks = append(tasks, t)
+	}
+
+	s.cache.Replace(tasks)
+
+	return len(tasks), nil
+}
+
+// persist saves the cached tasks with their local progress (counter and next
+// execute date).
+func (s *Scheduler) persist() {
+	path := s.stateFile()
+	if path == "" {
+		return
+	}
+
+	s.persistMu.Lock()
+	defer s.persistMu.Unlock()
+
+	tasks := s.cache.Snapshot()
+	sort.Slice(tasks, func(i, j int) bool {
+		return tasks[i].ID() < tasks[j].ID()
+	})
+
+	saved := make([]domain.ServerTaskOptions, 0, len(tasks))
+	for _, t := range tasks {
+		saved = append(saved, t.Options())
+	}
+
+	if err := statefile.Save(path, serverTasksFileVersion, saved); err != nil {
+		log.WithError(err).Warn("Failed to persist server tasks")
+	}
+}
@@ -17,6 +17,17 @@ type CommandLoader interface {
 	LoadServerCommand(cmd domain.ServerCommand, server *domain.Server) contracts.GameServerCommand
 }
 
+// ServerLocker keeps the executions apart from the panel tasks and the admin
+// API commands of the same server.
+type ServerLocker interface {
+	Lock(ctx context.Context, serverID int, exclusive bool) (func(), error)
+}
+
+// ReadinessWaiter blocks until a started server is ready.
+type ReadinessWaiter interface {
+	WaitReady(ctx context.Context, server *domain.Server) error
+}
+
 type executionRecord struct {
 	execID      string
 	taskID      uint64
@@ -4,6 +4,7 @@ import (
 	"context"
 
 	"github.com/gameap/daemon/internal/app/config"
+	"github.com/gameap/daemon/internal/app/contracts"
 	"github.com/gameap/daemon/internal/app/domain"
 	gameservercommands "github.com/gameap/daemon/internal/app/game_server_commands"
 	gdaemonscheduler "github.com/gameap/daemon/internal/app/gdaemon_scheduler"
@@ -21,9 +22,11 @@ type Runner struct {
 	commandFactory    *gameservercommands.ServerCommandFactory
 	gdTaskManager     *gdaemonscheduler.TaskManager
 	serverRepository  domain.ServerRepository
+	processManager    contracts.ProcessManager
 	serversScheduler  *serversscheduler.Scheduler
 	connectionManager *grpcclient.ConnectionManager
 	statusReporter    *grpcclient.ServerStatusReporter
+	readinessChecker  serversloop.ReadinessChecker
 }
 
 func NewProcessRunner(
@@ -31,12 +34,14 @@ func NewProcessRunner(
 	commandFactory *gameservercommands.ServerCommandFactory,
 	gdTaskManager *gdaemonscheduler.TaskManager,
 	serverRepository domain.ServerRepository,
+	processManager contracts.ProcessManager,
 ) (*Runner, error) {
 	return &Runner{
 		cfg:              cfg,
 		commandFactory:   commandFactory,
 		gdTaskManager:    gdTaskManager,
 		serverRepository: serverRepository,
+		processManager:   processManager,
 	}, nil
 }
 
@@ -44,6 +49,10 @@ func (r *Runner) SetServersScheduler(scheduler *serversscheduler.Scheduler) {
 	r.serversScheduler = scheduler
 }
 
+func (r *Runner) SetReadinessChecker(checker serversloop.ReadinessChecker) {
+	r.readinessChecker = checker
+}
+
 func (r *Runner) SetGRPCComponents(
 	connectionManager *grpcclient.ConnectionManager,
 	statusReporter *grpcclient.ServerStatusReporter,
@@ -112,6 +121,14 @@ func (r *Runner) RunServersLoop(ctx context.Context, cfg *config.Config) func()
 			r.statusReporter.Start(ctx)
 		}
 
+		if r.readinessChecker != nil {
+			loop.SetReadinessChecker(r.readinessChecker)
+		}
+
+		if reader, ok := r.processManager.(contracts.ExitCodeReader); ok {
+			loop.SetExitCodeReader(reader)
+		}
+
 		ctx = logger.WithLogger(ctx, logger.Logger(ctx).WithFields(log.Fields{
 			"service": "servers loop",
 		}))
@@ -0,0 +1,189 @@
+package sftpserver
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gameap/daemon/internal/app/config"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/crypto/ssh"
+)
+
+// The server variables the panel sets the login of a server with.
+const (
+	varUsername     = "sftp_username"
+	varPasswordHash = "sftp_password_hash"
+	varPublicKeys   = "sftp_public_keys"
+	varReadOnly     = "sftp_read_only"
+)
+
+var (
+	errAuthFailed    = errors.New("authentication failed")
+	errUnknownUser   = errors.New("unknown user")
+	errAmbiguousUser = errors.New("username is set on several servers")
+)
+
+// dummyPasswordHash is compared with the password of a login without a
+// password hash, so it takes as long to reject as a wrong password.
+var dummyPasswordHash = sync.OnceValue(func() []byte {
+	hash, err := bcrypt.GenerateFromPassword([]byte(rand.Text()), bcrypt.DefaultCost)
+	if err != nil {
+		log.WithError(err).Warn("Failed to generate the dummy SFTP password hash")
+	}
+
+	return hash
+})
+
+// account is a login of the SFTP server.
+type account struct {
+	username     string
+	serverID     int
+	passwordHash string
+	publicKeys   []ssh.PublicKey
+	readOnly     bool
+}
+
+func (acc *account) checkPassword(password []byte) bool {
+	if acc.passwordHash == "" {
+		_ = bcrypt.CompareHashAndPassword(dummyPasswordHash(), password)
+
+		return false
+	}
+
+	return bcrypt.CompareHashAndPassword([]byte(acc.passwordHash), password) == nil
+}
+
+func (acc *account) checkPublicKey(key ssh.PublicKey) bool {
+	marshaled := key.Marshal()
+
+	for _, k := range acc.publicKeys {
+		if bytes.Equal(k.Marshal(), marshaled) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// findAccount returns the login of the username. The users of the config
+// come first, then the server with the sftp_username variable. A username
+// set on several servers logs in to none of them.
+func (s *Server) findAccount(ctx context.Context, username string) (*account, error) {
+	if username == "" {
+		return nil, errUnknownUser
+	}
+
+	for _, u := range s.cfg.SFTP.Users {
+		if u.Username == username {
+			return accountFromConfig(u)
+		}
+	}
+
+	ids, err := s.serverRepo.IDs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		matchID   int
+		matchVars map[string]string
+		matches   []int
+	)
+
+	for _, id := range ids {
+		server, err := s.serverRepo.FindByID(ctx, id)
+		if err != nil || server == nil {
+			continue
+		}
+
+		vars := server.Vars()
+		if vars[varUsername] != username {
+			continue
+		}
+
+		matchID, matchVars = server.ID(), vars
+		matches = append(matches, matchID)
+	}
+
+	switch {
+	case len(matches) == 0:
+		return nil, errUnknownUser
+	case len(matches) > 1:
+		slices.Sort(matches)
+		log.WithFields(log.Fields{
+			"user":       username,
+			"server_ids": matches,
+		}).Errorf("The same %s is set on several servers, the login is rejected", varUsername)
+
+		return nil, errors.Wrapf(errAmbiguousUser, "servers %v", matches)
+	}
+
+	return accountFromVars(username, matchID, matchVars)
+}
+
+func accountFromVars(username string, serverID int, vars map[string]string) (*account, error) {
+	acc := &account{
+		username:     username,
+		serverID:     serverID,
+		passwordHash: vars[varPasswordHash],
+	}
+
+	var err error
+	if acc.publicKeys, err = parsePublicKeys(strings.Split(vars[varPublicKeys], "\n")); err != nil {
+		return nil, errors.WithMessagef(err, "server %d", serverID)
+	}
+
+	if val := vars[varReadOnly]; val != "" {
+		if acc.readOnly, err = strconv.ParseBool(val); err != nil {
+			// A login with a mistyped flag must not be able to write.
+			log.WithField("server_id", serverID).Warnf("Invalid %s, the login is read-only", varReadOnly)
+			acc.readOnly = true
+		}
+	}
+
+	return acc, nil
+}
+
+func accountFromConfig(u config.SFTPUser) (*account, error) {
+	keys, err := parsePublicKeys(u.PublicKeys)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "user %q", u.Username)
+	}
+
+	return &account{
+		username:     u.Username,
+		serverID:     u.ServerID,
+		passwordHash: u.PasswordHash,
+		publicKeys:   keys,
+		readOnly:     u.ReadOnly,
+	}, nil
+}
+
+// parsePublicKeys parses keys in the authorized_keys format, empty lines and
+// comments are skipped.
+func parsePublicKeys(lines []string) ([]ssh.PublicKey, error) {
+	keys := make([]ssh.PublicKey, 0, len(lines))
+
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		key, _, _, _, err := ssh.ParseAuthorizedKey([]byte(line))
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid public key")
+		}
+
+		keys = append(keys, key)
+	}
+
+	return keys, nil
+}
@@ -0,0 +1,319 @@
+package sftpserver
+
+import (
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"sync/atomic"
+
+	"github.com/gameap/daemon/internal/app/fsutil"
+	"github.com/gameap/daemon/internal/app/osowner"
+	"github.com/pkg/errors"
+	"github.com/pkg/sftp"
+	log "github.com/sirupsen/logrus"
+)
+
+// fileSystem serves the SFTP requests of a session in the directory of the
+// game server. Every path is resolved through os.Root, so neither ".." nor a
+// symlink leads out of the directory. Created files and directories are
+// owned by the server user.
+type fileSystem struct {
+	root     *os.Root
+	owner    osowner.Options
+	readOnly bool
+	logger   *log.Entry
+}
+
+func (fsys *fileSystem) handlers() sftp.Handlers {
+	return sftp.Handlers{
+		FileGet:  fsys,
+		FilePut:  fsys,
+		FileCmd:  fsys,
+		FileList: fsys,
+	}
+}
+
+func (fsys *fileSystem) Close() error {
+	return fsys.root.Close()
+}
+
+func (fsys *fileSystem) Fileread(r *sftp.Request) (io.ReaderAt, error) {
+	rel, err := fsutil.RootRel(r.Filepath)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := fsys.root.Open(rel)
+	if err != nil {
+		return nil, err
+	}
+
+	return fsys.track(f, rel), nil
+}
+
+func (fsys *fileSystem) Filewrite(r *sftp.Request) (io.WriterAt, error) {
+	return fsys.OpenFile(r)
+}
+
+// OpenFile opens the file for writing and, with the read flag, for reading.
+func (fsys *fileSystem) OpenFile(r *sftp.Request) (sftp.WriterAtReaderAt, error) {
+	if fsys.readOnly {
+		return nil, sftp.ErrSSHFxPermissionDenied
+	}
+
+	rel, err := fsutil.RootRel(r.Filepath)
+	if err != nil {
+		return nil, err
+	}
+
+	_, statErr := fsys.root.Lstat(rel)
+	created := errors.Is(statErr, os.ErrNotExist)
+
+	f, err := fsys.root.OpenFile(rel, openFlags(r.Pflags()), 0o644)
+	if err != nil {
+		return nil, err
+	}
+
+	if created {
+		if err := osowner.ApplyToPathInRoot(fsys.root, rel, fsys.owner); err != nil {
+			_ = f.Close()
+
+			return nil, errors.Wrap(err, "failed to apply file owner")
+		}
+	}
+
+	return fsys.track(f, rel), nil
+}
+
+// openFlags converts the SFTP open flags. Append is left out, the client
+// writes at offsets anyway and *os.File refuses WriteAt with O_APPEND.
+func openFlags(pflags sftp.FileOpenFlags) int {
+	var flags int
+
+	switch {
+	case pflags.Read && pflags.Write:
+		flags = os.O_RDWR
+	case pflags.Write:
+		flags = os.O_WRONLY
+	default:
+		flags = os.O_RDONLY
+	}
+
+	if pflags.Creat {
+		flags |= os.O_CREATE
+	}
+	if pflags.Trunc {
+		flags |= os.O_TRUNC
+	}
+	if pflags.Excl {
+		flags |= os.O_EXCL
+	}
+
+	return flags
+}
+
+func (fsys *fileSystem) Filecmd(r *sftp.Request) error {
+	if fsys.readOnly {
+		return sftp.ErrSSHFxPermissionDenied
+	}
+
+	rel, err := fsutil.RootRel(r.Filepath)
+	if err != nil {
+		return err
+	}
+
+	fsys.logger.WithFields(log.Fields{
+		"method": r.Method,
+		"path":   rel,
+	}).Debug("SFTP command")
+
+	switch r.Method {
+	case "Setstat":
+		return fsys.setstat(rel, r)
+	case "Rename":
+		return fsys.rename(rel, r.Target, false)
+	case "Rmdir", "Remove":
+		return fsys.root.Remove(rel)
+	case "Mkdir":
+		if err := fsys.root.Mkdir(rel, 0o755); err != nil {
+			return err
+		}
+
+		return osowner.ApplyToPathInRoot(fsys.root, rel, fsys.owner)
+	}
+
+	// Links are not supported, a link could make the same file reachable
+	// from two servers.
+	return sftp.ErrSSHFxOpUnsupported
+}
+
+// PosixRename renames the file replacing the target, the plain SFTP rename
+// refuses an existing target.
+func (fsys *fileSystem) PosixRename(r *sftp.Request) error {
+	if fsys.readOnly {
+		return sftp.ErrSSHFxPermissionDenied
+	}
+
+	rel, err := fsutil.RootRel(r.Filepath)
+	if err != nil {
+		return err
+	}
+
+	return fsys.rename(rel, r.Target, true)
+}
+
+func (fsys *fileSystem) rename(rel, target string, replace bool) error {
+	targetRel, err := fsutil.RootRel(target)
+	if err != nil {
+		return err
+	}
+
+	if !replace {
+		if _, err := fsys.root.Lstat(targetRel); err == nil {
+			return os.ErrExist
+		}
+	}
+
+	return fsys.root.Rename(rel, targetRel)
+}
+
+// setstat changes the size, permissions and times. The owner is not changed,
+// the files of a server stay owned by the server user.
+func (fsys *fileSystem) setstat(rel string, r *sftp.Request) error {
+	flags := r.AttrFlags()
+	attrs := r.Attributes()
+
+	if flags.Size {
+		f, err := fsys.root.OpenFile(rel, os.O_WRONLY, 0)
+		if err != nil {
+			return err
+		}
+
+		err = f.Truncate(int64(attrs.Size)) //nolint:gosec
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if flags.Permissions {
+		if err := fsys.root.Chmod(rel, attrs.FileMode().Perm()); err != nil {
+			return err
+		}
+	}
+
+	if flags.Acmodtime {
+		if err := fsys.root.Chtimes(rel, attrs.AccessTime(), attrs.ModTime()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (fsys *fileSystem) Filelist(r *sftp.Request) (sftp.ListerAt, error) {
+	rel, err := fsutil.RootRel(r.Filepath)
+	if err != nil {
+		return nil, err
+	}
+
+	switch r.Method {
+	case "List":
+		dir, err := fsys.root.Open(rel)
+		if err != nil {
+			return nil, err
+		}
+		defer dir.Close()
+
+		entries, err := dir.Readdir(-1)
+		if err != nil {
+			return nil, err
+		}
+
+		slices.SortFunc(entries, func(a, b os.FileInfo) int {
+			return strings.Compare(a.Name(), b.Name())
+		})
+
+		return listerAt(entries), nil
+	case "Stat":
+		info, err := fsys.root.Stat(rel)
+		if err != nil {
+			return nil, err
+		}
+
+		return listerAt{info}, nil
+	}
+
+	return nil, sftp.ErrSSHFxOpUnsupported
+}
+
+func (fsys *fileSystem) Lstat(r *sftp.Request) (sftp.ListerAt, error) {
+	rel, err := fsutil.RootRel(r.Filepath)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := fsys.root.Lstat(rel)
+	if err != nil {
+		return nil, err
+	}
+
+	return listerAt{info}, nil
+}
+
+type listerAt []os.FileInfo
+
+func (l listerAt) ListAt(ls []os.FileInfo, offset int64) (int, error) {
+	if offset >= int64(len(l)) {
+		return 0, io.EOF
+	}
+
+	n := copy(ls, l[offset:])
+	if n < len(ls) {
+		return n, io.EOF
+	}
+
+	return n, nil
+}
+
+// trackedFile counts the bytes transferred and logs the transfer when the
+// client closes the file.
+type trackedFile struct {
+	*os.File
+
+	path    string
+	logger  *log.Entry
+	read    atomic.Int64
+	written atomic.Int64
+}
+
+func (fsys *fileSystem) track(f *os.File, rel string) *trackedFile {
+	return &trackedFile{File: f, path: rel, logger: fsys.logger}
+}
+
+func (f *trackedFile) ReadAt(b []byte, off int64) (int, error) {
+	n, err := f.File.ReadAt(b, off)
+	f.read.Add(int64(n))
+
+	return n, err
+}
+
+func (f *trackedFile) WriteAt(b []byte, off int64) (int, error) {
+	n, err := f.File.WriteAt(b, off)
+	f.written.Add(int64(n))
+
+	return n, err
+}
+
+func (f *trackedFile) Close() error {
+	f.logger.WithFields(log.Fields{
+		"path":          f.path,
+		"bytes_read":    f.read.Load(),
+		"bytes_written": f.written.Load(),
+	}).Info("SFTP transfer")
+
+	return f.File.Close()
+}
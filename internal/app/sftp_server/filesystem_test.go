@@ -0,0 +1,150 @@
+package sftpserver
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/gameap/daemon/internal/app/osowner"
+	"github.com/pkg/sftp"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// SFTP open flags, see the pflags of SSH_FXP_OPEN.
+const (
+	fxfRead  = 0x01
+	fxfWrite = 0x02
+	fxfCreat = 0x08
+	fxfTrunc = 0x10
+)
+
+func givenFileSystem(t *testing.T, workDir string, readOnly bool) *fileSystem {
+	t.Helper()
+
+	root, err := os.OpenRoot(workDir)
+	require.NoError(t, err)
+
+	fsys := &fileSystem{
+		root:     root,
+		owner:    osowner.Options{},
+		readOnly: readOnly,
+		logger:   log.NewEntry(log.StandardLogger()),
+	}
+	t.Cleanup(func() { _ = fsys.Close() })
+
+	return fsys
+}
+
+func request(method, path string, flags uint32) *sftp.Request {
+	r := sftp.NewRequest(method, path)
+	r.Flags = flags
+
+	return r
+}
+
+func TestFileSystem_WriteAndRead(t *testing.T) {
+	workDir := t.TempDir()
+	fsys := givenFileSystem(t, workDir, false)
+
+	require.NoError(t, fsys.Filecmd(request("Mkdir", "/mods", 0)))
+	w, err := fsys.Filewrite(request("Put", "/mods/plugin.jar", fxfWrite|fxfCreat|fxfTrunc))
+	require.NoError(t, err)
+	_, err = w.WriteAt([]byte("plugin"), 0)
+	require.NoError(t, err)
+	require.NoError(t, w.(io.Closer).Close())
+
+	r, err := fsys.Fileread(request("Get", "/mods/plugin.jar", fxfRead))
+	require.NoError(t, err)
+	buf := make([]byte, 6)
+	_, err = r.ReadAt(buf, 0)
+	require.NoError(t, err)
+	require.NoError(t, r.(io.Closer).Close())
+
+	assert.Equal(t, "plugin", string(buf))
+	assert.FileExists(t, filepath.Join(workDir, "mods", "plugin.jar"))
+}
+
+func TestFileSystem_ListSortedByName(t *testing.T) {
+	workDir := t.TempDir()
+	for _, name := range []string{"world", "server.properties", "banned.json"} {
+		require.NoError(t, os.WriteFile(filepath.Join(workDir, name), nil, 0o600))
+	}
+	fsys := givenFileSystem(t, workDir, true)
+
+	lister, err := fsys.Filelist(request("List", "/", 0))
+	require.NoError(t, err)
+
+	entries := make([]os.FileInfo, 10)
+	n, err := lister.ListAt(entries, 0)
+
+	require.ErrorIs(t, err, io.EOF)
+	require.Equal(t, 3, n)
+	assert.Equal(t, "banned.json", entries[0].Name())
+	assert.Equal(t, "server.properties", entries[1].Name())
+	assert.Equal(t, "world", entries[2].Name())
+}
+
+func TestFileSystem_ReadOnly(t *testing.T) {
+	workDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(workDir, "server.properties"), []byte("motd"), 0o600))
+	fsys := givenFileSystem(t, workDir, true)
+
+	_, err := fsys.Filewrite(request("Put", "/server.properties", fxfWrite|fxfTrunc))
+	require.ErrorIs(t, err, sftp.ErrSSHFxPermissionDenied)
+
+	err = fsys.Filecmd(request("Remove", "/server.properties", 0))
+	require.ErrorIs(t, err, sftp.ErrSSHFxPermissionDenied)
+
+	_, err = fsys.Fileread(request("Get", "/server.properties", fxfRead))
+	require.NoError(t, err)
+	assert.FileExists(t, filepath.Join(workDir, "server.properties"))
+}
+
+func TestFileSystem_RenameKeepsExistingTarget(t *testing.T) {
+	workDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(workDir, "a.cfg"), []byte("a"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(workDir, "b.cfg"), []byte("b"), 0o600))
+	fsys := givenFileSystem(t, workDir, false)
+
+	rename := request("Rename", "/a.cfg", 0)
+	rename.Target = "/b.cfg"
+	require.ErrorIs(t, fsys.Filecmd(rename), os.ErrExist)
+
+	require.NoError(t, fsys.PosixRename(rename))
+
+	content, err := os.ReadFile(filepath.Join(workDir, "b.cfg"))
+	require.NoError(t, err)
+	assert.Equal(t, "a", string(content))
+}
+
+func TestFileSystem_SymlinkEscapeBlocked(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symlink creation needs privilege on Windows")
+	}
+
+	base := t.TempDir()
+	workDir := filepath.Join(base, "work")
+	secret := filepath.Join(base, "secret")
+	require.NoError(t, os.MkdirAll(workDir, 0o755))
+	require.NoError(t, os.MkdirAll(secret, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(secret, "passwd"), []byte("TOPSECRET"), 0o600))
+	require.NoError(t, os.Symlink(secret, filepath.Join(workDir, "escape")))
+	fsys := givenFileSystem(t, workDir, false)
+
+	_, err := fsys.Fileread(request("Get", "/escape/passwd", fxfRead))
+	require.Error(t, err, "must not read a file outside the server directory")
+
+	_, err = fsys.Filewrite(request("Put", "/escape/evil.txt", fxfWrite|fxfCreat))
+	require.Error(t, err, "must not write through an escaping symlink")
+	assert.NoFileExists(t, filepath.Join(secret, "evil.txt"))
+
+	_, err = fsys.Filelist(request("List", "/escape", 0))
+	require.Error(t, err, "must not list a directory outside the server directory")
+
+	err = fsys.Filecmd(request("Symlink", "/link", 0))
+	require.ErrorIs(t, err, sftp.ErrSSHFxOpUnsupported)
+}
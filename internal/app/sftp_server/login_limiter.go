@@ -0,0 +1,105 @@
+package sftpserver
+
+import (
+	"net"
+	"sync"
+	"time"
+)
+
+const (
+	// maxLoginFailures failed logins from one address within
+	// loginFailureWindow lock the address out until the window is over.
+	maxLoginFailures   = 10
+	loginFailureWindow = 5 * time.Minute
+)
+
+// loginLimiter counts the failed logins per remote address, so passwords are
+// not guessed faster than maxLoginFailures per loginFailureWindow.
+type loginLimiter struct {
+	mu        sync.Mutex
+	failures  map[string]*loginFailures
+	lastPrune time.Time
+
+	nowFn func() time.Time
+}
+
+type loginFailures struct {
+	count int
+	since time.Time
+}
+
+func newLoginLimiter() *loginLimiter {
+	return &loginLimiter{
+		failures: make(map[string]*loginFailures),
+		nowFn:    time.Now,
+	}
+}
+
+// allowed reports whether logins from the address are accepted.
+func (l *loginLimiter) allowed(addr net.Addr) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	f, ok := l.failures[remoteHost(addr)]
+
+	return !ok || l.expired(f) || f.count < maxLoginFailures
+}
+
+// fail records a failed login from the address.
+func (l *loginLimiter) fail(addr net.Addr) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.prune()
+
+	host := remoteHost(addr)
+
+	f, ok := l.failures[host]
+	if !ok || l.expired(f) {
+		f = &loginFailures{since: l.nowFn()}
+		l.failures[host] = f
+	}
+
+	f.count++
+}
+
+// succeed forgets the failed logins of the address.
+func (l *loginLimiter) succeed(addr net.Addr) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	delete(l.failures, remoteHost(addr))
+}
+
+func (l *loginLimiter) expired(f *loginFailures) bool {
+	return l.nowFn().Sub(f.since) >= loginFailureWindow
+}
+
+// prune drops the expired failures, at most once per window.
+func (l *loginLimiter) prune() {
+	if l.nowFn().Sub(l.lastPrune) < loginFailureWindow {
+		return
+	}
+	l.lastPrune = l.nowFn()
+
+	for host, f := range l.failures {
+		if l.expired(f) {
+			delete(l.failures, host)
+		}
+	}
+}
+
+// remoteHost returns the address without the port, the connections of a
+// client come from different ports.
+func remoteHost(addr net.Addr) string {
+	if tcp, ok := addr.(*net.TCPAddr); ok {
+		return tcp.IP.String()
+	}
+
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return addr.String()
+	}
+
+	return host
+}
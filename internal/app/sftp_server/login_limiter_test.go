@@ -0,0 +1,32 @@
+package sftpserver
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoginLimiter(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	limiter := newLoginLimiter()
+	limiter.nowFn = func() time.Time { return now }
+	attacker := &net.TCPAddr{IP: net.IPv4(203, 0, 113, 7), Port: 50000}
+	other := &net.TCPAddr{IP: net.IPv4(203, 0, 113, 8), Port: 50000}
+
+	for i := 0; i < maxLoginFailures; i++ {
+		assert.True(t, limiter.allowed(attacker))
+		limiter.fail(&net.TCPAddr{IP: attacker.IP, Port: 50000 + i})
+	}
+
+	assert.False(t, limiter.allowed(attacker), "the failures of every port count")
+	assert.True(t, limiter.allowed(other))
+
+	now = now.Add(loginFailureWindow)
+	assert.True(t, limiter.allowed(attacker), "the lockout ends with the window")
+
+	limiter.fail(attacker)
+	limiter.succeed(attacker)
+	assert.NotContains(t, limiter.failures, "203.0.113.7")
+}
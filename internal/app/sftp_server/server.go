@@ -0,0 +1,373 @@
+// Package sftpserver implements the built-in SFTP server. Every login is
+// mapped to a game server and works in the directory of the server only,
+// through os.Root as the gRPC file handler does.
+package sftpserver
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gameap/daemon/internal/app/config"
+	"github.com/gameap/daemon/internal/app/domain"
+	"github.com/gameap/daemon/internal/app/osowner"
+	"github.com/pkg/errors"
+	"github.com/pkg/sftp"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	serverVersion = "SSH-2.0-GameAP"
+	maxAuthTries  = 6
+
+	// handshakeTimeout bounds the handshake and the login of a connection.
+	handshakeTimeout = 30 * time.Second
+
+	extServerID = "server-id"
+	extReadOnly = "read-only"
+)
+
+var errServerNotFound = errors.New("server not found")
+
+type Server struct {
+	cfg        *config.Config
+	serverRepo domain.ServerRepository
+	logins     *loginLimiter
+
+	handshakeTimeout time.Duration
+}
+
+func NewServer(cfg *config.Config, serverRepo domain.ServerRepository) *Server {
+	return &Server{
+		cfg:              cfg,
+		serverRepo:       serverRepo,
+		logins:           newLoginLimiter(),
+		handshakeTimeout: handshakeTimeout,
+	}
+}
+
+// Run serves SFTP until ctx is done, the open connections are closed then.
+func (s *Server) Run(ctx context.Context) error {
+	sshConfig, err := s.sshConfig(ctx)
+	if err != nil {
+		return err
+	}
+
+	listener, err := net.Listen("tcp", s.cfg.SFTP.Listen)
+	if err != nil {
+		return errors.Wrap(err, "failed to listen sftp address")
+	}
+
+	log.WithField("address", listener.Addr().String()).Info("SFTP server listening")
+
+	return s.serve(ctx, listener, sshConfig)
+}
+
+func (s *Server) serve(ctx context.Context, listener net.Listener, sshConfig *ssh.ServerConfig) error {
+	go func() {
+		<-ctx.Done()
+		_ = listener.Close()
+	}()
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+
+			return errors.Wrap(err, "sftp server stopped")
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.serveConn(ctx, conn, sshConfig)
+		}()
+	}
+}
+
+func (s *Server) sshConfig(ctx context.Context) (*ssh.ServerConfig, error) {
+	hostKey, err := loadHostKey(s.cfg.SFTP.HostKey)
+	if err != nil {
+		return nil, err
+	}
+
+	sshConfig := &ssh.ServerConfig{
+		ServerVersion: serverVersion,
+		MaxAuthTries:  maxAuthTries,
+		PasswordCallback: func(meta ssh.ConnMetadata, password []byte) (*ssh.Permissions, error) {
+			perms, err := s.authenticate(ctx, meta, func(acc *account) bool {
+				return acc.checkPassword(password)
+			})
+			if err != nil {
+				s.logins.fail(meta.RemoteAddr())
+			}
+
+			return perms, err
+		},
+		// Offered keys which do not match are not failed logins: clients try
+		// every key of their agent. The callback also answers whether a key
+		// would be accepted before the client proves it holds the key, so the
+		// failures are forgotten only once the login completes.
+		PublicKeyCallback: func(meta ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			return s.authenticate(ctx, meta, func(acc *account) bool {
+				return acc.checkPublicKey(key)
+			})
+		},
+	}
+	sshConfig.AddHostKey(hostKey)
+
+	return sshConfig, nil
+}
+
+func (s *Server) authenticate(
+	ctx context.Context, meta ssh.ConnMetadata, check func(acc *account) bool,
+) (*ssh.Permissions, error) {
+	if !s.logins.allowed(meta.RemoteAddr()) {
+		return nil, errors.Wrap(errAuthFailed, "too many failed logins")
+	}
+
+	acc, err := s.findAccount(ctx, meta.User())
+	if err != nil {
+		// An unknown user is checked against an account without credentials,
+		// so it takes as long to reject as a wrong password.
+		check(&account{})
+	} else if !check(acc) {
+		err = errAuthFailed
+	}
+	if err != nil {
+		log.WithFields(log.Fields{
+			"user":   meta.User(),
+			"remote": meta.RemoteAddr().String(),
+		}).WithError(err).Debug("SFTP authentication failed")
+
+		return nil, errAuthFailed
+	}
+
+	return &ssh.Permissions{
+		Extensions: map[string]string{
+			extServerID: strconv.Itoa(acc.serverID),
+			extReadOnly: strconv.FormatBool(acc.readOnly),
+		},
+	}, nil
+}
+
+func (s *Server) serveConn(ctx context.Context, conn net.Conn, sshConfig *ssh.ServerConfig) {
+	conn = &idleConn{Conn: conn, timeout: s.cfg.SFTP.IdleTimeout}
+	defer conn.Close()
+
+	if !s.logins.allowed(conn.RemoteAddr()) {
+		log.WithField("remote", conn.RemoteAddr().String()).Info("SFTP connection refused, too many failed logins")
+		return
+	}
+
+	// The idle timeout is extended by every packet, it does not stop a client
+	// from dragging the handshake out.
+	handshake := time.AfterFunc(s.handshakeTimeout, func() { _ = conn.Close() })
+	sshConn, channels, requests, err := ssh.NewServerConn(conn, sshConfig)
+	handshake.Stop()
+	if err != nil {
+		log.WithField("remote", conn.RemoteAddr().String()).WithError(err).Info("SFTP login failed")
+		return
+	}
+	defer sshConn.Close()
+
+	s.logins.succeed(conn.RemoteAddr())
+
+	stop := context.AfterFunc(ctx, func() { _ = sshConn.Close() })
+	defer stop()
+
+	serverID, _ := strconv.Atoi(sshConn.Permissions.Extensions[extServerID])
+	readOnly, _ := strconv.ParseBool(sshConn.Permissions.Extensions[extReadOnly])
+
+	logger := log.WithFields(log.Fields{
+		"user":      sshConn.User(),
+		"server_id": serverID,
+		"remote":    sshConn.RemoteAddr().String(),
+	})
+	logger.WithField("read_only", readOnly).Info("SFTP login")
+
+	started := time.Now()
+	defer func() {
+		logger.WithField("duration", time.Since(started).Round(time.Second).String()).
+			Info("SFTP connection closed")
+	}()
+
+	go ssh.DiscardRequests(requests)
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	for newChannel := range channels {
+		if newChannel.ChannelType() != "session" {
+			_ = newChannel.Reject(ssh.UnknownChannelType, "unknown channel type")
+			continue
+		}
+
+		channel, channelRequests, err := newChannel.Accept()
+		if err != nil {
+			logger.WithError(err).Warn("Failed to accept SFTP channel")
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.serveSession(ctx, channel, channelRequests, serverID, readOnly, logger)
+		}()
+	}
+}
+
+// serveSession starts the sftp subsystem of the session, it is the only
+// request served.
+func (s *Server) serveSession(
+	ctx context.Context,
+	channel ssh.Channel,
+	requests <-chan *ssh.Request,
+	serverID int,
+	readOnly bool,
+	logger *log.Entry,
+) {
+	defer channel.Close()
+
+	for req := range requests {
+		var subsystem struct{ Name string }
+		if req.Type != "subsystem" || ssh.Unmarshal(req.Payload, &subsystem) != nil || subsystem.Name != "sftp" {
+			_ = req.Reply(false, nil)
+			continue
+		}
+
+		fsys, err := s.openFileSystem(ctx, serverID, readOnly, logger)
+		if err != nil {
+			logger.WithError(err).Warn("Failed to open server directory for SFTP")
+			_ = req.Reply(false, nil)
+
+			return
+		}
+
+		_ = req.Reply(true, nil)
+		go ssh.DiscardRequests(requests)
+
+		server := sftp.NewRequestServer(channel, fsys.handlers())
+		if err := server.Serve(); err != nil && !errors.Is(err, net.ErrClosed) {
+			logger.WithError(err).Debug("SFTP session ended")
+		}
+
+		_ = server.Close()
+		_ = fsys.Close()
+
+		return
+	}
+}
+
+func (s *Server) openFileSystem(
+	ctx context.Context, serverID int, readOnly bool, logger *log.Entry,
+) (*fileSystem, error) {
+	server, err := s.serverRepo.FindByID(ctx, serverID)
+	if err != nil {
+		return nil, err
+	}
+	if server == nil {
+		return nil, errors.Wrapf(errServerNotFound, "server %d", serverID)
+	}
+
+	root, err := os.OpenRoot(server.WorkDir(s.cfg))
+	if err != nil {
+		return nil, errors.Wrap(err, "work directory unavailable")
+	}
+
+	return &fileSystem{
+		root:     root,
+		owner:    osowner.Options{User: server.User()},
+		readOnly: readOnly,
+		logger:   logger,
+	}, nil
+}
+
+// loadHostKey reads the host key, an ed25519 key is generated when the file
+// does not exist.
+func loadHostKey(path string) (ssh.Signer, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		data, err = generateHostKey(path)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read sftp host key")
+	}
+
+	signer, err := ssh.ParsePrivateKey(data)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse sftp host key")
+	}
+
+	return signer, nil
+}
+
+func generateHostKey(path string) ([]byte, error) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := ssh.MarshalPrivateKey(key, "")
+	if err != nil {
+		return nil, err
+	}
+
+	data := pem.EncodeToMemory(block)
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return nil, err
+	}
+
+	log.WithField("path", path).Info("SFTP host key generated")
+
+	return data, nil
+}
+
+// idleConn closes the connection when nothing is read or written for the
+// timeout, none when it is not set.
+type idleConn struct {
+	net.Conn
+
+	timeout time.Duration
+}
+
+func (c *idleConn) Read(b []byte) (int, error) {
+	if err := c.extend(); err != nil {
+		return 0, err
+	}
+
+	return c.Conn.Read(b)
+}
+
+func (c *idleConn) Write(b []byte) (int, error) {
+	if err := c.extend(); err != nil {
+		return 0, err
+	}
+
+	return c.Conn.Write(b)
+}
+
+func (c *idleConn) extend() error {
+	if c.timeout <= 0 {
+		return nil
+	}
+
+	return c.Conn.SetDeadline(time.Now().Add(c.timeout))
+}
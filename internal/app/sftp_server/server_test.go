@@ -0,0 +1,300 @@
+package sftpserver
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gameap/daemon/internal/app/config"
+	"github.com/gameap/daemon/internal/app/domain"
+	"github.com/pkg/sftp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/crypto/ssh"
+)
+
+type fakeServerRepo struct {
+	servers map[int]*domain.Server
+}
+
+func (r *fakeServerRepo) IDs(_ context.Context) ([]int, error) {
+	ids := make([]int, 0, len(r.servers))
+	for id := range r.servers {
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
+
+func (r *fakeServerRepo) FindByID(_ context.Context, id int) (*domain.Server, error) {
+	return r.servers[id], nil
+}
+
+func (r *fakeServerRepo) Save(_ context.Context, _ *domain.Server) error {
+	return nil
+}
+
+func givenServer(id int, dir string, vars map[string]string) *domain.Server {
+	return domain.NewServer(
+		id, true, domain.ServerInstalled, false,
+		"server", "uuid", "uuid-short",
+		domain.Game{}, domain.GameMod{},
+		"127.0.0.1", 27015, 27015, 27015, "",
+		dir, "",
+		"", "", "", "",
+		false, time.Unix(0, 0),
+		vars, domain.Settings{}, time.Unix(0, 0),
+		0, 0,
+	)
+}
+
+func givenPasswordHash(t *testing.T, password string) string {
+	t.Helper()
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	return string(hash)
+}
+
+func TestServer_UploadAndDownload(t *testing.T) {
+	workPath := t.TempDir()
+	serverDir := filepath.Join(workPath, "servers", "7")
+	require.NoError(t, os.MkdirAll(serverDir, 0o755))
+
+	cfg := &config.Config{WorkPath: workPath}
+	cfg.SFTP.HostKey = filepath.Join(t.TempDir(), "host_key")
+	cfg.SFTP.IdleTimeout = time.Minute
+	cfg.SFTP.Users = []config.SFTPUser{
+		{Username: "alice", ServerID: 7, PasswordHash: givenPasswordHash(t, "secret")},
+	}
+	server := NewServer(cfg, &fakeServerRepo{servers: map[int]*domain.Server{
+		7: givenServer(7, "servers/7", nil),
+	}})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sshConfig, err := server.sshConfig(ctx)
+	require.NoError(t, err)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	done := make(chan error, 1)
+	go func() { done <- server.serve(ctx, listener, sshConfig) }()
+	server.logins.fail(&net.TCPAddr{IP: net.IPv4(127, 0, 0, 1)})
+
+	client, err := ssh.Dial("tcp", listener.Addr().String(), &ssh.ClientConfig{
+		User:            "alice",
+		Auth:            []ssh.AuthMethod{ssh.Password("secret")},
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(), //nolint:gosec
+	})
+	require.NoError(t, err)
+	sftpClient, err := sftp.NewClient(client)
+	require.NoError(t, err)
+	assert.True(t, server.logins.allowed(&net.TCPAddr{IP: net.IPv4(127, 0, 0, 1)}))
+	assert.Empty(t, server.logins.failures, "a completed login forgets the failures")
+
+	f, err := sftpClient.Create("/server.properties")
+	require.NoError(t, err)
+	_, err = f.Write([]byte("motd=hello"))
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	f, err = sftpClient.Open("/../../server.properties")
+	require.NoError(t, err)
+	content, err := io.ReadAll(f)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	assert.Equal(t, "motd=hello", string(content))
+	assert.FileExists(t, filepath.Join(serverDir, "server.properties"))
+	assert.NoFileExists(t, filepath.Join(workPath, "server.properties"))
+
+	_ = sftpClient.Close()
+	_ = client.Close()
+	cancel()
+	require.NoError(t, <-done)
+	assert.FileExists(t, cfg.SFTP.HostKey, "the host key is generated")
+}
+
+func TestServer_WrongPasswordRejected(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.SFTP.Users = []config.SFTPUser{
+		{Username: "alice", ServerID: 7, PasswordHash: givenPasswordHash(t, "secret")},
+	}
+	server := NewServer(cfg, &fakeServerRepo{})
+
+	_, err := server.authenticate(context.Background(), connMeta("alice"), func(acc *account) bool {
+		return acc.checkPassword([]byte("guess"))
+	})
+
+	require.ErrorIs(t, err, errAuthFailed)
+}
+
+func TestServer_UnknownUserIsChecked(t *testing.T) {
+	server := NewServer(&config.Config{}, &fakeServerRepo{})
+	checked := false
+
+	_, err := server.authenticate(context.Background(), connMeta("mallory"), func(acc *account) bool {
+		checked = true
+
+		return acc.checkPassword([]byte("guess"))
+	})
+
+	require.ErrorIs(t, err, errAuthFailed)
+	assert.True(t, checked, "an unknown user takes as long to reject as a wrong password")
+}
+
+func TestServer_FailedLoginsAreLimited(t *testing.T) {
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	key, err := ssh.NewPublicKey(pub)
+	require.NoError(t, err)
+	otherPub, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	otherKey, err := ssh.NewPublicKey(otherPub)
+	require.NoError(t, err)
+
+	cfg := &config.Config{}
+	cfg.SFTP.HostKey = filepath.Join(t.TempDir(), "host_key")
+	cfg.SFTP.Users = []config.SFTPUser{{
+		Username:     "alice",
+		ServerID:     7,
+		PasswordHash: givenPasswordHash(t, "secret"),
+		PublicKeys:   []string{string(ssh.MarshalAuthorizedKey(key))},
+	}}
+	server := NewServer(cfg, &fakeServerRepo{})
+	sshConfig, err := server.sshConfig(context.Background())
+	require.NoError(t, err)
+	login := func(password string) error {
+		_, err := sshConfig.PasswordCallback(connMeta("alice"), []byte(password))
+
+		return err
+	}
+
+	for i := 0; i < maxLoginFailures-1; i++ {
+		require.ErrorIs(t, login("guess"), errAuthFailed)
+	}
+
+	// Neither a key of the agent that does not match nor the query whether
+	// a known public key would be accepted change the failures.
+	for i := 0; i < maxLoginFailures; i++ {
+		_, err = sshConfig.PublicKeyCallback(connMeta("alice"), otherKey)
+		require.ErrorIs(t, err, errAuthFailed)
+	}
+	_, err = sshConfig.PublicKeyCallback(connMeta("alice"), key)
+	require.NoError(t, err)
+
+	require.ErrorIs(t, login("guess"), errAuthFailed)
+	require.ErrorIs(t, login("secret"), errAuthFailed)
+}
+
+func TestServer_HandshakeTimeout(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.SFTP.HostKey = filepath.Join(t.TempDir(), "host_key")
+	server := NewServer(cfg, &fakeServerRepo{})
+	server.handshakeTimeout = 50 * time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sshConfig, err := server.sshConfig(ctx)
+	require.NoError(t, err)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	done := make(chan error, 1)
+	go func() { done <- server.serve(ctx, listener, sshConfig) }()
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+
+	_, err = io.ReadAll(conn)
+
+	require.NoError(t, err, "the server closes the connection without a handshake")
+
+	cancel()
+	require.NoError(t, <-done)
+}
+
+func TestFindAccount_ServerVariables(t *testing.T) {
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	key, err := ssh.NewPublicKey(pub)
+	require.NoError(t, err)
+
+	server := NewServer(&config.Config{}, &fakeServerRepo{servers: map[int]*domain.Server{
+		3: givenServer(3, "servers/3", nil),
+		4: givenServer(4, "servers/4", map[string]string{
+			varUsername:     "srv4",
+			varPasswordHash: givenPasswordHash(t, "pass"),
+			varPublicKeys:   "# deploy key\n" + string(ssh.MarshalAuthorizedKey(key)),
+			varReadOnly:     "yes",
+		}),
+	}})
+
+	acc, err := server.findAccount(context.Background(), "srv4")
+
+	require.NoError(t, err)
+	assert.Equal(t, 4, acc.serverID)
+	assert.True(t, acc.checkPassword([]byte("pass")))
+	assert.False(t, acc.checkPassword([]byte("other")))
+	assert.True(t, acc.checkPublicKey(key))
+	assert.True(t, acc.readOnly, "an invalid flag makes the login read-only")
+
+	_, err = server.findAccount(context.Background(), "srv3")
+	require.ErrorIs(t, err, errUnknownUser)
+
+	_, err = server.findAccount(context.Background(), "")
+	require.ErrorIs(t, err, errUnknownUser)
+}
+
+func TestFindAccount_UsernameOnSeveralServers(t *testing.T) {
+	server := NewServer(&config.Config{}, &fakeServerRepo{servers: map[int]*domain.Server{
+		4: givenServer(4, "servers/4", map[string]string{varUsername: "srv", varPasswordHash: "hash"}),
+		5: givenServer(5, "servers/5", map[string]string{varUsername: "srv", varPasswordHash: "hash"}),
+		6: givenServer(6, "servers/6", map[string]string{varUsername: "other"}),
+	}})
+
+	for range 5 {
+		_, err := server.findAccount(context.Background(), "srv")
+
+		require.ErrorIs(t, err, errAmbiguousUser)
+		assert.Contains(t, err.Error(), "[4 5]")
+	}
+
+	acc, err := server.findAccount(context.Background(), "other")
+	require.NoError(t, err)
+	assert.Equal(t, 6, acc.serverID)
+}
+
+func TestFindAccount_ConfigTakesPrecedence(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.SFTP.Users = []config.SFTPUser{
+		{Username: "srv4", ServerID: 9, PasswordHash: givenPasswordHash(t, "pass"), ReadOnly: true},
+	}
+	server := NewServer(cfg, &fakeServerRepo{servers: map[int]*domain.Server{
+		4: givenServer(4, "servers/4", map[string]string{varUsername: "srv4"}),
+	}})
+
+	acc, err := server.findAccount(context.Background(), "srv4")
+
+	require.NoError(t, err)
+	assert.Equal(t, 9, acc.serverID)
+	assert.True(t, acc.readOnly)
+}
+
+type connMeta string
+
+func (m connMeta) User() string          { return string(m) }
+func (m connMeta) SessionID() []byte     { return nil }
+func (m connMeta) ClientVersion() []byte { return nil }
+func (m connMeta) ServerVersion() []byte { return nil }
+func (m connMeta) RemoteAddr() net.Addr  { return &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1)} }
+func (m connMeta) LocalAddr() net.Addr   { return &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1)} }
@@ -0,0 +1,80 @@
+// Package statefile stores the daemon local state (servers, games, scheduled
+// tasks) as versioned JSON documents under the configured state path. The
+// state lets the daemon keep managing servers after a restart while the
+// panel is unreachable.
+package statefile
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/gameap/daemon/internal/app/fsutil"
+	"github.com/pkg/errors"
+)
+
+// ErrUnsupportedVersion is returned by Load when the file was written by an
+// incompatible daemon version.
+var ErrUnsupportedVersion = errors.New("unsupported state file version")
+
+type envelope struct {
+	Version int             `json:"version"`
+	SavedAt time.Time       `json:"saved_at"`
+	Data    json.RawMessage `json:"data"`
+}
+
+// Load decodes the document stored at path into v. It reports false, without
+// an error, when the file does not exist yet.
+func Load(path string, version int, v any) (bool, error) {
+	raw, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, errors.Wrap(err, "failed to read state file")
+	}
+
+	var env envelope
+	if err = json.Unmarshal(raw, &env); err != nil {
+		return false, errors.Wrapf(err, "failed to decode state file %s", path)
+	}
+
+	if env.Version != version {
+		return false, errors.Wrapf(ErrUnsupportedVersion, "%s has version %d, expected %d", path, env.Version, version)
+	}
+
+	if err = json.Unmarshal(env.Data, v); err != nil {
+		return false, errors.Wrapf(err, "failed to decode state file %s", path)
+	}
+
+	return true, nil
+}
+
+// Save atomically replaces the document stored at path with v. The file may
+// hold secrets (RCON passwords), so it is only accessible by the daemon user.
+func Save(path string, version int, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return errors.Wrap(err, "failed to encode state")
+	}
+
+	raw, err := json.MarshalIndent(envelope{
+		Version: version,
+		SavedAt: time.Now(),
+		Data:    data,
+	}, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "failed to encode state")
+	}
+
+	if err = os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return errors.Wrap(err, "failed to create state directory")
+	}
+
+	if err = fsutil.WriteFileAtomic(path, raw, 0o600); err != nil {
+		return errors.WithMessagef(err, "failed to write state file %s", path)
+	}
+
+	return nil
+}
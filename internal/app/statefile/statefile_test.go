@@ -0,0 +1,62 @@
+package statefile
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testState struct {
+	Names []string `json:"names"`
+}
+
+func TestSaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "state.json")
+
+	require.NoError(t, Save(path, 1, testState{Names: []string{"a", "b"}}))
+
+	var got testState
+	found, err := Load(path, 1, &got)
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, []string{"a", "b"}, got.Names)
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	entries, err := os.ReadDir(filepath.Dir(path))
+	require.NoError(t, err)
+	assert.Len(t, entries, 1, "temporary file must not be left behind")
+}
+
+func TestLoad_MissingFile(t *testing.T) {
+	var got testState
+	found, err := Load(filepath.Join(t.TempDir(), "state.json"), 1, &got)
+
+	require.NoError(t, err)
+	assert.False(t, found)
+}
+
+func TestLoad_UnsupportedVersion(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	require.NoError(t, Save(path, 2, testState{}))
+
+	var got testState
+	_, err := Load(path, 1, &got)
+
+	require.ErrorIs(t, err, ErrUnsupportedVersion)
+}
+
+func TestLoad_CorruptedFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"version":1,"data":{"names":[`), 0o600))
+
+	var got testState
+	_, err := Load(path, 1, &got)
+
+	require.Error(t, err)
+}
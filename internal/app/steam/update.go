@@ -0,0 +1,249 @@
+// Package steam checks whether the game of a server installed with steamcmd
+// has a newer build on Steam.
+package steam
+
+import (
+	"context"
+	"os"
+	"os/user"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gameap/daemon/internal/app/config"
+	"github.com/gameap/daemon/internal/app/contracts"
+	"github.com/gameap/daemon/internal/app/domain"
+	"github.com/pkg/errors"
+)
+
+// latestBuildTTL is how long the latest build of an app is cached, every
+// check runs steamcmd otherwise.
+const latestBuildTTL = 10 * time.Minute
+
+const publicBranch = "public"
+
+var (
+	ErrNotSteamGame = errors.New("game is not installed from steam")
+	ErrNoBuild      = errors.New("no build id")
+)
+
+var betaRegexp = regexp.MustCompile(`-beta\s+(\S+)`)
+
+type latestBuild struct {
+	buildID   string
+	checkedAt time.Time
+}
+
+// UpdateChecker compares the build of the installed app with the latest
+// build of its branch on Steam.
+type UpdateChecker struct {
+	cfg      *config.Config
+	executor contracts.Executor
+
+	nowFn func() time.Time
+
+	mu     sync.Mutex
+	latest map[string]latestBuild
+}
+
+func NewUpdateChecker(cfg *config.Config, executor contracts.Executor) *UpdateChecker {
+	return &UpdateChecker{
+		cfg:      cfg,
+		executor: executor,
+		nowFn:    time.Now,
+		latest:   make(map[string]latestBuild),
+	}
+}
+
+// UpdateAvailable reports whether Steam has a build of the server app other
+// than the installed one.
+func (c *UpdateChecker) UpdateAvailable(ctx context.Context, server *domain.Server) (bool, error) {
+	appID := server.Game().SteamAppID
+	if appID <= 0 {
+		return false, ErrNotSteamGame
+	}
+
+	installed, branch, err := InstalledBuild(server.WorkDir(c.cfg), appID)
+	if err != nil {
+		return false, err
+	}
+
+	if branch == "" {
+		branch = branchFromAppSetConfig(server.Game().SteamAppSetConfig)
+	}
+
+	latest, err := c.LatestBuild(ctx, server, appID, branch)
+	if err != nil {
+		return false, err
+	}
+
+	return latest != installed, nil
+}
+
+// InstalledBuild reads the build id and the beta branch of the app from its
+// steamcmd manifest in the server directory.
+func InstalledBuild(workDir string, appID domain.SteamAppID) (string, string, error) {
+	path := filepath.Join(workDir, "steamapps", "appmanifest_"+appID.String()+".acf")
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", "", errors.Wrap(err, "failed to read app manifest")
+	}
+
+	manifest, err := parseVDF(string(data))
+	if err != nil {
+		return "", "", errors.WithMessagef(err, "failed to parse %s", path)
+	}
+
+	buildID, ok := manifest.lookup("AppState", "buildid")
+	if !ok || buildID == "" {
+		return "", "", errors.Wrapf(ErrNoBuild, "%s", path)
+	}
+
+	branch, _ := manifest.lookup("AppState", "UserConfig", "BetaKey")
+
+	return buildID, branch, nil
+}
+
+// LatestBuild returns the latest build id of the branch of the app, as
+// reported by steamcmd app_info_print.
+func (c *UpdateChecker) LatestBuild(
+	ctx context.Context, server *domain.Server, appID domain.SteamAppID, branch string,
+) (string, error) {
+	if branch == "" {
+		branch = publicBranch
+	}
+
+	key := appID.String() + "/" + branch
+	now := c.nowFn()
+
+	c.mu.Lock()
+	cached, ok := c.latest[key]
+	c.mu.Unlock()
+
+	if ok && now.Sub(cached.checkedAt) < latestBuildTTL {
+		return cached.buildID, nil
+	}
+
+	options, err := c.executorOptions(server)
+	if err != nil {
+		return "", err
+	}
+
+	out, _, err := c.executor.Exec(ctx, c.appInfoCommand(appID), options)
+	if err != nil {
+		return "", errors.WithMessage(err, "failed to execute steamcmd")
+	}
+
+	buildID, err := parseAppInfo(string(out), appID, branch)
+	if err != nil {
+		return "", err
+	}
+
+	c.mu.Lock()
+	c.latest[key] = latestBuild{buildID: buildID, checkedAt: now}
+	c.mu.Unlock()
+
+	return buildID, nil
+}
+
+func (c *UpdateChecker) appInfoCommand(appID domain.SteamAppID) string {
+	var cmd strings.Builder
+
+	cmd.WriteString(filepath.Join(c.cfg.SteamCMDPath, config.SteamCMDExecutableFile))
+
+	if c.cfg.SteamConfig.Login != "" && c.cfg.SteamConfig.Password != "" {
+		cmd.WriteString(" +login ")
+		cmd.WriteString(c.cfg.SteamConfig.Login)
+		cmd.WriteString(" ")
+		cmd.WriteString(c.cfg.SteamConfig.Password)
+	} else {
+		cmd.WriteString(" +login anonymous")
+	}
+
+	cmd.WriteString(" +app_info_update 1 +app_info_print ")
+	cmd.WriteString(appID.String())
+	cmd.WriteString(" +quit")
+
+	return cmd.String()
+}
+
+// executorOptions runs steamcmd as the server user when the daemon runs as
+// root, the same way it is run to install the server.
+func (c *UpdateChecker) executorOptions(server *domain.Server) (contracts.ExecutorOptions, error) {
+	options := contracts.ExecutorOptions{
+		WorkDir:         c.cfg.SteamCMDPath,
+		FallbackWorkDir: server.WorkDir(c.cfg),
+		Env:             map[string]string{"HOME": c.cfg.SteamCMDPath},
+	}
+
+	if os.Geteuid() == 0 && server.User() != "" {
+		systemUser, err := user.Lookup(server.User())
+		if err != nil {
+			return options, errors.Wrap(err, "failed to lookup user")
+		}
+
+		options.UID = systemUser.Uid
+		options.GID = systemUser.Gid
+	}
+
+	return options, nil
+}
+
+// parseAppInfo finds the build id of the branch in the app info printed by
+// steamcmd among its other output.
+func parseAppInfo(out string, appID domain.SteamAppID, branch string) (string, error) {
+	start := regexp.MustCompile(`"` + appID.String() + `"\s*\{`).FindStringIndex(out)
+	if start == nil {
+		return "", errors.Wrapf(ErrNoBuild, "no app info of app %s in steamcmd output", appID)
+	}
+
+	// steamcmd prints more after the app info.
+	text := out[start[0]:]
+
+	info, err := parseVDF(text[:sectionEnd(text)])
+	if err != nil {
+		return "", errors.WithMessage(err, "failed to parse app info")
+	}
+
+	buildID, ok := info.lookup(appID.String(), "depots", "branches", branch, "buildid")
+	if !ok || buildID == "" {
+		return "", errors.Wrapf(ErrNoBuild, "app %s branch %s", appID, branch)
+	}
+
+	return buildID, nil
+}
+
+// sectionEnd returns the end of the first braced section of the text.
+func sectionEnd(text string) int {
+	depth := 0
+	quoted := false
+
+	for i := 0; i < len(text); i++ {
+		switch c := text[i]; {
+		case c == '\\' && quoted:
+			i++
+		case c == '"':
+			quoted = !quoted
+		case c == '{' && !quoted:
+			depth++
+		case c == '}' && !quoted:
+			depth--
+			if depth == 0 {
+				return i + 1
+			}
+		}
+	}
+
+	return len(text)
+}
+
+func branchFromAppSetConfig(appSetConfig string) string {
+	if m := betaRegexp.FindStringSubmatch(appSetConfig); m != nil {
+		return m[1]
+	}
+
+	return publicBranch
+}
@@ -0,0 +1,175 @@
+package steam
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gameap/daemon/internal/app/config"
+	"github.com/gameap/daemon/internal/app/contracts"
+	"github.com/gameap/daemon/internal/app/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const appManifest = `"AppState"
+{
+	"appid"		"740"
+	"Universe"		"1"
+	"name"		"Counter-Strike Global Offensive - Dedicated Server"
+	"buildid"		"8924361"
+	"UserConfig"
+	{
+	}
+}
+`
+
+const appInfoOutput = `Redirecting stderr to '/home/gameap/steamcmd/logs/stderr.txt'
+Loading Steam API...OK
+Connecting anonymously to Steam Public...OK
+AppID : 740, change number : 21000000/0, last change : Tue Mar 10 12:00:00 2026
+"740"
+{
+	"common"
+	{
+		"name"		"Counter-Strike Global Offensive - Dedicated Server"
+		"type"		"Tool"
+	}
+	"depots"
+	{
+		"branches"
+		{
+			"public"
+			{
+				"buildid"		"9012345"
+				"timeupdated"		"1773140000"
+			}
+			"1.38.7.9"
+			{
+				"buildid"		"8924361"
+				"description"		"Legacy {build}"
+			}
+		}
+	}
+}
+Unloading Steam API...OK
+`
+
+type fakeExecutor struct {
+	contracts.Executor
+
+	commands []string
+}
+
+func (e *fakeExecutor) Exec(_ context.Context, command string, _ contracts.ExecutorOptions) ([]byte, int, error) {
+	e.commands = append(e.commands, command)
+
+	return []byte(appInfoOutput), 0, nil
+}
+
+func givenServer(workDir string, game domain.Game) *domain.Server {
+	return domain.NewServer(
+		1,
+		true,
+		domain.ServerInstalled,
+		false,
+		"test",
+		"9fd2e4d1-4a4e-4d3c-9a2f-5a4b5d8c9e01",
+		"9fd2e4d1",
+		game,
+		domain.GameMod{},
+		"127.0.0.1",
+		27015,
+		27015,
+		27016,
+		"",
+		workDir,
+		"",
+		"./start.sh",
+		"",
+		"",
+		"",
+		true,
+		time.Time{},
+		nil,
+		domain.Settings{},
+		time.Time{},
+		0,
+		0,
+	)
+}
+
+func givenInstalledServer(t *testing.T, game domain.Game, manifest string) *domain.Server {
+	t.Helper()
+
+	workDir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(workDir, "steamapps"), 0o755))
+	require.NoError(t, os.WriteFile(
+		filepath.Join(workDir, "steamapps", "appmanifest_740.acf"), []byte(manifest), 0o600,
+	))
+
+	return givenServer(workDir, game)
+}
+
+func TestUpdateChecker_UpdateAvailable(t *testing.T) {
+	executor := &fakeExecutor{}
+	checker := NewUpdateChecker(&config.Config{SteamCMDPath: "/opt/steamcmd"}, executor)
+	server := givenInstalledServer(t, domain.Game{SteamAppID: 740}, appManifest)
+
+	available, err := checker.UpdateAvailable(context.Background(), server)
+
+	require.NoError(t, err)
+	assert.True(t, available)
+	require.Len(t, executor.commands, 1)
+	assert.Contains(t, executor.commands[0], "+login anonymous +app_info_update 1 +app_info_print 740 +quit")
+
+	// The latest build is cached.
+	_, err = checker.UpdateAvailable(context.Background(), server)
+	require.NoError(t, err)
+	assert.Len(t, executor.commands, 1)
+}
+
+func TestUpdateChecker_BetaBranchUpToDate(t *testing.T) {
+	checker := NewUpdateChecker(&config.Config{SteamCMDPath: "/opt/steamcmd"}, &fakeExecutor{})
+	server := givenInstalledServer(t, domain.Game{SteamAppID: 740, SteamAppSetConfig: "-beta 1.38.7.9"}, appManifest)
+
+	available, err := checker.UpdateAvailable(context.Background(), server)
+
+	require.NoError(t, err)
+	assert.False(t, available)
+}
+
+func TestUpdateChecker_NotSteamGame(t *testing.T) {
+	checker := NewUpdateChecker(&config.Config{}, &fakeExecutor{})
+
+	_, err := checker.UpdateAvailable(context.Background(), givenServer(t.TempDir(), domain.Game{}))
+
+	require.ErrorIs(t, err, ErrNotSteamGame)
+}
+
+func TestInstalledBuild_BetaKey(t *testing.T) {
+	workDir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(workDir, "steamapps"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(workDir, "steamapps", "appmanifest_740.acf"), []byte(`"AppState"
+{
+	"buildid"		"8924361"
+	"UserConfig"
+	{
+		"BetaKey"		"1.38.7.9"
+	}
+}`), 0o600))
+
+	buildID, branch, err := InstalledBuild(workDir, 740)
+
+	require.NoError(t, err)
+	assert.Equal(t, "8924361", buildID)
+	assert.Equal(t, "1.38.7.9", branch)
+}
+
+func TestParseAppInfo_NoApp(t *testing.T) {
+	_, err := parseAppInfo("Connecting anonymously to Steam Public...FAILED", 740, "public")
+
+	require.ErrorIs(t, err, ErrNoBuild)
+}
@@ -0,0 +1,152 @@
+package steam
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+var errInvalidVDF = errors.New("invalid vdf")
+
+// vdfNode is a section of a Valve KeyValues (VDF) text: keys map to strings
+// or to nested sections.
+type vdfNode map[string]any
+
+// parseVDF parses a VDF text. Keys are lower-cased, VDF keys are case
+// insensitive and Steam is not consistent about them.
+func parseVDF(text string) (vdfNode, error) {
+	p := vdfParser{text: text}
+
+	root, err := p.section(false)
+	if err != nil {
+		return nil, err
+	}
+
+	return root, nil
+}
+
+type vdfParser struct {
+	text string
+	pos  int
+}
+
+func (p *vdfParser) section(nested bool) (vdfNode, error) {
+	node := vdfNode{}
+
+	for {
+		tok, ok, err := p.token()
+		if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case !ok && nested:
+			return nil, errors.Wrap(errInvalidVDF, "unexpected end of text")
+		case !ok:
+			return node, nil
+		case tok == "}" && nested:
+			return node, nil
+		case tok == "{" || tok == "}":
+			return nil, errors.Wrapf(errInvalidVDF, "unexpected %q at %d", tok, p.pos)
+		}
+
+		key := strings.ToLower(tok)
+
+		value, ok, err := p.token()
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, errors.Wrapf(errInvalidVDF, "no value for %q", tok)
+		}
+
+		if value == "{" {
+			child, err := p.section(true)
+			if err != nil {
+				return nil, err
+			}
+			node[key] = child
+
+			continue
+		}
+
+		node[key] = value
+	}
+}
+
+// token returns the next quoted string, bare word or brace. Comments are
+// skipped.
+func (p *vdfParser) token() (string, bool, error) {
+	for p.pos < len(p.text) {
+		c := p.text[p.pos]
+
+		switch {
+		case c == ' ' || c == '\t' || c == '\r' || c == '\n':
+			p.pos++
+		case strings.HasPrefix(p.text[p.pos:], "//"):
+			if end := strings.IndexByte(p.text[p.pos:], '\n'); end >= 0 {
+				p.pos += end
+			} else {
+				p.pos = len(p.text)
+			}
+		case c == '{' || c == '}':
+			p.pos++
+			return string(c), true, nil
+		case c == '"':
+			return p.quoted()
+		default:
+			start := p.pos
+			for p.pos < len(p.text) && !strings.ContainsRune(" \t\r\n{}\"", rune(p.text[p.pos])) {
+				p.pos++
+			}
+
+			return p.text[start:p.pos], true, nil
+		}
+	}
+
+	return "", false, nil
+}
+
+func (p *vdfParser) quoted() (string, bool, error) {
+	var b strings.Builder
+
+	for p.pos++; p.pos < len(p.text); p.pos++ {
+		c := p.text[p.pos]
+
+		switch {
+		case c == '\\' && p.pos+1 < len(p.text):
+			p.pos++
+			b.WriteByte(p.text[p.pos])
+		case c == '"':
+			p.pos++
+			return b.String(), true, nil
+		default:
+			b.WriteByte(c)
+		}
+	}
+
+	return "", false, errors.Wrap(errInvalidVDF, "unterminated string")
+}
+
+// lookup returns the string at the path of keys.
+func (n vdfNode) lookup(path ...string) (string, bool) {
+	node := n
+
+	for i, key := range path {
+		value, ok := node[strings.ToLower(key)]
+		if !ok {
+			return "", false
+		}
+
+		if i == len(path)-1 {
+			s, ok := value.(string)
+			return s, ok
+		}
+
+		if node, ok = value.(vdfNode); !ok {
+			return "", false
+		}
+	}
+
+	return "", false
+}
@@ -0,0 +1,52 @@
+//go:build linux || darwin
+
+package app
+
+import (
+	"context"
+	"os/signal"
+	"syscall"
+
+	"github.com/gameap/daemon/internal/processmanager/supervisor"
+	"github.com/urfave/cli/v2"
+)
+
+// supervisorCommands returns the hidden command the native process manager
+// runs the daemon executable with to supervise a single server.
+func supervisorCommands() []*cli.Command {
+	return []*cli.Command{
+		{
+			Name:   supervisor.ShimCommand,
+			Usage:  "Run a game server under the native process manager supervisor",
+			Hidden: true,
+			Flags: []cli.Flag{
+				&cli.StringFlag{Name: supervisor.FlagSocket, Required: true},
+				&cli.StringFlag{Name: supervisor.FlagWorkDir},
+				&cli.StringFlag{Name: supervisor.FlagUID},
+				&cli.StringFlag{Name: supervisor.FlagGID},
+				&cli.IntFlag{Name: supervisor.FlagBufferSize, Value: supervisor.DefaultBufferSize},
+				&cli.DurationFlag{Name: supervisor.FlagLinger, Value: supervisor.DefaultLinger},
+			},
+			Action: supervisorShimAction,
+		},
+	}
+}
+
+func supervisorShimAction(c *cli.Context) error {
+	// The shim outlives the daemon that started it, a hangup of the daemon
+	// terminal must not take the server down.
+	signal.Ignore(syscall.SIGHUP)
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
+	defer stop()
+
+	return supervisor.RunShim(ctx, supervisor.ShimOptions{
+		Socket:     c.String(supervisor.FlagSocket),
+		Args:       c.Args().Slice(),
+		WorkDir:    c.String(supervisor.FlagWorkDir),
+		UID:        c.String(supervisor.FlagUID),
+		GID:        c.String(supervisor.FlagGID),
+		BufferSize: c.Int(supervisor.FlagBufferSize),
+		Linger:     c.Duration(supervisor.FlagLinger),
+	})
+}
@@ -0,0 +1,11 @@
+//go:build windows
+
+package app
+
+import "github.com/urfave/cli/v2"
+
+// supervisorCommands is empty, the native process manager is not available
+// on Windows.
+func supervisorCommands() []*cli.Command {
+	return nil
+}
@@ -0,0 +1,594 @@
+//go:build linux
+
+package processmanager
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/gameap/daemon/internal/app/config"
+	"github.com/gameap/daemon/internal/app/domain"
+	"github.com/gameap/daemon/pkg/logger"
+	"github.com/pkg/errors"
+	"github.com/shirou/gopsutil/v4/process"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	// keyCgroup set to "false" keeps the servers out of cgroups.
+	keyCgroup = "cgroup"
+
+	// keyCgroupPath is the delegated cgroup the server cgroups are created
+	// in, relative to the cgroup2 mount. Defaults to the cgroup of the daemon.
+	keyCgroupPath = "cgroup_path"
+
+	// keyPidsLimit is the maximum number of tasks of a server.
+	keyPidsLimit = "pids_limit"
+
+	// keyIOMax is the io.max limit of the device holding the server
+	// directory, e.g. "rbps=52428800 wbps=52428800".
+	keyIOMax = "io_max"
+
+	cgroupMountPoint = "/sys/fs/cgroup"
+	cgroupDaemonLeaf = "daemon"
+	cgroupCPUPeriod  = 100000
+
+	// cgroupMovePasses bounds how often the process tree is rescanned for
+	// children forked while it was moved.
+	cgroupMovePasses = 3
+)
+
+var cgroupControllers = []string{"cpu", "memory", "pids", "io"}
+
+// cgroupDelegateXattrs mark a cgroup delegated by systemd, trusted.delegate
+// by the system manager and user.delegate by a user manager.
+var cgroupDelegateXattrs = []string{"trusted.delegate", "user.delegate"}
+
+var (
+	errCgroupDisabled    = errors.New("server cgroups are disabled")
+	errCgroupUnavailable = errors.New("cgroup v2 is not available")
+	errInvalidIOMax      = errors.New("invalid io_max")
+	errNoBlockDevice     = errors.New("server directory is not on a block device")
+)
+
+// serverCgroups places the process trees of tmux and simple servers into a
+// cgroup v2 subtree delegated to the daemon, one cgroup per server with the
+// server CPU, RAM, PIDs and IO limits. Without cgroup v2 or delegation the
+// servers run unlimited as before.
+type serverCgroups struct {
+	cfg *config.Config
+
+	mountPoint  string
+	selfCgroup  string
+	sysDevBlock string
+	euid        int
+	xattr       func(path, name string) (string, error)
+
+	once        sync.Once
+	base        string
+	controllers []string
+	err         error
+}
+
+func newServerCgroups(cfg *config.Config) *serverCgroups {
+	return &serverCgroups{
+		cfg:         cfg,
+		mountPoint:  cgroupMountPoint,
+		selfCgroup:  "/proc/self/cgroup",
+		sysDevBlock: "/sys/dev/block",
+		euid:        os.Geteuid(),
+		xattr:       readXattr,
+	}
+}
+
+// init detects the delegated subtree once and enables the controllers for
+// the server cgroups in it.
+func (c *serverCgroups) init(ctx context.Context) error {
+	c.once.Do(func() {
+		c.err = c.detect()
+
+		switch {
+		case c.err == nil:
+			logger.Infof(ctx, "Server cgroups are created in %s (controllers: %s)",
+				c.base, strings.Join(c.controllers, " "))
+		case !errors.Is(c.err, errCgroupDisabled):
+			logger.Warn(ctx, errors.WithMessage(c.err,
+				"server CPU and RAM limits are not enforced, cgroup v2 delegation is not available"))
+		}
+	})
+
+	return c.err
+}
+
+func (c *serverCgroups) detect() error {
+	if c.cfg.ProcessManager.Config[keyCgroup] == "false" {
+		return errCgroupDisabled
+	}
+
+	if _, err := os.Stat(filepath.Join(c.mountPoint, "cgroup.controllers")); err != nil {
+		return errors.Wrapf(errCgroupUnavailable, "no unified hierarchy at %s", c.mountPoint)
+	}
+
+	base, err := c.basePath()
+	if err != nil {
+		return err
+	}
+
+	available, err := readCgroupList(filepath.Join(base, "cgroup.controllers"))
+	if err != nil {
+		return errors.Wrapf(errCgroupUnavailable, "failed to read controllers of %s: %s", base, err)
+	}
+
+	controllers := make([]string, 0, len(cgroupControllers))
+	for _, controller := range cgroupControllers {
+		if slices.Contains(available, controller) {
+			controllers = append(controllers, controller)
+		}
+	}
+
+	if err := c.enableControllers(base, controllers); err != nil {
+		return errors.Wrapf(errCgroupUnavailable, "failed to enable controllers in %s: %s", base, err)
+	}
+
+	c.base = base
+	c.controllers = controllers
+
+	return nil
+}
+
+// basePath returns cgroup_path, taken as delegated by the configuration, or
+// the cgroup of the daemon when it is delegated to the daemon.
+func (c *serverCgroups) basePath() (string, error) {
+	if path := c.cfg.ProcessManager.Config[keyCgroupPath]; path != "" {
+		return filepath.Join(c.mountPoint, strings.TrimPrefix(path, c.mountPoint)), nil
+	}
+
+	file, err := os.Open(c.selfCgroup)
+	if err != nil {
+		return "", errors.Wrapf(errCgroupUnavailable, "failed to read daemon cgroup: %s", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		path, ok := strings.CutPrefix(scanner.Text(), "0::")
+		if !ok {
+			continue
+		}
+
+		base := filepath.Join(c.mountPoint, path)
+		if !c.delegated(base) {
+			return "", errors.Wrapf(errCgroupUnavailable, "daemon cgroup %s is not delegated", path)
+		}
+
+		return base, nil
+	}
+
+	return "", errors.Wrap(errCgroupUnavailable, "daemon is not in a cgroup v2 hierarchy")
+}
+
+// delegated reports whether the cgroup is delegated to the daemon: marked
+// by systemd with a delegate xattr, or owned by the unprivileged daemon
+// user. Every cgroup is owned by root, so ownership tells nothing for a
+// daemon run as root.
+func (c *serverCgroups) delegated(path string) bool {
+	for _, name := range cgroupDelegateXattrs {
+		if value, err := c.xattr(path, name); err == nil && value == "1" {
+			return true
+		}
+	}
+
+	if c.euid == 0 {
+		return false
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+
+	stat, ok := info.Sys().(*syscall.Stat_t)
+
+	return ok && int(stat.Uid) == c.euid
+}
+
+func readXattr(path, name string) (string, error) {
+	buf := make([]byte, 16)
+
+	n, err := unix.Getxattr(path, name, buf)
+	if err != nil {
+		return "", err
+	}
+
+	return string(buf[:n]), nil
+}
+
+// enableControllers turns the controllers on for the children of base. A
+// cgroup with processes cannot have controllers enabled for its children,
+// so the processes in base, the daemon among them, are moved to a leaf first.
+func (c *serverCgroups) enableControllers(base string, controllers []string) error {
+	enabled, err := readCgroupList(filepath.Join(base, "cgroup.subtree_control"))
+	if err != nil {
+		return err
+	}
+
+	missing := make([]string, 0, len(controllers))
+	for _, controller := range controllers {
+		if !slices.Contains(enabled, controller) {
+			missing = append(missing, "+"+controller)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+
+	subtreeControl := filepath.Join(base, "cgroup.subtree_control")
+
+	err = writeCgroupFile(subtreeControl, strings.Join(missing, " "))
+	if errors.Is(err, syscall.EBUSY) {
+		if err := c.moveProcesses(base, filepath.Join(base, cgroupDaemonLeaf)); err != nil {
+			return err
+		}
+
+		err = writeCgroupFile(subtreeControl, strings.Join(missing, " "))
+	}
+
+	return err
+}
+
+func (c *serverCgroups) moveProcesses(from, to string) error {
+	if err := os.Mkdir(to, 0o755); err != nil && !errors.Is(err, os.ErrExist) {
+		return errors.Wrap(err, "failed to create cgroup")
+	}
+
+	pids, err := readCgroupList(filepath.Join(from, "cgroup.procs"))
+	if err != nil {
+		return err
+	}
+
+	for _, pid := range pids {
+		err := writeCgroupFile(filepath.Join(to, "cgroup.procs"), pid)
+		if err != nil && !errors.Is(err, syscall.ESRCH) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c *serverCgroups) path(server *domain.Server) string {
+	return filepath.Join(c.base, server.XID())
+}
+
+// place creates the cgroup of the server, applies the server limits to it
+// and moves the process tree of pid into it. The server keeps running
+// without limits when cgroups are not available.
+func (c *serverCgroups) place(ctx context.Context, server *domain.Server, pid int) error {
+	if err := c.init(ctx); err != nil {
+		return nil //nolint:nilerr
+	}
+
+	if err := validateServerPID(server, pid); err != nil {
+		return err
+	}
+
+	dir := c.path(server)
+	if err := os.Mkdir(dir, 0o755); err != nil && !errors.Is(err, os.ErrExist) {
+		return errors.Wrapf(err, "failed to create cgroup %s", dir)
+	}
+
+	if err := c.applyLimits(server, dir); err != nil {
+		return err
+	}
+
+	return c.moveTree(ctx, dir, int32(pid))
+}
+
+// applyLimits writes every limit, "max" included, so a cgroup left by a
+// previous run does not keep outdated limits.
+func (c *serverCgroups) applyLimits(server *domain.Server, dir string) error {
+	if slices.Contains(c.controllers, "cpu") {
+		quota := "max"
+		if server.CPULimit() > 0 {
+			// millicores to microseconds per period: 1000 millicores = one full period
+			quota = strconv.Itoa(server.CPULimit() * cgroupCPUPeriod / 1000)
+		}
+
+		cpuMax := fmt.Sprintf("%s %d", quota, cgroupCPUPeriod)
+		if err := writeCgroupFile(filepath.Join(dir, "cpu.max"), cpuMax); err != nil {
+			return errors.WithMessage(err, "failed to set cpu.max")
+		}
+	}
+
+	if slices.Contains(c.controllers, "memory") {
+		limit := "max"
+		if server.RAMLimit() > 0 {
+			limit = strconv.FormatInt(server.RAMLimit(), 10)
+		}
+
+		if err := writeCgroupFile(filepath.Join(dir, "memory.max"), limit); err != nil {
+			return errors.WithMessage(err, "failed to set memory.max")
+		}
+	}
+
+	if slices.Contains(c.controllers, "pids") {
+		limit := getContainerConfig(c.cfg, server, keyPidsLimit)
+		if limit == "" {
+			limit = "max"
+		}
+
+		if err := writeCgroupFile(filepath.Join(dir, "pids.max"), limit); err != nil {
+			return errors.WithMessagef(err, "failed to set pids.max to %q", limit)
+		}
+	}
+
+	if ioMax := getContainerConfig(c.cfg, server, keyIOMax); ioMax != "" && slices.Contains(c.controllers, "io") {
+		device, err := c.blockDevice(server.WorkDir(c.cfg))
+		if err != nil {
+			return errors.WithMessage(err, "failed to set io.max")
+		}
+
+		if err := validateIOMax(ioMax); err != nil {
+			return err
+		}
+
+		if err := writeCgroupFile(filepath.Join(dir, "io.max"), device+" "+ioMax); err != nil {
+			return errors.WithMessage(err, "failed to set io.max")
+		}
+	}
+
+	return nil
+}
+
+func validateIOMax(value string) error {
+	for _, field := range strings.Fields(value) {
+		key, limit, ok := strings.Cut(field, "=")
+		if !ok || !slices.Contains([]string{"rbps", "wbps", "riops", "wiops"}, key) {
+			return errors.Wrapf(errInvalidIOMax, "unknown limit %q", field)
+		}
+
+		if _, err := strconv.ParseUint(limit, 10, 64); err != nil && limit != "max" {
+			return errors.Wrapf(errInvalidIOMax, "invalid value in %q", field)
+		}
+	}
+
+	return nil
+}
+
+// blockDevice returns "major:minor" of the disk holding path. io.max takes
+// whole disks only, partitions are resolved to their disk.
+func (c *serverCgroups) blockDevice(path string) (string, error) {
+	var stat unix.Stat_t
+	if err := unix.Stat(path, &stat); err != nil {
+		return "", errors.Wrapf(err, "failed to stat %s", path)
+	}
+
+	major, minor := unix.Major(stat.Dev), unix.Minor(stat.Dev)
+	if major == 0 {
+		return "", errors.Wrapf(errNoBlockDevice, "%s is on device %d:%d", path, major, minor)
+	}
+
+	device := fmt.Sprintf("%d:%d", major, minor)
+
+	sysDevice := filepath.Join(c.sysDevBlock, device)
+	if _, err := os.Stat(filepath.Join(sysDevice, "partition")); err != nil {
+		return device, nil
+	}
+
+	// The entry links to the partition directory inside the disk directory.
+	partition, err := filepath.EvalSymlinks(sysDevice)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to resolve partition %s", device)
+	}
+
+	disk, err := os.ReadFile(filepath.Join(filepath.Dir(partition), "dev"))
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to resolve disk of partition %s", device)
+	}
+
+	return strings.TrimSpace(string(disk)), nil
+}
+
+// moveTree moves pid and its descendants into the cgroup. Children forked
+// from then on start in it, the tree is rescanned for the ones forked
+// while it was being moved.
+func (c *serverCgroups) moveTree(ctx context.Context, dir string, pid int32) error {
+	procs := filepath.Join(dir, "cgroup.procs")
+	moved := make(map[int32]bool)
+
+	for range cgroupMovePasses {
+		root, err := process.NewProcessWithContext(ctx, pid)
+		if err != nil {
+			return errors.Wrapf(err, "failed to find process %d", pid)
+		}
+
+		tree, err := processTree(ctx, root)
+		if err != nil {
+			return err
+		}
+
+		movedAny := false
+		tree = sameOwnerTree(ctx, tree)
+		for _, p := range tree {
+			if moved[p.Pid] {
+				continue
+			}
+
+			err := writeCgroupFile(procs, strconv.Itoa(int(p.Pid)))
+			if err != nil && !errors.Is(err, syscall.ESRCH) {
+				return errors.WithMessagef(err, "failed to move process %d to cgroup", p.Pid)
+			}
+
+			moved[p.Pid] = true
+			movedAny = true
+		}
+
+		if !movedAny {
+			break
+		}
+	}
+
+	return nil
+}
+
+// remove deletes the cgroup of a stopped server. Removal fails harmlessly
+// while processes are left in it.
+func (c *serverCgroups) remove(ctx context.Context, server *domain.Server) {
+	if c.init(ctx) != nil {
+		return
+	}
+
+	err := os.Remove(c.path(server))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		logger.Debug(ctx, errors.Wrap(err, "failed to remove server cgroup"))
+	}
+}
+
+// stats reads the counters of the server cgroup. It reports false when the
+// server has no populated cgroup, so the caller falls back to the process
+// tree.
+func (c *serverCgroups) stats(ctx context.Context, server *domain.Server) (processTreeStats, bool) {
+	if c.init(ctx) != nil {
+		return processTreeStats{}, false
+	}
+
+	dir := c.path(server)
+
+	pids, err := readCgroupList(filepath.Join(dir, "cgroup.procs"))
+	if err != nil || len(pids) == 0 {
+		return processTreeStats{}, false
+	}
+
+	memory, err := readCgroupUint(filepath.Join(dir, "memory.current"))
+	if err != nil {
+		return processTreeStats{}, false
+	}
+
+	cpu, err := readCgroupKeyed(filepath.Join(dir, "cpu.stat"))
+	if err != nil {
+		return processTreeStats{}, false
+	}
+
+	stats := processTreeStats{
+		CPUSeconds: float64(cpu["usage_usec"]) / 1e6,
+		MemoryRSS:  memory,
+		PIDs:       uint64(len(pids)),
+	}
+
+	if limit, err := readCgroupUint(filepath.Join(dir, "memory.max")); err == nil {
+		stats.MemoryLimit = limit
+	}
+
+	if ioStat, err := os.ReadFile(filepath.Join(dir, "io.stat")); err == nil {
+		stats.IOReadBytes, stats.IOWriteBytes = parseIOStat(string(ioStat))
+		stats.HasIO = true
+	}
+
+	return stats, true
+}
+
+// parseIOStat sums the bytes over the devices of io.stat lines such as
+// "8:0 rbytes=1024 wbytes=2048 rios=1 wios=2 dbytes=0 dios=0".
+func parseIOStat(content string) (uint64, uint64) {
+	var read, written uint64
+
+	for _, line := range strings.Split(content, "\n") {
+		for _, field := range strings.Fields(line) {
+			key, value, ok := strings.Cut(field, "=")
+			if !ok {
+				continue
+			}
+
+			n, err := strconv.ParseUint(value, 10, 64)
+			if err != nil {
+				continue
+			}
+
+			switch key {
+			case "rbytes":
+				read += n
+			case "wbytes":
+				written += n
+			}
+		}
+	}
+
+	return read, written
+}
+
+func writeCgroupFile(path, value string) error {
+	// cgroup files take one value per write and report errors on write,
+	// so no O_CREATE, O_TRUNC or buffering.
+	file, err := os.OpenFile(path, os.O_WRONLY, 0)
+	if err != nil {
+		return errors.Wrapf(err, "failed to open %s", path)
+	}
+	defer file.Close()
+
+	if _, err := file.WriteString(value); err != nil {
+		return errors.Wrapf(err, "failed to write %s", path)
+	}
+
+	return nil
+}
+
+// readCgroupList reads a file of whitespace separated values such as
+// cgroup.controllers or cgroup.procs.
+func readCgroupList(path string) ([]string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return strings.Fields(string(content)), nil
+}
+
+// readCgroupUint reads a single value file, "max" is reported as 0.
+func readCgroupUint(path string) (uint64, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+
+	value := strings.TrimSpace(string(content))
+	if value == "max" {
+		return 0, nil
+	}
+
+	n, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return 0, errors.Wrapf(err, "invalid value in %s", path)
+	}
+
+	return n, nil
+}
+
+// readCgroupKeyed reads a flat keyed file such as cpu.stat.
+func readCgroupKeyed(path string) (map[string]uint64, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]uint64)
+	for _, line := range strings.Split(string(content), "\n") {
+		key, value, ok := strings.Cut(line, " ")
+		if !ok {
+			continue
+		}
+
+		if n, err := strconv.ParseUint(strings.TrimSpace(value), 10, 64); err == nil {
+			values[key] = n
+		}
+	}
+
+	return values, nil
+}
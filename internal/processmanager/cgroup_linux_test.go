@@ -0,0 +1,341 @@
+//go:build linux
+
+package processmanager
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/gameap/daemon/internal/app/config"
+	"github.com/gameap/daemon/internal/app/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// givenCgroupFS lays out a fake cgroup2 mount with the daemon in
+// /system.slice/gameap-daemon.service, delegated to the daemon by systemd.
+func givenCgroupFS(t *testing.T, cfg *config.Config) (*serverCgroups, string) {
+	t.Helper()
+
+	root := t.TempDir()
+	base := filepath.Join(root, "system.slice", "gameap-daemon.service")
+	require.NoError(t, os.MkdirAll(base, 0o755))
+
+	writeTestFile(t, filepath.Join(root, "cgroup.controllers"), "cpuset cpu io memory pids")
+	writeTestFile(t, filepath.Join(base, "cgroup.controllers"), "cpu io memory pids")
+	writeTestFile(t, filepath.Join(base, "cgroup.subtree_control"), "")
+
+	selfCgroup := filepath.Join(t.TempDir(), "cgroup")
+	writeTestFile(t, selfCgroup, "0::/system.slice/gameap-daemon.service\n")
+
+	c := newServerCgroups(cfg)
+	c.mountPoint = root
+	c.selfCgroup = selfCgroup
+	c.xattr = givenXattrs(map[string]string{base + ":trusted.delegate": "1"})
+
+	return c, base
+}
+
+func givenXattrs(attrs map[string]string) func(path, name string) (string, error) {
+	return func(path, name string) (string, error) {
+		value, ok := attrs[path+":"+name]
+		if !ok {
+			return "", syscall.ENODATA
+		}
+
+		return value, nil
+	}
+}
+
+// givenServerCgroup creates the interface files the kernel would create
+// with the server cgroup.
+func givenServerCgroup(t *testing.T, base string, files map[string]string) string {
+	t.Helper()
+
+	dir := filepath.Join(base, givenLimitedServer(nil, 0, 0).XID())
+	require.NoError(t, os.MkdirAll(dir, 0o755))
+
+	for _, name := range []string{"cgroup.procs", "cpu.max", "memory.max", "pids.max", "io.max"} {
+		writeTestFile(t, filepath.Join(dir, name), "")
+	}
+	for name, content := range files {
+		writeTestFile(t, filepath.Join(dir, name), content)
+	}
+
+	return dir
+}
+
+func writeTestFile(t *testing.T, path, content string) {
+	t.Helper()
+
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+}
+
+func readTestFile(t *testing.T, path string) string {
+	t.Helper()
+
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	return string(content)
+}
+
+// givenServerProcess starts a process in its own process group, as the
+// start scripts of servers do.
+func givenServerProcess(t *testing.T) int {
+	t.Helper()
+
+	cmd := exec.Command("sleep", "30")
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	require.NoError(t, cmd.Start())
+	t.Cleanup(func() {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+	})
+
+	return cmd.Process.Pid
+}
+
+func givenLimitedServer(vars map[string]string, cpuLimit int, ramLimit int64) *domain.Server {
+	return domain.NewServer(
+		1, true, domain.ServerInstalled, false,
+		"Test Server", "test-uuid-5678", "test5678",
+		domain.Game{}, domain.GameMod{},
+		"127.0.0.1", 27015, 27016, 27017, "",
+		"/servers/test", "",
+		"./game_server", "", "", "",
+		false, time.Time{},
+		vars, domain.Settings{}, time.Time{},
+		cpuLimit, ramLimit,
+	)
+}
+
+func TestServerCgroups_place(t *testing.T) {
+	c, base := givenCgroupFS(t, &config.Config{})
+	dir := givenServerCgroup(t, base, nil)
+	server := givenLimitedServer(map[string]string{"pids_limit": "64"}, 1500, 512*1024*1024)
+	pid := givenServerProcess(t)
+
+	err := c.place(context.Background(), server, pid)
+
+	require.NoError(t, err)
+	assert.Equal(t, "+cpu +memory +pids +io", readTestFile(t, filepath.Join(base, "cgroup.subtree_control")))
+	assert.Equal(t, "150000 100000", readTestFile(t, filepath.Join(dir, "cpu.max")))
+	assert.Equal(t, "536870912", readTestFile(t, filepath.Join(dir, "memory.max")))
+	assert.Equal(t, "64", readTestFile(t, filepath.Join(dir, "pids.max")))
+	assert.Equal(t, strconv.Itoa(pid), readTestFile(t, filepath.Join(dir, "cgroup.procs")))
+}
+
+func TestServerCgroups_place_NoLimits(t *testing.T) {
+	c, base := givenCgroupFS(t, &config.Config{})
+	dir := givenServerCgroup(t, base, nil)
+
+	err := c.place(context.Background(), givenLimitedServer(nil, 0, 0), givenServerProcess(t))
+
+	require.NoError(t, err)
+	assert.Equal(t, "max 100000", readTestFile(t, filepath.Join(dir, "cpu.max")))
+	assert.Equal(t, "max", readTestFile(t, filepath.Join(dir, "memory.max")))
+	assert.Equal(t, "max", readTestFile(t, filepath.Join(dir, "pids.max")))
+}
+
+func TestServerCgroups_place_ForeignPID(t *testing.T) {
+	c, base := givenCgroupFS(t, &config.Config{})
+	server := givenLimitedServer(nil, 1000, 0)
+
+	for _, pid := range []int{1, os.Getpid()} {
+		err := c.place(context.Background(), server, pid)
+
+		require.ErrorIs(t, err, ErrForeignPID)
+		assert.NoDirExists(t, filepath.Join(base, server.XID()))
+	}
+}
+
+func TestServerCgroups_Unavailable(t *testing.T) {
+	t.Run("disabled", func(t *testing.T) {
+		cfg := &config.Config{}
+		cfg.ProcessManager.Config = map[string]string{"cgroup": "false"}
+		c, base := givenCgroupFS(t, cfg)
+
+		err := c.place(context.Background(), givenLimitedServer(nil, 1000, 0), os.Getpid())
+
+		require.NoError(t, err)
+		assert.NoDirExists(t, filepath.Join(base, givenLimitedServer(nil, 0, 0).XID()))
+		assert.ErrorIs(t, c.init(context.Background()), errCgroupDisabled)
+	})
+
+	t.Run("no unified hierarchy", func(t *testing.T) {
+		c, _ := givenCgroupFS(t, &config.Config{})
+		require.NoError(t, os.Remove(filepath.Join(c.mountPoint, "cgroup.controllers")))
+
+		err := c.place(context.Background(), givenLimitedServer(nil, 1000, 0), os.Getpid())
+
+		require.NoError(t, err)
+		assert.ErrorIs(t, c.init(context.Background()), errCgroupUnavailable)
+	})
+
+	t.Run("not delegated", func(t *testing.T) {
+		c, base := givenCgroupFS(t, &config.Config{})
+		require.NoError(t, os.Chmod(filepath.Join(base, "cgroup.subtree_control"), 0o444))
+		if os.Getuid() == 0 {
+			t.Skip("root ignores file permissions")
+		}
+
+		err := c.place(context.Background(), givenLimitedServer(nil, 1000, 0), os.Getpid())
+
+		require.NoError(t, err)
+		assert.ErrorIs(t, c.init(context.Background()), errCgroupUnavailable)
+	})
+
+	t.Run("daemon cgroup not delegated", func(t *testing.T) {
+		c, base := givenCgroupFS(t, &config.Config{})
+		c.xattr = givenXattrs(nil)
+		c.euid = 0
+
+		err := c.place(context.Background(), givenLimitedServer(nil, 1000, 0), os.Getpid())
+
+		require.NoError(t, err)
+		assert.ErrorIs(t, c.init(context.Background()), errCgroupUnavailable)
+		assert.Empty(t, readTestFile(t, filepath.Join(base, "cgroup.subtree_control")))
+		assert.NoDirExists(t, filepath.Join(base, cgroupDaemonLeaf))
+	})
+
+	t.Run("stats", func(t *testing.T) {
+		c, _ := givenCgroupFS(t, &config.Config{})
+		require.NoError(t, os.Remove(filepath.Join(c.mountPoint, "cgroup.controllers")))
+
+		_, ok := c.stats(context.Background(), givenLimitedServer(nil, 0, 0))
+
+		assert.False(t, ok)
+	})
+}
+
+func TestServerCgroups_delegated(t *testing.T) {
+	t.Run("user manager", func(t *testing.T) {
+		c, base := givenCgroupFS(t, &config.Config{})
+		c.xattr = givenXattrs(map[string]string{base + ":user.delegate": "1"})
+
+		require.NoError(t, c.init(context.Background()))
+		assert.Equal(t, base, c.base)
+	})
+
+	t.Run("owned by the daemon user", func(t *testing.T) {
+		c, base := givenCgroupFS(t, &config.Config{})
+		c.xattr = givenXattrs(nil)
+		c.euid = os.Geteuid()
+		if c.euid == 0 {
+			c.euid = 1000
+			require.NoError(t, os.Chown(base, c.euid, c.euid))
+		}
+
+		require.NoError(t, c.init(context.Background()))
+		assert.Equal(t, base, c.base)
+	})
+
+	t.Run("owned by another user", func(t *testing.T) {
+		c, _ := givenCgroupFS(t, &config.Config{})
+		c.xattr = givenXattrs(nil)
+		c.euid = os.Geteuid() + 1
+
+		assert.ErrorIs(t, c.init(context.Background()), errCgroupUnavailable)
+	})
+}
+
+func TestServerCgroups_cgroupPath(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.ProcessManager.Config = map[string]string{"cgroup_path": "/gameap.slice"}
+	c, _ := givenCgroupFS(t, cfg)
+	base := filepath.Join(c.mountPoint, "gameap.slice")
+	require.NoError(t, os.Mkdir(base, 0o755))
+	writeTestFile(t, filepath.Join(base, "cgroup.controllers"), "cpu memory")
+	writeTestFile(t, filepath.Join(base, "cgroup.subtree_control"), "cpu")
+
+	require.NoError(t, c.init(context.Background()))
+
+	assert.Equal(t, base, c.base)
+	assert.Equal(t, []string{"cpu", "memory"}, c.controllers)
+	assert.Equal(t, "+memory", readTestFile(t, filepath.Join(base, "cgroup.subtree_control")))
+}
+
+func TestServerCgroups_stats(t *testing.T) {
+	c, base := givenCgroupFS(t, &config.Config{})
+	givenServerCgroup(t, base, map[string]string{
+		"cgroup.procs":   "100\n101\n102\n",
+		"memory.current": "104857600\n",
+		"memory.max":     "209715200\n",
+		"cpu.stat":       "usage_usec 2500000\nuser_usec 2000000\nsystem_usec 500000\n",
+		"io.stat": "8:0 rbytes=1024 wbytes=2048 rios=1 wios=2 dbytes=0 dios=0\n" +
+			"8:16 rbytes=1 wbytes=2 rios=1 wios=1 dbytes=0 dios=0\n",
+	})
+
+	stats, ok := c.stats(context.Background(), givenLimitedServer(nil, 0, 0))
+
+	require.True(t, ok)
+	assert.Equal(t, processTreeStats{
+		CPUSeconds:   2.5,
+		MemoryRSS:    104857600,
+		MemoryLimit:  209715200,
+		IOReadBytes:  1025,
+		IOWriteBytes: 2050,
+		HasIO:        true,
+		PIDs:         3,
+	}, stats)
+
+	metrics := processTreeToMetrics(time.Now(), nil, stats, 0, false)
+	names := make([]string, 0, len(metrics))
+	for _, m := range metrics {
+		names = append(names, m.Name)
+	}
+	assert.Contains(t, names, metricServerMemoryLimitBytes)
+	assert.Contains(t, names, metricServerMemoryUsagePercent)
+}
+
+func TestServerCgroups_stats_EmptyCgroup(t *testing.T) {
+	c, base := givenCgroupFS(t, &config.Config{})
+	givenServerCgroup(t, base, map[string]string{"memory.current": "0\n", "cpu.stat": "usage_usec 0\n"})
+
+	_, ok := c.stats(context.Background(), givenLimitedServer(nil, 0, 0))
+
+	assert.False(t, ok)
+}
+
+func TestValidateIOMax(t *testing.T) {
+	for _, value := range []string{"rbps=1048576", "rbps=1048576 wbps=max riops=100 wiops=100"} {
+		assert.NoError(t, validateIOMax(value), value)
+	}
+
+	for _, value := range []string{"rbps", "bps=100", "wbps=fast", "8:0 rbps=100"} {
+		assert.ErrorIs(t, validateIOMax(value), errInvalidIOMax, value)
+	}
+}
+
+func TestServerCgroups_blockDevice(t *testing.T) {
+	c := newServerCgroups(&config.Config{})
+	c.sysDevBlock = t.TempDir()
+
+	dir := t.TempDir()
+	device, err := c.blockDevice(dir)
+	if err != nil {
+		require.ErrorIs(t, err, errNoBlockDevice)
+		t.Skip("temp dir is not on a block device")
+	}
+
+	assert.Regexp(t, `^\d+:\d+$`, device)
+
+	// A partition resolves to its disk.
+	partition := filepath.Join(c.sysDevBlock, device)
+	require.NoError(t, os.MkdirAll(partition, 0o755))
+	writeTestFile(t, filepath.Join(partition, "partition"), "1\n")
+	writeTestFile(t, filepath.Join(c.sysDevBlock, "dev"), "8:0\n")
+
+	disk, err := c.blockDevice(dir)
+
+	require.NoError(t, err)
+	assert.Equal(t, "8:0", strings.TrimSpace(disk))
+}
@@ -0,0 +1,27 @@
+//go:build !linux
+
+package processmanager
+
+import (
+	"context"
+
+	"github.com/gameap/daemon/internal/app/config"
+	"github.com/gameap/daemon/internal/app/domain"
+)
+
+// serverCgroups does nothing outside Linux, servers run without limits.
+type serverCgroups struct{}
+
+func newServerCgroups(_ *config.Config) *serverCgroups {
+	return &serverCgroups{}
+}
+
+func (c *serverCgroups) place(_ context.Context, _ *domain.Server, _ int) error {
+	return nil
+}
+
+func (c *serverCgroups) remove(_ context.Context, _ *domain.Server) {}
+
+func (c *serverCgroups) stats(_ context.Context, _ *domain.Server) (processTreeStats, bool) {
+	return processTreeStats{}, false
+}
@@ -0,0 +1,137 @@
+//go:build linux || darwin || windows
+
+package processmanager
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/gameap/daemon/internal/app/domain"
+	"github.com/moby/moby/api/types/container"
+	"github.com/pkg/errors"
+)
+
+var errInvalidHealthcheck = errors.New("invalid docker_healthcheck")
+
+// healthcheckSpec is the docker_healthcheck value. A plain string is a shell
+// command, "NONE" disables the healthcheck of the image.
+type healthcheckSpec struct {
+	Test        json.RawMessage `json:"test"`
+	Interval    string          `json:"interval"`
+	Timeout     string          `json:"timeout"`
+	StartPeriod string          `json:"start_period"`
+	Retries     int             `json:"retries"`
+}
+
+// parseHealthcheck converts docker_healthcheck to the healthcheck config,
+// which Docker and Podman both accept. An empty value keeps the healthcheck
+// of the image, nil is returned then.
+func parseHealthcheck(val string) (*container.HealthConfig, error) {
+	val = strings.TrimSpace(val)
+	if val == "" {
+		return nil, nil //nolint:nilnil
+	}
+
+	if !strings.HasPrefix(val, "{") {
+		if strings.EqualFold(val, "NONE") {
+			return &container.HealthConfig{Test: []string{"NONE"}}, nil
+		}
+
+		return &container.HealthConfig{Test: []string{"CMD-SHELL", val}}, nil
+	}
+
+	var spec healthcheckSpec
+	if err := json.Unmarshal([]byte(val), &spec); err != nil {
+		return nil, errors.Wrap(errInvalidHealthcheck, err.Error())
+	}
+
+	test, err := spec.test()
+	if err != nil {
+		return nil, err
+	}
+
+	if spec.Retries < 0 {
+		return nil, errors.Wrap(errInvalidHealthcheck, "negative retries")
+	}
+
+	hc := &container.HealthConfig{Test: test, Retries: spec.Retries}
+
+	durations := []struct {
+		name string
+		val  string
+		dst  *time.Duration
+	}{
+		{"interval", spec.Interval, &hc.Interval},
+		{"timeout", spec.Timeout, &hc.Timeout},
+		{"start_period", spec.StartPeriod, &hc.StartPeriod},
+	}
+	for _, d := range durations {
+		if d.val == "" {
+			continue
+		}
+
+		parsed, err := time.ParseDuration(d.val)
+		if err != nil || parsed < 0 {
+			return nil, errors.Wrapf(errInvalidHealthcheck, "invalid %s %q", d.name, d.val)
+		}
+
+		*d.dst = parsed
+	}
+
+	return hc, nil
+}
+
+// test returns the test command, a string is run with the shell and a list
+// is passed as is, e.g. ["CMD", "/healthcheck.sh"].
+func (spec healthcheckSpec) test() ([]string, error) {
+	if len(spec.Test) == 0 {
+		return nil, errors.Wrap(errInvalidHealthcheck, "test is required")
+	}
+
+	var cmd string
+	if err := json.Unmarshal(spec.Test, &cmd); err == nil {
+		if strings.TrimSpace(cmd) == "" {
+			return nil, errors.Wrap(errInvalidHealthcheck, "test is required")
+		}
+
+		return []string{"CMD-SHELL", cmd}, nil
+	}
+
+	var test []string
+	if err := json.Unmarshal(spec.Test, &test); err != nil {
+		return nil, errors.Wrap(errInvalidHealthcheck, "test must be a string or a list of strings")
+	}
+
+	switch {
+	case len(test) == 1 && test[0] == "NONE":
+	case len(test) >= 2 && (test[0] == "CMD" || test[0] == "CMD-SHELL"):
+	default:
+		return nil, errors.Wrap(errInvalidHealthcheck, `test must start with "CMD", "CMD-SHELL" or be ["NONE"]`)
+	}
+
+	return test, nil
+}
+
+// containerStatusResult writes the state of the container. An unhealthy
+// container is reported as not running, so the servers loop starts it again.
+func containerStatusResult(out io.Writer, name string, running bool, status, health string) domain.Result {
+	if !running {
+		_, _ = fmt.Fprintf(out, "Container %s is not running (status: %s)\n", name, status)
+		return domain.ErrorResult
+	}
+
+	switch container.HealthStatus(health) {
+	case container.Unhealthy:
+		_, _ = fmt.Fprintf(out, "Container %s is running but unhealthy\n", name)
+		return domain.ErrorResult
+	case container.Starting, container.Healthy:
+		_, _ = fmt.Fprintf(out, "Container %s is running (health: %s)\n", name, health)
+	default:
+		_, _ = fmt.Fprintf(out, "Container %s is running\n", name)
+	}
+
+	return domain.SuccessResult
+}
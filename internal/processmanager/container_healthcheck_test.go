@@ -0,0 +1,109 @@
+//go:build linux || darwin || windows
+
+package processmanager
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/gameap/daemon/internal/app/domain"
+	"github.com/moby/moby/api/types/container"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseHealthcheck(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    string
+		expected *container.HealthConfig
+	}{
+		{
+			name:     "empty keeps the image healthcheck",
+			value:    "",
+			expected: nil,
+		},
+		{
+			name:     "plain command runs in shell",
+			value:    "pgrep srcds",
+			expected: &container.HealthConfig{Test: []string{"CMD-SHELL", "pgrep srcds"}},
+		},
+		{
+			name:     "none disables the image healthcheck",
+			value:    "none",
+			expected: &container.HealthConfig{Test: []string{"NONE"}},
+		},
+		{
+			name:  "full config",
+			value: `{"test": ["CMD", "/healthcheck.sh"], "interval": "30s", "timeout": "5s", "start_period": "2m", "retries": 3}`,
+			expected: &container.HealthConfig{
+				Test:        []string{"CMD", "/healthcheck.sh"},
+				Interval:    30 * time.Second,
+				Timeout:     5 * time.Second,
+				StartPeriod: 2 * time.Minute,
+				Retries:     3,
+			},
+		},
+		{
+			name:     "string test in config",
+			value:    `{"test": "nc -z localhost 27015"}`,
+			expected: &container.HealthConfig{Test: []string{"CMD-SHELL", "nc -z localhost 27015"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hc, err := parseHealthcheck(tt.value)
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, hc)
+		})
+	}
+}
+
+func TestParseHealthcheck_Invalid(t *testing.T) {
+	values := []string{
+		`{"interval": "30s"}`,
+		`{"test": ""}`,
+		`{"test": ["/healthcheck.sh"]}`,
+		`{"test": "true", "interval": "often"}`,
+		`{"test": "true", "retries": -1}`,
+		`{"test": `,
+	}
+
+	for _, val := range values {
+		t.Run(val, func(t *testing.T) {
+			_, err := parseHealthcheck(val)
+
+			require.ErrorIs(t, err, errInvalidHealthcheck)
+		})
+	}
+}
+
+func TestContainerStatusResult(t *testing.T) {
+	tests := []struct {
+		name     string
+		running  bool
+		health   string
+		expected domain.Result
+		output   string
+	}{
+		{"running without healthcheck", true, "", domain.SuccessResult, "Container srv is running\n"},
+		{"healthy", true, "healthy", domain.SuccessResult, "Container srv is running (health: healthy)\n"},
+		{"health starting", true, "starting", domain.SuccessResult, "Container srv is running (health: starting)\n"},
+		{"unhealthy", true, "unhealthy", domain.ErrorResult, "Container srv is running but unhealthy\n"},
+		{"stopped", false, "unhealthy", domain.ErrorResult, "Container srv is not running (status: exited)\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := &bytes.Buffer{}
+
+			result := containerStatusResult(out, "srv", tt.running, "exited", tt.health)
+
+			assert.Equal(t, tt.expected, result)
+			assert.Equal(t, tt.output, out.String())
+		})
+	}
+}
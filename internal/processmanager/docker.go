@@ -409,17 +409,44 @@ func (pm *Docker) Stop(ctx context.Context, server *domain.Server, out io.Writer
 	}
 
 	containerName := pm.resolveContainerName(ctx, server)
+
+	stopViaConsole(ctx, pm.cfg, pm, server, statusExitChecker(pm.Status, server), out)
+
 	_, _ = out.Write([]byte(fmt.Sprintf("Stopping container %s...\n", containerName)))
 
-	timeout := int(defaultStopTimeout.Seconds())
+	// Docker sends SIGTERM and, once the timeout expires, SIGKILL.
+	timeout := int(durationConfig(pm.cfg, server, keyKillTimeout, defaultStopTimeout).Seconds())
 	_, err := pm.client.ContainerStop(ctx, containerName, client.ContainerStopOptions{Timeout: &timeout})
 	if err != nil && !cerrdefs.IsNotFound(err) {
 		return domain.ErrorResult, errors.Wrap(err, "failed to stop container")
 	}
 
+	return pm.removeStoppedContainer(ctx, containerName, out)
+}
+
+// Kill sends SIGKILL to the container and removes it.
+func (pm *Docker) Kill(ctx context.Context, server *domain.Server, out io.Writer) (domain.Result, error) {
+	if err := pm.ensureClient(ctx); err != nil {
+		return domain.ErrorResult, err
+	}
+
+	containerName := pm.resolveContainerName(ctx, server)
+	_, _ = out.Write([]byte(fmt.Sprintf("Killing container %s...\n", containerName)))
+
+	_, err := pm.client.ContainerKill(ctx, containerName, client.ContainerKillOptions{Signal: "SIGKILL"})
+	if err != nil && !cerrdefs.IsNotFound(err) && !cerrdefs.IsConflict(err) {
+		return domain.ErrorResult, errors.Wrap(err, "failed to kill container")
+	}
+
+	return pm.removeStoppedContainer(ctx, containerName, out)
+}
+
+func (pm *Docker) removeStoppedContainer(
+	ctx context.Context, containerName string, out io.Writer,
+) (domain.Result, error) {
 	// Remove container after stop
 	_, _ = out.Write([]byte("Removing container...\n"))
-	_, err = pm.client.ContainerRemove(ctx, containerName, client.ContainerRemoveOptions{})
+	_, err := pm.client.ContainerRemove(ctx, containerName, client.ContainerRemoveOptions{})
 	if err != nil && !cerrdefs.IsNotFound(err) {
 		logger.Warn(ctx, errors.Wrap(err, "failed to remove container"))
 	}
@@ -452,17 +479,27 @@ func (pm *Docker) Status(ctx context.Context, server *domain.Server, out io.Writ
 		return domain.ErrorResult, errors.Wrap(err, "failed to inspect container")
 	}
 
-	if inspect.Container.State.Running {
-		_, _ = out.Write([]byte(fmt.Sprintf("Container %s is running\n", containerName)))
-		return domain.SuccessResult, nil
+	state := inspect.Container.State
+	var health string
+	if state.Health != nil {
+		health = string(state.Health.Status)
+	}
+
+	return containerStatusResult(out, containerName, state.Running, string(state.Status), health), nil
+}
+
+// ExitCode returns the exit code of the stopped server container.
+func (pm *Docker) ExitCode(ctx context.Context, server *domain.Server) (int, bool) {
+	if err := pm.ensureClient(ctx); err != nil {
+		return 0, false
+	}
+
+	inspect, err := pm.client.ContainerInspect(ctx, pm.resolveContainerName(ctx, server), client.ContainerInspectOptions{})
+	if err != nil || inspect.Container.State == nil || inspect.Container.State.Running {
+		return 0, false
 	}
 
-	msg := fmt.Sprintf(
-		"Container %s is not running (status: %s)\n",
-		containerName, inspect.Container.State.Status,
-	)
-	_, _ = out.Write([]byte(msg))
-	return domain.ErrorResult, nil
+	return inspect.Container.State.ExitCode, true
 }
 
 func (pm *Docker) GetOutput(ctx context.Context, server *domain.Server, out io.Writer) (domain.Result, error) {
@@ -521,6 +558,42 @@ func (pm *Docker) SendInput(
 	return domain.SuccessResult, nil
 }
 
+func (pm *Docker) Pause(ctx context.Context, server *domain.Server, out io.Writer) (domain.Result, error) {
+	if err := pm.ensureClient(ctx); err != nil {
+		return domain.ErrorResult, err
+	}
+
+	containerName := pm.resolveContainerName(ctx, server)
+	_, err := pm.client.ContainerPause(ctx, containerName, client.ContainerPauseOptions{})
+	if err != nil {
+		if cerrdefs.IsNotFound(err) {
+			return domain.ErrorResult, ErrContainerNotRunning
+		}
+		return domain.ErrorResult, errors.Wrap(err, "failed to pause container")
+	}
+
+	_, _ = out.Write([]byte(fmt.Sprintf("Container %s paused\n", containerName)))
+	return domain.SuccessResult, nil
+}
+
+func (pm *Docker) Unpause(ctx context.Context, server *domain.Server, out io.Writer) (domain.Result, error) {
+	if err := pm.ensureClient(ctx); err != nil {
+		return domain.ErrorResult, err
+	}
+
+	containerName := pm.resolveContainerName(ctx, server)
+	_, err := pm.client.ContainerUnpause(ctx, containerName, client.ContainerUnpauseOptions{})
+	if err != nil {
+		if cerrdefs.IsNotFound(err) {
+			return domain.ErrorResult, ErrContainerNotRunning
+		}
+		return domain.ErrorResult, errors.Wrap(err, "failed to unpause container")
+	}
+
+	_, _ = out.Write([]byte(fmt.Sprintf("Container %s unpaused\n", containerName)))
+	return domain.SuccessResult, nil
+}
+
 func (pm *Docker) Attach(
 	ctx context.Context, server *domain.Server, in io.Reader, out io.Writer,
 ) error {
@@ -652,6 +725,18 @@ func (pm *Docker) buildContainerConfig(server *domain.Server) (
 		hostConfig.Resources.NanoCPUs = int64(server.CPULimit()) * 1_000_000
 	}
 
+	// Process priority, nice and ionice have no container equivalent
+	priority, err := processPriority(pm.cfg, server)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(priority.CPUAffinity) > 0 {
+		hostConfig.Resources.CpusetCpus = formatCPUList(priority.CPUAffinity)
+	}
+	if priority.OOMScoreAdj != nil {
+		hostConfig.OomScoreAdj = *priority.OOMScoreAdj
+	}
+
 	// Capabilities
 	if caps := pm.getConfig(server, keyDockerCapabilities); caps != "" {
 		hostConfig.CapAdd = strings.Split(caps, ",")
@@ -685,6 +770,12 @@ func (pm *Docker) buildContainerConfig(server *domain.Server) (
 		hostConfig.NetworkMode = "host"
 	}
 
+	// Healthcheck
+	containerConfig.Healthcheck, err = parseHealthcheck(pm.getConfig(server, keyDockerHealthcheck))
+	if err != nil {
+		return nil, nil, err
+	}
+
 	return containerConfig, hostConfig, nil
 }
 
@@ -9,6 +9,7 @@ import (
 	"github.com/gameap/daemon/internal/app/config"
 	"github.com/gameap/daemon/internal/app/domain"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestNewDocker(t *testing.T) {
@@ -165,6 +166,25 @@ func TestDocker_getConfig_priority(t *testing.T) {
 	}
 }
 
+func TestDocker_buildContainerConfig_ProcessPriority(t *testing.T) {
+	cfg := &config.Config{WorkPath: "/tmp/test"}
+	cfg.Scripts.Start = "{command}"
+	pm := NewDocker(cfg, nil, nil)
+
+	_, hostConfig, err := pm.buildContainerConfig(createTestServer(map[string]string{
+		"cpu_affinity":  "2-3",
+		"oom_score_adj": "-100",
+		"nice":          "5",
+	}, nil, nil))
+
+	require.NoError(t, err)
+	assert.Equal(t, "2,3", hostConfig.Resources.CpusetCpus)
+	assert.Equal(t, -100, hostConfig.OomScoreAdj)
+
+	_, _, err = pm.buildContainerConfig(createTestServer(map[string]string{"oom_score_adj": "2000"}, nil, nil))
+	require.ErrorIs(t, err, errInvalidPriority)
+}
+
 func TestNormalizeImageName(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -11,6 +11,10 @@ var (
 	ErrNotImplemented        = errors.New("not implemented")
 	ErrContainerNotRunning   = errors.New("container is not running")
 	ErrServiceNotRunning     = errors.New("service is not running")
+	ErrInvalidPID            = errors.New("invalid pid")
+	ErrForeignPID            = errors.New("process does not belong to the server")
+	ErrUnsafePIDFile         = errors.New("unsafe pid file path")
+	ErrProcessStillRunning   = errors.New("process is still running")
 	ErrUserMismatch          = errors.New(
 		"server user does not match daemon user (required for systemctl --user mode)",
 	)
@@ -0,0 +1,199 @@
+package processmanager
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/gameap/daemon/internal/app/config"
+	"github.com/gameap/daemon/internal/app/domain"
+	"github.com/gameap/daemon/pkg/logger"
+	"github.com/pkg/errors"
+)
+
+const (
+	// keyStopGracePeriod is how long the server gets to exit after its stop
+	// command was sent to the console. "0" disables the console stage.
+	keyStopGracePeriod = "stop_grace_period"
+
+	// keyKillTimeout is how long the server gets to exit after SIGTERM before
+	// it is killed with SIGKILL.
+	keyKillTimeout = "kill_timeout"
+
+	defaultStopGracePeriod = 30 * time.Second
+	defaultKillTimeout     = 10 * time.Second
+
+	exitPollInterval = 500 * time.Millisecond
+)
+
+// exitChecker reports whether the server process is gone.
+type exitChecker func(ctx context.Context) bool
+
+// inputSender is implemented by every process manager.
+type inputSender interface {
+	SendInput(ctx context.Context, input string, server *domain.Server, out io.Writer) (domain.Result, error)
+}
+
+// stopViaConsole is the first stage of a graceful stop: it sends the server
+// stop command (e.g. "quit" or "stop") to the console, so the game can save
+// its state, and waits up to the stop grace period for the process to exit.
+// It reports whether the server exited on its own. When it returns false the
+// caller escalates to terminating the process.
+func stopViaConsole(
+	ctx context.Context,
+	cfg *config.Config,
+	pm inputSender,
+	server *domain.Server,
+	exited exitChecker,
+	out io.Writer,
+) bool {
+	stopCommand := server.StopCommand()
+	if stopCommand == "" {
+		return false
+	}
+
+	gracePeriod := durationConfig(cfg, server, keyStopGracePeriod, defaultStopGracePeriod)
+	if gracePeriod <= 0 {
+		return false
+	}
+
+	// Nobody reads the console of a server that is not running.
+	if exited(ctx) {
+		return false
+	}
+
+	result, err := pm.SendInput(ctx, stopCommand, server, io.Discard)
+	if err != nil {
+		logger.Warn(ctx, errors.WithMessage(err, "failed to send stop command to the server console"))
+
+		return false
+	}
+	if result != domain.SuccessResult {
+		logger.Warn(ctx, "failed to send stop command to the server console")
+
+		return false
+	}
+
+	_, _ = fmt.Fprintf(out, "Stop command sent, waiting up to %s for the server to exit...\n", gracePeriod)
+
+	if waitForExit(ctx, exited, gracePeriod) {
+		_, _ = out.Write([]byte("Server stopped\n"))
+
+		return true
+	}
+
+	_, _ = fmt.Fprintf(out, "Server is still running after %s\n", gracePeriod)
+
+	return false
+}
+
+// terminateProcess is the SIGTERM and SIGKILL stages of a graceful stop for
+// managers that own the server process directly. It reports whether the
+// process exited.
+func terminateProcess(
+	ctx context.Context,
+	cfg *config.Config,
+	server *domain.Server,
+	pid int,
+	exited exitChecker,
+	out io.Writer,
+) bool {
+	killTimeout := durationConfig(cfg, server, keyKillTimeout, defaultKillTimeout)
+
+	if err := terminateProcessGroup(pid); err != nil {
+		logger.Warn(ctx, errors.WithMessage(err, "failed to terminate server process"))
+	} else {
+		_, _ = fmt.Fprintf(out, "Sent SIGTERM to pid %d\n", pid)
+
+		if waitForExit(ctx, exited, killTimeout) {
+			_, _ = out.Write([]byte("Server stopped\n"))
+
+			return true
+		}
+	}
+
+	return killProcess(ctx, pid, exited, out)
+}
+
+// killProcess forcibly terminates the process group led by pid and waits a
+// short while for the kernel to reap it.
+func killProcess(ctx context.Context, pid int, exited exitChecker, out io.Writer) bool {
+	if err := killProcessGroup(pid); err != nil {
+		logger.Warn(ctx, errors.WithMessage(err, "failed to kill server process"))
+
+		return exited(ctx)
+	}
+
+	_, _ = fmt.Fprintf(out, "Sent SIGKILL to pid %d\n", pid)
+
+	return waitForExit(ctx, exited, defaultKillTimeout)
+}
+
+// waitForExit polls exited until it reports true, the timeout elapses or ctx
+// is done.
+func waitForExit(ctx context.Context, exited exitChecker, timeout time.Duration) bool {
+	if exited(ctx) {
+		return true
+	}
+
+	ticker := time.NewTicker(exitPollInterval)
+	defer ticker.Stop()
+
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-deadline.C:
+			return exited(ctx)
+		case <-ticker.C:
+			if exited(ctx) {
+				return true
+			}
+		}
+	}
+}
+
+// statusExitChecker treats any non-success status as "exited". Errors are
+// inconclusive, so they keep the caller waiting.
+func statusExitChecker(
+	status func(ctx context.Context, server *domain.Server, out io.Writer) (domain.Result, error),
+	server *domain.Server,
+) exitChecker {
+	return func(ctx context.Context) bool {
+		result, err := status(ctx, server, io.Discard)
+
+		return err == nil && result != domain.SuccessResult
+	}
+}
+
+func pidExitChecker(pid int) exitChecker {
+	return func(_ context.Context) bool {
+		return !processAlive(pid)
+	}
+}
+
+// durationConfig reads a duration from the server configuration. Plain
+// numbers are treated as seconds, everything else is parsed with
+// time.ParseDuration. Invalid values fall back to def.
+func durationConfig(cfg *config.Config, server *domain.Server, key string, def time.Duration) time.Duration {
+	val := getContainerConfig(cfg, server, key)
+	if val == "" {
+		return def
+	}
+
+	if seconds, err := strconv.Atoi(val); err == nil {
+		return time.Duration(seconds) * time.Second
+	}
+
+	d, err := time.ParseDuration(val)
+	if err != nil {
+		return def
+	}
+
+	return d
+}
@@ -0,0 +1,174 @@
+//go:build linux || darwin
+
+package processmanager
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os/exec"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/gameap/daemon/internal/app/config"
+	"github.com/gameap/daemon/internal/app/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeInputSender struct {
+	inputs []string
+	result domain.Result
+	onSend func()
+}
+
+func (f *fakeInputSender) SendInput(
+	_ context.Context, input string, _ *domain.Server, _ io.Writer,
+) (domain.Result, error) {
+	f.inputs = append(f.inputs, input)
+	if f.onSend != nil {
+		f.onSend()
+	}
+
+	return f.result, nil
+}
+
+func TestDurationConfig(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    string
+		expected time.Duration
+	}{
+		{name: "not set", value: "", expected: 15 * time.Second},
+		{name: "plain seconds", value: "45", expected: 45 * time.Second},
+		{name: "go duration", value: "2m", expected: 2 * time.Minute},
+		{name: "disabled", value: "0", expected: 0},
+		{name: "invalid", value: "soon", expected: 15 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newGracefulStopTestServer("", map[string]string{keyStopGracePeriod: tt.value})
+
+			got := durationConfig(&config.Config{}, server, keyStopGracePeriod, 15*time.Second)
+
+			assert.Equal(t, tt.expected, got)
+		})
+	}
+}
+
+func TestStopViaConsole_ServerExitsWithinGracePeriod(t *testing.T) {
+	var stopped atomic.Bool
+	sender := &fakeInputSender{
+		result: domain.SuccessResult,
+		onSend: func() { stopped.Store(true) },
+	}
+	server := newGracefulStopTestServer("quit", nil)
+	out := &bytes.Buffer{}
+
+	ok := stopViaConsole(context.Background(), &config.Config{}, sender, server, func(_ context.Context) bool {
+		return stopped.Load()
+	}, out)
+
+	assert.True(t, ok)
+	assert.Equal(t, []string{"quit"}, sender.inputs)
+	assert.Contains(t, out.String(), "Server stopped")
+}
+
+func TestStopViaConsole_ServerIgnoresStopCommand(t *testing.T) {
+	sender := &fakeInputSender{result: domain.SuccessResult}
+	server := newGracefulStopTestServer("quit", map[string]string{keyStopGracePeriod: "1"})
+	out := &bytes.Buffer{}
+
+	ok := stopViaConsole(context.Background(), &config.Config{}, sender, server, func(_ context.Context) bool {
+		return false
+	}, out)
+
+	assert.False(t, ok)
+	assert.Equal(t, []string{"quit"}, sender.inputs)
+	assert.Contains(t, out.String(), "still running")
+}
+
+func TestStopViaConsole_SkippedWithoutStopCommand(t *testing.T) {
+	sender := &fakeInputSender{result: domain.SuccessResult}
+	server := newGracefulStopTestServer("", nil)
+
+	ok := stopViaConsole(context.Background(), &config.Config{}, sender, server, func(_ context.Context) bool {
+		return false
+	}, io.Discard)
+
+	assert.False(t, ok)
+	assert.Empty(t, sender.inputs)
+}
+
+func TestStopViaConsole_SkippedWhenGracePeriodDisabled(t *testing.T) {
+	sender := &fakeInputSender{result: domain.SuccessResult}
+	server := newGracefulStopTestServer("quit", map[string]string{keyStopGracePeriod: "0"})
+
+	ok := stopViaConsole(context.Background(), &config.Config{}, sender, server, func(_ context.Context) bool {
+		return false
+	}, io.Discard)
+
+	assert.False(t, ok)
+	assert.Empty(t, sender.inputs)
+}
+
+func TestTerminateProcess_EscalatesToSIGKILL(t *testing.T) {
+	cmd := exec.Command("sh", "-c", `trap "" TERM; while true; do sleep 1; done`)
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	require.NoError(t, cmd.Start())
+	go func() { _ = cmd.Wait() }()
+	t.Cleanup(func() { _ = syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL) })
+
+	// Give the shell a moment to install the trap.
+	time.Sleep(200 * time.Millisecond)
+
+	server := newGracefulStopTestServer("", map[string]string{keyKillTimeout: "1"})
+	out := &bytes.Buffer{}
+
+	ok := terminateProcess(
+		context.Background(), &config.Config{}, server, cmd.Process.Pid, pidExitChecker(cmd.Process.Pid), out,
+	)
+
+	assert.True(t, ok)
+	assert.Contains(t, out.String(), "Sent SIGTERM")
+	assert.Contains(t, out.String(), "Sent SIGKILL")
+}
+
+func newGracefulStopTestServer(stopCommand string, vars map[string]string) *domain.Server {
+	if vars == nil {
+		vars = map[string]string{}
+	}
+
+	return domain.NewServer(
+		1,
+		true,
+		domain.ServerInstalled,
+		false,
+		"Test Server",
+		"test-uuid-5678",
+		"test5678",
+		domain.Game{},
+		domain.GameMod{},
+		"127.0.0.1",
+		27015,
+		27016,
+		27017,
+		"password",
+		"/servers/test",
+		"",
+		"./game_server",
+		stopCommand,
+		"",
+		"",
+		false,
+		time.Time{},
+		vars,
+		domain.Settings{},
+		time.Time{},
+		0,
+		0,
+	)
+}
@@ -17,6 +17,8 @@ func Load(
 		return NewSystemD(cfg, executor, detailedExecutor), nil
 	case "simple":
 		return NewSimple(cfg, executor, detailedExecutor), nil
+	case "native":
+		return NewNative(cfg, executor, detailedExecutor), nil
 	case "docker":
 		return NewDocker(cfg, executor, detailedExecutor), nil
 	case "podman":
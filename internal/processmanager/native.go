@@ -0,0 +1,529 @@
+//go:build linux || darwin
+
+package processmanager
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/gameap/daemon/internal/app/config"
+	"github.com/gameap/daemon/internal/app/contracts"
+	"github.com/gameap/daemon/internal/app/domain"
+	"github.com/gameap/daemon/internal/processmanager/supervisor"
+	"github.com/gameap/daemon/pkg/logger"
+	"github.com/pkg/errors"
+)
+
+const (
+	// keyOutputBufferSize is the number of console output bytes the shim
+	// keeps for GetOutput.
+	keyOutputBufferSize = "output_buffer_size"
+
+	nativeDir          = "native"
+	nativeStartTimeout = 5 * time.Second
+
+	// nativeDefaultPath is the PATH of servers when the daemon has none.
+	nativeDefaultPath = "/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin"
+)
+
+// shimLauncher starts the shim described by opts in the background.
+type shimLauncher func(opts supervisor.ShimOptions, env []string, logPath string) error
+
+// Native runs every server under its own supervisor shim, a detached daemon
+// process holding the server in a pseudo terminal. Neither tmux nor systemd
+// is needed, and servers keep running while the daemon restarts.
+type Native struct {
+	cfg              *config.Config
+	executor         contracts.Executor
+	detailedExecutor contracts.Executor
+	cpuSampler       *processCPUSampler
+	launch           shimLauncher
+}
+
+func NewNative(cfg *config.Config, executor, detailedExecutor contracts.Executor) *Native {
+	return &Native{
+		cfg:              cfg,
+		executor:         executor,
+		detailedExecutor: detailedExecutor,
+		cpuSampler:       newProcessCPUSampler(),
+		launch:           launchShim,
+	}
+}
+
+func (pm *Native) Install(_ context.Context, _ *domain.Server, _ io.Writer) (domain.Result, error) {
+	// Nothing to do here
+	return domain.SuccessResult, nil
+}
+
+func (pm *Native) Uninstall(_ context.Context, _ *domain.Server, _ io.Writer) (domain.Result, error) {
+	// Nothing to do here
+	return domain.SuccessResult, nil
+}
+
+func (pm *Native) Start(
+	ctx context.Context, server *domain.Server, out io.Writer,
+) (domain.Result, error) {
+	client := pm.client(server)
+
+	state, err := client.Status(ctx)
+	switch {
+	case err == nil && state.Running:
+		_, _ = fmt.Fprintf(out, "Server is already running (pid %d)\n", state.PID)
+
+		return domain.SuccessResult, nil
+	case err == nil:
+		// The shim of the previous run lingers to keep the exit code.
+		if err := client.Shutdown(ctx); err != nil {
+			return domain.ErrorResult, errors.WithMessage(err, "failed to shut down previous supervisor shim")
+		}
+	case !errors.Is(err, supervisor.ErrShimNotRunning):
+		return domain.ErrorResult, errors.WithMessage(err, "failed to get server status")
+	}
+
+	args, err := domain.BuildCommandArgs(pm.cfg, server, pm.cfg.Scripts.Start, server.StartCommand())
+	if err != nil {
+		return domain.ErrorResult, errors.WithMessage(err, "failed to build command")
+	}
+	if len(args) == 0 {
+		return domain.ErrorResult, ErrEmptyCommand
+	}
+
+	systemUser, err := serverUser(server)
+	if err != nil {
+		return domain.ErrorResult, errors.WithMessage(err, "invalid server configuration")
+	}
+
+	priority, err := processPriority(pm.cfg, server)
+	if err != nil {
+		return domain.ErrorResult, errors.WithMessage(err, "invalid server configuration")
+	}
+
+	workDir := server.WorkDir(pm.cfg)
+	if _, err := os.Stat(workDir); err != nil {
+		workDir = systemUser.HomeDir
+	}
+
+	bufferSize := supervisor.DefaultBufferSize
+	if val := getContainerConfig(pm.cfg, server, keyOutputBufferSize); val != "" {
+		bufferSize, err = strconv.Atoi(val)
+		if err != nil {
+			return domain.ErrorResult, errors.Wrapf(err, "invalid %s %q", keyOutputBufferSize, val)
+		}
+	}
+
+	env := nativeServerEnv(server, systemUser)
+
+	opts := supervisor.ShimOptions{
+		Socket:     pm.socketPath(server),
+		Args:       args,
+		WorkDir:    workDir,
+		UID:        systemUser.Uid,
+		GID:        systemUser.Gid,
+		BufferSize: bufferSize,
+		Linger:     supervisor.DefaultLinger,
+	}
+
+	if err := pm.launch(opts, env, pm.logPath(server)); err != nil {
+		return domain.ErrorResult, errors.WithMessage(err, "failed to start supervisor shim")
+	}
+
+	state, err = pm.waitForShim(ctx, client)
+	if err != nil {
+		return domain.ErrorResult, errors.WithMessagef(err, "supervisor shim did not start, see %s", pm.logPath(server))
+	}
+
+	if err := applyProcessTreePriority(ctx, server, state.PID, priority); err != nil {
+		logger.Warn(ctx, errors.WithMessage(err, "failed to apply server process priority"))
+	}
+
+	_, _ = fmt.Fprintf(out, "Server started (pid %d)\n", state.PID)
+
+	return domain.SuccessResult, nil
+}
+
+func (pm *Native) waitForShim(ctx context.Context, client *supervisor.Client) (supervisor.State, error) {
+	ctx, cancel := context.WithTimeout(ctx, nativeStartTimeout)
+	defer cancel()
+
+	ticker := time.NewTicker(50 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		state, err := client.Status(ctx)
+		if err == nil {
+			return state, nil
+		}
+		if !errors.Is(err, supervisor.ErrShimNotRunning) {
+			return supervisor.State{}, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return supervisor.State{}, err
+		case <-ticker.C:
+		}
+	}
+}
+
+// launchShim runs the daemon executable as the shim in a new session, so it
+// outlives the daemon and does not get the signals sent to its process group.
+func launchShim(opts supervisor.ShimOptions, env []string, logPath string) error {
+	executable, err := os.Executable()
+	if err != nil {
+		return errors.Wrap(err, "failed to find daemon executable")
+	}
+
+	if err := os.MkdirAll(filepath.Dir(logPath), 0o700); err != nil {
+		return errors.Wrap(err, "failed to create supervisor directory")
+	}
+
+	logFile, err := os.OpenFile(logPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return errors.Wrap(err, "failed to open supervisor shim log")
+	}
+	defer logFile.Close()
+
+	cmd := exec.Command(executable, opts.CommandArgs()...) //nolint:gosec
+	cmd.Env = env
+	cmd.Dir = opts.WorkDir
+	cmd.Stdout = logFile
+	cmd.Stderr = logFile
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+
+	if err := cmd.Start(); err != nil {
+		return errors.Wrap(err, "failed to exec supervisor shim")
+	}
+
+	// Reap the shim if it exits while the daemon is still running.
+	go func() { _ = cmd.Wait() }()
+
+	return nil
+}
+
+// Stop sends the stop command to the console first, then terminates and
+// finally kills the process group of the server, and shuts its shim down.
+func (pm *Native) Stop(
+	ctx context.Context, server *domain.Server, out io.Writer,
+) (domain.Result, error) {
+	client := pm.client(server)
+
+	state, err := client.Status(ctx)
+	if err != nil {
+		if errors.Is(err, supervisor.ErrShimNotRunning) {
+			return domain.SuccessResult, nil
+		}
+
+		return domain.ErrorResult, errors.WithMessage(err, "failed to get server status")
+	}
+
+	if state.Running {
+		exited := pm.exitChecker(client)
+
+		if !stopViaConsole(ctx, pm.cfg, pm, server, exited, out) &&
+			!terminateProcess(ctx, pm.cfg, server, state.PID, exited, out) {
+			return domain.ErrorResult, errors.Wrapf(ErrProcessStillRunning, "pid %d", state.PID)
+		}
+	}
+
+	pm.shutdownShim(ctx, client)
+
+	return domain.SuccessResult, nil
+}
+
+// Kill skips the console and SIGTERM stages and kills the process group of
+// the server with SIGKILL right away.
+func (pm *Native) Kill(
+	ctx context.Context, server *domain.Server, out io.Writer,
+) (domain.Result, error) {
+	client := pm.client(server)
+
+	state, err := client.Status(ctx)
+	if err != nil {
+		if errors.Is(err, supervisor.ErrShimNotRunning) {
+			return domain.SuccessResult, nil
+		}
+
+		return domain.ErrorResult, errors.WithMessage(err, "failed to get server status")
+	}
+
+	if state.Running && !killProcess(ctx, state.PID, pm.exitChecker(client), out) {
+		return domain.ErrorResult, errors.Wrapf(ErrProcessStillRunning, "pid %d", state.PID)
+	}
+
+	pm.shutdownShim(ctx, client)
+
+	return domain.SuccessResult, nil
+}
+
+// shutdownShim stops the shim of a server that exited. The shim would go
+// away by itself after lingering, this only frees it early.
+func (pm *Native) shutdownShim(ctx context.Context, client *supervisor.Client) {
+	if err := client.Shutdown(ctx); err != nil && !errors.Is(err, supervisor.ErrShimNotRunning) {
+		logger.Debug(ctx, errors.WithMessage(err, "failed to shut down supervisor shim"))
+	}
+}
+
+// exitChecker asks the shim, which reaps the server and so knows when it is
+// gone. A vanished shim takes the server with it, other errors are
+// inconclusive.
+func (pm *Native) exitChecker(client *supervisor.Client) exitChecker {
+	return func(ctx context.Context) bool {
+		state, err := client.Status(ctx)
+		if err != nil {
+			return errors.Is(err, supervisor.ErrShimNotRunning)
+		}
+
+		return !state.Running
+	}
+}
+
+func (pm *Native) Restart(
+	ctx context.Context, server *domain.Server, out io.Writer,
+) (domain.Result, error) {
+	statusResult, err := pm.Status(ctx, server, out)
+	if err != nil {
+		return domain.ErrorResult, errors.WithMessage(err, "failed to get server status")
+	}
+
+	if statusResult == domain.SuccessResult {
+		_, err = pm.Stop(ctx, server, out)
+		if err != nil {
+			return domain.ErrorResult, errors.WithMessage(err, "failed to stop server")
+		}
+	}
+
+	return pm.Start(ctx, server, out)
+}
+
+func (pm *Native) Status(
+	ctx context.Context, server *domain.Server, out io.Writer,
+) (domain.Result, error) {
+	state, err := pm.client(server).Status(ctx)
+	if err != nil {
+		if errors.Is(err, supervisor.ErrShimNotRunning) {
+			_, _ = fmt.Fprintln(out, "Server is not running")
+
+			return domain.ErrorResult, nil
+		}
+
+		return domain.ErrorResult, errors.WithMessage(err, "failed to get server status")
+	}
+
+	if !state.Running {
+		_, _ = fmt.Fprintf(out, "Server is not running (exit code %d)\n", state.ExitCode)
+
+		return domain.ErrorResult, nil
+	}
+
+	_, _ = fmt.Fprintf(out, "Server is running (pid %d)\n", state.PID)
+
+	return domain.SuccessResult, nil
+}
+
+// ExitCode returns the exit status of the server while its shim lingers
+// after the exit.
+func (pm *Native) ExitCode(ctx context.Context, server *domain.Server) (int, bool) {
+	state, err := pm.client(server).Status(ctx)
+	if err != nil || state.Running {
+		return 0, false
+	}
+
+	return state.ExitCode, true
+}
+
+func (pm *Native) GetOutput(
+	ctx context.Context, server *domain.Server, out io.Writer,
+) (domain.Result, error) {
+	output, err := pm.client(server).Output(ctx)
+	if err != nil {
+		if errors.Is(err, supervisor.ErrShimNotRunning) {
+			return domain.ErrorResult, ErrServiceNotRunning
+		}
+
+		return domain.ErrorResult, errors.WithMessage(err, "failed to get server output")
+	}
+
+	if _, err := out.Write(output); err != nil {
+		return domain.ErrorResult, errors.WithMessage(err, "failed to write output")
+	}
+
+	return domain.SuccessResult, nil
+}
+
+func (pm *Native) SendInput(
+	ctx context.Context, input string, server *domain.Server, _ io.Writer,
+) (domain.Result, error) {
+	err := pm.client(server).Input(ctx, input)
+	if err != nil {
+		if errors.Is(err, supervisor.ErrShimNotRunning) || errors.Is(err, supervisor.ErrProcessNotRunning) {
+			return domain.ErrorResult, ErrServiceNotRunning
+		}
+
+		return domain.ErrorResult, errors.WithMessage(err, "failed to send input")
+	}
+
+	return domain.SuccessResult, nil
+}
+
+func (pm *Native) Attach(
+	ctx context.Context, server *domain.Server, in io.Reader, out io.Writer,
+) error {
+	err := pm.client(server).Attach(ctx, in, out)
+	if errors.Is(err, supervisor.ErrShimNotRunning) || errors.Is(err, supervisor.ErrProcessNotRunning) {
+		return ErrServiceNotRunning
+	}
+
+	return err
+}
+
+func (pm *Native) Pause(
+	ctx context.Context, server *domain.Server, out io.Writer,
+) (domain.Result, error) {
+	return pm.signalServer(ctx, server, freezeProcessGroup, "paused", out)
+}
+
+func (pm *Native) Unpause(
+	ctx context.Context, server *domain.Server, out io.Writer,
+) (domain.Result, error) {
+	return pm.signalServer(ctx, server, thawProcessGroup, "resumed", out)
+}
+
+// signalServer applies signal to the process group of the server. The shim
+// starts the server in its own session, so the group holds the start command
+// and everything it spawned.
+func (pm *Native) signalServer(
+	ctx context.Context,
+	server *domain.Server,
+	signal func(pid int) error,
+	action string,
+	out io.Writer,
+) (domain.Result, error) {
+	pid, err := pm.serverPID(ctx, server)
+	if err != nil {
+		return domain.ErrorResult, err
+	}
+
+	if err := signal(pid); err != nil {
+		return domain.ErrorResult, errors.WithMessagef(err, "failed to signal process group %d", pid)
+	}
+
+	_, _ = fmt.Fprintf(out, "Server processes %s (pid %d)\n", action, pid)
+
+	return domain.SuccessResult, nil
+}
+
+func (pm *Native) serverPID(ctx context.Context, server *domain.Server) (int, error) {
+	state, err := pm.client(server).Status(ctx)
+	if err != nil {
+		if errors.Is(err, supervisor.ErrShimNotRunning) {
+			return 0, ErrServiceNotRunning
+		}
+
+		return 0, errors.WithMessage(err, "failed to get server status")
+	}
+	if !state.Running {
+		return 0, ErrServiceNotRunning
+	}
+
+	return state.PID, nil
+}
+
+func (pm *Native) HasOwnInstallation(_ *domain.Server) bool {
+	return false
+}
+
+// Metrics returns CPU, memory, block-IO and PID counters summed over the
+// process tree of the server. Falls back to the cached liveness gauge alone
+// when the server is not running.
+func (pm *Native) Metrics(ctx context.Context, server *domain.Server) ([]domain.Metric, error) {
+	now := time.Now()
+	out := make([]domain.Metric, 0, 6)
+	out = append(out, livenessMetric(server, now))
+
+	pid, err := pm.serverPID(ctx, server)
+	if err != nil {
+		return out, nil
+	}
+
+	stats, err := collectProcessTreeStats(ctx, int32(pid))
+	if err != nil {
+		logger.Debug(ctx, errors.WithMessage(err, "failed to collect server process metrics"))
+		return out, nil
+	}
+
+	cpuPercent, hasCPU := pm.cpuSampler.percent(server.XID(), processCPUSample{
+		rootPID:    int32(pid),
+		cpuSeconds: stats.CPUSeconds,
+		at:         now,
+	})
+
+	labels := map[string]string{metricLabelSession: server.XID()}
+	out = append(out, processTreeToMetrics(now, labels, stats, cpuPercent, hasCPU)...)
+
+	return out, nil
+}
+
+func (pm *Native) client(server *domain.Server) *supervisor.Client {
+	return supervisor.NewClient(pm.socketPath(server))
+}
+
+func (pm *Native) stateDir() string {
+	if pm.cfg.StatePath != "" {
+		return filepath.Join(pm.cfg.StatePath, nativeDir)
+	}
+
+	return filepath.Join(os.TempDir(), "gameap-daemon", nativeDir)
+}
+
+func (pm *Native) socketPath(server *domain.Server) string {
+	return filepath.Join(pm.stateDir(), server.XID()+".sock")
+}
+
+func (pm *Native) logPath(server *domain.Server) string {
+	return filepath.Join(pm.stateDir(), server.XID()+".log")
+}
+
+// serverUser returns the system user of the server, the daemon user when
+// the server has none.
+// nativeServerEnv returns the environment of the server process: PATH, the
+// home and the name of its user and the server variables. The daemon
+// environment is not passed on, it may hold the credentials of the daemon.
+func nativeServerEnv(server *domain.Server, systemUser *user.User) []string {
+	path := os.Getenv("PATH")
+	if path == "" {
+		path = nativeDefaultPath
+	}
+
+	env := []string{"PATH=" + path}
+	for key, value := range server.EnvironmentVars() {
+		env = append(env, key+"="+value)
+	}
+
+	return append(env, "HOME="+systemUser.HomeDir, "USER="+systemUser.Username)
+}
+
+func serverUser(server *domain.Server) (*user.User, error) {
+	if server.User() == "" {
+		u, err := user.Current()
+		if err != nil {
+			return nil, errors.WithMessage(err, "failed to get current user")
+		}
+
+		return u, nil
+	}
+
+	u, err := user.Lookup(server.User())
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to lookup user %s", server.User())
+	}
+
+	return u, nil
+}
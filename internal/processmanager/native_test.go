@@ -0,0 +1,181 @@
+//go:build linux || darwin
+
+package processmanager
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"os/user"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gameap/daemon/internal/app/config"
+	"github.com/gameap/daemon/internal/app/domain"
+	"github.com/gameap/daemon/internal/processmanager/supervisor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// givenNative returns a Native manager running its shims inside the test
+// process instead of a detached daemon executable.
+func givenNative(t *testing.T) *Native {
+	t.Helper()
+
+	// Unix socket paths are short, so not the test temp dir.
+	statePath, err := os.MkdirTemp("", "native")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var shims sync.WaitGroup
+	t.Cleanup(func() {
+		cancel()
+		shims.Wait()
+		_ = os.RemoveAll(statePath)
+	})
+
+	cfg := &config.Config{StatePath: statePath}
+	cfg.Scripts.Start = "{command}"
+
+	pm := NewNative(cfg, nil, nil)
+	pm.launch = func(opts supervisor.ShimOptions, env []string, _ string) error {
+		opts.Env = env
+		shims.Go(func() {
+			_ = supervisor.RunShim(ctx, opts)
+		})
+
+		return nil
+	}
+
+	return pm
+}
+
+func givenNativeServer(startCommand, stopCommand string) *domain.Server {
+	return domain.NewServer(
+		1, true, domain.ServerInstalled, false,
+		"Test Server", "test-uuid-5678", "test5678",
+		domain.Game{}, domain.GameMod{},
+		"127.0.0.1", 27015, 27016, 27017, "",
+		"/servers/test", "",
+		startCommand, stopCommand, "", "",
+		false, time.Time{},
+		map[string]string{"stop_grace_period": "5"}, domain.Settings{}, time.Time{},
+		0, 0,
+	)
+}
+
+func waitForNativeExit(t *testing.T, pm *Native, server *domain.Server) {
+	t.Helper()
+
+	require.Eventually(t, func() bool {
+		result, err := pm.Status(context.Background(), server, io.Discard)
+		return err == nil && result == domain.ErrorResult
+	}, 5*time.Second, 20*time.Millisecond)
+}
+
+func TestNative_StartInputOutput(t *testing.T) {
+	pm := givenNative(t)
+	server := givenNativeServer(`sh -c "read line; echo got $line; exit 3"`, "")
+	ctx := context.Background()
+
+	result, err := pm.Start(ctx, server, io.Discard)
+	require.NoError(t, err)
+	require.Equal(t, domain.SuccessResult, result)
+
+	result, err = pm.Status(ctx, server, io.Discard)
+	require.NoError(t, err)
+	assert.Equal(t, domain.SuccessResult, result)
+
+	result, err = pm.SendInput(ctx, "hello", server, io.Discard)
+	require.NoError(t, err)
+	assert.Equal(t, domain.SuccessResult, result)
+
+	waitForNativeExit(t, pm, server)
+
+	out := &bytes.Buffer{}
+	_, err = pm.GetOutput(ctx, server, out)
+	require.NoError(t, err)
+	assert.Contains(t, out.String(), "got hello")
+
+	code, ok := pm.ExitCode(ctx, server)
+	assert.True(t, ok)
+	assert.Equal(t, 3, code)
+
+	_, err = pm.SendInput(ctx, "again", server, io.Discard)
+	require.ErrorIs(t, err, ErrServiceNotRunning)
+}
+
+func TestNative_StartMinimalEnv(t *testing.T) {
+	t.Setenv("GAMEAP_DAEMON_SECRET", "secret")
+	pm := givenNative(t)
+	server := givenNativeServer(`sh -c 'echo "secret=$GAMEAP_DAEMON_SECRET user=$USER path=$PATH"'`, "")
+	ctx := context.Background()
+
+	_, err := pm.Start(ctx, server, io.Discard)
+	require.NoError(t, err)
+
+	waitForNativeExit(t, pm, server)
+
+	out := &bytes.Buffer{}
+	_, err = pm.GetOutput(ctx, server, out)
+	require.NoError(t, err)
+
+	current, err := user.Current()
+	require.NoError(t, err)
+	assert.Contains(t, out.String(), "secret= user="+current.Username+" path="+os.Getenv("PATH"))
+}
+
+func TestNative_StopViaConsole(t *testing.T) {
+	pm := givenNative(t)
+	server := givenNativeServer(`sh -c "while read line; do [ $line = quit ] && exit 0; done"`, "quit")
+	ctx := context.Background()
+
+	_, err := pm.Start(ctx, server, io.Discard)
+	require.NoError(t, err)
+
+	result, err := pm.Stop(ctx, server, io.Discard)
+
+	require.NoError(t, err)
+	assert.Equal(t, domain.SuccessResult, result)
+	_, ok := pm.ExitCode(ctx, server)
+	assert.False(t, ok, "the shim is shut down after stop")
+}
+
+func TestNative_Kill(t *testing.T) {
+	pm := givenNative(t)
+	server := givenNativeServer("sleep 60", "")
+	ctx := context.Background()
+
+	_, err := pm.Start(ctx, server, io.Discard)
+	require.NoError(t, err)
+
+	result, err := pm.Kill(ctx, server, io.Discard)
+
+	require.NoError(t, err)
+	assert.Equal(t, domain.SuccessResult, result)
+	result, err = pm.Status(ctx, server, io.Discard)
+	require.NoError(t, err)
+	assert.Equal(t, domain.ErrorResult, result)
+}
+
+func TestNative_NotRunning(t *testing.T) {
+	pm := givenNative(t)
+	server := givenNativeServer("sleep 60", "")
+	ctx := context.Background()
+
+	result, err := pm.Status(ctx, server, io.Discard)
+	require.NoError(t, err)
+	assert.Equal(t, domain.ErrorResult, result)
+
+	_, ok := pm.ExitCode(ctx, server)
+	assert.False(t, ok)
+
+	err = pm.Attach(ctx, server, bytes.NewReader(nil), io.Discard)
+	require.ErrorIs(t, err, ErrServiceNotRunning)
+
+	result, err = pm.Stop(ctx, server, io.Discard)
+	require.NoError(t, err)
+	assert.Equal(t, domain.SuccessResult, result)
+}
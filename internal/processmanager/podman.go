@@ -14,13 +14,16 @@ import (
 	"os/user"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/gameap/daemon/internal/app/config"
 	"github.com/gameap/daemon/internal/app/contracts"
 	"github.com/gameap/daemon/internal/app/domain"
 	"github.com/gameap/daemon/pkg/logger"
+	"github.com/moby/moby/api/pkg/stdcopy"
 	"github.com/pkg/errors"
+	"golang.org/x/sync/errgroup"
 )
 
 const (
@@ -32,14 +35,18 @@ const (
 var (
 	errPodmanInstallationFailed = errors.New("installation failed")
 	errPodmanPullImage          = errors.New("failed to pull image")
-	errPodmanCreateExec         = errors.New("failed to create exec")
 	errPodmanCreateContainer    = errors.New("failed to create container")
 	errPodmanStartContainer     = errors.New("failed to start container")
 	errPodmanStopContainer      = errors.New("failed to stop container")
+	errPodmanKillContainer      = errors.New("failed to kill container")
+	errPodmanPauseContainer     = errors.New("failed to pause container")
+	errPodmanUnpauseContainer   = errors.New("failed to unpause container")
 	errPodmanRemoveContainer    = errors.New("failed to remove container")
 	errPodmanWaitContainer      = errors.New("failed to wait for container")
 	errPodmanInspectContainer   = errors.New("failed to inspect container")
 	errPodmanGetLogs            = errors.New("failed to get logs")
+	errPodmanAttachContainer    = errors.New("failed to attach to container")
+	errPodmanAttachClosed       = errors.New("attach stream closed")
 )
 
 // Podman metadata configuration keys (same as Docker for compatibility)
@@ -54,6 +61,7 @@ const (
 	keyPodmanCapabilities           = "docker_capabilities"
 	keyPodmanPrivileged             = "docker_privileged"
 	keyPodmanVolumes                = "docker_volumes"
+	keyPodmanHealthcheck            = "docker_healthcheck"
 	keyPodmanDNS                    = "docker_dns"
 	keyPodmanWorkDir                = "docker_workdir"
 )
@@ -62,6 +70,11 @@ type Podman struct {
 	cfg        *config.Config
 	socketPath string
 	httpClient *http.Client
+	subUIDPath string
+	subGIDPath string
+
+	rootlessMu sync.Mutex
+	rootless   *bool
 }
 
 func NewPodman(cfg *config.Config, _, _ contracts.Executor) *Podman {
@@ -83,6 +96,8 @@ func NewPodman(cfg *config.Config, _, _ contracts.Executor) *Podman {
 			Transport: transport,
 			Timeout:   5 * time.Minute,
 		},
+		subUIDPath: defaultSubUIDPath,
+		subGIDPath: defaultSubGIDPath,
 	}
 }
 
@@ -297,7 +312,7 @@ func (pm *Podman) Uninstall(ctx context.Context, server *domain.Server, out io.W
 	_, _ = out.Write([]byte(fmt.Sprintf("Removing container %s...\n", containerName)))
 
 	// Stop container if running
-	_ = pm.stopContainer(ctx, containerName)
+	_ = pm.stopContainer(ctx, containerName, podmanDefaultStopTimeout)
 
 	// Remove container
 	if err := pm.removeContainer(ctx, containerName); err != nil && !isPodmanNotFoundError(err) {
@@ -307,7 +322,7 @@ func (pm *Podman) Uninstall(ctx context.Context, server *domain.Server, out io.W
 	// Also clean up container with the other name if different
 	legacyName := pm.legacyContainerName(server)
 	if legacyName != containerName {
-		_ = pm.stopContainer(ctx, legacyName)
+		_ = pm.stopContainer(ctx, legacyName, podmanDefaultStopTimeout)
 		_ = pm.removeContainer(ctx, legacyName)
 	}
 
@@ -336,7 +351,7 @@ func (pm *Podman) Start(ctx context.Context, server *domain.Server, out io.Write
 	}
 
 	// Build container spec
-	spec, err := pm.buildContainerSpec(server)
+	spec, err := pm.buildContainerSpec(ctx, server)
 	if err != nil {
 		return domain.ErrorResult, errors.Wrap(err, "failed to build container spec")
 	}
@@ -364,12 +379,35 @@ func (pm *Podman) Start(ctx context.Context, server *domain.Server, out io.Write
 
 func (pm *Podman) Stop(ctx context.Context, server *domain.Server, out io.Writer) (domain.Result, error) {
 	containerName := pm.resolveContainerName(ctx, server)
+
+	stopViaConsole(ctx, pm.cfg, pm, server, statusExitChecker(pm.Status, server), out)
+
 	_, _ = out.Write([]byte(fmt.Sprintf("Stopping container %s...\n", containerName)))
 
-	if err := pm.stopContainer(ctx, containerName); err != nil && !isPodmanNotFoundError(err) {
+	// Podman sends SIGTERM and, once the timeout expires, SIGKILL.
+	timeout := durationConfig(pm.cfg, server, keyKillTimeout, podmanDefaultStopTimeout)
+	if err := pm.stopContainer(ctx, containerName, timeout); err != nil && !isPodmanNotFoundError(err) {
 		return domain.ErrorResult, errors.Wrap(err, "failed to stop container")
 	}
 
+	return pm.removeStoppedContainer(ctx, containerName, out)
+}
+
+// Kill sends SIGKILL to the container and removes it.
+func (pm *Podman) Kill(ctx context.Context, server *domain.Server, out io.Writer) (domain.Result, error) {
+	containerName := pm.resolveContainerName(ctx, server)
+	_, _ = out.Write([]byte(fmt.Sprintf("Killing container %s...\n", containerName)))
+
+	if err := pm.killContainer(ctx, containerName); err != nil && !isPodmanNotFoundError(err) {
+		return domain.ErrorResult, errors.Wrap(err, "failed to kill container")
+	}
+
+	return pm.removeStoppedContainer(ctx, containerName, out)
+}
+
+func (pm *Podman) removeStoppedContainer(
+	ctx context.Context, containerName string, out io.Writer,
+) (domain.Result, error) {
 	// Remove container after stop
 	_, _ = out.Write([]byte("Removing container...\n"))
 	if err := pm.removeContainer(ctx, containerName); err != nil && !isPodmanNotFoundError(err) {
@@ -391,7 +429,7 @@ func (pm *Podman) Restart(ctx context.Context, server *domain.Server, out io.Wri
 
 func (pm *Podman) Status(ctx context.Context, server *domain.Server, out io.Writer) (domain.Result, error) {
 	containerName := pm.resolveContainerName(ctx, server)
-	running, status, err := pm.inspectContainer(ctx, containerName)
+	state, err := pm.inspectContainerState(ctx, containerName)
 	if err != nil {
 		if isPodmanNotFoundError(err) {
 			_, _ = out.Write([]byte(fmt.Sprintf("Container %s not found\n", containerName)))
@@ -400,13 +438,7 @@ func (pm *Podman) Status(ctx context.Context, server *domain.Server, out io.Writ
 		return domain.ErrorResult, errors.Wrap(err, "failed to inspect container")
 	}
 
-	if running {
-		_, _ = out.Write([]byte(fmt.Sprintf("Container %s is running\n", containerName)))
-		return domain.SuccessResult, nil
-	}
-
-	_, _ = out.Write([]byte(fmt.Sprintf("Container %s is not running (status: %s)\n", containerName, status)))
-	return domain.ErrorResult, nil
+	return containerStatusResult(out, containerName, state.Running, state.Status, state.healthStatus()), nil
 }
 
 func (pm *Podman) GetOutput(ctx context.Context, server *domain.Server, out io.Writer) (domain.Result, error) {
@@ -428,48 +460,21 @@ func (pm *Podman) SendInput(
 ) (domain.Result, error) {
 	containerName := pm.resolveContainerName(ctx, server)
 
-	// Use exec to send input (simpler than attach for single commands)
-	execSpec := map[string]interface{}{
-		"AttachStdin":  true,
-		"AttachStdout": false,
-		"AttachStderr": false,
-		"Tty":          false,
-		// Pass the input as a positional parameter ($1) instead of interpolating
-		// it into the script, so the shell never re-parses user-controlled text.
-		"Cmd": []string{"/bin/sh", "-c", `echo "$1"`, "sh", input},
-	}
-
-	path := fmt.Sprintf("/containers/%s/exec", containerName)
-	resp, err := pm.doRequest(ctx, http.MethodPost, path, execSpec)
+	// Write the input to the container stdin as the attached console does
+	conn, err := pm.attachContainer(ctx, containerName, false)
 	if err != nil {
-		return domain.ErrorResult, errors.Wrap(err, "failed to create exec")
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusCreated {
-		body, _ := io.ReadAll(resp.Body)
-		return domain.ErrorResult, errors.Wrapf(errPodmanCreateExec, "%s", string(body))
-	}
-
-	var execResp struct {
-		ID string `json:"Id"`
-	}
-	if err := json.NewDecoder(resp.Body).Decode(&execResp); err != nil {
-		return domain.ErrorResult, errors.Wrap(err, "failed to decode exec response")
+		return domain.ErrorResult, err
 	}
+	defer conn.Close()
 
-	// Start exec
-	startPath := fmt.Sprintf("/exec/%s/start", execResp.ID)
-	startResp, err := pm.doRequest(ctx, http.MethodPost, startPath, map[string]bool{"Detach": true})
-	if err != nil {
-		return domain.ErrorResult, errors.Wrap(err, "failed to start exec")
+	if _, err := io.WriteString(conn, input+"\n"); err != nil {
+		return domain.ErrorResult, errors.Wrap(err, "failed to write to container stdin")
 	}
-	defer startResp.Body.Close()
 
 	return domain.SuccessResult, nil
 }
 
-func (pm *Podman) buildContainerSpec(server *domain.Server) (map[string]interface{}, error) {
+func (pm *Podman) buildContainerSpec(ctx context.Context, server *domain.Server) (map[string]interface{}, error) {
 	imageName := normalizeImageName(pm.getConfig(server, keyPodmanImage))
 	containerName := pm.containerName(server)
 
@@ -479,10 +484,10 @@ func (pm *Podman) buildContainerSpec(server *domain.Server) (map[string]interfac
 		return nil, errors.Wrap(err, "failed to parse start command")
 	}
 
-	// Get user IDs
-	uid, gid, err := pm.getUserIDs(server)
+	// User and user namespace
+	userNS, err := pm.userNamespace(ctx, server)
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to get user IDs")
+		return nil, err
 	}
 
 	// Build environment variables
@@ -501,7 +506,7 @@ func (pm *Podman) buildContainerSpec(server *domain.Server) (map[string]interfac
 		"work_dir": containerWorkDir,
 		"env":      env,
 		"command":  cmdSlice,
-		"user":     fmt.Sprintf("%s:%s", uid, gid),
+		"user":     userNS.user,
 		"stdin":    true,
 		"terminal": false,
 		"mounts": []map[string]interface{}{
@@ -526,16 +531,40 @@ func (pm *Podman) buildContainerSpec(server *domain.Server) (map[string]interfac
 		nanoCPUs := int64(server.CPULimit()) * 1_000_000
 		period := int64(100000)
 		quota := int64((float64(nanoCPUs) / 1e9) * float64(period))
-		resourceLimits["cpu"] = map[string]int64{
+		resourceLimits["cpu"] = map[string]interface{}{
 			"period": period,
 			"quota":  quota,
 		}
 	}
 
+	// Process priority, nice and ionice have no container equivalent
+	priority, err := processPriority(pm.cfg, server)
+	if err != nil {
+		return nil, err
+	}
+	if len(priority.CPUAffinity) > 0 {
+		cpu, ok := resourceLimits["cpu"].(map[string]interface{})
+		if !ok {
+			cpu = make(map[string]interface{})
+			resourceLimits["cpu"] = cpu
+		}
+		cpu["cpus"] = formatCPUList(priority.CPUAffinity)
+	}
+	if priority.OOMScoreAdj != nil {
+		spec["oom_score_adj"] = *priority.OOMScoreAdj
+	}
+
 	if len(resourceLimits) > 0 {
 		spec["resource_limits"] = resourceLimits
 	}
 
+	if userNS.userns != nil {
+		spec["userns"] = userNS.userns
+	}
+	if userNS.idMappings != nil {
+		spec["idmappings"] = userNS.idMappings
+	}
+
 	// Capabilities
 	if caps := pm.getConfig(server, keyPodmanCapabilities); caps != "" {
 		spec["cap_add"] = strings.Split(caps, ",")
@@ -565,6 +594,15 @@ func (pm *Podman) buildContainerSpec(server *domain.Server) (map[string]interfac
 		spec["netns"] = map[string]string{"nsmode": "host"}
 	}
 
+	// Healthcheck, libpod takes the same config as Docker
+	healthcheck, err := parseHealthcheck(pm.getConfig(server, keyPodmanHealthcheck))
+	if err != nil {
+		return nil, err
+	}
+	if healthcheck != nil {
+		spec["healthconfig"] = healthcheck
+	}
+
 	return spec, nil
 }
 
@@ -637,9 +675,8 @@ func (pm *Podman) startContainer(ctx context.Context, nameOrID string) error {
 	return nil
 }
 
-func (pm *Podman) stopContainer(ctx context.Context, nameOrID string) error {
-	timeout := int(podmanDefaultStopTimeout.Seconds())
-	path := fmt.Sprintf("/containers/%s/stop?timeout=%d", nameOrID, timeout)
+func (pm *Podman) stopContainer(ctx context.Context, nameOrID string, timeout time.Duration) error {
+	path := fmt.Sprintf("/containers/%s/stop?timeout=%d", nameOrID, int(timeout.Seconds()))
 	resp, err := pm.doRequest(ctx, http.MethodPost, path, nil)
 	if err != nil {
 		return err
@@ -656,6 +693,51 @@ func (pm *Podman) stopContainer(ctx context.Context, nameOrID string) error {
 	return nil
 }
 
+// killContainer sends SIGKILL to a running container. Podman answers 409
+// when the container is not running, which is the desired state already.
+func (pm *Podman) killContainer(ctx context.Context, nameOrID string) error {
+	path := fmt.Sprintf("/containers/%s/kill?signal=SIGKILL", nameOrID)
+	resp, err := pm.doRequest(ctx, http.MethodPost, path, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent &&
+		resp.StatusCode != http.StatusOK &&
+		resp.StatusCode != http.StatusNotFound &&
+		resp.StatusCode != http.StatusConflict {
+		body, _ := io.ReadAll(resp.Body)
+		return errors.Wrapf(errPodmanKillContainer, "%s", string(body))
+	}
+
+	return nil
+}
+
+func (pm *Podman) pauseContainer(ctx context.Context, nameOrID string) error {
+	return pm.postContainerAction(ctx, nameOrID, "pause", errPodmanPauseContainer)
+}
+
+func (pm *Podman) unpauseContainer(ctx context.Context, nameOrID string) error {
+	return pm.postContainerAction(ctx, nameOrID, "unpause", errPodmanUnpauseContainer)
+}
+
+func (pm *Podman) postContainerAction(ctx context.Context, nameOrID, action string, actionErr error) error {
+	path := fmt.Sprintf("/containers/%s/%s", nameOrID, action)
+	resp, err := pm.doRequest(ctx, http.MethodPost, path, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return errors.Wrapf(actionErr, "%s", string(body))
+	}
+
+	return nil
+}
+
 func (pm *Podman) removeContainer(ctx context.Context, nameOrID string) error {
 	path := fmt.Sprintf("/containers/%s?force=true", nameOrID)
 	resp, err := pm.doRequest(ctx, http.MethodDelete, path, nil)
@@ -697,34 +779,75 @@ func (pm *Podman) waitContainer(ctx context.Context, nameOrID string) (int, erro
 	return waitResp.StatusCode, nil
 }
 
+type podmanContainerState struct {
+	Running  bool                `json:"Running"`
+	Status   string              `json:"Status"`
+	ExitCode int                 `json:"ExitCode"`
+	Health   *podmanHealthResult `json:"Health,omitempty"`
+
+	// Healthcheck is the name of Health before Podman 4.3.
+	Healthcheck *podmanHealthResult `json:"Healthcheck,omitempty"`
+}
+
+type podmanHealthResult struct {
+	Status string `json:"Status"`
+}
+
+func (s podmanContainerState) healthStatus() string {
+	switch {
+	case s.Health != nil && s.Health.Status != "":
+		return s.Health.Status
+	case s.Healthcheck != nil:
+		return s.Healthcheck.Status
+	}
+
+	return ""
+}
+
 func (pm *Podman) inspectContainer(ctx context.Context, nameOrID string) (bool, string, error) {
+	state, err := pm.inspectContainerState(ctx, nameOrID)
+	if err != nil {
+		return false, "", err
+	}
+
+	return state.Running, state.Status, nil
+}
+
+func (pm *Podman) inspectContainerState(ctx context.Context, nameOrID string) (podmanContainerState, error) {
 	path := fmt.Sprintf("/containers/%s/json", nameOrID)
 	resp, err := pm.doRequest(ctx, http.MethodGet, path, nil)
 	if err != nil {
-		return false, "", err
+		return podmanContainerState{}, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode == http.StatusNotFound {
-		return false, "", errors.New("container not found")
+		return podmanContainerState{}, errors.New("container not found")
 	}
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return false, "", errors.Wrapf(errPodmanInspectContainer, "%s", string(body))
+		return podmanContainerState{}, errors.Wrapf(errPodmanInspectContainer, "%s", string(body))
 	}
 
 	var inspectResp struct {
-		State struct {
-			Running bool   `json:"Running"`
-			Status  string `json:"Status"`
-		} `json:"State"`
+		State podmanContainerState `json:"State"`
 	}
 	if err := json.NewDecoder(resp.Body).Decode(&inspectResp); err != nil {
-		return false, "", errors.Wrap(err, "failed to decode inspect response")
+		return podmanContainerState{}, errors.Wrap(err, "failed to decode inspect response")
 	}
 
-	return inspectResp.State.Running, inspectResp.State.Status, nil
+	return inspectResp.State, nil
+}
+
+// ExitCode returns the exit code of the stopped server container.
+func (pm *Podman) ExitCode(ctx context.Context, server *domain.Server) (int, bool) {
+	state, err := pm.inspectContainerState(ctx, pm.resolveContainerName(ctx, server))
+	if err != nil || state.Running {
+		return 0, false
+	}
+
+	return state.ExitCode, true
 }
 
 func (pm *Podman) getLogs(ctx context.Context, nameOrID string, tailLines int) (string, error) {
@@ -833,10 +956,120 @@ func (pm *Podman) getConfig(server *domain.Server, key string) string {
 	return getContainerConfig(pm.cfg, server, key)
 }
 
+func (pm *Podman) Pause(ctx context.Context, server *domain.Server, out io.Writer) (domain.Result, error) {
+	containerName := pm.resolveContainerName(ctx, server)
+	if err := pm.pauseContainer(ctx, containerName); err != nil {
+		return domain.ErrorResult, err
+	}
+
+	_, _ = out.Write([]byte(fmt.Sprintf("Container %s paused\n", containerName)))
+	return domain.SuccessResult, nil
+}
+
+func (pm *Podman) Unpause(ctx context.Context, server *domain.Server, out io.Writer) (domain.Result, error) {
+	containerName := pm.resolveContainerName(ctx, server)
+	if err := pm.unpauseContainer(ctx, containerName); err != nil {
+		return domain.ErrorResult, err
+	}
+
+	_, _ = out.Write([]byte(fmt.Sprintf("Container %s unpaused\n", containerName)))
+	return domain.SuccessResult, nil
+}
+
 func (pm *Podman) Attach(
-	_ context.Context, _ *domain.Server, _ io.Reader, _ io.Writer,
+	ctx context.Context, server *domain.Server, in io.Reader, out io.Writer,
 ) error {
-	return ErrNotImplemented
+	containerName := pm.resolveContainerName(ctx, server)
+
+	running, _, err := pm.inspectContainer(ctx, containerName)
+	if err != nil {
+		return errors.Wrap(err, "failed to inspect container")
+	}
+	if !running {
+		return ErrContainerNotRunning
+	}
+
+	conn, err := pm.attachContainer(ctx, containerName, true)
+	if err != nil {
+		return err
+	}
+
+	g, gctx := errgroup.WithContext(ctx)
+
+	// Close the hijacked connection when context is done to unblock I/O goroutines.
+	g.Go(func() error {
+		<-gctx.Done()
+		_ = conn.Close()
+		return nil
+	})
+
+	// stdin: copy input to container
+	g.Go(func() error {
+		_, cpErr := io.Copy(conn, in)
+		if cpErr != nil && !errors.Is(cpErr, io.EOF) && !errors.Is(cpErr, net.ErrClosed) {
+			return errors.Wrap(cpErr, "stdin copy failed")
+		}
+		return nil
+	})
+
+	// stdout/stderr: the container has no terminal, so the stream is multiplexed
+	// in the same way as the Docker one
+	g.Go(func() error {
+		_, cpErr := stdcopy.StdCopy(out, out, conn)
+		if cpErr != nil && !errors.Is(cpErr, io.EOF) && !errors.Is(cpErr, net.ErrClosed) {
+			return errors.Wrap(cpErr, "stdout copy failed")
+		}
+		// The container closed the stream, end the stdin copy as well.
+		return errPodmanAttachClosed
+	})
+
+	err = g.Wait()
+	if err != nil && !errors.Is(err, context.Canceled) && !errors.Is(err, errPodmanAttachClosed) {
+		return err
+	}
+
+	return nil
+}
+
+// attachContainer opens the attach stream of the container. Podman switches
+// the connection to a raw stream, the returned connection carries stdin to
+// the container and, with output, the multiplexed stdout and stderr back.
+func (pm *Podman) attachContainer(ctx context.Context, nameOrID string, output bool) (io.ReadWriteCloser, error) {
+	path := fmt.Sprintf(
+		"/containers/%s/attach?stream=true&stdin=true&stdout=%t&stderr=%t", nameOrID, output, output,
+	)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, pm.apiURL(path), nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create request")
+	}
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "tcp")
+
+	// The stream lives as long as the console is attached, so the client
+	// timeout must not apply to it.
+	attachClient := &http.Client{Transport: pm.httpClient.Transport}
+
+	resp, err := attachClient.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to attach to container")
+	}
+
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		if resp.StatusCode == http.StatusNotFound {
+			return nil, ErrContainerNotRunning
+		}
+		return nil, errors.Wrapf(errPodmanAttachContainer, "status %d: %s", resp.StatusCode, string(body))
+	}
+
+	conn, ok := resp.Body.(io.ReadWriteCloser)
+	if !ok {
+		_ = resp.Body.Close()
+		return nil, errors.Wrap(errPodmanAttachContainer, "connection is not writable")
+	}
+
+	return conn, nil
 }
 
 func (pm *Podman) HasOwnInstallation(server *domain.Server) bool {
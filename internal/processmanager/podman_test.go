@@ -3,12 +3,24 @@
 package processmanager
 
 import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/gameap/daemon/internal/app/config"
 	"github.com/gameap/daemon/internal/app/domain"
+	"github.com/moby/moby/api/types/container"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestNewPodman(t *testing.T) {
@@ -292,6 +304,214 @@ func TestGetDefaultPodmanSocket(t *testing.T) {
 	}
 }
 
+func TestPodman_Status_Health(t *testing.T) {
+	tests := []struct {
+		name     string
+		state    string
+		expected domain.Result
+	}{
+		{"healthy", `{"Running": true, "Status": "running", "Health": {"Status": "healthy"}}`, domain.SuccessResult},
+		{"unhealthy", `{"Running": true, "Status": "running", "Health": {"Status": "unhealthy"}}`, domain.ErrorResult},
+		{"legacy field", `{"Running": true, "Status": "running", "Healthcheck": {"Status": "unhealthy"}}`, domain.ErrorResult},
+		{"no healthcheck", `{"Running": true, "Status": "running"}`, domain.SuccessResult},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mux := http.NewServeMux()
+			mux.HandleFunc("GET /v4.0.0/libpod/containers/{name}/json", func(w http.ResponseWriter, _ *http.Request) {
+				_, _ = fmt.Fprintf(w, `{"State": %s}`, tt.state)
+			})
+			pm := givenPodmanAPI(t, mux)
+
+			result, err := pm.Status(context.Background(), createPodmanTestServer(nil, nil, nil), &bytes.Buffer{})
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
+
+func TestPodman_buildContainerSpec_Healthcheck(t *testing.T) {
+	cfg := &config.Config{WorkPath: "/tmp/test"}
+	cfg.Scripts.Start = "{command}"
+	cfg.ProcessManager.Config = map[string]string{"rootless": "false"}
+	pm := NewPodman(cfg, nil, nil)
+
+	spec, err := pm.buildContainerSpec(context.Background(), createPodmanTestServer(map[string]string{
+		"docker_healthcheck": `{"test": "pgrep game_server", "interval": "15s", "retries": 2}`,
+	}, nil, nil))
+
+	require.NoError(t, err)
+	assert.Equal(t, &container.HealthConfig{
+		Test:     []string{"CMD-SHELL", "pgrep game_server"},
+		Interval: 15 * time.Second,
+		Retries:  2,
+	}, spec["healthconfig"])
+
+	_, err = pm.buildContainerSpec(context.Background(), createPodmanTestServer(map[string]string{
+		"docker_healthcheck": `{"test": ["check"]}`,
+	}, nil, nil))
+	require.ErrorIs(t, err, errInvalidHealthcheck)
+}
+
+func TestPodman_buildContainerSpec_ProcessPriority(t *testing.T) {
+	cfg := &config.Config{WorkPath: "/tmp/test"}
+	cfg.Scripts.Start = "{command}"
+	cfg.ProcessManager.Config = map[string]string{"rootless": "false"}
+	pm := NewPodman(cfg, nil, nil)
+
+	t.Run("with cpu limit", func(t *testing.T) {
+		server := domain.NewServer(
+			1, true, domain.ServerInstalled, false,
+			"Test Server", "test-uuid-5678", "test5678",
+			domain.Game{}, domain.GameMod{},
+			"127.0.0.1", 27015, 27016, 27017, "",
+			"/servers/test", "",
+			"./game_server", "", "", "",
+			false, time.Time{},
+			map[string]string{"cpu_affinity": "1,3", "oom_score_adj": "250"}, domain.Settings{}, time.Time{},
+			500, 0,
+		)
+
+		spec, err := pm.buildContainerSpec(context.Background(), server)
+
+		require.NoError(t, err)
+		assert.Equal(t, 250, spec["oom_score_adj"])
+		cpu := spec["resource_limits"].(map[string]interface{})["cpu"].(map[string]interface{})
+		assert.Equal(t, "1,3", cpu["cpus"])
+		assert.NotZero(t, cpu["quota"])
+	})
+
+	t.Run("affinity only", func(t *testing.T) {
+		spec, err := pm.buildContainerSpec(context.Background(), createPodmanTestServer(map[string]string{
+			"cpu_affinity": "0",
+		}, nil, nil))
+
+		require.NoError(t, err)
+		assert.NotContains(t, spec, "oom_score_adj")
+		cpu := spec["resource_limits"].(map[string]interface{})["cpu"].(map[string]interface{})
+		assert.Equal(t, map[string]interface{}{"cpus": "0"}, cpu)
+	})
+}
+
+func TestPodman_Attach(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v4.0.0/libpod/containers/{name}/json", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`{"State": {"Running": true, "Status": "running"}}`))
+	})
+	mux.HandleFunc("POST /v4.0.0/libpod/containers/{name}/attach", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Upgrade") != "tcp" || r.URL.Query().Get("stdin") != "true" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		conn, buf, err := http.NewResponseController(w).Hijack()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		_, _ = buf.WriteString("HTTP/1.1 101 UPGRADED\r\n" +
+			"Content-Type: application/vnd.docker.multiplexed-stream\r\n" +
+			"Connection: Upgrade\r\nUpgrade: tcp\r\n\r\n")
+		_ = buf.Flush()
+
+		// Echo one console line as stdout and answer on stderr
+		line, _ := bufio.NewReader(buf).ReadString('\n')
+		_, _ = conn.Write(podmanStreamFrame(1, "> "+line))
+		_, _ = conn.Write(podmanStreamFrame(2, "Unknown command\n"))
+	})
+	pm := givenPodmanAPI(t, mux)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	out := &bytes.Buffer{}
+
+	err := pm.Attach(ctx, createPodmanTestServer(nil, nil, nil), strings.NewReader("status\n"), out)
+
+	require.NoError(t, err)
+	assert.Equal(t, "> status\nUnknown command\n", out.String())
+}
+
+func TestPodman_SendInput(t *testing.T) {
+	received := make(chan string, 1)
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /v4.0.0/libpod/containers/{name}/attach", func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if r.Header.Get("Upgrade") != "tcp" || query.Get("stdin") != "true" || query.Get("stdout") != "false" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		conn, buf, err := http.NewResponseController(w).Hijack()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		_, _ = buf.WriteString("HTTP/1.1 101 UPGRADED\r\nConnection: Upgrade\r\nUpgrade: tcp\r\n\r\n")
+		_ = buf.Flush()
+
+		line, _ := bufio.NewReader(buf).ReadString('\n')
+		received <- line
+	})
+	pm := givenPodmanAPI(t, mux)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	result, err := pm.SendInput(ctx, `say "hi" $(id)`, createPodmanTestServer(nil, nil, nil), io.Discard)
+
+	require.NoError(t, err)
+	assert.Equal(t, domain.SuccessResult, result)
+	select {
+	case line := <-received:
+		assert.Equal(t, "say \"hi\" $(id)\n", line)
+	case <-ctx.Done():
+		t.Fatal("input is not received")
+	}
+}
+
+func TestPodman_Attach_NotRunning(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v4.0.0/libpod/containers/{name}/json", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`{"State": {"Running": false, "Status": "exited"}}`))
+	})
+	pm := givenPodmanAPI(t, mux)
+
+	err := pm.Attach(context.Background(), createPodmanTestServer(nil, nil, nil), strings.NewReader(""), &bytes.Buffer{})
+
+	require.ErrorIs(t, err, ErrContainerNotRunning)
+}
+
+// givenPodmanAPI serves the handler as the libpod API on a unix socket.
+func givenPodmanAPI(t *testing.T, handler http.Handler) *Podman {
+	t.Helper()
+
+	socketPath := filepath.Join(t.TempDir(), "podman.sock")
+	listener, err := net.Listen("unix", socketPath)
+	require.NoError(t, err)
+
+	srv := &http.Server{Handler: handler, ReadHeaderTimeout: time.Second}
+	go func() { _ = srv.Serve(listener) }()
+	t.Cleanup(func() { _ = srv.Close() })
+
+	cfg := &config.Config{WorkPath: t.TempDir()}
+	cfg.ProcessManager.Config = map[string]string{"socket_path": "unix://" + socketPath}
+
+	return NewPodman(cfg, nil, nil)
+}
+
+// podmanStreamFrame frames the payload as the multiplexed attach stream does.
+func podmanStreamFrame(stream byte, payload string) []byte {
+	frame := make([]byte, 8, 8+len(payload))
+	frame[0] = stream
+	binary.BigEndian.PutUint32(frame[4:], uint32(len(payload))) //nolint:gosec
+
+	return append(frame, payload...)
+}
+
 type podmanTestError struct {
 	msg string
 }
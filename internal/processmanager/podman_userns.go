@@ -0,0 +1,290 @@
+//go:build linux || darwin
+
+package processmanager
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/user"
+	"strconv"
+	"strings"
+
+	"github.com/gameap/daemon/internal/app/domain"
+	"github.com/pkg/errors"
+)
+
+const (
+	// keyPodmanUserns selects how the server user is mapped into the container:
+	// "auto" (default), "keep-id" or "host".
+	keyPodmanUserns = "podman_userns"
+
+	// configPodmanRootless overrides the rootless detection in the process
+	// manager config.
+	configPodmanRootless = "rootless"
+
+	podmanUsernsAuto   = "auto"
+	podmanUsernsKeepID = "keep-id"
+	podmanUsernsHost   = "host"
+
+	defaultSubUIDPath = "/etc/subuid"
+	defaultSubGIDPath = "/etc/subgid"
+)
+
+var (
+	errPodmanInfo          = errors.New("failed to get podman info")
+	errPodmanInvalidUserns = errors.New("invalid podman_userns")
+	errPodmanKeepIDUser    = errors.New("keep-id maps only the podman user")
+	errPodmanNoSubIDRange  = errors.New("no subordinate id range")
+	errPodmanInvalidSubID  = errors.New("invalid subordinate id entry")
+	errPodmanOutsideSubID  = errors.New("server user is outside the subordinate id ranges")
+)
+
+// subIDRange is a range of subordinate ids from /etc/subuid or /etc/subgid.
+type subIDRange struct {
+	start int
+	count int
+}
+
+func (r subIDRange) contains(id int) bool {
+	return id >= r.start && id < r.start+r.count
+}
+
+func (r subIDRange) String() string {
+	return fmt.Sprintf("%d-%d", r.start, r.start+r.count-1)
+}
+
+// podmanIDMap is a single mapping of the libpod idmappings, the host id is
+// an id of the rootless user namespace: 0 is the podman user itself and
+// 1..n are its subordinate ids in the order of /etc/subuid.
+type podmanIDMap struct {
+	ContainerID int `json:"container_id"`
+	HostID      int `json:"host_id"`
+	Size        int `json:"size"`
+}
+
+// podmanUserNamespace is how the server user is mapped into the container.
+type podmanUserNamespace struct {
+	user       string
+	userns     map[string]string
+	idMappings map[string][]podmanIDMap
+}
+
+// isRootless tells whether the podman service runs rootless. The
+// process manager config wins over the service info.
+func (pm *Podman) isRootless(ctx context.Context) (bool, error) {
+	if val := pm.cfg.ProcessManager.Config[configPodmanRootless]; val != "" {
+		rootless, err := strconv.ParseBool(val)
+		if err != nil {
+			return false, errors.Wrapf(err, "invalid process manager config %s", configPodmanRootless)
+		}
+
+		return rootless, nil
+	}
+
+	pm.rootlessMu.Lock()
+	defer pm.rootlessMu.Unlock()
+
+	if pm.rootless != nil {
+		return *pm.rootless, nil
+	}
+
+	resp, err := pm.doRequest(ctx, http.MethodGet, "/info", nil)
+	if err != nil {
+		return false, errors.Wrap(err, "failed to get podman info")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return false, errors.Wrapf(errPodmanInfo, "%s", string(body))
+	}
+
+	var info struct {
+		Host struct {
+			Security struct {
+				Rootless bool `json:"rootless"`
+			} `json:"security"`
+		} `json:"host"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+		return false, errors.Wrap(err, "failed to decode podman info")
+	}
+
+	rootless := info.Host.Security.Rootless
+	pm.rootless = &rootless
+
+	return rootless, nil
+}
+
+// userNamespace returns the user and the user namespace of the server
+// container. Rootful podman and the "host" mode run the container with the
+// raw ids of the server user. Rootless podman shifts the ids, so the files
+// written to the server directory would get foreign owners on the host:
+//   - the podman user itself is mapped with keep-id,
+//   - another server user is mapped explicitly, its ids have to be in the
+//     subordinate ranges of the podman user.
+func (pm *Podman) userNamespace(ctx context.Context, server *domain.Server) (podmanUserNamespace, error) {
+	mode := pm.getConfig(server, keyPodmanUserns)
+	switch mode {
+	case "", podmanUsernsAuto, podmanUsernsKeepID, podmanUsernsHost:
+	default:
+		return podmanUserNamespace{}, errors.Wrapf(
+			errPodmanInvalidUserns, "%q, expected %s, %s or %s",
+			mode, podmanUsernsAuto, podmanUsernsKeepID, podmanUsernsHost,
+		)
+	}
+
+	uid, gid, err := pm.getUserIDs(server)
+	if err != nil {
+		return podmanUserNamespace{}, errors.Wrap(err, "failed to get user IDs")
+	}
+
+	hostNS := podmanUserNamespace{user: uid + ":" + gid}
+
+	if mode == podmanUsernsHost {
+		return hostNS, nil
+	}
+
+	rootless, err := pm.isRootless(ctx)
+	if err != nil {
+		return podmanUserNamespace{}, err
+	}
+	if !rootless {
+		return hostNS, nil
+	}
+
+	podmanUser, err := user.Current()
+	if err != nil {
+		return podmanUserNamespace{}, errors.Wrap(err, "failed to get current user")
+	}
+
+	if uid == podmanUser.Uid && gid == podmanUser.Gid {
+		return podmanUserNamespace{
+			user:   hostNS.user,
+			userns: map[string]string{"nsmode": "keep-id"},
+		}, nil
+	}
+
+	if mode == podmanUsernsKeepID {
+		return podmanUserNamespace{}, errors.Wrapf(
+			errPodmanKeepIDUser, "podman runs as %s, the server user is %s", podmanUser.Username, server.User(),
+		)
+	}
+
+	uidMap, err := mapSubID(pm.subUIDPath, podmanUser, podmanUser.Uid, uid)
+	if err != nil {
+		return podmanUserNamespace{}, errors.WithMessagef(err, "server user %s uid %s", server.User(), uid)
+	}
+
+	gidMap, err := mapSubID(pm.subGIDPath, podmanUser, podmanUser.Gid, gid)
+	if err != nil {
+		return podmanUserNamespace{}, errors.WithMessagef(err, "server user %s gid %s", server.User(), gid)
+	}
+
+	return podmanUserNamespace{
+		user:   hostNS.user,
+		userns: map[string]string{"nsmode": "private"},
+		idMappings: map[string][]podmanIDMap{
+			"UIDMap": uidMap,
+			"GIDMap": gidMap,
+		},
+	}, nil
+}
+
+// mapSubID maps the host id to itself through the subordinate ranges of the
+// podman user and the container root to the podman user. The own id of the
+// podman user (ownID) is mapped directly, the root gets a subordinate id then.
+func mapSubID(path string, podmanUser *user.User, ownID, hostID string) ([]podmanIDMap, error) {
+	id, err := strconv.Atoi(hostID)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid id %s", hostID)
+	}
+
+	ranges, err := readSubIDRanges(path, podmanUser)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(ranges) == 0 {
+		return nil, errors.Wrapf(errPodmanNoSubIDRange, "%s has no entry for %s", path, podmanUser.Username)
+	}
+
+	// The subordinate ids follow the podman user (0) in the namespace
+	// in the order of the file.
+	if hostID == ownID {
+		return []podmanIDMap{
+			{ContainerID: 0, HostID: 1, Size: 1},
+			{ContainerID: id, HostID: 0, Size: 1},
+		}, nil
+	}
+
+	offset := 1
+	for _, r := range ranges {
+		if r.contains(id) {
+			return []podmanIDMap{
+				{ContainerID: 0, HostID: 0, Size: 1},
+				{ContainerID: id, HostID: offset + id - r.start, Size: 1},
+			}, nil
+		}
+
+		offset += r.count
+	}
+
+	available := make([]string, 0, len(ranges))
+	for _, r := range ranges {
+		available = append(available, r.String())
+	}
+
+	return nil, errors.Wrapf(
+		errPodmanOutsideSubID, "%d is not in the ranges %s of %s in %s",
+		id, strings.Join(available, ", "), podmanUser.Username, path,
+	)
+}
+
+// readSubIDRanges reads the ranges of the user from a subuid or subgid file.
+// The entries are "name:start:count", the user is matched by name or uid.
+func readSubIDRanges(path string, u *user.User) ([]subIDRange, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to open %s", path)
+	}
+	defer f.Close()
+
+	var ranges []subIDRange
+
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		fields := strings.Split(text, ":")
+		if len(fields) != 3 {
+			return nil, errors.Wrapf(errPodmanInvalidSubID, "%s:%d: %q", path, line, text)
+		}
+
+		if fields[0] != u.Username && fields[0] != u.Uid {
+			continue
+		}
+
+		start, startErr := strconv.Atoi(fields[1])
+		count, countErr := strconv.Atoi(fields[2])
+		if startErr != nil || countErr != nil || start < 0 || count <= 0 {
+			return nil, errors.Wrapf(errPodmanInvalidSubID, "%s:%d: %q", path, line, text)
+		}
+
+		ranges = append(ranges, subIDRange{start: start, count: count})
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrapf(err, "failed to read %s", path)
+	}
+
+	return ranges, nil
+}
@@ -0,0 +1,176 @@
+//go:build linux || darwin
+
+package processmanager
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"os/user"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gameap/daemon/internal/app/config"
+	"github.com/gameap/daemon/internal/app/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func givenSubIDFile(t *testing.T, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "subid")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+
+	return path
+}
+
+func TestReadSubIDRanges(t *testing.T) {
+	path := givenSubIDFile(t, "# comment\n"+
+		"other:100000:65536\n"+
+		"gameap:165536:65536\n"+
+		"\n"+
+		"1001:300000:1000\n")
+
+	ranges, err := readSubIDRanges(path, &user.User{Username: "gameap", Uid: "1001"})
+
+	require.NoError(t, err)
+	assert.Equal(t, []subIDRange{{start: 165536, count: 65536}, {start: 300000, count: 1000}}, ranges)
+}
+
+func TestReadSubIDRanges_Invalid(t *testing.T) {
+	for _, content := range []string{"gameap:165536\n", "gameap:start:65536\n", "gameap:165536:0\n"} {
+		path := givenSubIDFile(t, content)
+
+		_, err := readSubIDRanges(path, &user.User{Username: "gameap", Uid: "1001"})
+
+		require.ErrorIs(t, err, errPodmanInvalidSubID, content)
+	}
+}
+
+func TestMapSubID(t *testing.T) {
+	path := givenSubIDFile(t, "gameap:100000:1000\ngameap:200000:65536\nother:300000:65536\n")
+	podmanUser := &user.User{Username: "gameap", Uid: "1000", Gid: "1000"}
+
+	t.Run("id in second range", func(t *testing.T) {
+		idMap, err := mapSubID(path, podmanUser, "1000", "200010")
+
+		require.NoError(t, err)
+		assert.Equal(t, []podmanIDMap{
+			{ContainerID: 0, HostID: 0, Size: 1},
+			{ContainerID: 200010, HostID: 1011, Size: 1},
+		}, idMap)
+	})
+
+	t.Run("own id of podman user", func(t *testing.T) {
+		idMap, err := mapSubID(path, podmanUser, "1000", "1000")
+
+		require.NoError(t, err)
+		assert.Equal(t, []podmanIDMap{
+			{ContainerID: 0, HostID: 1, Size: 1},
+			{ContainerID: 1000, HostID: 0, Size: 1},
+		}, idMap)
+	})
+
+	t.Run("id outside ranges", func(t *testing.T) {
+		_, err := mapSubID(path, podmanUser, "1000", "300010")
+
+		require.ErrorIs(t, err, errPodmanOutsideSubID)
+		assert.Contains(t, err.Error(), "100000-100999, 200000-265535")
+	})
+
+	t.Run("no entry", func(t *testing.T) {
+		_, err := mapSubID(path, &user.User{Username: "nobody", Uid: "65534"}, "65534", "200010")
+
+		require.ErrorIs(t, err, errPodmanNoSubIDRange)
+	})
+}
+
+func TestPodman_userNamespace(t *testing.T) {
+	current, err := user.Current()
+	require.NoError(t, err)
+
+	t.Run("rootful uses raw ids", func(t *testing.T) {
+		pm := NewPodman(&config.Config{}, nil, nil)
+		pm.cfg.ProcessManager.Config = map[string]string{"rootless": "false"}
+
+		ns, err := pm.userNamespace(context.Background(), createPodmanTestServer(nil, nil, nil))
+
+		require.NoError(t, err)
+		assert.Equal(t, podmanUserNamespace{user: current.Uid + ":" + current.Gid}, ns)
+	})
+
+	t.Run("rootless podman user uses keep-id", func(t *testing.T) {
+		mux := http.NewServeMux()
+		mux.HandleFunc("GET /v4.0.0/libpod/info", func(w http.ResponseWriter, _ *http.Request) {
+			_, _ = w.Write([]byte(`{"host": {"security": {"rootless": true}}}`))
+		})
+		pm := givenPodmanAPI(t, mux)
+
+		ns, err := pm.userNamespace(context.Background(), createPodmanTestServer(nil, nil, nil))
+
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{"nsmode": "keep-id"}, ns.userns)
+		assert.Nil(t, ns.idMappings)
+	})
+
+	t.Run("host mode", func(t *testing.T) {
+		pm := NewPodman(&config.Config{}, nil, nil)
+		pm.cfg.ProcessManager.Config = map[string]string{"rootless": "true"}
+
+		ns, err := pm.userNamespace(context.Background(), createPodmanTestServer(map[string]string{
+			"podman_userns": "host",
+		}, nil, nil))
+
+		require.NoError(t, err)
+		assert.Nil(t, ns.userns)
+	})
+
+	t.Run("invalid mode", func(t *testing.T) {
+		pm := NewPodman(&config.Config{}, nil, nil)
+
+		_, err := pm.userNamespace(context.Background(), createPodmanTestServer(map[string]string{
+			"podman_userns": "shifted",
+		}, nil, nil))
+
+		require.ErrorIs(t, err, errPodmanInvalidUserns)
+	})
+}
+
+func TestPodman_userNamespace_OtherServerUser(t *testing.T) {
+	current, err := user.Current()
+	require.NoError(t, err)
+	nobody, err := user.Lookup("nobody")
+	if err != nil || nobody.Uid == current.Uid {
+		t.Skip("no other user to map")
+	}
+
+	pm := NewPodman(&config.Config{}, nil, nil)
+	pm.cfg.ProcessManager.Config = map[string]string{"rootless": "true"}
+	pm.subUIDPath = givenSubIDFile(t, current.Username+":"+nobody.Uid+":1\n")
+	pm.subGIDPath = givenSubIDFile(t, current.Username+":"+nobody.Gid+":1\n")
+	server := domain.NewServer(
+		1, true, domain.ServerInstalled, false,
+		"Test Server", "test-uuid-5678", "test5678",
+		domain.Game{}, domain.GameMod{},
+		"127.0.0.1", 27015, 27016, 27017, "",
+		"/servers/test", "nobody",
+		"./game_server", "", "", "",
+		false, time.Time{},
+		map[string]string{}, domain.Settings{}, time.Time{},
+		0, 0,
+	)
+
+	ns, err := pm.userNamespace(context.Background(), server)
+
+	require.NoError(t, err)
+	assert.Equal(t, nobody.Uid+":"+nobody.Gid, ns.user)
+	assert.Equal(t, map[string]string{"nsmode": "private"}, ns.userns)
+	require.Len(t, ns.idMappings["UIDMap"], 2)
+	assert.Equal(t, 1, ns.idMappings["UIDMap"][1].HostID)
+
+	pm.subUIDPath = givenSubIDFile(t, current.Username+":100000:65536\n")
+	_, err = pm.userNamespace(context.Background(), server)
+	require.ErrorIs(t, err, errPodmanOutsideSubID)
+}
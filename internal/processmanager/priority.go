@@ -0,0 +1,180 @@
+package processmanager
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/gameap/daemon/internal/app/components"
+	"github.com/gameap/daemon/internal/app/config"
+	"github.com/gameap/daemon/internal/app/contracts"
+	"github.com/gameap/daemon/internal/app/domain"
+	"github.com/pkg/errors"
+	"github.com/shirou/gopsutil/v4/process"
+)
+
+const (
+	// keyNice is the nice value of the server, -20 (highest priority) to 19.
+	keyNice = "nice"
+
+	// keyCPUAffinity is the list of CPUs the server may run on, e.g. "0-3,6".
+	keyCPUAffinity = "cpu_affinity"
+
+	// keyIONiceClass is the IO scheduling class: realtime, best-effort or idle.
+	keyIONiceClass = "ionice_class"
+
+	// keyIONiceLevel is the IO priority within the class, 0 (highest) to 7.
+	keyIONiceLevel = "ionice_level"
+
+	// keyOOMScoreAdj is the OOM killer score adjustment, -1000 to 1000.
+	keyOOMScoreAdj = "oom_score_adj"
+
+	maxCPUIndex = 1023
+)
+
+var errInvalidPriority = errors.New("invalid process priority")
+
+// ioNiceClasses maps the ionice class names and numbers to the names used by
+// systemd.
+var ioNiceClasses = map[string]string{
+	"realtime":    "realtime",
+	"best-effort": "best-effort",
+	"idle":        "idle",
+	"1":           "realtime",
+	"2":           "best-effort",
+	"3":           "idle",
+}
+
+// processPriority reads the scheduling settings of the server. The values
+// are read like the other settings: server vars, game mod metadata, game
+// metadata, then the process manager config.
+func processPriority(cfg *config.Config, server *domain.Server) (contracts.ProcessPriority, error) {
+	var priority contracts.ProcessPriority
+	var err error
+
+	priority.Nice, err = intSetting(cfg, server, keyNice, -20, 19)
+	if err != nil {
+		return contracts.ProcessPriority{}, err
+	}
+
+	if val := getContainerConfig(cfg, server, keyCPUAffinity); val != "" {
+		priority.CPUAffinity, err = parseCPUList(val)
+		if err != nil {
+			return contracts.ProcessPriority{}, err
+		}
+	}
+
+	if val := getContainerConfig(cfg, server, keyIONiceClass); val != "" {
+		class, ok := ioNiceClasses[strings.ToLower(val)]
+		if !ok {
+			return contracts.ProcessPriority{}, errors.Wrapf(errInvalidPriority, "%s %q", keyIONiceClass, val)
+		}
+
+		priority.IOClass = class
+	}
+
+	priority.IOLevel, err = intSetting(cfg, server, keyIONiceLevel, 0, 7)
+	if err != nil {
+		return contracts.ProcessPriority{}, err
+	}
+
+	priority.OOMScoreAdj, err = intSetting(cfg, server, keyOOMScoreAdj, -1000, 1000)
+	if err != nil {
+		return contracts.ProcessPriority{}, err
+	}
+
+	return priority, nil
+}
+
+func intSetting(cfg *config.Config, server *domain.Server, key string, lower, upper int) (*int, error) {
+	val := getContainerConfig(cfg, server, key)
+	if val == "" {
+		return nil, nil //nolint:nilnil
+	}
+
+	n, err := strconv.Atoi(strings.TrimSpace(val))
+	if err != nil || n < lower || n > upper {
+		return nil, errors.Wrapf(errInvalidPriority, "%s %q, expected %d to %d", key, val, lower, upper)
+	}
+
+	return &n, nil
+}
+
+// parseCPUList parses a list of CPU indices and ranges separated by commas
+// or spaces, such as "0-3,6".
+func parseCPUList(val string) ([]int, error) {
+	var cpus []int
+	seen := make(map[int]bool)
+
+	for _, part := range strings.FieldsFunc(val, func(r rune) bool { return r == ',' || r == ' ' }) {
+		first, last, isRange := strings.Cut(part, "-")
+		if !isRange {
+			last = first
+		}
+
+		from, fromErr := strconv.Atoi(first)
+		to, toErr := strconv.Atoi(last)
+		if fromErr != nil || toErr != nil || from < 0 || to < from || to > maxCPUIndex {
+			return nil, errors.Wrapf(errInvalidPriority, "%s %q", keyCPUAffinity, val)
+		}
+
+		for cpu := from; cpu <= to; cpu++ {
+			if !seen[cpu] {
+				seen[cpu] = true
+				cpus = append(cpus, cpu)
+			}
+		}
+	}
+
+	if len(cpus) == 0 {
+		return nil, errors.Wrapf(errInvalidPriority, "%s %q", keyCPUAffinity, val)
+	}
+
+	return cpus, nil
+}
+
+// applyProcessTreePriority applies priority to pid and its descendants. It
+// is for server processes that are not started by the executor itself, such
+// as a tmux pane, or that could have forked before the executor applied it.
+// Nothing is applied when pid does not belong to the server, and children of
+// another user are skipped.
+func applyProcessTreePriority(
+	ctx context.Context, server *domain.Server, pid int, priority contracts.ProcessPriority,
+) error {
+	if priority.IsZero() {
+		return nil
+	}
+
+	if err := validateServerPID(server, pid); err != nil {
+		return err
+	}
+
+	root, err := process.NewProcessWithContext(ctx, int32(pid))
+	if err != nil {
+		return errors.Wrapf(err, "failed to find process %d", pid)
+	}
+
+	tree, err := processTree(ctx, root)
+	if err != nil {
+		return err
+	}
+
+	for _, p := range sameOwnerTree(ctx, tree) {
+		if err := components.ApplyProcessPriority(int(p.Pid), priority); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// formatCPUList formats CPUs as a comma separated list, the form docker,
+// podman and systemd all accept.
+func formatCPUList(cpus []int) string {
+	parts := make([]string, 0, len(cpus))
+	for _, cpu := range cpus {
+		parts = append(parts, strconv.Itoa(cpu))
+	}
+
+	return strings.Join(parts, ",")
+}
@@ -0,0 +1,35 @@
+//go:build linux
+
+package processmanager
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gameap/daemon/internal/app/contracts"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sys/unix"
+)
+
+func TestApplyProcessTreePriority(t *testing.T) {
+	server := givenLimitedServer(nil, 0, 0)
+	priority := contracts.ProcessPriority{Nice: intPtr(3)}
+
+	t.Run("server process", func(t *testing.T) {
+		pid := givenServerProcess(t)
+
+		err := applyProcessTreePriority(context.Background(), server, pid, priority)
+
+		require.NoError(t, err)
+		prio, err := unix.Getpriority(unix.PRIO_PROCESS, pid)
+		require.NoError(t, err)
+		assert.Equal(t, 20-3, prio)
+	})
+
+	t.Run("init", func(t *testing.T) {
+		err := applyProcessTreePriority(context.Background(), server, 1, priority)
+
+		assert.ErrorIs(t, err, ErrForeignPID)
+	})
+}
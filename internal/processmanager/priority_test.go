@@ -0,0 +1,112 @@
+package processmanager
+
+import (
+	"testing"
+
+	"github.com/gameap/daemon/internal/app/config"
+	"github.com/gameap/daemon/internal/app/contracts"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func intPtr(n int) *int {
+	return &n
+}
+
+func TestProcessPriority(t *testing.T) {
+	tests := []struct {
+		name     string
+		vars     map[string]string
+		pmConfig map[string]string
+		expected contracts.ProcessPriority
+	}{
+		{
+			name:     "nothing set",
+			expected: contracts.ProcessPriority{},
+		},
+		{
+			name: "all settings",
+			vars: map[string]string{
+				"nice":          "10",
+				"cpu_affinity":  "0-2, 5",
+				"ionice_class":  "Best-Effort",
+				"ionice_level":  "7",
+				"oom_score_adj": "-500",
+			},
+			expected: contracts.ProcessPriority{
+				Nice:        intPtr(10),
+				CPUAffinity: []int{0, 1, 2, 5},
+				IOClass:     "best-effort",
+				IOLevel:     intPtr(7),
+				OOMScoreAdj: intPtr(-500),
+			},
+		},
+		{
+			name:     "ionice class number",
+			vars:     map[string]string{"ionice_class": "3"},
+			expected: contracts.ProcessPriority{IOClass: "idle"},
+		},
+		{
+			name:     "server vars override the process manager config",
+			vars:     map[string]string{"nice": "-5"},
+			pmConfig: map[string]string{"nice": "5", "oom_score_adj": "300"},
+			expected: contracts.ProcessPriority{Nice: intPtr(-5), OOMScoreAdj: intPtr(300)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{}
+			cfg.ProcessManager.Config = tt.pmConfig
+
+			priority, err := processPriority(cfg, createTestServer(tt.vars, nil, nil))
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, priority)
+		})
+	}
+}
+
+func TestProcessPriority_Invalid(t *testing.T) {
+	for _, vars := range []map[string]string{
+		{"nice": "20"},
+		{"nice": "high"},
+		{"cpu_affinity": "3-1"},
+		{"ionice_class": "fast"},
+		{"ionice_level": "8"},
+		{"oom_score_adj": "-1001"},
+	} {
+		_, err := processPriority(&config.Config{}, createTestServer(vars, nil, nil))
+
+		assert.ErrorIs(t, err, errInvalidPriority, vars)
+	}
+}
+
+func TestParseCPUList(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected []int
+	}{
+		{value: "3", expected: []int{3}},
+		{value: "0-3,6", expected: []int{0, 1, 2, 3, 6}},
+		{value: "4 0 4-5", expected: []int{4, 0, 5}},
+	}
+
+	for _, tt := range tests {
+		cpus, err := parseCPUList(tt.value)
+
+		require.NoError(t, err, tt.value)
+		assert.Equal(t, tt.expected, cpus, tt.value)
+	}
+
+	for _, value := range []string{",", "-1", "a-b", "0-1024", "1-"} {
+		_, err := parseCPUList(value)
+
+		assert.ErrorIs(t, err, errInvalidPriority, value)
+	}
+}
+
+func TestFormatCPUList(t *testing.T) {
+	assert.Equal(t, "0,1,2,5", formatCPUList([]int{0, 1, 2, 5}))
+	assert.Empty(t, formatCPUList(nil))
+}
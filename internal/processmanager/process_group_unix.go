@@ -0,0 +1,147 @@
+//go:build linux || darwin
+
+package processmanager
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"syscall"
+
+	"github.com/gameap/daemon/internal/app/domain"
+	"github.com/pkg/errors"
+	"github.com/shirou/gopsutil/v4/process"
+)
+
+// openFlagNoFollow makes opening a file fail when it is a symbolic link.
+const openFlagNoFollow = syscall.O_NOFOLLOW
+
+// freezeProcessGroup stops every process in the group led by pid with SIGSTOP.
+// SIGSTOP cannot be caught or ignored, so the game server is suspended even if
+// it installs its own signal handlers.
+func freezeProcessGroup(pid int) error {
+	return signalProcessGroup(pid, syscall.SIGSTOP)
+}
+
+// thawProcessGroup resumes a group previously frozen by freezeProcessGroup.
+func thawProcessGroup(pid int) error {
+	return signalProcessGroup(pid, syscall.SIGCONT)
+}
+
+// terminateProcessGroup asks the group led by pid to exit with SIGTERM.
+func terminateProcessGroup(pid int) error {
+	return signalProcessGroup(pid, syscall.SIGTERM)
+}
+
+// killProcessGroup forcibly terminates the group led by pid with SIGKILL.
+func killProcessGroup(pid int) error {
+	return signalProcessGroup(pid, syscall.SIGKILL)
+}
+
+// processAlive reports whether pid still exists. EPERM means the process is
+// alive but owned by another user.
+func processAlive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+
+	err := syscall.Kill(pid, 0)
+
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
+
+// signalProcessGroup delivers sig to the whole process group when pid leads
+// one (tmux panes and setsid-started servers do), so a wrapper shell and the
+// game server it spawned are signalled together. Otherwise only pid itself
+// receives the signal.
+func signalProcessGroup(pid int, sig syscall.Signal) error {
+	if pid <= 1 {
+		return errors.Wrapf(ErrInvalidPID, "pid %d", pid)
+	}
+
+	pgid, err := syscall.Getpgid(pid)
+	if err != nil {
+		return errors.Wrapf(err, "failed to get process group of pid %d", pid)
+	}
+
+	// kill(-1) signals every process, and the group of the daemon includes
+	// the daemon itself.
+	if pgid <= 1 || pgid == syscall.Getpgrp() {
+		return errors.Wrapf(ErrForeignPID, "pid %d is in process group %d", pid, pgid)
+	}
+
+	target := pid
+	if pgid == pid {
+		target = -pgid
+	}
+
+	if err := syscall.Kill(target, sig); err != nil {
+		return errors.Wrapf(err, "failed to send %s to pid %d", sig, pid)
+	}
+
+	return nil
+}
+
+// validateServerPID checks that pid, read from a file the server user can
+// write, is a process of the server: not init, not the daemon or its process
+// group and owned by the server user.
+func validateServerPID(server *domain.Server, pid int) error {
+	if pid <= 1 || pid == os.Getpid() {
+		return errors.Wrapf(ErrForeignPID, "pid %d", pid)
+	}
+
+	pgid, err := syscall.Getpgid(pid)
+	if errors.Is(err, syscall.ESRCH) {
+		return errors.Wrapf(ErrServiceNotRunning, "process %d not found", pid)
+	}
+	if err != nil {
+		return errors.Wrapf(err, "failed to get process group of pid %d", pid)
+	}
+	if pgid <= 1 || pgid == syscall.Getpgrp() {
+		return errors.Wrapf(ErrForeignPID, "pid %d is in process group %d", pid, pgid)
+	}
+
+	u, err := serverUser(server)
+	if err != nil {
+		return err
+	}
+
+	p, err := process.NewProcess(int32(pid))
+	if err != nil {
+		return errors.Wrapf(ErrServiceNotRunning, "process %d not found", pid)
+	}
+
+	uids, err := p.Uids()
+	if err != nil || len(uids) == 0 {
+		return errors.Wrapf(ErrForeignPID, "failed to read owner of pid %d", pid)
+	}
+
+	if strconv.FormatUint(uint64(uids[0]), 10) != u.Uid {
+		return errors.Wrapf(ErrForeignPID, "pid %d is owned by uid %d, not by user %s", pid, uids[0], u.Username)
+	}
+
+	return nil
+}
+
+// sameOwnerTree drops the processes of a process tree that are not owned by
+// the owner of its root, such as a setuid child of the server.
+func sameOwnerTree(ctx context.Context, tree []*process.Process) []*process.Process {
+	if len(tree) == 0 {
+		return tree
+	}
+
+	rootUIDs, err := tree[0].UidsWithContext(ctx)
+	if err != nil || len(rootUIDs) == 0 {
+		return nil
+	}
+
+	owned := make([]*process.Process, 0, len(tree))
+	for _, p := range tree {
+		uids, err := p.UidsWithContext(ctx)
+		if err == nil && len(uids) > 0 && uids[0] == rootUIDs[0] {
+			owned = append(owned, p)
+		}
+	}
+
+	return owned
+}
@@ -0,0 +1,79 @@
+//go:build windows
+
+package processmanager
+
+import (
+	"context"
+	"os"
+
+	"github.com/gameap/daemon/internal/app/domain"
+	"github.com/pkg/errors"
+	"github.com/shirou/gopsutil/v4/process"
+)
+
+// openFlagNoFollow is not needed on Windows, where creating symbolic links
+// requires a privilege.
+const openFlagNoFollow = 0
+
+func freezeProcessGroup(_ int) error {
+	return ErrNotImplemented
+}
+
+func thawProcessGroup(_ int) error {
+	return ErrNotImplemented
+}
+
+// terminateProcessGroup is not available on Windows: there is no signal that
+// asks a console process to exit, so callers fall back to killProcessGroup.
+func terminateProcessGroup(_ int) error {
+	return ErrNotImplemented
+}
+
+// killProcessGroup terminates the process itself. Windows has no process
+// groups in the POSIX sense, children are left to the job object if any.
+func killProcessGroup(pid int) error {
+	if pid <= 0 {
+		return errors.Wrapf(ErrInvalidPID, "pid %d", pid)
+	}
+
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return errors.Wrapf(err, "failed to find process %d", pid)
+	}
+
+	if err := p.Kill(); err != nil {
+		return errors.Wrapf(err, "failed to kill process %d", pid)
+	}
+
+	return nil
+}
+
+func processAlive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	_ = p.Release()
+
+	return true
+}
+
+// validateServerPID refuses the system processes and the daemon itself,
+// Windows has no process owner to compare with the server user.
+func validateServerPID(_ *domain.Server, pid int) error {
+	if pid <= 4 || pid == os.Getpid() {
+		return errors.Wrapf(ErrForeignPID, "pid %d", pid)
+	}
+
+	return nil
+}
+
+// sameOwnerTree keeps the whole tree, the process owner is not checked on
+// Windows.
+func sameOwnerTree(_ context.Context, tree []*process.Process) []*process.Process {
+	return tree
+}
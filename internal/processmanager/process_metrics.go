@@ -0,0 +1,212 @@
+package processmanager
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/gameap/daemon/internal/app/domain"
+	"github.com/pkg/errors"
+	"github.com/shirou/gopsutil/v4/process"
+)
+
+const metricLabelSession = "session"
+
+// processTreeStats is the sum of the resource counters of a server root
+// process and all of its descendants. MemoryLimit is only known when the
+// counters come from the server cgroup, 0 means unlimited or unknown.
+type processTreeStats struct {
+	CPUSeconds   float64
+	MemoryRSS    uint64
+	MemoryLimit  uint64
+	IOReadBytes  uint64
+	IOWriteBytes uint64
+	HasIO        bool
+	PIDs         uint64
+}
+
+type processCPUSample struct {
+	rootPID    int32
+	cpuSeconds float64
+	at         time.Time
+}
+
+// processCPUSampler keeps the previous CPU time reading per server, CPU% is
+// derived from the delta between two collections.
+type processCPUSampler struct {
+	mu      sync.Mutex
+	samples map[string]processCPUSample
+}
+
+func newProcessCPUSampler() *processCPUSampler {
+	return &processCPUSampler{samples: make(map[string]processCPUSample)}
+}
+
+// percent records current and returns CPU as a fraction of one core against
+// the previous sample for key. Emission is suppressed on the first sample,
+// when the root process changed (server restarted) or when the summed CPU
+// time went backwards because a child exited.
+func (s *processCPUSampler) percent(key string, current processCPUSample) (float64, bool) {
+	s.mu.Lock()
+	prior, ok := s.samples[key]
+	s.samples[key] = current
+	s.mu.Unlock()
+
+	if !ok || prior.rootPID != current.rootPID || current.cpuSeconds < prior.cpuSeconds {
+		return 0, false
+	}
+
+	wallDelta := current.at.Sub(prior.at).Seconds()
+	if wallDelta <= 0 {
+		return 0, false
+	}
+
+	return (current.cpuSeconds - prior.cpuSeconds) / wallDelta * 100, true
+}
+
+// collectProcessTreeStats reads the counters of rootPID and its descendants
+// from /proc (or the platform equivalent). Processes that exit while being
+// read are skipped. IO counters of processes owned by other users are not
+// readable without privileges, HasIO reports whether at least one was read.
+func collectProcessTreeStats(ctx context.Context, rootPID int32) (processTreeStats, error) {
+	root, err := process.NewProcessWithContext(ctx, rootPID)
+	if err != nil {
+		return processTreeStats{}, errors.Wrapf(err, "failed to find process %d", rootPID)
+	}
+
+	procs, err := processTree(ctx, root)
+	if err != nil {
+		return processTreeStats{}, err
+	}
+
+	stats := processTreeStats{}
+	for _, p := range procs {
+		times, err := p.TimesWithContext(ctx)
+		if err != nil {
+			continue
+		}
+
+		stats.PIDs++
+		stats.CPUSeconds += times.User + times.System
+
+		if mem, err := p.MemoryInfoWithContext(ctx); err == nil {
+			stats.MemoryRSS += mem.RSS
+		}
+
+		if io, err := p.IOCountersWithContext(ctx); err == nil {
+			stats.IOReadBytes += io.ReadBytes
+			stats.IOWriteBytes += io.WriteBytes
+			stats.HasIO = true
+		}
+	}
+
+	return stats, nil
+}
+
+// processTree returns root followed by all of its descendants. The process
+// table is scanned once and the tree is built from the parent PIDs, which is
+// much cheaper than asking every process for its children.
+func processTree(ctx context.Context, root *process.Process) ([]*process.Process, error) {
+	all, err := process.ProcessesWithContext(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list processes")
+	}
+
+	children := make(map[int32][]*process.Process, len(all))
+	for _, p := range all {
+		ppid, err := p.PpidWithContext(ctx)
+		if err != nil || ppid == p.Pid {
+			continue
+		}
+		children[ppid] = append(children[ppid], p)
+	}
+
+	tree := []*process.Process{root}
+	for i := 0; i < len(tree); i++ {
+		tree = append(tree, children[tree[i].Pid]...)
+	}
+
+	return tree, nil
+}
+
+func processTreeToMetrics(
+	ts time.Time,
+	labels map[string]string,
+	stats processTreeStats,
+	cpuPercent float64, hasCPU bool,
+) []domain.Metric {
+	out := make([]domain.Metric, 0, 7)
+
+	if hasCPU {
+		out = append(out, domain.Metric{
+			Name:      metricServerCPUUsagePercent,
+			Type:      domain.MetricTypeGauge,
+			Unit:      domain.MetricUnitPercent,
+			Labels:    cloneLabelMap(labels),
+			Timestamp: ts,
+			Value:     domain.Float64Value(cpuPercent),
+		})
+	}
+
+	out = append(out, domain.Metric{
+		Name:      metricServerMemoryUsageBytes,
+		Type:      domain.MetricTypeGauge,
+		Unit:      domain.MetricUnitBytes,
+		Labels:    cloneLabelMap(labels),
+		Timestamp: ts,
+		Value:     domain.Uint64Value(stats.MemoryRSS),
+	})
+
+	if stats.MemoryLimit > 0 {
+		out = append(out,
+			domain.Metric{
+				Name:      metricServerMemoryLimitBytes,
+				Type:      domain.MetricTypeGauge,
+				Unit:      domain.MetricUnitBytes,
+				Labels:    cloneLabelMap(labels),
+				Timestamp: ts,
+				Value:     domain.Uint64Value(stats.MemoryLimit),
+			},
+			domain.Metric{
+				Name:      metricServerMemoryUsagePercent,
+				Type:      domain.MetricTypeGauge,
+				Unit:      domain.MetricUnitPercent,
+				Labels:    cloneLabelMap(labels),
+				Timestamp: ts,
+				Value:     domain.Float64Value(float64(stats.MemoryRSS) / float64(stats.MemoryLimit) * 100),
+			},
+		)
+	}
+
+	if stats.HasIO {
+		out = append(out,
+			domain.Metric{
+				Name:      metricServerBlockIOReadBytesTotal,
+				Type:      domain.MetricTypeCounter,
+				Unit:      domain.MetricUnitBytes,
+				Labels:    cloneLabelMap(labels),
+				Timestamp: ts,
+				Value:     domain.Uint64Value(stats.IOReadBytes),
+			},
+			domain.Metric{
+				Name:      metricServerBlockIOWriteBytesTotal,
+				Type:      domain.MetricTypeCounter,
+				Unit:      domain.MetricUnitBytes,
+				Labels:    cloneLabelMap(labels),
+				Timestamp: ts,
+				Value:     domain.Uint64Value(stats.IOWriteBytes),
+			},
+		)
+	}
+
+	out = append(out, domain.Metric{
+		Name:      metricServerProcessPIDs,
+		Type:      domain.MetricTypeGauge,
+		Unit:      domain.MetricUnitCount,
+		Labels:    cloneLabelMap(labels),
+		Timestamp: ts,
+		Value:     domain.Uint64Value(stats.PIDs),
+	})
+
+	return out
+}
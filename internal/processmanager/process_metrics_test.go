@@ -0,0 +1,107 @@
+//go:build linux
+
+package processmanager
+
+import (
+	"context"
+	"os/exec"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCollectProcessTreeStats_IncludesDescendants(t *testing.T) {
+	cmd := exec.Command("sh", "-c", "sleep 30 & wait")
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	require.NoError(t, cmd.Start())
+	t.Cleanup(func() {
+		_ = killProcessGroup(cmd.Process.Pid)
+		_ = cmd.Wait()
+	})
+
+	var stats processTreeStats
+	require.Eventually(t, func() bool {
+		var err error
+		stats, err = collectProcessTreeStats(context.Background(), int32(cmd.Process.Pid))
+		return err == nil && stats.PIDs >= 2
+	}, 5*time.Second, 50*time.Millisecond)
+
+	assert.Positive(t, stats.MemoryRSS)
+}
+
+func TestCollectProcessTreeStats_UnknownPID(t *testing.T) {
+	_, err := collectProcessTreeStats(context.Background(), 1<<30)
+
+	assert.Error(t, err)
+}
+
+func TestProcessCPUSampler_percent(t *testing.T) {
+	at := time.Unix(1_700_000_000, 0)
+
+	tests := []struct {
+		name        string
+		prior       *processCPUSample
+		current     processCPUSample
+		wantPercent float64
+		wantOK      bool
+	}{
+		{
+			name:    "first sample",
+			current: processCPUSample{rootPID: 10, cpuSeconds: 5, at: at},
+		},
+		{
+			name:        "one and a half cores",
+			prior:       &processCPUSample{rootPID: 10, cpuSeconds: 5, at: at},
+			current:     processCPUSample{rootPID: 10, cpuSeconds: 8, at: at.Add(2 * time.Second)},
+			wantPercent: 150,
+			wantOK:      true,
+		},
+		{
+			name:    "server restarted",
+			prior:   &processCPUSample{rootPID: 10, cpuSeconds: 5, at: at},
+			current: processCPUSample{rootPID: 11, cpuSeconds: 8, at: at.Add(2 * time.Second)},
+		},
+		{
+			name:    "child exited",
+			prior:   &processCPUSample{rootPID: 10, cpuSeconds: 5, at: at},
+			current: processCPUSample{rootPID: 10, cpuSeconds: 3, at: at.Add(2 * time.Second)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sampler := newProcessCPUSampler()
+			if tt.prior != nil {
+				sampler.samples["server"] = *tt.prior
+			}
+
+			percent, ok := sampler.percent("server", tt.current)
+
+			assert.Equal(t, tt.wantOK, ok)
+			assert.InDelta(t, tt.wantPercent, percent, 0.001)
+		})
+	}
+}
+
+func TestProcessTreeToMetrics(t *testing.T) {
+	ts := time.Unix(1_700_000_000, 0)
+	stats := processTreeStats{MemoryRSS: 1024, IOReadBytes: 10, IOWriteBytes: 20, HasIO: true, PIDs: 3}
+
+	metrics := processTreeToMetrics(ts, map[string]string{metricLabelSession: "gameap-1"}, stats, 12.5, true)
+
+	names := make([]string, 0, len(metrics))
+	for _, m := range metrics {
+		names = append(names, m.Name)
+		assert.Equal(t, "gameap-1", m.Labels[metricLabelSession])
+	}
+	assert.Equal(t, []string{
+		metricServerCPUUsagePercent,
+		metricServerMemoryUsageBytes,
+		metricServerBlockIOReadBytesTotal,
+		metricServerBlockIOWriteBytesTotal,
+		metricServerProcessPIDs,
+	}, names)
+}
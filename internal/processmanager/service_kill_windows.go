@@ -0,0 +1,70 @@
+//go:build windows
+
+package processmanager
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/gameap/daemon/internal/app/contracts"
+	"github.com/gameap/daemon/internal/app/domain"
+	"github.com/pkg/errors"
+)
+
+// killServiceProcess forcibly terminates the process tree of a Windows
+// service. The service wrapper (winsw, shawl) and the game server it spawned
+// are killed together, the service control manager then marks the service
+// as stopped.
+func killServiceProcess(
+	ctx context.Context,
+	executor contracts.Executor,
+	serviceName string,
+	options contracts.ExecutorOptions,
+	out io.Writer,
+) (domain.Result, error) {
+	var query strings.Builder
+	_, err := executor.ExecWithWriter(ctx, fmt.Sprintf("sc queryex %s", serviceName), &query, options)
+	if err != nil {
+		return domain.ErrorResult, errors.WithMessage(err, "failed to query service")
+	}
+
+	pid := parseServicePID(query.String())
+	if pid <= 0 {
+		_, _ = out.Write([]byte("Service " + serviceName + " is not running\n"))
+
+		return domain.SuccessResult, nil
+	}
+
+	result, err := executor.ExecWithWriter(ctx, fmt.Sprintf("taskkill /F /T /PID %d", pid), out, options)
+	if err != nil {
+		return domain.ErrorResult, errors.WithMessage(err, "failed to kill service process")
+	}
+
+	return domain.Result(result), nil
+}
+
+// parseServicePID extracts the PID line of the "sc queryex" output:
+//
+//	PID                : 1234
+func parseServicePID(output string) int {
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		key, value, found := strings.Cut(scanner.Text(), ":")
+		if !found || strings.TrimSpace(key) != "PID" {
+			continue
+		}
+
+		pid, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil {
+			return 0
+		}
+
+		return pid
+	}
+
+	return 0
+}
@@ -171,6 +171,14 @@ func (pm *Shawl) Stop(ctx context.Context, server *domain.Server, out io.Writer)
 	return domain.SuccessResult, nil
 }
 
+// Kill terminates the service process tree without waiting for the
+// service to stop.
+func (pm *Shawl) Kill(ctx context.Context, server *domain.Server, out io.Writer) (domain.Result, error) {
+	return killServiceProcess(ctx, pm.executor, pm.serviceName(server), contracts.ExecutorOptions{
+		WorkDir: pm.cfg.WorkDir(),
+	}, out)
+}
+
 func (pm *Shawl) waitForServiceStopped(ctx context.Context, server *domain.Server) error {
 	ticker := time.NewTicker(stopTickerInterval)
 	defer ticker.Stop()
@@ -619,6 +627,14 @@ func (pm *Shawl) Attach(
 	return ErrNotImplemented
 }
 
+func (pm *Shawl) Pause(_ context.Context, _ *domain.Server, _ io.Writer) (domain.Result, error) {
+	return domain.ErrorResult, ErrNotImplemented
+}
+
+func (pm *Shawl) Unpause(_ context.Context, _ *domain.Server, _ io.Writer) (domain.Result, error) {
+	return domain.ErrorResult, ErrNotImplemented
+}
+
 func (pm *Shawl) HasOwnInstallation(_ *domain.Server) bool {
 	return false
 }
@@ -4,20 +4,33 @@ import (
 	"bufio"
 	"bytes"
 	"context"
+	"fmt"
 	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gameap/daemon/internal/app/config"
 	"github.com/gameap/daemon/internal/app/contracts"
 	"github.com/gameap/daemon/internal/app/domain"
+	"github.com/gameap/daemon/pkg/logger"
 	"github.com/pkg/errors"
 	"golang.org/x/sync/errgroup"
 )
 
+const (
+	keyPIDFile     = "pid_file"
+	defaultPIDFile = ".gameap.pid"
+)
+
 type Simple struct {
 	cfg              *config.Config
 	executor         contracts.Executor
 	detailedExecutor contracts.Executor
+	cpuSampler       *processCPUSampler
+	cgroups          *serverCgroups
 }
 
 func NewSimple(cfg *config.Config, executor, detailedExecutor contracts.Executor) *Simple {
@@ -25,6 +38,8 @@ func NewSimple(cfg *config.Config, executor, detailedExecutor contracts.Executor
 		cfg:              cfg,
 		executor:         executor,
 		detailedExecutor: detailedExecutor,
+		cpuSampler:       newProcessCPUSampler(),
+		cgroups:          newServerCgroups(cfg),
 	}
 }
 
@@ -38,16 +53,103 @@ func (pm *Simple) Uninstall(_ context.Context, _ *domain.Server, _ io.Writer) (d
 	return domain.SuccessResult, nil
 }
 
+// Start runs the start script with the server process priority, which the
+// server inherits from it. The process tree of the PID the script wrote to
+// the PID file is then moved into the server cgroup and gets the priority as
+// well, a server without a PID file runs without resource limits.
 func (pm *Simple) Start(
 	ctx context.Context, server *domain.Server, out io.Writer,
 ) (domain.Result, error) {
-	return pm.execCommand(ctx, server, pm.cfg.Scripts.Start, server.StartCommand(), out)
+	priority, err := processPriority(pm.cfg, server)
+	if err != nil {
+		return domain.ErrorResult, errors.WithMessage(err, "invalid server configuration")
+	}
+
+	options := pm.executeOptions(server)
+	options.Priority = priority
+
+	result, err := pm.execCommandWith(
+		ctx, pm.detailedExecutor, server, pm.cfg.Scripts.Start, server.StartCommand(), options, out,
+	)
+	if err != nil || result != domain.SuccessResult {
+		return result, err
+	}
+
+	pid, err := pm.serverPID(server)
+	if errors.Is(err, ErrServiceNotRunning) {
+		logger.Debug(ctx, errors.WithMessage(err, "server resource limits are not applied"))
+
+		return result, nil
+	}
+	if err != nil {
+		logger.Warn(ctx, errors.WithMessage(err, "server resource limits are not applied"))
+
+		return result, nil
+	}
+
+	if err := pm.cgroups.place(ctx, server, pid); err != nil {
+		logger.Warn(ctx, errors.WithMessage(err, "failed to apply server resource limits"))
+	}
+
+	if err := applyProcessTreePriority(ctx, server, pid, priority); err != nil {
+		logger.Warn(ctx, errors.WithMessage(err, "failed to apply server process priority"))
+	}
+
+	return result, nil
 }
 
+// Stop runs the configured stop script. Without one, the server is stopped
+// gracefully: the stop command is sent to the console first, then the process
+// group from the PID file receives SIGTERM and finally SIGKILL. A PID that does
+// not belong to the server is not signalled.
 func (pm *Simple) Stop(
 	ctx context.Context, server *domain.Server, out io.Writer,
 ) (domain.Result, error) {
-	return pm.execCommand(ctx, server, pm.cfg.Scripts.Stop, server.StopCommand(), out)
+	defer pm.cgroups.remove(ctx, server)
+
+	if pm.cfg.Scripts.Stop != "" {
+		return pm.execCommand(ctx, server, pm.cfg.Scripts.Stop, server.StopCommand(), out)
+	}
+
+	pid, err := pm.serverPID(server)
+	if err != nil {
+		return domain.ErrorResult, err
+	}
+
+	exited := pidExitChecker(pid)
+
+	if stopViaConsole(ctx, pm.cfg, pm, server, exited, out) {
+		return domain.SuccessResult, nil
+	}
+
+	if !terminateProcess(ctx, pm.cfg, server, pid, exited, out) {
+		return domain.ErrorResult, errors.Wrapf(ErrProcessStillRunning, "pid %d", pid)
+	}
+
+	return domain.SuccessResult, nil
+}
+
+// Kill runs the configured kill script with the server force stop command,
+// or kills the process group from the PID file when no script is set.
+func (pm *Simple) Kill(
+	ctx context.Context, server *domain.Server, out io.Writer,
+) (domain.Result, error) {
+	defer pm.cgroups.remove(ctx, server)
+
+	if pm.cfg.Scripts.Kill != "" {
+		return pm.execCommand(ctx, server, pm.cfg.Scripts.Kill, server.ForceStopCommand(), out)
+	}
+
+	pid, err := pm.serverPID(server)
+	if err != nil {
+		return domain.ErrorResult, err
+	}
+
+	if !killProcess(ctx, pid, pidExitChecker(pid), out) {
+		return domain.ErrorResult, errors.Wrapf(ErrProcessStillRunning, "pid %d", pid)
+	}
+
+	return domain.SuccessResult, nil
 }
 
 func (pm *Simple) Restart(
@@ -67,7 +169,9 @@ func (pm *Simple) GetOutput(
 ) (domain.Result, error) {
 	// The console is read with the plain executor: the detailed one prefixes the
 	// command line and appends the exit code, which would pollute the output.
-	return pm.execCommandWith(ctx, pm.executor, server, pm.cfg.Scripts.GetConsole, "", out)
+	return pm.execCommandWith(
+		ctx, pm.executor, server, pm.cfg.Scripts.GetConsole, "", pm.executeOptions(server), out,
+	)
 }
 
 func (pm *Simple) SendInput(
@@ -76,10 +180,102 @@ func (pm *Simple) SendInput(
 	return pm.execCommand(ctx, server, pm.cfg.Scripts.SendCommand, input, out)
 }
 
+// Pause runs the configured pause script, or freezes the process group
+// recorded in the server PID file when no script is set.
+func (pm *Simple) Pause(
+	ctx context.Context, server *domain.Server, out io.Writer,
+) (domain.Result, error) {
+	if pm.cfg.Scripts.Pause != "" {
+		return pm.execCommand(ctx, server, pm.cfg.Scripts.Pause, "", out)
+	}
+
+	return pm.signalPIDFile(server, freezeProcessGroup, "paused", out)
+}
+
+// Unpause runs the configured unpause script, or thaws the process group
+// recorded in the server PID file when no script is set.
+func (pm *Simple) Unpause(
+	ctx context.Context, server *domain.Server, out io.Writer,
+) (domain.Result, error) {
+	if pm.cfg.Scripts.Unpause != "" {
+		return pm.execCommand(ctx, server, pm.cfg.Scripts.Unpause, "", out)
+	}
+
+	return pm.signalPIDFile(server, thawProcessGroup, "resumed", out)
+}
+
+func (pm *Simple) signalPIDFile(
+	server *domain.Server, signal func(pid int) error, action string, out io.Writer,
+) (domain.Result, error) {
+	pid, err := pm.serverPID(server)
+	if err != nil {
+		return domain.ErrorResult, err
+	}
+
+	if err := signal(pid); err != nil {
+		return domain.ErrorResult, errors.WithMessagef(err, "failed to signal process %d", pid)
+	}
+
+	_, _ = fmt.Fprintf(out, "Server processes %s (pid %d)\n", action, pid)
+
+	return domain.SuccessResult, nil
+}
+
+// serverPID returns the PID from the server PID file once it is checked to
+// be a process of the server. The file is in the server directory, so the
+// server user can write any PID to it.
+func (pm *Simple) serverPID(server *domain.Server) (int, error) {
+	path, err := pm.pidFilePath(server)
+	if err != nil {
+		return 0, err
+	}
+
+	pid, err := readPIDFile(path)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := validateServerPID(server, pid); err != nil {
+		return 0, err
+	}
+
+	return pid, nil
+}
+
+// pidFilePath resolves the file the start script writes the server PID to.
+// Relative paths are resolved against the server working directory. A path
+// from the server vars must stay in that directory, absolute paths are only
+// taken from the game metadata and the process manager config.
+func (pm *Simple) pidFilePath(server *domain.Server) (string, error) {
+	path := getContainerConfig(pm.cfg, server, keyPIDFile)
+	if path == "" {
+		path = defaultPIDFile
+	}
+
+	path = domain.ReplaceShortCodes(path, pm.cfg, server)
+	if filepath.IsAbs(path) {
+		if server.Vars()[keyPIDFile] != "" {
+			return "", errors.Wrapf(ErrUnsafePIDFile, "%s from server vars is absolute", path)
+		}
+
+		return path, nil
+	}
+
+	workDir := server.WorkDir(pm.cfg)
+	path = filepath.Join(workDir, path)
+
+	if rel, err := filepath.Rel(workDir, path); err != nil || rel == ".." ||
+		strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", errors.Wrapf(ErrUnsafePIDFile, "%s is outside of the server directory", path)
+	}
+
+	return path, nil
+}
+
 func (pm *Simple) execCommand(
 	ctx context.Context, server *domain.Server, wrapper, serverCommand string, out io.Writer,
 ) (domain.Result, error) {
-	return pm.execCommandWith(ctx, pm.detailedExecutor, server, wrapper, serverCommand, out)
+	return pm.execCommandWith(ctx, pm.detailedExecutor, server, wrapper, serverCommand, pm.executeOptions(server), out)
 }
 
 func (pm *Simple) execCommandWith(
@@ -87,6 +283,7 @@ func (pm *Simple) execCommandWith(
 	executor contracts.Executor,
 	server *domain.Server,
 	wrapper, serverCommand string,
+	options contracts.ExecutorOptions,
 	out io.Writer,
 ) (domain.Result, error) {
 	args, err := domain.BuildCommandArgs(pm.cfg, server, wrapper, serverCommand)
@@ -98,7 +295,7 @@ func (pm *Simple) execCommandWith(
 		ctx,
 		args,
 		out,
-		pm.executeOptions(server),
+		options,
 	)
 	if err != nil {
 		return domain.ErrorResult, errors.WithMessage(err, "failed to exec command")
@@ -214,9 +411,62 @@ func (pm *Simple) HasOwnInstallation(_ *domain.Server) bool {
 	return false
 }
 
-// Metrics returns only the cached process-active gauge for now. PID-based
-// resource collection (cpu/memory/io via gopsutil/process) is tracked as a
-// follow-up since the simple process manager does not persist PIDs.
-func (pm *Simple) Metrics(_ context.Context, server *domain.Server) ([]domain.Metric, error) {
-	return []domain.Metric{livenessMetric(server, time.Now())}, nil
+// Metrics returns CPU, memory, block-IO and PID counters of the server
+// cgroup, or summed over the process tree rooted at the PID from the server
+// PID file when the server has no cgroup. Falls back to the
+// cached liveness gauge alone when the start script does not write one.
+func (pm *Simple) Metrics(ctx context.Context, server *domain.Server) ([]domain.Metric, error) {
+	now := time.Now()
+	out := make([]domain.Metric, 0, 6)
+	out = append(out, livenessMetric(server, now))
+
+	pid, err := pm.serverPID(server)
+	if err != nil {
+		return out, nil
+	}
+
+	stats, ok := pm.cgroups.stats(ctx, server)
+	if !ok {
+		stats, err = collectProcessTreeStats(ctx, int32(pid))
+		if err != nil {
+			logger.Debug(ctx, errors.WithMessage(err, "failed to collect server process metrics"))
+			return out, nil
+		}
+	}
+
+	cpuPercent, hasCPU := pm.cpuSampler.percent(server.UUID(), processCPUSample{
+		rootPID:    int32(pid),
+		cpuSeconds: stats.CPUSeconds,
+		at:         now,
+	})
+
+	out = append(out, processTreeToMetrics(now, nil, stats, cpuPercent, hasCPU)...)
+
+	return out, nil
+}
+
+// readPIDFile reads the PID from path. A symbolic link is not followed, the
+// server user could point it at any file.
+func readPIDFile(path string) (int, error) {
+	f, err := os.OpenFile(path, os.O_RDONLY|openFlagNoFollow, 0)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, errors.Wrapf(ErrServiceNotRunning, "pid file %s not found", path)
+		}
+
+		return 0, errors.Wrap(err, "failed to open pid file")
+	}
+	defer f.Close()
+
+	data, err := io.ReadAll(io.LimitReader(f, 64))
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to read pid file")
+	}
+
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil || pid <= 0 {
+		return 0, errors.Wrapf(ErrInvalidPID, "pid file %s contains %q", path, strings.TrimSpace(string(data)))
+	}
+
+	return pid, nil
 }
@@ -0,0 +1,205 @@
+//go:build linux || darwin
+
+package processmanager
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"testing"
+
+	"github.com/gameap/daemon/internal/app/config"
+	"github.com/gameap/daemon/internal/app/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSimple_pidFilePath(t *testing.T) {
+	cfg := &config.Config{WorkPath: "/srv/gameap"}
+	pm := NewSimple(cfg, nil, nil)
+
+	tests := []struct {
+		name     string
+		vars     map[string]string
+		pmConfig map[string]string
+		expected string
+	}{
+		{
+			name:     "default pid file in server directory",
+			expected: "/srv/gameap/servers/test/.gameap.pid",
+		},
+		{
+			name:     "relative pid file from server vars",
+			vars:     map[string]string{"pid_file": "run/server.pid"},
+			expected: "/srv/gameap/servers/test/run/server.pid",
+		},
+		{
+			name:     "absolute pid file with shortcodes from process manager config",
+			pmConfig: map[string]string{"pid_file": "/run/gameap/{uuid}.pid"},
+			expected: "/run/gameap/test-uuid-5678.pid",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg.ProcessManager.Config = tt.pmConfig
+			server := createPodmanTestServer(tt.vars, nil, nil)
+
+			path, err := pm.pidFilePath(server)
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, path)
+		})
+	}
+}
+
+func TestSimple_pidFilePath_Unsafe(t *testing.T) {
+	pm := NewSimple(&config.Config{WorkPath: "/srv/gameap"}, nil, nil)
+
+	for _, pidFile := range []string{"/run/gameap/server.pid", "../other/.gameap.pid", ".."} {
+		_, err := pm.pidFilePath(createPodmanTestServer(map[string]string{"pid_file": pidFile}, nil, nil))
+
+		assert.ErrorIs(t, err, ErrUnsafePIDFile, pidFile)
+	}
+}
+
+func TestReadPIDFile(t *testing.T) {
+	dir := t.TempDir()
+
+	t.Run("missing file", func(t *testing.T) {
+		_, err := readPIDFile(filepath.Join(dir, "missing.pid"))
+
+		assert.ErrorIs(t, err, ErrServiceNotRunning)
+	})
+
+	t.Run("invalid content", func(t *testing.T) {
+		path := filepath.Join(dir, "invalid.pid")
+		require.NoError(t, os.WriteFile(path, []byte("not-a-pid\n"), 0600))
+
+		_, err := readPIDFile(path)
+
+		assert.ErrorIs(t, err, ErrInvalidPID)
+	})
+
+	t.Run("symbolic link", func(t *testing.T) {
+		target := filepath.Join(dir, "target.pid")
+		require.NoError(t, os.WriteFile(target, []byte("1\n"), 0600))
+		path := filepath.Join(dir, "link.pid")
+		require.NoError(t, os.Symlink(target, path))
+
+		_, err := readPIDFile(path)
+
+		assert.Error(t, err)
+	})
+
+	t.Run("valid pid with trailing newline", func(t *testing.T) {
+		path := filepath.Join(dir, "valid.pid")
+		require.NoError(t, os.WriteFile(path, []byte("4242\n"), 0600))
+
+		pid, err := readPIDFile(path)
+
+		require.NoError(t, err)
+		assert.Equal(t, 4242, pid)
+	})
+}
+
+func TestSimple_PauseUnpause_SignalsProcessFromPIDFile(t *testing.T) {
+	cmd := exec.Command("sleep", "30")
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	require.NoError(t, cmd.Start())
+	t.Cleanup(func() {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+	})
+
+	pm, server := givenSimpleServerPIDFile(t, strconv.Itoa(cmd.Process.Pid))
+
+	out := &bytes.Buffer{}
+	result, err := pm.Pause(context.Background(), server, out)
+	require.NoError(t, err)
+	assert.Equal(t, domain.SuccessResult, result)
+	assert.Contains(t, out.String(), "paused")
+
+	result, err = pm.Unpause(context.Background(), server, out)
+	require.NoError(t, err)
+	assert.Equal(t, domain.SuccessResult, result)
+	assert.Contains(t, out.String(), "resumed")
+}
+
+func TestSimple_serverPID(t *testing.T) {
+	t.Run("own process group", func(t *testing.T) {
+		cmd := exec.Command("sleep", "30")
+		require.NoError(t, cmd.Start())
+		t.Cleanup(func() {
+			_ = cmd.Process.Kill()
+			_ = cmd.Wait()
+		})
+		pm, server := givenSimpleServerPIDFile(t, strconv.Itoa(cmd.Process.Pid))
+
+		_, err := pm.serverPID(server)
+
+		assert.ErrorIs(t, err, ErrForeignPID)
+	})
+
+	for _, pid := range []string{"1", strconv.Itoa(os.Getpid())} {
+		t.Run("pid "+pid, func(t *testing.T) {
+			pm, server := givenSimpleServerPIDFile(t, pid)
+
+			result, err := pm.Pause(context.Background(), server, &bytes.Buffer{})
+
+			require.ErrorIs(t, err, ErrForeignPID)
+			assert.Equal(t, domain.ErrorResult, result)
+		})
+	}
+
+	t.Run("other user", func(t *testing.T) {
+		cmd := exec.Command("sleep", "30")
+		cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+		if os.Getuid() == 0 {
+			cmd.SysProcAttr.Credential = &syscall.Credential{Uid: 65534, Gid: 65534}
+		}
+		require.NoError(t, cmd.Start())
+		t.Cleanup(func() {
+			_ = cmd.Process.Kill()
+			_ = cmd.Wait()
+		})
+		if os.Getuid() != 0 {
+			t.Skip("starting a process of another user needs root")
+		}
+		pm, server := givenSimpleServerPIDFile(t, strconv.Itoa(cmd.Process.Pid))
+
+		_, err := pm.serverPID(server)
+
+		assert.ErrorIs(t, err, ErrForeignPID)
+	})
+}
+
+func TestSimple_StopKill_RefuseForeignPID(t *testing.T) {
+	pm, server := givenSimpleServerPIDFile(t, "1")
+
+	result, err := pm.Stop(context.Background(), server, &bytes.Buffer{})
+	require.ErrorIs(t, err, ErrForeignPID)
+	assert.Equal(t, domain.ErrorResult, result)
+
+	result, err = pm.Kill(context.Background(), server, &bytes.Buffer{})
+	require.ErrorIs(t, err, ErrForeignPID)
+	assert.Equal(t, domain.ErrorResult, result)
+}
+
+// givenSimpleServerPIDFile writes pid to the default PID file of a server
+// without a user, which runs as the daemon user.
+func givenSimpleServerPIDFile(t *testing.T, pid string) (*Simple, *domain.Server) {
+	t.Helper()
+
+	pm := NewSimple(&config.Config{WorkPath: t.TempDir()}, nil, nil)
+	server := createPodmanTestServer(nil, nil, nil)
+
+	require.NoError(t, os.MkdirAll(server.WorkDir(pm.cfg), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(server.WorkDir(pm.cfg), defaultPIDFile), []byte(pid), 0o600))
+
+	return pm, server
+}
@@ -0,0 +1,176 @@
+//go:build linux || darwin
+
+package supervisor
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/sync/errgroup"
+)
+
+const defaultRequestTimeout = 10 * time.Second
+
+var errAttachClosed = errors.New("attach stream closed")
+
+// Client talks to the shim of a server.
+type Client struct {
+	socket string
+}
+
+func NewClient(socket string) *Client {
+	return &Client{socket: socket}
+}
+
+// Status returns the state of the server process.
+func (c *Client) Status(ctx context.Context) (State, error) {
+	resp, err := c.call(ctx, request{Op: opStatus})
+	if err != nil {
+		return State{}, err
+	}
+	if resp.State == nil {
+		return State{}, errors.Wrap(errShimRequest, "no state in response")
+	}
+
+	return *resp.State, nil
+}
+
+// Output returns the buffered console output.
+func (c *Client) Output(ctx context.Context) ([]byte, error) {
+	resp, err := c.call(ctx, request{Op: opOutput})
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Output, nil
+}
+
+// Input writes a line to the server console.
+func (c *Client) Input(ctx context.Context, line string) error {
+	_, err := c.call(ctx, request{Op: opInput, Data: line})
+
+	return err
+}
+
+// Shutdown stops the shim of an exited server.
+func (c *Client) Shutdown(ctx context.Context) error {
+	_, err := c.call(ctx, request{Op: opShutdown})
+
+	return err
+}
+
+// Attach connects in and out to the server console until ctx is done or the
+// server exits.
+func (c *Client) Attach(ctx context.Context, in io.Reader, out io.Writer) error {
+	conn, err := c.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	reader := bufio.NewReader(conn)
+	if _, err := c.exchange(ctx, conn, reader, request{Op: opAttach}); err != nil {
+		return err
+	}
+	_ = conn.SetDeadline(time.Time{})
+
+	g, gctx := errgroup.WithContext(ctx)
+
+	// Close the connection when context is done to unblock I/O goroutines.
+	g.Go(func() error {
+		<-gctx.Done()
+		_ = conn.Close()
+		return nil
+	})
+
+	g.Go(func() error {
+		_, cpErr := io.Copy(conn, in)
+		if uc, ok := conn.(*net.UnixConn); ok {
+			_ = uc.CloseWrite()
+		}
+		if cpErr != nil && !errors.Is(cpErr, net.ErrClosed) {
+			return errors.Wrap(cpErr, "stdin copy failed")
+		}
+		return nil
+	})
+
+	g.Go(func() error {
+		_, cpErr := io.Copy(out, reader)
+		if cpErr != nil && !errors.Is(cpErr, net.ErrClosed) {
+			return errors.Wrap(cpErr, "stdout copy failed")
+		}
+		// The shim closes the stream when the server exits.
+		return errAttachClosed
+	})
+
+	err = g.Wait()
+	if err != nil && !errors.Is(err, context.Canceled) && !errors.Is(err, errAttachClosed) {
+		return err
+	}
+
+	return nil
+}
+
+func (c *Client) call(ctx context.Context, req request) (response, error) {
+	conn, err := c.dial(ctx)
+	if err != nil {
+		return response{}, err
+	}
+	defer conn.Close()
+
+	return c.exchange(ctx, conn, bufio.NewReader(conn), req)
+}
+
+func (c *Client) dial(ctx context.Context) (net.Conn, error) {
+	var dialer net.Dialer
+
+	conn, err := dialer.DialContext(ctx, "unix", c.socket)
+	if err != nil {
+		if errors.Is(err, syscall.ENOENT) || errors.Is(err, syscall.ECONNREFUSED) {
+			return nil, errors.Wrapf(ErrShimNotRunning, "socket %s", c.socket)
+		}
+
+		return nil, errors.Wrap(err, "failed to connect to supervisor shim")
+	}
+
+	return conn, nil
+}
+
+func (c *Client) exchange(
+	ctx context.Context, conn net.Conn, reader *bufio.Reader, req request,
+) (response, error) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(defaultRequestTimeout)
+	}
+	_ = conn.SetDeadline(deadline)
+
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		return response{}, errors.Wrap(err, "failed to send request to supervisor shim")
+	}
+
+	line, err := reader.ReadBytes('\n')
+	if err != nil {
+		return response{}, errors.Wrap(err, "failed to read supervisor shim response")
+	}
+
+	var resp response
+	if err := json.Unmarshal(line, &resp); err != nil {
+		return response{}, errors.Wrap(err, "invalid supervisor shim response")
+	}
+
+	switch {
+	case resp.Error == ErrProcessNotRunning.Error():
+		return response{}, ErrProcessNotRunning
+	case resp.Error != "":
+		return response{}, errors.Wrap(errShimRequest, resp.Error)
+	}
+
+	return resp, nil
+}
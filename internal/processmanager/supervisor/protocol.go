@@ -0,0 +1,53 @@
+//go:build linux || darwin
+
+// Package supervisor runs a game server in a pseudo terminal under a small
+// shim process. The shim is detached from the daemon, so the server keeps
+// running when the daemon restarts. It keeps the recent console output and
+// serves the daemon over a Unix socket: one JSON request line per
+// connection, answered with one JSON response line. An attach connection
+// turns into a raw stream afterwards, console input in and output out.
+package supervisor
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	opStatus   = "status"
+	opOutput   = "output"
+	opInput    = "input"
+	opAttach   = "attach"
+	opShutdown = "shutdown"
+)
+
+var (
+	// ErrShimNotRunning is returned when there is no shim listening on the socket.
+	ErrShimNotRunning = errors.New("supervisor shim is not running")
+
+	// ErrProcessNotRunning is returned for console requests after the server exited.
+	ErrProcessNotRunning = errors.New("server process is not running")
+
+	errShimRequest = errors.New("supervisor shim request failed")
+)
+
+// State is the state of the server process of a shim.
+type State struct {
+	PID       int       `json:"pid"`
+	Running   bool      `json:"running"`
+	ExitCode  int       `json:"exit_code"`
+	StartedAt time.Time `json:"started_at"`
+	ExitedAt  time.Time `json:"exited_at,omitzero"`
+}
+
+type request struct {
+	Op   string `json:"op"`
+	Data string `json:"data,omitempty"`
+}
+
+type response struct {
+	Error  string `json:"error,omitempty"`
+	State  *State `json:"state,omitempty"`
+	Output []byte `json:"output,omitempty"`
+}
@@ -0,0 +1,60 @@
+//go:build linux || darwin
+
+package supervisor
+
+import "sync"
+
+// ringBuffer keeps the last size bytes written to it.
+type ringBuffer struct {
+	mu   sync.Mutex
+	buf  []byte
+	pos  int
+	full bool
+}
+
+func newRingBuffer(size int) *ringBuffer {
+	return &ringBuffer{buf: make([]byte, size)}
+}
+
+func (r *ringBuffer) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	n := len(p)
+
+	if len(p) >= len(r.buf) {
+		copy(r.buf, p[len(p)-len(r.buf):])
+		r.pos = 0
+		r.full = true
+
+		return n, nil
+	}
+
+	copied := copy(r.buf[r.pos:], p)
+	if copied < len(p) {
+		copy(r.buf, p[copied:])
+		r.full = true
+	}
+
+	r.pos = (r.pos + len(p)) % len(r.buf)
+	if r.pos == 0 {
+		r.full = true
+	}
+
+	return n, nil
+}
+
+// Bytes returns a copy of the buffered bytes, oldest first.
+func (r *ringBuffer) Bytes() []byte {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.full {
+		return append([]byte(nil), r.buf[:r.pos]...)
+	}
+
+	out := make([]byte, 0, len(r.buf))
+	out = append(out, r.buf[r.pos:]...)
+
+	return append(out, r.buf[:r.pos]...)
+}
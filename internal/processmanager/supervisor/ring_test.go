@@ -0,0 +1,49 @@
+//go:build linux || darwin
+
+package supervisor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRingBuffer(t *testing.T) {
+	t.Run("not full", func(t *testing.T) {
+		r := newRingBuffer(8)
+
+		_, _ = r.Write([]byte("abc"))
+		_, _ = r.Write([]byte("de"))
+
+		assert.Equal(t, []byte("abcde"), r.Bytes())
+	})
+
+	t.Run("wraps around", func(t *testing.T) {
+		r := newRingBuffer(8)
+
+		_, _ = r.Write([]byte("abcdef"))
+		_, _ = r.Write([]byte("ghijk"))
+
+		assert.Equal(t, []byte("defghijk"), r.Bytes())
+	})
+
+	t.Run("exactly full", func(t *testing.T) {
+		r := newRingBuffer(4)
+
+		_, _ = r.Write([]byte("ab"))
+		_, _ = r.Write([]byte("cd"))
+
+		assert.Equal(t, []byte("abcd"), r.Bytes())
+	})
+
+	t.Run("write larger than buffer", func(t *testing.T) {
+		r := newRingBuffer(4)
+
+		_, _ = r.Write([]byte("x"))
+		n, err := r.Write([]byte("abcdefgh"))
+
+		assert.NoError(t, err)
+		assert.Equal(t, 8, n)
+		assert.Equal(t, []byte("efgh"), r.Bytes())
+	})
+}
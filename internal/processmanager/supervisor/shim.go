@@ -0,0 +1,457 @@
+//go:build linux || darwin
+
+package supervisor
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/creack/pty"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// ShimCommand is the hidden daemon command that runs the shim.
+const ShimCommand = "supervisor-shim"
+
+// The flags of ShimCommand.
+const (
+	FlagSocket     = "socket"
+	FlagWorkDir    = "work-dir"
+	FlagUID        = "uid"
+	FlagGID        = "gid"
+	FlagBufferSize = "buffer-size"
+	FlagLinger     = "linger"
+)
+
+const (
+	DefaultBufferSize = 256 * 1024
+	DefaultLinger     = 10 * time.Minute
+
+	terminalCols = 200
+	terminalRows = 50
+
+	// outputDrainTimeout is how long the exit is held back for the output the
+	// server wrote just before it. Children left running keep the terminal
+	// open, so the output does not always end.
+	outputDrainTimeout = time.Second
+
+	// attachQueue is the number of output chunks queued per attached client,
+	// a client that falls further behind misses output instead of blocking
+	// the server.
+	attachQueue = 256
+)
+
+var errEmptyCommand = errors.New("empty command")
+
+// ShimOptions configures the shim of a server.
+type ShimOptions struct {
+	Socket  string
+	Args    []string
+	WorkDir string
+
+	// UID and GID of the server process, the shim user when empty.
+	UID string
+	GID string
+
+	// Env of the server process, the shim environment when nil.
+	Env []string
+
+	BufferSize int
+
+	// Linger is how long the shim waits after the server exited, so the
+	// daemon can read the exit code and the last output.
+	Linger time.Duration
+}
+
+// CommandArgs returns the arguments of ShimCommand for the options.
+func (opts ShimOptions) CommandArgs() []string {
+	args := []string{
+		ShimCommand,
+		"--" + FlagSocket, opts.Socket,
+		"--" + FlagWorkDir, opts.WorkDir,
+		"--" + FlagUID, opts.UID,
+		"--" + FlagGID, opts.GID,
+		"--" + FlagBufferSize, strconv.Itoa(opts.BufferSize),
+		"--" + FlagLinger, opts.Linger.String(),
+		"--",
+	}
+
+	return append(args, opts.Args...)
+}
+
+type shim struct {
+	opts     ShimOptions
+	cmd      *exec.Cmd
+	terminal *os.File
+	output   *ringBuffer
+
+	mu          sync.Mutex
+	state       State
+	subscribers map[chan []byte]struct{}
+	outputDone  bool
+
+	pumped   chan struct{}
+	exited   chan struct{}
+	shutdown chan struct{}
+	once     sync.Once
+}
+
+// RunShim starts the server process and serves the socket until the server
+// exited and the daemon asked for shutdown, the linger time passed or ctx is
+// done. A server still running when ctx is done gets SIGTERM.
+func RunShim(ctx context.Context, opts ShimOptions) error {
+	if len(opts.Args) == 0 {
+		return errEmptyCommand
+	}
+	if opts.BufferSize <= 0 {
+		opts.BufferSize = DefaultBufferSize
+	}
+	if opts.Linger <= 0 {
+		opts.Linger = DefaultLinger
+	}
+
+	listener, err := listen(opts.Socket)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = listener.Close()
+		_ = os.Remove(opts.Socket)
+	}()
+
+	s := &shim{
+		opts:        opts,
+		output:      newRingBuffer(opts.BufferSize),
+		subscribers: make(map[chan []byte]struct{}),
+		pumped:      make(chan struct{}),
+		exited:      make(chan struct{}),
+		shutdown:    make(chan struct{}),
+	}
+
+	if err := s.start(); err != nil {
+		return err
+	}
+
+	go s.pumpOutput()
+	go s.wait()
+	go s.serve(listener)
+
+	select {
+	case <-s.exited:
+	case <-ctx.Done():
+		s.terminate()
+		<-s.exited
+	}
+
+	linger := time.NewTimer(opts.Linger)
+	defer linger.Stop()
+
+	select {
+	case <-s.shutdown:
+	case <-linger.C:
+	case <-ctx.Done():
+	}
+
+	return nil
+}
+
+func listen(socket string) (net.Listener, error) {
+	if err := os.MkdirAll(filepath.Dir(socket), 0o700); err != nil {
+		return nil, errors.Wrap(err, "failed to create socket directory")
+	}
+
+	// A socket left by a killed shim would block the listener.
+	if err := os.Remove(socket); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, errors.Wrap(err, "failed to remove stale socket")
+	}
+
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to listen on socket")
+	}
+
+	if err := os.Chmod(socket, 0o600); err != nil {
+		_ = listener.Close()
+		return nil, errors.Wrap(err, "failed to chmod socket")
+	}
+
+	return listener, nil
+}
+
+func (s *shim) start() error {
+	cmd := exec.Command(s.opts.Args[0], s.opts.Args[1:]...) //nolint:gosec
+	cmd.Dir = s.opts.WorkDir
+	cmd.Env = s.opts.Env
+
+	// The server leads its own session with the terminal as the controlling
+	// one, so it and everything it spawns form a process group to signal.
+	attrs := &syscall.SysProcAttr{Setsid: true, Setctty: true}
+
+	credential, err := processCredential(s.opts.UID, s.opts.GID)
+	if err != nil {
+		return err
+	}
+	attrs.Credential = credential
+
+	terminal, err := pty.StartWithAttrs(cmd, &pty.Winsize{Cols: terminalCols, Rows: terminalRows}, attrs)
+	if err != nil {
+		return errors.Wrap(err, "failed to start server process")
+	}
+
+	s.cmd = cmd
+	s.terminal = terminal
+	s.state = State{PID: cmd.Process.Pid, Running: true, StartedAt: time.Now()}
+
+	return nil
+}
+
+// processCredential returns the credential of the server user, nil to keep
+// the user of the shim.
+func processCredential(uid, gid string) (*syscall.Credential, error) {
+	if uid == "" || uid == strconv.Itoa(os.Getuid()) && (gid == "" || gid == strconv.Itoa(os.Getgid())) {
+		return nil, nil //nolint:nilnil
+	}
+
+	u, err := user.LookupId(uid)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to lookup user %s", uid)
+	}
+
+	if gid == "" {
+		gid = u.Gid
+	}
+
+	uidNum, err := strconv.ParseUint(uid, 10, 32)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid uid %s", uid)
+	}
+
+	gidNum, err := strconv.ParseUint(gid, 10, 32)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid gid %s", gid)
+	}
+
+	credential := &syscall.Credential{Uid: uint32(uidNum), Gid: uint32(gidNum)}
+
+	groupIDs, err := u.GroupIds()
+	if err != nil {
+		log.WithError(err).Warnf("Failed to resolve supplementary groups of user %s", u.Username)
+	}
+	for _, g := range groupIDs {
+		if n, err := strconv.ParseUint(g, 10, 32); err == nil {
+			credential.Groups = append(credential.Groups, uint32(n))
+		}
+	}
+
+	return credential, nil
+}
+
+// pumpOutput copies the terminal output to the buffer and the attached
+// clients. Reading fails once every process holding the terminal exited.
+func (s *shim) pumpOutput() {
+	buf := make([]byte, 32*1024)
+
+	for {
+		n, err := s.terminal.Read(buf)
+		if n > 0 {
+			chunk := append([]byte(nil), buf[:n]...)
+			_, _ = s.output.Write(chunk)
+			s.broadcast(chunk)
+		}
+		if err != nil {
+			break
+		}
+	}
+
+	defer close(s.pumped)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.outputDone = true
+	for ch := range s.subscribers {
+		close(ch)
+		delete(s.subscribers, ch)
+	}
+}
+
+func (s *shim) broadcast(chunk []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for ch := range s.subscribers {
+		select {
+		case ch <- chunk:
+		default:
+		}
+	}
+}
+
+func (s *shim) wait() {
+	err := s.cmd.Wait()
+
+	exitCode := 0
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		exitCode = exitErr.ExitCode()
+		if status, ok := exitErr.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+			exitCode = 128 + int(status.Signal())
+		}
+	}
+
+	select {
+	case <-s.pumped:
+	case <-time.After(outputDrainTimeout):
+	}
+
+	s.mu.Lock()
+	s.state.Running = false
+	s.state.ExitCode = exitCode
+	s.state.ExitedAt = time.Now()
+	s.mu.Unlock()
+
+	close(s.exited)
+}
+
+// terminate sends SIGTERM to the process group of the server.
+func (s *shim) terminate() {
+	if err := syscall.Kill(-s.cmd.Process.Pid, syscall.SIGTERM); err != nil {
+		_ = s.cmd.Process.Signal(syscall.SIGTERM)
+	}
+}
+
+func (s *shim) serve(listener net.Listener) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+
+		go s.handle(conn)
+	}
+}
+
+func (s *shim) handle(conn net.Conn) {
+	defer conn.Close()
+
+	_ = conn.SetDeadline(time.Now().Add(defaultRequestTimeout))
+
+	reader := bufio.NewReader(conn)
+	line, err := reader.ReadBytes('\n')
+	if err != nil {
+		return
+	}
+
+	var req request
+	if err := json.Unmarshal(line, &req); err != nil {
+		writeResponse(conn, response{Error: "invalid request"})
+		return
+	}
+
+	switch req.Op {
+	case opStatus:
+		state := s.currentState()
+		writeResponse(conn, response{State: &state})
+	case opOutput:
+		writeResponse(conn, response{Output: s.output.Bytes()})
+	case opInput:
+		writeResponse(conn, s.input(req.Data))
+	case opAttach:
+		s.attach(conn, reader)
+	case opShutdown:
+		if s.currentState().Running {
+			writeResponse(conn, response{Error: "server is running"})
+			return
+		}
+
+		writeResponse(conn, response{})
+		s.once.Do(func() { close(s.shutdown) })
+	default:
+		writeResponse(conn, response{Error: "unknown operation " + strconv.Quote(req.Op)})
+	}
+}
+
+func (s *shim) currentState() State {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.state
+}
+
+func (s *shim) input(line string) response {
+	if !s.currentState().Running {
+		return response{Error: ErrProcessNotRunning.Error()}
+	}
+
+	if _, err := io.WriteString(s.terminal, line+"\n"); err != nil {
+		return response{Error: err.Error()}
+	}
+
+	return response{}
+}
+
+// attach streams the output to the connection and the connection to the
+// terminal until either side closes.
+func (s *shim) attach(conn net.Conn, reader *bufio.Reader) {
+	ch := make(chan []byte, attachQueue)
+
+	s.mu.Lock()
+	if !s.state.Running || s.outputDone {
+		s.mu.Unlock()
+		writeResponse(conn, response{Error: ErrProcessNotRunning.Error()})
+
+		return
+	}
+	s.subscribers[ch] = struct{}{}
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		if _, ok := s.subscribers[ch]; ok {
+			delete(s.subscribers, ch)
+			close(ch)
+		}
+		s.mu.Unlock()
+	}()
+
+	writeResponse(conn, response{})
+	_ = conn.SetDeadline(time.Time{})
+
+	inputDone := make(chan struct{})
+	go func() {
+		defer close(inputDone)
+		_, _ = io.Copy(s.terminal, reader)
+	}()
+
+	for {
+		select {
+		case chunk, ok := <-ch:
+			if !ok {
+				return
+			}
+			if _, err := conn.Write(chunk); err != nil {
+				return
+			}
+		case <-inputDone:
+			// The client closed its side, the output is streamed until
+			// the connection breaks.
+			inputDone = nil
+		}
+	}
+}
+
+func writeResponse(conn net.Conn, resp response) {
+	_ = json.NewEncoder(conn).Encode(resp)
+}
@@ -0,0 +1,168 @@
+//go:build linux || darwin
+
+package supervisor
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// givenShim runs a shim for args in the background and returns its client.
+func givenShim(t *testing.T, args ...string) (*Client, chan error) {
+	t.Helper()
+
+	// Unix socket paths are short, so not the test temp dir.
+	dir, err := os.MkdirTemp("", "shim")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = os.RemoveAll(dir) })
+
+	socket := filepath.Join(dir, "server.sock")
+	ctx, cancel := context.WithCancel(context.Background())
+
+	done := make(chan error, 1)
+	go func() {
+		done <- RunShim(ctx, ShimOptions{Socket: socket, Args: args, WorkDir: dir, Linger: time.Minute})
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+
+	client := NewClient(socket)
+	require.Eventually(t, func() bool {
+		_, err := client.Status(context.Background())
+		return err == nil
+	}, 5*time.Second, 10*time.Millisecond)
+
+	return client, done
+}
+
+func waitForExit(t *testing.T, client *Client) State {
+	t.Helper()
+
+	var state State
+	require.Eventually(t, func() bool {
+		var err error
+		state, err = client.Status(context.Background())
+		return err == nil && !state.Running
+	}, 5*time.Second, 10*time.Millisecond)
+
+	return state
+}
+
+func TestShim_InputAndOutput(t *testing.T) {
+	client, _ := givenShim(t, "/bin/sh", "-c", "read line; echo got $line")
+	ctx := context.Background()
+
+	state, err := client.Status(ctx)
+	require.NoError(t, err)
+	assert.True(t, state.Running)
+	assert.Positive(t, state.PID)
+
+	require.NoError(t, client.Input(ctx, "hello"))
+
+	state = waitForExit(t, client)
+	assert.Equal(t, 0, state.ExitCode)
+	assert.False(t, state.ExitedAt.IsZero())
+
+	output, err := client.Output(ctx)
+	require.NoError(t, err)
+	assert.Contains(t, string(output), "got hello")
+
+	err = client.Input(ctx, "again")
+	require.ErrorIs(t, err, ErrProcessNotRunning)
+}
+
+func TestShim_ExitCode(t *testing.T) {
+	client, _ := givenShim(t, "/bin/sh", "-c", "exit 3")
+
+	state := waitForExit(t, client)
+
+	assert.Equal(t, 3, state.ExitCode)
+}
+
+func TestShim_Shutdown(t *testing.T) {
+	client, done := givenShim(t, "/bin/sh", "-c", "read line")
+	ctx := context.Background()
+
+	err := client.Shutdown(ctx)
+	require.ErrorIs(t, err, errShimRequest, "a running server keeps its shim")
+
+	require.NoError(t, client.Input(ctx, ""))
+	waitForExit(t, client)
+
+	require.NoError(t, client.Shutdown(ctx))
+
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+		done <- err // for the cleanup
+	case <-time.After(5 * time.Second):
+		t.Fatal("shim did not exit after shutdown")
+	}
+
+	_, err = client.Status(ctx)
+	require.ErrorIs(t, err, ErrShimNotRunning)
+}
+
+func TestShim_Attach(t *testing.T) {
+	client, _ := givenShim(t, "/bin/sh", "-c", "read line; echo got $line")
+
+	inReader, inWriter := io.Pipe()
+	out := &syncBuffer{}
+
+	attached := make(chan error, 1)
+	go func() {
+		attached <- client.Attach(context.Background(), inReader, out)
+	}()
+
+	// The server reads the line only once the attach stream is set up.
+	require.Eventually(t, func() bool {
+		_, _ = inWriter.Write([]byte("world\n"))
+		return bytes.Contains(out.Bytes(), []byte("got world"))
+	}, 5*time.Second, 50*time.Millisecond)
+	require.NoError(t, inWriter.Close())
+
+	select {
+	case err := <-attached:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("attach did not end after the server exited")
+	}
+}
+
+func TestClient_NoShim(t *testing.T) {
+	client := NewClient(filepath.Join(t.TempDir(), "missing.sock"))
+
+	_, err := client.Status(context.Background())
+
+	require.ErrorIs(t, err, ErrShimNotRunning)
+}
+
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) Bytes() []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return append([]byte(nil), b.buf.Bytes()...)
+}
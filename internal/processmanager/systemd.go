@@ -323,6 +323,33 @@ func (pm *SystemD) Stop(ctx context.Context, server *domain.Server, out io.Write
 	}
 	pm.ensureLingerChecked(ctx, out)
 
+	stopViaConsole(ctx, pm.cfg, pm, server, statusExitChecker(pm.Status, server), out)
+
+	// systemd sends SIGTERM and, after TimeoutStopSec, SIGKILL itself.
+	return pm.stopUnits(ctx, server, out)
+}
+
+// Kill sends SIGKILL to every process of the service and stops its units.
+func (pm *SystemD) Kill(ctx context.Context, server *domain.Server, out io.Writer) (domain.Result, error) {
+	if err := pm.requireUserMatch(server); err != nil {
+		return domain.ErrorResult, err
+	}
+	pm.ensureLingerChecked(ctx, out)
+
+	_, err := pm.executor.ExecWithWriter(
+		ctx,
+		pm.systemctl("kill --signal=SIGKILL", pm.resolveServiceName(server)),
+		out,
+		pm.execOpts(),
+	)
+	if err != nil {
+		return domain.ErrorResult, errors.WithMessage(err, "failed to exec command")
+	}
+
+	return pm.stopUnits(ctx, server, out)
+}
+
+func (pm *SystemD) stopUnits(ctx context.Context, server *domain.Server, out io.Writer) (domain.Result, error) {
 	socketName := pm.resolveSocketName(server)
 	serviceName := pm.resolveServiceName(server)
 
@@ -494,6 +521,37 @@ func (pm *SystemD) status(ctx context.Context, name string, out io.Writer) (doma
 	return domain.ErrorResult, errors.New("unknown exit code")
 }
 
+// ExitCode returns the exit status of the main process of the stopped
+// server unit.
+func (pm *SystemD) ExitCode(ctx context.Context, server *domain.Server) (int, bool) {
+	output, code, err := pm.executor.Exec(
+		ctx,
+		pm.systemctl("show --property=ActiveState,ExecMainStatus", pm.resolveServiceName(server)),
+		pm.execOpts(),
+	)
+	if err != nil || code != 0 {
+		return 0, false
+	}
+
+	props := make(map[string]string, 2)
+	for _, line := range strings.Split(string(output), "\n") {
+		if key, value, ok := strings.Cut(strings.TrimSpace(line), "="); ok {
+			props[key] = value
+		}
+	}
+
+	if props["ActiveState"] == "active" {
+		return 0, false
+	}
+
+	exitCode, err := strconv.Atoi(props["ExecMainStatus"])
+	if err != nil {
+		return 0, false
+	}
+
+	return exitCode, true
+}
+
 func (pm *SystemD) GetOutput(ctx context.Context, server *domain.Server, out io.Writer) (domain.Result, error) {
 	f, err := os.Open(pm.resolveLogFile(server))
 	if err != nil {
@@ -550,6 +608,36 @@ func (pm *SystemD) SendInput(
 	return domain.SuccessResult, nil
 }
 
+// Pause freezes the service cgroup. systemd freezes every process of the
+// unit at once, so the server cannot be half-stopped.
+func (pm *SystemD) Pause(ctx context.Context, server *domain.Server, out io.Writer) (domain.Result, error) {
+	return pm.serviceFreezer(ctx, server, "freeze", out)
+}
+
+func (pm *SystemD) Unpause(ctx context.Context, server *domain.Server, out io.Writer) (domain.Result, error) {
+	return pm.serviceFreezer(ctx, server, "thaw", out)
+}
+
+func (pm *SystemD) serviceFreezer(
+	ctx context.Context, server *domain.Server, action string, out io.Writer,
+) (domain.Result, error) {
+	if err := pm.requireUserMatch(server); err != nil {
+		return domain.ErrorResult, err
+	}
+
+	result, err := pm.executor.ExecWithWriter(
+		ctx,
+		pm.systemctl(action, pm.resolveServiceName(server)),
+		out,
+		pm.execOpts(),
+	)
+	if err != nil {
+		return domain.ErrorResult, errors.WithMessage(err, "failed to exec command")
+	}
+
+	return domain.Result(result), nil
+}
+
 func (pm *SystemD) makeService(ctx context.Context, server *domain.Server, out io.Writer) error {
 	f, err := os.OpenFile(pm.serviceFile(server), os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
 	if err != nil {
@@ -672,6 +760,13 @@ func (pm *SystemD) buildServiceConfig(server *domain.Server) (string, error) {
 		builder.WriteString("%\n")
 	}
 
+	// Process priority
+	priority, err := processPriority(pm.cfg, server)
+	if err != nil {
+		return "", err
+	}
+	writeSystemdPriority(&builder, priority)
+
 	// Environment variables
 	for key, value := range server.EnvironmentVars() {
 		builder.WriteString("Environment=")
@@ -1154,3 +1249,35 @@ func escapeSystemdEnv(key, value string) string {
 	sb.WriteByte('"')
 	return sb.String()
 }
+
+func writeSystemdPriority(builder *strings.Builder, priority contracts.ProcessPriority) {
+	if priority.Nice != nil {
+		builder.WriteString("Nice=")
+		builder.WriteString(strconv.Itoa(*priority.Nice))
+		builder.WriteString("\n")
+	}
+
+	if len(priority.CPUAffinity) > 0 {
+		builder.WriteString("CPUAffinity=")
+		builder.WriteString(formatCPUList(priority.CPUAffinity))
+		builder.WriteString("\n")
+	}
+
+	if priority.IOClass != "" {
+		builder.WriteString("IOSchedulingClass=")
+		builder.WriteString(priority.IOClass)
+		builder.WriteString("\n")
+	}
+
+	if priority.IOLevel != nil {
+		builder.WriteString("IOSchedulingPriority=")
+		builder.WriteString(strconv.Itoa(*priority.IOLevel))
+		builder.WriteString("\n")
+	}
+
+	if priority.OOMScoreAdj != nil {
+		builder.WriteString("OOMScoreAdjust=")
+		builder.WriteString(strconv.Itoa(*priority.OOMScoreAdj))
+		builder.WriteString("\n")
+	}
+}
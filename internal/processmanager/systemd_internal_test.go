@@ -353,6 +353,49 @@ func Test_buildServiceConfig_scopeDifferences(t *testing.T) {
 	})
 }
 
+func Test_buildServiceConfig_processPriority(t *testing.T) {
+	tempDir := t.TempDir()
+	cfg := makeConfigWithScope("user")
+
+	t.Run("directives", func(t *testing.T) {
+		cfg.ProcessManager.Config["nice"] = "5"
+		cfg.ProcessManager.Config["cpu_affinity"] = "0-1,3"
+		cfg.ProcessManager.Config["ionice_class"] = "idle"
+		cfg.ProcessManager.Config["ionice_level"] = "0"
+		cfg.ProcessManager.Config["oom_score_adj"] = "500"
+		pm := NewSystemD(cfg, nil, nil)
+
+		got, err := pm.buildServiceConfig(makeServerWithUser("", tempDir))
+
+		require.NoError(t, err)
+		assert.Contains(t, got, "\nNice=5\n")
+		assert.Contains(t, got, "\nCPUAffinity=0,1,3\n")
+		assert.Contains(t, got, "\nIOSchedulingClass=idle\n")
+		assert.Contains(t, got, "\nIOSchedulingPriority=0\n")
+		assert.Contains(t, got, "\nOOMScoreAdjust=500\n")
+	})
+
+	t.Run("not set", func(t *testing.T) {
+		pm := NewSystemD(makeConfigWithScope("user"), nil, nil)
+
+		got, err := pm.buildServiceConfig(makeServerWithUser("", tempDir))
+
+		require.NoError(t, err)
+		assert.NotContains(t, got, "Nice=")
+		assert.NotContains(t, got, "CPUAffinity=")
+		assert.NotContains(t, got, "OOMScoreAdjust=")
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		cfg.ProcessManager.Config["nice"] = "-21"
+		pm := NewSystemD(cfg, nil, nil)
+
+		_, err := pm.buildServiceConfig(makeServerWithUser("", tempDir))
+
+		assert.ErrorIs(t, err, errInvalidPriority)
+	})
+}
+
 func makeConfigWithScope(scope string) *config.Config {
 	cfg := &config.Config{
 		WorkPath: "",
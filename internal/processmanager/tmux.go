@@ -10,6 +10,7 @@ import (
 	"os"
 	"os/user"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gameap/daemon/internal/app/config"
@@ -30,6 +31,8 @@ type Tmux struct {
 	cfg              *config.Config
 	executor         contracts.Executor
 	detailedExecutor contracts.Executor
+	cpuSampler       *processCPUSampler
+	cgroups          *serverCgroups
 }
 
 func NewTmux(cfg *config.Config, executor, detailedExecutor contracts.Executor) *Tmux {
@@ -37,6 +40,8 @@ func NewTmux(cfg *config.Config, executor, detailedExecutor contracts.Executor)
 		cfg:              cfg,
 		executor:         executor,
 		detailedExecutor: detailedExecutor,
+		cpuSampler:       newProcessCPUSampler(),
+		cgroups:          newServerCgroups(cfg),
 	}
 }
 
@@ -68,6 +73,11 @@ func (pm *Tmux) Start(
 		return domain.ErrorResult, errors.WithMessage(err, "invalid server configuration")
 	}
 
+	priority, err := processPriority(pm.cfg, server)
+	if err != nil {
+		return domain.ErrorResult, errors.WithMessage(err, "invalid server configuration")
+	}
+
 	err = pm.makeTmuxInitialSession(ctx, server, out)
 	if err != nil {
 		return domain.ErrorResult, errors.WithMessage(err, "failed to create initial tmux session")
@@ -82,6 +92,11 @@ func (pm *Tmux) Start(
 		)
 	}
 
+	// The pane is started by the tmux server, not by the tmux client the
+	// executor runs, so the priority is applied to the pane afterwards too.
+	startOptions := options
+	startOptions.Priority = priority
+
 	result, err := pm.detailedExecutor.ExecWithWriterArgs(
 		ctx,
 		[]string{
@@ -91,7 +106,7 @@ func (pm *Tmux) Start(
 			startCmd,
 		},
 		out,
-		options,
+		startOptions,
 	)
 	if err != nil {
 		return domain.ErrorResult, errors.WithMessage(err, "failed to exec command")
@@ -108,9 +123,39 @@ func (pm *Tmux) Start(
 		logger.Logger(ctx).WithError(err).Warn("Failed to set history limit")
 	}
 
+	if domain.Result(result) == domain.SuccessResult {
+		pm.limitSession(ctx, server, sessionName, options, priority)
+	}
+
 	return domain.Result(result), nil
 }
 
+// limitSession moves the process tree of the session pane into the server
+// cgroup and applies the server process priority to it. A server that cannot
+// be limited keeps running.
+func (pm *Tmux) limitSession(
+	ctx context.Context,
+	server *domain.Server,
+	sessionName string,
+	options contracts.ExecutorOptions,
+	priority contracts.ProcessPriority,
+) {
+	pid, err := pm.panePID(ctx, sessionName, options)
+	if err != nil {
+		logger.Warn(ctx, errors.WithMessage(err, "failed to get pane pid for the server cgroup"))
+
+		return
+	}
+
+	if err := pm.cgroups.place(ctx, server, pid); err != nil {
+		logger.Warn(ctx, errors.WithMessage(err, "failed to apply server resource limits"))
+	}
+
+	if err := applyProcessTreePriority(ctx, server, pid, priority); err != nil {
+		logger.Warn(ctx, errors.WithMessage(err, "failed to apply server process priority"))
+	}
+}
+
 func (pm *Tmux) Stop(
 	ctx context.Context, server *domain.Server, out io.Writer,
 ) (domain.Result, error) {
@@ -120,7 +165,62 @@ func (pm *Tmux) Stop(
 	}
 
 	sessionName := pm.resolveSessionName(ctx, server, options)
+	exited := statusExitChecker(pm.Status, server)
+
+	defer pm.cgroups.remove(ctx, server)
+
+	if stopViaConsole(ctx, pm.cfg, pm, server, exited, out) {
+		pm.closeSession(ctx, sessionName, options)
+
+		return domain.SuccessResult, nil
+	}
+
+	pid, err := pm.panePID(ctx, sessionName, options)
+	if err != nil {
+		// Nothing to signal, killing the session is all that is left to do.
+		return pm.killSession(ctx, sessionName, options, out)
+	}
+
+	if terminateProcess(ctx, pm.cfg, server, pid, exited, out) {
+		pm.closeSession(ctx, sessionName, options)
+
+		return domain.SuccessResult, nil
+	}
+
+	return pm.killSession(ctx, sessionName, options, out)
+}
+
+// Kill skips the console and SIGTERM stages and kills the session processes
+// with SIGKILL right away.
+func (pm *Tmux) Kill(
+	ctx context.Context, server *domain.Server, out io.Writer,
+) (domain.Result, error) {
+	options, err := pm.executeOptions(server)
+	if err != nil {
+		return domain.ErrorResult, errors.WithMessage(err, "invalid server configuration")
+	}
+
+	sessionName := pm.resolveSessionName(ctx, server, options)
+
+	defer pm.cgroups.remove(ctx, server)
+
+	pid, err := pm.panePID(ctx, sessionName, options)
+	if err != nil {
+		return pm.killSession(ctx, sessionName, options, out)
+	}
+
+	if killProcess(ctx, pid, statusExitChecker(pm.Status, server), out) {
+		pm.closeSession(ctx, sessionName, options)
+
+		return domain.SuccessResult, nil
+	}
 
+	return pm.killSession(ctx, sessionName, options, out)
+}
+
+func (pm *Tmux) killSession(
+	ctx context.Context, sessionName string, options contracts.ExecutorOptions, out io.Writer,
+) (domain.Result, error) {
 	result, err := pm.detailedExecutor.ExecWithWriter(
 		ctx,
 		fmt.Sprintf(`tmux kill-session -t %s`, sessionName),
@@ -134,6 +234,17 @@ func (pm *Tmux) Stop(
 	return domain.Result(result), nil
 }
 
+// closeSession removes a session whose process has already exited. tmux
+// usually closes it by itself, unless remain-on-exit is set.
+func (pm *Tmux) closeSession(ctx context.Context, sessionName string, options contracts.ExecutorOptions) {
+	_, _ = pm.executor.ExecWithWriter(
+		ctx,
+		fmt.Sprintf(`tmux kill-session -t %s`, sessionName),
+		io.Discard,
+		options,
+	)
+}
+
 func (pm *Tmux) Restart(
 	ctx context.Context, server *domain.Server, out io.Writer,
 ) (domain.Result, error) {
@@ -221,6 +332,72 @@ func (pm *Tmux) SendInput(
 	return domain.Result(result), nil
 }
 
+func (pm *Tmux) Pause(
+	ctx context.Context, server *domain.Server, out io.Writer,
+) (domain.Result, error) {
+	return pm.signalSession(ctx, server, freezeProcessGroup, "paused", out)
+}
+
+func (pm *Tmux) Unpause(
+	ctx context.Context, server *domain.Server, out io.Writer,
+) (domain.Result, error) {
+	return pm.signalSession(ctx, server, thawProcessGroup, "resumed", out)
+}
+
+// signalSession applies signal to the process group of the session's pane.
+// tmux starts every pane in its own session, so the pane PID leads a group
+// that holds the start command and everything it spawned.
+func (pm *Tmux) signalSession(
+	ctx context.Context,
+	server *domain.Server,
+	signal func(pid int) error,
+	action string,
+	out io.Writer,
+) (domain.Result, error) {
+	options, err := pm.executeOptions(server)
+	if err != nil {
+		return domain.ErrorResult, errors.WithMessage(err, "invalid server configuration")
+	}
+
+	sessionName := pm.resolveSessionName(ctx, server, options)
+
+	pid, err := pm.panePID(ctx, sessionName, options)
+	if err != nil {
+		return domain.ErrorResult, err
+	}
+
+	if err := signal(pid); err != nil {
+		return domain.ErrorResult, errors.WithMessagef(err, "failed to signal tmux session %s", sessionName)
+	}
+
+	_, _ = fmt.Fprintf(out, "Server processes %s (tmux session %s, pid %d)\n", action, sessionName, pid)
+
+	return domain.SuccessResult, nil
+}
+
+func (pm *Tmux) panePID(
+	ctx context.Context, sessionName string, options contracts.ExecutorOptions,
+) (int, error) {
+	output, result, err := pm.executor.ExecArgs(
+		ctx,
+		[]string{"tmux", "display-message", "-p", "-t", sessionName, "#{pane_pid}"},
+		options,
+	)
+	if err != nil {
+		return 0, errors.WithMessage(err, "failed to exec command")
+	}
+	if domain.Result(result) != domain.SuccessResult {
+		return 0, ErrServiceNotRunning
+	}
+
+	pid, err := strconv.Atoi(strings.TrimSpace(string(output)))
+	if err != nil {
+		return 0, errors.Wrapf(ErrInvalidPID, "tmux session %s reported pane pid %q", sessionName, output)
+	}
+
+	return pid, nil
+}
+
 func (pm *Tmux) makeTmuxInitialSession(ctx context.Context, server *domain.Server, out io.Writer) error {
 	defaultOptions, err := pm.executeOptions(server)
 	if err != nil {
@@ -479,9 +656,44 @@ func (pm *Tmux) HasOwnInstallation(_ *domain.Server) bool {
 	return false
 }
 
-// Metrics returns only the cached process-active gauge for now. Tmux sessions
-// hold child PIDs needed for resource stats; richer metrics are tracked as a
-// follow-up.
-func (pm *Tmux) Metrics(_ context.Context, server *domain.Server) ([]domain.Metric, error) {
-	return []domain.Metric{livenessMetric(server, time.Now())}, nil
+// Metrics returns CPU, memory, block-IO and PID counters of the server
+// cgroup, or summed over the process tree of the session pane when the
+// server has no cgroup. Falls back to the cached liveness gauge
+// alone when the session is gone or its pane PID cannot be read.
+func (pm *Tmux) Metrics(ctx context.Context, server *domain.Server) ([]domain.Metric, error) {
+	now := time.Now()
+	out := make([]domain.Metric, 0, 6)
+	out = append(out, livenessMetric(server, now))
+
+	options, err := pm.executeOptions(server)
+	if err != nil {
+		return out, nil
+	}
+
+	sessionName := pm.resolveSessionName(ctx, server, options)
+
+	pid, err := pm.panePID(ctx, sessionName, options)
+	if err != nil {
+		return out, nil
+	}
+
+	stats, ok := pm.cgroups.stats(ctx, server)
+	if !ok {
+		stats, err = collectProcessTreeStats(ctx, int32(pid))
+		if err != nil {
+			logger.Debug(ctx, errors.WithMessage(err, "failed to collect tmux session metrics"))
+			return out, nil
+		}
+	}
+
+	cpuPercent, hasCPU := pm.cpuSampler.percent(sessionName, processCPUSample{
+		rootPID:    int32(pid),
+		cpuSeconds: stats.CPUSeconds,
+		at:         now,
+	})
+
+	labels := map[string]string{metricLabelSession: sessionName}
+	out = append(out, processTreeToMetrics(now, labels, stats, cpuPercent, hasCPU)...)
+
+	return out, nil
 }
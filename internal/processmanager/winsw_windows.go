@@ -107,6 +107,14 @@ func (pm *WinSW) Stop(ctx context.Context, server *domain.Server, out io.Writer)
 	return domain.SuccessResult, nil
 }
 
+// Kill terminates the service process tree without waiting for the
+// service to stop.
+func (pm *WinSW) Kill(ctx context.Context, server *domain.Server, out io.Writer) (domain.Result, error) {
+	return killServiceProcess(ctx, pm.executor, pm.serviceName(server), contracts.ExecutorOptions{
+		WorkDir: pm.cfg.WorkDir(),
+	}, out)
+}
+
 func (pm *WinSW) Restart(ctx context.Context, server *domain.Server, out io.Writer) (domain.Result, error) {
 	return pm.command(ctx, server, commandRestart, out)
 }
@@ -525,6 +533,14 @@ func (pm *WinSW) Attach(
 	return ErrNotImplemented
 }
 
+func (pm *WinSW) Pause(_ context.Context, _ *domain.Server, _ io.Writer) (domain.Result, error) {
+	return domain.ErrorResult, ErrNotImplemented
+}
+
+func (pm *WinSW) Unpause(_ context.Context, _ *domain.Server, _ io.Writer) (domain.Result, error) {
+	return domain.ErrorResult, ErrNotImplemented
+}
+
 func (pm *WinSW) HasOwnInstallation(_ *domain.Server) bool {
 	return false
 }
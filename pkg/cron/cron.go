@@ -0,0 +1,396 @@
+// Package cron parses cron schedules and finds their next activation.
+//
+// A schedule is either 5 fields (minute, hour, day of month, month, day of
+// week) or 6 fields with the seconds first. A field is "*", "?" (same as
+// "*"), a value, a range "1-5", a step "*/15" or "10-50/10", or a comma
+// separated list of them. Months and days of week also accept names, JAN-DEC
+// and SUN-SAT, and 7 is Sunday as well as 0. The descriptors @yearly
+// (@annually), @monthly, @weekly, @daily (@midnight) and @hourly are
+// supported too.
+//
+// As in Vixie cron, a schedule restricting both the day of month and the day
+// of week is due on the days matching either of them.
+package cron
+
+import (
+	"math/bits"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// searchDays bounds the search for the next activation, a schedule such as
+// "0 0 30 2 *" is never due.
+const searchDays = 5 * 366
+
+var ErrInvalidSchedule = errors.New("invalid cron schedule")
+
+var descriptors = map[string]string{
+	"@yearly":   "0 0 0 1 1 *",
+	"@annually": "0 0 0 1 1 *",
+	"@monthly":  "0 0 0 1 * *",
+	"@weekly":   "0 0 0 * * 0",
+	"@daily":    "0 0 0 * * *",
+	"@midnight": "0 0 0 * * *",
+	"@hourly":   "0 0 * * * *",
+}
+
+var monthNames = map[string]uint{
+	"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+	"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+}
+
+var dowNames = map[string]uint{
+	"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+}
+
+type field struct {
+	name     string
+	min, max uint
+	names    map[string]uint
+}
+
+var (
+	secondField = field{name: "second", min: 0, max: 59}
+	minuteField = field{name: "minute", min: 0, max: 59}
+	hourField   = field{name: "hour", min: 0, max: 23}
+	domField    = field{name: "day of month", min: 1, max: 31}
+	monthField  = field{name: "month", min: 1, max: 12, names: monthNames}
+	dowField    = field{name: "day of week", min: 0, max: 7, names: dowNames}
+)
+
+// Schedule is a parsed cron schedule. Every field is a bit set of the values
+// it matches.
+type Schedule struct {
+	spec string
+
+	second, minute, hour, dom, month, dow uint64
+
+	// domAny and dowAny are set for unrestricted day fields, see the
+	// package docs.
+	domAny, dowAny bool
+}
+
+// Parse parses a 5 or 6 field schedule or a descriptor.
+func Parse(spec string) (*Schedule, error) {
+	spec = strings.TrimSpace(spec)
+
+	expr := spec
+	if strings.HasPrefix(expr, "@") {
+		var ok bool
+		if expr, ok = descriptors[strings.ToLower(expr)]; !ok {
+			return nil, errors.Wrapf(ErrInvalidSchedule, "unknown descriptor %q", spec)
+		}
+	}
+
+	fields := strings.Fields(expr)
+	switch len(fields) {
+	case 5:
+		fields = append([]string{"0"}, fields...)
+	case 6:
+	default:
+		return nil, errors.Wrapf(ErrInvalidSchedule, "%q: expected 5 or 6 fields, got %d", spec, len(fields))
+	}
+
+	s := &Schedule{spec: spec}
+
+	var err error
+	targets := []struct {
+		bits *uint64
+		f    field
+	}{
+		{&s.second, secondField},
+		{&s.minute, minuteField},
+		{&s.hour, hourField},
+		{&s.dom, domField},
+		{&s.month, monthField},
+		{&s.dow, dowField},
+	}
+
+	for i, target := range targets {
+		if *target.bits, err = parseField(fields[i], target.f); err != nil {
+			return nil, errors.WithMessagef(err, "%q", spec)
+		}
+	}
+
+	// Sunday is both 0 and 7.
+	if s.dow&(1<<7) != 0 {
+		s.dow = s.dow&^(1<<7) | 1
+	}
+
+	s.domAny = isAnyDay(fields[3])
+	s.dowAny = isAnyDay(fields[5])
+
+	return s, nil
+}
+
+func isAny(f string) bool {
+	return f == "*" || f == "?"
+}
+
+// isAnyDay reports whether a day field is unrestricted for combining the day
+// fields, "*/2" counts as "*" as in Vixie cron.
+func isAnyDay(f string) bool {
+	return strings.HasPrefix(f, "*") || strings.HasPrefix(f, "?")
+}
+
+func parseField(expr string, f field) (uint64, error) {
+	var set uint64
+
+	for _, part := range strings.Split(expr, ",") {
+		bits, err := parsePart(part, f)
+		if err != nil {
+			return 0, err
+		}
+		set |= bits
+	}
+
+	return set, nil
+}
+
+func parsePart(part string, f field) (uint64, error) {
+	rangeExpr, stepExpr, hasStep := strings.Cut(part, "/")
+
+	step := uint(1)
+	if hasStep {
+		n, err := strconv.ParseUint(stepExpr, 10, 8)
+		if err != nil || n == 0 {
+			return 0, errors.Wrapf(ErrInvalidSchedule, "%s: invalid step %q", f.name, stepExpr)
+		}
+		step = uint(n)
+	}
+
+	var low, high uint
+
+	switch {
+	case isAny(rangeExpr):
+		low, high = f.min, f.max
+		if f.max == 7 {
+			// Day of week "*" is 0-6, 7 would be Sunday twice.
+			high = 6
+		}
+	case strings.Contains(rangeExpr, "-"):
+		lowExpr, highExpr, _ := strings.Cut(rangeExpr, "-")
+
+		var err error
+		if low, err = parseValue(lowExpr, f); err != nil {
+			return 0, err
+		}
+		if high, err = parseValue(highExpr, f); err != nil {
+			return 0, err
+		}
+		if low > high {
+			return 0, errors.Wrapf(ErrInvalidSchedule, "%s: invalid range %q", f.name, rangeExpr)
+		}
+	default:
+		value, err := parseValue(rangeExpr, f)
+		if err != nil {
+			return 0, err
+		}
+
+		low, high = value, value
+		if hasStep {
+			// "5/15" is "5-59/15".
+			high = f.max
+		}
+	}
+
+	var set uint64
+	for v := low; v <= high; v += step {
+		set |= 1 << v
+	}
+
+	return set, nil
+}
+
+func parseValue(expr string, f field) (uint, error) {
+	if v, ok := f.names[strings.ToLower(expr)]; ok {
+		return v, nil
+	}
+
+	n, err := strconv.ParseUint(expr, 10, 8)
+	if err != nil || uint(n) < f.min || uint(n) > f.max {
+		return 0, errors.Wrapf(ErrInvalidSchedule, "%s: invalid value %q", f.name, expr)
+	}
+
+	return uint(n), nil
+}
+
+// String returns the schedule as it was parsed.
+func (s *Schedule) String() string {
+	return s.spec
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (s *Schedule) MarshalText() ([]byte, error) {
+	return []byte(s.spec), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (s *Schedule) UnmarshalText(text []byte) error {
+	parsed, err := Parse(string(text))
+	if err != nil {
+		return err
+	}
+
+	*s = *parsed
+
+	return nil
+}
+
+// Next returns the first activation after t, in the location of t. The zero
+// time is returned when the schedule is not due in the next five years.
+//
+// The schedule follows the wall clock of the location. A wall clock time
+// skipped when the clock is moved forward is due at the same offset after
+// the change, e.g. 02:30 at 03:30. A wall clock time repeated when the clock
+// is moved back is due once, at its first occurrence.
+func (s *Schedule) Next(t time.Time) time.Time {
+	loc := t.Location()
+
+	year, month, day := t.Date()
+	hour, minute, second := t.Clock()
+
+	// Dates are stepped in UTC, where every day is 24 hours long.
+	date := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+
+	for i := range searchDays {
+		d := date.AddDate(0, 0, i)
+		if !s.dayMatches(d) {
+			continue
+		}
+
+		from := clock{}
+		if i == 0 {
+			from = clock{hour, minute, second + 1}
+		}
+
+		for c, ok := s.nextClock(from); ok; c, ok = s.nextClock(c.next()) {
+			next := firstOccurrence(time.Date(d.Year(), d.Month(), d.Day(), c.hour, c.minute, c.second, 0, loc))
+			if next.After(t) {
+				return next
+			}
+
+			// t is in an hour repeated after the clock was moved back and next
+			// is the first occurrence of the wall clock time.
+			if later := secondOccurrence(next); later.After(t) {
+				return later
+			}
+		}
+	}
+
+	return time.Time{}
+}
+
+// firstOccurrence returns the same wall clock time before the clock is moved
+// back, time.Date picks either of them.
+func firstOccurrence(t time.Time) time.Time {
+	_, offset := t.Zone()
+	_, earlierOffset := t.Add(-3 * time.Hour).Zone()
+
+	if earlierOffset <= offset {
+		return t
+	}
+
+	earlier := t.Add(-time.Duration(earlierOffset-offset) * time.Second)
+	if !sameWallClock(earlier, t) {
+		return t
+	}
+
+	return earlier
+}
+
+// secondOccurrence returns the same wall clock time after the clock is moved
+// back, or t when the time is not repeated.
+func secondOccurrence(t time.Time) time.Time {
+	_, offset := t.Zone()
+	_, laterOffset := t.Add(3 * time.Hour).Zone()
+
+	if laterOffset >= offset {
+		return t
+	}
+
+	later := t.Add(time.Duration(offset-laterOffset) * time.Second)
+	if !sameWallClock(later, t) {
+		return t
+	}
+
+	return later
+}
+
+func sameWallClock(a, b time.Time) bool {
+	return a.Hour() == b.Hour() && a.Minute() == b.Minute() && a.Second() == b.Second()
+}
+
+func (s *Schedule) dayMatches(d time.Time) bool {
+	if s.month&(1<<uint(d.Month())) == 0 {
+		return false
+	}
+
+	domMatch := s.dom&(1<<uint(d.Day())) != 0
+	dowMatch := s.dow&(1<<uint(d.Weekday())) != 0
+
+	if !s.domAny && !s.dowAny {
+		return domMatch || dowMatch
+	}
+
+	return domMatch && dowMatch
+}
+
+type clock struct {
+	hour, minute, second int
+}
+
+func (c clock) next() clock {
+	return clock{c.hour, c.minute, c.second + 1}
+}
+
+// nextClock returns the first time of the day at or after from matching the
+// schedule.
+func (s *Schedule) nextClock(from clock) (clock, bool) {
+	for hour := from.hour; hour < 24; hour++ {
+		if s.hour&(1<<uint(hour)) == 0 {
+			continue
+		}
+
+		minFrom := 0
+		if hour == from.hour {
+			minFrom = from.minute
+		}
+
+		for minute := minFrom; minute < 60; minute++ {
+			if s.minute&(1<<uint(minute)) == 0 {
+				continue
+			}
+
+			secFrom := 0
+			if hour == from.hour && minute == from.minute {
+				secFrom = from.second
+			}
+
+			if second, ok := nextBit(s.second, secFrom, 60); ok {
+				return clock{hour, minute, second}, true
+			}
+		}
+	}
+
+	return clock{}, false
+}
+
+// nextBit returns the lowest set bit of set in [from, limit).
+func nextBit(set uint64, from, limit int) (int, bool) {
+	if from >= limit {
+		return 0, false
+	}
+
+	rest := set >> uint(from)
+	if rest == 0 {
+		return 0, false
+	}
+
+	bit := from + bits.TrailingZeros64(rest)
+
+	return bit, bit < limit
+}
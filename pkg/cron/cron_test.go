@@ -0,0 +1,146 @@
+package cron
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func mustLoadLocation(t *testing.T, name string) *time.Location {
+	t.Helper()
+
+	loc, err := time.LoadLocation(name)
+	require.NoError(t, err)
+
+	return loc
+}
+
+func TestSchedule_Next(t *testing.T) {
+	from := time.Date(2026, 3, 11, 10, 17, 30, 0, time.UTC) // Wednesday
+
+	tests := []struct {
+		spec     string
+		expected time.Time
+	}{
+		{"*/15 * * * *", time.Date(2026, 3, 11, 10, 30, 0, 0, time.UTC)},
+		{"0 5 * * *", time.Date(2026, 3, 12, 5, 0, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2026, 3, 11, 11, 0, 0, 0, time.UTC)},
+		{"@daily", time.Date(2026, 3, 12, 0, 0, 0, 0, time.UTC)},
+		{"@weekly", time.Date(2026, 3, 15, 0, 0, 0, 0, time.UTC)},
+		{"@monthly", time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC)},
+		{"@yearly", time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 6 * * mon-fri", time.Date(2026, 3, 12, 6, 0, 0, 0, time.UTC)},
+		{"0 6 * * SAT,SUN", time.Date(2026, 3, 14, 6, 0, 0, 0, time.UTC)},
+		{"0 6 * * 7", time.Date(2026, 3, 15, 6, 0, 0, 0, time.UTC)},
+		{"0 0 1 jan-jun/3 *", time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC)},
+		{"45 17 10 * * ?", time.Date(2026, 3, 11, 10, 17, 45, 0, time.UTC)},
+		{"*/20 * * * * *", time.Date(2026, 3, 11, 10, 17, 40, 0, time.UTC)},
+		// Either day field matches when both are restricted.
+		{"0 0 13 * fri", time.Date(2026, 3, 13, 0, 0, 0, 0, time.UTC)},
+		{"0 0 20 * wed", time.Date(2026, 3, 18, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, test := range tests {
+		t.Run(test.spec, func(t *testing.T) {
+			s, err := Parse(test.spec)
+			require.NoError(t, err)
+
+			assert.Equal(t, test.expected, s.Next(from))
+		})
+	}
+}
+
+func TestSchedule_Next_NeverDue(t *testing.T) {
+	s, err := Parse("0 0 30 2 *")
+	require.NoError(t, err)
+
+	assert.True(t, s.Next(time.Now()).IsZero())
+}
+
+func TestSchedule_Next_FollowsWallClockOverDST(t *testing.T) {
+	berlin := mustLoadLocation(t, "Europe/Berlin")
+	s, err := Parse("0 5 * * *")
+	require.NoError(t, err)
+
+	// Clocks move forward on 2026-03-29 and back on 2026-10-25.
+	next := s.Next(time.Date(2026, 3, 28, 5, 0, 0, 0, berlin))
+	assert.Equal(t, time.Date(2026, 3, 29, 5, 0, 0, 0, berlin), next)
+	assert.Equal(t, 23*time.Hour, next.Sub(time.Date(2026, 3, 28, 5, 0, 0, 0, berlin)))
+
+	next = s.Next(time.Date(2026, 10, 24, 5, 0, 0, 0, berlin))
+	assert.Equal(t, time.Date(2026, 10, 25, 5, 0, 0, 0, berlin), next)
+	assert.Equal(t, 25*time.Hour, next.Sub(time.Date(2026, 10, 24, 5, 0, 0, 0, berlin)))
+}
+
+func TestSchedule_Next_SkippedWallClockTime(t *testing.T) {
+	berlin := mustLoadLocation(t, "Europe/Berlin")
+	s, err := Parse("30 2 * * *")
+	require.NoError(t, err)
+
+	next := s.Next(time.Date(2026, 3, 28, 2, 30, 0, 0, berlin))
+
+	assert.Equal(t, time.Date(2026, 3, 29, 3, 30, 0, 0, berlin), next)
+	assert.Equal(t, time.Date(2026, 3, 30, 2, 30, 0, 0, berlin), s.Next(next))
+}
+
+func TestSchedule_Next_RepeatedWallClockTimeIsDueOnce(t *testing.T) {
+	berlin := mustLoadLocation(t, "Europe/Berlin")
+	s, err := Parse("30 2 * * *")
+	require.NoError(t, err)
+
+	first := s.Next(time.Date(2026, 10, 24, 2, 30, 0, 0, berlin))
+	_, offset := first.Zone()
+	assert.Equal(t, 2*60*60, offset, "the first occurrence is in summer time")
+
+	assert.Equal(t, time.Date(2026, 10, 26, 2, 30, 0, 0, berlin), s.Next(first))
+}
+
+func TestSchedule_Next_InRepeatedHour(t *testing.T) {
+	berlin := mustLoadLocation(t, "Europe/Berlin")
+	s, err := Parse("*/15 * * * *")
+	require.NoError(t, err)
+
+	// 02:20 of winter time, after the clock was moved back at 03:00.
+	from := time.Date(2026, 10, 25, 1, 20, 0, 0, time.UTC).In(berlin)
+
+	next := s.Next(from)
+
+	assert.Equal(t, time.Date(2026, 10, 25, 1, 30, 0, 0, time.UTC), next.UTC())
+}
+
+func TestParse_Invalid(t *testing.T) {
+	specs := []string{
+		"",
+		"* * * *",
+		"* * * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"* * * foo *",
+		"@every 5m",
+	}
+
+	for _, spec := range specs {
+		_, err := Parse(spec)
+
+		assert.ErrorIs(t, err, ErrInvalidSchedule, spec)
+	}
+}
+
+func TestSchedule_Text(t *testing.T) {
+	var s Schedule
+
+	require.NoError(t, s.UnmarshalText([]byte("0 5 * * mon-fri")))
+
+	text, err := s.MarshalText()
+	require.NoError(t, err)
+	assert.Equal(t, "0 5 * * mon-fri", string(text))
+	assert.Error(t, s.UnmarshalText([]byte("0 25 * * *")))
+}